@@ -0,0 +1,71 @@
+package docxgen
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// [annex/file.docx Title] — includes appended as numbered annexes, [annexes] — their list
+// ============================================================================
+
+const (
+	// annexOpenPrefix - the start of the annex marker.
+	annexOpenPrefix = "[annex/"
+	// annexListPrefix - the start of the marker of the list of the annexes.
+	annexListPrefix = "[annexes"
+	// annexLabel - the label of an annex before its number.
+	annexLabel = "Приложение № "
+)
+
+// ResolveAnnexes replaces the paragraphs with [annex/file.docx Title] by the document file.docx
+// (the fragments /table/N and /p/N and %var% of [include/...] work too) as an annex: a page break,
+// "Приложение № N" at the right, the title (the text after the path, if any) in bold at the center
+// and the content. The annexes are numbered in their order in the part, from 1.
+//
+// Every paragraph with [annexes] is replaced by the list of the annexes of the part, a paragraph
+// per annex: "Приложение № 1 — Прайс-лист". An annex that cannot be included is removed with
+// the empty_include warning and does not take a number.
+func (d *Docx) ResolveAnnexes(body string, data map[string]any) string {
+	var list []string
+	body = replaceMarkerParagraphs(body, annexOpenPrefix, func(spec string) string {
+		path, title, _ := strings.Cut(strings.TrimSpace(spec), " ")
+		title = strings.TrimSpace(title)
+		inc, err := ParseBracketIncludeTag("[include/"+path+"]", data)
+		if err != nil {
+			d.warn(WarnEmptyInclude, spec, "%v", err)
+			return ""
+		}
+		frag, _, err := d.getIncludeXML(inc)
+		if err != nil {
+			d.warn(WarnEmptyInclude, inc.File, "%v", err)
+			return ""
+		}
+		frag = frag[:bodyEnd(frag, len(frag))] // the section of the annex file is not carried over
+		d.emit(IncludeResolved{Part: d.activePart, File: inc.File})
+
+		label := annexLabel + strconv.Itoa(len(list)+1)
+		entry := label
+		if title != "" {
+			entry += " — " + title
+		}
+		list = append(list, entry)
+
+		var b strings.Builder
+		b.WriteString(pageBreakParagraph)
+		b.WriteString(`<w:p><w:pPr><w:jc w:val="right"/></w:pPr><w:r><w:t xml:space="preserve">` + xmlEscape(label) + `</w:t></w:r></w:p>`)
+		if title != "" {
+			b.WriteString(`<w:p><w:pPr><w:jc w:val="center"/></w:pPr><w:r><w:rPr><w:b/></w:rPr>` +
+				`<w:t xml:space="preserve">` + xmlEscape(title) + `</w:t></w:r></w:p>`)
+		}
+		b.WriteString(frag)
+		return b.String()
+	})
+	return replaceMarkerParagraphs(body, annexListPrefix, func(string) string {
+		var b strings.Builder
+		for _, entry := range list {
+			b.WriteString(cellText(entry, false, false))
+		}
+		return b.String()
+	})
+}
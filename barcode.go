@@ -2,40 +2,60 @@ package docxgen
 
 import (
 	"bytes"
+	"docxgen/geometry"
 	"docxgen/modifiers"
 	"fmt"
 	"image"
+	"image/draw"
 	"image/png"
+	"math"
 	"strconv"
 	"strings"
 
 	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/aztec"
 	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/code39"
+	"github.com/boombuler/barcode/datamatrix"
 	"github.com/boombuler/barcode/ean"
+	"github.com/boombuler/barcode/pdf417"
+	"github.com/boombuler/barcode/twooffive"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
 )
 
-// Barcode - Inserts a barcode (Code128, EAN13) into a document.
+// Barcode - Inserts a barcode into a document: code128 (default), ean13 (ean8 by the length),
+// code39, itf14, and the 2D datamatrix, pdf417 and aztec (see barcodeSymbologies).
 // Supports crop (%), margins (x/y), inline/anchor, and relative sizes (% of page).
+// Without a height a 1D code is 1:3 of its width, DataMatrix and Aztec are square,
+// PDF417 keeps its own proportions; with a height only ("*15mm") the width follows the same proportions.
+// A size too small for a pixel per module is reported as a barcode error.
 func (d *Docx) Barcode(value string, opts ...string) modifiers.RawXML {
 	if value == "" {
 		return ""
 	}
 
-	const emuPerMM = 36000
-
 	// ---------- Default parameters ----------
 	codeType := "code128"
 	mode := "anchor"
 	align := "right"
 	valign := "top"
-	sizeWMM := 40.0
-	sizeHMM := 0.0 // if 0, count 1:3
+	sizeW := geometry.FromMM(40)
+	sizeH := geometry.Length(0) // if 0, count 1:3
 	crop := 0.0
 	hasBorder := false
-	distT, distB, distL, distR := 0, 0, 0, 0
+	quietModules := -1.0 // -1 — by the symbology standard
+	quietZone := geometry.Length(0)
+	caption := false
+	captionPt := 8.0
+	var dist geometry.Sides
+	wrap := defaultAnchorWrap
 
-	// ---------- Page Dimensions (for % Calculations) ----------
-	pageW, pageH := d.GetPageSizeEMU()
+	// ---------- Text area of the section (for % Calculations) ----------
+	pageW, pageH := d.GetUsableSizeEMU()
+	refW, refH := geometry.Length(pageW), geometry.Length(pageH)
 
 	// ---------- Parsing options ----------
 	for _, token := range opts {
@@ -44,6 +64,9 @@ func (d *Docx) Barcode(value string, opts ...string) modifiers.RawXML {
 		case token == "anchor" || token == "inline":
 			mode = token
 
+		case parseWrapToken(token, &wrap):
+			// wrapping and z-order of the anchor
+
 		case strings.EqualFold(token, "left"),
 			strings.EqualFold(token, "center"),
 			strings.EqualFold(token, "right"):
@@ -57,75 +80,53 @@ func (d *Docx) Barcode(value string, opts ...string) modifiers.RawXML {
 			}
 			valign = token
 
-		case strings.HasSuffix(token, "%"):
-			// crop or relative dimensions
-			if strings.Contains(token, "*") {
-				break
+		case strings.HasPrefix(token, "*"):
+			// height only: "*15mm", the width keeps the proportions
+			if h, err := geometry.ParseLength(token[1:], refH); err == nil && h > 0 {
+				sizeW, sizeH = 0, h
 			}
+
+		case strings.Contains(token, "*"),
+			strings.HasSuffix(token, "mm"):
+			// Dimensions: "40mm", "50mm*15mm", "80%*10mm" (percent of the page)
+			if w, h, err := geometry.ParseSize(token, refW, refH); err == nil {
+				sizeW, sizeH = w, h
+			}
+
+		case strings.HasSuffix(token, "%"):
+			// crop
 			if v, err := strconv.ParseFloat(strings.TrimSuffix(token, "%"), 64); err == nil {
 				crop = v
 			}
 
 		case strings.Contains(token, "/"): // padding
-			parts := strings.Split(token, "/")
-			switch len(parts) {
-			case 2:
-				if v, err := strconv.ParseFloat(parts[0], 64); err == nil {
-					distT = int(v * emuPerMM)
-					distB = distT
-				}
-				if v, err := strconv.ParseFloat(parts[1], 64); err == nil {
-					distL = int(v * emuPerMM)
-					distR = distL
-				}
-			case 3:
-				if v, err := strconv.ParseFloat(parts[0], 64); err == nil {
-					distT = int(v * emuPerMM)
-				}
-				if v, err := strconv.ParseFloat(parts[1], 64); err == nil {
-					distL = int(v * emuPerMM)
-					distR = distL
-				}
-				if v, err := strconv.ParseFloat(parts[2], 64); err == nil {
-					distB = int(v * emuPerMM)
-				}
-			case 4:
-				if v, err := strconv.ParseFloat(parts[0], 64); err == nil {
-					distT = int(v * emuPerMM)
-				}
-				if v, err := strconv.ParseFloat(parts[1], 64); err == nil {
-					distR = int(v * emuPerMM)
-				}
-				if v, err := strconv.ParseFloat(parts[2], 64); err == nil {
-					distB = int(v * emuPerMM)
-				}
-				if v, err := strconv.ParseFloat(parts[3], 64); err == nil {
-					distL = int(v * emuPerMM)
-				}
+			if sides, err := geometry.ParseSides(token); err == nil {
+				dist = sides
 			}
 
-		case strings.HasSuffix(token, "mm"):
-			// Dimensions (possibly A*B)
-			if strings.Contains(token, "*") {
-				parts := strings.Split(token, "*")
-				if len(parts) == 2 {
-					sizeWMM = parseMMorPercent(parts[0], pageW)
-					sizeHMM = parseMMorPercent(parts[1], pageH)
-				}
-			} else if v, err := strconv.ParseFloat(strings.TrimSuffix(token, "mm"), 64); err == nil {
-				sizeWMM = v
-			}
+		case token == "border":
+			hasBorder = true
 
-		case strings.Contains(token, "*") && (strings.HasSuffix(token, "%")):
-			// Option with percentages (e.g. 80%*10mm)
-			parts := strings.Split(token, "*")
-			if len(parts) == 2 {
-				sizeWMM = parseMMorPercent(parts[0], pageW)
-				sizeHMM = parseMMorPercent(parts[1], pageH)
+		case strings.EqualFold(token, "text"), strings.EqualFold(token, "caption"):
+			caption = true
+
+		case strings.HasSuffix(token, "pt"):
+			// caption font size
+			if v, err := strconv.ParseFloat(strings.TrimSuffix(token, "pt"), 64); err == nil && v > 0 {
+				caption = true
+				captionPt = v
 			}
 
-		case token == "border":
-			hasBorder = true
+		case strings.HasPrefix(token, "quiet="), strings.HasPrefix(token, "qz="):
+			// quiet zone: in modules (narrowest bars) or in millimeters
+			_, v, _ := strings.Cut(token, "=")
+			if strings.HasSuffix(v, "mm") {
+				if l, err := geometry.ParseLength(v, 0); err == nil && l >= 0 {
+					quietZone, quietModules = l, 0
+				}
+			} else if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 {
+				quietModules, quietZone = f, 0
+			}
 
 		case token != "":
 			codeType = strings.ToLower(token)
@@ -133,33 +134,63 @@ func (d *Docx) Barcode(value string, opts ...string) modifiers.RawXML {
 	}
 
 	// ---------- Generating an image ----------
-	var img barcode.Barcode
-	var err error
-	switch codeType {
-	case "ean13":
-		img, err = ean.Encode(value)
-	default:
-		img, err = code128.Encode(value)
+	if alias, ok := barcodeAliases[codeType]; ok {
+		codeType = alias
+	}
+	sym, ok := barcodeSymbologies[codeType]
+	if !ok {
+		sym = barcodeSymbologies["code128"]
 	}
+	img, err := sym.encode(value)
 	if err != nil {
 		return modifiers.RawXML(fmt.Sprintf("<w:p><w:t>barcode error: %v</w:t></w:p>", err))
 	}
 
-	// ---------- scalable ----------
-	if sizeHMM <= 0 {
-		sizeHMM = sizeWMM / 3
-		img, _ = barcode.Scale(img, int(sizeWMM*12), int(sizeHMM*12))
-	} else {
-		// if it is set explicitly, leave the original barcode,
-		// to maintain clarity and not break the aspect ratio
-		img, _ = barcode.Scale(img, img.Bounds().Dx(), img.Bounds().Dy())
+	// ---------- Rendering: quiet zone and caption inside the image ----------
+	b := img.Bounds()
+	if sizeW <= 0 {
+		switch {
+		case !sym.twoD:
+			sizeW = sizeH * 3
+		case b.Dy() > 0:
+			sizeW = sizeH * geometry.Length(b.Dx()) / geometry.Length(b.Dy())
+		default:
+			sizeW = sizeH
+		}
 	}
-	buf, _ := encodePNG(img)
+	if sizeH <= 0 {
+		switch {
+		case !sym.twoD:
+			sizeH = sizeW / 3
+		case b.Dx() > 0:
+			sizeH = sizeW * geometry.Length(b.Dy()) / geometry.Length(b.Dx())
+		default:
+			sizeH = sizeW
+		}
+	}
+	if quietModules < 0 {
+		quietModules = sym.quiet
+	}
+	canvas, err := renderBarcode(img, barcodeLayout{
+		width:        sizeW.Pixels(rasterDPI),
+		height:       sizeH.Pixels(rasterDPI),
+		quietModules: quietModules,
+		quietPx:      quietZone.Pixels(rasterDPI),
+		caption:      caption,
+		captionPx:    captionPt * rasterDPI / 72,
+		text:         value,
+		twoD:         sym.twoD,
+	})
+	if err != nil {
+		return modifiers.RawXML(fmt.Sprintf("<w:p><w:t>barcode error: %s: %v</w:t></w:p>", codeType, err))
+	}
+	buf, _ := encodePNG(canvas)
 	rId, base := d.AddImageRel(buf)
+	id, name := d.nextDrawing(base)
 
 	// ---------- XML ----------
-	cx := int(sizeWMM * emuPerMM)
-	cy := int(sizeHMM * emuPerMM)
+	cx := sizeW.EMU()
+	cy := sizeH.EMU()
 	cropVal := int(crop * 1000)
 
 	cropXML := ""
@@ -174,11 +205,11 @@ func (d *Docx) Barcode(value string, opts ...string) modifiers.RawXML {
 
 	pic := fmt.Sprintf(`
 <pic:pic xmlns:pic="http://schemas.openxmlformats.org/drawingml/2006/picture">
-  <pic:nvPicPr><pic:cNvPr id="1" name="%s"/><pic:cNvPicPr/></pic:nvPicPr>
+  <pic:nvPicPr><pic:cNvPr id="%d" name="%s"/><pic:cNvPicPr/></pic:nvPicPr>
   <pic:blipFill><a:blip r:embed="%s" cstate="print"/>%s<a:stretch><a:fillRect/></a:stretch></pic:blipFill>
   <pic:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="%d" cy="%d"/></a:xfrm>
   <a:prstGeom prst="rect"><a:avLst/></a:prstGeom><a:noFill/>%s</pic:spPr>
-</pic:pic>`, base, rId, cropXML, cx, cy, borderXML)
+</pic:pic>`, id, name, rId, cropXML, cx, cy, borderXML)
 
 	var xml string
 	if mode == "inline" {
@@ -186,66 +217,39 @@ func (d *Docx) Barcode(value string, opts ...string) modifiers.RawXML {
 <w:drawing xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
   <wp:inline distT="0" distB="0" distL="0" distR="0">
     <wp:extent cx="%d" cy="%d"/>
-    <wp:docPr id="1" name="%s"/>
+    <wp:docPr id="%d" name="%s"/>
     <a:graphic xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main">
       <a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/picture">%s</a:graphicData>
     </a:graphic>
   </wp:inline>
-</w:drawing>`, cx, cy, base, pic)
+</w:drawing>`, cx, cy, id, name, pic)
 	} else {
 		xml = fmt.Sprintf(`
 <w:drawing>
-  <wp:anchor behindDoc="0" distT="%d" distB="%d" distL="%d" distR="%d"
-    simplePos="0" locked="0" layoutInCell="0" allowOverlap="1" relativeHeight="2">
+  <wp:anchor behindDoc="%d" distT="%d" distB="%d" distL="%d" distR="%d"
+    simplePos="0" locked="0" layoutInCell="0" allowOverlap="1" relativeHeight="%d">
     <wp:simplePos x="0" y="0"/>
     <wp:positionH relativeFrom="column"><wp:align>%s</wp:align></wp:positionH>
     <wp:positionV relativeFrom="paragraph"><wp:align>%s</wp:align></wp:positionV>
     <wp:extent cx="%d" cy="%d"/>
-    <wp:wrapSquare wrapText="bothSides"/>
-    <wp:docPr id="1" name="%s"/>
+    %s
+    <wp:docPr id="%d" name="%s"/>
     <a:graphic xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main">
       <a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/picture">%s</a:graphicData>
     </a:graphic>
   </wp:anchor>
-</w:drawing>`, distT, distB, distL, distR, align, valign, cx, cy, base, pic)
+</w:drawing>`, wrap.behindDoc(), dist.Top.EMU(), dist.Bottom.EMU(), dist.Left.EMU(), dist.Right.EMU(),
+			wrap.z, align, valign, cx, cy, wrap.xml(), id, name, pic)
 	}
 
-	return modifiers.RawXML("</w:t></w:r><w:r>" + xml + "</w:r><w:r><w:t>")
-}
-
-// parseMMorPercent — parses a string like "40mm" or "80%" in millimeters,
-// using page sizes in the EMU to calculate percentages.
-func parseMMorPercent(token string, pageSizeEMU int) float64 {
-	token = strings.TrimSpace(token)
-	switch {
-	case strings.HasSuffix(token, "mm"):
-		v, _ := strconv.ParseFloat(strings.TrimSuffix(token, "mm"), 64)
-		return v
-	case strings.HasSuffix(token, "%"):
-		v, _ := strconv.ParseFloat(strings.TrimSuffix(token, "%"), 64)
-		// EMU → mm (1 mm = 36000 EMU)
-		pageMM := float64(pageSizeEMU) / 36000
-		return pageMM * v / 100
-	default:
-		return 0
-	}
+	return modifiers.RawXML("</w:t></w:r><w:r>" + xml + strings.TrimPrefix(modifiers.RunReopen, "</w:t>"))
 }
 
-// GetPageSizeEMU — gets page sizes from document.xml in EMU.
+// GetPageSizeEMU — gets the page size of the current section in EMU (A4 if the document has no w:pgSz).
+// For sizes relative to the text area use GetUsableSizeEMU.
 func (d *Docx) GetPageSizeEMU() (width, height int) {
-	data, ok := d.files["word/document.xml"]
-	if !ok {
-		// A4 Default: 210×297mm
-		return 210 * 36000, 297 * 36000
-	}
-	str := string(data)
-	w := extractAttrInt(str, `w:pgSz`, `w:w`)
-	h := extractAttrInt(str, `w:pgSz`, `w:h`)
-	if w == 0 || h == 0 {
-		return 210 * 36000, 297 * 36000
-	}
-	// Values in twips (1/20 pt), 1 pt = 12700 EMU, 1 twip = 635 EMU
-	return w * 635, h * 635
+	l := d.CurrentPageLayout()
+	return l.Width.EMU(), l.Height.EMU()
 }
 
 func extractAttrInt(xml, tag, attr string) int {
@@ -272,3 +276,151 @@ func encodePNG(img image.Image) ([]byte, error) {
 	}
 	return buf.Bytes(), nil
 }
+
+// barcodeSymbology - a barcode type of the barcode modifier.
+//   - encode — builds the modules of the value;
+//   - quiet — the quiet zone on each side by the standard, in modules;
+//   - twoD — a matrix code (square modules, rows as well as columns).
+type barcodeSymbology struct {
+	encode func(value string) (barcode.Barcode, error)
+	quiet  float64
+	twoD   bool
+}
+
+// barcodeSymbologies - the types of {value|barcode:`type`}.
+var barcodeSymbologies = map[string]barcodeSymbology{
+	// Code128: at least 10 modules on each side
+	"code128": {encode: func(v string) (barcode.Barcode, error) { return code128.Encode(v) }, quiet: 10},
+	// EAN-13: 11 modules (7 on the right, the wider one is used for both); EAN-8 by the length of the value
+	"ean13": {encode: func(v string) (barcode.Barcode, error) { return ean.Encode(v) }, quiet: 11},
+	"ean8":  {encode: func(v string) (barcode.Barcode, error) { return ean.Encode(v) }, quiet: 7},
+	// Code 39: 10 narrow bars; lower case letters and symbols go through the full ASCII mode
+	"code39": {encode: func(v string) (barcode.Barcode, error) { return code39.Encode(v, false, v != strings.ToUpper(v)) }, quiet: 10},
+	// ITF-14 (GTIN-14 of shipping cartons): 13 digits get the check digit, 14 are taken as they are
+	"itf14": {encode: encodeITF14, quiet: 10},
+	// DataMatrix and Aztec are square; DataMatrix needs 1 module around, Aztec none
+	"datamatrix": {encode: datamatrix.Encode, quiet: 1, twoD: true},
+	"aztec": {encode: func(v string) (barcode.Barcode, error) {
+		return aztec.Encode([]byte(v), aztec.DEFAULT_EC_PERCENT, aztec.DEFAULT_LAYERS)
+	}, quiet: 0, twoD: true},
+	// PDF417: 2 modules, error correction level 2 (the common default)
+	"pdf417": {encode: func(v string) (barcode.Barcode, error) { return pdf417.Encode(v, 2) }, quiet: 2, twoD: true},
+}
+
+// barcodeAliases - other names of the barcode types.
+var barcodeAliases = map[string]string{"ean": "ean13", "itf": "itf14", "dm": "datamatrix", "data_matrix": "datamatrix"}
+
+// encodeITF14 encodes a GTIN-14 as Interleaved 2 of 5.
+func encodeITF14(v string) (barcode.Barcode, error) {
+	v = strings.TrimSpace(v)
+	switch len(v) {
+	case 13:
+		var err error
+		if v, err = twooffive.AddCheckSum(v); err != nil {
+			return nil, err
+		}
+	case 14:
+	default:
+		return nil, fmt.Errorf("itf14: want 13 or 14 digits, got %d", len(v))
+	}
+	return twooffive.Encode(v, true)
+}
+
+// barcodeLayout - the geometry of the barcode image in pixels.
+type barcodeLayout struct {
+	width, height int
+	quietModules  float64 // quiet zone in modules; used if quietPx == 0
+	quietPx       int     // quiet zone in pixels
+	caption       bool    // print the human-readable value under the bars
+	twoD          bool    // a matrix code: the modules are square, the rows are scaled as the columns
+	captionPx     float64 // caption font size in pixels
+	text          string
+}
+
+// renderBarcode places the bars on a white canvas of the given size: each module is an integer number of pixels
+// (the bars stay sharp), the quiet zone is kept on both sides, the caption is printed under the bars.
+// A canvas that has less than a pixel for a module is an error: the bars would run past its edge.
+func renderBarcode(bc barcode.Barcode, l barcodeLayout) (image.Image, error) {
+	canvas := image.NewRGBA(image.Rect(0, 0, l.width, l.height))
+	draw.Draw(canvas, canvas.Bounds(), image.White, image.Point{}, draw.Src)
+
+	modules, rows := bc.Bounds().Dx(), bc.Bounds().Dy()
+	if modules == 0 || rows == 0 {
+		return canvas, nil
+	}
+
+	barsH := l.height
+	var face font.Face
+	if l.caption {
+		face = captionFace(l.captionPx)
+		barsH = l.height - int(l.captionPx*1.25)
+		if barsH < l.height/3 {
+			barsH = l.height / 3
+		}
+	}
+
+	// module width in pixels, taking the quiet zone into account
+	module := l.moduleFits(l.width, modules)
+	if module < 1 {
+		return nil, fmt.Errorf("%d modules need a width of at least %.1fmm", modules, l.minSize(modules))
+	}
+	y0 := 0
+	if l.twoD {
+		// the rows get the same module, and the symbol with its quiet zone fits the height too
+		byHeight := l.moduleFits(barsH, rows)
+		if byHeight < 1 {
+			return nil, fmt.Errorf("%d rows need a height of at least %.1fmm", rows, l.minSize(rows)+float64(l.height-barsH)*25.4/rasterDPI)
+		}
+		module = min(module, byHeight)
+		y0 = (barsH - rows*module) / 2
+		barsH = rows * module
+	}
+
+	bars, err := barcode.Scale(bc, modules*module, barsH)
+	if err != nil {
+		return canvas, nil
+	}
+	x0 := (l.width - modules*module) / 2
+	draw.Draw(canvas, image.Rect(x0, y0, x0+modules*module, y0+barsH), bars, image.Point{}, draw.Src)
+	barsH += y0
+
+	if face != nil {
+		dr := &font.Drawer{Dst: canvas, Src: image.Black, Face: face}
+		textW := dr.MeasureString(l.text).Round()
+		ascent := face.Metrics().Ascent.Round()
+		dr.Dot = fixed.P((l.width-textW)/2, barsH+ascent+int(l.captionPx*0.1))
+		dr.DrawString(l.text)
+	}
+	return canvas, nil
+}
+
+// moduleFits - the whole pixels of a module when n modules and the quiet zone on both sides share
+// px pixels; less than 1 when they do not fit.
+func (l barcodeLayout) moduleFits(px, n int) int {
+	if l.quietPx > 0 {
+		return (px - 2*l.quietPx) / n
+	}
+	return int(float64(px) / (float64(n) + 2*l.quietModules))
+}
+
+// minSize - the millimeters n modules of a pixel and the quiet zone on both sides take.
+func (l barcodeLayout) minSize(n int) float64 {
+	px := float64(n) + 2*l.quietModules
+	if l.quietPx > 0 {
+		px = float64(n + 2*l.quietPx)
+	}
+	return math.Ceil(px*25.4/rasterDPI*10) / 10
+}
+
+// captionFace - a monospaced face (Go Mono) for the human-readable barcode caption.
+func captionFace(sizePx float64) font.Face {
+	f, err := opentype.Parse(gomono.TTF)
+	if err != nil {
+		return nil
+	}
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: sizePx, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil
+	}
+	return face
+}
@@ -0,0 +1,322 @@
+package docxgen
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// ============================================================================
+// [for items] ... [/for], [block/items] ... [/block], [repeat items] ... [/repeat], [list items] ... [/list],
+// [if key] ... [/if] and [if-channel/print] ... [/if-channel] paragraph blocks
+// ============================================================================
+
+// reBlockMarker - a paragraph that holds only a block marker: [for items], [block/items], [repeat items],
+// [list items], [list items numbered], [if key], [if !key], [if-channel print,pdf], [/for], [/block],
+// [/repeat], [/list], [/if], [/if-channel]; "[for/items]" is accepted too, like [table/name].
+var reBlockMarker = regexp.MustCompile(`^\[(/?)(for|block|repeat|list|if-channel|if)(?:[ /]+(!?)[ \t]*([A-Za-z0-9_.,]+))?(?:[ /]+(bullet|numbered))?[ \t]*]$`)
+
+// blockMarker - a marker paragraph found in the body.
+//   - start, end — the bounds of the whole paragraph;
+//   - kind — "for", "block", "repeat", "list", "if" or "if-channel", closing — [/for], [/block], ...;
+//   - key, negate — the data key (the channels for if-channel) of the opening marker and its "!";
+//   - style — "bullet" or "numbered" of [list items numbered], empty for bullets by default.
+type blockMarker struct {
+	start, end int
+	kind       string
+	closing    bool
+	key        string
+	negate     bool
+	style      string
+}
+
+// ResolveBlocks — expands the paragraph blocks of the body:
+//
+//	[for items]
+//	  paragraphs with {name}, {price|money}, %[1]s
+//	[/for]
+//
+// repeats the paragraphs between the markers for every element of data[items]: the fields of a map
+// element are substituted like in a smart table row (a field that only other elements have becomes
+// empty, the other tags stay for ExecuteTemplate), a list or a scalar element fills %[N]s.
+// Blocks and smart tables inside see the fields of the element on top of data.
+//
+//	[block/parties] ... [/block]   — the same as [for], written like [table/name]: the clauses, the
+//	                                 parties of a contract repeated with their own fields
+//
+//	[repeat employees] ... [/repeat]   — the same for a whole region, a page (or several) per element
+//
+// The copies of [repeat] are separated by page breaks, unless the region has its own page break or
+// section break; the drawings of the copies get new ids.
+//
+//	[list items] ... [/list]   — the same as [for], the paragraphs of the copies become the items of a
+//	                             bulleted list ([list items numbered] — numbered), see listBlock
+//
+//	[if key] ... [/if]   — the paragraphs stay when data[key] is filled, [if !key] — when it is empty
+//
+// Empty is a missing key, nil, false, "", 0 and an empty list or map; a dotted key looks into maps.
+//
+//	[if-channel/print] ... [/if-channel]   — the paragraphs stay when the document is rendered for
+//	                                         the channel (see SetChannel); [if-channel/!print] — for
+//	                                         any other, [if-channel/email,sms] — for either of them
+//
+// Every marker must be the only text of its paragraph; the marker paragraphs are removed.
+func (d *Docx) ResolveBlocks(body string, data map[string]any) string {
+	markers := findBlockMarkers(body)
+	if len(markers) == 0 {
+		return body
+	}
+
+	var out strings.Builder
+	pos := 0
+	for i := 0; i < len(markers); i++ {
+		open := markers[i]
+		if open.closing {
+			d.warn(WarnBrokenBlock, open.String(), "no opening marker, removed")
+			out.WriteString(body[pos:open.start])
+			pos = open.end
+			continue
+		}
+		j := matchingMarker(markers, i)
+		if j < 0 {
+			d.warn(WarnBrokenBlock, open.String(), "the block is not closed, the marker is removed")
+			out.WriteString(body[pos:open.start])
+			pos = open.end
+			continue
+		}
+		closeM := markers[j]
+		inner := body[open.end:closeM.start]
+
+		out.WriteString(body[pos:open.start])
+		switch open.kind {
+		case "if":
+			if filledValue(blockValue(data, open.key)) != open.negate {
+				out.WriteString(d.ResolveBlocks(inner, data))
+			}
+		case "if-channel":
+			if d.inChannel(open.key) != open.negate {
+				out.WriteString(d.ResolveBlocks(inner, data))
+			}
+		case "list":
+			out.WriteString(d.listBlock(open.key, open.style, inner, data))
+		default:
+			out.WriteString(d.repeatBlock(open.kind, open.key, inner, data))
+		}
+		pos = closeM.end
+		i = j
+	}
+	out.WriteString(body[pos:])
+	return out.String()
+}
+
+// repeatBlock renders the inner paragraphs of [for key] or [repeat key] once per element of data[key].
+func (d *Docx) repeatBlock(kind, key, inner string, data map[string]any) string {
+	raw := blockValue(data, key)
+	if raw == nil {
+		d.warn(WarnEmptyLoop, key, "no data for [%s %s], the block is removed", kind, key)
+		return ""
+	}
+	items, ok := normalizeItems(raw)
+	if !ok {
+		if items, ok = listItems(raw); !ok {
+			d.warn(WarnEmptyLoop, key, "the data is %T, not a list, the block is removed", raw)
+			return ""
+		}
+	}
+	if !d.takeRows(blockMarker{kind: kind, key: key}.String(), len(items)) {
+		return ""
+	}
+	pageBreak := kind == "repeat" && !rePageBreak.MatchString(inner) &&
+		!rePageBreakBefore.MatchString(inner) && !strings.Contains(inner, "<w:sectPr")
+
+	// fields that only some elements have are emptied in the others (L2 of the smart tables)
+	union := map[string]struct{}{}
+	for _, it := range items {
+		if m, ok := it.(map[string]any); ok {
+			for k := range m {
+				union[k] = struct{}{}
+			}
+		}
+	}
+
+	var out strings.Builder
+	for n, it := range items {
+		item, isMap := it.(map[string]any)
+		scope := data
+		if isMap {
+			scope = maps.Clone(data)
+			if scope == nil {
+				scope = map[string]any{}
+			}
+			maps.Copy(scope, item)
+		}
+		chunk := d.ResolveBlocks(inner, scope)
+		chunk = d.ResolveTables(chunk, scope)
+		chunk = d.ResolvePivots(chunk, scope)
+		chunk = d.ResolveCalendars(chunk, scope)
+		chunk = d.ResolveGantts(chunk, scope)
+		chunk = d.ResolveOrgCharts(chunk, scope)
+		chunk = d.ResolveSigners(chunk, scope)
+		chunk = d.ResolveRequisites(chunk, scope)
+		switch {
+		case isMap:
+			chunk = renderNamedWithUnion(chunk, parseTplMeta(chunk), item, union)
+		default:
+			if ni := normalizeItem(it); ni.kind == "slice" {
+				chunk = renderPositional(chunk, ni.sliceVal)
+			} else {
+				chunk = renderPositional(chunk, []any{it})
+			}
+		}
+		if kind == "repeat" && n > 0 {
+			if pageBreak {
+				out.WriteString(pageBreakParagraph)
+			}
+			chunk = d.renumberDrawings(chunk)
+		}
+		out.WriteString(chunk)
+	}
+	return out.String()
+}
+
+// inChannel - whether the document is rendered for one of the channels of the list "print,pdf".
+func (d *Docx) inChannel(list string) bool {
+	for _, c := range strings.Split(list, ",") {
+		if c != "" && strings.EqualFold(c, d.channel) {
+			return true
+		}
+	}
+	return false
+}
+
+// findBlockMarkers lists the marker paragraphs of the body in order.
+func findBlockMarkers(body string) []blockMarker {
+	var markers []blockMarker
+	pos := 0
+	for {
+		start := paragraphStart(body, pos)
+		if start < 0 {
+			return markers
+		}
+		end := strings.Index(body[start:], "</w:p>")
+		if end < 0 {
+			return markers
+		}
+		end += start + len("</w:p>")
+		pos = end
+
+		text := strings.TrimSpace(extractParagraphText(body[start:end]))
+		if !strings.HasPrefix(text, "[") {
+			continue
+		}
+		m := reBlockMarker.FindStringSubmatch(text)
+		if m == nil || (m[1] == "" && m[4] == "") || (m[1] != "" && m[4] != "") || (m[5] != "" && (m[2] != "list" || m[1] != "")) {
+			continue
+		}
+		markers = append(markers, blockMarker{
+			start: start, end: end,
+			kind: m[2], closing: m[1] != "",
+			key: m[4], negate: m[3] != "",
+			style: m[5],
+		})
+	}
+}
+
+// paragraphStart - the next <w:p> or <w:p ...> (not <w:pPr>) from pos, -1 if there is none.
+func paragraphStart(body string, pos int) int {
+	for {
+		i := strings.Index(body[pos:], "<w:p")
+		if i < 0 {
+			return -1
+		}
+		i += pos
+		if next := i + len("<w:p"); next < len(body) && (body[next] == '>' || body[next] == ' ') {
+			return i
+		}
+		pos = i + len("<w:p")
+	}
+}
+
+// matchingMarker returns the index of the marker that closes markers[i], -1 if the block is not closed.
+func matchingMarker(markers []blockMarker, i int) int {
+	var stack []string
+	for j := i; j < len(markers); j++ {
+		m := markers[j]
+		if !m.closing {
+			stack = append(stack, m.kind)
+			continue
+		}
+		if stack[len(stack)-1] != m.kind {
+			return -1
+		}
+		stack = stack[:len(stack)-1]
+		if len(stack) == 0 {
+			return j
+		}
+	}
+	return -1
+}
+
+// blockValue - the value of a block key, "client.vip" looks into the maps; nil when there is none.
+func blockValue(data map[string]any, key string) any {
+	var cur any = data
+	for _, part := range strings.Split(key, ".") {
+		switch m := cur.(type) {
+		case map[string]any:
+			cur = m[part]
+		case map[string]string:
+			cur = m[part]
+		default:
+			return nil
+		}
+	}
+	return cur
+}
+
+// filledValue - whether the value counts as filled for [if]: not nil, false, "", 0 or an empty list.
+func filledValue(v any) bool {
+	if v == nil {
+		return false
+	}
+	if s, ok := v.(string); ok {
+		return strings.TrimSpace(s) != ""
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return rv.Len() > 0
+	case reflect.Pointer, reflect.Interface:
+		return !rv.IsNil()
+	}
+	return !rv.IsZero()
+}
+
+// listItems - any other slice ([]string, []int, ...) as the elements of [for].
+func listItems(v any) ([]any, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	out := make([]any, rv.Len())
+	for i := range out {
+		out[i] = rv.Index(i).Interface()
+	}
+	return out, true
+}
+
+// String - the marker as the author writes it: "[for items]", "[/if]".
+func (m blockMarker) String() string {
+	if m.closing {
+		return fmt.Sprintf("[/%s]", m.kind)
+	}
+	neg := ""
+	if m.negate {
+		neg = "!"
+	}
+	if m.style != "" {
+		return fmt.Sprintf("[%s %s%s %s]", m.kind, neg, m.key, m.style)
+	}
+	return fmt.Sprintf("[%s %s%s]", m.kind, neg, m.key)
+}
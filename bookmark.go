@@ -0,0 +1,137 @@
+package docxgen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ============================================================================
+// Bookmarks (w:bookmarkStart … w:bookmarkEnd) as the places to put content at
+// ============================================================================
+
+// BookmarkPosition - where InsertAtBookmark puts the content.
+type BookmarkPosition int
+
+const (
+	// BookmarkBefore - before the start of the bookmark.
+	BookmarkBefore BookmarkPosition = iota
+	// BookmarkAfter - after the end of the bookmark.
+	BookmarkAfter
+)
+
+var reBookmarkStart = regexp.MustCompile(`<w:bookmarkStart\b[^>]*>`)
+
+// bookmarkSpan - a bookmark found in a part.
+//   - part — "document", "header1", ...;
+//   - start — the bounds of <w:bookmarkStart>, end — of the matching <w:bookmarkEnd>.
+type bookmarkSpan struct {
+	part       string
+	start, end [2]int
+}
+
+// Bookmarks returns the names of the bookmarks of the document, headers and footers in order,
+// without the hidden ones of Word (_GoBack, _Toc…).
+func (d *Docx) Bookmarks() []string {
+	var names []string
+	for _, part := range append([]string{"document"}, d.ListHeaderFooterParts()...) {
+		for _, tag := range reBookmarkStart.FindAllString(string(d.files["word/"+part+".xml"]), -1) {
+			if name := xmlAttr(tag, "w:name"); name != "" && !strings.HasPrefix(name, "_") {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// ReplaceBookmark replaces the content of the bookmark with xml and keeps the bookmark around it.
+// Runs (<w:r>...) replace the content between the bookmark marks inside the text; paragraphs or
+// tables replace the whole paragraphs the bookmark starts and ends in. The bookmark is looked up in
+// the document, then in the headers and footers; an unknown name is an error.
+func (d *Docx) ReplaceBookmark(name, xml string) error {
+	b, content, err := d.findBookmark(name)
+	if err != nil {
+		return err
+	}
+	if isBlockXML(xml) {
+		startTag, endTag := content[b.start[0]:b.start[1]], content[b.end[0]:b.end[1]]
+		from, to := b.start[0], b.end[1]
+		if p, _, ok := enclosingParagraph(content, b.start[0]); ok {
+			from = p
+		}
+		if _, e, ok := enclosingParagraph(content, b.end[0]); ok {
+			to = e
+		}
+		d.UpdateContentPart(b.part, content[:from]+startTag+xml+endTag+content[to:])
+		return nil
+	}
+	d.UpdateContentPart(b.part, content[:b.start[1]]+xml+content[b.end[0]:])
+	return nil
+}
+
+// InsertAtBookmark puts xml before the start or after the end of the bookmark, the bookmark keeps
+// its content. Runs go right next to the bookmark mark; paragraphs or tables go before the paragraph
+// the bookmark starts in (after the one it ends in). An unknown name is an error.
+func (d *Docx) InsertAtBookmark(name, xml string, pos BookmarkPosition) error {
+	b, content, err := d.findBookmark(name)
+	if err != nil {
+		return err
+	}
+	at := b.start[0]
+	if pos == BookmarkAfter {
+		at = b.end[1]
+	}
+	if isBlockXML(xml) {
+		if p, e, ok := enclosingParagraph(content, at); ok {
+			at = p
+			if pos == BookmarkAfter {
+				at = e
+			}
+		}
+	}
+	d.UpdateContentPart(b.part, content[:at]+xml+content[at:])
+	return nil
+}
+
+// findBookmark looks the bookmark up in the document, then in the headers and footers.
+func (d *Docx) findBookmark(name string) (bookmarkSpan, string, error) {
+	for _, part := range append([]string{"document"}, d.ListHeaderFooterParts()...) {
+		content := string(d.files["word/"+part+".xml"])
+		for _, loc := range reBookmarkStart.FindAllStringIndex(content, -1) {
+			tag := content[loc[0]:loc[1]]
+			if xmlAttr(tag, "w:name") != name {
+				continue
+			}
+			endTag := pattern(`<w:bookmarkEnd\b[^>]*\bw:id="` + regexp.QuoteMeta(xmlAttr(tag, "w:id")) + `"[^>]*>`)
+			end := endTag.FindStringIndex(content[loc[1]:])
+			if end == nil {
+				return bookmarkSpan{}, "", fmt.Errorf("bookmark %q has no end", name)
+			}
+			return bookmarkSpan{
+				part:  part,
+				start: [2]int{loc[0], loc[1]},
+				end:   [2]int{loc[1] + end[0], loc[1] + end[1]},
+			}, content, nil
+		}
+	}
+	return bookmarkSpan{}, "", fmt.Errorf("bookmark %q not found", name)
+}
+
+// isBlockXML - whether the fragment is made of paragraphs or tables rather than runs.
+func isBlockXML(xml string) bool {
+	xml = strings.TrimSpace(xml)
+	return paragraphStart(xml, 0) == 0 || strings.HasPrefix(xml, "<w:tbl>") || strings.HasPrefix(xml, "<w:tbl ")
+}
+
+// enclosingParagraph - the bounds of the paragraph that s[i] is inside of.
+func enclosingParagraph(s string, i int) (start, end int, ok bool) {
+	start = max(strings.LastIndex(s[:i], "<w:p>"), strings.LastIndex(s[:i], "<w:p "))
+	if start < 0 || strings.LastIndex(s[:i], "</w:p>") > start {
+		return 0, 0, false
+	}
+	end = strings.Index(s[i:], "</w:p>")
+	if end < 0 {
+		return 0, 0, false
+	}
+	return start, i + end + len("</w:p>"), true
+}
@@ -0,0 +1,199 @@
+package docxgen
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	stylesPath    = "word/styles.xml"
+	relTypeStyles = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles"
+	relTypeTheme  = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme"
+)
+
+// StyleMode - how the styles of a brand pack are combined with the styles of the template.
+type StyleMode int
+
+const (
+	// StyleMerge - the styles of the pack replace the template styles with the same type and w:styleId,
+	// the other styles of the pack are added, the rest of the template styles stay;
+	// the pack's w:docDefaults (fonts and spacing of the whole document) replace the template ones.
+	StyleMerge StyleMode = iota
+	// StyleReplace - styles.xml of the pack replaces the template one entirely.
+	StyleReplace
+)
+
+// BrandPack - a corporate style override applied at render time, so that one neutral template
+// can be branded per subsidiary.
+//   - Styles — the content of styles.xml (empty — the styles are not changed);
+//   - Theme — the content of theme1.xml: colors and theme fonts (empty — the theme is not changed);
+//   - Mode — how Styles is combined with the template.
+type BrandPack struct {
+	Styles []byte
+	Theme  []byte
+	Mode   StyleMode
+}
+
+// LoadBrandPack reads a brand pack from a DOCX/DOTX (its styles and theme are taken)
+// or from a directory with styles.xml and/or theme1.xml.
+func LoadBrandPack(path string, mode StyleMode) (BrandPack, error) {
+	pack := BrandPack{Mode: mode}
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		pack.Styles, _ = os.ReadFile(filepath.Join(path, "styles.xml"))
+		pack.Theme, _ = os.ReadFile(filepath.Join(path, "theme1.xml"))
+		if pack.Styles == nil && pack.Theme == nil {
+			return pack, fmt.Errorf("brand pack %s: neither styles.xml nor theme1.xml found", path)
+		}
+		return pack, nil
+	}
+
+	src, err := Open(path)
+	if err != nil {
+		return pack, fmt.Errorf("brand pack: %w", err)
+	}
+	if pack, err = NewBrandPack(src, mode); err != nil {
+		return pack, fmt.Errorf("brand pack %s: %w", path, err)
+	}
+	return pack, nil
+}
+
+// NewBrandPack takes the styles and theme of an opened DOCX/DOTX (for example, from OpenBytes).
+func NewBrandPack(src *Docx, mode StyleMode) (BrandPack, error) {
+	pack := BrandPack{Mode: mode, Styles: src.files[stylesPath]}
+	if theme, ok := src.themePath(); ok {
+		pack.Theme = src.files[theme]
+	}
+	if pack.Styles == nil && pack.Theme == nil {
+		return pack, errors.New("the document has no styles and no theme")
+	}
+	return pack, nil
+}
+
+// ApplyBrand applies the brand pack to the document: the theme is replaced, the styles are
+// merged or replaced according to pack.Mode. Call it before saving; the content of the
+// document keeps its style references, so only the look changes.
+func (d *Docx) ApplyBrand(pack BrandPack) error {
+	if len(pack.Styles) > 0 {
+		if pack.Mode == StyleReplace || !strings.Contains(string(d.files[stylesPath]), "</w:styles>") {
+			d.setPart(stylesPath, pack.Styles, relTypeStyles, wmlType+"styles+xml")
+		} else {
+			merged, err := mergeStyles(string(d.files[stylesPath]), string(pack.Styles))
+			if err != nil {
+				return err
+			}
+			d.files[stylesPath] = []byte(merged)
+		}
+	}
+	if len(pack.Theme) > 0 {
+		theme, ok := d.themePath()
+		if !ok {
+			theme = "word/theme/theme1.xml"
+		}
+		d.setPart(theme, pack.Theme, relTypeTheme, "application/vnd.openxmlformats-officedocument.theme+xml")
+	}
+	return nil
+}
+
+// themePath returns the theme part of the document by the relationship of document.xml.
+func (d *Docx) themePath() (string, bool) {
+	for _, r := range d.Rels().List("document") {
+		if r.Type == relTypeTheme {
+			p := d.Rels().ResolveTarget("document", r)
+			_, ok := d.files[p]
+			return p, ok
+		}
+	}
+	return "", false
+}
+
+// setPart writes the part, adding the relationship from document.xml and the content type if they are missing.
+func (d *Docx) setPart(name string, data []byte, relType, contentType string) {
+	if _, ok := d.files[name]; !ok {
+		d.Rels().Add("document", Relationship{Type: relType, Target: strings.TrimPrefix(name, "word/")})
+	}
+	d.files[name] = data
+	if _, ok := d.ContentTypes().Override(name); !ok {
+		d.ContentTypes().SetOverride(name, contentType)
+	}
+}
+
+var (
+	reStylesRoot  = regexp.MustCompile(`<w:styles\b[^>]*>`)
+	reStyle       = regexp.MustCompile(`(?s)<w:style\b[^>]*?/>|<w:style\b[^>]*>.*?</w:style>`)
+	reDocDefaults = regexp.MustCompile(`(?s)<w:docDefaults\b.*?</w:docDefaults>`)
+	reXMLNS       = regexp.MustCompile(`\sxmlns:(\w+)="[^"]*"`)
+)
+
+// withNamespaces adds to the opening tag of a root element the xmlns:* declarations of from that it lacks.
+func withNamespaces(open, from string) string {
+	var extra strings.Builder
+	for _, ns := range reXMLNS.FindAllStringSubmatch(from, -1) {
+		if !strings.Contains(open, " xmlns:"+ns[1]+"=") {
+			extra.WriteString(ns[0])
+		}
+	}
+	if extra.Len() == 0 {
+		return open
+	}
+	i := strings.IndexAny(open, " \t\r\n/>")
+	return open[:i] + extra.String() + open[i:]
+}
+
+// mergeStyles merges the styles of the override into the template styles (see StyleMerge).
+func mergeStyles(base, override string) (string, error) {
+	root := reStylesRoot.FindStringIndex(base)
+	oroot := reStylesRoot.FindString(override)
+	if root == nil || oroot == "" {
+		return "", fmt.Errorf("brand pack: styles.xml has no <w:styles> root")
+	}
+
+	// the styles of the pack may use prefixes (w14:, w15:) the template does not declare
+	base = base[:root[0]] + withNamespaces(base[root[0]:root[1]], oroot) + base[root[1]:]
+
+	if docDefaults := reDocDefaults.FindString(override); docDefaults != "" {
+		if reDocDefaults.MatchString(base) {
+			base = reDocDefaults.ReplaceAllLiteralString(base, docDefaults)
+		} else {
+			// docDefaults is the first child of <w:styles>
+			i := reStylesRoot.FindStringIndex(base)[1]
+			base = base[:i] + docDefaults + base[i:]
+		}
+	}
+
+	added := map[string]string{}
+	defaults := map[string]bool{} // the types whose default style comes from the pack
+	var order []string
+	for _, s := range reStyle.FindAllString(override, -1) {
+		k := styleKey(s)
+		if _, ok := added[k]; !ok {
+			order = append(order, k)
+		}
+		added[k] = s
+		if open := s[:strings.Index(s, ">")+1]; xmlAttr(open, "w:default") == "1" {
+			defaults[xmlAttr(open, "w:type")] = true
+		}
+	}
+
+	base = reStyle.ReplaceAllStringFunc(base, func(s string) string {
+		k := styleKey(s)
+		if repl, ok := added[k]; ok {
+			delete(added, k)
+			return repl
+		}
+		// only one default style of a type
+		if open := s[:strings.Index(s, ">")+1]; defaults[xmlAttr(open, "w:type")] {
+			return strings.Replace(open, ` w:default="1"`, "", 1) + s[len(open):]
+		}
+		return s
+	})
+	var tail strings.Builder
+	for _, k := range order {
+		tail.WriteString(added[k])
+	}
+	i := strings.LastIndex(base, "</w:styles>")
+	return base[:i] + tail.String() + base[i:], nil
+}
@@ -0,0 +1,187 @@
+package docxgen
+
+import (
+	"docxgen/modifiers"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// [calendar/month 2025-11 events:meetings] — a month grid with the events in the day cells
+// ============================================================================
+
+// calendarOpenPrefix - the start of the calendar marker.
+const calendarOpenPrefix = "[calendar/"
+
+// monthsNominative - the month names of the calendar title ("Ноябрь 2025").
+var monthsNominative = [...]string{
+	"Январь", "Февраль", "Март", "Апрель", "Май", "Июнь",
+	"Июль", "Август", "Сентябрь", "Октябрь", "Ноябрь", "Декабрь",
+}
+
+// weekdayHeaders - the header of the grid, the week starts on Monday.
+var weekdayHeaders = [...]string{"Пн", "Вт", "Ср", "Чт", "Пт", "Сб", "Вс"}
+
+// calendarWeekendFill - the fill of the days off.
+const calendarWeekendFill = "F2F2F2"
+
+// calendarSpec - the options of the [calendar/month ...] marker.
+//   - month — the first day of the month;
+//   - events — the data key of the events, "" — an empty grid;
+//   - dateField, textField — the fields of an event: its date (with an optional time) and its text.
+type calendarSpec struct {
+	month                time.Time
+	events               string
+	dateField, textField string
+}
+
+// parseCalendarSpec reads "month 2025-11 events:meetings date:day text:title". The month is "2025-11",
+// "11.2025", a date of that month, or a data key with one of them.
+func parseCalendarSpec(spec string, data map[string]any) (calendarSpec, error) {
+	c := calendarSpec{dateField: "date", textField: "title"}
+	fields := strings.Fields(spec)
+	if len(fields) < 2 || !strings.EqualFold(fields[0], "month") {
+		return c, fmt.Errorf("want [calendar/month YYYY-MM ...], got %q", spec)
+	}
+	for _, f := range fields[2:] {
+		key, val, _ := strings.Cut(f, ":")
+		switch strings.ToLower(key) {
+		case "events":
+			c.events = val
+		case "date":
+			c.dateField = val
+		case "text":
+			c.textField = val
+		}
+	}
+
+	month, ok := parseMonth(fields[1])
+	if !ok {
+		if month, ok = parseMonth(blockValue(data, fields[1])); !ok {
+			return c, fmt.Errorf("%q is not a month (2025-11) or a key of one", fields[1])
+		}
+	}
+	c.month = month
+	return c, nil
+}
+
+// parseMonth - the first day of the month of "2025-11", "11.2025" or any date value.
+func parseMonth(v any) (time.Time, bool) {
+	if s, ok := v.(string); ok {
+		for _, layout := range []string{"2006-01", "01.2006", "2006/01"} {
+			if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+				return t, true
+			}
+		}
+	}
+	t, ok := modifiers.DateValue(v)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC), true
+}
+
+// ResolveCalendars replaces the paragraphs with [calendar/month 2025-11 events:meetings] by a month grid:
+// a title ("Ноябрь 2025"), a header Пн…Вс and a row per week; every day cell has the number of the day
+// and the events of data[events] that fall on it, ordered by time ("09:30 Планёрка"). The days off
+// (Saturday and Sunday, or the production calendar of SetWorkCalendar) are shaded.
+//
+// An event is a map with the fields date (time.Time, "2025-11-05", "05.11.2025 09:30", ...) and
+// title; date:field and text:field take other ones. Events of other months are left out.
+// A marker without a month it can read is removed with the bad_calendar warning.
+func (d *Docx) ResolveCalendars(body string, data map[string]any) string {
+	return replaceMarkerParagraphs(body, calendarOpenPrefix, func(spec string) string {
+		return d.renderCalendar(spec, data)
+	})
+}
+
+// calendarEvent - an event placed in a day cell.
+type calendarEvent struct {
+	at   time.Time
+	text string
+}
+
+// renderCalendar builds the grid of one marker; "" — nothing to show (a warning is issued).
+func (d *Docx) renderCalendar(specText string, data map[string]any) string {
+	spec, err := parseCalendarSpec(specText, data)
+	if err != nil {
+		d.warn(WarnBadCalendar, specText, "%v", err)
+		return ""
+	}
+
+	// -------- The events of the month by day --------
+	days := time.Date(spec.month.Year(), spec.month.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	byDay := make([][]calendarEvent, days+1)
+	if spec.events != "" {
+		raw := blockValue(data, spec.events)
+		items, ok := normalizeItems(raw)
+		if raw != nil && !ok {
+			d.warn(WarnBadCalendar, spec.events, "the events are %T, not a list", raw)
+		}
+		if !d.takeRows("calendar "+spec.events, len(items)) {
+			return ""
+		}
+		for _, it := range items {
+			ev := normalizeItem(it).mapVal
+			at, ok := modifiers.DateValue(blockValue(ev, spec.dateField))
+			if !ok || at.Year() != spec.month.Year() || at.Month() != spec.month.Month() {
+				continue
+			}
+			byDay[at.Day()] = append(byDay[at.Day()], calendarEvent{at: at, text: modifiers.ValueText(blockValue(ev, spec.textField))})
+		}
+	}
+
+	// -------- The grid: the header and a row per week, from Monday --------
+	rows := [][]string{make([]string, 7)}
+	for i, h := range weekdayHeaders {
+		rows[0][i] = cellText(h, true, false)
+	}
+	offset := (int(spec.month.Weekday()) + 6) % 7 // Monday is 0
+	weekend := map[[2]int]bool{}
+	for day := 1; day <= days; day++ {
+		cell := offset + day - 1
+		r, c := cell/7+1, cell%7
+		if r == len(rows) {
+			rows = append(rows, make([]string, 7))
+		}
+		date := time.Date(spec.month.Year(), spec.month.Month(), day, 0, 0, 0, 0, time.UTC)
+		weekend[[2]int{r, c}] = !d.isWorkday(date)
+		rows[r][c] = calendarDayCell(day, byDay[day])
+	}
+
+	title := `<w:p><w:pPr><w:jc w:val="center"/></w:pPr><w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">` +
+		fmt.Sprintf("%s %d", monthsNominative[spec.month.Month()-1], spec.month.Year()) + `</w:t></w:r></w:p>`
+	grid := shadedGridTable(rows, d.CurrentPageLayout().UsableWidth().Twips(), func(r, c int) string {
+		if weekend[[2]int{r, c}] {
+			return calendarWeekendFill
+		}
+		return ""
+	})
+	return title + grid
+}
+
+// isWorkday - by the production calendar of the document, or Monday to Friday without one.
+func (d *Docx) isWorkday(t time.Time) bool {
+	if d.calendar != nil {
+		return d.calendar.IsWorkday(t)
+	}
+	return t.Weekday() != time.Saturday && t.Weekday() != time.Sunday
+}
+
+// calendarDayCell - the number of the day and a paragraph per event, the timed ones in time order.
+func calendarDayCell(day int, events []calendarEvent) string {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].at.Before(events[j].at) })
+
+	var b strings.Builder
+	b.WriteString(cellText(fmt.Sprint(day), true, false))
+	for _, ev := range events {
+		text := ev.text
+		if ev.at.Hour() != 0 || ev.at.Minute() != 0 {
+			text = ev.at.Format("15:04") + " " + text
+		}
+		b.WriteString(cellText(text, false, false))
+	}
+	return b.String()
+}
@@ -0,0 +1,68 @@
+// docxgen from Node through the shared library (koffi: npm install koffi).
+//
+//   go build -buildmode=c-shared -o libdocxgen.so ./capi
+//   node docxgen.js template.docx data.json out.docx
+//
+// As a module:
+//
+//   const { Docxgen } = require('./docxgen');
+//   const lib = new Docxgen('./libdocxgen.so');
+//   const docx = lib.render(fs.readFileSync('template.docx'), { number: 7 });
+
+'use strict';
+
+const fs = require('fs');
+const koffi = require('koffi');
+
+class DocxgenError extends Error {}
+
+class Docxgen {
+  constructor(path = './libdocxgen.so') {
+    const lib = koffi.load(path);
+    const result = '_Out_ void **out, _Out_ int *outLen, _Out_ void **err';
+    this._render = lib.func(`int docxgen_render(const uint8_t *tpl, int tplLen, const uint8_t *data, int dataLen, ${result})`);
+    this._renderFile = lib.func(`int docxgen_render_file(const char *path, const uint8_t *data, int dataLen, ${result})`);
+    this._free = lib.func('void docxgen_free(void *p)');
+  }
+
+  // render fills the DOCX template (a Buffer) with data (an object or a JSON string).
+  render(template, data) {
+    const raw = json(data);
+    return this._call((...res) => this._render(template, template.length, raw, raw.length, ...res));
+  }
+
+  // renderFile fills the template file; its [include/...] are read next to it.
+  renderFile(path, data) {
+    const raw = json(data);
+    return this._call((...res) => this._renderFile(path, raw, raw.length, ...res));
+  }
+
+  _call(fn) {
+    const out = [null], outLen = [0], err = [null];
+    if (fn(out, outLen, err) !== 0) {
+      const message = koffi.decode(err[0], 'char', -1);
+      this._free(err[0]);
+      throw new DocxgenError(message);
+    }
+    try {
+      return Buffer.from(koffi.decode(out[0], 'uint8_t', outLen[0]));
+    } finally {
+      this._free(out[0]);
+    }
+  }
+}
+
+function json(data) {
+  return Buffer.from(typeof data === 'string' || Buffer.isBuffer(data) ? data : JSON.stringify(data));
+}
+
+module.exports = { Docxgen, DocxgenError };
+
+if (require.main === module) {
+  const [template, data, out] = process.argv.slice(2);
+  if (!out) {
+    console.error('usage: node docxgen.js template.docx data.json out.docx');
+    process.exit(1);
+  }
+  fs.writeFileSync(out, new Docxgen().renderFile(template, fs.readFileSync(data)));
+}
@@ -0,0 +1,70 @@
+"""docxgen from Python through the shared library (ctypes, no dependencies).
+
+    go build -buildmode=c-shared -o libdocxgen.so ./capi
+    python3 docxgen.py template.docx data.json out.docx
+
+As a module:
+
+    from docxgen import Docxgen
+    lib = Docxgen("./libdocxgen.so")
+    docx = lib.render(open("template.docx", "rb").read(), {"number": 7})
+"""
+
+import ctypes
+import json
+import sys
+
+_char_p = ctypes.POINTER(ctypes.c_char)
+_result = [ctypes.POINTER(_char_p), ctypes.POINTER(ctypes.c_int), ctypes.POINTER(_char_p)]
+
+
+class DocxgenError(Exception):
+    """The template could not be filled: the message of docxgen."""
+
+
+class Docxgen:
+    def __init__(self, path="./libdocxgen.so"):
+        lib = ctypes.CDLL(path)
+        lib.docxgen_render.argtypes = [ctypes.c_char_p, ctypes.c_int, ctypes.c_char_p, ctypes.c_int] + _result
+        lib.docxgen_render.restype = ctypes.c_int
+        lib.docxgen_render_file.argtypes = [ctypes.c_char_p, ctypes.c_char_p, ctypes.c_int] + _result
+        lib.docxgen_render_file.restype = ctypes.c_int
+        lib.docxgen_free.argtypes = [ctypes.c_void_p]
+        lib.docxgen_free.restype = None
+        self._lib = lib
+
+    def render(self, template: bytes, data) -> bytes:
+        """Fills the DOCX template (bytes) with data (a dict or a JSON string)."""
+        raw = _json(data)
+        return self._call(self._lib.docxgen_render, template, len(template), raw, len(raw))
+
+    def render_file(self, path: str, data) -> bytes:
+        """Fills the template file; its [include/...] are read next to it."""
+        raw = _json(data)
+        return self._call(self._lib.docxgen_render_file, path.encode(), raw, len(raw))
+
+    def _call(self, fn, *args) -> bytes:
+        out, out_len, err = _char_p(), ctypes.c_int(), _char_p()
+        if fn(*args, ctypes.byref(out), ctypes.byref(out_len), ctypes.byref(err)) != 0:
+            message = ctypes.string_at(err).decode()
+            self._lib.docxgen_free(err)
+            raise DocxgenError(message)
+        try:
+            return ctypes.string_at(out, out_len.value)
+        finally:
+            self._lib.docxgen_free(out)
+
+
+def _json(data) -> bytes:
+    if isinstance(data, (bytes, str)):
+        return data.encode() if isinstance(data, str) else data
+    return json.dumps(data, ensure_ascii=False).encode()
+
+
+if __name__ == "__main__":
+    if len(sys.argv) != 4:
+        sys.exit("usage: docxgen.py template.docx data.json out.docx")
+    with open(sys.argv[2], "rb") as f:
+        docx = Docxgen().render_file(sys.argv[1], f.read())
+    with open(sys.argv[3], "wb") as f:
+        f.write(docx)
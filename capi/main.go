@@ -0,0 +1,99 @@
+//go:build cgo
+
+// Command capi is docxgen as a C shared library, for the services on other stacks (Python, Node,
+// C#) that embed the engine instead of calling the HTTP daemon:
+//
+//	go build -buildmode=c-shared -o libdocxgen.so ./capi   # .dylib on macOS, .dll on Windows
+//
+// The build also writes libdocxgen.h with the functions:
+//
+//	int docxgen_render(char* tpl, int tpl_len, char* data, int data_len, char** out, int* out_len, char** err);
+//	int docxgen_render_file(char* path, char* data, int data_len, char** out, int* out_len, char** err);
+//	void docxgen_free(void* p);
+//
+// docxgen_render fills the DOCX template of tpl with the JSON data, docxgen_render_file — the template
+// file, with its [include/...] next to it. On success they return 0 and *out holds the rendered DOCX
+// of *out_len bytes; on failure they return 1 and *err holds the message, a C string. Both are
+// allocated with malloc and are released with docxgen_free. The calls are safe from several threads;
+// a panic of the engine is returned as an error too, it does not take the host process down.
+//
+// The wrappers of examples/ show the use from Python (ctypes) and Node (koffi).
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"unsafe"
+
+	"docxgen"
+)
+
+//export docxgen_render
+func docxgen_render(tpl *C.char, tplLen C.int, data *C.char, dataLen C.int, out **C.char, outLen *C.int, errOut **C.char) (code C.int) {
+	defer recoverTo(errOut, &code)
+	doc, err := docxgen.OpenBytes(C.GoBytes(unsafe.Pointer(tpl), tplLen))
+	return result(doc, err, C.GoBytes(unsafe.Pointer(data), dataLen), out, outLen, errOut)
+}
+
+//export docxgen_render_file
+func docxgen_render_file(path *C.char, data *C.char, dataLen C.int, out **C.char, outLen *C.int, errOut **C.char) (code C.int) {
+	defer recoverTo(errOut, &code)
+	doc, err := docxgen.Open(C.GoString(path))
+	return result(doc, err, C.GoBytes(unsafe.Pointer(data), dataLen), out, outLen, errOut)
+}
+
+//export docxgen_free
+func docxgen_free(p unsafe.Pointer) {
+	defer func() { _ = recover() }()
+	C.free(p)
+}
+
+// recoverTo turns a panic of an exported call into its error result: a Go panic that crossed
+// into the C caller would abort the whole process.
+func recoverTo(errOut **C.char, code *C.int) {
+	if r := recover(); r != nil {
+		*errOut = C.CString(fmt.Sprintf("docxgen panic: %v", r))
+		*code = 1
+	}
+}
+
+// result renders the opened template and passes the DOCX or the error to the caller.
+func result(doc *docxgen.Docx, err error, data []byte, out **C.char, outLen *C.int, errOut **C.char) C.int {
+	var docx []byte
+	if err == nil {
+		docx, err = render(doc, data)
+	}
+	if err != nil {
+		*errOut = C.CString(err.Error())
+		return 1
+	}
+	*out = (*C.char)(C.CBytes(docx))
+	*outLen = C.int(len(docx))
+	return 0
+}
+
+// render executes the template with the JSON data and packs the result.
+func render(doc *docxgen.Docx, data []byte) ([]byte, error) {
+	values := map[string]any{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("data: %w", err)
+		}
+	}
+	if err := doc.ExecuteTemplate(values); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := doc.SaveToWriter(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func main() {}
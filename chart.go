@@ -0,0 +1,283 @@
+package docxgen
+
+import (
+	"crypto/sha1"
+	"docxgen/geometry"
+	"docxgen/modifiers"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RelTypeChart - the relationship of a DrawingML chart part (word/charts/*.xml).
+const RelTypeChart = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/chart"
+
+// chartContentType - the content type of the chart parts.
+const chartContentType = "application/vnd.openxmlformats-officedocument.drawingml.chart+xml"
+
+// chartSeries - one series of the chart: its name (the legend) and a value per category.
+type chartSeries struct {
+	name   string
+	values []float64
+}
+
+// chartData - the categories (the labels of the X axis, the slices of a pie) and the series.
+type chartData struct {
+	categories []string
+	series     []chartSeries
+}
+
+// Chart - inserts a native Word chart (a c:chart part) built from an array of the data:
+//
+//	{sales|chart:`bar`:`label:month`:`value:plan,fact`:`series:План,Факт`:`150mm*80mm`:`title:Продажи`}
+//
+// The value is a list of numbers ([]float64, "3,5,2") or of records (maps, a map of columns);
+// label:field takes the categories of the records, value:a,b — one series per field ("value" by default),
+// series:A,B renames them in the legend. The types are bar (columns, default), hbar, line and pie
+// (the first series only). The size is "150mm*80mm" or a width, "100%" — of the text width; without
+// a height the chart is half as high as wide. The legend is shown for several series and for a pie,
+// legend / nolegend force it.
+//
+// The values are written into the chart as literals: Word draws and prints the chart, but "Edit Data"
+// has no workbook behind it. Data without numbers is skipped with the bad_chart warning.
+func (d *Docx) Chart(value any, opts ...string) modifiers.RawXML {
+	// ---------- Default parameters ----------
+	kind := "bar"
+	label, title := "", ""
+	fields := []string{"value"}
+	var names []string
+	legend := ""
+	pageW, pageH := d.GetUsableSizeEMU()
+	refW, refH := geometry.Length(pageW), geometry.Length(pageH)
+	width, height := geometry.FromMM(150), geometry.Length(0)
+
+	// ---------- Parsing options ----------
+	for _, token := range opts {
+		token = strings.TrimSpace(token)
+		key, val, _ := strings.Cut(token, ":")
+		switch lower := strings.ToLower(key); {
+		case token == "":
+			continue
+		case lower == "bar" || lower == "column" || lower == "hbar" || lower == "line" || lower == "pie":
+			kind = lower
+		case lower == "legend" || lower == "nolegend":
+			legend = lower
+		case lower == "label":
+			label = strings.TrimSpace(val)
+		case lower == "value":
+			fields = splitList(val)
+		case lower == "series":
+			names = splitList(val)
+		case lower == "title":
+			title = strings.TrimSpace(val)
+		default:
+			if w, h, err := geometry.ParseSize(token, refW, refH); err == nil && w > 0 {
+				width, height = w, h
+			}
+		}
+	}
+	if kind == "column" {
+		kind = "bar"
+	}
+	if height == 0 {
+		height = width / 2
+	}
+
+	data, err := chartValues(value, label, fields)
+	if err != nil {
+		d.warn(WarnBadChart, "chart", "%v", err)
+		return ""
+	}
+	for i := range data.series {
+		if i < len(names) && names[i] != "" {
+			data.series[i].name = names[i]
+		}
+	}
+	if kind == "pie" {
+		data.series = data.series[:1]
+	}
+	showLegend := len(data.series) > 1 || kind == "pie"
+	if legend != "" {
+		showLegend = legend == "legend"
+	}
+
+	// ---------- The chart part, shared by the equal charts of the part ----------
+	part := chartSpaceXML(kind, title, showLegend, data)
+	base := fmt.Sprintf("%s_%x", d.activePart, sha1.Sum([]byte(part)))
+	target := "charts/chart_" + base + ".xml"
+	d.SetFile("word/"+target, []byte(part))
+	d.ContentTypes().SetOverride("word/"+target, chartContentType)
+	rId := d.Rels().Add(d.activePart, Relationship{ID: "rId_chart_" + base, Type: RelTypeChart, Target: target})
+
+	id, name := d.nextDrawing("chart_" + base)
+	return modifiers.RawXML(runBreakout(inlineChartXML(id, rId, name, width.EMU(), height.EMU())))
+}
+
+// splitList - "plan, fact" → ["plan" "fact"].
+func splitList(s string) []string {
+	var out []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// chartValues reads the categories and the series of the value: records by the label and value fields,
+// or plain numbers numbered from 1.
+func chartValues(value any, label string, fields []string) (chartData, error) {
+	var data chartData
+	items, ok := normalizeItems(value)
+	if !ok || len(items) == 0 || normalizeItem(items[0]).mapVal == nil {
+		numbers := modifiers.ParseNumbers(value)
+		if len(numbers) == 0 {
+			return data, fmt.Errorf("%T has no numbers to draw", value)
+		}
+		for i := range numbers {
+			data.categories = append(data.categories, strconv.Itoa(i+1))
+		}
+		data.series = []chartSeries{{name: fieldOr(fields, "value"), values: numbers}}
+		return data, nil
+	}
+
+	if len(fields) == 0 {
+		fields = []string{"value"}
+	}
+	data.series = make([]chartSeries, len(fields))
+	for i, f := range fields {
+		data.series[i].name = f
+	}
+	found := false
+	for i, it := range items {
+		rec := normalizeItem(it).mapVal
+		cat := strconv.Itoa(i + 1)
+		if label != "" {
+			cat = modifiers.ValueText(blockValue(rec, label))
+		}
+		data.categories = append(data.categories, cat)
+		for j, f := range fields {
+			n := modifiers.ParseNumbers(blockValue(rec, f))
+			v := 0.0
+			if len(n) == 1 {
+				v, found = n[0], true
+			}
+			data.series[j].values = append(data.series[j].values, v)
+		}
+	}
+	if !found {
+		return data, fmt.Errorf("the records have no numbers in %s", strings.Join(fields, ", "))
+	}
+	return data, nil
+}
+
+// fieldOr - the first field, or def.
+func fieldOr(fields []string, def string) string {
+	if len(fields) > 0 {
+		return fields[0]
+	}
+	return def
+}
+
+// chartAxisIDs - the ids of the category and the value axes of bar and line charts.
+const chartAxisIDs = `<c:axId val="500000001"/><c:axId val="500000002"/>`
+
+// chartSpaceXML - the chart part: the plot of the kind, the axes (not for a pie), the title and the legend.
+func chartSpaceXML(kind, title string, legend bool, data chartData) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	b.WriteString(`<c:chartSpace xmlns:c="http://schemas.openxmlformats.org/drawingml/2006/chart" ` +
+		`xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`)
+	b.WriteString(`<c:roundedCorners val="0"/><c:chart>`)
+	if title != "" {
+		b.WriteString(`<c:title><c:tx><c:rich><a:bodyPr/><a:p><a:r><a:t>` + xmlEscape(title) +
+			`</a:t></a:r></a:p></c:rich></c:tx><c:overlay val="0"/></c:title><c:autoTitleDeleted val="0"/>`)
+	} else {
+		b.WriteString(`<c:autoTitleDeleted val="1"/>`)
+	}
+	b.WriteString(`<c:plotArea><c:layout/>`)
+
+	switch kind {
+	case "pie":
+		b.WriteString(`<c:pieChart><c:varyColors val="1"/>`)
+		writeChartSeries(&b, kind, data)
+		b.WriteString(`<c:firstSliceAng val="0"/></c:pieChart>`)
+	case "line":
+		b.WriteString(`<c:lineChart><c:grouping val="standard"/><c:varyColors val="0"/>`)
+		writeChartSeries(&b, kind, data)
+		b.WriteString(`<c:marker val="1"/>` + chartAxisIDs + `</c:lineChart>`)
+	default:
+		dir := "col"
+		if kind == "hbar" {
+			dir = "bar"
+		}
+		b.WriteString(`<c:barChart><c:barDir val="` + dir + `"/><c:grouping val="clustered"/><c:varyColors val="0"/>`)
+		writeChartSeries(&b, kind, data)
+		b.WriteString(`<c:gapWidth val="150"/>` + chartAxisIDs + `</c:barChart>`)
+	}
+
+	if kind != "pie" {
+		catPos, valPos := "b", "l"
+		if kind == "hbar" {
+			catPos, valPos = "l", "b"
+		}
+		b.WriteString(`<c:catAx><c:axId val="500000001"/><c:scaling><c:orientation val="minMax"/></c:scaling>` +
+			`<c:delete val="0"/><c:axPos val="` + catPos + `"/><c:numFmt formatCode="General" sourceLinked="0"/>` +
+			`<c:majorTickMark val="out"/><c:minorTickMark val="none"/><c:tickLblPos val="nextTo"/>` +
+			`<c:crossAx val="500000002"/><c:crosses val="autoZero"/><c:auto val="1"/><c:lblAlgn val="ctr"/>` +
+			`<c:lblOffset val="100"/></c:catAx>`)
+		b.WriteString(`<c:valAx><c:axId val="500000002"/><c:scaling><c:orientation val="minMax"/></c:scaling>` +
+			`<c:delete val="0"/><c:axPos val="` + valPos + `"/><c:majorGridlines/>` +
+			`<c:numFmt formatCode="General" sourceLinked="0"/><c:majorTickMark val="out"/>` +
+			`<c:minorTickMark val="none"/><c:tickLblPos val="nextTo"/><c:crossAx val="500000001"/>` +
+			`<c:crosses val="autoZero"/><c:crossBetween val="between"/></c:valAx>`)
+	}
+	b.WriteString(`</c:plotArea>`)
+	if legend {
+		b.WriteString(`<c:legend><c:legendPos val="b"/><c:overlay val="0"/></c:legend>`)
+	}
+	b.WriteString(`<c:plotVisOnly val="1"/><c:dispBlanksAs val="gap"/></c:chart></c:chartSpace>`)
+	return b.String()
+}
+
+// writeChartSeries writes the c:ser of the series with the categories and the values as literals.
+func writeChartSeries(b *strings.Builder, kind string, data chartData) {
+	for i, s := range data.series {
+		fmt.Fprintf(b, `<c:ser><c:idx val="%[1]d"/><c:order val="%[1]d"/><c:tx><c:v>%[2]s</c:v></c:tx>`,
+			i, xmlEscape(s.name))
+		switch kind {
+		case "bar", "hbar":
+			b.WriteString(`<c:invertIfNegative val="0"/>`)
+		case "line":
+			b.WriteString(`<c:marker><c:symbol val="circle"/></c:marker>`)
+		}
+
+		fmt.Fprintf(b, `<c:cat><c:strLit><c:ptCount val="%d"/>`, len(data.categories))
+		for j, c := range data.categories {
+			fmt.Fprintf(b, `<c:pt idx="%d"><c:v>%s</c:v></c:pt>`, j, xmlEscape(c))
+		}
+		fmt.Fprintf(b, `</c:strLit></c:cat><c:val><c:numLit><c:formatCode>General</c:formatCode><c:ptCount val="%d"/>`,
+			len(s.values))
+		for j, v := range s.values {
+			fmt.Fprintf(b, `<c:pt idx="%d"><c:v>%s</c:v></c:pt>`, j, strconv.FormatFloat(v, 'f', -1, 64))
+		}
+		b.WriteString(`</c:numLit></c:val>`)
+		if kind == "line" {
+			b.WriteString(`<c:smooth val="0"/>`)
+		}
+		b.WriteString(`</c:ser>`)
+	}
+}
+
+// inlineChartXML - the inline drawing that shows the chart part rId.
+func inlineChartXML(id int, rId, name string, cx, cy int) string {
+	return fmt.Sprintf(`<w:drawing><wp:inline distT="0" distB="0" distL="0" distR="0">`+
+		`<wp:extent cx="%[4]d" cy="%[5]d"/><wp:effectExtent l="0" t="0" r="0" b="0"/>`+
+		`<wp:docPr id="%[1]d" name="%[3]s"/><wp:cNvGraphicFramePr/>`+
+		`<a:graphic xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main">`+
+		`<a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/chart">`+
+		`<c:chart xmlns:c="http://schemas.openxmlformats.org/drawingml/2006/chart" `+
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" r:id="%[2]s"/>`+
+		`</a:graphicData></a:graphic></wp:inline></w:drawing>`, id, rId, name, cx, cy)
+}
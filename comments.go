@@ -0,0 +1,251 @@
+package docxgen
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	commentsPath    = "word/comments.xml"
+	relTypeComments = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/comments"
+)
+
+var (
+	reComment        = regexp.MustCompile(`(?s)<w:comment\b[^>]*>.*?</w:comment>`)
+	reCommentMark    = regexp.MustCompile(`<w:comment(?:RangeStart|RangeEnd)\b[^>]*/>`)
+	reCommentRef     = regexp.MustCompile(`<w:commentReference\b[^>]*/>`)
+	reCommentPartRel = regexp.MustCompile(`^comments[A-Za-z]*\.xml$`)
+)
+
+// Comment - a comment of the document.
+//   - ID — w:id, the same in comments.xml and in the marks of the text;
+//   - Author, Initials, Date — who left it and when (zero Date — not saved);
+//   - Text — the text of the comment, paragraphs joined by "\n";
+//   - Anchor — the commented text of the document.
+type Comment struct {
+	ID       int
+	Author   string
+	Initials string
+	Date     time.Time
+	Text     string
+	Anchor   string
+}
+
+// AddComment comments the first occurrence of anchor in the text of the document: a tag ("{client.inn}")
+// before ExecuteTemplate, the rendered text after it — to flag missing data during generation. The
+// comment covers the runs that hold anchor and is dated now; comments.xml and its relationship are
+// created when the document has none. An anchor that is not in the text is an error.
+func (d *Docx) AddComment(anchor, author, text string) error {
+	if anchor == "" {
+		return fmt.Errorf("add comment: empty anchor")
+	}
+	doc, ok := d.files["word/document.xml"]
+	if !ok {
+		return fmt.Errorf("add comment: no word/document.xml")
+	}
+	body := string(doc)
+	from, to, ok := anchorRuns(body, xmlEscape(anchor))
+	if !ok {
+		return fmt.Errorf("add comment: %q is not in the text of the document", anchor)
+	}
+
+	comments := string(d.files[commentsPath])
+	if !strings.Contains(comments, "</w:comments>") {
+		comments = xml.Header + `<w:comments xmlns:w="` + wordMainNamespace + `"></w:comments>`
+	}
+	id := 0
+	for _, c := range reComment.FindAllString(comments, -1) {
+		if n, err := strconv.Atoi(xmlAttr(c[:strings.Index(c, ">")+1], "w:id")); err == nil && n >= id {
+			id = n + 1
+		}
+	}
+	sid := strconv.Itoa(id)
+
+	var c strings.Builder
+	fmt.Fprintf(&c, `<w:comment w:id="%s" w:author="%s" w:date="%s" w:initials="%s">`, sid,
+		xmlEscape(author), time.Now().UTC().Format(time.RFC3339), xmlEscape(initials(author)))
+	for _, line := range strings.Split(text, "\n") {
+		c.WriteString(`<w:p><w:r><w:t xml:space="preserve">` + xmlEscape(line) + `</w:t></w:r></w:p>`)
+	}
+	c.WriteString(`</w:comment>`)
+	end := strings.LastIndex(comments, "</w:comments>")
+	d.setPart(commentsPath, []byte(comments[:end]+c.String()+comments[end:]), relTypeComments, wmlType+"comments+xml")
+
+	body = body[:to] + `<w:commentRangeEnd w:id="` + sid + `"/><w:r><w:commentReference w:id="` + sid + `"/></w:r>` + body[to:]
+	body = body[:from] + `<w:commentRangeStart w:id="` + sid + `"/>` + body[from:]
+	d.files["word/document.xml"] = []byte(body)
+	return nil
+}
+
+// ListComments returns the comments of the document in the order of comments.xml.
+func (d *Docx) ListComments() []Comment {
+	body := string(d.files["word/document.xml"])
+	var out []Comment
+	for _, s := range reComment.FindAllString(string(d.files[commentsPath]), -1) {
+		open := s[:strings.Index(s, ">")+1]
+		c := Comment{
+			Author:   xmlUnescaper.Replace(xmlAttr(open, "w:author")),
+			Initials: xmlUnescaper.Replace(xmlAttr(open, "w:initials")),
+		}
+		c.ID, _ = strconv.Atoi(xmlAttr(open, "w:id"))
+		c.Date, _ = time.Parse(time.RFC3339, xmlAttr(open, "w:date"))
+		var lines []string
+		for _, p := range strings.Split(s, "</w:p>") {
+			if strings.Contains(p, "<w:p") {
+				lines = append(lines, xmlUnescaper.Replace(extractParagraphText(p)))
+			}
+		}
+		c.Text = strings.Join(lines, "\n")
+
+		id := regexp.QuoteMeta(xmlAttr(open, "w:id"))
+		start := pattern(`<w:commentRangeStart\b[^>]*\bw:id="` + id + `"[^>]*/>`).FindStringIndex(body)
+		end := pattern(`<w:commentRangeEnd\b[^>]*\bw:id="` + id + `"[^>]*/>`).FindStringIndex(body)
+		if start != nil && end != nil && end[0] > start[1] {
+			c.Anchor = xmlUnescaper.Replace(extractParagraphText(body[start[1]:end[0]]))
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// StripComments removes all comments before delivery: the marks and the references in the text,
+// comments.xml and the parts of the replies and ids of newer Word versions (commentsExtended.xml, ...)
+// with their relationships and content types. Returns the number of removed comments.
+func (d *Docx) StripComments() int {
+	n := len(reComment.FindAllString(string(d.files[commentsPath]), -1))
+	if doc, ok := d.files["word/document.xml"]; ok {
+		body := reCommentMark.ReplaceAllString(string(doc), "")
+		d.files["word/document.xml"] = []byte(stripCommentRefs(body))
+	}
+	d.Rels().Rewrite("document", func(r Relationship) (Relationship, bool) {
+		return r, r.External() || !reCommentPartRel.MatchString(path.Base(r.Target))
+	})
+	for name := range d.files {
+		if path.Dir(name) == "word" && reCommentPartRel.MatchString(path.Base(name)) {
+			delete(d.files, name)
+			delete(d.files, relsPathOf(name))
+			d.ContentTypes().RemoveOverride(name)
+		}
+	}
+	return n
+}
+
+// stripCommentRefs removes the comment references: the whole run when it holds nothing else.
+func stripCommentRefs(body string) string {
+	return stripRefRuns(body, reCommentRef)
+}
+
+// stripRefRuns removes the references found by re (<w:commentReference/>, <w:footnoteReference/>, ...):
+// the whole run when it holds nothing else.
+func stripRefRuns(body string, re *regexp.Regexp) string {
+	for {
+		loc := re.FindStringIndex(body)
+		if loc == nil {
+			return body
+		}
+		run := max(strings.LastIndex(body[:loc[0]], "<w:r>"), strings.LastIndex(body[:loc[0]], "<w:r "))
+		end := strings.Index(body[loc[1]:], "</w:r>")
+		if run >= 0 && end >= 0 && !strings.Contains(body[run:loc[0]], "</w:r>") {
+			end += loc[1] + len("</w:r>")
+			rest := body[run:loc[0]] + body[loc[1]:end]
+			if !strings.Contains(rest, "<w:t") && !strings.Contains(rest, "<w:drawing") {
+				body = body[:run] + body[end:]
+				continue
+			}
+		}
+		body = body[:loc[0]] + body[loc[1]:]
+	}
+}
+
+// anchorRuns finds the escaped text in a paragraph of the body and returns the bounds of the runs
+// that hold it.
+func anchorRuns(body, text string) (from, to int, ok bool) {
+	for pos := 0; ; {
+		start := paragraphStart(body, pos)
+		if start < 0 {
+			return 0, 0, false
+		}
+		end := strings.Index(body[start:], ParagraphClosingTag)
+		if end < 0 {
+			return 0, 0, false
+		}
+		end += start + len(ParagraphClosingTag)
+		pos = start + len("<w:p")
+
+		p := body[start:end]
+		at := strings.Index(extractParagraphText(p), text)
+		if at < 0 {
+			continue
+		}
+		// walk the <w:t> of the paragraph to the ones with the first and the last character
+		offset, first, last := 0, -1, -1
+		for i := 0; i < len(p); {
+			t := textTagStart(p, i)
+			if t < 0 {
+				break
+			}
+			gt := t + strings.IndexByte(p[t:], '>') + 1
+			close := strings.Index(p[gt:], "</w:t>")
+			if close < 0 {
+				break
+			}
+			n := close
+			if first < 0 && at < offset+n {
+				first = t
+			}
+			if at+len(text) <= offset+n {
+				last = gt + close
+				break
+			}
+			offset += n
+			i = gt + close
+		}
+		if first < 0 || last < 0 {
+			continue
+		}
+		runStart := max(strings.LastIndex(p[:first], "<w:r>"), strings.LastIndex(p[:first], "<w:r "))
+		runEnd := strings.Index(p[last:], "</w:r>")
+		if runStart < 0 || runEnd < 0 {
+			continue
+		}
+		return start + runStart, start + last + runEnd + len("</w:r>"), true
+	}
+}
+
+// textTagStart - the next <w:t> or <w:t ...> (not <w:tab/>, <w:tbl>) from pos, -1 if there is none.
+func textTagStart(s string, pos int) int {
+	for {
+		i := strings.Index(s[pos:], "<w:t")
+		if i < 0 {
+			return -1
+		}
+		i += pos
+		if next := i + len("<w:t"); next < len(s) && (s[next] == '>' || s[next] == ' ') {
+			return i
+		}
+		pos = i + len("<w:t")
+	}
+}
+
+// initials - "ИИ" of "Иванов Иван": the first letters of up to three words.
+func initials(name string) string {
+	var b strings.Builder
+	for i, w := range strings.Fields(name) {
+		if i == 3 {
+			break
+		}
+		for _, r := range w {
+			if unicode.IsLetter(r) {
+				b.WriteRune(unicode.ToUpper(r))
+				break
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,233 @@
+package docxgen
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// Compiled templates (.docxgenc): repaired and checked once at deploy time, opened without the work
+// ============================================================================
+
+const (
+	// CompiledExt - the extension of a compiled template.
+	CompiledExt = ".docxgenc"
+	// compiledFormat - the version of the artifact; an artifact of another version is compiled again.
+	compiledFormat = "docxgenc/1"
+	// manifestPath - the manifest inside the artifact, removed from the files on open.
+	manifestPath = "docxgen/manifest.json"
+)
+
+// Manifest - what Compile found in the template, stored in the artifact.
+//   - Format — the version of the artifact;
+//   - Source — the name of the template file (empty for a template from memory);
+//   - SourceHash — sha256 of the files of the template before the compile;
+//   - CompiledAt — the time of the compile, UTC;
+//   - Parts — the parts with tags, in the order of the render.
+type Manifest struct {
+	Format     string         `json:"format"`
+	Source     string         `json:"source,omitempty"`
+	SourceHash string         `json:"source_hash"`
+	CompiledAt time.Time      `json:"compiled_at"`
+	Parts      []PartManifest `json:"parts"`
+}
+
+// PartManifest - the tags of a part.
+//   - Part — "document", "header1", ...;
+//   - Tags — the data paths the tags read ("client.name"), sorted; the fields of the rows of the smart
+//     tables and of the [for] blocks are among them, as written;
+//   - Tables — the names of the smart tables ([table/goods] — "goods");
+//   - Includes — the files of [include/...], with the %key% placeholders as written;
+//   - Blocks — the opening block markers ("[for items]", "[if !paid]").
+type PartManifest struct {
+	Part     string   `json:"part"`
+	Tags     []string `json:"tags,omitempty"`
+	Tables   []string `json:"tables,omitempty"`
+	Includes []string `json:"includes,omitempty"`
+	Blocks   []string `json:"blocks,omitempty"`
+}
+
+// Compile writes the document as a compiled template: the tags of every part repaired, the merge
+// fields converted and the {*tag*} paragraphs unwrapped, with the manifest of the tags, the tables,
+// the includes and the blocks. The template is checked on the way: a part that does not parse
+// (a broken tag, a modifier not registered on d) and an include file that does not open fail the
+// compile, so a broken template is found at deploy time instead of by the first request. Register
+// the modifiers of the renders (ImportModifiers) before the compile; d itself is not changed.
+//
+//	doc, _ := docxgen.Open("act.docx")
+//	doc.ImportModifiers(mods)
+//	f, _ := os.Create("act" + docxgen.CompiledExt)
+//	if _, err := doc.Compile(f); err != nil { ... }
+func (d *Docx) Compile(w io.Writer) (Manifest, error) {
+	c := d.Clone()
+	c.warnings, c.observer = nil, nil
+	m := Manifest{Format: compiledFormat, SourceHash: d.filesHash(), CompiledAt: time.Now().UTC()}
+	if d.sourcePath != "" {
+		m.Source = filepath.Base(d.sourcePath)
+	}
+
+	for _, part := range c.templateParts() {
+		content, err := c.ContentPart(part)
+		if err != nil {
+			if part == "document" {
+				return m, fmt.Errorf("compile: %w", err)
+			}
+			continue
+		}
+		if content, err = c.RepairTags(content); err != nil {
+			return m, fmt.Errorf("compile %s: repair tags: %w", partPath(part), err)
+		}
+		content = c.ProcessUnWrapParagraphTags(ConvertMergeFields(content))
+		c.UpdateContentPart(part, content)
+
+		pm, err := c.compilePart(part, content)
+		if err != nil {
+			return m, fmt.Errorf("compile %s: %w", partPath(part), err)
+		}
+		if len(pm.Tags)+len(pm.Tables)+len(pm.Includes)+len(pm.Blocks) > 0 {
+			m.Parts = append(m.Parts, pm)
+		}
+	}
+
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return m, fmt.Errorf("compile: %w", err)
+	}
+	c.files[manifestPath] = manifest
+	return m, c.writeZip(w)
+}
+
+// CompileFile compiles the template file src into dst (src with CompiledExt if dst is empty),
+// see Compile; prepare, if given, registers the modifiers on the template first.
+func CompileFile(src, dst string, prepare func(*Docx) error) (Manifest, error) {
+	doc, err := Open(src)
+	if err != nil {
+		return Manifest{}, err
+	}
+	if prepare != nil {
+		if err := prepare(doc); err != nil {
+			return Manifest{}, err
+		}
+	}
+	if dst == "" {
+		dst = strings.TrimSuffix(src, filepath.Ext(src)) + CompiledExt
+	}
+	var buf bytes.Buffer
+	m, err := doc.Compile(&buf)
+	if err != nil {
+		return m, err
+	}
+	return m, os.WriteFile(dst, buf.Bytes(), 0o644)
+}
+
+// OpenCompiled opens a template compiled by Compile, without repairing its tags again: the render
+// starts from the includes and the tables. The [include/...] files are resolved next to the artifact,
+// so keep it in the directory of the template. An artifact of another format version fails to open.
+func OpenCompiled(path string, opts ...OpenOption) (*Docx, error) {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("open compiled: %w", err)
+	}
+	defer func(reader *zip.ReadCloser) {
+		_ = reader.Close()
+	}(reader)
+
+	doc, err := unpackZip(&reader.Reader, path, opts)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(doc.files[manifestPath], &m); err != nil {
+		return nil, fmt.Errorf("open compiled %s: bad manifest: %w", path, err)
+	}
+	if m.Format != compiledFormat {
+		return nil, fmt.Errorf("open compiled %s: format %q, want %q; compile the template again", path, m.Format, compiledFormat)
+	}
+	delete(doc.files, manifestPath)
+	doc.compiled = true
+	return doc, nil
+}
+
+// compilePart checks the repaired part and lists its tags, tables, includes and blocks.
+func (d *Docx) compilePart(part, content string) (PartManifest, error) {
+	pm := PartManifest{Part: part}
+
+	text := TransformTemplate(d.ProcessTrimTags(content))
+	tmpl, err := parseTemplate(text, d.templateFuncs(nil))
+	if err != nil {
+		return pm, err
+	}
+	walkFields(tmpl.Tree, func(ident []string) {
+		if name := strings.Join(ident, "."); name != "" && !slices.Contains(pm.Tags, name) {
+			pm.Tags = append(pm.Tags, name)
+		}
+	})
+	slices.Sort(pm.Tags)
+
+	for _, m := range findTableMarkers(content) {
+		if !m.closing {
+			name, _ := parseTableOptions(strings.TrimSuffix(strings.TrimPrefix(m.tag, tableOpenPrefix), "]"))
+			pm.Tables = append(pm.Tables, name)
+		}
+	}
+	for _, m := range findBlockMarkers(content) {
+		if !m.closing {
+			pm.Blocks = append(pm.Blocks, m.String())
+		}
+	}
+
+	for pos := 0; ; {
+		start := strings.Index(content[pos:], "[include/")
+		if start < 0 {
+			break
+		}
+		start += pos
+		end := strings.IndexByte(content[start:], ']')
+		if end < 0 {
+			break
+		}
+		raw := content[start : start+end+1]
+		pos = start + end + 1
+		if strings.Contains(raw, "%") { // the file is known with the data only
+			if file := strings.TrimSuffix(strings.TrimPrefix(raw, "[include/"), "]"); !slices.Contains(pm.Includes, file) {
+				pm.Includes = append(pm.Includes, file)
+			}
+			continue
+		}
+		spec, err := ParseBracketIncludeTag(raw, nil)
+		if err != nil {
+			return pm, fmt.Errorf("%s: %w", raw, err)
+		}
+		if slices.Contains(pm.Includes, spec.File) {
+			continue
+		}
+		pm.Includes = append(pm.Includes, spec.File)
+		if d.hasTemplateDir() {
+			if _, err := d.openFragmentDoc(spec.File); err != nil {
+				return pm, fmt.Errorf("%s: %w", raw, err)
+			}
+		}
+	}
+	return pm, nil
+}
+
+// filesHash - sha256 of the names and the contents of the files, in the order of the names.
+func (d *Docx) filesHash() string {
+	h := sha256.New()
+	for _, name := range slices.Sorted(maps.Keys(d.files)) {
+		fmt.Fprintf(h, "%s\x00%d\x00", name, len(d.files[name]))
+		h.Write(d.files[name])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,279 @@
+package docxgen
+
+import (
+	"encoding/xml"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	contentTypesPath      = "[Content_Types].xml"
+	contentTypesNamespace = "http://schemas.openxmlformats.org/package/2006/content-types"
+)
+
+// mediaContentTypes - content types of the media extensions (Default entries).
+var mediaContentTypes = map[string]string{
+	"png":   "image/png",
+	"jpg":   "image/jpeg",
+	"jpeg":  "image/jpeg",
+	"gif":   "image/gif",
+	"bmp":   "image/bmp",
+	"tif":   "image/tiff",
+	"tiff":  "image/tiff",
+	"svg":   "image/svg+xml",
+	"emf":   "image/x-emf",
+	"wmf":   "image/x-wmf",
+	"odttf": "application/vnd.openxmlformats-officedocument.obfuscatedFont",
+	"rels":  "application/vnd.openxmlformats-package.relationships+xml",
+	"xml":   "application/xml",
+}
+
+// wmlType - prefix of the WordprocessingML content types.
+const wmlType = "application/vnd.openxmlformats-officedocument.wordprocessingml."
+
+// knownPartTypes - content types of the standard parts, used to rebuild a broken [Content_Types].xml.
+var knownPartTypes = []struct {
+	pattern     *regexp.Regexp
+	contentType string
+}{
+	{regexp.MustCompile(`^word/document\.xml$`), wmlType + "document.main+xml"},
+	{regexp.MustCompile(`^word/styles\.xml$`), wmlType + "styles+xml"},
+	{regexp.MustCompile(`^word/settings\.xml$`), wmlType + "settings+xml"},
+	{regexp.MustCompile(`^word/webSettings\.xml$`), wmlType + "webSettings+xml"},
+	{regexp.MustCompile(`^word/fontTable\.xml$`), wmlType + "fontTable+xml"},
+	{regexp.MustCompile(`^word/numbering\.xml$`), wmlType + "numbering+xml"},
+	{regexp.MustCompile(`^word/footnotes\.xml$`), wmlType + "footnotes+xml"},
+	{regexp.MustCompile(`^word/endnotes\.xml$`), wmlType + "endnotes+xml"},
+	{regexp.MustCompile(`^word/comments\.xml$`), wmlType + "comments+xml"},
+	{regexp.MustCompile(`^word/header\d+\.xml$`), wmlType + "header+xml"},
+	{regexp.MustCompile(`^word/footer\d+\.xml$`), wmlType + "footer+xml"},
+	{regexp.MustCompile(`^word/theme/theme\d+\.xml$`), "application/vnd.openxmlformats-officedocument.theme+xml"},
+	{regexp.MustCompile(`^docProps/core\.xml$`), "application/vnd.openxmlformats-package.core-properties+xml"},
+	{regexp.MustCompile(`^docProps/app\.xml$`), "application/vnd.openxmlformats-officedocument.extended-properties+xml"},
+}
+
+// ContentTypeDefault - <Default Extension="png" ContentType="image/png"/>.
+type ContentTypeDefault struct {
+	Extension   string `xml:"Extension,attr"`
+	ContentType string `xml:"ContentType,attr"`
+}
+
+// ContentTypeOverride - <Override PartName="/word/document.xml" ContentType="..."/>.
+type ContentTypeOverride struct {
+	PartName    string `xml:"PartName,attr"`
+	ContentType string `xml:"ContentType,attr"`
+}
+
+type contentTypes struct {
+	XMLName   xml.Name              `xml:"Types"`
+	XMLNS     string                `xml:"xmlns,attr"`
+	Defaults  []ContentTypeDefault  `xml:"Default"`
+	Overrides []ContentTypeOverride `xml:"Override"`
+}
+
+// ContentTypesManager - reading and editing [Content_Types].xml: Default entries by extension
+// and Override entries by part name. Like RelationshipManager, it keeps no state of its own.
+type ContentTypesManager struct {
+	d *Docx
+}
+
+// ContentTypes returns the [Content_Types].xml manager of the document.
+func (d *Docx) ContentTypes() *ContentTypesManager {
+	return &ContentTypesManager{d: d}
+}
+
+// Default returns the content type registered for the extension ("png", case-insensitive).
+func (m *ContentTypesManager) Default(ext string) (string, bool) {
+	ext = normalizeExt(ext)
+	for _, def := range m.read().Defaults {
+		if strings.EqualFold(def.Extension, ext) {
+			return def.ContentType, true
+		}
+	}
+	return "", false
+}
+
+// SetDefault registers (or replaces) the content type of the extension.
+func (m *ContentTypesManager) SetDefault(ext, contentType string) {
+	ext = normalizeExt(ext)
+	ct := m.read()
+	for i, def := range ct.Defaults {
+		if strings.EqualFold(def.Extension, ext) {
+			if def.ContentType == contentType {
+				return
+			}
+			ct.Defaults[i].ContentType = contentType
+			m.write(ct)
+			return
+		}
+	}
+	ct.Defaults = append(ct.Defaults, ContentTypeDefault{Extension: ext, ContentType: contentType})
+	m.write(ct)
+}
+
+// AddDefaultForFile registers the Default entry for the extension of a media file
+// ("image.png" → image/png) if it is not registered yet. Unknown extensions get application/octet-stream.
+func (m *ContentTypesManager) AddDefaultForFile(name string) {
+	ext := normalizeExt(path.Ext(name))
+	if _, ok := m.Default(ext); ok {
+		return
+	}
+	contentType := mediaContentTypes[ext]
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	m.SetDefault(ext, contentType)
+}
+
+// Override returns the content type of the part ("word/document.xml" or "/word/document.xml").
+func (m *ContentTypesManager) Override(partName string) (string, bool) {
+	partName = normalizePartName(partName)
+	for _, o := range m.read().Overrides {
+		if o.PartName == partName {
+			return o.ContentType, true
+		}
+	}
+	return "", false
+}
+
+// SetOverride registers (or replaces) the content type of the part.
+func (m *ContentTypesManager) SetOverride(partName, contentType string) {
+	partName = normalizePartName(partName)
+	ct := m.read()
+	for i, o := range ct.Overrides {
+		if o.PartName == partName {
+			if o.ContentType == contentType {
+				return
+			}
+			ct.Overrides[i].ContentType = contentType
+			m.write(ct)
+			return
+		}
+	}
+	ct.Overrides = append(ct.Overrides, ContentTypeOverride{PartName: partName, ContentType: contentType})
+	m.write(ct)
+}
+
+// RemoveOverride removes the Override of the part. Returns false if there was none.
+func (m *ContentTypesManager) RemoveOverride(partName string) bool {
+	partName = normalizePartName(partName)
+	ct := m.read()
+	for i, o := range ct.Overrides {
+		if o.PartName == partName {
+			ct.Overrides = append(ct.Overrides[:i], ct.Overrides[i+1:]...)
+			m.write(ct)
+			return true
+		}
+	}
+	return false
+}
+
+// ContentTypeOf returns the effective content type of the part: the Override, otherwise the Default of its extension.
+func (m *ContentTypesManager) ContentTypeOf(partName string) string {
+	if ct, ok := m.Override(partName); ok {
+		return ct
+	}
+	ct, _ := m.Default(path.Ext(partName))
+	return ct
+}
+
+// Repair brings [Content_Types].xml into a consistent state:
+//   - a file that cannot be parsed is rebuilt from the parts of the package;
+//   - duplicate Default (case-insensitive) and Override entries are removed, the first one wins;
+//   - PartName without the leading "/" is fixed, Overrides of missing parts are dropped;
+//   - the mandatory rels/xml Defaults are added, as well as Defaults for media extensions
+//     and Overrides for standard parts that have no content type.
+func (m *ContentTypesManager) Repair() {
+	ct := m.read()
+
+	seenExt := map[string]bool{}
+	defaults := ct.Defaults[:0:0]
+	for _, def := range ct.Defaults {
+		ext := normalizeExt(def.Extension)
+		if ext == "" || def.ContentType == "" || seenExt[ext] {
+			continue
+		}
+		seenExt[ext] = true
+		defaults = append(defaults, ContentTypeDefault{Extension: ext, ContentType: def.ContentType})
+	}
+	for _, ext := range []string{"rels", "xml"} {
+		if !seenExt[ext] {
+			seenExt[ext] = true
+			defaults = append(defaults, ContentTypeDefault{Extension: ext, ContentType: mediaContentTypes[ext]})
+		}
+	}
+
+	seenPart := map[string]bool{}
+	overrides := ct.Overrides[:0:0]
+	for _, o := range ct.Overrides {
+		name := normalizePartName(o.PartName)
+		if o.ContentType == "" || seenPart[name] {
+			continue
+		}
+		if _, exists := m.d.files[strings.TrimPrefix(name, "/")]; !exists {
+			continue
+		}
+		seenPart[name] = true
+		overrides = append(overrides, ContentTypeOverride{PartName: name, ContentType: o.ContentType})
+	}
+
+	names := make([]string, 0, len(m.d.files))
+	for name := range m.d.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == contentTypesPath || seenPart["/"+name] {
+			continue
+		}
+		for _, k := range knownPartTypes {
+			if k.pattern.MatchString(name) {
+				seenPart["/"+name] = true
+				overrides = append(overrides, ContentTypeOverride{PartName: "/" + name, ContentType: k.contentType})
+				break
+			}
+		}
+		if seenPart["/"+name] {
+			continue
+		}
+		ext := normalizeExt(path.Ext(name))
+		if contentType, ok := mediaContentTypes[ext]; ok && !seenExt[ext] {
+			seenExt[ext] = true
+			defaults = append(defaults, ContentTypeDefault{Extension: ext, ContentType: contentType})
+		}
+	}
+
+	ct.Defaults, ct.Overrides = defaults, overrides
+	m.write(ct)
+}
+
+// read parses [Content_Types].xml; a missing or broken file gives an empty set.
+func (m *ContentTypesManager) read() contentTypes {
+	var ct contentTypes
+	if data, ok := m.d.files[contentTypesPath]; ok {
+		if err := xml.Unmarshal(data, &ct); err != nil {
+			ct = contentTypes{}
+		}
+	}
+	ct.XMLNS = contentTypesNamespace
+	return ct
+}
+
+func (m *ContentTypesManager) write(ct contentTypes) {
+	ct.XMLNS = contentTypesNamespace
+	out, _ := xml.MarshalIndent(ct, "", "  ")
+	m.d.files[contentTypesPath] = append([]byte(xml.Header), out...)
+}
+
+// normalizeExt - ".PNG" → "png".
+func normalizeExt(ext string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+}
+
+// normalizePartName - "word/document.xml" → "/word/document.xml".
+func normalizePartName(name string) string {
+	name = strings.ReplaceAll(strings.TrimSpace(name), "\\", "/")
+	return "/" + strings.TrimPrefix(name, "/")
+}
@@ -4,68 +4,85 @@ import (
 	"archive/zip"
 	"bytes"
 	"crypto/sha1"
+	"crypto/sha256"
 	"docxgen/metrics"
 	"docxgen/modifiers"
-	"encoding/xml"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
-	"regexp"
+	"slices"
 	"strings"
-	"sync"
 	"text/template"
 	"time"
 )
 
-// sharedMedia — thread-safe storage of media files (png, jpg, etc.),
-// used by all Docx instances when generating documents.
-type sharedMedia struct {
-	mu    sync.Mutex
-	files map[string][]byte
-}
-
-// Global Instance
-var globalMedia = &sharedMedia{
-	files: make(map[string][]byte),
-}
-
-// AddAll — Adds all files from another map to the shared pool.
-func (m *sharedMedia) AddAll(from map[string][]byte) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	for k, v := range from {
-		m.files[k] = v
-	}
-}
-
-// ForEach - Performs an action for each file in the pool.
-func (m *sharedMedia) ForEach(fn func(name string, data []byte)) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	for k, v := range m.files {
-		fn(k, v)
-	}
-}
-
 // Docx is an unpacked DOCX document
 // and provides an API for reading, modifying, and repackaging.
 //
 // Structure Fields:
 //   - files — all files from the archive (xml, styles, media, etc.);
-//   - localMedia — attachments added inside the current instance;
-//   - sourcePath — the original path to the template;
+//   - localMedia — media added by this document (QR codes, charts), linked to their parts on save;
+//   - mediaParts — the part of every media file of AddMediaRel ("footnotes", "header1", ...);
+//   - sourcePath — the original path to the template (empty for OpenReader/OpenBytes);
+//   - fsys — the files next to the template instead of the disk (see WithFS);
 //   - extraFuncs — additional registered modifiers;
 //   - fonts — a set of fonts (for p_split and similar operations);
+//   - calendar — production calendar for date arithmetic (add_days, next_workday);
 //   - activePart — the currently editable section of the document ("document", "header1", "footer1", etc.).
-//     ⚠️ Not flow-safe – you cannot change in several goroutines at the same time.
+//     ⚠️ Not flow-safe – you cannot change in several goroutines at the same time
+//     (render concurrently through Template, every render gets its own Clone).
+//   - sections, section — page layouts of the document sections and the index of the section
+//     in which the template is being executed (for "% of page" sizes of drawings);
+//   - drawingID — the last issued id of wp:docPr (unique drawing ids, see nextDrawing);
+//   - middleware — application processing steps of ExecuteTemplate by stage (see Use);
+//   - observer — the receiver of ExecuteTemplate events (see Observe);
+//   - formatRules — conditional formatting of the tags (see SetFormatRules);
+//   - modifierLimits — the sandbox of the custom modifiers (see SetModifierLimits);
+//   - warnings — the non-fatal issues of ExecuteTemplateResult (nil — not collected);
+//   - locale — the number locale of money/percent/unit (see SetLocale);
+//   - imageHosts — the hosts the image modifier downloads the pictures from (see SetImageHosts);
+//   - strictTypes — a modifier argument of a wrong type fails the render (see SetStrictTypes);
+//   - contentControls — the content controls are filled from the data (see SetContentControls);
+//   - channel — the output channel of [if-channel/...] blocks (see SetChannel);
+//   - tableErr — the failure of a smart table, a loop (unmatched=error, MaxRows) or a generated picture
+//     (see takeRaster), returned by ExecuteTemplate;
+//   - limits, rows — the size limits (see SetSizeLimits) and the rows of the tables and loops of the current render;
+//   - afterOpen — the steps of the OpenOptions that need the unpacked parts (WithAcceptedRevisions);
+//   - compiled — the parts were repaired by Compile (see OpenCompiled), the render does not repeat it;
+//   - timing — the time spent in the stages since the open or the clone (see Timing).
 type Docx struct {
-	files      map[string][]byte
-	localMedia map[string][]byte
-	sourcePath string
-	extraFuncs map[string]modifiers.ModifierMeta
-	fonts      *metrics.FontSet
-	activePart string
+	files           map[string][]byte
+	localMedia      map[string][]byte
+	mediaParts      map[string]string
+	sourcePath      string
+	fsys            fs.FS
+	extraFuncs      map[string]modifiers.ModifierMeta
+	fonts           *metrics.FontSet
+	calendar        *modifiers.WorkCalendar
+	activePart      string
+	sections        []PageLayout
+	section         int
+	drawingID       int
+	middleware      map[Stage][]ProcessFunc
+	observer        Observer
+	formatRules     []compiledRule
+	modifierLimits  modifiers.Limits
+	warnings        *[]Warning
+	locale          string
+	imageHosts      []string
+	strictTypes     bool
+	contentControls bool
+	channel         string
+	tableErr        error
+	limits          SizeLimits
+	rows            int
+	afterOpen       []func()
+	compiled        bool
+	timing          Timing
 }
 
 //
@@ -73,7 +90,7 @@ type Docx struct {
 //
 
 // Open - Opens the DOCX file, unpacks it, and prepares the structure.
-func Open(path string) (*Docx, error) {
+func Open(path string, opts ...OpenOption) (*Docx, error) {
 	reader, err := zip.OpenReader(path)
 	if err != nil {
 		return nil, fmt.Errorf("open docx: %w", err)
@@ -82,32 +99,34 @@ func Open(path string) (*Docx, error) {
 		_ = reader.Close()
 	}(reader)
 
-	files := make(map[string][]byte)
-	for _, file := range reader.File {
-		rc, err := file.Open()
-		if err != nil {
-			return nil, fmt.Errorf("read %s: %w", file.Name, err)
-		}
-		data, err := io.ReadAll(rc)
-		if err != nil {
-			return nil, fmt.Errorf("read %s: %w", file.Name, err)
-		}
-
-		err = rc.Close()
-		if err != nil {
-			return nil, fmt.Errorf("close %s: %w", file.Name, err)
-		}
+	return openZip(&reader.Reader, path, opts)
+}
 
-		files[file.Name] = data
+// OpenReader - opens a DOCX from memory (an upload, an S3 object) the same way as Open.
+// The template has no directory, so [include/...] fragments are resolved only from WithFS.
+func OpenReader(r io.ReaderAt, size int64, opts ...OpenOption) (*Docx, error) {
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("open docx: %w", err)
 	}
+	return openZip(reader, "", opts)
+}
 
-	doc := &Docx{
-		files:      files,
-		sourcePath: path,
-		localMedia: make(map[string][]byte),
+// OpenBytes - opens a DOCX from a byte slice, see OpenReader.
+func OpenBytes(data []byte, opts ...OpenOption) (*Docx, error) {
+	return OpenReader(bytes.NewReader(data), int64(len(data)), opts...)
+}
+
+// openZip unpacks the archive, applies the options and repairs the tags of the body; path is
+// the template file, if any.
+func openZip(reader *zip.Reader, path string, opts []OpenOption) (*Docx, error) {
+	doc, err := unpackZip(reader, path, opts)
+	if err != nil {
+		return nil, err
 	}
 
 	//Restoring broken tags so that the template can be interpreted correctly.
+	defer since(&doc.timing.Repair, time.Now())
 	body, err := doc.ContentPart("document")
 	if err != nil {
 		return nil, err
@@ -124,44 +143,124 @@ func Open(path string) (*Docx, error) {
 	return doc, nil
 }
 
+// unpackZip reads the files of the archive within the size limits and applies the options.
+func unpackZip(reader *zip.Reader, path string, opts []OpenOption) (*Docx, error) {
+	doc := &Docx{
+		files:      make(map[string][]byte),
+		sourcePath: path,
+		localMedia: make(map[string][]byte),
+	}
+	defer since(&doc.timing.Unzip, time.Now())
+	for _, opt := range opts {
+		opt(doc)
+	}
+
+	var total uint64
+	for _, file := range reader.File {
+		if total += file.UncompressedSize64; doc.limits.MaxTotalSize > 0 && total > uint64(doc.limits.MaxTotalSize) {
+			return nil, fmt.Errorf("open docx: %w: the archive unpacks to more than %d bytes", ErrLimitExceeded, doc.limits.MaxTotalSize)
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", file.Name, err)
+		}
+		data, err := doc.readPart(file.Name, file.UncompressedSize64, rc)
+		if err != nil {
+			_ = rc.Close()
+			return nil, fmt.Errorf("read %s: %w", file.Name, err)
+		}
+
+		err = rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("close %s: %w", file.Name, err)
+		}
+
+		doc.files[file.Name] = data
+	}
+	if err := doc.checkTotalSize(); err != nil {
+		return nil, fmt.Errorf("open docx: %w", err)
+	}
+	for _, step := range doc.afterOpen {
+		step()
+	}
+	doc.afterOpen = nil
+	return doc, nil
+}
+
+// Clone - a copy of the document with its own parts and media and the same settings
+// (modifiers, fonts, calendar, rules, middleware, observer). The parts are copied by reference
+// (Docx never changes a part in place), so the copy is cheap.
+func (d *Docx) Clone() *Docx {
+	c := *d
+	c.files = maps.Clone(d.files)
+	c.localMedia = maps.Clone(d.localMedia)
+	c.mediaParts = maps.Clone(d.mediaParts)
+	c.extraFuncs = maps.Clone(d.extraFuncs)
+	c.middleware = make(map[Stage][]ProcessFunc, len(d.middleware))
+	for stage, fns := range d.middleware {
+		c.middleware[stage] = slices.Clone(fns)
+	}
+	c.formatRules = slices.Clone(d.formatRules)
+	c.sections, c.section = nil, 0
+	c.warnings = nil
+	c.timing = Timing{}
+	return &c
+}
+
 // Save — writes all files of the document back to the DOCX archive.
 func (d *Docx) Save(path string) error {
 	buffer := new(bytes.Buffer)
-	writer := zip.NewWriter(buffer)
+	if err := d.writeZip(buffer); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buffer.Bytes(), 0644); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+	return nil
+}
 
-	// 1. Combining all media files into a single card
+// linkLocalMedia moves the media added by this document into its files and links them to their parts:
+// the part that added the file (footnotes, comments, a header), or, for a file set with SetFile,
+// the part named before "_" in its name (word/media/footer1_xyz.png), else the body.
+func (d *Docx) linkLocalMedia() {
 	// mediaByPart - stores files for different parts of the document
 	mediaByPart := map[string][]string{}
-	globalMedia.ForEach(func(filename string, data []byte) {
+	for filename, data := range d.localMedia {
 		d.files[filename] = data
 
 		mediaName := strings.TrimPrefix(filename, "word/media/")
-		// Encode the section name in the file name, for example:
-		//  word/media/document_abc.png
-		//  word/media/footer1_xyz.png
-		//  word/media/header2_zzz.png
-		parts := strings.SplitN(mediaName, "_", 2)
-		part := "document" // по умолчанию
-
-		if len(parts) > 1 {
-			switch {
-			case strings.HasPrefix(parts[0], "header"):
-				part = parts[0]
-			case strings.HasPrefix(parts[0], "footer"):
-				part = parts[0]
+		part := d.mediaParts[filename]
+		if part == "" {
+			part = "document" // по умолчанию
+			if prefix, _, ok := strings.Cut(mediaName, "_"); ok && (strings.HasPrefix(prefix, "header") || strings.HasPrefix(prefix, "footer")) {
+				part = prefix
 			}
 		}
 
 		mediaByPart[part] = append(mediaByPart[part], mediaName)
-	})
+	}
 
-	// 2. Update rels and [Content_Types].xml
 	for part, names := range mediaByPart {
 		d.updateMediaRelationships(part, names)
 	}
+}
 
-	// 3. Create a ZIP archive
-	for name, data := range d.files {
+// writeZip links the media of the document and writes the DOCX archive.
+func (d *Docx) writeZip(w io.Writer) error {
+	defer since(&d.timing.Save, time.Now())
+	writer := zip.NewWriter(w)
+
+	// 1-2. Media files added by this document, with their rels and [Content_Types].xml
+	d.linkLocalMedia()
+
+	// Drop images that are no longer referenced after templating
+	d.RemoveUnusedMedia()
+	d.ContentTypes().Repair()
+
+	// 3. Create a ZIP archive: the entries in a fixed order and with a fixed time, so that the same
+	// document is packed into the same bytes (see Checksum)
+	for _, name := range zipOrder(slices.Collect(maps.Keys(d.files))) {
+		data := d.files[name]
 		name = strings.TrimPrefix(name, "/")
 		name = strings.ReplaceAll(name, "\\", "/")
 		if strings.TrimSpace(name) == "" {
@@ -171,7 +270,7 @@ func (d *Docx) Save(path string) error {
 		header := &zip.FileHeader{
 			Name:     name,
 			Method:   zip.Deflate,
-			Modified: time.Now().UTC(),
+			Modified: zipModified,
 		}
 		writerFile, err := writer.CreateHeader(header)
 		if err != nil {
@@ -185,12 +284,33 @@ func (d *Docx) Save(path string) error {
 	if err := writer.Close(); err != nil {
 		return fmt.Errorf("close zip: %w", err)
 	}
-	if err := os.WriteFile(path, buffer.Bytes(), 0644); err != nil {
-		return fmt.Errorf("write file: %w", err)
-	}
 	return nil
 }
 
+// zipModified - the time of the entries of a saved DOCX: the earliest one of the zip format.
+var zipModified = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// zipOrder sorts the names of the entries: [Content_Types].xml and _rels/.rels first, as Word
+// writes them, then by name.
+func zipOrder(names []string) []string {
+	rank := func(name string) int {
+		switch strings.TrimPrefix(name, "/") {
+		case "[Content_Types].xml":
+			return 0
+		case "_rels/.rels":
+			return 1
+		}
+		return 2
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		if ra, rb := rank(a), rank(b); ra != rb {
+			return ra - rb
+		}
+		return strings.Compare(a, b)
+	})
+	return names
+}
+
 //
 // ──────────────────────────── WORKING WITH XML ────────────────────────────
 //
@@ -244,44 +364,19 @@ func (d *Docx) UpdateContentPart(part, content string) {
 // actually connected to the document via <w:headerReference> / <w:footerReference>.
 // ListHeaderFooterParts returns the names of all header*/footer* files that are actually connected.
 func (d *Docx) ListHeaderFooterParts() []string {
-	const (
-		docPath  = "word/document.xml"
-		relsPath = "word/_rels/document.xml.rels"
-	)
 	var parts []string
 
-	doc, ok1 := d.files[docPath]
-	rels, ok2 := d.files[relsPath]
-	if !ok1 || !ok2 {
+	doc, ok := d.files["word/document.xml"]
+	if !ok {
 		return parts
 	}
 
 	// look for r:id from <w:headerReference> / <w:footerReference>
-	re := regexp.MustCompile(`<w:(?:headerReference|footerReference)[^>]+r:id="([^"]+)"`)
-	ids := re.FindAllStringSubmatch(string(doc), -1)
-	if len(ids) == 0 {
-		return parts
-	}
-
-	type Relationship struct {
-		ID     string `xml:"Id,attr"`
-		Type   string `xml:"Type,attr"`
-		Target string `xml:"Target,attr"`
-	}
-	type Relationships struct {
-		XMLName xml.Name       `xml:"Relationships"`
-		Items   []Relationship `xml:"Relationship"`
-	}
-	var rel Relationships
-	_ = xml.Unmarshal(rels, &rel)
-
-	for _, id := range ids {
-		for _, r := range rel.Items {
-			if r.ID == id[1] &&
-				(strings.Contains(r.Type, "/header") || strings.Contains(r.Type, "/footer")) {
-				name := strings.TrimSuffix(filepath.Base(r.Target), ".xml")
-				parts = append(parts, name)
-			}
+	rels := d.Rels()
+	for _, id := range reHdrFtrRef.FindAllStringSubmatch(string(doc), -1) {
+		r, ok := rels.Lookup("document", id[1])
+		if ok && (r.Type == RelTypeHeader || r.Type == RelTypeFooter) {
+			parts = append(parts, partName(r.Target))
 		}
 	}
 	return parts
@@ -292,35 +387,49 @@ func (d *Docx) ListHeaderFooterParts() []string {
 //
 
 // ImportBuiltins adds built-in standard modifiers
-// (QRCODE, BARCODE, etc.) through the common ImportModifiers mechanism.
+// (QRCODE, BARCODE, SPARKLINE, CHART, PROGRESSBAR, IMAGE, HTML, LINK, etc.) through the common ImportModifiers mechanism.
 func (d *Docx) ImportBuiltins() {
-	// add QR here so that several documents work with their data, and globalMedia receives information about the files
+	// the drawings keep their media and the links their relationships in this document (see AddImageRel);
+	// they are large by nature, so SetModifierLimits does not apply to them
+	unlimited := &modifiers.Limits{}
 	mods := map[string]modifiers.ModifierMeta{
-		"qrcode": {
-			Func: func(value string, opts ...string) modifiers.RawXML {
-				xmlData := d.QrCode(value, opts...)
-				globalMedia.AddAll(d.localMedia)
-				return xmlData
-			},
-			Count: 0,
-		},
-		"barcode": {
-			Func: func(value string, opts ...string) modifiers.RawXML {
-				xmlData := d.Barcode(value, opts...)
-				globalMedia.AddAll(d.localMedia)
-				return xmlData
-			},
-			Count: 0,
-		},
+		"qrcode":      {Func: d.QrCode, Count: 0, Limits: unlimited},
+		"barcode":     {Func: d.Barcode, Count: 0, Limits: unlimited},
+		"sparkline":   {Func: d.Sparkline, Count: 0, Limits: unlimited},
+		"chart":       {Func: d.Chart, Count: 0, Limits: unlimited},
+		"progressbar": {Func: d.ProgressBar, Count: 0, Limits: unlimited},
+		"image":       {Func: d.Image, Count: 0, Limits: unlimited},
+		"html":        {Func: d.HTML, Count: 0, Limits: unlimited},
+		"link":        {Func: d.Link, Count: 0, Limits: unlimited},
 	}
 
 	d.ImportModifiers(mods)
 }
 
+// notesParts - the footnotes, endnotes and comments parts the document has, in this order.
+var notesParts = []string{"footnotes", "endnotes", "comments"}
+
+// templateParts returns the parts with tags in the order they are executed: the headers and footers,
+// the body, then the footnotes, endnotes and comments that are present.
+func (d *Docx) templateParts() []string {
+	parts := append(d.ListHeaderFooterParts(), "document")
+	for _, part := range notesParts {
+		if _, ok := d.files["word/"+part+".xml"]; ok {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
 // ExecuteTemplate executes a document template using the data that is uploaded.
 func (d *Docx) ExecuteTemplate(data map[string]any) error {
-	parts := d.ListHeaderFooterParts()
-	parts = append(parts, "document")
+	parts := d.templateParts()
+	data = withMergeFieldNames(data)
+
+	d.sections = d.Sections()
+	d.rows = 0
+	defer func() { d.sections, d.section = nil, 0 }()
+
 	for _, part := range parts {
 		content, err := d.ContentPart(part)
 		if err != nil {
@@ -330,49 +439,151 @@ func (d *Docx) ExecuteTemplate(data map[string]any) error {
 				continue
 			}
 		}
+		d.emit(PartStarted{Part: part})
 
-		if content, err = d.RepairTags(content); err != nil {
-			return fmt.Errorf("repair tags (initial): %w", err)
+		source, content, err := d.transformPart(part, content, data)
+		if err != nil {
+			return err
 		}
 
-		content = d.ResolveIncludes(content, data)
-		content = d.ResolveTables(content, data)
-
-		if content, err = d.RepairTags(content); err != nil {
-			return fmt.Errorf("repair tags (after includes): %w", err)
+		// Section of the tags: headers/footers take the referencing section,
+		// the body switches it with markers after the section breaks.
+		if part == "document" {
+			d.sections, d.section = parseSections(content), 0 // [section ...] may have added sections
+		} else {
+			d.section = d.sectionOfPart(part, d.sections)
 		}
 
-		content = d.ProcessUnWrapParagraphTags(content)
-		content = d.ProcessTrimTags(content)
-
-		// Converting tags {var|mod} to {{ .var | mod }}
-		content = TransformTemplate(content)
-
-		d.ImportBuiltins()
-		funcMap := modifiers.NewFuncMap(modifiers.Options{
-			Fonts:      d.fonts,
-			Data:       data,
-			ExtraFuncs: d.extraFuncs,
-		})
-
-		tmpl, err := template.New("docx").
-			Delims("{", "}").
-			Funcs(funcMap).
-			Parse(content)
+		start := time.Now()
+		funcs := d.templateFuncs(data)
+		tmpl, err := d.parsePart(content, funcs)
 		if err != nil {
-			return fmt.Errorf("parse template: %w", err)
+			return fmt.Errorf("parse template: %w", locateParseError(part, source, content, funcs, err))
 		}
+		d.emitUnresolved(part, tmpl.Tree, data)
+		applyValueText(tmpl.Tree)
+		d.applyFormatRules(tmpl.Tree)
+		applyChainText(tmpl.Tree, funcs)
+		since(&d.timing.Parse, start)
 
+		start = time.Now()
 		var out bytes.Buffer
-		if err := tmpl.Execute(&out, data); err != nil {
+		if err := tmpl.Execute(d.partWriter(&out, part), data); err != nil {
+			return fmt.Errorf("execute template: %w", err)
+		}
+		if err, d.tableErr = d.tableErr, nil; err != nil {
 			return fmt.Errorf("execute template: %w", err)
 		}
 
-		d.UpdateContentPart(part, out.String())
+		result := inheritRunProps(collapseBlockBreakouts(out.String()))
+		if d.contentControls {
+			result = d.BindContentControls(result, data)
+		}
+		since(&d.timing.Execute, start)
+		d.UpdateContentPart(part, d.runStage(StageAfterExecute, part, result))
+		if err := d.checkTotalSize(); err != nil {
+			return fmt.Errorf("execute template: %w", err)
+		}
 	}
 	return nil
 }
 
+// transformPart repairs the tags of a part, resolves includes and tables and converts
+// the tags into the Go template text that ExecuteTemplate parses.
+// source is the part before the conversion, with the tags as the author wrote them.
+func (d *Docx) transformPart(part, content string, data map[string]any) (source, text string, err error) {
+	start := time.Now()
+	if !d.compiled {
+		content, err = d.RepairTags(content)
+		if err != nil {
+			return "", "", fmt.Errorf("repair tags (initial): %w", err)
+		}
+		content = ConvertMergeFields(content)
+	}
+	since(&d.timing.Repair, start)
+
+	content = d.runStage(StageBeforeIncludes, part, content)
+
+	start = time.Now()
+	content = d.ResolveIncludes(content, data)
+	content = d.ResolveAnnexes(content, data)
+	since(&d.timing.Includes, start)
+
+	start = time.Now()
+	content = d.ResolveBlocks(content, data)
+	content = d.ResolveSections(content, data)
+	content = d.ResolveTables(content, data)
+	content = d.ResolvePivots(content, data)
+	content = d.ResolveCalendars(content, data)
+	content = d.ResolveGantts(content, data)
+	content = d.ResolveOrgCharts(content, data)
+	content = d.ResolveSigners(content, data)
+	content = d.ResolveRequisites(content, data)
+	since(&d.timing.Tables, start)
+	if err, d.tableErr = d.tableErr, nil; err != nil {
+		return "", "", err
+	}
+
+	start = time.Now()
+	if content, err = d.RepairTags(content); err != nil {
+		return "", "", fmt.Errorf("repair tags (after includes): %w", err)
+	}
+	since(&d.timing.Repair, start)
+
+	content = d.runStage(StageAfterTables, part, content)
+
+	start = time.Now()
+	content = d.ProcessUnWrapParagraphTags(content)
+	source = d.ProcessTrimTags(content)
+	since(&d.timing.Repair, start)
+
+	// Converting tags {var|mod} to {{ .var | mod }}
+	start = time.Now()
+	text = TransformTemplate(source)
+
+	if part == "document" {
+		text = markSections(text)
+	}
+	since(&d.timing.Parse, start)
+	return source, text, nil
+}
+
+// templateFuncs returns all modifiers of the document for the template of one part.
+func (d *Docx) templateFuncs(data map[string]any) template.FuncMap {
+	d.ImportBuiltins()
+	opts := modifiers.Options{
+		Fonts:       d.fonts,
+		Data:        data,
+		Calendar:    d.calendar,
+		ExtraFuncs:  d.extraFuncs,
+		Limits:      d.modifierLimits,
+		Locale:      d.locale,
+		StrictTypes: d.strictTypes,
+	}
+	if d.warnings != nil {
+		opts.OnLossy = func(name string, err error) {
+			d.warn(WarnLossyCoercion, name, "%v", err)
+		}
+		opts.OnUnknownOption = func(name, option string) {
+			d.warn(WarnUnknownOption, name, "unknown option %q is ignored", option)
+		}
+	}
+	funcMap := modifiers.NewFuncMap(opts)
+	funcMap[sectionMarkerFunc] = d.sectionFunc
+	funcMap[formatRuleFunc] = d.formatValue
+	funcMap[valueTextFunc] = plainValue
+	funcMap[chainTextFunc] = chainText
+	return funcMap
+}
+
+// parseTemplate parses the transformed text of a part.
+func parseTemplate(text string, funcs template.FuncMap) (*template.Template, error) {
+	return template.New("docx").
+		Delims("{", "}").
+		Funcs(funcs).
+		Parse(text)
+}
+
 // ImportModifiers Adds a set of custom modifiers.
 func (d *Docx) ImportModifiers(mods map[string]modifiers.ModifierMeta) {
 	if d.extraFuncs == nil {
@@ -383,6 +594,12 @@ func (d *Docx) ImportModifiers(mods map[string]modifiers.ModifierMeta) {
 	}
 }
 
+// SetModifierLimits Sets the default time and output size limits of the custom modifiers
+// (ImportModifiers, AddModifier); a ModifierMeta with its own Limits overrides them.
+func (d *Docx) SetModifierLimits(limits modifiers.Limits) {
+	d.modifierLimits = limits
+}
+
 // AddModifier Adds one modifier.
 func (d *Docx) AddModifier(name string, fn any, args int) {
 	if d.extraFuncs == nil {
@@ -401,196 +618,119 @@ func (d *Docx) LoadFontsForPSplit(pathRegular, pathBold, pathItalic, pathBoldIta
 	return nil
 }
 
+// SetFonts Sets the font set for p_split, e.g. one found in a metrics.Registry.
+func (d *Docx) SetFonts(fonts *metrics.FontSet) {
+	d.fonts = fonts
+}
+
+// SetLocale sets the number locale of money, percent and unit: separators, grouping, currency symbol
+// (see modifiers.RegisterLocale). The data key "_locale" overrides it for one render.
+func (d *Docx) SetLocale(name string) error {
+	if _, ok := modifiers.LookupLocale(name); !ok {
+		return fmt.Errorf("unknown locale %q, available: %s", name, strings.Join(modifiers.Locales(), ", "))
+	}
+	d.locale = name
+	return nil
+}
+
+// SetStrictTypes turns the strict typing of modifier arguments on or off.
+// By default an argument that does not fit ({qty|truncate:`abc`:`…`}, "1.5" into an int) quietly
+// becomes the zero value or loses its fraction; strict, the render fails with modifiers.ErrLossyCoercion.
+// ExecuteTemplateResult reports such coercions as warnings either way.
+func (d *Docx) SetStrictTypes(strict bool) {
+	d.strictTypes = strict
+}
+
+// SetContentControls turns on or off the filling of the content controls (w:sdt) by ExecuteTemplate:
+// a template made of controls instead of {tags} gets the values of their tags, see BindContentControls.
+func (d *Docx) SetContentControls(on bool) {
+	d.contentControls = on
+}
+
+// SetChannel selects the output channel the document is rendered for ("print", "email", ...):
+// ExecuteTemplate keeps the [if-channel/...] blocks of that channel and removes the others.
+// Without a channel only the [if-channel/!name] blocks stay.
+func (d *Docx) SetChannel(name string) {
+	d.channel = name
+}
+
+// SetWorkCalendar Sets the production calendar used by add_days and next_workday.
+func (d *Docx) SetWorkCalendar(cal *modifiers.WorkCalendar) {
+	d.calendar = cal
+}
+
+// LoadWorkCalendar Loads the production calendar from a JSON file.
+func (d *Docx) LoadWorkCalendar(path string) error {
+	cal, err := modifiers.LoadWorkCalendar(path)
+	if err != nil {
+		return fmt.Errorf("load calendar: %w", err)
+	}
+	d.calendar = cal
+	return nil
+}
+
 //
 // ──────────────────────────── MEDIA ────────────────────────────
 //
 
 // AddImageRel adds an image and returns its rId + base name.
 func (d *Docx) AddImageRel(data []byte) (string, string) {
+	return d.AddMediaRel(data, "png")
+}
+
+// AddMediaRel adds a media file with the extension ext ("jpeg", "gif", ...) and returns its rId + base name.
+// The relationship and the content type are registered when the document is saved.
+func (d *Docx) AddMediaRel(data []byte, ext string) (string, string) {
 	hash := sha1.Sum(data)
 	base := fmt.Sprintf("%s_%x", d.activePart, hash)
-	filename := base + ".png"
+	filename := base + "." + strings.TrimPrefix(ext, ".")
 	rId := "rId_" + base
 
 	d.SetFile("word/media/"+filename, data)
+	if d.mediaParts == nil {
+		d.mediaParts = map[string]string{}
+	}
+	part := d.activePart
+	if part == "" {
+		part = "document"
+	}
+	d.mediaParts["word/media/"+filename] = part
 	return rId, base
 }
 
 // updateMediaRelationships Updates rels and MIME types for a set of media files.
+// The relationship id is "rId_" + file name without extension, as returned by AddImageRel.
 func (d *Docx) updateMediaRelationships(part string, filenames []string) {
-	var relsPath = fmt.Sprintf("word/_rels/%s.xml.rels", part)
-
-	// READ OR CREATE <Relationships>
-	relsData, _ := d.GetFile(relsPath)
-	if len(relsData) == 0 {
-		relsData = []byte(`<?xml version="1.0" encoding="UTF-8"?><Relationships></Relationships>`)
-	}
-
-	type Relationship struct {
-		ID     string `xml:"Id,attr"`
-		Type   string `xml:"Type,attr"`
-		Target string `xml:"Target,attr"`
-	}
-	type Relationships struct {
-		XMLName xml.Name       `xml:"Relationships"`
-		XMLNS   string         `xml:"xmlns,attr,omitempty"`
-		Items   []Relationship `xml:"Relationship"`
-	}
-
-	var rels Relationships
-	err := xml.Unmarshal(relsData, &rels)
-	if err != nil {
-		return
-	}
-	if rels.XMLNS == "" {
-		rels.XMLNS = "http://schemas.openxmlformats.org/package/2006/relationships"
-	}
-
-	existing := make(map[string]bool)
-	for _, r := range rels.Items {
-		existing[r.ID] = true
-	}
-
+	rels := d.Rels()
 	for _, name := range filenames {
-		ext := filepath.Ext(name)
-		base := strings.TrimSuffix(name, ext)
-		rId := "rId_" + base
-		if existing[rId] {
-			continue
-		}
-		rels.Items = append(rels.Items, Relationship{
-			ID:     rId,
-			Type:   "http://schemas.openxmlformats.org/officeDocument/2006/relationships/image",
-			Target: "media/" + name,
-		})
+		base := strings.TrimSuffix(name, filepath.Ext(name))
+		rels.Add(part, Relationship{ID: "rId_" + base, Type: RelTypeImage, Target: "media/" + name})
+		d.ContentTypes().AddDefaultForFile(name)
 	}
-
-	output, _ := xml.MarshalIndent(rels, "", "  ")
-	d.SetFile(relsPath, append([]byte(xml.Header), output...))
-	d.updateContentTypes(filenames)
 }
 
-// updateContentTypes adds MIME types to a set of images.
-func (d *Docx) updateContentTypes(filenames []string) {
-	const contentPath = "[Content_Types].xml"
-
-	data, _ := d.GetFile(contentPath)
-	if len(data) == 0 {
-		data = []byte(`<?xml version="1.0" encoding="UTF-8"?><Types></Types>`)
-	}
-
-	type Override struct {
-		PartName    string `xml:"PartName,attr"`
-		ContentType string `xml:"ContentType,attr"`
-	}
-	type Types struct {
-		XMLName   xml.Name   `xml:"Types"`
-		XMLNS     string     `xml:"xmlns,attr,omitempty"`
-		Overrides []Override `xml:"Override"`
-	}
-
-	var types Types
-	err := xml.Unmarshal(data, &types)
-	if err != nil {
-		return
-	}
-	if types.XMLNS == "" {
-		types.XMLNS = "http://schemas.openxmlformats.org/package/2006/content-types"
-	}
-
-	mime := map[string]string{
-		"png":  "image/png",
-		"jpg":  "image/jpeg",
-		"jpeg": "image/jpeg",
-		"gif":  "image/gif",
-		"bmp":  "image/bmp",
-		"tif":  "image/tiff",
-		"tiff": "image/tiff",
-		"svg":  "image/svg+xml",
-	}
-
-	exists := make(map[string]struct{})
-	for _, o := range types.Overrides {
-		exists[o.PartName] = struct{}{}
-	}
-
-	for _, file := range filenames {
-		part := "/word/media/" + file
-		if _, ok := exists[part]; ok {
-			continue
-		}
-		ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(file), "."))
-		ct := mime[ext]
-		if ct == "" {
-			ct = "application/octet-stream"
-		}
-		types.Overrides = append(types.Overrides, Override{
-			PartName:    part,
-			ContentType: ct,
-		})
-	}
-
-	out, _ := xml.MarshalIndent(types, "", "  ")
-	d.SetFile(contentPath, append([]byte(xml.Header), out...))
+// Checksum - the SHA-256 (hex) of the document as Save and SaveToWriter write it: the packing is
+// reproducible, so a registration system may compare it with the hash of the delivered file.
+// The document is packed for it, the Save stage of Timing is not affected.
+func (d *Docx) Checksum() (string, error) {
+	saveTime := d.timing.Save
+	defer func() { d.timing.Save = saveTime }()
+	h := sha256.New()
+	if err := d.writeZip(h); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
 // SaveToWriter - Writes the current DOCX document directly to the stream (e.g. http. ResponseWriter).
 // Repeats the Save() logic, but does not write to a temporary file.
 func (d *Docx) SaveToWriter(w io.Writer) error {
 	buffer := new(bytes.Buffer)
-	writer := zip.NewWriter(buffer)
-
-	// 1. Combining all media files into a single card
-	mediaByPart := map[string][]string{}
-	globalMedia.ForEach(func(filename string, data []byte) {
-		d.files[filename] = data
-
-		mediaName := strings.TrimPrefix(filename, "word/media/")
-		parts := strings.SplitN(mediaName, "_", 2)
-		part := "document"
-		if len(parts) > 1 {
-			switch {
-			case strings.HasPrefix(parts[0], "header"):
-				part = parts[0]
-			case strings.HasPrefix(parts[0], "footer"):
-				part = parts[0]
-			}
-		}
-		mediaByPart[part] = append(mediaByPart[part], mediaName)
-	})
-
-	// 2. Update rels and [Content_Types].xml
-	for part, names := range mediaByPart {
-		d.updateMediaRelationships(part, names)
-	}
-
-	// 3. Create a ZIP archive
-	for name, data := range d.files {
-		name = strings.TrimPrefix(name, "/")
-		name = strings.ReplaceAll(name, "\\", "/")
-		if strings.TrimSpace(name) == "" {
-			continue
-		}
-
-		header := &zip.FileHeader{
-			Name:     name,
-			Method:   zip.Deflate,
-			Modified: time.Now().UTC(),
-		}
-		writerFile, err := writer.CreateHeader(header)
-		if err != nil {
-			return fmt.Errorf("create entry %s: %w", name, err)
-		}
-		if _, err := writerFile.Write(data); err != nil {
-			return fmt.Errorf("write entry %s: %w", name, err)
-		}
-	}
-
-	if err := writer.Close(); err != nil {
-		return fmt.Errorf("close zip: %w", err)
+	if err := d.writeZip(buffer); err != nil {
+		return err
 	}
 
-	// 4. Giving the result to the stream
+	// Giving the result to the stream
 	if _, err := io.Copy(w, buffer); err != nil {
 		return fmt.Errorf("write to stream: %w", err)
 	}
@@ -0,0 +1,235 @@
+package daemon
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"docxgen"
+	"docxgen/pdf"
+)
+
+// ---------- batch ----------
+
+// Progress - the state of a batch render: how many documents are done
+// and what the current one is doing (part, table, include, save, pdf).
+type Progress struct {
+	Done  int    `json:"done"`
+	Total int    `json:"total"`
+	Name  string `json:"name"`
+	Stage string `json:"stage"`
+}
+
+// File - one rendered document of the batch.
+type File struct {
+	Name string
+	Data []byte
+}
+
+// ParseBatchData - the data of a batch: a JSON array of objects, one document per object.
+func ParseBatchData(raw []byte) ([]map[string]any, error) {
+	var items []map[string]any
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("batch: ожидается JSON-массив объектов: %w", err)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("batch: пустой массив данных")
+	}
+	return items, nil
+}
+
+// BatchName - the file name of the n-th file (1-based) with the extension: base_001.docx.
+func BatchName(base string, n, total int, ext string) string {
+	width := len(fmt.Sprint(total))
+	if width < 3 {
+		width = 3
+	}
+	return fmt.Sprintf("%s_%0*d%s", base, width, n, ext)
+}
+
+// RenderBatch renders one document per data item. open returns a fresh template for every item;
+// with a converter the documents are converted to PDF. report (if set) receives the progress
+// after every stage, synchronously. The cancellation of ctx stops the batch between documents.
+func RenderBatch(ctx context.Context, open func() (*docxgen.Docx, error), items []map[string]any, base string,
+	conv pdf.Converter, report func(Progress)) ([]File, error) {
+	if report == nil {
+		report = func(Progress) {}
+	}
+	ext := ".docx"
+	if conv != nil {
+		ext = ".pdf"
+	}
+	files := make([]File, 0, len(items))
+	for i, data := range items {
+		p := Progress{Done: i, Total: len(items), Name: BatchName(base, i+1, len(items), ext), Stage: "open"}
+		report(p)
+		if err := ctx.Err(); err != nil {
+			return files, err
+		}
+
+		doc, err := open()
+		if err != nil {
+			return files, fmt.Errorf("%s: %w", p.Name, err)
+		}
+		doc.Observe(func(e docxgen.Event) {
+			switch ev := e.(type) {
+			case docxgen.PartStarted:
+				p.Stage = ev.Part
+			case docxgen.TableRendered:
+				p.Stage = "table/" + ev.Name
+			case docxgen.IncludeResolved:
+				p.Stage = "include/" + ev.File
+			default:
+				return
+			}
+			report(p)
+		})
+		if err := doc.ExecuteTemplate(data); err != nil {
+			return files, fmt.Errorf("%s: шаблон: %w", p.Name, err)
+		}
+
+		p.Stage = "save"
+		report(p)
+		var buf bytes.Buffer
+		if err := doc.SaveToWriter(&buf); err != nil {
+			return files, fmt.Errorf("%s: сохранение: %w", p.Name, err)
+		}
+		out := buf.Bytes()
+		if conv != nil {
+			p.Stage = "pdf"
+			report(p)
+			if out, err = conv.Convert(ctx, out); err != nil {
+				return files, fmt.Errorf("%s: %w", p.Name, err)
+			}
+		}
+		files = append(files, File{Name: p.Name, Data: out})
+	}
+	report(Progress{Done: len(items), Total: len(items), Stage: "done"})
+	return files, nil
+}
+
+// FileDigest - an entry of the manifest of a batch: the name, the size and the SHA-256 (hex) of a document.
+type FileDigest struct {
+	Name   string `json:"name"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// ManifestName - the manifest of the documents in the zip of /batch.
+const ManifestName = "manifest.json"
+
+// Manifest lists the checksums of the documents of the batch.
+func Manifest(files []File) []FileDigest {
+	out := make([]FileDigest, len(files))
+	for i, f := range files {
+		sum := sha256.Sum256(f.Data)
+		out[i] = FileDigest{Name: f.Name, Size: len(f.Data), SHA256: hex.EncodeToString(sum[:])}
+	}
+	return out
+}
+
+// withManifest - the documents and their manifest.json, for the zip of /batch.
+func withManifest(files []File) []File {
+	manifest, _ := json.MarshalIndent(Manifest(files), "", "  ")
+	return append(slices.Clip(files), File{Name: ManifestName, Data: manifest})
+}
+
+// ZipFiles packs the documents of the batch into one archive.
+func ZipFiles(w io.Writer, files []File) error {
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		fw, err := zw.Create(f.Name)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(f.Data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// batch — POST /batch {"template": ..., "items": [{...}, ...], "format": "pdf"}.
+// Returns a zip with a document per item and manifest.json with their checksums (see FileDigest).
+// With "Accept: text/event-stream" the progress is streamed as SSE: "event: progress" with Progress,
+// then "event: done" with the zip in base64 and the manifest in "files" (or "event: error").
+func (cfg Config) batch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Template string           `json:"template"`
+		Items    []map[string]any `json:"items"`
+		Format   string           `json:"format,omitempty"`
+	}
+	if !cfg.decodeRequest(w, r, &req) {
+		return
+	}
+	if len(req.Items) == 0 {
+		jsonErr(w, 400, "items are required: an array of data objects, one per document")
+		return
+	}
+	tpl, code, err := cfg.preparedTemplate(req.Template)
+	if err != nil {
+		jsonErr(w, code, "%v", err)
+		return
+	}
+
+	open := func() (*docxgen.Docx, error) { return tpl.Docx(), nil }
+	var conv pdf.Converter
+	if strings.EqualFold(req.Format, "pdf") {
+		if cfg.PDF == nil {
+			jsonErr(w, 501, "pdf is not available")
+			return
+		}
+		conv = cfg.PDF
+	}
+
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		files, err := RenderBatch(r.Context(), open, req.Items, "result", conv, nil)
+		if err != nil {
+			jsonErr(w, renderStatus(err), "%v", err)
+			return
+		}
+		var buf bytes.Buffer
+		if err := ZipFiles(&buf, withManifest(files)); err != nil {
+			jsonErr(w, 500, "%v", err)
+			return
+		}
+		setDigest(w, buf.Bytes())
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="result.zip"`)
+		_, _ = w.Write(buf.Bytes())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	send := func(event string, v any) {
+		b, _ := json.Marshal(v)
+		_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b)
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+
+	files, err := RenderBatch(r.Context(), open, req.Items, "result", conv, func(p Progress) {
+		send("progress", p)
+	})
+	if err != nil {
+		send("error", map[string]string{"error": err.Error()})
+		return
+	}
+	var buf bytes.Buffer
+	if err := ZipFiles(&buf, withManifest(files)); err != nil {
+		send("error", map[string]string{"error": err.Error()})
+		return
+	}
+	send("done", map[string]any{"zip": base64.StdEncoding.EncodeToString(buf.Bytes()), "files": Manifest(files)})
+}
@@ -0,0 +1,51 @@
+package daemon
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"docxgen"
+)
+
+// templateCache - the prepared template files of the handler: a file is opened and prepared once
+// and renders in parallel (see docxgen.Template); a changed file is opened again.
+type templateCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedTemplate
+}
+
+type cachedTemplate struct {
+	modTime time.Time
+	size    int64
+	tpl     *docxgen.Template
+}
+
+func newTemplateCache() *templateCache {
+	return &templateCache{entries: map[string]cachedTemplate{}}
+}
+
+// get returns the template of the file, open opens and prepares it when it is new or changed.
+func (c *templateCache) get(path string, open func() (*docxgen.Docx, error)) (*docxgen.Template, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	e, ok := c.entries[path]
+	c.mu.Unlock()
+	if ok && e.modTime.Equal(fi.ModTime()) && e.size == fi.Size() {
+		return e.tpl, nil
+	}
+
+	// two requests may open a new file at once, the last one stays
+	doc, err := open()
+	if err != nil {
+		return nil, err
+	}
+	tpl := docxgen.NewTemplate(doc)
+	c.mu.Lock()
+	c.entries[path] = cachedTemplate{modTime: fi.ModTime(), size: fi.Size(), tpl: tpl}
+	c.mu.Unlock()
+	return tpl, nil
+}
@@ -0,0 +1,638 @@
+// Package daemon is the HTTP API of docxgen (POST /generate, POST /batch) as a reusable http.Handler.
+//
+// The handler can be mounted under an application router together with its own middleware:
+//
+//	h := daemon.NewHandler(daemon.Config{
+//		TemplateRoot: "/srv/templates",
+//		Prepare: func(doc *docxgen.Docx) error {
+//			doc.ImportModifiers(myModifiers)
+//			return nil
+//		},
+//	})
+//	mux.Handle("/docs/", http.StripPrefix("/docs", h))
+//
+// or run standalone with Server.
+//
+// One handler can serve several departments: with Config.Tenants every request is bound to
+// a tenant by its API key (or a header of a trusted proxy) and sees only the templates, fonts
+// and modifiers of that tenant, within its rate limit (see Tenant).
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"docxgen"
+	"docxgen/geometry"
+	"docxgen/metrics"
+	"docxgen/pdf"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+)
+
+// Config - settings of the handler.
+//   - TemplateRoot — the directory for relative template paths ("blocks/act.docx"),
+//     its subdirectory main/ is checked too;
+//   - Skeleton — a DOCX used as the package when the template is passed as <w:document> XML;
+//   - Prepare — called for an opened template before execution: fonts, modifiers, calendar.
+//     A template file is prepared once and kept while the file is unchanged, parallel requests
+//     render copies of it (see docxgen.Template); an uploaded template is prepared on every request;
+//   - PDF — the converter for "format": "pdf" (nil — PDF is not available);
+//   - Pages — the rasterizer for "format": "png", a zip of page images (nil — not available, needs PDF too);
+//   - Fonts — the font sets for the "fonts" field of /generate: p_split measures text with the set
+//     the template was designed in (nil — only the fonts set by Prepare);
+//   - Limits — the size limits of the templates and the renders (zero — none); a request beyond them
+//     gets 413 instead of the daemon running out of memory;
+//   - MaxBodySize — the largest request body in bytes, the JSON with the template and the data
+//     (zero — none); a larger one gets 413 before it is read to the end;
+//   - Logger — the debug log of the stage times of every render (nil — none); the times are also
+//     returned in the Server-Timing header of /generate;
+//   - Tenants — the departments of a shared daemon (nil — one for all requests): a request is served
+//     with the template root, fonts and modifiers of its tenant, without a known API key it gets 401;
+//   - TenantHeader — the header with the tenant name ("X-Tenant") that a trusted proxy sets after
+//     its own authentication (empty — API keys only); a client must not be able to set it.
+type Config struct {
+	TemplateRoot string
+	Skeleton     string
+	Prepare      func(doc *docxgen.Docx) error
+	PDF          pdf.Converter
+	Pages        pdf.PageRasterizer
+	Fonts        *metrics.Registry
+	Limits       docxgen.SizeLimits
+	MaxBodySize  int64
+	Logger       *slog.Logger
+	Tenants      []Tenant
+	TenantHeader string
+
+	templates *templateCache
+	confined  bool // the templates only under TemplateRoot (of a tenant)
+}
+
+// NewHandler returns the handler with the routes /generate and /batch on its own mux;
+// with Tenants — the routes of every tenant behind the authentication.
+func NewHandler(cfg Config) http.Handler {
+	if len(cfg.Tenants) > 0 {
+		return newTenantRouter(cfg)
+	}
+	return routes(cfg)
+}
+
+// routes - the mux of /generate and /batch with its own template cache.
+func routes(cfg Config) http.Handler {
+	cfg.templates = newTemplateCache()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/generate", cfg.generate)
+	mux.HandleFunc("/batch", cfg.batch)
+	return mux
+}
+
+// Server - the standalone daemon: the handler of NewHandler on its own http.Server.
+//
+// Example:
+//
+//	srv := &daemon.Server{Addr: ":8080", Config: cfg}
+//	go srv.ListenAndServe()
+//	...
+//	srv.Shutdown(ctx)
+type Server struct {
+	Addr   string
+	Config Config
+
+	mu      sync.Mutex
+	srv     *http.Server
+	started bool // ListenAndServe was called
+	closed  bool // Shutdown came before ListenAndServe
+}
+
+// server returns the http.Server of the daemon, created once; s.mu is held.
+func (s *Server) server() *http.Server {
+	if s.srv == nil {
+		s.srv = &http.Server{
+			Addr:              s.Addr,
+			Handler:           NewHandler(s.Config),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+	}
+	return s.srv
+}
+
+// ListenAndServe starts the daemon; after Shutdown it returns nil, and http.ErrServerClosed
+// at once if Shutdown came first.
+func (s *Server) ListenAndServe() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return http.ErrServerClosed
+	}
+	srv := s.server()
+	s.started = true
+	s.mu.Unlock()
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops the daemon gracefully, waiting for the current requests; a daemon not started yet
+// will not start.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	srv := s.server()
+	if !s.started {
+		s.closed = true
+	}
+	s.mu.Unlock()
+	return srv.Shutdown(ctx)
+}
+
+// StampRequest - the "stamp" field of /generate: a registration stamp over the produced PDF.
+//   - Lines — text lines of the stamp, {page} and {pages} are the page number and the number of pages
+//     (at most pdf.MaxStampLines lines of pdf.MaxStampLineLen characters);
+//   - QR — the text of the QR code (empty — without QR);
+//   - Date — the date line: "YYYY-MM-DD" or "today" (empty — without date);
+//   - Pages — "first" (by default), "last" or "all";
+//   - Corner — "bottom-right" (by default), "bottom-left", "top-right", "top-left";
+//   - Width — the width of the stamp in mm (0 — 60mm), at most the page width and pdf.MaxStampWidth.
+type StampRequest struct {
+	Lines  []string `json:"lines,omitempty"`
+	QR     string   `json:"qr,omitempty"`
+	Date   string   `json:"date,omitempty"`
+	Pages  string   `json:"pages,omitempty"`
+	Corner string   `json:"corner,omitempty"`
+	Width  float64  `json:"width,omitempty"`
+}
+
+// Stamp converts the request to pdf.Stamp.
+func (s StampRequest) Stamp() (pdf.Stamp, error) {
+	if s.Width < 0 || s.Width > pdf.MaxStampWidth.MM() {
+		return pdf.Stamp{}, fmt.Errorf("stamp: width %gmm, at most %s", s.Width, pdf.MaxStampWidth)
+	}
+	st := pdf.Stamp{
+		Lines:  s.Lines,
+		QR:     s.QR,
+		Pages:  pdf.StampPages(s.Pages),
+		Corner: s.Corner,
+		Width:  geometry.FromMM(s.Width),
+	}
+	if err := st.Validate(); err != nil {
+		return st, fmt.Errorf("stamp: %w", err)
+	}
+	switch st.Pages {
+	case "", pdf.StampFirstPage, pdf.StampLastPage, pdf.StampEveryPage:
+	default:
+		return st, fmt.Errorf("stamp: unknown pages %q", s.Pages)
+	}
+	switch s.Date {
+	case "":
+	case "today":
+		st.Date = time.Now()
+	default:
+		d, err := time.Parse("2006-01-02", s.Date)
+		if err != nil {
+			return st, fmt.Errorf("stamp: bad date %q", s.Date)
+		}
+		st.Date = d
+	}
+	return st, nil
+}
+
+// generate — POST /generate {"template": ..., "data": {...}, "format": "docx|pdf|xml|png|template", "stamp": {...}, "brand": ...}.
+// "brand" is a DOCX/DOTX brand pack (path or base64) whose styles and theme are merged into the template;
+// "rules" is conditional formatting: [{"field": "balance", "when": "< 0", "color": "C00000"}];
+// "fonts" is the font set ID or family for p_split ("PTSerif", "PT Serif"), see Config.Fonts.
+// "format": "template" returns the transformed Go template of every part with line numbers
+// instead of the document (see docxgen.TransformedTemplates).
+func (cfg Config) generate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Template string               `json:"template"`
+		Data     map[string]any       `json:"data,omitempty"`
+		Format   string               `json:"format,omitempty"`
+		Stamp    *StampRequest        `json:"stamp,omitempty"`
+		Brand    string               `json:"brand,omitempty"`
+		Rules    []docxgen.FormatRule `json:"rules,omitempty"`
+		Fonts    string               `json:"fonts,omitempty"`
+	}
+	if !cfg.decodeRequest(w, r, &req) {
+		return
+	}
+	var stamp *pdf.Stamp
+	if req.Stamp != nil {
+		st, err := req.Stamp.Stamp()
+		if err != nil {
+			jsonErr(w, 400, "%v", err)
+			return
+		}
+		stamp = &st
+	}
+	if strings.TrimSpace(req.Template) == "" {
+		jsonErr(w, 400, "template is required: pass a file path, base64 DOCX, or <w:document> xml")
+		return
+	}
+
+	var doc *docxgen.Docx
+	if strings.HasPrefix(strings.TrimSpace(req.Template), "<w:") {
+		// you need a docx "skeleton"; use any valid in the project
+		skeleton, err := docxgen.Open(cfg.Skeleton, docxgen.WithSizeLimits(cfg.Limits))
+		if err != nil {
+			jsonErr(w, 500, "template skeleton error: %v", err)
+			return
+		}
+		skeleton.UpdateContentPart("document", req.Template)
+		doc = skeleton
+		if err := cfg.prepare(doc); err != nil {
+			jsonErr(w, 500, "%v", err)
+			return
+		}
+	} else {
+		tpl, code, err := cfg.preparedTemplate(req.Template)
+		if err != nil {
+			jsonErr(w, code, "%v", err)
+			return
+		}
+		doc = tpl.Docx()
+	}
+
+	if req.Fonts != "" {
+		if cfg.Fonts == nil {
+			jsonErr(w, 501, "font sets are not configured")
+			return
+		}
+		fonts, err := cfg.Fonts.Lookup(req.Fonts)
+		if err != nil {
+			jsonErr(w, 400, "%v", err)
+			return
+		}
+		doc.SetFonts(fonts)
+	}
+	if len(req.Rules) > 0 {
+		if err := doc.SetFormatRules(req.Rules); err != nil {
+			jsonErr(w, 400, "%v", err)
+			return
+		}
+	}
+	if req.Brand != "" {
+		open, code, err := cfg.templateSource(req.Brand)
+		if err != nil {
+			jsonErr(w, code, "brand: %v", err)
+			return
+		}
+		src, err := open()
+		if err != nil {
+			jsonErr(w, 400, "brand pack: %v", err)
+			return
+		}
+		pack, err := docxgen.NewBrandPack(src, docxgen.StyleMerge)
+		if err != nil {
+			jsonErr(w, 400, "brand pack: %v", err)
+			return
+		}
+		if err := doc.ApplyBrand(pack); err != nil {
+			jsonErr(w, 400, "%v", err)
+			return
+		}
+	}
+	if strings.EqualFold(req.Format, "template") {
+		parts, err := doc.TransformedTemplates(req.Data)
+		if err != nil {
+			jsonErr(w, 500, "шаблон: %v", err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_ = docxgen.WriteTransformed(w, parts)
+		return
+	}
+	if err := doc.ExecuteTemplate(req.Data); err != nil {
+		jsonErr(w, renderStatus(err), "шаблон: %v", err)
+		return
+	}
+
+	switch strings.ToLower(req.Format) {
+	case "xml":
+		xml, _ := doc.ContentPart("document")
+		cfg.reportTiming(w, req.Template, doc.Timing())
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		_, _ = w.Write([]byte(xml))
+
+	case "pdf":
+		if cfg.PDF == nil {
+			jsonErr(w, 501, "pdf is not available")
+			return
+		}
+		var buf bytes.Buffer
+		if err := doc.SaveToWriter(&buf); err != nil {
+			jsonErr(w, 500, "save error: %v", err)
+			return
+		}
+		start := time.Now()
+		out, err := cfg.PDF.Convert(r.Context(), buf.Bytes())
+		if err != nil {
+			jsonErr(w, 500, "pdf error: %v", err)
+			return
+		}
+		if stamp != nil {
+			if out, err = pdf.ApplyStamp(out, *stamp); err != nil {
+				code := 500
+				if errors.Is(err, pdf.ErrStampTooLarge) {
+					code = 400
+				}
+				jsonErr(w, code, "stamp error: %v", err)
+				return
+			}
+		}
+		timing := doc.Timing()
+		timing.PDF = time.Since(start)
+		cfg.reportTiming(w, req.Template, timing)
+		setDigest(w, out)
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", `attachment; filename="result.pdf"`)
+		_, _ = w.Write(out)
+
+	case "png":
+		if cfg.PDF == nil || cfg.Pages == nil {
+			jsonErr(w, 501, "png is not available")
+			return
+		}
+		var buf bytes.Buffer
+		if err := doc.SaveToWriter(&buf); err != nil {
+			jsonErr(w, 500, "save error: %v", err)
+			return
+		}
+		start := time.Now()
+		files, err := PageImages(r.Context(), cfg.PDF, cfg.Pages, buf.Bytes(), "page")
+		if err != nil {
+			jsonErr(w, 500, "png error: %v", err)
+			return
+		}
+		timing := doc.Timing()
+		timing.PDF = time.Since(start) // with the pages to PNG
+		cfg.reportTiming(w, req.Template, timing)
+		var zipped bytes.Buffer
+		if err := ZipFiles(&zipped, files); err != nil {
+			jsonErr(w, 500, "png error: %v", err)
+			return
+		}
+		setDigest(w, zipped.Bytes())
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="pages.zip"`)
+		_, _ = w.Write(zipped.Bytes())
+
+	default:
+		// the archive is packed before the headers, so Server-Timing has the save
+		var buf bytes.Buffer
+		if err := doc.SaveToWriter(&buf); err != nil {
+			jsonErr(w, 500, "save error: %v", err)
+			return
+		}
+		cfg.reportTiming(w, req.Template, doc.Timing())
+		setDigest(w, buf.Bytes())
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.wordprocessingml.document")
+		w.Header().Set("Content-Disposition", `attachment; filename="result.docx"`)
+		_, _ = w.Write(buf.Bytes())
+	}
+}
+
+// setDigest puts the SHA-256 of the response body into the headers: Content-Digest (RFC 9530)
+// and X-Content-SHA256 in hex, as the registration systems store it (see docxgen.Docx.Checksum).
+func setDigest(w http.ResponseWriter, body []byte) {
+	sum := sha256.Sum256(body)
+	w.Header().Set("Content-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(sum[:])+":")
+	w.Header().Set("X-Content-SHA256", hex.EncodeToString(sum[:]))
+}
+
+// reportTiming puts the stage times of the render into the Server-Timing header and the debug log.
+func (cfg Config) reportTiming(w http.ResponseWriter, tmpl string, t docxgen.Timing) {
+	if v := t.ServerTiming(); v != "" {
+		w.Header().Set("Server-Timing", v)
+	}
+	if cfg.Logger == nil {
+		return
+	}
+	name := "inline"
+	if path, _, err := cfg.templatePath(tmpl); err == nil && path != "" {
+		name = path
+	}
+	cfg.Logger.Debug("render timing", "template", name,
+		"unzip", t.Unzip, "repair", t.Repair, "includes", t.Includes, "tables", t.Tables,
+		"parse", t.Parse, "execute", t.Execute, "save", t.Save, "pdf", t.PDF, "total", t.Total())
+}
+
+// PageImages converts the DOCX to PDF and renders its pages to PNG files base_001.png, base_002.png, ...
+func PageImages(ctx context.Context, conv pdf.Converter, pages pdf.PageRasterizer, docx []byte, base string) ([]File, error) {
+	out, err := conv.Convert(ctx, docx)
+	if err != nil {
+		return nil, err
+	}
+	images, err := pages.Pages(ctx, out)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]File, len(images))
+	for i, img := range images {
+		files[i] = File{Name: BatchName(base, i+1, len(images), ".png"), Data: img}
+	}
+	return files, nil
+}
+
+// open opens the template and prepares it for execution.
+func (cfg Config) open(open func() (*docxgen.Docx, error)) (*docxgen.Docx, error) {
+	doc, err := open()
+	if err != nil {
+		return nil, fmt.Errorf("template open error: %w", err)
+	}
+	return doc, cfg.prepare(doc)
+}
+
+func (cfg Config) prepare(doc *docxgen.Docx) error {
+	if cfg.Prepare == nil {
+		return nil
+	}
+	return cfg.Prepare(doc)
+}
+
+// preparedTemplate opens and prepares the template of a request; a template file comes from the cache.
+// code is the HTTP status for the error.
+func (cfg Config) preparedTemplate(tmpl string) (*docxgen.Template, int, error) {
+	path, code, err := cfg.templatePath(tmpl)
+	if err != nil {
+		return nil, code, err
+	}
+	if path != "" && cfg.templates != nil {
+		tpl, err := cfg.templates.get(path, func() (*docxgen.Docx, error) {
+			return cfg.open(func() (*docxgen.Docx, error) { return cfg.openFile(path) })
+		})
+		if err != nil {
+			return nil, renderStatus(err), err
+		}
+		return tpl, 0, nil
+	}
+
+	open, code, err := cfg.templateSource(tmpl)
+	if err != nil {
+		return nil, code, err
+	}
+	doc, err := cfg.open(open)
+	if err != nil {
+		return nil, renderStatus(err), err
+	}
+	return docxgen.NewTemplate(doc), 0, nil
+}
+
+// templateSource resolves the "template" field of a request to an opener of the DOCX: an existing path,
+// a path relative to TemplateRoot (or its main/), or base64 DOCX opened in memory.
+// code is the HTTP status for the error.
+func (cfg Config) templateSource(tmpl string) (open func() (*docxgen.Docx, error), code int, err error) {
+	path, code, err := cfg.templatePath(tmpl)
+	if err != nil {
+		return nil, code, err
+	}
+	if path != "" {
+		return func() (*docxgen.Docx, error) { return cfg.openFile(path) }, 0, nil
+	}
+
+	raw, decErr := base64.StdEncoding.DecodeString(tmpl)
+	if decErr != nil {
+		return nil, 400, fmt.Errorf("template: not a path, not xml, and bad base64: %v", decErr)
+	}
+	return func() (*docxgen.Docx, error) { return docxgen.OpenBytes(raw, docxgen.WithSizeLimits(cfg.Limits)) }, 0, nil
+}
+
+// renderStatus - the HTTP status of a failed open or render: 413 for a template or data beyond
+// Config.Limits, 500 otherwise.
+func renderStatus(err error) int {
+	if errors.Is(err, docxgen.ErrLimitExceeded) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return 500
+}
+
+// openFile opens the template file, a compiled one (.docxgenc) with docxgen.OpenCompiled.
+func (cfg Config) openFile(path string) (*docxgen.Docx, error) {
+	if strings.EqualFold(filepath.Ext(path), docxgen.CompiledExt) {
+		return docxgen.OpenCompiled(path, docxgen.WithSizeLimits(cfg.Limits))
+	}
+	return docxgen.Open(path, docxgen.WithSizeLimits(cfg.Limits))
+}
+
+// templatePath resolves the "template" field to a file: an existing path or a path relative
+// to TemplateRoot (or its main/); empty when it is not a path. A template compiled next to the
+// file (act.docx — act.docxgenc, not older than it) is taken instead of it. For a tenant only
+// the files under its TemplateRoot are found.
+func (cfg Config) templatePath(tmpl string) (path string, code int, err error) {
+	switch {
+	case strings.TrimSpace(tmpl) == "":
+		return "", 400, fmt.Errorf("template is required: pass a file path or base64 DOCX")
+	case cfg.confined:
+		if !hasAnySuffix(strings.ToLower(tmpl), ".docx", ".docm", ".dotx", docxgen.CompiledExt) {
+			break
+		}
+		if path = cfg.confinedPath(tmpl); path == "" {
+			return "", 400, fmt.Errorf("file not found: %s", tmpl)
+		}
+	case fileExists(tmpl):
+		path = tmpl
+	case hasAnySuffix(strings.ToLower(tmpl), ".docx", ".docm", ".dotx", docxgen.CompiledExt):
+		for _, candidate := range []string{filepath.Join(cfg.TemplateRoot, tmpl), filepath.Join(cfg.TemplateRoot, "main", tmpl)} {
+			if fileExists(candidate) {
+				path = candidate
+				break
+			}
+		}
+		if path == "" {
+			return "", 400, fmt.Errorf("file not found: %s", tmpl)
+		}
+	}
+	return compiledPath(path), 0, nil
+}
+
+// confinedPath - the template file under TemplateRoot (or its main/), empty if there is none:
+// an absolute path is taken relative to the root, ".." does not leave it.
+func (cfg Config) confinedPath(tmpl string) string {
+	root, err := filepath.Abs(cfg.TemplateRoot)
+	if err != nil {
+		return ""
+	}
+	if filepath.IsAbs(tmpl) {
+		rel, err := filepath.Rel(root, tmpl)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return ""
+		}
+		tmpl = rel
+	}
+	for _, dir := range []string{root, filepath.Join(root, "main")} {
+		if candidate, err := securejoin.SecureJoin(dir, tmpl); err == nil && fileExists(candidate) {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// ---------- helpers ----------
+
+// compiledPath - the compiled template next to the file, if it is there and not older; the path otherwise.
+func compiledPath(path string) string {
+	if path == "" || strings.EqualFold(filepath.Ext(path), docxgen.CompiledExt) {
+		return path
+	}
+	src, err := os.Stat(path)
+	if err != nil {
+		return path
+	}
+	compiled := strings.TrimSuffix(path, filepath.Ext(path)) + docxgen.CompiledExt
+	if fi, err := os.Stat(compiled); err == nil && !fi.IsDir() && !fi.ModTime().Before(src.ModTime()) {
+		return compiled
+	}
+	return path
+}
+
+func fileExists(p string) bool {
+	fi, err := os.Stat(p)
+	return err == nil && !fi.IsDir()
+}
+
+func hasAnySuffix(s string, exts ...string) bool {
+	for _, e := range exts {
+		if strings.HasSuffix(s, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeRequest reads the JSON body into req within MaxBodySize; on failure it writes the error
+// (400, 413 for a body beyond the limit) and returns false.
+func (cfg Config) decodeRequest(w http.ResponseWriter, r *http.Request, req any) bool {
+	body := r.Body
+	if cfg.MaxBodySize > 0 {
+		body = http.MaxBytesReader(w, r.Body, cfg.MaxBodySize)
+	}
+	err := json.NewDecoder(body).Decode(req)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		jsonErr(w, http.StatusRequestEntityTooLarge, "request body is larger than %d bytes", tooLarge.Limit)
+	case err != nil:
+		jsonErr(w, 400, "invalid json: %v", err)
+	}
+	return err == nil
+}
+
+func jsonErr(w http.ResponseWriter, code int, format string, a ...any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	b, _ := json.Marshal(map[string]string{"error": fmt.Sprintf(format, a...)})
+	_, _ = w.Write(b)
+}
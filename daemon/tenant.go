@@ -0,0 +1,183 @@
+package daemon
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"docxgen"
+	"docxgen/metrics"
+)
+
+// ---------- tenants ----------
+
+// Tenant - a department served by a shared daemon (Config.Tenants): its requests see only its own
+// templates, fonts and modifiers.
+//   - Name — the ID of the tenant, the value of Config.TenantHeader;
+//   - Keys — the API keys of the tenant: "Authorization: Bearer <key>" or "X-API-Key: <key>";
+//   - TemplateRoot — the templates of the tenant: the relative paths resolve under it (and its main/),
+//     a path outside it is not found even if the file exists;
+//   - Prepare — the modifiers, fonts and calendar of the tenant, called instead of Config.Prepare
+//     (nil — Config.Prepare);
+//   - Fonts — the font sets of the "fonts" field (nil — Config.Fonts);
+//   - Rate, Burst — the requests per second of the tenant and how many can come at once above it;
+//     the requests beyond get 429 (zero Rate — no limit, zero Burst — one second of Rate).
+type Tenant struct {
+	Name         string
+	Keys         []string
+	TemplateRoot string
+	Prepare      func(doc *docxgen.Docx) error
+	Fonts        *metrics.Registry
+	Rate         float64
+	Burst        int
+}
+
+// tenantHandler - the routes of a tenant with its own config and template cache.
+type tenantHandler struct {
+	Tenant
+	handler http.Handler
+	limit   *rateLimiter
+}
+
+// tenantRouter - the handler of a daemon with tenants: finds the tenant of the request
+// and passes it to the routes of the tenant.
+type tenantRouter struct {
+	header  string
+	tenants []*tenantHandler
+}
+
+// newTenantRouter builds the routes of every tenant over the common settings of cfg.
+func newTenantRouter(cfg Config) *tenantRouter {
+	rt := &tenantRouter{header: cfg.TenantHeader}
+	for _, t := range cfg.Tenants {
+		tc := cfg
+		tc.Tenants = nil
+		tc.TemplateRoot = t.TemplateRoot
+		tc.confined = true
+		if t.Prepare != nil {
+			tc.Prepare = t.Prepare
+		}
+		if t.Fonts != nil {
+			tc.Fonts = t.Fonts
+		}
+		if tc.Logger != nil {
+			tc.Logger = tc.Logger.With(slog.String("tenant", t.Name))
+		}
+		rt.tenants = append(rt.tenants, &tenantHandler{Tenant: t, handler: routes(tc), limit: newRateLimiter(t.Rate, t.Burst)})
+	}
+	return rt
+}
+
+func (rt *tenantRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	t, code := rt.lookup(r)
+	switch code {
+	case http.StatusUnauthorized:
+		w.Header().Set("WWW-Authenticate", `Bearer realm="docxgen"`)
+		jsonErr(w, code, "unauthorized: pass the API key of the tenant")
+		return
+	case http.StatusForbidden:
+		jsonErr(w, code, "the API key is not of the tenant %q", r.Header.Get(rt.header))
+		return
+	}
+	if ok, wait := t.limit.allow(time.Now()); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		jsonErr(w, http.StatusTooManyRequests, "rate limit of the tenant %s exceeded", t.Name)
+		return
+	}
+	t.handler.ServeHTTP(w, r)
+}
+
+// lookup finds the tenant of the request: by its API key, else by the tenant header.
+// A key and a header of another tenant give 403, neither of them 401.
+func (rt *tenantRouter) lookup(r *http.Request) (*tenantHandler, int) {
+	var byHeader *tenantHandler
+	if rt.header != "" {
+		if name := strings.TrimSpace(r.Header.Get(rt.header)); name != "" {
+			for _, t := range rt.tenants {
+				if t.Name == name {
+					byHeader = t
+					break
+				}
+			}
+		}
+	}
+	key := requestKey(r)
+	if key == "" {
+		if byHeader == nil {
+			return nil, http.StatusUnauthorized
+		}
+		return byHeader, 0
+	}
+	for _, t := range rt.tenants {
+		if t.hasKey(key) {
+			if byHeader != nil && byHeader != t {
+				return nil, http.StatusForbidden
+			}
+			return t, 0
+		}
+	}
+	return nil, http.StatusUnauthorized
+}
+
+// hasKey compares the key with every key of the tenant in constant time.
+func (t *tenantHandler) hasKey(key string) bool {
+	found := false
+	for _, k := range t.Keys {
+		if k != "" && subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			found = true
+		}
+	}
+	return found
+}
+
+// requestKey - the API key of the request: "Authorization: Bearer <key>" or "X-API-Key".
+func requestKey(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); len(auth) > len("Bearer ") && strings.EqualFold(auth[:len("Bearer ")], "Bearer ") {
+		return strings.TrimSpace(auth[len("Bearer "):])
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+// rateLimiter - a token bucket: rate tokens a second, at most burst of them.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns the limiter of rate requests a second; nil — no limit.
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	b := float64(burst)
+	if burst <= 0 {
+		b = math.Max(1, math.Ceil(rate))
+	}
+	return &rateLimiter{rate: rate, burst: b, tokens: b}
+}
+
+// allow takes a token for a request at now; false — there is none, wait — until the next one.
+func (l *rateLimiter) allow(now time.Time) (ok bool, wait time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.last.IsZero() {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
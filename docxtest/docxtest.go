@@ -0,0 +1,311 @@
+// Package docxtest builds minimal DOCX packages in memory for tests — a document with paragraphs,
+// tables, pictures, headers, footers and styles — instead of hand-written zip boilerplate:
+//
+//	doc := docxtest.New().
+//		Paragraph("Договор № {number}").
+//		Table([]string{"Товар", "Цена"}, []string{"{name}", "{price}"}).
+//		Header("<w:p><w:r><w:t>{company}</w:t></w:r></w:p>").
+//		Open(t)
+//
+// Zip packs arbitrary parts as they are, for the fixtures that test broken or unusual packages.
+package docxtest
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"testing"
+
+	"docxgen"
+)
+
+const (
+	nsW   = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+	nsR   = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	nsWP  = "http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing"
+	nsA   = "http://schemas.openxmlformats.org/drawingml/2006/main"
+	nsPic = "http://schemas.openxmlformats.org/drawingml/2006/picture"
+	wml   = "application/vnd.openxmlformats-officedocument.wordprocessingml."
+
+	relTypeBase = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/"
+)
+
+// Builder - a DOCX package being assembled. The methods append to the body in call order and return
+// the builder; the package is an A4 portrait document with the parts that were asked for.
+type Builder struct {
+	body    strings.Builder
+	rels    []rel
+	refs    []string // <w:headerReference>, <w:footerReference> of the section
+	styles  []string
+	files   map[string]string
+	headers int
+	footers int
+	images  int
+}
+
+// rel - a relationship of word/document.xml.
+type rel struct {
+	id, typ, target string
+}
+
+// New starts an empty document.
+func New() *Builder {
+	return &Builder{files: map[string]string{}}
+}
+
+// Paragraph appends a paragraph per text, a plain run each.
+func (b *Builder) Paragraph(texts ...string) *Builder {
+	for _, text := range texts {
+		b.body.WriteString(paragraph(text))
+	}
+	return b
+}
+
+// Body appends the XML as it is: paragraphs, tables, content controls.
+func (b *Builder) Body(xml string) *Builder {
+	b.body.WriteString(xml)
+	return b
+}
+
+// Table appends a table with single borders, a row per slice, a paragraph per cell.
+func (b *Builder) Table(rows ...[]string) *Builder {
+	cols := 0
+	for _, row := range rows {
+		cols = max(cols, len(row))
+	}
+	if cols == 0 {
+		return b
+	}
+	width := 9638 / cols
+	b.body.WriteString(`<w:tbl><w:tblPr><w:tblW w:w="0" w:type="auto"/><w:tblBorders>`)
+	for _, side := range []string{"top", "left", "bottom", "right", "insideH", "insideV"} {
+		b.body.WriteString(`<w:` + side + ` w:val="single" w:sz="4" w:space="0" w:color="auto"/>`)
+	}
+	b.body.WriteString(`</w:tblBorders></w:tblPr><w:tblGrid>`)
+	for range cols {
+		fmt.Fprintf(&b.body, `<w:gridCol w:w="%d"/>`, width)
+	}
+	b.body.WriteString(`</w:tblGrid>`)
+	for _, row := range rows {
+		b.body.WriteString(`<w:tr>`)
+		for i := range cols {
+			text := ""
+			if i < len(row) {
+				text = row[i]
+			}
+			fmt.Fprintf(&b.body, `<w:tc><w:tcPr><w:tcW w:w="%d" w:type="dxa"/></w:tcPr>%s</w:tc>`, width, paragraph(text))
+		}
+		b.body.WriteString(`</w:tr>`)
+	}
+	b.body.WriteString(`</w:tbl>`)
+	return b
+}
+
+// Image appends a paragraph with the picture data (PNG or JPEG, see PNG) inline, widthPx × heightPx
+// at 96 dpi, stored as word/media/imageN with its relationship.
+func (b *Builder) Image(data []byte, widthPx, heightPx int) *Builder {
+	b.images++
+	ext := "png"
+	if bytes.HasPrefix(data, []byte{0xFF, 0xD8}) {
+		ext = "jpeg"
+	}
+	name := fmt.Sprintf("media/image%d.%s", b.images, ext)
+	b.files["word/"+name] = string(data)
+	id := b.addRel("image", name)
+	cx, cy := widthPx*9525, heightPx*9525
+	fmt.Fprintf(&b.body, `<w:p><w:r><w:drawing><wp:inline distT="0" distB="0" distL="0" distR="0">`+
+		`<wp:extent cx="%[3]d" cy="%[4]d"/><wp:docPr id="%[1]d" name="Picture %[1]d"/>`+
+		`<a:graphic><a:graphicData uri="%[5]s"><pic:pic>`+
+		`<pic:nvPicPr><pic:cNvPr id="%[1]d" name="Picture %[1]d"/><pic:cNvPicPr/></pic:nvPicPr>`+
+		`<pic:blipFill><a:blip r:embed="%[2]s"/><a:stretch><a:fillRect/></a:stretch></pic:blipFill>`+
+		`<pic:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="%[3]d" cy="%[4]d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></pic:spPr>`+
+		`</pic:pic></a:graphicData></a:graphic></wp:inline></w:drawing></w:r></w:p>`, b.images, id, cx, cy, nsPic)
+	return b
+}
+
+// Header sets the default header of the section to the paragraphs xml (word/headerN.xml).
+func (b *Builder) Header(xml string) *Builder {
+	b.headers++
+	b.addHdrFtr("header", "hdr", b.headers, xml)
+	return b
+}
+
+// Footer sets the default footer of the section to the paragraphs xml (word/footerN.xml).
+func (b *Builder) Footer(xml string) *Builder {
+	b.footers++
+	b.addHdrFtr("footer", "ftr", b.footers, xml)
+	return b
+}
+
+// Style adds a paragraph style to word/styles.xml: <w:style w:type="paragraph" w:styleId="id">
+// with the name and the run properties rPr ("<w:b/>"), which may be empty.
+func (b *Builder) Style(id, name, rPr string) *Builder {
+	style := `<w:style w:type="paragraph" w:styleId="` + id + `"><w:name w:val="` + name + `"/>`
+	if rPr != "" {
+		style += `<w:rPr>` + rPr + `</w:rPr>`
+	}
+	b.styles = append(b.styles, style+`</w:style>`)
+	return b
+}
+
+// File puts a part as it is (settings.xml, numbering.xml, ...), replacing a generated one.
+// Content types and relationships of the part are up to the caller.
+func (b *Builder) File(name, data string) *Builder {
+	b.files[name] = data
+	return b
+}
+
+// Bytes packs the document.
+func (b *Builder) Bytes() []byte {
+	files := map[string]string{}
+	overrides := map[string]string{"word/document.xml": wml + "document.main+xml"}
+
+	var body strings.Builder
+	body.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	fmt.Fprintf(&body, `<w:document xmlns:w="%s" xmlns:r="%s" xmlns:wp="%s" xmlns:a="%s" xmlns:pic="%s"><w:body>`,
+		nsW, nsR, nsWP, nsA, nsPic)
+	body.WriteString(b.body.String())
+	body.WriteString(`<w:sectPr>` + strings.Join(b.refs, "") +
+		`<w:pgSz w:w="11906" w:h="16838"/>` +
+		`<w:pgMar w:top="1134" w:right="850" w:bottom="1134" w:left="1134" w:header="708" w:footer="708" w:gutter="0"/>` +
+		`</w:sectPr></w:body></w:document>`)
+	files["word/document.xml"] = body.String()
+
+	rels := slices.Clone(b.rels)
+	if len(b.styles) > 0 {
+		files["word/styles.xml"] = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+			`<w:styles xmlns:w="` + nsW + `">` + strings.Join(b.styles, "") + `</w:styles>`
+		overrides["word/styles.xml"] = wml + "styles+xml"
+		rels = append(rels, rel{fmt.Sprintf("rId%d", len(rels)+1), relTypeBase + "styles", "styles.xml"})
+	}
+
+	var r strings.Builder
+	r.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for _, x := range rels {
+		fmt.Fprintf(&r, `<Relationship Id="%s" Type="%s" Target="%s"/>`, x.id, x.typ, x.target)
+	}
+	r.WriteString(`</Relationships>`)
+	files["word/_rels/document.xml.rels"] = r.String()
+	files["_rels/.rels"] = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="` + relTypeBase + `officeDocument" Target="word/document.xml"/></Relationships>`
+
+	for name, data := range b.files {
+		files[name] = data
+		switch {
+		case strings.HasPrefix(name, "word/header"):
+			overrides[name] = wml + "header+xml"
+		case strings.HasPrefix(name, "word/footer"):
+			overrides[name] = wml + "footer+xml"
+		}
+	}
+
+	if _, ok := b.files["[Content_Types].xml"]; !ok {
+		var ct strings.Builder
+		ct.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+			`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Default Extension="png" ContentType="image/png"/>` +
+			`<Default Extension="jpeg" ContentType="image/jpeg"/>`)
+		names := make([]string, 0, len(overrides))
+		for name := range overrides {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			fmt.Fprintf(&ct, `<Override PartName="/%s" ContentType="%s"/>`, name, overrides[name])
+		}
+		ct.WriteString(`</Types>`)
+		files["[Content_Types].xml"] = ct.String()
+	}
+	return Zip(files)
+}
+
+// WriteFile saves the document to path, for the code that opens templates and includes from disk.
+func (b *Builder) WriteFile(t testing.TB, path string) {
+	t.Helper()
+	if err := os.WriteFile(path, b.Bytes(), 0644); err != nil {
+		t.Fatalf("docxtest: write %s: %v", path, err)
+	}
+}
+
+// Open packs the document and opens it with docxgen.OpenBytes.
+func (b *Builder) Open(t testing.TB) *docxgen.Docx {
+	t.Helper()
+	doc, err := docxgen.OpenBytes(b.Bytes())
+	if err != nil {
+		t.Fatalf("docxtest: open: %v", err)
+	}
+	return doc
+}
+
+// addRel adds a relationship of the document and returns its id.
+func (b *Builder) addRel(typ, target string) string {
+	id := fmt.Sprintf("rId%d", len(b.rels)+1)
+	b.rels = append(b.rels, rel{id, relTypeBase + typ, target})
+	return id
+}
+
+// addHdrFtr stores a header or footer part and references it from the section.
+func (b *Builder) addHdrFtr(kind, root string, n int, xml string) {
+	name := fmt.Sprintf("%s%d.xml", kind, n)
+	b.files["word/"+name] = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<w:` + root + ` xmlns:w="` + nsW + `" xmlns:r="` + nsR + `">` + xml + `</w:` + root + `>`
+	id := b.addRel(kind, name)
+	// a later header replaces the earlier one of the section, as there is a single default one
+	b.refs = slices.DeleteFunc(b.refs, func(ref string) bool { return strings.HasPrefix(ref, `<w:`+kind+`Reference`) })
+	b.refs = append(b.refs, `<w:`+kind+`Reference w:type="default" r:id="`+id+`"/>`)
+}
+
+// Zip packs the parts as they are, in the order of their names.
+func Zip(files map[string]string) []byte {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		// [Content_Types].xml goes first, as Word writes it
+		return strings.Compare(strings.TrimPrefix(a, "["), strings.TrimPrefix(b, "["))
+	})
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for _, name := range names {
+		w, err := zw.Create(path.Clean(name))
+		if err != nil {
+			panic(err)
+		}
+		_, _ = w.Write([]byte(files[name]))
+	}
+	_ = zw.Close()
+	return buf.Bytes()
+}
+
+// PNG returns a w × h PNG filled with a single gray color, a picture for the fixtures.
+func PNG(w, h int) []byte {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 0x80
+	}
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+// paragraph - a paragraph with the text in a single run.
+func paragraph(text string) string {
+	if text == "" {
+		return `<w:p/>`
+	}
+	return `<w:p><w:r><w:t xml:space="preserve">` + escaper.Replace(text) + `</w:t></w:r></w:p>`
+}
+
+// escaper - the text for an XML text node.
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
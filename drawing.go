@@ -0,0 +1,242 @@
+package docxgen
+
+import (
+	"docxgen/geometry"
+	"docxgen/modifiers"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// rasterDPI - density of the generated images (charts, barcodes): 12 px per millimeter.
+const rasterDPI = 304.8
+
+// takeRaster checks the w×h px canvas of a generated picture (sparkline, progressbar) against
+// maxImageBytes and MaxPartSize: the size comes from the template. false — larger: the error
+// (ErrLimitExceeded) is kept for ExecuteTemplate and the picture is not drawn.
+func (d *Docx) takeRaster(what string, w, h int) bool {
+	limit := int64(maxImageBytes)
+	if d.limits.MaxPartSize > 0 && d.limits.MaxPartSize < limit {
+		limit = d.limits.MaxPartSize
+	}
+	if float64(w)*float64(h)*4 <= float64(limit) {
+		return true
+	}
+	if d.tableErr == nil {
+		d.tableErr = fmt.Errorf("%s: %w: a %d×%d px picture is larger than %d bytes", what, ErrLimitExceeded, w, h, limit)
+	}
+	return false
+}
+
+// inlinePictureXML - a picture embedded in the text line (<w:drawing><wp:inline>) for the image rId.
+// Used by the small drawing modifiers (sparkline, progressbar), which always go inline with the text.
+// id and name come from Docx.nextDrawing.
+func inlinePictureXML(id int, rId, name string, cx, cy int) string {
+	return fmt.Sprintf(`<w:drawing xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+  <wp:inline distT="0" distB="0" distL="0" distR="0">
+    <wp:extent cx="%[4]d" cy="%[5]d"/>
+    <wp:effectExtent l="0" t="0" r="0" b="0"/>
+    <wp:docPr id="%[1]d" name="%[3]s"/>
+    <wp:cNvGraphicFramePr>
+      <a:graphicFrameLocks xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" noChangeAspect="1"/>
+    </wp:cNvGraphicFramePr>
+    <a:graphic xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main">
+      <a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/picture">
+        <pic:pic xmlns:pic="http://schemas.openxmlformats.org/drawingml/2006/picture">
+          <pic:nvPicPr><pic:cNvPr id="%[1]d" name="%[3]s"/><pic:cNvPicPr/></pic:nvPicPr>
+          <pic:blipFill><a:blip r:embed="%[2]s"/><a:stretch><a:fillRect/></a:stretch></pic:blipFill>
+          <pic:spPr>
+            <a:xfrm><a:off x="0" y="0"/><a:ext cx="%[4]d" cy="%[5]d"/></a:xfrm>
+            <a:prstGeom prst="rect"><a:avLst/></a:prstGeom><a:noFill/>
+          </pic:spPr>
+        </pic:pic>
+      </a:graphicData>
+    </a:graphic>
+  </wp:inline>
+</w:drawing>`, id, rId, name, cx, cy)
+}
+
+// reDocPrID - ids of the drawings (wp:docPr) already present in the document.
+var reDocPrID = regexp.MustCompile(`<wp:docPr\b[^>]*\bid="(\d+)"`)
+
+// nextDrawing returns a unique id of wp:docPr and a unique drawing name for a new drawing.
+// Word requires docPr ids to be unique within the document; identical ids (and names of several
+// QR codes with the same value) make some versions report the file as damaged.
+// The counter starts after the largest id found in the parts of the template.
+func (d *Docx) nextDrawing(base string) (int, string) {
+	if d.drawingID == 0 {
+		for name, data := range d.files {
+			if !strings.HasPrefix(name, "word/") || !strings.HasSuffix(name, ".xml") {
+				continue
+			}
+			for _, m := range reDocPrID.FindAllSubmatch(data, -1) {
+				if n, err := strconv.Atoi(string(m[1])); err == nil && n > d.drawingID {
+					d.drawingID = n
+				}
+			}
+		}
+	}
+	d.drawingID++
+	return d.drawingID, fmt.Sprintf("%s_%d", base, d.drawingID)
+}
+
+// renumberDrawings gives the drawings of a copied fragment new ids (see nextDrawing).
+func (d *Docx) renumberDrawings(frag string) string {
+	return reDocPrID.ReplaceAllStringFunc(frag, func(s string) string {
+		id, _ := d.nextDrawing("")
+		i := strings.LastIndex(s, `id="`)
+		return s[:i] + `id="` + strconv.Itoa(id) + `"`
+	})
+}
+
+// runBreakout puts the drawing into its own run, closing the current run of the tag and reopening it after.
+func runBreakout(drawing string) string {
+	return "</w:t></w:r><w:r>" + drawing + strings.TrimPrefix(modifiers.RunReopen, "</w:t>")
+}
+
+// anchorWrap - text wrapping and z-order of a floating (anchor) drawing.
+type anchorWrap struct {
+	mode string // square (default), tight, through, topbottom, none, front, behind
+	z    int    // relativeHeight: the higher, the closer to the reader
+}
+
+// defaultAnchorWrap - the wrapping of QR codes and barcodes used before the options appeared.
+var defaultAnchorWrap = anchorWrap{mode: "square", z: 2}
+
+// parseWrapToken recognizes the wrapping options of anchor drawings:
+//
+//	square, tight, through, topbottom — the text flows around the drawing;
+//	none / front — the drawing lies over the text;
+//	behind — the drawing lies under the text (stamps, seals under the signature);
+//	z=<N> — z-order among other drawings.
+//
+// Returns false if the token is not a wrapping option.
+func parseWrapToken(token string, w *anchorWrap) bool {
+	lower := strings.ToLower(strings.TrimSpace(token))
+	switch lower {
+	case "square", "tight", "through", "topbottom", "none", "front", "behind":
+		w.mode = lower
+		return true
+	}
+	if v, ok := strings.CutPrefix(lower, "z="); ok {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			w.z = n
+			return true
+		}
+	}
+	return false
+}
+
+// behindDoc - the value of the behindDoc attribute of <wp:anchor>.
+func (w anchorWrap) behindDoc() int {
+	if w.mode == "behind" {
+		return 1
+	}
+	return 0
+}
+
+// xml - the wrapping element of <wp:anchor>.
+func (w anchorWrap) xml() string {
+	// the contour of the whole picture (21600 — 100% in wrapPolygon units)
+	const polygon = `<wp:wrapPolygon edited="0"><wp:start x="0" y="0"/><wp:lineTo x="0" y="21600"/>` +
+		`<wp:lineTo x="21600" y="21600"/><wp:lineTo x="21600" y="0"/><wp:lineTo x="0" y="0"/></wp:wrapPolygon>`
+	switch w.mode {
+	case "tight":
+		return `<wp:wrapTight wrapText="bothSides">` + polygon + `</wp:wrapTight>`
+	case "through":
+		return `<wp:wrapThrough wrapText="bothSides">` + polygon + `</wp:wrapThrough>`
+	case "topbottom":
+		return `<wp:wrapTopAndBottom/>`
+	case "none", "front", "behind":
+		return `<wp:wrapNone/>`
+	default:
+		return `<wp:wrapSquare wrapText="bothSides"/>`
+	}
+}
+
+// pictureDrawing - a picture placed like the QR codes: in the text line (inline) or floating (anchor).
+//   - id, name — from Docx.nextDrawing, rId — from AddImageRel / AddMediaRel;
+//   - cx, cy — the size in EMU;
+//   - align, valign — the position of the anchor: left/center/right, top/center/bottom;
+//   - dist, wrap — the distance from the text and the wrapping of the anchor;
+//   - crop — the percentage cut off each side, border — a thin black frame.
+type pictureDrawing struct {
+	id            int
+	rId, name     string
+	cx, cy        int
+	inline        bool
+	align, valign string
+	dist          geometry.Sides
+	wrap          anchorWrap
+	crop          float64
+	border        bool
+}
+
+// xml - the <w:drawing> of the picture.
+func (p pictureDrawing) xml() string {
+	cropXML := ""
+	if p.crop > 0 {
+		v := int(p.crop * 1000)
+		cropXML = fmt.Sprintf(`<a:srcRect l="%d" t="%d" r="%d" b="%d"/>`, v, v, v, v)
+	}
+	borderXML := ""
+	if p.border {
+		borderXML = `<a:ln w="12700"><a:solidFill><a:srgbClr val="000000"/></a:solidFill></a:ln>`
+	}
+
+	pic := fmt.Sprintf(`
+<pic:pic xmlns:pic="http://schemas.openxmlformats.org/drawingml/2006/picture">
+  <pic:nvPicPr>
+    <pic:cNvPr id="%d" name="%s"/>
+    <pic:cNvPicPr><a:picLocks noChangeAspect="1" noChangeArrowheads="1"/></pic:cNvPicPr>
+  </pic:nvPicPr>
+  <pic:blipFill>
+    <a:blip r:embed="%s" cstate="print"/>
+    %s
+    <a:stretch><a:fillRect/></a:stretch>
+  </pic:blipFill>
+  <pic:spPr bwMode="auto">
+    <a:xfrm><a:off x="0" y="0"/><a:ext cx="%d" cy="%d"/></a:xfrm>
+    <a:prstGeom prst="rect"><a:avLst/></a:prstGeom>
+    <a:noFill/>%s
+  </pic:spPr>
+</pic:pic>`, p.id, p.name, p.rId, cropXML, p.cx, p.cy, borderXML)
+
+	if p.inline {
+		return fmt.Sprintf(`
+<w:drawing xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+  <wp:inline distT="0" distB="0" distL="0" distR="0">
+    <wp:extent cx="%d" cy="%d"/>
+    <wp:effectExtent l="0" t="0" r="0" b="0"/>
+    <wp:docPr id="%d" name="%s"/>
+    <wp:cNvGraphicFramePr>
+      <a:graphicFrameLocks xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" noChangeAspect="1"/>
+    </wp:cNvGraphicFramePr>
+    <a:graphic xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main">
+      <a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/picture">%s</a:graphicData>
+    </a:graphic>
+  </wp:inline>
+</w:drawing>`, p.cx, p.cy, p.id, p.name, pic)
+	}
+	return fmt.Sprintf(`
+<w:drawing xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+  <wp:anchor behindDoc="%d" distT="%d" distB="%d" distL="%d" distR="%d" 
+	simplePos="0" locked="0" layoutInCell="0" allowOverlap="1" relativeHeight="%d">
+	<wp:simplePos x="0" y="0"/>
+    <wp:positionH relativeFrom="column"><wp:align>%s</wp:align></wp:positionH>
+    <wp:positionV relativeFrom="paragraph"><wp:align>%s</wp:align></wp:positionV>
+    <wp:extent cx="%d" cy="%d"/>
+    <wp:effectExtent l="0" t="0" r="0" b="0"/>
+    %s
+    <wp:docPr id="%d" name="%s"/>
+    <wp:cNvGraphicFramePr>
+      <a:graphicFrameLocks xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" noChangeAspect="1"/>
+    </wp:cNvGraphicFramePr>
+    <a:graphic xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main">
+      <a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/picture">%s</a:graphicData>
+    </a:graphic>
+  </wp:anchor>
+</w:drawing>`, p.wrap.behindDoc(), p.dist.Top.EMU(), p.dist.Bottom.EMU(), p.dist.Left.EMU(), p.dist.Right.EMU(),
+		p.wrap.z, p.align, p.valign, p.cx, p.cy, p.wrap.xml(), p.id, p.name, pic)
+}
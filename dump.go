@@ -0,0 +1,72 @@
+package docxgen
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TransformedPart - the Go template of a part exactly as ExecuteTemplate parses it.
+//   - Part — "document", "header1", ...;
+//   - Template — the text after tag repair, includes, tables and the {a|mod} → {.a | mod} conversion;
+//   - Err — the parse error of Template (nil — it parses), the line in the message is the line of Template.
+type TransformedPart struct {
+	Part     string
+	Template string
+	Err      error
+}
+
+// TransformedTemplates prepares every part like ExecuteTemplate does and returns the template
+// texts without executing them — to see what the engine parses when chasing "parse template" errors.
+// Includes and tables are resolved with data and may add media and relationships,
+// so dump a document that is not saved afterwards.
+func (d *Docx) TransformedTemplates(data map[string]any) ([]TransformedPart, error) {
+	parts := d.templateParts()
+
+	var out []TransformedPart
+	for _, part := range parts {
+		content, err := d.ContentPart(part)
+		if err != nil {
+			if part == "document" {
+				return nil, fmt.Errorf("transform template: %w", err)
+			}
+			continue
+		}
+		source, text, err := d.transformPart(part, content, data)
+		if err != nil {
+			return nil, err
+		}
+		funcs := d.templateFuncs(data)
+		if _, err = parseTemplate(text, funcs); err != nil {
+			err = locateParseError(part, source, text, funcs, err)
+		}
+		out = append(out, TransformedPart{Part: part, Template: text, Err: err})
+	}
+	return out, nil
+}
+
+// WriteTransformed writes the parts with numbered lines, the parse error goes under the part header:
+//
+//	=== word/document.xml ===
+//	!!! template: docx:2: function "upper" not defined
+//	   1 | <?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+//	   2 | <w:document ...>...{.name | upper}...</w:document>
+func WriteTransformed(w io.Writer, parts []TransformedPart) error {
+	bw := bufio.NewWriter(w)
+	for i, p := range parts {
+		if i > 0 {
+			bw.WriteString("\n")
+		}
+		fmt.Fprintf(bw, "=== %s ===\n", partPath(p.Part))
+		if p.Err != nil {
+			fmt.Fprintf(bw, "!!! %v\n", p.Err)
+		}
+		lines := strings.Split(strings.TrimSuffix(p.Template, "\n"), "\n")
+		width := len(fmt.Sprint(len(lines)))
+		for n, line := range lines {
+			fmt.Fprintf(bw, "%*d | %s\n", max(width, 4), n+1, strings.TrimSuffix(line, "\r"))
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,169 @@
+package docxgen
+
+import (
+	"strings"
+	"text/template/parse"
+)
+
+// Event - an event of ExecuteTemplate delivered to the observer (see Observe).
+// The concrete types: PartStarted, TableRendered, IncludeResolved, TagUnresolved.
+type Event interface {
+	isEvent()
+}
+
+// PartStarted - processing of a part ("document", "header1", ...) has started.
+type PartStarted struct {
+	Part string
+}
+
+// TableRendered - the smart table [table/name] was filled with Rows data items.
+type TableRendered struct {
+	Part string
+	Name string
+	Rows int
+}
+
+// IncludeResolved - the fragment [include/file...] or the annex [annex/file...] was inserted into the part.
+type IncludeResolved struct {
+	Part string
+	File string
+}
+
+// TagUnresolved - the data has no value for the tag; Name is the path of the field ("client.name").
+// Modifiers like default may still have produced text for it.
+type TagUnresolved struct {
+	Part string
+	Name string
+}
+
+func (PartStarted) isEvent()     {}
+func (TableRendered) isEvent()   {}
+func (IncludeResolved) isEvent() {}
+func (TagUnresolved) isEvent()   {}
+
+// Observer - receives the events of ExecuteTemplate synchronously, in the order they occur.
+type Observer func(Event)
+
+// Observe sets the observer of ExecuteTemplate events (nil disables them).
+// Useful for progress of long renders and for audit logs.
+//
+// Example:
+//
+//	doc.Observe(func(e docxgen.Event) {
+//		if t, ok := e.(docxgen.TagUnresolved); ok {
+//			log.Printf("no data for {%s} in %s", t.Name, t.Part)
+//		}
+//	})
+func (d *Docx) Observe(fn Observer) {
+	d.observer = fn
+}
+
+// emit passes the event to the observer, if there is one.
+func (d *Docx) emit(e Event) {
+	if d.observer != nil {
+		d.observer(e)
+	}
+}
+
+// emitUnresolved reports the fields of the template that are missing in the data.
+// Only the fields of the root context are checked: the bodies of range/with change the dot.
+func (d *Docx) emitUnresolved(part string, tree *parse.Tree, data map[string]any) {
+	if d.observer == nil || tree == nil || tree.Root == nil {
+		return
+	}
+	seen := map[string]bool{}
+	walkFields(tree, func(ident []string) {
+		if len(ident) == 0 || lookupPath(data, ident) {
+			return
+		}
+		name := strings.Join(ident, ".")
+		if !seen[name] {
+			seen[name] = true
+			d.emit(TagUnresolved{Part: part, Name: name})
+		}
+	})
+}
+
+// walkFields passes the data paths of the root context the template reads to report: the fields
+// (.a.b), the fields of $ and the chains ((.a).b). The bodies of range/with are skipped.
+func walkFields(tree *parse.Tree, report func(ident []string)) {
+	var walkPipe func(p *parse.PipeNode)
+	var walk func(n parse.Node)
+
+	walkArg := func(n parse.Node) {
+		switch a := n.(type) {
+		case *parse.FieldNode:
+			report(a.Ident)
+		case *parse.VariableNode:
+			if len(a.Ident) > 1 && a.Ident[0] == "$" {
+				report(a.Ident[1:])
+			}
+		case *parse.ChainNode:
+			// (.x).y — checked by the field inside
+			if f, ok := a.Node.(*parse.FieldNode); ok {
+				report(append(append([]string{}, f.Ident...), a.Field...))
+			}
+		case *parse.PipeNode:
+			walkPipe(a)
+		}
+	}
+	walkPipe = func(p *parse.PipeNode) {
+		if p == nil {
+			return
+		}
+		for _, cmd := range p.Cmds {
+			for _, arg := range cmd.Args {
+				walkArg(arg)
+			}
+		}
+	}
+	walk = func(n parse.Node) {
+		switch x := n.(type) {
+		case *parse.ListNode:
+			if x == nil {
+				return
+			}
+			for _, c := range x.Nodes {
+				walk(c)
+			}
+		case *parse.ActionNode:
+			walkPipe(x.Pipe)
+		case *parse.IfNode:
+			walkPipe(x.Pipe)
+			walk(x.List)
+			walk(x.ElseList)
+		case *parse.RangeNode:
+			walkPipe(x.Pipe)
+			walk(x.ElseList)
+		case *parse.WithNode:
+			walkPipe(x.Pipe)
+			walk(x.ElseList)
+		}
+	}
+	walk(tree.Root)
+}
+
+// lookupPath reports whether the data has a value by the path of map keys.
+// A value that is not a map ends the check: its fields are resolved by the template itself.
+func lookupPath(data map[string]any, path []string) bool {
+	var cur any = data
+	for _, key := range path {
+		var (
+			v  any
+			ok bool
+		)
+		switch m := cur.(type) {
+		case map[string]any:
+			v, ok = m[key]
+		case map[string]string:
+			v, ok = m[key]
+		default:
+			return true
+		}
+		if !ok {
+			return false
+		}
+		cur = v
+	}
+	return true
+}
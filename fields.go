@@ -0,0 +1,179 @@
+package docxgen
+
+import (
+	"slices"
+	"strings"
+)
+
+// ============================================================================
+// Fields (TOC, PAGE, REF, ...): the list and the refresh by Word on open
+// ============================================================================
+
+// Field - a field of the document.
+//   - Part — the part it is in ("document", "header1", "footnotes", ...);
+//   - Type — the first word of the instruction in upper case ("TOC", "PAGE", "REF");
+//   - Instruction — the instruction as it is (` TOC \o "1-3" \h `);
+//   - Result — the text Word showed last time, stale after the template is filled.
+type Field struct {
+	Part        string
+	Type        string
+	Instruction string
+	Result      string
+}
+
+// ListFields returns the fields of the document, the headers and footers, the notes and the comments:
+// the simple ones (<w:fldSimple>), then the complex ones (fldChar begin … end), a field inside another
+// one after it.
+func (d *Docx) ListFields() []Field {
+	var fields []Field
+	for _, part := range d.templateParts() {
+		content, ok := d.files["word/"+part+".xml"]
+		if !ok {
+			continue
+		}
+		fields = append(fields, partFields(part, string(content))...)
+	}
+	return fields
+}
+
+// MarkFieldsDirty marks the fields of the given types ("TOC", "PAGEREF"; none — all of them) with
+// w:dirty, so Word updates them when the document is opened. Returns how many fields were marked.
+func (d *Docx) MarkFieldsDirty(types ...string) int {
+	marked := 0
+	for _, part := range d.templateParts() {
+		name := "word/" + part + ".xml"
+		content, ok := d.files[name]
+		if !ok {
+			continue
+		}
+		s, n := markFieldsDirty(string(content), types)
+		if n > 0 {
+			d.files[name] = []byte(s)
+			marked += n
+		}
+	}
+	return marked
+}
+
+// RefreshFieldsOnOpen asks Word and LibreOffice to refresh the fields when the document is opened:
+// the table of contents, the page numbers and the cross-references left stale by ExecuteTemplate.
+// It sets w:updateFields in settings.xml (Word asks "update the fields?" on open) and marks the fields
+// of the types (none — all) dirty, see MarkFieldsDirty. Returns how many fields were marked.
+func (d *Docx) RefreshFieldsOnOpen(types ...string) int {
+	d.Settings().SetUpdateFields(true)
+	return d.MarkFieldsDirty(types...)
+}
+
+// complexField - a complex field of a part: where its fldChar begin is, its instruction and result.
+type complexField struct {
+	begin         int
+	instr, result string
+}
+
+// complexFields reads the complex fields (fldChar begin … separate … end) of s in the order of their
+// begin: the outer field before the fields inside it.
+func complexFields(s string) []complexField {
+	type open struct {
+		begin, result int // result — past the separate, 0 before it
+		instr         strings.Builder
+	}
+	var fields []complexField
+	var stack []*open
+	for pos := 0; ; {
+		loc := reFldChar.FindStringSubmatchIndex(s[pos:])
+		next := len(s)
+		if loc != nil {
+			next = pos + loc[0]
+		}
+		if n := len(stack); n > 0 && stack[n-1].result == 0 {
+			for _, m := range reInstrText.FindAllStringSubmatch(s[pos:next], -1) {
+				stack[n-1].instr.WriteString(m[1])
+			}
+		}
+		if loc == nil {
+			break
+		}
+		n := len(stack)
+		switch s[pos+loc[2] : pos+loc[3]] {
+		case "begin":
+			stack = append(stack, &open{begin: next})
+		case "separate":
+			if n > 0 {
+				stack[n-1].result = pos + loc[1]
+			}
+		case "end":
+			if n > 0 {
+				f := stack[n-1]
+				stack = stack[:n-1]
+				result := ""
+				if f.result > 0 {
+					result = xmlUnescaper.Replace(extractParagraphText(s[f.result:next]))
+				}
+				fields = append(fields, complexField{f.begin, xmlUnescaper.Replace(f.instr.String()), result})
+			}
+		}
+		pos += loc[1]
+	}
+	slices.SortFunc(fields, func(a, b complexField) int { return a.begin - b.begin })
+	return fields
+}
+
+// partFields reads the fields of the XML of a part.
+func partFields(part, s string) []Field {
+	var fields []Field
+	for _, m := range reFldSimple.FindAllStringSubmatch(s, -1) {
+		fields = append(fields, newField(part, xmlUnescaper.Replace(m[1]), xmlUnescaper.Replace(extractParagraphText(m[2]))))
+	}
+	for _, f := range complexFields(s) {
+		fields = append(fields, newField(part, f.instr, f.result))
+	}
+	return fields
+}
+
+// newField - the field of the part with the instruction and the shown result.
+func newField(part, instr, result string) Field {
+	typ := ""
+	if words := fieldWords(instr); len(words) > 0 {
+		typ = strings.ToUpper(words[0])
+	}
+	return Field{Part: part, Type: typ, Instruction: instr, Result: result}
+}
+
+// markFieldsDirty adds w:dirty="true" to the <w:fldSimple> and the fldChar begin of the fields of the
+// types (all if none) in s; the fields already dirty stay as they are.
+func markFieldsDirty(s string, types []string) (string, int) {
+	wanted := func(instr string) bool {
+		typ := newField("", instr, "").Type
+		return len(types) == 0 || slices.ContainsFunc(types, func(t string) bool { return strings.EqualFold(t, typ) })
+	}
+	marked := 0
+	// dirty - the element that opens at s[at] with w:dirty among its attributes
+	dirty := func(s string, at int) string {
+		end := at + strings.IndexByte(s[at:], '>')
+		if s[end-1] == '/' {
+			end--
+		}
+		if strings.Contains(s[at:end], "w:dirty=") {
+			return s
+		}
+		marked++
+		return s[:end] + ` w:dirty="true"` + s[end:]
+	}
+
+	s = reFldSimple.ReplaceAllStringFunc(s, func(el string) string {
+		if !wanted(xmlUnescaper.Replace(reFldSimple.FindStringSubmatch(el)[1])) {
+			return el
+		}
+		return dirty(el, 0)
+	})
+	if !strings.Contains(s, "<w:fldChar") {
+		return s, marked
+	}
+	fields := complexFields(s)
+	for i := len(fields) - 1; i >= 0; i-- { // from the end, the begins before stay in place
+		if wanted(fields[i].instr) {
+			s = dirty(s, fields[i].begin)
+		}
+	}
+	return s, marked
+}
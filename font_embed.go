@@ -0,0 +1,218 @@
+package docxgen
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+const (
+	fontTablePath     = "word/fontTable.xml"
+	relTypeFontTable  = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/fontTable"
+	RelTypeFont       = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/font"
+	relsRNamespace    = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	wordMainNamespace = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+)
+
+// ErrFontNotEmbeddable - the license of the font (OS/2 fsType) forbids embedding.
+var ErrFontNotEmbeddable = errors.New("font license does not allow embedding")
+
+// embedKinds - the embedding elements of <w:font> in the schema order (CT_Font).
+var embedKinds = []string{"embedRegular", "embedBold", "embedItalic", "embedBoldItalic"}
+
+// EmbedFonts embeds TrueType fonts into the document, so that it renders the same on machines
+// where the fonts are not installed (archival copies). The family and the style are read from
+// the name table of every file: "Times New Roman" + "Bold Italic" → embedBoldItalic of the
+// <w:font w:name="Times New Roman"> entry of word/fontTable.xml.
+//
+// The fonts are stored obfuscated (word/fonts/*.odttf) as Word does, and settings.xml gets
+// <w:embedTrueTypeFonts/>. Embedding the same file again replaces the previous copy.
+func (d *Docx) EmbedFonts(fontPaths ...string) error {
+	for _, p := range fontPaths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("embed font: %w", err)
+		}
+		if err := d.EmbedFont(data); err != nil {
+			return fmt.Errorf("embed font %s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// EmbedFont embeds one TrueType font given as bytes (see EmbedFonts).
+func (d *Docx) EmbedFont(data []byte) error {
+	family, kind, err := fontIdentity(data)
+	if err != nil {
+		return err
+	}
+	if !embeddable(data) {
+		return fmt.Errorf("%s: %w", family, ErrFontNotEmbeddable)
+	}
+
+	sum := sha1.Sum(data)
+	key := fontKey(sum)
+	target := fmt.Sprintf("fonts/font_%x.odttf", sum[:6])
+	d.files["word/"+target] = obfuscateFont(data, key)
+	d.ContentTypes().AddDefaultForFile(target)
+
+	table := d.ensureFontTable()
+	id := d.Rels().Add("fontTable", Relationship{Type: RelTypeFont, Target: target})
+	embed := fmt.Sprintf(`<w:%s r:id="%s" w:fontKey="%s"/>`, kind, id, key)
+	table = setFontEmbed(table, family, kind, embed)
+	d.files[fontTablePath] = []byte(table)
+
+	// the replaced copy of the font is not referenced anymore
+	d.Rels().Rewrite("fontTable", func(r Relationship) (Relationship, bool) {
+		if r.Type != RelTypeFont || strings.Contains(table, `r:id="`+r.ID+`"`) {
+			return r, true
+		}
+		delete(d.files, d.Rels().ResolveTarget("fontTable", r))
+		return r, false
+	})
+
+	d.Settings().SetEmbedTrueTypeFonts(true)
+	return nil
+}
+
+// fontIdentity returns the family and the embedding element of the font by its name table.
+func fontIdentity(data []byte) (family, kind string, err error) {
+	f, err := sfnt.Parse(data)
+	if err != nil {
+		return "", "", fmt.Errorf("parse font: %w", err)
+	}
+	var buf sfnt.Buffer
+	family, err = f.Name(&buf, sfnt.NameIDFamily)
+	if err != nil || family == "" {
+		return "", "", fmt.Errorf("font has no family name")
+	}
+	style, _ := f.Name(&buf, sfnt.NameIDSubfamily)
+	style = strings.ToLower(style)
+	bold := strings.Contains(style, "bold")
+	italic := strings.Contains(style, "italic") || strings.Contains(style, "oblique")
+	switch {
+	case bold && italic:
+		kind = "embedBoldItalic"
+	case bold:
+		kind = "embedBold"
+	case italic:
+		kind = "embedItalic"
+	default:
+		kind = "embedRegular"
+	}
+	return family, kind, nil
+}
+
+// embeddable checks the fsType of the OS/2 table: 0x0002 — restricted license embedding.
+// A font without the OS/2 table is considered embeddable.
+func embeddable(data []byte) bool {
+	if len(data) < 12 {
+		return false
+	}
+	n := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < n; i++ {
+		rec := 12 + 16*i
+		if rec+16 > len(data) {
+			return false
+		}
+		if string(data[rec:rec+4]) != "OS/2" {
+			continue
+		}
+		off := int(binary.BigEndian.Uint32(data[rec+8:]))
+		if off+10 > len(data) {
+			return false
+		}
+		fsType := binary.BigEndian.Uint16(data[off+8:])
+		return fsType&0x000F != 0x0002
+	}
+	return true
+}
+
+// fontKey - the GUID of the font ({XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX}), derived from its content,
+// so that the output of the same template does not change from run to run.
+func fontKey(sum [20]byte) string {
+	h := fmt.Sprintf("%X", sum[:16])
+	return "{" + h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:] + "}"
+}
+
+// obfuscateFont - ECMA-376 font obfuscation: the first 32 bytes are XORed with the key,
+// whose bytes are the hex pairs of the GUID read from the end.
+func obfuscateFont(data []byte, key string) []byte {
+	hex := strings.NewReplacer("{", "", "}", "", "-", "").Replace(key)
+	var k [16]byte
+	for i := range k {
+		_, _ = fmt.Sscanf(hex[30-2*i:32-2*i], "%02X", &k[i])
+	}
+	out := append([]byte(nil), data...)
+	for i := 0; i < 32 && i < len(out); i++ {
+		out[i] ^= k[i%16]
+	}
+	return out
+}
+
+// ensureFontTable returns fontTable.xml, creating it with the relationship and the content type if it is missing,
+// and declares the r: namespace used by the embedding elements.
+func (d *Docx) ensureFontTable() string {
+	data, ok := d.files[fontTablePath]
+	if !ok || !strings.Contains(string(data), "</w:fonts>") {
+		content := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<w:fonts xmlns:w="` + wordMainNamespace + `" xmlns:r="` + relsRNamespace + `"></w:fonts>`
+		d.files[fontTablePath] = []byte(content)
+		d.Rels().Add("document", Relationship{Type: relTypeFontTable, Target: "fontTable.xml"})
+		d.ContentTypes().SetOverride(fontTablePath, wmlType+"fontTable+xml")
+		return content
+	}
+	table := string(data)
+	if root := reFontsRoot.FindStringIndex(table); root != nil && !strings.Contains(table[root[0]:root[1]], "xmlns:r=") {
+		table = table[:root[0]+len("<w:fonts")] + ` xmlns:r="` + relsRNamespace + `"` + table[root[0]+len("<w:fonts"):]
+	}
+	return table
+}
+
+var (
+	reFontsRoot = regexp.MustCompile(`<w:fonts\b[^>]*>`)
+	reFontEntry = regexp.MustCompile(`(?s)<w:font\b[^>]*?/>|<w:font\b[^>]*>.*?</w:font>`)
+)
+
+// setFontEmbed puts the embedding element into the <w:font> entry of the family (adding the entry if needed),
+// replacing the element of the same kind and keeping the schema order of the embedding elements.
+func setFontEmbed(table, family, kind, embed string) string {
+	for _, loc := range reFontEntry.FindAllStringIndex(table, -1) {
+		entry := table[loc[0]:loc[1]]
+		open := entry[:strings.Index(entry, ">")+1]
+		if xmlAttr(open, "w:name") != xmlEscape(family) {
+			continue
+		}
+		return table[:loc[0]] + withEmbed(entry, kind, embed) + table[loc[1]:]
+	}
+	entry := `<w:font w:name="` + xmlEscape(family) + `">` + embed + `</w:font>`
+	i := strings.LastIndex(table, "</w:fonts>")
+	return table[:i] + entry + table[i:]
+}
+
+// withEmbed rebuilds the embedding elements of a <w:font> entry.
+func withEmbed(entry, kind, embed string) string {
+	if strings.HasSuffix(entry, "/>") && !strings.Contains(entry, "</w:font>") {
+		entry = strings.TrimSuffix(entry, "/>") + "></w:font>"
+	}
+	embeds := map[string]string{kind: embed}
+	for _, k := range embedKinds {
+		re := pattern(`<w:` + k + `\b[^>]*/>`)
+		if old := re.FindString(entry); old != "" && k != kind {
+			embeds[k] = old
+		}
+		entry = re.ReplaceAllString(entry, "")
+	}
+	var b strings.Builder
+	for _, k := range embedKinds {
+		b.WriteString(embeds[k])
+	}
+	i := strings.LastIndex(entry, "</w:font>")
+	return entry[:i] + b.String() + entry[i:]
+}
@@ -0,0 +1,419 @@
+package docxgen
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template/parse"
+
+	"docxgen/modifiers"
+)
+
+// formatRuleFunc - the template function that applies the formatting rules to the output of a tag.
+const formatRuleFunc = "_format"
+
+// FormatRule - a conditional formatting rule: when the value of the field satisfies the predicate,
+// the output of the tag gets the formatting. Business display rules stay out of both the template
+// and the data.
+//   - Field — the name of the tag as written in the template ("balance", "total.sum",
+//     "amount" inside {range}); * and ? are wildcards ("*" — every tag);
+//   - When — the predicate on the raw value (before modifiers): "< 0", ">= 100000", "== closed",
+//     "!= 0", "empty", "not empty", "contains срочно", "matches ^\d+$"; empty — always;
+//   - Color, Highlight — the text color (hex) and the highlight (Word name: yellow, green, ...);
+//   - Bold, Italic, Underline, Strike — the font style.
+//
+// When several rules match, all of them are applied in order; a later one overrides the same property.
+type FormatRule struct {
+	Field     string `json:"field"`
+	When      string `json:"when,omitempty"`
+	Color     string `json:"color,omitempty"`
+	Highlight string `json:"highlight,omitempty"`
+	Bold      bool   `json:"bold,omitempty"`
+	Italic    bool   `json:"italic,omitempty"`
+	Underline bool   `json:"underline,omitempty"`
+	Strike    bool   `json:"strike,omitempty"`
+}
+
+// compiledRule - a rule with the parsed predicate and the run properties.
+type compiledRule struct {
+	field string
+	match func(v any) bool
+	props []string
+}
+
+// LoadFormatRules reads the rules file (YAML or JSON, see ParseFormatRules).
+func LoadFormatRules(path string) ([]FormatRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("format rules: %w", err)
+	}
+	rules, err := ParseFormatRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("format rules %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// ParseFormatRules parses the rules: a JSON array, or YAML — a list of flat mappings,
+// optionally under the "rules:" key:
+//
+//	rules:
+//	  - field: balance
+//	    when: "< 0"
+//	    color: C00000
+//	  - field: total
+//	    when: "> 100000"
+//	    bold: true
+func ParseFormatRules(data []byte) ([]FormatRule, error) {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		var rules []FormatRule
+		if err := json.Unmarshal(trimmed, &rules); err != nil {
+			return nil, err
+		}
+		return rules, nil
+	}
+
+	var (
+		rules   []FormatRule
+		current *FormatRule
+		indent  = -1
+	)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimRight(stripYAMLComment(sc.Text()), " \t")
+		text := strings.TrimSpace(line)
+		if text == "" || text == "---" {
+			continue
+		}
+		depth := len(line) - len(strings.TrimLeft(line, " "))
+
+		switch {
+		case strings.HasPrefix(text, "- ") || text == "-":
+			rules = append(rules, FormatRule{})
+			current, indent = &rules[len(rules)-1], depth+2
+			text = strings.TrimSpace(strings.TrimPrefix(text, "-"))
+			if text == "" {
+				continue
+			}
+		case current == nil && text == "rules:":
+			continue
+		case current == nil || depth < indent:
+			return nil, fmt.Errorf("line %d: expected a list item \"- field: ...\"", n)
+		}
+
+		key, value, ok := strings.Cut(text, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", n)
+		}
+		if err := current.set(strings.TrimSpace(key), unquoteYAML(strings.TrimSpace(value))); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+	}
+	return rules, sc.Err()
+}
+
+// set assigns a field of the rule by its YAML key.
+func (r *FormatRule) set(key, value string) error {
+	flag := func(dst *bool) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s: expected true or false, got %q", key, value)
+		}
+		*dst = b
+		return nil
+	}
+	switch key {
+	case "field":
+		r.Field = value
+	case "when":
+		r.When = value
+	case "color":
+		r.Color = value
+	case "highlight":
+		r.Highlight = value
+	case "bold":
+		return flag(&r.Bold)
+	case "italic":
+		return flag(&r.Italic)
+	case "underline":
+		return flag(&r.Underline)
+	case "strike":
+		return flag(&r.Strike)
+	default:
+		return fmt.Errorf("unknown key %q", key)
+	}
+	return nil
+}
+
+// stripYAMLComment removes a "# comment" outside of quotes.
+func stripYAMLComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func unquoteYAML(s string) string {
+	if len(s) >= 2 && (s[0] == '"' && s[len(s)-1] == '"') {
+		if u, err := strconv.Unquote(s); err == nil {
+			return u
+		}
+	}
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	}
+	return s
+}
+
+// SetFormatRules sets the conditional formatting applied to the tags during ExecuteTemplate.
+// An invalid predicate or color is reported here, not at render time.
+func (d *Docx) SetFormatRules(rules []FormatRule) error {
+	compiled := make([]compiledRule, 0, len(rules))
+	for i, r := range rules {
+		c, err := r.compile()
+		if err != nil {
+			return fmt.Errorf("format rule %d (%s): %w", i+1, r.Field, err)
+		}
+		compiled = append(compiled, c)
+	}
+	d.formatRules = compiled
+	return nil
+}
+
+func (r FormatRule) compile() (compiledRule, error) {
+	c := compiledRule{field: strings.TrimSpace(r.Field)}
+	if c.field == "" {
+		return c, fmt.Errorf("field is required")
+	}
+	if _, err := path.Match(c.field, ""); err != nil {
+		return c, fmt.Errorf("bad field pattern: %w", err)
+	}
+	match, err := parsePredicate(r.When)
+	if err != nil {
+		return c, err
+	}
+	c.match = match
+
+	if r.Bold {
+		c.props = append(c.props, "<w:b/>", "<w:bCs/>")
+	}
+	if r.Italic {
+		c.props = append(c.props, "<w:i/>", "<w:iCs/>")
+	}
+	if r.Strike {
+		c.props = append(c.props, "<w:strike/>")
+	}
+	if r.Color != "" {
+		color := strings.TrimPrefix(r.Color, "#")
+		if _, err := strconv.ParseUint(color, 16, 32); err != nil || len(color) != 6 {
+			return c, fmt.Errorf("bad color %q", r.Color)
+		}
+		c.props = append(c.props, `<w:color w:val="`+strings.ToUpper(color)+`"/>`)
+	}
+	if r.Highlight != "" {
+		c.props = append(c.props, `<w:highlight w:val="`+xmlEscape(r.Highlight)+`"/>`)
+	}
+	if r.Underline {
+		c.props = append(c.props, `<w:u w:val="single"/>`)
+	}
+	if len(c.props) == 0 {
+		return c, fmt.Errorf("the rule has no formatting")
+	}
+	return c, nil
+}
+
+// parsePredicate compiles the "when" expression of a rule.
+func parsePredicate(when string) (func(v any) bool, error) {
+	when = strings.TrimSpace(when)
+	switch strings.ToLower(when) {
+	case "":
+		return func(any) bool { return true }, nil
+	case "empty":
+		return func(v any) bool { return ruleText(v) == "" }, nil
+	case "not empty":
+		return func(v any) bool { return ruleText(v) != "" }, nil
+	}
+	if arg, ok := strings.CutPrefix(when, "contains "); ok {
+		arg = strings.ToLower(unquoteYAML(strings.TrimSpace(arg)))
+		return func(v any) bool { return strings.Contains(strings.ToLower(ruleText(v)), arg) }, nil
+	}
+	if arg, ok := strings.CutPrefix(when, "matches "); ok {
+		re, err := regexp.Compile(unquoteYAML(strings.TrimSpace(arg)))
+		if err != nil {
+			return nil, fmt.Errorf("when: %w", err)
+		}
+		return func(v any) bool { return re.MatchString(ruleText(v)) }, nil
+	}
+
+	for _, op := range []string{"<=", ">=", "==", "!=", "<", ">"} {
+		arg, ok := strings.CutPrefix(when, op)
+		if !ok {
+			continue
+		}
+		arg = unquoteYAML(strings.TrimSpace(arg))
+		want, numeric := ruleNumber(arg)
+		if !numeric && op != "==" && op != "!=" {
+			return nil, fmt.Errorf("when: %s needs a number, got %q", op, arg)
+		}
+		return func(v any) bool {
+			if numeric {
+				got, ok := ruleNumber(v)
+				if !ok {
+					return op == "!="
+				}
+				switch op {
+				case "<":
+					return got < want
+				case "<=":
+					return got <= want
+				case ">":
+					return got > want
+				case ">=":
+					return got >= want
+				case "==":
+					return got == want
+				default:
+					return got != want
+				}
+			}
+			if op == "==" {
+				return ruleText(v) == arg
+			}
+			return ruleText(v) != arg
+		}, nil
+	}
+	return nil, fmt.Errorf("when: unknown predicate %q", when)
+}
+
+// ruleText - the value as text for the predicates (nil — empty).
+func ruleText(v any) string {
+	if v == nil {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprint(v))
+}
+
+// ruleNumber - the value as a number: numeric types and strings like "-1 234,50".
+func ruleNumber(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	s := strings.NewReplacer(" ", "", " ", "", ",", ".").Replace(ruleText(v))
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil
+}
+
+// applyFormatRules appends the rule function to the pipeline of every output tag whose field
+// has rules: {.balance | money} → {.balance | money | _format "balance" .balance}.
+// The function gets the raw value for the predicates and the output of the tag for the formatting.
+func (d *Docx) applyFormatRules(tree *parse.Tree) {
+	if len(d.formatRules) == 0 || tree == nil {
+		return
+	}
+	walkOutputFields(tree, func(n *parse.ActionNode, field *parse.FieldNode) {
+		name := strings.Join(field.Ident, ".")
+		if !d.hasFormatRule(name) {
+			return
+		}
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      n.Pos,
+			Args: []parse.Node{
+				parse.NewIdentifier(formatRuleFunc).SetTree(tree).SetPos(n.Pos),
+				&parse.StringNode{NodeType: parse.NodeString, Pos: n.Pos, Quoted: strconv.Quote(name), Text: name},
+				field.Copy(),
+			},
+		})
+	})
+}
+
+// walkOutputFields calls fn for every output tag of the tree that starts with a field: {.a}, {.a | mod}.
+func walkOutputFields(tree *parse.Tree, fn func(n *parse.ActionNode, field *parse.FieldNode)) {
+	walkActions(tree, func(n *parse.ActionNode) {
+		if field, ok := n.Pipe.Cmds[0].Args[0].(*parse.FieldNode); ok {
+			fn(n, field)
+		}
+	})
+}
+
+// walkActions calls fn for every output tag of the tree: {.a | mod}, {"literal" | mod}.
+func walkActions(tree *parse.Tree, fn func(n *parse.ActionNode)) {
+	var walk func(n parse.Node)
+	walk = func(n parse.Node) {
+		switch n := n.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Nodes {
+				walk(c)
+			}
+		case *parse.IfNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.ActionNode:
+			if n.Pipe == nil || len(n.Pipe.Decl) > 0 || len(n.Pipe.Cmds) == 0 || len(n.Pipe.Cmds[0].Args) == 0 {
+				return
+			}
+			fn(n)
+		}
+	}
+	walk(tree.Root)
+}
+
+func (d *Docx) hasFormatRule(field string) bool {
+	for _, r := range d.formatRules {
+		if ok, _ := path.Match(r.field, field); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// formatValue - the template function of the rules: wraps the output of the tag into a styled run
+// with the properties of every matching rule. Drawings and other raw XML are left as they are.
+func (d *Docx) formatValue(field string, raw, out any) any {
+	var props []string
+	for _, r := range d.formatRules {
+		if ok, _ := path.Match(r.field, field); ok && r.match(raw) {
+			props = append(props, r.props...)
+		}
+	}
+	if len(props) == 0 || out == nil {
+		return out
+	}
+	if x, ok := out.(modifiers.RawXML); ok && strings.Contains(string(x), "<w:") && !modifiers.IsStyledRun(string(x)) {
+		return out
+	}
+	return modifiers.StyledRun(out, props...)
+}
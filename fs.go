@@ -0,0 +1,58 @@
+package docxgen
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// ============================================================================
+// The directory of the template as a file system: includes and pictures without the disk (WASM)
+// ============================================================================
+
+// WithFS - the files next to the template come from fsys instead of the disk: the documents of
+// [include/...] and [annex/...] and the pictures by path. A template opened from memory (OpenBytes,
+// the browser build, where there is no disk) resolves its includes this way:
+//
+//	doc, _ := docxgen.OpenBytes(template, docxgen.WithFS(fstest.MapFS{
+//		"blocks/sign.docx": {Data: sign},
+//	}))
+//
+// The paths are relative to the root of fsys, as to the directory of the template on the disk, and
+// may not leave it.
+func WithFS(fsys fs.FS) OpenOption {
+	return func(d *Docx) { d.fsys = fsys }
+}
+
+// SetFS sets the file system of the files next to the template, see WithFS.
+func (d *Docx) SetFS(fsys fs.FS) {
+	d.fsys = fsys
+}
+
+// hasTemplateDir - whether the template has files next to it: a file system or a path on the disk.
+func (d *Docx) hasTemplateDir() bool {
+	return d.fsys != nil || d.sourcePath != ""
+}
+
+// readFS reads the file rel of the file system of the template, at most limit bytes (0 — no limit).
+func (d *Docx) readFS(rel string, limit int64) ([]byte, error) {
+	name := path.Clean(strings.ReplaceAll(rel, "\\", "/"))
+	if !fs.ValidPath(name) {
+		return nil, fmt.Errorf("forbidden path %s: it leaves the directory of the template", rel)
+	}
+	f, err := d.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if limit <= 0 {
+		return io.ReadAll(f)
+	}
+	data, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err == nil && int64(len(data)) > limit {
+		err = fmt.Errorf("%w: %s is larger than %d bytes", ErrLimitExceeded, rel, limit)
+	}
+	return data, err
+}
@@ -0,0 +1,220 @@
+package docxgen
+
+import (
+	"docxgen/modifiers"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// [gantt/tasks start:from end:to] — a timeline of the tasks: a row per task, a column per day/week/month
+// ============================================================================
+
+// ganttOpenPrefix - the start of the timeline marker.
+const ganttOpenPrefix = "[gantt/"
+
+// ganttMaxColumns - the most time columns; an automatic step takes the finest unit that fits.
+const ganttMaxColumns = 31
+
+// ganttBarFill - the default fill of the task bars.
+const ganttBarFill = "4472C4"
+
+// ganttNameShare - the share of the text width taken by the task names.
+const ganttNameShare = 0.3
+
+// ganttSpec - the options of the [gantt/name ...] marker.
+//   - name — the data key of the tasks;
+//   - textField, startField, endField — the fields of a task: its name, first and last day;
+//   - step — "day", "week", "month" or "" (by the length of the plan);
+//   - fill — the color of the bars.
+type ganttSpec struct {
+	name                            string
+	textField, startField, endField string
+	step                            string
+	fill                            string
+}
+
+// parseGanttSpec reads "tasks text:title start:from end:to step:week color:2E7D32".
+func parseGanttSpec(spec string) (ganttSpec, error) {
+	g := ganttSpec{textField: "title", startField: "start", endField: "end", fill: ganttBarFill}
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return g, fmt.Errorf("want [gantt/name ...], got %q", spec)
+	}
+	g.name = fields[0]
+	for _, f := range fields[1:] {
+		key, val, _ := strings.Cut(f, ":")
+		switch strings.ToLower(key) {
+		case "text":
+			g.textField = val
+		case "start":
+			g.startField = val
+		case "end":
+			g.endField = val
+		case "step":
+			switch val = strings.ToLower(val); val {
+			case "day", "week", "month":
+				g.step = val
+			default:
+				return g, fmt.Errorf("step:%s is not day, week or month", val)
+			}
+		case "color":
+			if hex, ok := modifiers.ParseColor(val); ok {
+				g.fill = hex
+			}
+		}
+	}
+	return g, nil
+}
+
+// ganttTask - a row of the timeline; the days are at midnight, without dates the row has no bar.
+type ganttTask struct {
+	text       string
+	start, end time.Time
+	dated      bool
+}
+
+// ResolveGantts replaces the paragraphs with [gantt/tasks] by a timeline table: the first column has
+// the names of the tasks, the others are days, weeks (from Monday) or months of the plan, and the cells
+// a task covers are shaded, as in a Gantt chart. A task is a map with title, start and end (time.Time,
+// "2025-11-05", "05.11.2025"); text:, start:, end: take other fields, a task without end takes one day.
+// Without step: the plan is shown by days, by weeks if it is longer than ganttMaxColumns days,
+// else by months.
+//
+// A broken marker, tasks that are not a list or have no dates are removed with the bad_gantt warning.
+func (d *Docx) ResolveGantts(body string, data map[string]any) string {
+	return replaceMarkerParagraphs(body, ganttOpenPrefix, func(spec string) string {
+		return d.renderGantt(spec, data)
+	})
+}
+
+// renderGantt builds the table of one marker; "" — nothing to show (a warning is issued).
+func (d *Docx) renderGantt(specText string, data map[string]any) string {
+	spec, err := parseGanttSpec(specText)
+	if err != nil {
+		d.warn(WarnBadGantt, specText, "%v", err)
+		return ""
+	}
+	raw := blockValue(data, spec.name)
+	items, ok := normalizeItems(raw)
+	if !ok || len(items) == 0 {
+		d.warn(WarnBadGantt, spec.name, "the tasks are %T, not a list", raw)
+		return ""
+	}
+	if !d.takeRows("gantt "+spec.name, len(items)) {
+		return ""
+	}
+
+	// -------- The tasks and the span of the plan --------
+	var tasks []ganttTask
+	var first, last time.Time
+	for _, it := range items {
+		rec := normalizeItem(it).mapVal
+		task := ganttTask{text: modifiers.ValueText(blockValue(rec, spec.textField))}
+		start, ok := modifiers.DateValue(blockValue(rec, spec.startField))
+		if ok {
+			task.start = dayOf(start)
+			task.end = task.start
+			if end, ok := modifiers.DateValue(blockValue(rec, spec.endField)); ok && !dayOf(end).Before(task.start) {
+				task.end = dayOf(end)
+			}
+			task.dated = true
+			if first.IsZero() || task.start.Before(first) {
+				first = task.start
+			}
+			if task.end.After(last) {
+				last = task.end
+			}
+		}
+		tasks = append(tasks, task)
+	}
+	if first.IsZero() {
+		d.warn(WarnBadGantt, spec.name, "no task has a %s date", spec.startField)
+		return ""
+	}
+
+	// -------- The time columns --------
+	step := spec.step
+	if step == "" {
+		step = "month"
+		for _, s := range []string{"day", "week"} {
+			if len(ganttUnits(first, last, s)) <= ganttMaxColumns {
+				step = s
+				break
+			}
+		}
+	}
+	units := ganttUnits(first, last, step)
+
+	rows := [][]string{make([]string, len(units)+1)}
+	rows[0][0] = cellText("Задача", true, false)
+	for i, u := range units {
+		rows[0][i+1] = ganttHeaderCell(u, step)
+	}
+	for _, task := range tasks {
+		row := make([]string, len(units)+1)
+		row[0] = cellText(task.text, false, false)
+		rows = append(rows, row)
+	}
+
+	usable := d.CurrentPageLayout().UsableWidth().Twips()
+	nameW := int(float64(usable) * ganttNameShare)
+	widths := []int{nameW}
+	for range units {
+		widths = append(widths, (usable-nameW)/len(units))
+	}
+	return columnGridTable(rows, widths, func(r, c int) string {
+		if r == 0 || c == 0 {
+			return ""
+		}
+		task, from := tasks[r-1], units[c-1]
+		if task.dated && !task.start.After(ganttNext(from, step).AddDate(0, 0, -1)) && !task.end.Before(from) {
+			return spec.fill
+		}
+		return ""
+	})
+}
+
+// dayOf - the midnight of the day of t.
+func dayOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// ganttUnits - the first days of the days, weeks (Mondays) or months from first to last.
+func ganttUnits(first, last time.Time, step string) []time.Time {
+	cur := first
+	switch step {
+	case "week":
+		cur = first.AddDate(0, 0, -((int(first.Weekday()) + 6) % 7))
+	case "month":
+		cur = time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	var units []time.Time
+	for ; !cur.After(last); cur = ganttNext(cur, step) {
+		units = append(units, cur)
+	}
+	return units
+}
+
+// ganttNext - the start of the unit after the one starting at t.
+func ganttNext(t time.Time, step string) time.Time {
+	switch step {
+	case "week":
+		return t.AddDate(0, 0, 7)
+	case "month":
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// ganttHeaderCell - the label of a time column in small type: "05.11" for a day or a week (its Monday),
+// "11.2025" for a month.
+func ganttHeaderCell(t time.Time, step string) string {
+	label := t.Format("02.01")
+	if step == "month" {
+		label = t.Format("01.2006")
+	}
+	return `<w:p><w:pPr><w:jc w:val="center"/></w:pPr><w:r><w:rPr><w:b/><w:sz w:val="14"/></w:rPr>` +
+		`<w:t xml:space="preserve">` + label + `</w:t></w:r></w:p>`
+}
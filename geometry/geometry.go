@@ -0,0 +1,166 @@
+// Package geometry - units of length used in WordprocessingML and DrawingML
+// and their conversion: EMU (drawings), twips (page setup, tables), points, millimeters, pixels.
+//
+// Lengths are stored as EMU (English Metric Units) - the smallest unit of OOXML, so conversion between
+// millimeters, points and twips is exact. Custom modifiers that emit drawings should use this package
+// instead of their own constants.
+//
+// Example:
+//
+//	w, _ := geometry.ParseLength("40mm", 0)
+//	cx := w.EMU()                                   // 1440000
+//	pct, _ := geometry.ParseLength("50%", pageWidth) // half of the page width
+package geometry
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Length - a length in EMU. 1 mm = 36000 EMU, 1 pt = 12700 EMU, 1 twip = 635 EMU, 1 in = 914400 EMU.
+type Length int64
+
+// Units in EMU.
+const (
+	EMU   Length = 1
+	Twip  Length = 635
+	Point Length = 12700
+	MM    Length = 36000
+	CM    Length = 360000
+	Inch  Length = 914400
+	Pixel Length = 9525 // CSS pixel: 1/96 inch
+)
+
+// A4 paper size.
+const (
+	A4Width  = 210 * MM
+	A4Height = 297 * MM
+)
+
+// FromMM - a length from millimeters.
+func FromMM(mm float64) Length { return Length(math.Round(mm * float64(MM))) }
+
+// FromPt - a length from points.
+func FromPt(pt float64) Length { return Length(math.Round(pt * float64(Point))) }
+
+// FromTwips - a length from twips (twentieths of a point), the unit of w:pgSz, w:pgMar, w:tblW.
+func FromTwips(tw int) Length { return Length(tw) * Twip }
+
+// EMU - the length in EMU, as used in wp:extent and a:ext.
+func (l Length) EMU() int { return int(l) }
+
+// MM - the length in millimeters.
+func (l Length) MM() float64 { return float64(l) / float64(MM) }
+
+// Pt - the length in points.
+func (l Length) Pt() float64 { return float64(l) / float64(Point) }
+
+// Twips - the length in twips, rounded.
+func (l Length) Twips() int { return int(math.Round(float64(l) / float64(Twip))) }
+
+// HalfPoints - the length in half-points, the unit of w:sz (font size).
+func (l Length) HalfPoints() int { return int(math.Round(float64(l) / float64(Point) * 2)) }
+
+// Pixels - the length in pixels at the given density (dots per inch).
+func (l Length) Pixels(dpi float64) int { return int(math.Round(float64(l) / float64(Inch) * dpi)) }
+
+// String - the length in millimeters: "40mm".
+func (l Length) String() string {
+	return strconv.FormatFloat(l.MM(), 'f', -1, 64) + "mm"
+}
+
+// units - suffixes accepted by ParseLength; longer suffixes go first.
+var units = []struct {
+	suffix string
+	unit   Length
+}{
+	{"emu", EMU}, {"twip", Twip}, {"tw", Twip}, {"mm", MM}, {"cm", CM},
+	{"pt", Point}, {"in", Inch}, {"px", Pixel},
+}
+
+// ParseLength parses a length with a unit: "40mm", "2.5cm", "12pt", "1in", "96px", "1440tw", "360000emu".
+// "50%" is a percentage of ref (for example, the usable page width). A number without a unit is millimeters.
+// A decimal comma is accepted.
+func ParseLength(s string, ref Length) (Length, error) {
+	orig := s
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return 0, fmt.Errorf("geometry: empty length")
+	}
+
+	unit := MM
+	percent := false
+	if v, ok := strings.CutSuffix(s, "%"); ok {
+		s, percent = v, true
+	} else {
+		for _, u := range units {
+			if v, ok := strings.CutSuffix(s, u.suffix); ok {
+				s, unit = v, u.unit
+				break
+			}
+		}
+	}
+
+	f, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(s), ",", "."), 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("geometry: invalid length %q", orig)
+	}
+	if percent {
+		return Length(math.Round(float64(ref) * f / 100)), nil
+	}
+	return Length(math.Round(f * float64(unit))), nil
+}
+
+// ParseSize parses "W*H" (for example "40mm*15mm", "80%*10mm"); percentages are taken of refW and refH.
+// If the height is omitted ("40mm"), h is 0 and the caller chooses it (usually by the aspect ratio).
+func ParseSize(s string, refW, refH Length) (w, h Length, err error) {
+	ws, hs, hasH := strings.Cut(s, "*")
+	if w, err = ParseLength(ws, refW); err != nil {
+		return 0, 0, err
+	}
+	if hasH {
+		if h, err = ParseLength(hs, refH); err != nil {
+			return 0, 0, err
+		}
+	}
+	if w < 0 || h < 0 {
+		return 0, 0, fmt.Errorf("geometry: negative size %q", s)
+	}
+	return w, h, nil
+}
+
+// Sides - distances on the four sides (margins, distances from the text).
+type Sides struct {
+	Top, Right, Bottom, Left Length
+}
+
+// ParseSides parses distances separated by "/" (millimeters by default, any unit of ParseLength):
+//
+//	"5"        — all sides;
+//	"5/3"      — top and bottom 5, left and right 3;
+//	"5/3/7"    — top 5, left and right 3, bottom 7;
+//	"5/3/7/2"  — top, right, bottom, left.
+func ParseSides(s string) (Sides, error) {
+	parts := strings.Split(s, "/")
+	v := make([]Length, len(parts))
+	for i, p := range parts {
+		l, err := ParseLength(p, 0)
+		if err != nil {
+			return Sides{}, err
+		}
+		v[i] = l
+	}
+	switch len(v) {
+	case 1:
+		return Sides{v[0], v[0], v[0], v[0]}, nil
+	case 2:
+		return Sides{v[0], v[1], v[0], v[1]}, nil
+	case 3:
+		return Sides{v[0], v[1], v[2], v[1]}, nil
+	case 4:
+		return Sides{v[0], v[1], v[2], v[3]}, nil
+	}
+	return Sides{}, fmt.Errorf("geometry: invalid sides %q", s)
+}
@@ -10,9 +10,10 @@ require (
 	github.com/normiridium/rusnum v0.0.0-20251125194557-f17083a5ee4a
 	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
 	golang.org/x/image v0.32.0
+	golang.org/x/net v0.46.0
 )
 
 require (
-	golang.org/x/sys v0.18.0 // indirect
+	golang.org/x/sys v0.37.0 // indirect
 	golang.org/x/text v0.30.0 // indirect
 )
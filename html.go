@@ -0,0 +1,577 @@
+package docxgen
+
+import (
+	"docxgen/geometry"
+	"docxgen/modifiers"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ============================================================================
+// {text|html} — rich text from the data as real paragraphs, lists and tables
+// ============================================================================
+
+// Markers around the blocks of the html modifier: the modifier closes the paragraph of the tag,
+// outputs its own paragraphs and reopens it; collapseBlockBreakouts then removes the halves
+// of the tag paragraph that stayed empty.
+const (
+	blockBreakoutOpen  = "</w:t></w:r></w:p><!--docxgen:blocks-->"
+	blockBreakoutClose = "<!--/docxgen:blocks--><w:p><w:r><w:t>"
+)
+
+// HTML - converts an HTML fragment of the data (rich text of a CMS) into WordprocessingML:
+//
+//	{description|html}
+//
+// Supported: <p>, <div>, <h1>…<h6> (paragraphs), <b>/<strong>, <i>/<em>, <u>, <s>/<del>,
+// <sup>, <sub>, <br>, <a href> (an external link), <ul>/<ol>/<li> (with nesting: "•" and "1." markers
+// with an indent), <table>/<tr>/<td>/<th> (a table over the text width with thin borders).
+// Other tags are dropped with their text kept, <script> and <style> are dropped entirely.
+// HTML entities, unquoted attributes and unclosed tags (<br>, <li> without </li>) are accepted.
+//
+// The blocks replace the paragraph of the tag and take its properties (a list item keeps its indent),
+// their runs take the properties of the run of the tag (font, size); text before and after the tag
+// stays in paragraphs of its own. {*description|html*} works the same way.
+func (d *Docx) HTML(value string) modifiers.RawXML {
+	if strings.TrimSpace(value) == "" {
+		return ""
+	}
+	part := d.activePart
+	if part == "" {
+		part = "document"
+	}
+	c := &htmlConverter{d: d, part: part}
+	c.blocks = []*strings.Builder{{}}
+	c.convert(value)
+	body := c.blocks[0].String()
+	if body == "" {
+		return ""
+	}
+	return modifiers.RawXML(blockBreakoutOpen + body + blockBreakoutClose)
+}
+
+// htmlList - an open <ul>/<ol>.
+type htmlList struct {
+	ordered bool
+	n       int
+}
+
+// htmlTable - an open <table>: finished rows, the cells of the current row and the open cell
+// ("td", "th" or "" when there is none).
+type htmlTable struct {
+	rows  [][]string
+	cells []string
+	cell  string
+}
+
+// htmlConverter - the state of HTML conversion.
+//   - blocks — the output: the document level and the content of open table cells;
+//   - runs, pPr — the current paragraph;
+//   - bold, italic, ... — nesting depth of the inline formatting;
+//   - links — the relationship ids of open <a>, linkOpen — <w:hyperlink> is open in runs.
+type htmlConverter struct {
+	d    *Docx
+	part string
+
+	blocks []*strings.Builder
+	runs   strings.Builder
+	pPr    string
+
+	bold, italic, underline, strike, sup, sub int
+	links                                     []string
+	linkOpen                                  bool
+
+	lists  []htmlList
+	tables []*htmlTable
+	skip   int // inside <script>/<style>
+	space  bool
+}
+
+var reHTMLSpace = regexp.MustCompile(`\s+`)
+
+// convert reads the fragment with the HTML tokenizer: unquoted attributes, a bare "<" or "&"
+// of the text and unclosed tags are read the way a browser reads them.
+func (c *htmlConverter) convert(src string) {
+	z := html.NewTokenizer(strings.NewReader(src))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if err := z.Err(); !errors.Is(err, io.EOF) {
+				c.d.warn(WarnBadHTML, "html", "%v; the rest of the fragment is dropped", err)
+			}
+			break
+		}
+		t := z.Token()
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			c.start(t.Data, t.Attr)
+		case html.EndTagToken:
+			c.end(t.Data)
+		case html.TextToken:
+			if c.skip == 0 {
+				c.text(t.Data)
+			}
+		}
+	}
+	c.flush()
+	for len(c.tables) > 0 {
+		c.endTable()
+	}
+}
+
+func (c *htmlConverter) start(name string, attrs []html.Attribute) {
+	if c.skip > 0 {
+		if name == "script" || name == "style" {
+			c.skip++
+		}
+		return
+	}
+	switch name {
+	case "script", "style":
+		c.skip++
+	case "p", "div", "blockquote":
+		c.flush()
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		c.flush()
+		c.bold++
+	case "br":
+		c.run("<w:br/>")
+		c.space = false
+	case "b", "strong":
+		c.bold++
+	case "i", "em":
+		c.italic++
+	case "u", "ins":
+		c.underline++
+	case "s", "strike", "del":
+		c.strike++
+	case "sup":
+		c.sup++
+	case "sub":
+		c.sub++
+	case "a":
+		href := ""
+		for _, a := range attrs {
+			if a.Key == "href" {
+				href = strings.TrimSpace(a.Val)
+			}
+		}
+		id := ""
+		if lower := strings.ToLower(href); strings.HasPrefix(lower, "http://") ||
+			strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "mailto:") {
+			id = c.d.Rels().AddHyperlink(c.part, href)
+		}
+		c.closeLink()
+		c.links = append(c.links, id)
+	case "ul", "ol":
+		c.flush()
+		c.lists = append(c.lists, htmlList{ordered: name == "ol"})
+	case "li":
+		c.flush()
+		c.listItem()
+	case "table":
+		c.flush()
+		c.tables = append(c.tables, &htmlTable{})
+	case "tr":
+		if t := c.table(); t != nil {
+			c.endCell(t)
+			c.endRow(t)
+		}
+	case "td", "th":
+		t := c.table()
+		if t == nil {
+			return
+		}
+		c.endCell(t) // <td>1<td>2: the open cell ends where the next one starts
+		c.flush()
+		c.blocks = append(c.blocks, &strings.Builder{})
+		t.cell = name
+		if name == "th" {
+			c.bold++
+		}
+	}
+}
+
+func (c *htmlConverter) end(name string) {
+	if c.skip > 0 {
+		if name == "script" || name == "style" {
+			c.skip--
+		}
+		return
+	}
+	switch name {
+	case "p", "div", "blockquote", "li":
+		c.flush()
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		c.flush()
+		c.bold = max(0, c.bold-1)
+	case "b", "strong":
+		c.bold = max(0, c.bold-1)
+	case "i", "em":
+		c.italic = max(0, c.italic-1)
+	case "u", "ins":
+		c.underline = max(0, c.underline-1)
+	case "s", "strike", "del":
+		c.strike = max(0, c.strike-1)
+	case "sup":
+		c.sup = max(0, c.sup-1)
+	case "sub":
+		c.sub = max(0, c.sub-1)
+	case "a":
+		if len(c.links) > 0 {
+			c.closeLink()
+			c.links = c.links[:len(c.links)-1]
+		}
+	case "ul", "ol":
+		c.flush()
+		if len(c.lists) > 0 {
+			c.lists = c.lists[:len(c.lists)-1]
+		}
+	case "td", "th":
+		if t := c.table(); t != nil {
+			c.endCell(t)
+		}
+	case "tr":
+		if t := c.table(); t != nil {
+			c.endCell(t)
+			c.endRow(t)
+		}
+	case "table":
+		if c.table() != nil {
+			c.endTable()
+		}
+	}
+}
+
+// text adds the text with the HTML whitespace rules: any run of spaces and line breaks is one space,
+// spaces at the start of a paragraph are dropped.
+func (c *htmlConverter) text(s string) {
+	s = reHTMLSpace.ReplaceAllString(s, " ")
+	if s == " " && (c.runs.Len() == 0 || c.space) {
+		return
+	}
+	if c.runs.Len() == 0 || c.space {
+		s = strings.TrimLeft(s, " ")
+	}
+	if s == "" {
+		return
+	}
+	c.space = strings.HasSuffix(s, " ")
+	c.run(`<w:t xml:space="preserve">` + xmlEscape(s) + `</w:t>`)
+}
+
+// run adds a run with the current formatting; inside <a> it goes into <w:hyperlink>.
+func (c *htmlConverter) run(content string) {
+	if n := len(c.links); n > 0 && c.links[n-1] != "" && !c.linkOpen {
+		c.runs.WriteString(`<w:hyperlink r:id="` + c.links[n-1] + `" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`)
+		c.linkOpen = true
+	}
+	var rPr strings.Builder
+	if c.bold > 0 {
+		rPr.WriteString("<w:b/><w:bCs/>")
+	}
+	if c.italic > 0 {
+		rPr.WriteString("<w:i/><w:iCs/>")
+	}
+	if c.strike > 0 {
+		rPr.WriteString("<w:strike/>")
+	}
+	if c.linkOpen {
+		rPr.WriteString(`<w:color w:val="0563C1"/>`)
+	}
+	if c.underline > 0 || c.linkOpen {
+		rPr.WriteString(`<w:u w:val="single"/>`)
+	}
+	switch {
+	case c.sup > 0:
+		rPr.WriteString(`<w:vertAlign w:val="superscript"/>`)
+	case c.sub > 0:
+		rPr.WriteString(`<w:vertAlign w:val="subscript"/>`)
+	}
+	// the run takes the properties of the run of the tag (font, size), see collapseBlockBreakouts
+	c.runs.WriteString("<w:r>" + modifiers.InheritRunMarker)
+	if rPr.Len() > 0 {
+		c.runs.WriteString("<w:rPr>" + rPr.String() + "</w:rPr>")
+	}
+	c.runs.WriteString(content + "</w:r>")
+}
+
+func (c *htmlConverter) closeLink() {
+	if c.linkOpen {
+		c.runs.WriteString("</w:hyperlink>")
+		c.linkOpen = false
+	}
+}
+
+// flush ends the current paragraph, if it has anything.
+func (c *htmlConverter) flush() {
+	c.closeLink()
+	if c.runs.Len() > 0 {
+		out := c.blocks[len(c.blocks)-1]
+		out.WriteString("<w:p>")
+		if c.pPr != "" {
+			out.WriteString("<w:pPr>" + c.pPr + "</w:pPr>")
+		}
+		out.WriteString(c.runs.String())
+		out.WriteString("</w:p>")
+	}
+	c.runs.Reset()
+	c.pPr = ""
+	c.space = false
+}
+
+// listItem starts the paragraph of <li>: the marker and the hanging indent of the nesting level.
+func (c *htmlConverter) listItem() {
+	level := len(c.lists)
+	if level == 0 {
+		c.lists = append(c.lists, htmlList{})
+		level = 1
+	}
+	l := &c.lists[level-1]
+	l.n++
+	marker := "•"
+	if l.ordered {
+		marker = fmt.Sprintf("%d.", l.n)
+	}
+	c.pPr = fmt.Sprintf(`<w:ind w:left="%d" w:hanging="360"/>`, 360*(level+1))
+	c.run(`<w:t xml:space="preserve">` + marker + "</w:t><w:tab/>")
+	c.space = true
+}
+
+func (c *htmlConverter) table() *htmlTable {
+	if len(c.tables) == 0 {
+		return nil
+	}
+	return c.tables[len(c.tables)-1]
+}
+
+// endCell ends the open cell of the table, if there is one; a stray </td> changes nothing.
+func (c *htmlConverter) endCell(t *htmlTable) {
+	if t.cell == "" || len(c.blocks) < 2 {
+		return
+	}
+	c.flush()
+	cell := c.blocks[len(c.blocks)-1].String()
+	c.blocks = c.blocks[:len(c.blocks)-1]
+	if !strings.HasSuffix(cell, "</w:p>") {
+		cell += "<w:p/>" // a cell ends with a paragraph
+	}
+	t.cells = append(t.cells, cell)
+	if t.cell == "th" {
+		c.bold = max(0, c.bold-1)
+	}
+	t.cell = ""
+}
+
+func (c *htmlConverter) endRow(t *htmlTable) {
+	if len(t.cells) > 0 {
+		t.rows = append(t.rows, t.cells)
+		t.cells = nil
+	}
+}
+
+// endTable writes the table over the width of the text; the rows are padded to the widest one.
+func (c *htmlConverter) endTable() {
+	t := c.table()
+	c.endCell(t) // a cell that was not closed
+	c.flush()
+	c.endRow(t)
+	c.tables = c.tables[:len(c.tables)-1]
+
+	// the text width of the section of the tag, so the table stays within its margins
+	pageW, _ := c.d.GetUsableSizeEMU()
+	c.blocks[len(c.blocks)-1].WriteString(gridTable(t.rows, geometry.Length(pageW).Twips()))
+}
+
+// gridTable - a table over the width of the text (usable, in twips) with thin borders and equal columns;
+// the cells are ready block XML, the rows are padded to the widest one.
+func gridTable(rows [][]string, usable int) string {
+	return shadedGridTable(rows, usable, nil)
+}
+
+// shadedGridTable is gridTable whose cells may have a fill: shade gives "RRGGBB" or "" for the cell.
+func shadedGridTable(rows [][]string, usable int, shade func(row, col int) string) string {
+	cols := 0
+	for _, r := range rows {
+		cols = max(cols, len(r))
+	}
+	if cols == 0 {
+		return ""
+	}
+	widths := make([]int, cols)
+	for i := range widths {
+		widths[i] = usable / cols
+	}
+	return columnGridTable(rows, widths, shade)
+}
+
+// columnGridTable is shadedGridTable with the given width of every column (twips).
+func columnGridTable(rows [][]string, widths []int, shade func(row, col int) string) string {
+	var out strings.Builder
+	out.WriteString(`<w:tbl><w:tblPr><w:tblW w:w="5000" w:type="pct"/><w:tblBorders>`)
+	for _, side := range []string{"top", "left", "bottom", "right", "insideH", "insideV"} {
+		fmt.Fprintf(&out, `<w:%s w:val="single" w:sz="4" w:space="0" w:color="auto"/>`, side)
+	}
+	out.WriteString(`</w:tblBorders></w:tblPr><w:tblGrid>`)
+	for _, w := range widths {
+		fmt.Fprintf(&out, `<w:gridCol w:w="%d"/>`, w)
+	}
+	out.WriteString(`</w:tblGrid>`)
+	for ri, r := range rows {
+		out.WriteString("<w:tr>")
+		for i, w := range widths {
+			cell := "<w:p/>"
+			if i < len(r) && r[i] != "" {
+				cell = r[i]
+			}
+			fill := ""
+			if shade != nil {
+				if hex := shade(ri, i); hex != "" {
+					fill = `<w:shd w:val="clear" w:color="auto" w:fill="` + hex + `"/>`
+				}
+			}
+			fmt.Fprintf(&out, `<w:tc><w:tcPr><w:tcW w:w="%d" w:type="dxa"/>%s</w:tcPr>%s</w:tc>`, w, fill, cell)
+		}
+		out.WriteString("</w:tr>")
+	}
+	out.WriteString("</w:tbl>")
+	return out.String()
+}
+
+// reParagraphProps - the properties at the start of a paragraph.
+var reParagraphProps = regexp.MustCompile(`(?s)^<w:p(?:\s[^>]*)?>(<w:pPr>.*?</w:pPr>|<w:pPr/>)?`)
+
+// collapseBlockBreakouts tidies the output of the html modifier in the executed part:
+// the halves of the tag paragraph around the blocks are removed when they are empty,
+// the paragraphs of the blocks and the text after the tag get the paragraph properties of the tag,
+// their runs — the run properties of the tag. When the tag stood
+// outside a paragraph ({*text|html*}), the closing and reopening markup is dropped.
+func collapseBlockBreakouts(body string) string {
+	const openMarker, closeMarker = "<!--docxgen:blocks-->", "<!--/docxgen:blocks-->"
+	const pClose, pOpen = "</w:t></w:r></w:p>", "<w:p><w:r><w:t>"
+	if !strings.Contains(body, openMarker) {
+		return body
+	}
+
+	var out strings.Builder
+	for {
+		i := strings.Index(body, blockBreakoutOpen)
+		if i < 0 {
+			out.WriteString(body)
+			return out.String()
+		}
+		j := strings.Index(body[i:], blockBreakoutClose)
+		if j < 0 {
+			out.WriteString(body)
+			return out.String()
+		}
+		j += i
+		blocks := body[i+len(blockBreakoutOpen) : j]
+		rest := body[j+len(blockBreakoutClose):]
+
+		// the paragraph before: from its start to the closing of the tag run
+		before := body[:i] + pClose
+		leadStart := lastParagraphStart(before)
+		lead := ""
+		if leadStart >= 0 && !strings.Contains(before[leadStart:len(before)-len("</w:p>")], "</w:p>") {
+			lead = before[leadStart:]
+			before = before[:leadStart]
+		} else {
+			before = body[:i] // outside a paragraph
+		}
+
+		// the paragraph after: the reopened tag run up to </w:p>
+		trail := ""
+		if k := strings.Index(rest, "</w:p>"); k >= 0 && paragraphStart(pOpen+rest[:k], 1) < 0 {
+			trail = pOpen + rest[:k+len("</w:p>")]
+			rest = rest[k+len("</w:p>"):]
+		}
+		// the runs of the blocks and the text after the tag keep the run properties of the tag
+		runProps := tagRunProps(lead, len(lead))
+		blocks = mergeMarkedRuns(blocks, func(int) string { return runProps })
+		if trail != "" {
+			run := "<w:r>"
+			if runProps != "" {
+				run += "<w:rPr>" + runProps + "</w:rPr>"
+			}
+			trail = "<w:p>" + run + `<w:t xml:space="preserve">` + strings.TrimPrefix(trail, pOpen)
+		}
+
+		pPr := ""
+		if m := reParagraphProps.FindStringSubmatch(lead); m != nil {
+			pPr = m[1]
+		}
+		out.WriteString(before)
+		if lead != "" && !blankParagraph(lead) {
+			if strings.Contains(pPr, "<w:sectPr") && trail != "" {
+				// the section ends with the last paragraph of the tag
+				lead = strings.Replace(lead, pPr, reSectPr.ReplaceAllString(pPr, ""), 1)
+			}
+			out.WriteString(lead)
+		}
+		// the paragraphs of the blocks take the properties of the tag paragraph (alignment, style, spacing)
+		if inherited := reSectPr.ReplaceAllString(pPr, ""); inherited != "" && inherited != "<w:pPr></w:pPr>" && inherited != "<w:pPr/>" {
+			blocks = inheritParagraphProps(blocks, strings.TrimSuffix(strings.TrimPrefix(inherited, "<w:pPr>"), "</w:pPr>"))
+		}
+		out.WriteString(blocks)
+		if trail != "" && (!blankParagraph(trail) || strings.Contains(pPr, "<w:sectPr")) {
+			out.WriteString("<w:p>" + pPr + strings.TrimPrefix(trail, "<w:p>"))
+		}
+		body = rest
+	}
+}
+
+// inheritParagraphProps gives every paragraph of the blocks the properties of the tag paragraph
+// (the content of its <w:pPr>); the own properties of a paragraph, like the indent of a list item, win.
+func inheritParagraphProps(blocks, pPr string) string {
+	var out strings.Builder
+	for {
+		i := strings.Index(blocks, "<w:p>")
+		if i < 0 {
+			out.WriteString(blocks)
+			return out.String()
+		}
+		i += len("<w:p>")
+		out.WriteString(blocks[:i])
+		blocks = blocks[i:]
+		own := ""
+		if strings.HasPrefix(blocks, "<w:pPr>") {
+			if end := strings.Index(blocks, "</w:pPr>"); end >= 0 {
+				own = blocks[len("<w:pPr>"):end]
+				blocks = blocks[end+len("</w:pPr>"):]
+			}
+		}
+		out.WriteString("<w:pPr>" + modifiers.MergeParagraphProps(pPr, own) + "</w:pPr>")
+	}
+}
+
+// lastParagraphStart - the start of the last <w:p> or <w:p ...> in s, -1 if there is none.
+func lastParagraphStart(s string) int {
+	last := -1
+	for pos := 0; ; {
+		i := paragraphStart(s, pos)
+		if i < 0 {
+			return last
+		}
+		last = i
+		pos = i + 1
+	}
+}
+
+// blankParagraph - the paragraph has no text, drawings, fields or breaks.
+func blankParagraph(p string) bool {
+	if strings.TrimSpace(extractParagraphText(p)) != "" {
+		return false
+	}
+	for _, el := range []string{"<w:drawing", "<w:pict", "<w:object", "<w:fldChar", "<w:fldSimple", "<w:br", "<w:tab/>", "<w:sym"} {
+		if strings.Contains(p, el) {
+			return false
+		}
+	}
+	return true
+}
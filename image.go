@@ -0,0 +1,349 @@
+package docxgen
+
+import (
+	"bytes"
+	"docxgen/geometry"
+	"docxgen/modifiers"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// maxImageBytes - the largest picture the image modifier loads (20 MB).
+const maxImageBytes = 20 << 20
+
+// The limits of the download of a picture by URL: the whole request with its redirects,
+// and how many redirects it may follow.
+const (
+	imageFetchTimeout = 15 * time.Second
+	maxImageRedirects = 3
+)
+
+// Image - inserts a picture from the data: {photo|image:40mm*30mm:inline}.
+//
+// The value is one of:
+//   - base64 of the file or a data URI ("data:image/png;base64,...");
+//   - an http(s) URL of a host allowed with SetImageHosts (by default no URL is fetched);
+//   - a path to the file relative to the directory of the template (it may not leave the directory).
+//
+// PNG, JPEG, GIF, BMP and TIFF go into the document as they are, WebP is converted to PNG.
+// The options are those of qrcode: inline (default) / anchor, wrapping, left/center/right,
+// top/middle/bottom, "5/5" distances, border, crop in "%". The size is "40mm*30mm"; "40mm", "80%*"
+// (of the text width) or "*30mm" set one side and the other keeps the aspect ratio. Without a size
+// the picture takes its own size at 96 dpi, but not wider than the text.
+// A picture that cannot be loaded is skipped with the bad_image warning.
+func (d *Docx) Image(value string, opts ...string) modifiers.RawXML {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ""
+	}
+
+	// -------- Default values ----------
+	mode := "inline"
+	align := "center"
+	valign := "top"
+	var sizeW, sizeH geometry.Length
+	crop := 0.0
+	hasBorder := false
+	var dist geometry.Sides
+	wrap := defaultAnchorWrap
+
+	pageW, pageH := d.GetUsableSizeEMU()
+	refW, refH := geometry.Length(pageW), geometry.Length(pageH)
+
+	// -------- Parse the parameters ----------
+	for _, token := range opts {
+		token = strings.TrimSpace(token)
+		switch {
+		case token == "anchor" || token == "inline":
+			mode = token
+		case parseWrapToken(token, &wrap):
+			// wrapping and z-order of the anchor
+		case token == "left" || token == "center" || token == "right":
+			align = token
+		case token == "top" || token == "middle" || token == "bottom":
+			if token == "middle" {
+				token = "center"
+			}
+			valign = token
+		case token == "border":
+			hasBorder = true
+		case strings.HasPrefix(token, "*"):
+			// height only: "*30mm"
+			if h, err := geometry.ParseLength(token[1:], refH); err == nil && h > 0 {
+				sizeW, sizeH = 0, h
+			}
+		case strings.HasSuffix(token, "*"):
+			// width only: "80%*"
+			if w, err := geometry.ParseLength(strings.TrimSuffix(token, "*"), refW); err == nil && w > 0 {
+				sizeW, sizeH = w, 0
+			}
+		case strings.Contains(token, "*"):
+			if w, h, err := geometry.ParseSize(token, refW, refH); err == nil {
+				sizeW, sizeH = w, h
+			}
+		case strings.HasSuffix(token, "%"):
+			crop, _ = strconv.ParseFloat(strings.TrimSuffix(token, "%"), 64)
+		case strings.Contains(token, "/"):
+			if sides, err := geometry.ParseSides(token); err == nil {
+				dist = sides
+			}
+		default:
+			if w, err := geometry.ParseLength(token, refW); err == nil && w > 0 {
+				sizeW, sizeH = w, 0
+			}
+		}
+	}
+
+	// -------- Load the picture --------
+	data, err := d.loadImage(value)
+	if err != nil {
+		d.warn(WarnBadImage, shortSource(value), "%v", err)
+		return ""
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width == 0 || cfg.Height == 0 {
+		d.warn(WarnBadImage, shortSource(value), "not a picture: %v", err)
+		return ""
+	}
+	ext := format
+	switch format {
+	case "png", "jpeg", "gif", "bmp", "tiff":
+	default:
+		// Word does not show WebP: keep the pixels in PNG
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			d.warn(WarnBadImage, shortSource(value), "%v", err)
+			return ""
+		}
+		if data, err = encodePNG(img); err != nil {
+			d.warn(WarnBadImage, shortSource(value), "%v", err)
+			return ""
+		}
+		ext = "png"
+	}
+
+	// -------- Size: the missing side keeps the aspect ratio --------
+	natW, natH := geometry.Length(cfg.Width)*geometry.Pixel, geometry.Length(cfg.Height)*geometry.Pixel
+	switch {
+	case sizeW == 0 && sizeH == 0:
+		sizeW, sizeH = natW, natH
+		if refW > 0 && sizeW > refW {
+			sizeW, sizeH = refW, natH*refW/natW
+		}
+	case sizeH == 0:
+		sizeH = natH * sizeW / natW
+	case sizeW == 0:
+		sizeW = natW * sizeH / natH
+	}
+
+	rId, base := d.AddMediaRel(data, ext)
+	id, name := d.nextDrawing(base)
+	drawing := pictureDrawing{
+		id: id, rId: rId, name: name,
+		cx: sizeW.EMU(), cy: sizeH.EMU(),
+		inline: mode == "inline",
+		align:  align, valign: valign,
+		dist: dist, wrap: wrap,
+		crop: crop, border: hasBorder,
+	}.xml()
+
+	return modifiers.RawXML(runBreakout(drawing))
+}
+
+// loadImage returns the bytes of the picture given by a data URI, an URL, base64 or a file path.
+func (d *Docx) loadImage(src string) ([]byte, error) {
+	lower := strings.ToLower(src)
+	switch {
+	case strings.HasPrefix(lower, "data:"):
+		meta, payload, ok := strings.Cut(src, ",")
+		if !ok || !strings.HasSuffix(strings.ToLower(meta), ";base64") {
+			return nil, fmt.Errorf("only base64 data URIs are supported")
+		}
+		return decodeBase64(payload)
+	case strings.HasPrefix(lower, "http://"), strings.HasPrefix(lower, "https://"):
+		return d.fetchImage(src)
+	}
+
+	if data, err := decodeBase64(src); err == nil {
+		if _, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+			return data, nil
+		}
+	}
+	return d.readImageFile(src)
+}
+
+// readImageFile reads a picture next to the template (or from its WithFS); the path may not leave
+// its directory.
+func (d *Docx) readImageFile(rel string) ([]byte, error) {
+	if d.fsys != nil {
+		return d.readFS(rel, maxImageBytes)
+	}
+	if d.sourcePath == "" {
+		return nil, fmt.Errorf("the template was opened from memory and has no directory for %s", rel)
+	}
+	full, err := securejoin.SecureJoin(filepath.Dir(d.sourcePath), rel)
+	if err != nil {
+		return nil, fmt.Errorf("forbidden image path: %w", err)
+	}
+	f, err := os.Open(full)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readLimited(f)
+}
+
+// SetImageHosts allows the image modifier to download the pictures by URL from these hosts:
+// "cdn.example.com" — the host itself, ".example.com" — the domain and its subdomains. Without
+// them no URL is fetched, so the data of a render cannot make the server request its own network.
+// The addresses of the loopback, private and link-local networks are refused after the name
+// is resolved, unless the host is allowed as that very IP ("127.0.0.1").
+func (d *Docx) SetImageHosts(hosts ...string) {
+	d.imageHosts = nil
+	for _, h := range hosts {
+		if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+			d.imageHosts = append(d.imageHosts, h)
+		}
+	}
+}
+
+// imageHostAllowed - whether the host of an URL is in SetImageHosts.
+func (d *Docx) imageHostAllowed(host string) bool {
+	host = strings.ToLower(host)
+	for _, h := range d.imageHosts {
+		if host == h || host == strings.TrimPrefix(h, ".") || (strings.HasPrefix(h, ".") && strings.HasSuffix(host, h)) {
+			return true
+		}
+	}
+	return false
+}
+
+// imageAddressAllowed checks the address a download connects to: not an internal one, unless
+// it is allowed as an IP.
+func (d *Docx) imageAddressAllowed(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+	if !internalAddress(ip) {
+		return nil
+	}
+	for _, h := range d.imageHosts {
+		if allowed, err := netip.ParseAddr(strings.Trim(h, "[]")); err == nil && allowed.Unmap() == ip {
+			return nil
+		}
+	}
+	return fmt.Errorf("the address %s is internal", ip)
+}
+
+// sharedAddressSpace - the carrier-grade NAT network (RFC 6598), internal like the private ones.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// internalAddress - whether ip is of the host or its networks: loopback, private, link-local
+// (the metadata of the clouds, 169.254.169.254), unspecified or multicast.
+func internalAddress(ip netip.Addr) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip)
+}
+
+// fetchImage downloads a picture by URL from an allowed host (see SetImageHosts): at most
+// maxImageRedirects redirects, each to an allowed host, within imageFetchTimeout
+// and maxImageBytes. No proxy is used, so the address checked is the one connected to.
+func (d *Docx) fetchImage(rawURL string) ([]byte, error) {
+	if len(d.imageHosts) == 0 {
+		return nil, fmt.Errorf("pictures by URL are off, see SetImageHosts")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if !d.imageHostAllowed(u.Hostname()) {
+		return nil, fmt.Errorf("the host %s is not allowed for pictures", u.Hostname())
+	}
+
+	dialer := &net.Dialer{
+		Timeout: imageFetchTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			return d.imageAddressAllowed(address)
+		},
+	}
+	client := &http.Client{
+		Timeout:   imageFetchTimeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext, DisableKeepAlives: true},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxImageRedirects {
+				return fmt.Errorf("more than %d redirects", maxImageRedirects)
+			}
+			if !d.imageHostAllowed(req.URL.Hostname()) {
+				return fmt.Errorf("redirect to %s: the host is not allowed for pictures", req.URL.Hostname())
+			}
+			return nil
+		},
+	}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", rawURL, resp.Status)
+	}
+	if resp.ContentLength > maxImageBytes {
+		return nil, fmt.Errorf("the picture is larger than %d MB", maxImageBytes>>20)
+	}
+	return readLimited(resp.Body)
+}
+
+// readLimited reads at most maxImageBytes.
+func readLimited(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxImageBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImageBytes {
+		return nil, fmt.Errorf("the picture is larger than %d MB", maxImageBytes>>20)
+	}
+	return data, nil
+}
+
+// decodeBase64 accepts padded and unpadded base64 with line breaks.
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.Join(strings.Fields(s), "")
+	if data, err := base64.StdEncoding.DecodeString(s); err == nil {
+		return data, nil
+	}
+	return base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// shortSource - the value for a warning: base64 is cut to a readable length.
+func shortSource(src string) string {
+	if len(src) > 64 {
+		return src[:61] + "..."
+	}
+	return src
+}
@@ -0,0 +1,116 @@
+package docxgen
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ============================================================================
+// Size limits: a pathological template or data fails the render instead of eating the memory
+// ============================================================================
+
+// ErrLimitExceeded - the template or the render went beyond the SizeLimits of the document.
+var ErrLimitExceeded = errors.New("size limit exceeded")
+
+// SizeLimits - the guardrails of a service that renders templates and data it does not control
+// (a zip bomb, a table of a million rows); zero — no limit.
+//   - MaxPartSize — the largest part of the archive unpacked (word/document.xml, a picture) and the
+//     largest part after the render, in bytes;
+//   - MaxTotalSize — the sum of the unpacked parts, on open and after the render of every part;
+//   - MaxRows — the most items the smart tables, loops ([for], [block], [repeat], [list]), pivot tables,
+//     calendars and Gantt charts of one render take together.
+type SizeLimits struct {
+	MaxPartSize  int64
+	MaxTotalSize int64
+	MaxRows      int
+}
+
+// WithSizeLimits - the limits checked while the archive is unpacked and kept for ExecuteTemplate,
+// see SetSizeLimits. The includes of the template are opened with the same limits.
+func WithSizeLimits(limits SizeLimits) OpenOption {
+	return func(d *Docx) { d.limits = limits }
+}
+
+// SetSizeLimits sets the limits of the renders of the document; going beyond one fails ExecuteTemplate
+// with ErrLimitExceeded as soon as it happens: the output of a part stops growing at MaxPartSize.
+func (d *Docx) SetSizeLimits(limits SizeLimits) {
+	d.limits = limits
+}
+
+// checkTotalSize fails when the parts and the media of the document are larger than MaxTotalSize.
+func (d *Docx) checkTotalSize() error {
+	if d.limits.MaxTotalSize <= 0 {
+		return nil
+	}
+	var total int64
+	for _, files := range []map[string][]byte{d.files, d.localMedia} {
+		for _, data := range files {
+			total += int64(len(data))
+		}
+	}
+	if total > d.limits.MaxTotalSize {
+		return fmt.Errorf("%w: the document is larger than %d bytes", ErrLimitExceeded, d.limits.MaxTotalSize)
+	}
+	return nil
+}
+
+// countRows adds the items of an expansion ("table rows", "[for items]") to the rows of the render,
+// an error when there are more than MaxRows.
+func (d *Docx) countRows(what string, items int) error {
+	d.rows += items
+	if d.limits.MaxRows > 0 && d.rows > d.limits.MaxRows {
+		return fmt.Errorf("%s: %w: more than %d rows", what, ErrLimitExceeded, d.limits.MaxRows)
+	}
+	return nil
+}
+
+// takeRows counts the items of an expansion (see countRows); false — beyond MaxRows: the error
+// is kept for ExecuteTemplate and the expansion renders nothing.
+func (d *Docx) takeRows(what string, items int) bool {
+	err := d.countRows(what, items)
+	if err != nil && d.tableErr == nil {
+		d.tableErr = err
+	}
+	return err == nil
+}
+
+// readPart reads a file of the archive of the declared size, at most MaxPartSize bytes of it: the
+// size in the zip header is not trusted.
+func (d *Docx) readPart(name string, declared uint64, r io.Reader) ([]byte, error) {
+	limit := d.limits.MaxPartSize
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+	if declared > uint64(limit) {
+		return nil, fmt.Errorf("%w: %s is larger than %d bytes", ErrLimitExceeded, name, limit)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err == nil && int64(len(data)) > limit {
+		err = fmt.Errorf("%w: %s is larger than %d bytes", ErrLimitExceeded, name, limit)
+	}
+	return data, err
+}
+
+// limitedWriter - the output of a part, failing past max bytes so that the template stops.
+type limitedWriter struct {
+	w    io.Writer
+	part string
+	left int64
+	max  int64
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if l.left -= int64(len(p)); l.left < 0 {
+		return 0, fmt.Errorf("%w: the rendered %s is larger than %d bytes", ErrLimitExceeded, l.part, l.max)
+	}
+	return l.w.Write(p)
+}
+
+// partWriter - w limited to MaxPartSize, if set.
+func (d *Docx) partWriter(w io.Writer, part string) io.Writer {
+	if d.limits.MaxPartSize <= 0 {
+		return w
+	}
+	return &limitedWriter{w: w, part: part, left: d.limits.MaxPartSize, max: d.limits.MaxPartSize}
+}
@@ -0,0 +1,63 @@
+package docxgen
+
+import (
+	"docxgen/modifiers"
+	"strings"
+)
+
+// linkSchemes - the addresses the link modifier makes a relationship for.
+var linkSchemes = []string{"http://", "https://", "mailto:", "tel:", "ftp://"}
+
+// Link - a clickable hyperlink: a real <w:hyperlink> with its relationship in the .rels of the part
+// of the tag (the body, a header or a footer), so Word opens it with Ctrl+Click.
+//
+//	{url|link}                      → the address itself as the text
+//	{url|link:`Открыть договор`}    → the given text
+//	{email|link}                    → "mailto:" is added to an address with "@"
+//	{anchor|link:`см. раздел 2`}    → "#name" links to the bookmark name of the document
+//
+// "www.site.ru" becomes https://www.site.ru. Other addresses (javascript:, plain words) are not linked:
+// the text is output as it is with the bad_link warning.
+func (d *Docx) Link(value string, text ...string) modifiers.RawXML {
+	url := strings.TrimSpace(value)
+	if url == "" {
+		return ""
+	}
+	label := url
+	if len(text) > 0 && strings.TrimSpace(text[0]) != "" {
+		label = text[0]
+	}
+
+	var attr, target string
+	switch lower := strings.ToLower(url); {
+	case strings.HasPrefix(url, "#") && len(url) > 1:
+		attr = `w:anchor="` + xmlEscape(url[1:]) + `"`
+	case strings.HasPrefix(lower, "www."):
+		target = "https://" + url
+	case hasLinkScheme(lower):
+		target = url
+	case strings.Contains(url, "@") && !strings.ContainsAny(url, " :/"):
+		target = "mailto:" + url
+	default:
+		d.warn(WarnBadLink, shortSource(url), "not a link address, the text is output as is")
+		return modifiers.RawXML(xmlEscape(label))
+	}
+	if target != "" {
+		attr = `r:id="` + d.Rels().AddHyperlink(d.activePart, target) +
+			`" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	}
+
+	return modifiers.RawXML(`</w:t></w:r><w:hyperlink ` + attr + `><w:r>` + modifiers.InheritRunMarker +
+		`<w:rPr><w:color w:val="0563C1"/><w:u w:val="single"/></w:rPr>` +
+		`<w:t xml:space="preserve">` + xmlEscape(label) + `</w:t></w:r></w:hyperlink>` + strings.TrimPrefix(modifiers.RunReopen, "</w:t></w:r>"))
+}
+
+// hasLinkScheme - whether the address starts with one of linkSchemes.
+func hasLinkScheme(lower string) bool {
+	for _, s := range linkSchemes {
+		if strings.HasPrefix(lower, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package docxgen
+
+import (
+	"encoding/xml"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// [list items] ... [/list]: the elements of an array as the items of a bulleted or numbered list
+// ============================================================================
+
+const (
+	// listBullet, listNumbered - the kinds of [list items] and [list items numbered].
+	listBullet   = "bullet"
+	listNumbered = "numbered"
+)
+
+// listLevels - the definitions of the first level of the lists docxgen creates in numbering.xml;
+// the abstractNum is found again by its w:name.
+var listLevels = map[string]string{
+	listBullet: `<w:lvl w:ilvl="0"><w:start w:val="1"/><w:numFmt w:val="bullet"/><w:lvlText w:val="•"/>` +
+		`<w:lvlJc w:val="left"/><w:pPr><w:ind w:left="720" w:hanging="360"/></w:pPr></w:lvl>`,
+	listNumbered: `<w:lvl w:ilvl="0"><w:start w:val="1"/><w:numFmt w:val="decimal"/><w:lvlText w:val="%1."/>` +
+		`<w:lvlJc w:val="left"/><w:pPr><w:ind w:left="720" w:hanging="360"/></w:pPr></w:lvl>`,
+}
+
+var (
+	reNumPr = regexp.MustCompile(`<w:numPr\b`)
+	// reBeforeNumPr - the elements of pPr that go before numPr in the schema.
+	reBeforeNumPr = regexp.MustCompile(`^(?:<w:(?:pStyle|keepNext|keepLines|pageBreakBefore|widowControl)\b[^>]*/>|<w:framePr\b[^>]*/>)*`)
+)
+
+// listBlock renders [list key] … [/list] like [for key] (see repeatBlock) and makes the paragraphs
+// of the copies the items of one list: a bulleted one, or numbered from 1 for the style "numbered".
+// A paragraph that is a list item in the template already keeps its own list of numbering.xml;
+// the paragraphs of the tables inside the block are not touched.
+func (d *Docx) listBlock(key, style, inner string, data map[string]any) string {
+	if !filledValue(blockValue(data, key)) {
+		return d.repeatBlock("list", key, inner, data) // nothing to list: the warning, no numbering
+	}
+	if style == "" {
+		style = listBullet
+	}
+	numID := ""
+	inner = forEachTopParagraph(inner, func(p string) string {
+		if reNumPr.MatchString(p) {
+			return p
+		}
+		if numID == "" {
+			numID = d.listNumID(style)
+		}
+		return setNumbering(p, numID, 0)
+	})
+	return d.repeatBlock("list", key, inner, data)
+}
+
+// forEachTopParagraph replaces the paragraphs of s outside its tables with fn of them.
+func forEachTopParagraph(s string, fn func(p string) string) string {
+	var out strings.Builder
+	pos := 0
+	for {
+		p := paragraphStart(s, pos)
+		if p < 0 {
+			break
+		}
+		if tbl := tableStart(s, pos); tbl >= 0 && tbl < p {
+			end := tableEnd(s, tbl)
+			if end < 0 {
+				break
+			}
+			out.WriteString(s[pos:end])
+			pos = end
+			continue
+		}
+		end := elementEnd(s, p)
+		out.WriteString(s[pos:p])
+		out.WriteString(fn(s[p:end]))
+		pos = end
+	}
+	out.WriteString(s[pos:])
+	return out.String()
+}
+
+// setNumbering makes the paragraph p an item of the list numID at the level.
+func setNumbering(p, numID string, level int) string {
+	numPr := `<w:numPr><w:ilvl w:val="` + strconv.Itoa(level) + `"/><w:numId w:val="` + numID + `"/></w:numPr>`
+	open := p[:strings.IndexByte(p, '>')+1]
+	rest := p[len(open):]
+	switch {
+	case strings.HasPrefix(rest, "<w:pPr/>"):
+		return open + "<w:pPr>" + numPr + "</w:pPr>" + rest[len("<w:pPr/>"):]
+	case strings.HasPrefix(rest, "<w:pPr>") || strings.HasPrefix(rest, "<w:pPr "):
+		gt := strings.IndexByte(rest, '>') + 1
+		at := gt + len(reBeforeNumPr.FindString(rest[gt:]))
+		return open + rest[:at] + numPr + rest[at:]
+	}
+	return open + "<w:pPr>" + numPr + "</w:pPr>" + rest
+}
+
+// listNumID returns the w:numId of a new list of the style ("bullet", "numbered") in numbering.xml,
+// creating the part and the abstract definition of the style when needed. Every numbered list gets
+// a num of its own that restarts at 1; the bulleted ones share one.
+func (d *Docx) listNumID(style string) string {
+	base := string(d.files[numberingPath])
+	if !strings.Contains(base, "</w:numbering>") {
+		base = xml.Header + `<w:numbering xmlns:w="` + wordMainNamespace + `"></w:numbering>`
+	}
+	name := `<w:name w:val="docxgen-` + style + `"/>`
+
+	abstractID := ""
+	for _, def := range reAbstractNum.FindAllString(base, -1) {
+		if strings.Contains(def, name) {
+			abstractID = xmlAttr(def[:strings.IndexByte(def, '>')+1], "w:abstractNumId")
+			break
+		}
+	}
+	if abstractID == "" {
+		abstractID = strconv.Itoa(maxNumberingID(base, reAbstractNum, "w:abstractNumId") + 1)
+		def := `<w:abstractNum w:abstractNumId="` + abstractID + `"><w:multiLevelType w:val="hybridMultilevel"/>` +
+			name + listLevels[style] + `</w:abstractNum>`
+		// the abstract definitions go before all <w:num>
+		at := strings.Index(base, "<w:num ")
+		if at < 0 {
+			at = strings.LastIndex(base, "</w:numbering>")
+		}
+		base = base[:at] + def + base[at:]
+	} else if style == listBullet {
+		ref := `<w:abstractNumId w:val="` + abstractID + `"/>`
+		for _, num := range reNum.FindAllString(base, -1) {
+			if strings.Contains(num, ref) {
+				return xmlAttr(num[:strings.IndexByte(num, '>')+1], "w:numId")
+			}
+		}
+	}
+
+	numID := strconv.Itoa(maxNumberingID(base, reNum, "w:numId") + 1)
+	num := `<w:num w:numId="` + numID + `"><w:abstractNumId w:val="` + abstractID + `"/>`
+	if style == listNumbered {
+		num += `<w:lvlOverride w:ilvl="0"><w:startOverride w:val="1"/></w:lvlOverride>`
+	}
+	num += `</w:num>`
+	at := strings.LastIndex(base, "</w:numbering>")
+	base = base[:at] + num + base[at:]
+	d.setPart(numberingPath, []byte(base), relTypeNumbering, wmlType+"numbering+xml")
+	return numID
+}
+
+// maxNumberingID - the largest id (the attribute attr) of the elements of numbering.xml found by re.
+func maxNumberingID(numbering string, re *regexp.Regexp, attr string) int {
+	n := 0
+	for _, s := range re.FindAllString(numbering, -1) {
+		v, _ := strconv.Atoi(xmlAttr(s[:strings.Index(s, ">")+1], attr))
+		n = max(n, v)
+	}
+	return n
+}
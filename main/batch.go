@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"docxgen"
+	"docxgen/daemon"
+	"docxgen/pdf"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ---------- batch ----------
+
+// renderBatchCLI renders the documents of --batch next to out (out_001.docx, ...)
+// and draws the progress in the terminal.
+func renderBatchCLI(in, dataFile, out, projectRoot string, pdfOut bool) ([]string, error) {
+	raw, err := os.ReadFile(dataFile)
+	if err != nil {
+		return nil, fmt.Errorf("чтение JSON: %w", err)
+	}
+	items, err := daemon.ParseBatchData(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(out)
+	base := strings.TrimSuffix(filepath.Base(out), filepath.Ext(out))
+	open := func() (*docxgen.Docx, error) { return buildDocFromPath(in, projectRoot) }
+
+	var conv pdf.Converter
+	if pdfOut {
+		conv = pdfConverter
+	}
+
+	bar := newTermProgress(os.Stderr)
+	files, err := daemon.RenderBatch(context.Background(), open, items, base, conv, bar.update)
+	bar.finish()
+
+	var written []string
+	for _, f := range files {
+		path := filepath.Join(dir, f.Name)
+		if werr := os.WriteFile(path, f.Data, 0644); werr != nil {
+			return written, werr
+		}
+		written = append(written, path)
+	}
+	return written, err
+}
+
+// termProgress — a one-line progress bar for the terminal: [█████░░░░░] 5/10 name · stage.
+type termProgress struct {
+	w     io.Writer
+	start time.Time
+}
+
+func newTermProgress(w io.Writer) *termProgress {
+	return &termProgress{w: w, start: time.Now()}
+}
+
+func (t *termProgress) update(p daemon.Progress) {
+	const width = 30
+	filled := 0
+	if p.Total > 0 {
+		filled = width * p.Done / p.Total
+	}
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+
+	line := fmt.Sprintf("📦  [%s] %d/%d", bar, p.Done, p.Total)
+	if p.Done > 0 && p.Done < p.Total {
+		// the remaining time by the average time of a finished document
+		left := time.Since(t.start) / time.Duration(p.Done) * time.Duration(p.Total-p.Done)
+		line += fmt.Sprintf(" ~%s", left.Round(time.Second))
+	}
+	if p.Name != "" {
+		line += "  " + p.Name + " · " + p.Stage
+	}
+	_, _ = fmt.Fprint(t.w, "\r\033[K"+line)
+}
+
+func (t *termProgress) finish() {
+	_, _ = fmt.Fprintf(t.w, "\r\033[K")
+}
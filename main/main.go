@@ -3,20 +3,24 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto/subtle"
 	"docxgen"
+	"docxgen/daemon"
+	"docxgen/metrics"
 	"docxgen/modifiers"
-	"encoding/base64"
+	"docxgen/pdf"
 	"encoding/json"
-	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
-	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -26,12 +30,13 @@ import (
 
 // ---------- live-preview (SSE) ----------
 
+// reload subscribers: SSE (/events) and WebSocket (/ws) connections
 var (
 	sseMu      sync.Mutex
 	sseClients = map[chan struct{}]struct{}{}
 )
 
-// Send a signal to all subscribers /events
+// Send a signal to all subscribers /events and /ws
 func sseNotifyReload() {
 	sseMu.Lock()
 	defer sseMu.Unlock()
@@ -117,25 +122,89 @@ const previewHTML = `<!DOCTYPE html>
 	<body>
 		<iframe id="frame" src="/file"></iframe>
 		<script>
-			const es = new EventSource("/events");
-			es.onmessage = function() {
-			const f = document.getElementById("frame");
-			f.src = "/file?t=" + Date.now();
-			};
+			function reload() {
+				const f = document.getElementById("frame");
+				f.src = "/file?t=" + Date.now();
+			}
+			// SSE — fallback when the WebSocket cannot be opened (blocked by a proxy)
+			function sse() {
+				const es = new EventSource("/events");
+				es.onmessage = reload;
+			}
+			function ws() {
+				let opened = false;
+				let sock;
+				try {
+					sock = new WebSocket((location.protocol === "https:" ? "wss://" : "ws://") + location.host + "/ws");
+				} catch (e) {
+					sse();
+					return;
+				}
+				sock.onopen = function() { opened = true; };
+				sock.onmessage = function(e) { if (e.data === "reload") reload(); };
+				sock.onclose = function() {
+					if (opened) {
+						setTimeout(ws, 1000);
+					} else {
+						sse();
+					}
+				};
+			}
+			ws();
 		</script>
 	</body>
 </html>
 `
 
-func runPreviewServer(port int, out string, pdfOut bool) {
+// previewConfig — where and how the preview server listens.
+//   - Bind — interface address (--bind), loopback by default so drafts are not exposed to the network;
+//   - CertFile, KeyFile — TLS certificate and key (--tls-cert, --tls-key), both or neither;
+//   - Auth — "user:password" for HTTP basic auth (--preview-auth), empty — no auth.
+type previewConfig struct {
+	Bind     string
+	Port     int
+	CertFile string
+	KeyFile  string
+	Auth     string
+}
+
+func runPreviewServer(cfg previewConfig, out string, pdfOut bool) {
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		log.Fatal("preview: --tls-cert and --tls-key must be set together")
+	}
+	if cfg.Auth != "" && !strings.Contains(cfg.Auth, ":") {
+		log.Fatal("preview: --preview-auth must be user:password")
+	}
+
+	addr := net.JoinHostPort(cfg.Bind, strconv.Itoa(cfg.Port))
+	handler := previewHandler(out, pdfOut, cfg.Auth)
+
+	scheme, host := "http", cfg.Bind
+	if cfg.CertFile != "" {
+		scheme = "https"
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	log.Printf("🦌 preview: %s://%s/view\n", scheme, net.JoinHostPort(host, strconv.Itoa(cfg.Port)))
+
+	if cfg.CertFile != "" {
+		log.Fatal(http.ListenAndServeTLS(addr, cfg.CertFile, cfg.KeyFile, handler))
+	}
+	log.Fatal(http.ListenAndServe(addr, handler))
+}
+
+// previewHandler — /view, /file and the reload notifications (/events, /ws), behind basic auth if it is set.
+func previewHandler(out string, pdfOut bool, auth string) http.Handler {
 	outPath := previewOutputPath(out, pdfOut)
+	mux := http.NewServeMux()
 
-	http.HandleFunc("/view", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/view", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		_, _ = io.WriteString(w, previewHTML)
 	})
 
-	http.HandleFunc("/file", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/file", func(w http.ResponseWriter, r *http.Request) {
 		path := outPath
 
 		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
@@ -150,10 +219,29 @@ func runPreviewServer(port int, out string, pdfOut bool) {
 		http.ServeFile(w, r, path)
 	})
 
-	http.HandleFunc("/events", sseHandler)
+	mux.HandleFunc("/events", sseHandler)
+	mux.HandleFunc("/ws", wsHandler)
 
-	log.Printf("🦌 preview: http://localhost:%d/view\n", port)
-	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", port), nil))
+	if auth == "" {
+		return mux
+	}
+	return basicAuth(mux, auth, "docxgen preview")
+}
+
+// basicAuth lets through only the requests with the credentials "user:password".
+func basicAuth(next http.Handler, credentials, realm string) http.Handler {
+	wantUser, wantPass, _ := strings.Cut(credentials, ":")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		userOK := subtle.ConstantTimeCompare([]byte(user), []byte(wantUser)) == 1
+		passOK := subtle.ConstantTimeCompare([]byte(pass), []byte(wantPass)) == 1
+		if !ok || !userOK || !passOK {
+			w.Header().Set("WWW-Authenticate", `Basic realm="`+realm+`", charset="UTF-8"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
 }
 
 // ---------- main ----------
@@ -166,15 +254,80 @@ func main() {
 	debounce := flag.Duration("debounce", 300*time.Millisecond, "debounce before rebuild")
 	serve := flag.Bool("serve", false, "daemon mode (HTTP API)")
 	port := flag.Int("port", 8080, "daemon HTTP port/preview")
+	debug := flag.Bool("debug", false, "daemon mode: log the stage times of every render (unzip, repair, tables, parse, execute, save, pdf)")
 	download := flag.Bool("download", false, "do not save, but output the finished DOCX to stdout")
 	pdfOut := flag.Bool("pdf", false, "immediately convert to PDF (without saving DOCX)")
+	images := flag.Bool("images", false, "render the pages to PNG (out_page_001.png, ...) via the PDF engine")
 	preview := flag.Bool("preview", false, "run the HTML /view viewer for the result (handy with --watch and --pdf)")
 	pdfEngine := flag.String("pdf-engine", "", "preferred PDF engine: libreoffice|soffice|unoconv")
+	pdfRetries := flag.Int("pdf-retries", 0, "extra attempts of a failed PDF engine (with backoff)")
+	scratch := flag.String("scratch", os.Getenv("DOCXGEN_SCRATCH"), "scratch directory of the PDF and PNG engines, e.g. a tmpfs under a read-only root (default $DOCXGEN_SCRATCH, else the system temp dir)")
 	lang := flag.String("lang", "eng", "localization")
+	calendar := flag.String("calendar", "", "JSON production calendar for add_days/next_workday")
+	bind := flag.String("bind", "127.0.0.1", "preview address to listen on (0.0.0.0 — all interfaces)")
+	tlsCert := flag.String("tls-cert", "", "TLS certificate for the preview (with --tls-key)")
+	tlsKey := flag.String("tls-key", "", "TLS key for the preview (with --tls-cert)")
+	previewAuth := flag.String("preview-auth", "", "basic auth for the preview: user:password")
+	batch := flag.Bool("batch", false, "data is a JSON array: one document per item (out_001.docx, ...) with progress")
+	rules := flag.String("rules", "", "conditional formatting rules (YAML or JSON): color, bold, ... by data predicates")
+	brand := flag.String("brand", "", "brand pack: DOCX/DOTX or a directory with styles.xml/theme1.xml, merged into the template styles")
+	fonts := flag.String("fonts", fontsFlag, "font set for p_split and --embed-fonts: a directory of fonts/ or a family name")
+	embedFonts := flag.Bool("embed-fonts", false, "embed the project fonts into the DOCX (archival copies)")
+	stamp := flag.String("stamp", "", "registration stamp over the PDF (with --pdf): lines separated by |, {page} and {pages} — the page number and the number of pages")
+	stampQR := flag.String("stamp-qr", "", "QR code text of the stamp")
+	stampDate := flag.Bool("stamp-date", false, "add today's date to the stamp")
+	stampPages := flag.String("stamp-pages", "first", "stamped pages: first|last|all")
+	imageHosts := flag.String("image-hosts", "", "hosts the image modifier may download pictures by URL from, comma-separated (\".example.com\" — with subdomains); empty — none")
+	strictTypes := flag.Bool("strict-types", false, "fail the render when a modifier argument does not fit its type (\"abc\" into a number) instead of using zero")
+	dumpTransformed := flag.Bool("dump-transformed", false, "print the repaired and transformed Go template of every part with line numbers and exit")
+	tenants := flag.String("tenants", "", "daemon mode: JSON of the tenants — template roots, fonts, modifier sets and rate limits by API key or header")
+	compile := flag.Bool("compile", false, "check the template and write it compiled (.docxgenc next to --in) for the daemon, then exit")
 	flag.Parse()
 
 	baseDir, _ := os.Getwd()
-	pdfEngineFlag = *pdfEngine
+	pdfConverter = pdf.New(pdf.Options{
+		Preferred: *pdfEngine,
+		Retries:   *pdfRetries,
+		TempDir:   *scratch,
+		Log: func(format string, a ...any) {
+			fmt.Fprintf(os.Stderr, format+"\n", a...)
+		},
+	})
+	pageRasterizer = pdf.NewRasterizer(pdf.RasterOptions{TempDir: *scratch})
+	if *calendar != "" {
+		cal, err := modifiers.LoadWorkCalendar(*calendar)
+		if err != nil {
+			log.Fatalf("💥  календарь: %v\n", err)
+		}
+		workCalendar = cal
+	}
+	embedFontsFlag = *embedFonts
+	strictTypesFlag = *strictTypes
+	imageHostsFlag = strings.Split(*imageHosts, ",")
+	fontsFlag = *fonts
+	brandFlag = *brand
+	if *rules != "" {
+		r, err := docxgen.LoadFormatRules(*rules)
+		if err != nil {
+			log.Fatalf("💥  %v\n", err)
+		}
+		formatRules = r
+	}
+	if *stamp != "" || *stampQR != "" {
+		date := ""
+		if *stampDate {
+			date = "today"
+		}
+		req := daemon.StampRequest{QR: *stampQR, Date: date, Pages: *stampPages}
+		if *stamp != "" {
+			req.Lines = strings.Split(*stamp, "|")
+		}
+		st, err := req.Stamp()
+		if err != nil {
+			log.Fatalf("💥  %v\n", err)
+		}
+		pdfStamp = &st
+	}
 
 	// ищем корень проекта по наличию go.mod
 	projectRoot := baseDir
@@ -190,7 +343,10 @@ func main() {
 	}
 
 	if *serve {
-		runServer(*port, projectRoot)
+		if err := checkScratch(*scratch); err != nil {
+			log.Fatalf("💥  %v\n", err)
+		}
+		runServer(*port, projectRoot, *debug, *tenants)
 		return
 	}
 
@@ -206,22 +362,54 @@ func main() {
 		*out = base + "_out.docx"
 	}
 
+	if *dumpTransformed {
+		if err := dumpTemplates(os.Stdout, *in, *dataFile, projectRoot); err != nil {
+			log.Fatalf("💥  %v\n", err)
+		}
+		return
+	}
+
+	if *compile {
+		if err := compileTemplate(*in, projectRoot); err != nil {
+			log.Fatalf("💥  компиляция: %v\n", err)
+		}
+		return
+	}
+
+	if *batch {
+		written, err := renderBatchCLI(*in, *dataFile, *out, projectRoot, *pdfOut)
+		for _, p := range written {
+			fmt.Println("💚  готово: " + prettyOutputPath(p, false, baseDir))
+		}
+		if err != nil {
+			log.Fatalf("💥  ошибка сборки: %v\n", err)
+		}
+		return
+	}
+
 	// First assembly
-	if err := render(*in, *dataFile, *out, projectRoot, *download, *pdfOut); err != nil {
+	if err := render(*in, *dataFile, *out, projectRoot, *download, *pdfOut, *images); err != nil {
 		log.Fatalf("💥  ошибка сборки: %v\n", err)
 	}
 	if *download {
 		return
 	}
-	fmt.Println("💚  готово: " + prettyOutputPath(*out, *pdfOut, baseDir))
+	done := func() string {
+		if *images {
+			return prettyOutputPath(strings.TrimSuffix(*out, filepath.Ext(*out))+"_page_*.png", false, baseDir)
+		}
+		return prettyOutputPath(*out, *pdfOut, baseDir)
+	}
+	fmt.Println("💚  готово: " + done())
 
 	// If it's a preview, start the server
 	if *preview {
+		cfg := previewConfig{Bind: *bind, Port: *port, CertFile: *tlsCert, KeyFile: *tlsKey, Auth: *previewAuth}
 		if *watch {
-			go runPreviewServer(*port, *out, *pdfOut)
+			go runPreviewServer(cfg, *out, *pdfOut)
 		} else {
 			// без watch — просто сервер-просмотрщик
-			runPreviewServer(*port, *out, *pdfOut)
+			runPreviewServer(cfg, *out, *pdfOut)
 			return
 		}
 	}
@@ -279,10 +467,10 @@ func main() {
 		}
 		t = time.AfterFunc(*debounce, func() {
 			fmt.Println("🔄  пересборка…")
-			if err := render(*in, *dataFile, *out, projectRoot, false, *pdfOut); err != nil {
+			if err := render(*in, *dataFile, *out, projectRoot, false, *pdfOut, *images); err != nil {
 				fmt.Printf("💥  %v\n", err)
 			} else {
-				fmt.Println("💚  готово: " + prettyOutputPath(*out, *pdfOut, baseDir))
+				fmt.Println("💚  готово: " + done())
 				// пинг браузеру
 				sseNotifyReload()
 			}
@@ -323,9 +511,51 @@ func buildDocFromPath(path, projectRoot string) (*docxgen.Docx, error) {
 		log.Printf("шрифты: %v\n", err)
 	}
 	registerCommonModifiers(doc)
+	doc.SetStrictTypes(strictTypesFlag)
+	doc.SetImageHosts(imageHostsFlag...)
+	doc.SetWorkCalendar(workCalendar)
+	if err := doc.SetFormatRules(formatRules); err != nil {
+		return nil, err
+	}
+	if brandFlag != "" {
+		pack, err := docxgen.LoadBrandPack(brandFlag, docxgen.StyleMerge)
+		if err != nil {
+			return nil, err
+		}
+		if err := doc.ApplyBrand(pack); err != nil {
+			return nil, fmt.Errorf("бренд: %w", err)
+		}
+	}
+	if embedFontsFlag {
+		paths, err := fontPaths(projectRoot)
+		if err == nil {
+			err = doc.EmbedFonts(paths...)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("встраивание шрифтов: %w", err)
+		}
+	}
 	return doc, nil
 }
 
+// formatRules — conditional formatting of --rules
+var formatRules []docxgen.FormatRule
+
+// brandFlag — the brand pack applied to every template (--brand), empty means none
+var brandFlag string
+
+// embedFontsFlag — embed the project fonts into the result (--embed-fonts), for archival copies
+var embedFontsFlag bool
+
+// strictTypesFlag — a modifier argument of a wrong type fails the render (--strict-types)
+var strictTypesFlag bool
+
+// imageHostsFlag — the hosts of the pictures by URL (--image-hosts), empty means none
+var imageHostsFlag []string
+
+// workCalendar — the production calendar of --calendar, read once at startup; nil means weekends only
+var workCalendar *modifiers.WorkCalendar
+
 func executeTemplate(doc *docxgen.Docx, data map[string]any) error {
 	// builtins are added inside the ExecuteTemplate; our mods are already in extraFuncs
 	if err := doc.ExecuteTemplate(data); err != nil {
@@ -334,17 +564,52 @@ func executeTemplate(doc *docxgen.Docx, data map[string]any) error {
 	return nil
 }
 
+// fontsFlag — the font set of p_split and --embed-fonts (--fonts): a directory of fonts/ or a family name
+var fontsFlag = "TimesNewRoman"
+
+var (
+	fontsOnce     sync.Once
+	fontsRegistry *metrics.Registry
+	fontsErr      error
+)
+
+// projectFonts — the font sets of fonts/ (one subdirectory per set), scanned once
+func projectFonts(projectRoot string) (*metrics.Registry, error) {
+	fontsOnce.Do(func() {
+		fontsRegistry, fontsErr = metrics.ScanFonts(filepath.Join(projectRoot, "fonts"))
+	})
+	return fontsRegistry, fontsErr
+}
+
+// fontPaths — the files of the selected font set
+func fontPaths(projectRoot string) ([]string, error) {
+	reg, err := projectFonts(projectRoot)
+	if err != nil {
+		return nil, err
+	}
+	return reg.Paths(fontsFlag)
+}
+
 func loadFonts(doc *docxgen.Docx, projectRoot string) error {
-	return doc.LoadFontsForPSplit(
-		filepath.Join(projectRoot, "fonts/TimesNewRoman/TimesNewRoman.ttf"),
-		filepath.Join(projectRoot, "fonts/TimesNewRoman/TimesNewRomanBold.ttf"),
-		filepath.Join(projectRoot, "fonts/TimesNewRoman/TimesNewRomanItalic.ttf"),
-		filepath.Join(projectRoot, "fonts/TimesNewRoman/TimesNewRomanBoldItalic.ttf"),
-	)
+	reg, err := projectFonts(projectRoot)
+	if err != nil {
+		return err
+	}
+	fonts, err := reg.Lookup(fontsFlag)
+	if err != nil {
+		return err
+	}
+	doc.SetFonts(fonts)
+	return nil
 }
 
 func registerCommonModifiers(doc *docxgen.Docx) {
-	doc.ImportModifiers(map[string]modifiers.ModifierMeta{
+	doc.ImportModifiers(commonModifiers())
+}
+
+// commonModifiers — the modifiers of the CLI and the daemon, the "common" set of --tenants
+func commonModifiers() map[string]modifiers.ModifierMeta {
+	return map[string]modifiers.ModifierMeta{
 		"upper": {Func: func(value string) string { return strings.ToUpper(value) }, Count: 0},
 		"lower": {Func: func(value string) string { return strings.ToLower(value) }, Count: 0},
 		"wrap":  {Func: func(v, l, r string) string { return l + v + r }, Count: 2},
@@ -396,11 +661,63 @@ func registerCommonModifiers(doc *docxgen.Docx) {
 			},
 			Count: 0,
 		},
-	})
+	}
 }
 
 // ---------- CLI render ----------
-func render(in, dataFile, out, projectRoot string, download, pdfOut bool) error {
+// dumpTemplates — --dump-transformed: the text the engine parses, a parse error fails the command
+func dumpTemplates(w io.Writer, in, dataFile, projectRoot string) error {
+	data := map[string]any{}
+	raw, err := os.ReadFile(dataFile)
+	if err != nil {
+		return fmt.Errorf("чтение JSON: %w", err)
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("разбор JSON: %w", err)
+	}
+
+	doc, err := buildDocFromPath(in, projectRoot)
+	if err != nil {
+		return err
+	}
+	parts, err := doc.TransformedTemplates(data)
+	if err != nil {
+		return fmt.Errorf("шаблон: %w", err)
+	}
+	if err := docxgen.WriteTransformed(w, parts); err != nil {
+		return err
+	}
+	for _, p := range parts {
+		if p.Err != nil {
+			return fmt.Errorf("шаблон %s не разбирается: %w", p.Part, p.Err)
+		}
+	}
+	return nil
+}
+
+// compileTemplate writes the template compiled with the modifiers of the CLI next to it and lists its tags.
+func compileTemplate(in, projectRoot string) error {
+	doc, err := buildDocFromPath(in, projectRoot)
+	if err != nil {
+		return err
+	}
+	dst := strings.TrimSuffix(in, filepath.Ext(in)) + docxgen.CompiledExt
+	var buf bytes.Buffer
+	m, err := doc.Compile(&buf)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(dst, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	for _, p := range m.Parts {
+		fmt.Printf("%s: %d тегов, таблицы %v, вставки %v\n", p.Part, len(p.Tags), p.Tables, p.Includes)
+	}
+	fmt.Println("💚  готово: " + dst)
+	return nil
+}
+
+func render(in, dataFile, out, projectRoot string, download, pdfOut, images bool) error {
 	data := map[string]any{}
 	raw, err := os.ReadFile(dataFile)
 	if err != nil {
@@ -419,15 +736,41 @@ func render(in, dataFile, out, projectRoot string, download, pdfOut bool) error
 		return err
 	}
 
+	if images {
+		var buf bytes.Buffer
+		if err := doc.SaveToWriter(&buf); err != nil {
+			return err
+		}
+		base := strings.TrimSuffix(filepath.Base(out), filepath.Ext(out)) + "_page"
+		pages, err := daemon.PageImages(context.Background(), pdfConverter, pageRasterizer, buf.Bytes(), base)
+		if err != nil {
+			return err
+		}
+		if download {
+			return daemon.ZipFiles(os.Stdout, pages)
+		}
+		for _, p := range pages {
+			if err := os.WriteFile(filepath.Join(filepath.Dir(out), p.Name), p.Data, 0644); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	if pdfOut {
 		var buf bytes.Buffer
 		if err := doc.SaveToWriter(&buf); err != nil {
 			return err
 		}
-		pdfData, err := convertToPDF(buf.Bytes())
+		pdfData, err := pdfConverter.Convert(context.Background(), buf.Bytes())
 		if err != nil {
 			return err
 		}
+		if pdfStamp != nil {
+			if pdfData, err = pdf.ApplyStamp(pdfData, *pdfStamp); err != nil {
+				return fmt.Errorf("штамп: %w", err)
+			}
+		}
 		if download {
 			_, err = os.Stdout.Write(pdfData)
 			return err
@@ -454,232 +797,86 @@ func render(in, dataFile, out, projectRoot string, download, pdfOut bool) error
 }
 
 // ---------- demon ----------
-func runServer(port int, projectRoot string) {
-	http.HandleFunc("/generate", func(w http.ResponseWriter, r *http.Request) {
-		var req struct {
-			Template string         `json:"template"`
-			Data     map[string]any `json:"data,omitempty"`
-			Format   string         `json:"format,omitempty"`
-		}
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			jsonErr(w, 400, "invalid json: %v", err)
-			return
-		}
-		if strings.TrimSpace(req.Template) == "" {
-			jsonErr(w, 400, "template is required: pass a file path, base64 DOCX, or <w:document> xml")
-			return
-		}
-
-		var (
-			doc *docxgen.Docx
-			err error
-		)
-
-		switch {
-		case fileExists(req.Template):
-			doc, err = docxgen.Open(req.Template)
-			if err != nil {
-				jsonErr(w, 500, "template open error: %v", err)
-				return
-			}
-		case hasAnySuffix(strings.ToLower(req.Template), ".docx", ".docm", ".dotx"):
-			candidate := filepath.Join(projectRoot, req.Template)
-			if fileExists(candidate) {
-				doc, err = docxgen.Open(candidate)
-			} else {
-				candidate = filepath.Join(projectRoot, "main", req.Template)
-				if fileExists(candidate) {
-					doc, err = docxgen.Open(candidate)
-				} else {
-					jsonErr(w, 400, "file not found: %s", candidate)
-					return
-				}
-			}
-		case strings.HasPrefix(strings.TrimSpace(req.Template), "<w:"):
-			// you need a docx "skeleton"; use any valid in the project
-			doc, err = docxgen.Open("examples/template.docx")
-			if err != nil {
-				jsonErr(w, 500, "template skeleton error: %v", err)
-				return
-			}
-			doc.UpdateContentPart("document", req.Template)
-		default:
-			raw, decErr := base64.StdEncoding.DecodeString(req.Template)
-			if decErr != nil {
-				jsonErr(w, 400, "template: not a path, not xml, and bad base64: %v", decErr)
-				return
-			}
-			tmp := filepath.Join(os.TempDir(), fmt.Sprintf("tmpl_%d.docx", time.Now().UnixNano()))
-			if err := os.WriteFile(tmp, raw, 0644); err != nil {
-				jsonErr(w, 500, "write temp: %v", err)
-				return
-			}
-			defer func() {
-				err = os.Remove(tmp)
-				if err != nil {
-					jsonErr(w, 500, "template remove error: %v", err)
-					return
+func runServer(port int, projectRoot string, debug bool, tenantsPath string) {
+	fonts, err := projectFonts(projectRoot)
+	if err != nil {
+		log.Printf("шрифты: %v\n", err)
+	}
+	var (
+		tenantHeader string
+		tenants      []daemon.Tenant
+	)
+	if tenantsPath != "" {
+		if tenantHeader, tenants, err = loadTenants(tenantsPath, projectRoot); err != nil {
+			log.Fatalf("💥  %v\n", err)
+		}
+	}
+	var logger *slog.Logger
+	if debug {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+	srv := &daemon.Server{
+		Addr: fmt.Sprintf(":%d", port),
+		Config: daemon.Config{
+			TemplateRoot: projectRoot,
+			Skeleton:     filepath.Join(projectRoot, "main/examples/template_eng.docx"),
+			Prepare: func(doc *docxgen.Docx) error {
+				// Common fonts/modifiers
+				if err := loadFonts(doc, projectRoot); err != nil {
+					log.Printf("шрифты: %v\n", err)
 				}
-			}()
-			doc, err = docxgen.Open(tmp)
-			if err != nil {
-				jsonErr(w, 500, "template open error: %v", err)
-				return
-			}
-		}
-
-		// Common fonts/modifiers and execution
-		if err := loadFonts(doc, "."); err != nil {
-			log.Printf("шрифты: %v\n", err)
-		}
-		registerCommonModifiers(doc)
-		if err := executeTemplate(doc, req.Data); err != nil {
-			jsonErr(w, 500, "%v", err)
-			return
-		}
-
-		if strings.EqualFold(req.Format, "xml") {
-			xml, _ := doc.ContentPart("document")
-			w.Header().Set("Content-Type", "application/xml; charset=utf-8")
-			_, _ = w.Write([]byte(xml))
-			return
-		}
-
-		// Send the file directly
-		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.wordprocessingml.document")
-		w.Header().Set("Content-Disposition", `attachment; filename="result.docx"`)
-		if err := doc.SaveToWriter(w); err != nil {
-			jsonErr(w, 500, "stream error: %v", err)
-			return
-		}
-	})
-
-	log.Printf("🦌  Демон слушает порт %d\n", port)
-	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", port), nil))
-}
-
-var pdfEngineFlag string
-
-// Engine Order: From Best to Worst
-var pdfEngines = []string{
-	"soffice", // LibreOffice headless
-	"libreoffice",
-	"lowriter",
-	"unoconv", // fallback, но крайне ненадёжный
-}
-
-func findExec(bin string) (string, bool) {
-	p, err := exec.LookPath(bin)
-	return p, err == nil
-}
-
-func runEngine(engine string, docx, pdf string) error {
-	fmt.Printf("📑  пробуем конвертацию в pdf через: %s\n", engine)
-	switch engine {
-
-	case "soffice", "libreoffice":
-		return exec.Command(engine,
-			"--headless",
-			"--convert-to", "pdf:writer_pdf_Export",
-			"--outdir", filepath.Dir(pdf),
-			docx,
-		).Run()
-
-	case "lowriter":
-		return exec.Command("lowriter",
-			"--convert-to", "pdf",
-			"--outdir", filepath.Dir(pdf),
-			docx,
-		).Run()
-
-	case "unoconv":
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
-
-		// unoconv требует basename без расширения
-		outNoExt := strings.TrimSuffix(pdf, filepath.Ext(pdf))
-
-		cmd := exec.CommandContext(ctx,
-			"unoconv",
-			"-f", "pdf",
-			"-o", outNoExt, // <--- ВАЖНО!
-			docx,
-		)
-
-		if err := cmd.Run(); err != nil {
-			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
-				return fmt.Errorf("unoconv timeout")
-			}
-			return fmt.Errorf("unoconv failed: %w", err)
-		}
-
-		return nil
+				registerCommonModifiers(doc)
+				doc.SetImageHosts(imageHostsFlag...)
+				doc.SetWorkCalendar(workCalendar)
+				return nil
+			},
+			PDF:   pdfConverter,
+			Pages: pageRasterizer,
+			Fonts: fonts,
+			// far beyond any real contract or report, but a zip bomb or a runaway dataset stops here
+			Limits:       docxgen.SizeLimits{MaxPartSize: 256 << 20, MaxTotalSize: 512 << 20, MaxRows: 200_000},
+			MaxBodySize:  256 << 20,
+			Logger:       logger,
+			Tenants:      tenants,
+			TenantHeader: tenantHeader,
+		},
 	}
 
-	return fmt.Errorf("unknown engine: %s", engine)
+	if len(tenants) > 0 {
+		log.Printf("🦌  Демон слушает порт %d, арендаторов: %d\n", port, len(tenants))
+	} else {
+		log.Printf("🦌  Демон слушает порт %d\n", port)
+	}
+	log.Fatal(srv.ListenAndServe())
 }
 
-func convertToPDF(docxBytes []byte) ([]byte, error) {
+// pdfConverter — PDF engines with the preference of --pdf-engine; messages go to stderr,
+// so that --download output stays clean.
+var pdfConverter pdf.Converter = pdf.New(pdf.Options{})
 
-	tmpDocx := filepath.Join(os.TempDir(), fmt.Sprintf("doc_%d.docx", time.Now().UnixNano()))
-	tmpPDF := strings.TrimSuffix(tmpDocx, ".docx") + ".pdf"
+// pdfStamp — the registration stamp of --stamp, drawn over the PDF of --pdf (nil — no stamp)
+var pdfStamp *pdf.Stamp
 
-	if err := os.WriteFile(tmpDocx, docxBytes, 0644); err != nil {
-		return nil, err
-	}
-	defer func(name string) {
-		err := os.Remove(name)
-		if err != nil {
-			fmt.Printf("не удалился файл %s, ошибка: %v", name, err)
-		}
-	}(tmpDocx)
+// pageRasterizer — PDF pages → PNG for --images and "format": "png"
+var pageRasterizer pdf.PageRasterizer = pdf.NewRasterizer(pdf.RasterOptions{})
 
-	// preferred engine
-	if pdfEngineFlag != "" {
-		if _, ok := findExec(pdfEngineFlag); ok {
-			if err := runEngine(pdfEngineFlag, tmpDocx, tmpPDF); err == nil {
-				data, _ := os.ReadFile(tmpPDF)
-				_ = os.Remove(tmpPDF)
-				return data, nil
-			}
-		}
+// checkScratch makes sure the engines can write to the scratch directory (empty — the system temp dir):
+// under a read-only root file system the daemon fails at the start, not at the first PDF.
+func checkScratch(dir string) error {
+	if dir == "" {
+		dir = os.TempDir()
 	}
-
-	// try engines in order
-	for _, engine := range pdfEngines {
-		_, ok := findExec(engine)
-		if !ok {
-			continue
-		}
-
-		err := runEngine(engine, tmpDocx, tmpPDF)
-		if err != nil {
-			// skip silently → continue to next engine
-			continue
-		}
-
-		// success
-		data, err := os.ReadFile(tmpPDF)
-		_ = os.Remove(tmpPDF)
-		return data, err
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("scratch dir: %w (mount a tmpfs and pass it with --scratch)", err)
 	}
-
-	return nil, fmt.Errorf("no available PDF engines found")
+	probe, err := os.MkdirTemp(dir, "docxgen-probe-*")
+	if err != nil {
+		return fmt.Errorf("scratch dir %s is not writable: %w (mount a tmpfs and pass it with --scratch)", dir, err)
+	}
+	return os.Remove(probe)
 }
 
 // ---------- helpers ----------
-func fileExists(p string) bool {
-	fi, err := os.Stat(p)
-	return err == nil && !fi.IsDir()
-}
-
-func jsonErr(w http.ResponseWriter, code int, fmtStr string, a ...any) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(code)
-	msg := fmt.Sprintf(fmtStr, a...)
-	_, _ = w.Write([]byte(`{"error":"` + strings.ReplaceAll(msg, `"`, `\"`) + `"}`))
-}
-
 func dedupe(in []string) []string {
 	seen := map[string]struct{}{}
 	var out []string
@@ -2,16 +2,22 @@ package main
 
 import (
 	"archive/zip"
+	"bufio"
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"docxgen"
+	"docxgen/daemon"
 )
 
 // makeFakeDocx создаёт минимальный DOCX с тегом {name}
@@ -35,87 +41,174 @@ func makeFakeDocx() []byte {
 	return buf.Bytes()
 }
 
-// openDocxFromBytes — вспомогательный хелпер: открывает docx прямо из байтов без файлов на диске
-func openDocxFromBytes(b []byte) (*docxgen.Docx, error) {
-	tmp, err := os.CreateTemp("", "tmpl*.docx")
-	if err != nil {
-		return nil, err
-	}
-	defer func(name string) {
-		_ = os.Remove(name)
-	}(tmp.Name())
-	_, _ = tmp.Write(b)
-	_ = tmp.Close()
-	return docxgen.Open(tmp.Name())
-}
-
-// TestHTTPGenerate_MemoryOnly — тестирует endpoint /generate без файлов и без шрифтов
+// TestHTTPGenerate_MemoryOnly — /generate демона с шаблоном base64 без файлов и без шрифтов;
+// ошибки запроса приходят JSON с кодом 400
 func TestHTTPGenerate_MemoryOnly(t *testing.T) {
-	docxBytes := makeFakeDocx()
-	docxB64 := base64.StdEncoding.EncodeToString(docxBytes)
+	handler := daemon.NewHandler(daemon.Config{
+		Prepare: func(doc *docxgen.Docx) error {
+			// просто не вызываем loadFonts, чтобы не требовались файлы
+			registerCommonModifiers(doc)
+			return nil
+		},
+	})
+	post := func(body string) *http.Response {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("POST", "/generate", strings.NewReader(body)))
+		return w.Result()
+	}
 
-	reqBody := map[string]any{
-		"template": docxB64,
+	body, _ := json.Marshal(map[string]any{
+		"template": base64.StdEncoding.EncodeToString(makeFakeDocx()),
 		"data":     map[string]any{"name": "Оленька"},
 		"format":   "xml",
+	})
+	resp := post(string(body))
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	if resp.StatusCode != 200 {
+		t.Fatalf("unexpected status: %d", resp.StatusCode)
+	}
+
+	xml, _ := io.ReadAll(resp.Body)
+	if !bytes.Contains(xml, []byte("Оленька")) {
+		t.Fatalf("XML не содержит подстановку:\n%s", xml)
 	}
-	body, _ := json.Marshal(reqBody)
 
-	// создаём handler прямо из runServer без запуска порта
-	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var req struct {
-			Template string         `json:"template"`
-			Data     map[string]any `json:"data,omitempty"`
-			Format   string         `json:"format,omitempty"`
+	for _, bad := range []string{`{"template":`, `{"data": {}}`} {
+		resp := post(bad)
+		var answer struct {
+			Error string `json:"error"`
 		}
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			jsonErr(w, 400, "bad json: %v", err)
-			return
+		if err := json.NewDecoder(resp.Body).Decode(&answer); resp.StatusCode != 400 || err != nil || answer.Error == "" {
+			t.Errorf("%s: %d %+v %v", bad, resp.StatusCode, answer, err)
 		}
+		_ = resp.Body.Close()
+	}
+}
+
+// TestWebSocketReload — /ws принимает рукопожатие и присылает reload после пересборки
+func TestWebSocketReload(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(wsHandler))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
 
-		// распаковываем шаблон из base64
-		raw, err := base64.StdEncoding.DecodeString(req.Template)
+	_, _ = io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: x\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("handshake: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("bad handshake: %d %q", resp.StatusCode, resp.Header.Get("Sec-WebSocket-Accept"))
+	}
+
+	for _, want := range []string{"init", "reload"} {
+		op, payload, err := wsReadFrame(br)
 		if err != nil {
-			jsonErr(w, 400, "bad base64: %v", err)
-			return
+			t.Fatalf("read %s: %v", want, err)
 		}
-		doc, err := openDocxFromBytes(raw)
-		if err != nil {
-			jsonErr(w, 500, "open docx: %v", err)
-			return
+		if op != wsText || string(payload) != want {
+			t.Fatalf("frame = %d %q, want %q", op, payload, want)
 		}
+		if want == "init" {
+			sseNotifyReload()
+		}
+	}
+}
 
-		// просто не вызываем loadFonts, чтобы не требовались файлы
-		registerCommonModifiers(doc)
+// TestPreviewBasicAuth — с --preview-auth предпросмотр закрыт паролем
+func TestPreviewBasicAuth(t *testing.T) {
+	h := previewHandler("out.docx", false, "user:secret")
 
-		if err := executeTemplate(doc, req.Data); err != nil {
-			jsonErr(w, 500, "exec: %v", err)
-			return
+	for _, tc := range []struct {
+		user, pass string
+		want       int
+	}{
+		{"", "", http.StatusUnauthorized},
+		{"user", "wrong", http.StatusUnauthorized},
+		{"user", "secret", http.StatusOK},
+	} {
+		req := httptest.NewRequest("GET", "/view", nil)
+		if tc.user != "" {
+			req.SetBasicAuth(tc.user, tc.pass)
 		}
-		xml, _ := doc.ContentPart("document")
-		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
-		_, err = w.Write([]byte(xml))
-		if err != nil {
-			jsonErr(w, 500, "exec: %v", err)
-			return
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("%s:%s → %d, want %d", tc.user, tc.pass, w.Code, tc.want)
 		}
-	})
+	}
+}
 
-	req := httptest.NewRequest("POST", "/generate", bytes.NewReader(body))
-	w := httptest.NewRecorder()
-	handler(w, req)
+// TestLoadTenants — --tenants: пути относительно файла, ключи из окружения, ошибки конфигурации
+func TestLoadTenants(t *testing.T) {
+	dir := t.TempDir()
+	for _, d := range []string{"legal", "finance"} {
+		if err := os.Mkdir(filepath.Join(dir, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("LEGAL_API_KEY", "secret")
+	write := func(content string) string {
+		path := filepath.Join(dir, "tenants.json")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
 
-	resp := w.Result()
-	defer func(Body io.ReadCloser) {
-		_ = Body.Close()
-	}(resp.Body)
+	header, tenants, err := loadTenants(write(`{"header": "X-Tenant", "tenants": [
+		{"name": "legal", "keys": ["${LEGAL_API_KEY}"], "templates": "legal", "rate": 5},
+		{"name": "finance", "templates": "finance", "modifiers": ["common"]}
+	]}`), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header != "X-Tenant" || len(tenants) != 2 {
+		t.Fatalf("header %q, %d tenants", header, len(tenants))
+	}
+	if l := tenants[0]; l.TemplateRoot != filepath.Join(dir, "legal") || len(l.Keys) != 1 || l.Keys[0] != "secret" || l.Rate != 5 || l.Prepare == nil {
+		t.Errorf("legal: %+v", l)
+	}
 
-	if resp.StatusCode != 200 {
-		t.Fatalf("unexpected status: %d", resp.StatusCode)
+	for name, content := range map[string]string{"calendar.json": `{"holidays": ["2025-11-04"]}`, "broken.json": `{"holidays": [`} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
 	}
 
-	xml, _ := io.ReadAll(resp.Body)
-	if !bytes.Contains(xml, []byte("Оленька")) {
-		t.Fatalf("XML не содержит подстановку:\n%s", xml)
+	for name, content := range map[string]string{
+		"shared key":    `{"tenants": [{"name": "a", "keys": ["k"], "templates": "legal"}, {"name": "b", "keys": ["k"], "templates": "finance"}]}`,
+		"unset key":     `{"tenants": [{"name": "a", "keys": ["${NO_SUCH_KEY}"], "templates": "legal"}]}`,
+		"unknown set":   `{"tenants": [{"name": "a", "keys": ["k"], "templates": "legal", "modifiers": ["legal"]}]}`,
+		"no templates":  `{"tenants": [{"name": "a", "keys": ["k"], "templates": "missing"}]}`,
+		"no way in":     `{"tenants": [{"name": "a", "templates": "legal"}]}`,
+		"no tenants":    `{"tenants": []}`,
+		"the same name": `{"tenants": [{"name": "a", "keys": ["k"], "templates": "legal"}, {"name": "a", "keys": ["m"], "templates": "legal"}]}`,
+		"no calendar":   `{"tenants": [{"name": "a", "keys": ["k"], "templates": "legal", "calendar": "missing.json"}]}`,
+		"bad calendar":  `{"tenants": [{"name": "a", "keys": ["k"], "templates": "legal", "calendar": "broken.json"}]}`,
+	} {
+		if _, _, err := loadTenants(write(content), dir); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+
+	// the tenants naming the same calendar share it
+	if _, tenants, err := loadTenants(write(`{"tenants": [
+		{"name": "legal", "keys": ["l"], "templates": "legal", "calendar": "calendar.json"},
+		{"name": "finance", "keys": ["f"], "templates": "finance", "calendar": "calendar.json"}
+	]}`), dir); err != nil || len(tenants) != 2 {
+		t.Errorf("tenants with a calendar: %d, %v", len(tenants), err)
 	}
 }
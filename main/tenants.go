@@ -0,0 +1,166 @@
+package main
+
+import (
+	"docxgen"
+	"docxgen/daemon"
+	"docxgen/metrics"
+	"docxgen/modifiers"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ---------- tenants (--tenants) ----------
+
+// tenantsFile — the departments of a shared daemon (--tenants).
+//   - header — the tenant header set by a trusted proxy (empty — API keys only);
+//   - keys — the API keys, ${VAR} is taken from the environment;
+//   - templates — the template root of the tenant, relative to the file;
+//   - fonts, font — the font sets (default fonts/ of the project) and the set of p_split (default --fonts);
+//   - modifiers — the sets of modifierSets the templates can use (default "common");
+//   - calendar — the production calendar (default --calendar), read once with the file; the tenants
+//     naming the same file share it;
+//   - rate, burst — requests per second and the burst above it (0 — no limit).
+//
+// Example:
+//
+//	{"header": "X-Tenant", "tenants": [
+//	  {"name": "legal", "keys": ["${LEGAL_API_KEY}"], "templates": "legal", "modifiers": ["common"], "rate": 5, "burst": 10}
+//	]}
+type tenantsFile struct {
+	Header  string `json:"header"`
+	Tenants []struct {
+		Name      string   `json:"name"`
+		Keys      []string `json:"keys"`
+		Templates string   `json:"templates"`
+		Fonts     string   `json:"fonts"`
+		Font      string   `json:"font"`
+		Modifiers []string `json:"modifiers"`
+		Calendar  string   `json:"calendar"`
+		Rate      float64  `json:"rate"`
+		Burst     int      `json:"burst"`
+	} `json:"tenants"`
+}
+
+// modifierSets — the modifier sets a tenant can take by name; a department's own modifiers
+// are added here as a set of their own, so the templates of other tenants do not see them.
+var modifierSets = map[string]func() map[string]modifiers.ModifierMeta{
+	"common": commonModifiers,
+}
+
+// loadTenants reads --tenants: the tenant header and the tenants with their templates, fonts,
+// modifiers and limits. The paths are relative to the file.
+func loadTenants(path, projectRoot string) (string, []daemon.Tenant, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("tenants: %w", err)
+	}
+	var file tenantsFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return "", nil, fmt.Errorf("tenants %s: %w", path, err)
+	}
+	if len(file.Tenants) == 0 {
+		return "", nil, fmt.Errorf("tenants %s: no tenants", path)
+	}
+	dir := filepath.Dir(path)
+	abs := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+
+	names := map[string]bool{}
+	keys := map[string]string{}
+	calendars := map[string]*modifiers.WorkCalendar{}
+	tenants := make([]daemon.Tenant, 0, len(file.Tenants))
+	for _, ft := range file.Tenants {
+		if ft.Name == "" {
+			return "", nil, fmt.Errorf("tenants %s: a tenant without a name", path)
+		}
+		if names[ft.Name] {
+			return "", nil, fmt.Errorf("tenant %s: the name is used twice", ft.Name)
+		}
+		names[ft.Name] = true
+		if ft.Templates == "" {
+			return "", nil, fmt.Errorf("tenant %s: templates is required", ft.Name)
+		}
+		if len(ft.Keys) == 0 && file.Header == "" {
+			return "", nil, fmt.Errorf("tenant %s: no keys and no header, it cannot be reached", ft.Name)
+		}
+
+		t := daemon.Tenant{Name: ft.Name, TemplateRoot: abs(ft.Templates), Rate: ft.Rate, Burst: ft.Burst}
+		if fi, err := os.Stat(t.TemplateRoot); err != nil || !fi.IsDir() {
+			return "", nil, fmt.Errorf("tenant %s: templates %s is not a directory", ft.Name, t.TemplateRoot)
+		}
+		for _, k := range ft.Keys {
+			k = strings.TrimSpace(os.ExpandEnv(k))
+			if k == "" {
+				return "", nil, fmt.Errorf("tenant %s: an empty key (is its variable set?)", ft.Name)
+			}
+			if other, ok := keys[k]; ok {
+				return "", nil, fmt.Errorf("tenant %s: the key of %s", ft.Name, other)
+			}
+			keys[k] = ft.Name
+			t.Keys = append(t.Keys, k)
+		}
+
+		sets := ft.Modifiers
+		if len(sets) == 0 {
+			sets = []string{"common"}
+		}
+		mods := map[string]modifiers.ModifierMeta{}
+		for _, name := range sets {
+			set, ok := modifierSets[name]
+			if !ok {
+				return "", nil, fmt.Errorf("tenant %s: unknown modifier set %q", ft.Name, name)
+			}
+			for k, m := range set() {
+				mods[k] = m
+			}
+		}
+
+		// without fonts/ of the project the tenant renders without p_split metrics, as the daemon does
+		fonts, _ := projectFonts(projectRoot)
+		if ft.Fonts != "" {
+			if fonts, err = metrics.ScanFonts(abs(ft.Fonts)); err != nil {
+				return "", nil, fmt.Errorf("tenant %s: fonts: %w", ft.Name, err)
+			}
+		}
+		font := ft.Font
+		if font == "" {
+			font = fontsFlag
+		}
+		calendar := workCalendar
+		if ft.Calendar != "" {
+			calPath := abs(ft.Calendar)
+			if calendar = calendars[calPath]; calendar == nil {
+				if calendar, err = modifiers.LoadWorkCalendar(calPath); err != nil {
+					return "", nil, fmt.Errorf("tenant %s: %w", ft.Name, err)
+				}
+				calendars[calPath] = calendar
+			}
+		}
+
+		name := ft.Name
+		t.Fonts = fonts
+		t.Prepare = func(doc *docxgen.Docx) error {
+			if fonts != nil {
+				if set, err := fonts.Lookup(font); err == nil {
+					doc.SetFonts(set)
+				} else {
+					log.Printf("tenant %s: шрифты: %v\n", name, err)
+				}
+			}
+			doc.ImportModifiers(mods)
+			doc.SetImageHosts(imageHostsFlag...)
+			doc.SetWorkCalendar(calendar)
+			return nil
+		}
+		tenants = append(tenants, t)
+	}
+	return file.Header, tenants, nil
+}
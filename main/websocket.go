@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ---------- live-preview (WebSocket) ----------
+//
+// Some proxies buffer text/event-stream, and the SSE reload never reaches the browser.
+// /ws delivers the same notifications over a WebSocket (RFC 6455, only what the preview needs:
+// server text frames, ping/pong and close); previewHTML falls back to /events if it cannot connect.
+
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes.
+const (
+	wsText  = 0x1
+	wsClose = 0x8
+	wsPing  = 0x9
+	wsPong  = 0xA
+)
+
+// wsPingInterval keeps idle connections alive through proxies.
+const wsPingInterval = 30 * time.Second
+
+func wsHandler(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || key == "" {
+		http.Error(w, "websocket upgrade required", http.StatusBadRequest)
+		return
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket is not supported", http.StatusInternalServerError)
+		return
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	sum := sha1.Sum([]byte(key + wsGUID))
+	_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if rw.Flush() != nil {
+		return
+	}
+
+	ch := make(chan struct{}, 1)
+	sseMu.Lock()
+	sseClients[ch] = struct{}{}
+	sseMu.Unlock()
+	defer func() {
+		sseMu.Lock()
+		delete(sseClients, ch)
+		sseMu.Unlock()
+	}()
+
+	var wmu sync.Mutex
+	send := func(op byte, payload []byte) error {
+		wmu.Lock()
+		defer wmu.Unlock()
+		if err := wsWriteFrame(rw.Writer, op, payload); err != nil {
+			return err
+		}
+		return rw.Flush()
+	}
+
+	// the reader answers pings and notices the close of the connection
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			op, payload, err := wsReadFrame(rw.Reader)
+			if err != nil {
+				return
+			}
+			switch op {
+			case wsPing:
+				_ = send(wsPong, payload)
+			case wsClose:
+				_ = send(wsClose, nil)
+				return
+			}
+		}
+	}()
+
+	if send(wsText, []byte("init")) != nil {
+		return
+	}
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ch:
+			err = send(wsText, []byte("reload"))
+		case <-ticker.C:
+			err = send(wsPing, nil)
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// wsWriteFrame writes an unmasked server frame (small payloads only: control frames and notifications).
+func wsWriteFrame(w *bufio.Writer, op byte, payload []byte) error {
+	_ = w.WriteByte(0x80 | op) // FIN
+	switch n := len(payload); {
+	case n < 126:
+		_ = w.WriteByte(byte(n))
+	case n <= 0xFFFF:
+		_ = w.WriteByte(126)
+		_ = binary.Write(w, binary.BigEndian, uint16(n))
+	default:
+		_ = w.WriteByte(127)
+		_ = binary.Write(w, binary.BigEndian, uint64(n))
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// wsMaxFrame limits client frames: the preview only expects control frames from the browser.
+const wsMaxFrame = 1 << 16
+
+// wsReadFrame reads one client frame and unmasks its payload.
+func wsReadFrame(r *bufio.Reader) (op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(r, head[:]); err != nil {
+		return 0, nil, err
+	}
+	op = head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+	n := uint64(head[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext uint16
+		err = binary.Read(r, binary.BigEndian, &ext)
+		n = uint64(ext)
+	case 127:
+		err = binary.Read(r, binary.BigEndian, &n)
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	if n > wsMaxFrame {
+		return 0, nil, errors.New("websocket: frame too large")
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(r, mask[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return op, payload, nil
+}
@@ -0,0 +1,64 @@
+package docxgen
+
+import (
+	"path"
+	"strings"
+)
+
+// RemoveUnusedMedia removes image and chart relationships that are no longer referenced from their part
+// (pictures inside removed conditional blocks, loops with no rows) and then the media files and
+// the chart parts that no relationship points to, together with their [Content_Types].xml overrides.
+// Save and SaveToWriter call it automatically. Returns the number of removed media files and charts.
+func (d *Docx) RemoveUnusedMedia() int {
+	// 1. Image relationships without a reference in the part
+	rels := d.Rels()
+	used := map[string]bool{}
+	for relsPath := range d.files {
+		if !strings.HasSuffix(relsPath, ".rels") || !strings.Contains(relsPath, "_rels/") {
+			continue
+		}
+		partPath := path.Join(path.Dir(path.Dir(relsPath)), strings.TrimSuffix(path.Base(relsPath), ".rels"))
+		part, hasPart := d.files[partPath]
+
+		rels.Rewrite(partPath, func(r Relationship) (Relationship, bool) {
+			if r.External() {
+				return r, true
+			}
+			if (r.Type == RelTypeImage || r.Type == RelTypeChart) && hasPart && !referencesRel(part, r.ID) {
+				return r, false
+			}
+			used[rels.ResolveTarget(partPath, r)] = true
+			return r, true
+		})
+	}
+
+	// 2. Media files and charts that nobody points to
+	removed := map[string]bool{}
+	for name := range d.files {
+		isChart := strings.HasPrefix(name, "word/charts/") && path.Dir(name) == "word/charts"
+		if (strings.HasPrefix(name, "word/media/") || isChart) && !used[name] {
+			delete(d.files, name)
+			delete(d.localMedia, name)
+			delete(d.mediaParts, name)
+			removed["/"+name] = true
+			if isChart {
+				delete(d.files, path.Join("word/charts/_rels", path.Base(name)+".rels"))
+			}
+		}
+	}
+	if len(removed) == 0 {
+		return 0
+	}
+
+	// 3. Overrides of the removed files
+	types := d.ContentTypes()
+	for name := range removed {
+		types.RemoveOverride(name)
+	}
+	return len(removed)
+}
+
+// referencesRel reports whether the part refers to the relationship (r:embed, r:id, r:link, ...).
+func referencesRel(part []byte, id string) bool {
+	return strings.Contains(string(part), `="`+id+`"`)
+}
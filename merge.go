@@ -0,0 +1,224 @@
+package docxgen
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	numberingPath    = "word/numbering.xml"
+	relTypeNumbering = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/numbering"
+)
+
+var (
+	reDocumentRoot  = regexp.MustCompile(`<w:document\b[^>]*>`)
+	reNumberingRoot = regexp.MustCompile(`<w:numbering\b[^>]*>`)
+	reAbstractNum   = regexp.MustCompile(`(?s)<w:abstractNum\b[^>]*>.*?</w:abstractNum>`)
+	reNum           = regexp.MustCompile(`(?s)<w:num\b[^>]*>.*?</w:num>`)
+	reNumIDRef      = regexp.MustCompile(`<w:numId w:val="(\d+)"\s*/>`)
+	reAbstractIDRef = regexp.MustCompile(`<w:abstractNumId w:val="(\d+)"\s*/>`)
+	reNsid          = regexp.MustCompile(`<w:nsid\b[^>]*/>`)
+	reHdrFtrRefTag  = regexp.MustCompile(`<w:(?:headerReference|footerReference)\b[^>]*/>`)
+	reNoteRef       = regexp.MustCompile(`<w:(?:footnoteReference|endnoteReference)\b[^>]*/>`)
+)
+
+// AppendDocument appends the body of other to the end of the document, so that the documents
+// generated one per client can be printed as one file. With withPageBreak the appended body starts
+// on a new page.
+//
+// Together with the body come:
+//   - the pictures, charts and hyperlinks it refers to (with new r:ids, see ImportFragment);
+//   - its lists: the numbering definitions get new ids and the paragraphs are renumbered;
+//   - its styles that the document does not have (a style with the same id keeps the look of the document);
+//   - the namespaces of its root, the drawings get new ids.
+//
+// The appended body follows the page setup, headers and footers of the last section of the document;
+// footnotes, endnotes and comments of other are not carried over, and neither are their marks and
+// references in its body (their ids would point at the notes of the document). other itself is not changed.
+func (d *Docx) AppendDocument(other *Docx, withPageBreak bool) error {
+	src := other.Clone()
+	src.linkLocalMedia()
+
+	srcDoc, ok := src.files["word/document.xml"]
+	if !ok {
+		return fmt.Errorf("append document: the appended document has no word/document.xml")
+	}
+	frag, err := GetBodyFragment(string(srcDoc))
+	if err != nil {
+		return fmt.Errorf("append document: %w", err)
+	}
+	doc, ok := d.files["word/document.xml"]
+	if !ok || !strings.Contains(string(doc), BodyClosingTag) {
+		return fmt.Errorf("append document: the document has no body")
+	}
+
+	// -------- The body: without its own section, with the links of this document --------
+	frag = frag[:bodyEnd(frag, len(frag))]
+	frag = reHdrFtrRefTag.ReplaceAllString(frag, "") // the headers of other are not carried over
+	frag = stripRefRuns(stripCommentRefs(reCommentMark.ReplaceAllString(frag, "")), reNoteRef)
+	frag = d.Rels().ImportFragment(src, "document", "document", frag)
+
+	styles := d.missingStyles(src)
+	renumber := d.importNumbering(src, frag+strings.Join(styles, ""))
+	frag = renumber(frag)
+	for i := range styles {
+		styles[i] = renumber(styles[i])
+	}
+	d.addStyles(src, styles)
+
+	frag = d.renumberDrawings(frag)
+	if withPageBreak {
+		frag = pageBreakParagraph + frag
+	}
+
+	// -------- Before the body level sectPr of the document --------
+	body := string(doc)
+	if root := reDocumentRoot.FindStringIndex(body); root != nil {
+		if srcRoot := reDocumentRoot.FindString(string(srcDoc)); srcRoot != "" {
+			body = body[:root[0]] + withNamespaces(body[root[0]:root[1]], srcRoot) + body[root[1]:]
+		}
+	}
+	at := bodyEnd(body, strings.LastIndex(body, BodyClosingTag))
+	d.files["word/document.xml"] = []byte(body[:at] + frag + body[at:])
+	return nil
+}
+
+// bodyEnd - where the content of the body ends in s[:end]: at the body level <w:sectPr>
+// (the last element, after the last paragraph or table) or at end.
+func bodyEnd(s string, end int) int {
+	i := strings.LastIndex(s[:end], "<w:sectPr")
+	if i < 0 || strings.Contains(s[i:end], "</w:p>") || strings.Contains(s[i:end], "</w:tbl>") {
+		return end
+	}
+	return i
+}
+
+// missingStyles - the styles of src whose type and id the document does not have.
+func (d *Docx) missingStyles(src *Docx) []string {
+	have := map[string]bool{}
+	for _, s := range reStyle.FindAllString(string(d.files[stylesPath]), -1) {
+		have[styleKey(s)] = true
+	}
+	var out []string
+	for _, s := range reStyle.FindAllString(string(src.files[stylesPath]), -1) {
+		if k := styleKey(s); !have[k] {
+			have[k] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// styleKey - "paragraph/Heading1": the type and the id of a <w:style>.
+func styleKey(style string) string {
+	open := style[:strings.Index(style, ">")+1]
+	return xmlAttr(open, "w:type") + "/" + xmlAttr(open, "w:styleId")
+}
+
+// addStyles appends the styles to styles.xml, a document without styles takes those of src as a whole.
+func (d *Docx) addStyles(src *Docx, styles []string) {
+	if len(styles) == 0 {
+		return
+	}
+	base := string(d.files[stylesPath])
+	end := strings.LastIndex(base, "</w:styles>")
+	if end < 0 {
+		d.setPart(stylesPath, src.files[stylesPath], relTypeStyles, wmlType+"styles+xml")
+		return
+	}
+	if root := reStylesRoot.FindStringIndex(base); root != nil {
+		if srcRoot := reStylesRoot.FindString(string(src.files[stylesPath])); srcRoot != "" {
+			open := withNamespaces(base[root[0]:root[1]], srcRoot)
+			base = base[:root[0]] + open + base[root[1]:]
+			end += len(open) - (root[1] - root[0])
+		}
+	}
+	d.files[stylesPath] = []byte(base[:end] + strings.Join(styles, "") + base[end:])
+}
+
+// importNumbering copies the lists of src that text uses (<w:numId w:val="N"/>) into numbering.xml
+// under new ids and returns the function that renumbers the references of src.
+func (d *Docx) importNumbering(src *Docx, text string) func(string) string {
+	keep := func(s string) string { return s }
+	srcNumbering := string(src.files[numberingPath])
+	used := map[string]bool{}
+	for _, m := range reNumIDRef.FindAllStringSubmatch(text, -1) {
+		used[m[1]] = m[1] != "0" // 0 — "no list"
+	}
+	if srcNumbering == "" || len(used) == 0 {
+		return keep
+	}
+
+	base := string(d.files[numberingPath])
+	if !strings.Contains(base, "</w:numbering>") {
+		open := "<w:numbering>"
+		if root := reNumberingRoot.FindString(srcNumbering); root != "" {
+			open = root
+		}
+		base = xml.Header + open + "</w:numbering>"
+	}
+	nextAbstract, nextNum := maxNumberingID(base, reAbstractNum, "w:abstractNumId"), maxNumberingID(base, reNum, "w:numId")
+
+	// -------- The lists used and their definitions, with new ids --------
+	abstracts := map[string]string{}
+	for _, s := range reAbstractNum.FindAllString(srcNumbering, -1) {
+		abstracts[xmlAttr(s[:strings.Index(s, ">")+1], "w:abstractNumId")] = s
+	}
+	numIDs, abstractIDs := map[string]string{}, map[string]string{}
+	var newAbstracts, newNums strings.Builder
+	for _, s := range reNum.FindAllString(srcNumbering, -1) {
+		id := xmlAttr(s[:strings.Index(s, ">")+1], "w:numId")
+		if !used[id] || numIDs[id] != "" {
+			continue
+		}
+		nextNum++
+		numIDs[id] = strconv.Itoa(nextNum)
+		s = strings.Replace(s, `w:numId="`+id+`"`, `w:numId="`+numIDs[id]+`"`, 1)
+		if m := reAbstractIDRef.FindStringSubmatch(s); m != nil {
+			abs := m[1]
+			if abstractIDs[abs] == "" {
+				if def, ok := abstracts[abs]; ok {
+					nextAbstract++
+					abstractIDs[abs] = strconv.Itoa(nextAbstract)
+					def = strings.Replace(def, `w:abstractNumId="`+abs+`"`, `w:abstractNumId="`+abstractIDs[abs]+`"`, 1)
+					newAbstracts.WriteString(reNsid.ReplaceAllString(def, "")) // a list of its own, not a continuation
+				}
+			}
+			if abstractIDs[abs] != "" {
+				s = strings.Replace(s, m[0], `<w:abstractNumId w:val="`+abstractIDs[abs]+`"/>`, 1)
+			}
+		}
+		newNums.WriteString(s)
+	}
+	if len(numIDs) == 0 {
+		return keep
+	}
+
+	// the abstract definitions go before all <w:num>
+	at := strings.Index(base, "<w:num ")
+	if at < 0 {
+		at = strings.LastIndex(base, "</w:numbering>")
+	}
+	base = base[:at] + newAbstracts.String() + base[at:]
+	at = strings.LastIndex(base, "</w:num>")
+	if at < 0 {
+		at = strings.LastIndex(base, "</w:numbering>")
+	} else {
+		at += len("</w:num>")
+	}
+	base = base[:at] + newNums.String() + base[at:]
+	d.setPart(numberingPath, []byte(base), relTypeNumbering, wmlType+"numbering+xml")
+
+	return func(s string) string {
+		return reNumIDRef.ReplaceAllStringFunc(s, func(ref string) string {
+			id := reNumIDRef.FindStringSubmatch(ref)[1]
+			if n, ok := numIDs[id]; ok {
+				return `<w:numId w:val="` + n + `"/>`
+			}
+			return ref
+		})
+	}
+}
@@ -0,0 +1,278 @@
+package docxgen
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// ============================================================================
+// { MERGEFIELD name } of the Word mail merge templates as {name} tags
+// ============================================================================
+
+var (
+	reFldSimple    = regexp.MustCompile(`(?s)<w:fldSimple\b[^>]*\bw:instr="([^"]*)"[^>]*?(?:/>|>(.*?)</w:fldSimple>)`)
+	reFldChar      = regexp.MustCompile(`<w:fldChar\b[^>]*\bw:fldCharType="(begin|separate|end)"`)
+	reInstrText    = regexp.MustCompile(`(?s)<w:instrText\b[^>]*>(.*?)</w:instrText>`)
+	reFieldRunProp = regexp.MustCompile(`(?s)<w:rPr>.*?</w:rPr>`)
+)
+
+// xmlUnescaper - the XML entities of a text or an attribute value.
+var xmlUnescaper = strings.NewReplacer("&quot;", `"`, "&apos;", "'", "&lt;", "<", "&gt;", ">", "&amp;", "&")
+
+// wordDateLayout - the parts of a Word date picture (\@ "dd.MM.yyyy") and their Go layout,
+// the longer first.
+var wordDateLayout = strings.NewReplacer(
+	"yyyy", "2006", "yy", "06", "MM", "01", "M", "1", "dd", "02", "d", "2",
+	"HH", "15", "H", "15", "hh", "03", "h", "3", "mm", "04", "ss", "05",
+)
+
+// ConvertMergeFields turns the MERGEFIELD fields of a Word mail merge template — simple
+// (<w:fldSimple>) and complex (fldChar begin … instrText … end) — into {name} tags in a run with
+// the format of the shown result, so the template fills them from the data like its own tags.
+// A name with spaces or other characters a tag cannot hold ("Имя клиента", "client-name") becomes
+// Имя_клиента, client_name (see mergeFieldName), and ExecuteTemplate finds the data of the tag under
+// the original name too. The switches
+// become modifiers: \b "text" — prefix, \f "text" — postfix, \* Upper — caps,
+// \@ "dd.MM.yyyy" — date_format; the others are dropped. Other fields and a MERGEFIELD inside
+// another field (IF, ...) stay as they are.
+func ConvertMergeFields(body string) string {
+	body = reFldSimple.ReplaceAllStringFunc(body, func(s string) string {
+		m := reFldSimple.FindStringSubmatch(s)
+		tag, ok := mergeFieldTag(xmlUnescaper.Replace(m[1]))
+		if !ok {
+			return s
+		}
+		return fieldRun(reFieldRunProp.FindString(m[2]), tag)
+	})
+	if !strings.Contains(body, "<w:fldChar") {
+		return body
+	}
+
+	var out strings.Builder
+	pos := 0
+	for {
+		loc := reFldChar.FindStringSubmatchIndex(body[pos:])
+		if loc == nil {
+			break
+		}
+		at := pos + loc[0]
+		if body[pos+loc[2]:pos+loc[3]] != "begin" {
+			out.WriteString(body[pos : pos+loc[1]])
+			pos += loc[1]
+			continue
+		}
+		start, end, tag, ok := complexMergeField(body, at)
+		if !ok {
+			skip := fieldEnd(body, at) // the fields inside another one stay with it
+			out.WriteString(body[pos:skip])
+			pos = skip
+			continue
+		}
+		out.WriteString(body[pos:start])
+		out.WriteString(tag)
+		pos = end
+	}
+	out.WriteString(body[pos:])
+	return out.String()
+}
+
+// complexMergeField reads the field that begins with the fldChar at s[at]: the bounds of its runs and
+// the run with the tag; ok is false when it is not a MERGEFIELD, has a field inside or spans paragraphs.
+func complexMergeField(s string, at int) (start, end int, run string, ok bool) {
+	start = max(strings.LastIndex(s[:at], "<w:r>"), strings.LastIndex(s[:at], "<w:r "))
+	if start < 0 {
+		return 0, 0, "", false
+	}
+	var instr strings.Builder
+	separate := -1
+	pos := at
+	for n := 0; ; n++ {
+		loc := reFldChar.FindStringSubmatchIndex(s[pos:])
+		if loc == nil {
+			return 0, 0, "", false
+		}
+		kind := s[pos+loc[2] : pos+loc[3]]
+		next := pos + loc[0]
+		if n > 0 && kind == "begin" {
+			return 0, 0, "", false // a field inside: left for Word
+		}
+		if separate < 0 {
+			for _, m := range reInstrText.FindAllStringSubmatch(s[pos:next], -1) {
+				instr.WriteString(m[1])
+			}
+		}
+		switch kind {
+		case "separate":
+			separate = next
+		case "end":
+			closing := strings.Index(s[next:], "</w:r>")
+			if closing < 0 || strings.Contains(s[start:next], "</w:p>") {
+				return 0, 0, "", false
+			}
+			end = next + closing + len("</w:r>")
+			tag, isMerge := mergeFieldTag(xmlUnescaper.Replace(instr.String()))
+			if !isMerge {
+				return 0, 0, "", false
+			}
+			props := reFieldRunProp.FindString(s[start:at])
+			if separate >= 0 {
+				if p := reFieldRunProp.FindString(s[separate:next]); p != "" {
+					props = p
+				}
+			}
+			return start, end, fieldRun(props, tag), true
+		}
+		pos += loc[1]
+	}
+}
+
+// fieldEnd - the end of the fldChar "end" that closes the field beginning at s[at], or past the begin.
+func fieldEnd(s string, at int) int {
+	depth := 0
+	for pos := at; ; {
+		loc := reFldChar.FindStringSubmatchIndex(s[pos:])
+		if loc == nil {
+			return at + len("<w:fldChar")
+		}
+		switch s[pos+loc[2] : pos+loc[3]] {
+		case "begin":
+			depth++
+		case "end":
+			if depth--; depth == 0 {
+				return pos + loc[1]
+			}
+		}
+		pos += loc[1]
+	}
+}
+
+// fieldRun - the run of the tag with the run properties of the field.
+func fieldRun(props, tag string) string {
+	return "<w:r>" + props + "<w:t xml:space=\"preserve\">" + xmlEscape(tag) + "</w:t></w:r>"
+}
+
+// mergeFieldTag converts the instruction " MERGEFIELD name \* MERGEFORMAT " into "{name}".
+func mergeFieldTag(instr string) (string, bool) {
+	words := fieldWords(instr)
+	if len(words) < 2 || !strings.EqualFold(words[0], "MERGEFIELD") {
+		return "", false
+	}
+	name := mergeFieldName(words[1])
+	if name == "" {
+		return "", false
+	}
+	var mods []string
+	for i := 2; i < len(words); i++ {
+		arg := ""
+		if i+1 < len(words) {
+			arg = words[i+1]
+		}
+		switch words[i] {
+		case `\b`:
+			mods, i = append(mods, "prefix:`"+arg+"`"), i+1
+		case `\f`:
+			mods, i = append(mods, "postfix:`"+arg+"`"), i+1
+		case `\*`:
+			if strings.EqualFold(arg, "Upper") {
+				mods = append(mods, "caps")
+			}
+			i++
+		case `\@`:
+			if layout, ok := goDateLayout(arg); ok {
+				mods = append(mods, "date_format:`"+layout+"`")
+			}
+			i++
+		}
+	}
+	if len(mods) == 0 {
+		return "{" + name + "}", true
+	}
+	return "{" + name + "|" + strings.Join(mods, "|") + "}", true
+}
+
+// mergeFieldName - the tag name of a merge field: the spaces and the other characters that a tag name
+// cannot hold become "_" ("Имя клиента" — Имя_клиента, "client-name" — client_name), a name that starts
+// with a digit gets "_" before it.
+func mergeFieldName(name string) string {
+	name = strings.Join(strings.Fields(name), "_")
+	var out strings.Builder
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r) || r == '_':
+		case unicode.IsDigit(r):
+			if i == 0 {
+				out.WriteByte('_')
+			}
+		default:
+			r = '_'
+		}
+		out.WriteRune(r)
+	}
+	return out.String()
+}
+
+// withMergeFieldNames - the data where each top-level key that is not a tag name ("Имя клиента") is also
+// under its merge field name (Имя_клиента), unless the data has that key already. The data itself
+// is not changed: the copy is made only when there is something to add.
+func withMergeFieldNames(data map[string]any) map[string]any {
+	var out map[string]any
+	for k, v := range data {
+		name := mergeFieldName(k)
+		if name == k || name == "" {
+			continue
+		}
+		if _, ok := data[name]; ok {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]any, len(data)+1)
+			for k, v := range data {
+				out[k] = v
+			}
+		}
+		out[name] = v
+	}
+	if out == nil {
+		return data
+	}
+	return out
+}
+
+// fieldWords splits a field instruction into its words, a "quoted text" is one word.
+func fieldWords(instr string) []string {
+	var words []string
+	var word strings.Builder
+	quoted, inWord := false, false
+	for _, r := range instr {
+		switch {
+		case r == '"':
+			quoted, inWord = !quoted, true
+		case unicode.IsSpace(r) && !quoted:
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// goDateLayout converts a numeric Word date picture ("dd.MM.yyyy HH:mm") into a Go layout;
+// ok is false for the pictures with month names and other letters.
+func goDateLayout(picture string) (string, bool) {
+	layout := wordDateLayout.Replace(picture)
+	for _, r := range layout {
+		if unicode.IsLetter(r) {
+			return "", false
+		}
+	}
+	return layout, layout != ""
+}
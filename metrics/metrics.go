@@ -34,16 +34,30 @@ type FontMeasurer interface {
 // LoadFonts загружает шрифты из файловой системы.
 func LoadFonts(pathRegular, pathBold, pathItalic, pathBoldItalic string) (*FontSet, error) {
 	paths := []string{pathRegular, pathBold, pathItalic, pathBoldItalic}
-	var fonts [4]*sfnt.Font
+	var data [4][]byte
 
 	for i, path := range paths {
-		data, err := ioutil.ReadFile(path)
+		raw, err := ioutil.ReadFile(path)
 		if err != nil {
 			return nil, fmt.Errorf("read font %s: %w", path, err)
 		}
-		font, err := sfnt.Parse(data)
+		data[i] = raw
+	}
+	return parseFonts(data, paths)
+}
+
+// ParseFonts собирает набор из TTF в памяти — для сборок без файловой системы (WASM в браузере).
+func ParseFonts(regular, bold, italic, boldItalic []byte) (*FontSet, error) {
+	return parseFonts([4][]byte{regular, bold, italic, boldItalic}, []string{"regular", "bold", "italic", "bold italic"})
+}
+
+// parseFonts разбирает четыре начертания; names — их имена для ошибок (пути файлов).
+func parseFonts(data [4][]byte, names []string) (*FontSet, error) {
+	var fonts [4]*sfnt.Font
+	for i := range data {
+		font, err := sfnt.Parse(data[i])
 		if err != nil {
-			return nil, fmt.Errorf("parse font %s: %w", path, err)
+			return nil, fmt.Errorf("parse font %s: %w", names[i], err)
 		}
 		fonts[i] = font
 	}
@@ -0,0 +1,180 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// ErrUnknownFontSet — в реестре нет набора с таким ID или семейством.
+var ErrUnknownFontSet = errors.New("unknown font set")
+
+// Registry — реестр наборов шрифтов для p_split: шаблоны, свёрстанные не в Times New Roman,
+// получают свой набор по ID (имя папки: "PTSerif") или по семейству ("PT Serif").
+// Безопасен для одновременного использования.
+type Registry struct {
+	mu   sync.RWMutex
+	sets map[string]*registryEntry
+}
+
+type registryEntry struct {
+	id     string
+	family string
+	paths  []string
+	set    *FontSet
+}
+
+// NewRegistry создаёт пустой реестр.
+func NewRegistry() *Registry {
+	return &Registry{sets: map[string]*registryEntry{}}
+}
+
+// ScanFonts строит реестр по каталогу: каждая подпапка — набор, её имя — ID,
+// начертания определяются по таблице name файлов .ttf/.otf внутри.
+//
+//	fonts/
+//	  TimesNewRoman/ TimesNewRoman.ttf TimesNewRomanBold.ttf ...
+//	  PTSerif/       PTSerif-Regular.ttf PTSerif-Bold.ttf ...
+func ScanFonts(dir string) (*Registry, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("scan fonts: %w", err)
+	}
+	r := NewRegistry()
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		files, _ := filepath.Glob(filepath.Join(dir, e.Name(), "*.[ot]tf"))
+		if len(files) == 0 {
+			continue
+		}
+		if err := r.Add(e.Name(), files...); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Add регистрирует набор из файлов шрифтов. Начертание каждого файла берётся из его
+// подсемейства (Regular, Bold, Italic, Bold Italic); недостающие начертания заменяются обычным.
+func (r *Registry) Add(id string, paths ...string) error {
+	var fonts [4]*sfnt.Font
+	family := ""
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read font %s: %w", path, err)
+		}
+		f, err := sfnt.Parse(data)
+		if err != nil {
+			return fmt.Errorf("parse font %s: %w", path, err)
+		}
+		var buf sfnt.Buffer
+		name, _ := f.Name(&buf, sfnt.NameIDFamily)
+		sub, _ := f.Name(&buf, sfnt.NameIDSubfamily)
+		style := styleOf(sub)
+		if fonts[style] == nil {
+			fonts[style] = f
+		}
+		if family == "" || style == Regular {
+			family = name
+		}
+	}
+
+	base := fonts[Regular]
+	for _, f := range fonts {
+		if base == nil {
+			base = f
+		}
+	}
+	if base == nil {
+		return fmt.Errorf("font set %s: no fonts", id)
+	}
+	for i := range fonts {
+		if fonts[i] == nil {
+			fonts[i] = base
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sets[strings.ToLower(id)] = &registryEntry{
+		id:     id,
+		family: family,
+		paths:  append([]string(nil), paths...),
+		set:    &FontSet{Regular: fonts[Regular], Bold: fonts[Bold], Italic: fonts[Italic], BoldItalic: fonts[BoldItalic]},
+	}
+	return nil
+}
+
+// Lookup находит набор по ID или по семейству, без учёта регистра.
+func (r *Registry) Lookup(name string) (*FontSet, error) {
+	e, err := r.find(name)
+	if err != nil {
+		return nil, err
+	}
+	return e.set, nil
+}
+
+// Paths возвращает файлы набора (например, для встраивания шрифтов в документ).
+func (r *Registry) Paths(name string) ([]string, error) {
+	e, err := r.find(name)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string(nil), e.paths...), nil
+}
+
+func (r *Registry) find(name string) (*registryEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key := strings.ToLower(strings.TrimSpace(name))
+	if e, ok := r.sets[key]; ok {
+		return e, nil
+	}
+	for _, e := range r.sets {
+		if strings.ToLower(e.family) == key {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %q (available: %s)", ErrUnknownFontSet, name, strings.Join(r.names(), ", "))
+}
+
+// Names возвращает ID зарегистрированных наборов по алфавиту.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.names()
+}
+
+func (r *Registry) names() []string {
+	names := make([]string, 0, len(r.sets))
+	for _, e := range r.sets {
+		names = append(names, e.id)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// styleOf — начертание по подсемейству шрифта.
+func styleOf(subfamily string) Style {
+	s := strings.ToLower(subfamily)
+	bold := strings.Contains(s, "bold")
+	italic := strings.Contains(s, "italic") || strings.Contains(s, "oblique")
+	switch {
+	case bold && italic:
+		return BoldItalic
+	case bold:
+		return Bold
+	case italic:
+		return Italic
+	}
+	return Regular
+}
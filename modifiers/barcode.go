@@ -7,10 +7,11 @@ var BarCodeFunc func(string, ...string) RawXML
 // Example of use:
 //
 // {product.code|barcode:`code128`:`anchor`:`right`:`top`:`50mm*15mm`:`10%`:`2/5`:`border`}
+// {product.ean|barcode:`ean13`:`inline`:`40mm*20mm`:`8pt`:`quiet=11`}
 //
 // Format:
 //
-// {value|barcode:[type]:[mode]:[align]:[valign]:[size]:[crop%]:[margins]:[border]}
+// {value|barcode:[type]:[mode]:[align]:[valign]:[size]:[crop%]:[margins]:[border]:[text|<N>pt]:[quiet=N]:[wrap]:[z=N]}
 //
 // Parameters (all optional, the order is not important):
 //
@@ -35,7 +36,7 @@ var BarCodeFunc func(string, ...string) RawXML
 //
 // - "<W>mm*<H>mm" — both sides are explicitly specified;
 //
-// - "<N>%" — width as a percentage of the page width;
+// - "<N>%" — width as a percentage of the text area width (page minus margins) of the section of the tag;
 //
 // - <W>"%*<H>mm" or vice versa - combined sizes (percent + millimeters).
 //
@@ -50,9 +51,22 @@ var BarCodeFunc func(string, ...string) RawXML
 //
 // - border — a flag that adds a thin black border (≈ 0.5 pt) around the barcode.
 //
+//   - text / caption — prints the human-readable value under the bars (inside the image).
+//     "<N>pt" sets the caption font size (8pt by default) and turns the caption on.
+//
+//   - wrap — text wrapping of the anchor: "square" (default), "tight", "through", "topbottom",
+//     "none" / "front" (over the text), "behind" (under the text — stamps and seals under a signature).
+//
+//   - z=<N> — z-order of the anchor among other drawings (2 by default).
+//
+//   - quiet=<N> / qz=<N> — the quiet zone (white margin) on the left and right inside the image,
+//     in modules (the width of the narrowest bar) or in millimeters ("quiet=3mm").
+//     By default the symbology minimum: 10 modules for Code128, 11 for EAN-13.
+//     Scanners often reject barcodes without it, so crop should not eat into it; "quiet=0" gives tight bars.
+//
 // Features:
 //
-// - Barcode scales proportionally or to specified sizes.
+// - Bars are scaled by an integer number of pixels per module, so they stay sharp at any size.
 //   - Dimensions can be set as absolute (mm) or relative (% of page).
 //   - "Inline" and "anchor" modes are supported, similar to a QR code.
 //   - Cropping of white margins and setting of external paddings are supported.
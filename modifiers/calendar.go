@@ -0,0 +1,249 @@
+package modifiers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// WorkCalendar is a production calendar: holidays that fall on weekdays
+// and weekends that were moved to working days.
+// Without a calendar, only Saturday and Sunday are considered days off.
+//
+// JSON format (RF production calendar):
+//
+//	{
+//	  "holidays": ["2025-01-01", "2025-01-02", "2025-05-01"],
+//	  "workdays": ["2025-11-01"]
+//	}
+type WorkCalendar struct {
+	Holidays map[string]bool // non-working days, "2006-01-02"
+	Workdays map[string]bool // working Saturdays and Sundays, "2006-01-02"
+}
+
+// calendarLayout is the key format of the WorkCalendar days.
+const calendarLayout = "2006-01-02"
+
+// ParseWorkCalendar reads the calendar from JSON.
+func ParseWorkCalendar(data []byte) (*WorkCalendar, error) {
+	var raw struct {
+		Holidays []string `json:"holidays"`
+		Workdays []string `json:"workdays"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse calendar: %w", err)
+	}
+
+	cal := &WorkCalendar{
+		Holidays: make(map[string]bool, len(raw.Holidays)),
+		Workdays: make(map[string]bool, len(raw.Workdays)),
+	}
+	for _, d := range raw.Holidays {
+		t, err := time.Parse(calendarLayout, strings.TrimSpace(d))
+		if err != nil {
+			return nil, fmt.Errorf("calendar holiday %q: %w", d, err)
+		}
+		cal.Holidays[t.Format(calendarLayout)] = true
+	}
+	for _, d := range raw.Workdays {
+		t, err := time.Parse(calendarLayout, strings.TrimSpace(d))
+		if err != nil {
+			return nil, fmt.Errorf("calendar workday %q: %w", d, err)
+		}
+		cal.Workdays[t.Format(calendarLayout)] = true
+	}
+	return cal, nil
+}
+
+// LoadWorkCalendar reads the calendar from a JSON file.
+func LoadWorkCalendar(path string) (*WorkCalendar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read calendar %s: %w", path, err)
+	}
+	return ParseWorkCalendar(data)
+}
+
+// IsWorkday reports whether the day is a working day. A nil calendar only knows weekends.
+func (c *WorkCalendar) IsWorkday(t time.Time) bool {
+	if c != nil {
+		key := t.Format(calendarLayout)
+		if c.Workdays[key] {
+			return true
+		}
+		if c.Holidays[key] {
+			return false
+		}
+	}
+	wd := t.Weekday()
+	return wd != time.Saturday && wd != time.Sunday
+}
+
+// AddWorkdays shifts the date by n working days (backward if n < 0). It walks day by day:
+// the shifts of the data are bounded by MaxWorkdayShift.
+func (c *WorkCalendar) AddWorkdays(t time.Time, n int) time.Time {
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	for n > 0 {
+		t = t.AddDate(0, 0, step)
+		if c.IsWorkday(t) {
+			n--
+		}
+	}
+	return t
+}
+
+// NextWorkday returns the date itself if it is a working day, otherwise the nearest next working day.
+func (c *WorkCalendar) NextWorkday(t time.Time) time.Time {
+	for !c.IsWorkday(t) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// -------- date arithmetic --------
+
+// MaxWorkdayShift - the largest shift of add_days in working days, about four centuries: a larger one
+// in the data leaves the date as it is instead of stalling the render.
+const MaxWorkdayShift = 100000
+
+// defaultDateLayout - The output format of the date arithmetic modifiers,
+// it is understood by date_format further along the pipeline.
+const defaultDateLayout = "02.01.2006"
+
+// AddDaysFactory returns the add_days modifier bound to the production calendar (nil — weekends only).
+//
+// Options (any order):
+//   - "рабочие" / "рабочих" / "working" / "business" — count working days instead of calendar days;
+//   - `layout:2 January 2006` (`формат:...`) — the output format, "02.01.2006" by default;
+//     a bare Go layout (`2006-01-02`) works too, if it has the year 2006, the month 01 or the day 02.
+//
+// Other options are ignored (see Options.OnUnknownOption).
+// A shift of more than MaxWorkdayShift working days leaves the value as it is.
+//
+// Examples:
+//
+//	{date|add_days:10}                             → "24.10.2025"
+//	{date|add_days:10:`рабочие`}                   → "28.10.2025"
+//	{date|add_days:-3:`рабочих`}                   → "09.10.2025"
+//	{date|add_days:10:`рабочие`|date_ordinal:`род`} → "28-го октября"
+//	{date|add_days:1:`layout:Jan 2`}               → "Oct 15"
+func AddDaysFactory(cal *WorkCalendar) func(val any, n int, opts ...string) string {
+	return addDays(cal, nil)
+}
+
+// addDays - add_days telling unknown about the options it ignores (nil — silently).
+func addDays(cal *WorkCalendar, unknown func(option string)) func(val any, n int, opts ...string) string {
+	return func(val any, n int, opts ...string) string {
+		t, ok := toTime(val)
+		if !ok || t.IsZero() {
+			return strings.TrimSpace(ValueText(val))
+		}
+
+		working, layout := parseDateArithOpts(opts, unknown)
+		if working {
+			if n > MaxWorkdayShift || n < -MaxWorkdayShift {
+				return strings.TrimSpace(ValueText(val))
+			}
+			t = cal.AddWorkdays(t, n)
+		} else {
+			t = t.AddDate(0, 0, n)
+		}
+		return t.Format(layout)
+	}
+}
+
+// NextWorkdayFactory returns the next_workday modifier: the date itself or the nearest working day after it.
+//
+// Example:
+//
+//	{deadline|next_workday} → "13.10.2025" (for Saturday 11.10.2025)
+func NextWorkdayFactory(cal *WorkCalendar) func(val any, opts ...string) string {
+	return nextWorkday(cal, nil)
+}
+
+// nextWorkday - next_workday telling unknown about the options it ignores (nil — silently).
+func nextWorkday(cal *WorkCalendar, unknown func(option string)) func(val any, opts ...string) string {
+	return func(val any, opts ...string) string {
+		t, ok := toTime(val)
+		if !ok || t.IsZero() {
+			return strings.TrimSpace(ValueText(val))
+		}
+		_, layout := parseDateArithOpts(opts, unknown)
+		return cal.NextWorkday(t).Format(layout)
+	}
+}
+
+// AddMonths - Shifts the date by n months. The end of the month is kept:
+// 31.01 + 1 month = 28.02 (29.02), not 03.03 as time.AddDate would do.
+//
+// Examples:
+//
+//	{date|add_months:3}               → "14.01.2026"
+//	{date|add_months:1:`2006-01-02`}  → "2025-11-14"
+func AddMonths(val any, n int, opts ...string) string {
+	return addMonths(nil)(val, n, opts...)
+}
+
+// addMonths - add_months telling unknown about the options it ignores (nil — silently).
+func addMonths(unknown func(option string)) func(val any, n int, opts ...string) string {
+	return func(val any, n int, opts ...string) string {
+		t, ok := toTime(val)
+		if !ok || t.IsZero() {
+			return strings.TrimSpace(ValueText(val))
+		}
+		_, layout := parseDateArithOpts(opts, unknown)
+
+		first := time.Date(t.Year(), t.Month(), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+		first = first.AddDate(0, n, 0)
+		lastDay := first.AddDate(0, 1, -1).Day()
+		day := t.Day()
+		if day > lastDay {
+			day = lastDay
+		}
+		return first.AddDate(0, 0, day-1).Format(layout)
+	}
+}
+
+// isBareLayout - an option without `layout:` is taken for the output layout only when it has
+// the year, the month or the day of the reference date: "2006-01-02" is, "Jan 2" or a typo is not.
+func isBareLayout(o string) bool {
+	return strings.Contains(o, "2006") || strings.Contains(o, "01") || strings.Contains(o, "02")
+}
+
+// parseDateArithOpts recognizes the "working days" flag and the output layout: given explicitly
+// (`layout:...`, `формат:...`) or a bare option with a reference date element (see isBareLayout).
+// Any other option is passed to unknown, if set, and ignored.
+func parseDateArithOpts(opts []string, unknown func(option string)) (working bool, layout string) {
+	layout = defaultDateLayout
+	for _, o := range opts {
+		o = strings.TrimSpace(o)
+		if name, value, ok := strings.Cut(o, ":"); ok {
+			if name = strings.ToLower(strings.TrimSpace(name)); name == "layout" || name == "формат" {
+				if value != "" {
+					layout = value
+				}
+				continue
+			}
+		}
+		switch strings.ToLower(o) {
+		case "":
+			continue
+		case "рабочие", "рабочих", "рабочий", "раб", "working", "business", "workdays":
+			working = true
+		case "календарные", "календарных", "calendar":
+			working = false
+		default:
+			if isBareLayout(o) {
+				layout = o
+			} else if unknown != nil {
+				unknown(o)
+			}
+		}
+	}
+	return working, layout
+}
@@ -0,0 +1,218 @@
+package modifiers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// -------- Luhn --------
+
+// Luhn - Appends the Luhn check digit (bank cards, some registry numbers).
+//
+// Example:
+//
+//	{card|luhn} → "79927398713" (for "7992739871")
+func Luhn(s string) string {
+	digits := onlyDigits(s)
+	if digits == "" {
+		return s
+	}
+	return strings.TrimSpace(s) + strconv.Itoa(luhnDigit(digits))
+}
+
+// LuhnCheck - Validates the number by the Luhn algorithm and returns ok or bad.
+// If ok/bad are not set, "✓" and "✗" are used.
+//
+// Example:
+//
+//	{account|luhn_check:`✓`:`✗`} → "✓"
+func LuhnCheck(s string, marks ...string) string {
+	digits := onlyDigits(s)
+	valid := len(digits) > 1 && luhnDigit(digits[:len(digits)-1]) == int(digits[len(digits)-1]-'0')
+	return pickMark(valid, marks)
+}
+
+// -------- Control digits of Russian registry numbers --------
+
+// ControlDigit - Appends control digits to a number without them.
+// The kind of number is the first parameter:
+//   - "inn" / "инн" — 9 digits → 10-digit INN of an organization, 10 digits → 12-digit INN of a person;
+//   - "ogrn" / "огрн" — 12 digits → OGRN, 14 digits → OGRNIP;
+//   - "snils" / "снилс" — 9 digits → SNILS (two control digits through a space);
+//   - "ean" / "ean13" — 12 digits → EAN-13;
+//   - "luhn" — Luhn digit.
+//
+// If the length does not fit the kind, the value is returned unchanged.
+//
+// Examples:
+//
+//	{inn_base|control_digit:`инн`}      → "7707083893"
+//	{snils_base|control_digit:`снилс`}  → "112-233-445 95"
+func ControlDigit(s, kind string) string {
+	digits := onlyDigits(s)
+	check, ok := controlDigits(strings.ToLower(strings.TrimSpace(kind)), digits)
+	if !ok {
+		return s
+	}
+	s = strings.TrimSpace(s)
+	if len(check) == 2 && strings.ContainsAny(s, "- ") {
+		return s + " " + check
+	}
+	return s + check
+}
+
+// ControlCheck - Validates the control digits of a complete number and returns ok or bad
+// (by default "✓" / "✗"). The kinds are the same as in ControlDigit.
+//
+// Examples:
+//
+//	{inn|control_check:`инн`}                        → "✓"
+//	{ogrn|control_check:`огрн`:`верно`:`ошибка`}     → "ошибка"
+func ControlCheck(s, kind string, marks ...string) string {
+	return pickMark(ValidControlDigits(s, kind), marks)
+}
+
+// ValidControlDigits reports whether the number of the given kind has correct control digits.
+func ValidControlDigits(s, kind string) bool {
+	digits := onlyDigits(s)
+	kind = strings.ToLower(strings.TrimSpace(kind))
+
+	n := 1
+	switch kind {
+	case "inn", "инн":
+		if len(digits) == 12 {
+			n = 2
+		}
+	case "snils", "снилс":
+		n = 2
+	}
+	if len(digits) <= n {
+		return false
+	}
+	base, tail := digits[:len(digits)-n], digits[len(digits)-n:]
+	check, ok := controlDigits(kind, base)
+	return ok && check == tail
+}
+
+// controlDigits calculates the control digits for the number base of the given kind.
+func controlDigits(kind, base string) (string, bool) {
+	switch kind {
+	case "luhn":
+		if base == "" {
+			return "", false
+		}
+		return strconv.Itoa(luhnDigit(base)), true
+
+	case "inn", "инн":
+		switch len(base) {
+		case 9:
+			return strconv.Itoa(weightedMod11(base, []int{2, 4, 10, 3, 5, 9, 4, 6, 8})), true
+		case 10:
+			n11 := weightedMod11(base, []int{7, 2, 4, 10, 3, 5, 9, 4, 6, 8})
+			n12 := weightedMod11(base+strconv.Itoa(n11), []int{3, 7, 2, 4, 10, 3, 5, 9, 4, 6, 8})
+			return fmt.Sprintf("%d%d", n11, n12), true
+		}
+
+	case "ogrn", "огрн", "ogrnip", "огрнип":
+		switch len(base) {
+		case 12:
+			return strconv.Itoa(modDigits(base, 11) % 10), true
+		case 14:
+			return strconv.Itoa(modDigits(base, 13) % 10), true
+		}
+
+	case "snils", "снилс":
+		if len(base) != 9 {
+			return "", false
+		}
+		sum := 0
+		for i, r := range base {
+			sum += int(r-'0') * (9 - i)
+		}
+		if sum > 101 {
+			sum %= 101
+		}
+		if sum == 100 || sum == 101 {
+			sum = 0
+		}
+		return fmt.Sprintf("%02d", sum), true
+
+	case "ean", "ean13":
+		if len(base) != 12 {
+			return "", false
+		}
+		sum := 0
+		for i, r := range base {
+			w := 1
+			if i%2 == 1 {
+				w = 3
+			}
+			sum += int(r-'0') * w
+		}
+		return strconv.Itoa((10 - sum%10) % 10), true
+	}
+	return "", false
+}
+
+// luhnDigit calculates the Luhn check digit for a string of digits.
+func luhnDigit(digits string) int {
+	sum := 0
+	double := true
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return (10 - sum%10) % 10
+}
+
+// weightedMod11 - the weighted sum of digits modulo 11, then modulo 10 (INN algorithm).
+func weightedMod11(digits string, weights []int) int {
+	sum := 0
+	for i, w := range weights {
+		sum += int(digits[i]-'0') * w
+	}
+	return sum % 11 % 10
+}
+
+// modDigits - the remainder of dividing a long decimal number by m without overflow.
+func modDigits(digits string, m int) int {
+	rem := 0
+	for _, r := range digits {
+		rem = (rem*10 + int(r-'0')) % m
+	}
+	return rem
+}
+
+// onlyDigits leaves only the digits 0-9 in the string.
+func onlyDigits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// pickMark returns marks[0] for valid and marks[1] otherwise (by default "✓" / "✗").
+func pickMark(valid bool, marks []string) string {
+	ok, bad := "✓", "✗"
+	if len(marks) >= 1 {
+		ok = marks[0]
+	}
+	if len(marks) >= 2 {
+		bad = marks[1]
+	}
+	if valid {
+		return ok
+	}
+	return bad
+}
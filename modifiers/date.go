@@ -1,7 +1,8 @@
 package modifiers
 
 import (
-	"fmt"
+	"encoding/json"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -17,65 +18,208 @@ func DateFormat(val any, layout string) string {
 		return ""
 	}
 
-	var t time.Time
+	t, ok := toTime(val)
+	if !ok {
+		// если не смогли распознать — вернём исходное
+		return strings.TrimSpace(ValueText(val))
+	}
+
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Format(layout)
+}
+
+// -------- DateOrdinal --------
+
+// monthsGenitive - Names of months in the genitive case ("14 октября").
+var monthsGenitive = [...]string{
+	"января", "февраля", "марта", "апреля", "мая", "июня",
+	"июля", "августа", "сентября", "октября", "ноября", "декабря",
+}
+
+// ordinalEndings - Endings of the ordinal numeral of the neuter gender ("число") by case.
+var ordinalEndings = map[string]string{
+	"nom":  "е",
+	"gen":  "го",
+	"dat":  "му",
+	"acc":  "е",
+	"ins":  "м",
+	"prep": "м",
+}
+
+// dayWordForms - The word "число" by case.
+var dayWordForms = map[string]string{
+	"nom":  "число",
+	"gen":  "числа",
+	"dat":  "числу",
+	"acc":  "число",
+	"ins":  "числом",
+	"prep": "числе",
+}
+
+// DateOrdinal - Renders the day of the month with the Russian ordinal ending agreed in case.
+// The value is a date (time.Time, a string in one of the recognized formats, unix time)
+// or just a day number (1..31), in which case the month is not printed.
+//
+// Options (any order):
+//   - case — "им" (default), "род", "дат", "вин", "тв", "пред" (and the full names);
+//   - "числа" / "число" — append the word "число" in the desired case instead of the month;
+//   - "год" / "year" — append the year: "14-е октября 2025 г.".
+//
+// Examples:
+//
+//	{date|date_ordinal}                  → "14-е октября"
+//	{date|date_ordinal:`род`}            → "14-го октября"
+//	{date|date_ordinal:`род`:`год`}      → "14-го октября 2025 г."
+//	{day|date_ordinal:`род`:`числа`}     → "1-го числа"
+//	{day|date_ordinal:`пред`:`числа`}    → "1-м числе"
+func DateOrdinal(val any, opts ...string) string {
+	if val == nil {
+		return ""
+	}
+
+	grammarCase := "nom"
+	withWord, withYear := false, false
+	for _, o := range opts {
+		o = strings.ToLower(strings.TrimSpace(o))
+		if key, ok := lookupCase(o); ok {
+			grammarCase = key
+			continue
+		}
+		switch o {
+		case "числа", "число", "day":
+			withWord = true
+		case "год", "year", "г":
+			withYear = true
+		}
+	}
+
+	day, month, year := 0, 0, 0
+	if n, ok := dayNumber(val); ok {
+		day = n
+	} else {
+		t, ok := toTime(val)
+		if !ok {
+			return strings.TrimSpace(ValueText(val))
+		}
+		if t.IsZero() {
+			return ""
+		}
+		day, month, year = t.Day(), int(t.Month()), t.Year()
+	}
+
+	out := strconv.Itoa(day) + "-" + ordinalEndings[grammarCase]
+	switch {
+	case withWord || month == 0:
+		if withWord {
+			out += " " + dayWordForms[grammarCase]
+		}
+	default:
+		out += " " + monthsGenitive[month-1]
+		if withYear {
+			out += " " + strconv.Itoa(year) + NBSP + "г."
+		}
+	}
+	return out
+}
+
+// -------------------- helpers --------------------
 
+// dateLayouts - The order of attempts to parse string date formats.
+var dateLayouts = []string{
+	time.RFC3339,          // 2025-10-14T22:15:00Z
+	"2006-01-02",          // 2025-10-14
+	"02.01.2006",          // 14.10.2025
+	"2006/01/02",          // 2025/10/14
+	"02.01.2006 15:04",    // 14.10.2025 08:30
+	"2006-01-02 15:04:05", // 2025-10-14 22:15:00
+	time.ANSIC,            // Mon Jan _2 15:04:05 2006
+}
+
+// toTime - Gently converts the value into time.Time.
+// ok=false means the value could not be recognized as a date;
+// a zero time with ok=true means "empty" (nil pointer, empty string).
+func toTime(val any) (time.Time, bool) {
 	switch v := val.(type) {
 	case time.Time:
-		t = v
+		return v, true
 
 	case *time.Time:
 		if v != nil {
-			t = *v
+			return *v, true
 		}
+		return time.Time{}, true
 
 	case string:
 		s := strings.TrimSpace(v)
 		if s == "" {
-			return ""
-		}
-
-		// Порядок попыток разбора строковых форматов
-		tryFormats := []string{
-			time.RFC3339,          // 2025-10-14T22:15:00Z
-			"2006-01-02",          // 2025-10-14
-			"02.01.2006",          // 14.10.2025
-			"2006/01/02",          // 2025/10/14
-			"02.01.2006 15:04",    // 14.10.2025 08:30
-			"2006-01-02 15:04:05", // 2025-10-14 22:15:00
-			time.ANSIC,            // Mon Jan _2 15:04:05 2006
+			return time.Time{}, true
 		}
-		for _, f := range tryFormats {
+		for _, f := range dateLayouts {
 			if parsed, err := time.Parse(f, s); err == nil {
-				t = parsed
-				break
+				return parsed, true
 			}
 		}
-		if t.IsZero() {
-			// если не смогли распознать — вернём исходное
-			return s
-		}
+		return time.Time{}, false
 
 	case int64:
-		t = time.Unix(v, 0)
+		return time.Unix(v, 0), true
 
 	case float64:
-		t = time.Unix(int64(v), 0)
+		return time.Unix(int64(v), 0), true
+
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return time.Unix(n, 0), true
+		}
+		return toTime(v.String())
 
 	default:
-		s := strings.TrimSpace(fmt.Sprint(v))
+		if isNumberValue(v) {
+			f, _ := numberValue(v)
+			return time.Unix(int64(f), 0), true
+		}
+		s := strings.TrimSpace(ValueText(v))
 		if s == "" {
-			return ""
+			return time.Time{}, true
 		}
 		if parsed, err := time.Parse(time.RFC3339, s); err == nil {
-			t = parsed
-		} else {
-			return s
+			return parsed, true
 		}
+		return time.Time{}, false
 	}
+}
 
-	if t.IsZero() {
-		return ""
-	}
+// DateValue - the date of a data value as date_format reads it (time.Time, a date string, unix time);
+// false for an empty value or one that is not a date. Used by the calendar blocks of the document.
+func DateValue(val any) (time.Time, bool) {
+	t, ok := toTime(val)
+	return t, ok && !t.IsZero()
+}
 
-	return t.Format(layout)
+// dayNumber recognizes a bare day of the month (1..31) given as int or a short numeric string.
+func dayNumber(val any) (int, bool) {
+	switch v := val.(type) {
+	case int:
+		return v, v >= 1 && v <= 31
+	case float64:
+		// numbers from JSON come as float64; unix time in 1..31 seconds makes no sense
+		n := int(v)
+		return n, float64(n) == v && n >= 1 && n <= 31
+	case string:
+		s := strings.TrimSpace(v)
+		if len(s) > 2 {
+			return 0, false
+		}
+		n, err := strconv.Atoi(s)
+		return n, err == nil && n >= 1 && n <= 31
+	}
+	if isNumberValue(val) {
+		f, _ := numberValue(val)
+		n := int(f)
+		return n, float64(n) == f && n >= 1 && n <= 31
+	}
+	return 0, false
 }
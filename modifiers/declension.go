@@ -1,12 +1,42 @@
 package modifiers
 
 import (
-	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/normiridium/petrovich"
 )
 
+var (
+	// declensionRules - the petrovich rules of Declension: loaded once on the first use
+	// (the embedded JSON is large, a document may hold hundreds of names) or set by SetDeclensionRules.
+	declensionRules atomic.Pointer[petrovich.Rules]
+	declensionOnce  sync.Once
+)
+
+// SetDeclensionRules - use the given petrovich rules (e.g. loaded once by the service and shared with
+// its own code) for Declension instead of loading the embedded ones; nil returns to the embedded rules.
+func SetDeclensionRules(r *petrovich.Rules) {
+	if r == nil {
+		r, _ = petrovich.LoadRules()
+	}
+	declensionRules.Store(r)
+}
+
+// rules returns the petrovich rules, loading the embedded ones on the first call; nil if they cannot be read.
+func rules() *petrovich.Rules {
+	if r := declensionRules.Load(); r != nil {
+		return r
+	}
+	declensionOnce.Do(func() {
+		if r, err := petrovich.LoadRules(); err == nil {
+			declensionRules.CompareAndSwap(nil, r)
+		}
+	})
+	return declensionRules.Load()
+}
+
 // Declension — declenses the full name in the specified case and format, using petrovich-go.
 // If the line "Surname, First Name, Patronymic" comes, it makes an automatic declension.
 // If a map[string]string comes with ready-made forms, it selects the desired one.
@@ -18,7 +48,7 @@ import (
 //	{user_fio|declension:`винительный`:`фамилия`} = "Петрова"
 //	{user_fio|decl:`п`:`и.о. ф`} = "И.И. Сидорову"
 func Declension(v any, opts ...string) string {
-	src := strings.TrimSpace(fmt.Sprint(v))
+	src := strings.TrimSpace(ValueText(v))
 	if src == "" {
 		return ""
 	}
@@ -40,9 +70,9 @@ func Declension(v any, opts ...string) string {
 	}
 
 	// Otherwise, we use petrovich
-	p, _ := petrovich.LoadRules()
+	p := rules()
 	parts := strings.Fields(src)
-	if len(parts) == 0 {
+	if len(parts) == 0 || p == nil {
 		return src
 	}
 
@@ -175,21 +205,29 @@ func coalesce(vals ...string) string {
 }
 
 func normalizeCase(c string) string {
+	if key, ok := lookupCase(c); ok {
+		return key
+	}
+	return "gen" // Default is genitive
+}
+
+// lookupCase recognizes the name of the case and returns its short key (nom, gen, dat, acc, ins, prep).
+func lookupCase(c string) (string, bool) {
 	c = strings.ToLower(strings.TrimSpace(c))
 	switch c {
 	case "им", "именительный", "nom", "nominative":
-		return "nom"
+		return "nom", true
 	case "род", "родительный", "gen", "genitive", "р":
-		return "gen"
+		return "gen", true
 	case "дат", "дательный", "dat", "dative", "д":
-		return "dat"
+		return "dat", true
 	case "вин", "винительный", "acc", "accusative", "в":
-		return "acc"
+		return "acc", true
 	case "тв", "творительный", "ins", "instrumental", "т":
-		return "ins"
+		return "ins", true
 	case "пред", "предложный", "prep", "prepositional", "п":
-		return "prep"
+		return "prep", true
 	default:
-		return "gen" // Default is genitive
+		return "", false
 	}
 }
@@ -0,0 +1,598 @@
+package modifiers
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Styled runs.
+//
+// Formatting modifiers close the current run of the tag, output the value in their own run
+// with explicit <w:rPr> and open a new run for the text after the tag (the same technique as qrcode).
+// Modifiers can be chained: {title|smallcaps|spacing:2} — each next one adds its property
+// to the already created run instead of nesting runs. Both runs are marked with InheritRunMarker:
+// after the execution they take the font, size and language of the run of the tag.
+const (
+	styledRunOpen  = "</w:t></w:r><w:r>" + InheritRunMarker + "<w:rPr>"
+	styledRunText  = `</w:rPr><w:t xml:space="preserve">`
+	styledRunClose = RunReopen
+)
+
+// InheritRunMarker - stands right after <w:r> of a run that a modifier outputs instead of the run
+// of its tag: after the execution the run takes the <w:rPr> of the tag run, its own properties
+// on top of it (see MergeRunProps), and the marker is removed.
+const InheritRunMarker = "<!--docxgen:rpr-->"
+
+// RunReopen - ends the run of a modifier and reopens the run of the tag for the text after it,
+// with the properties of the tag run and its spaces kept.
+const RunReopen = "</w:t></w:r><w:r>" + InheritRunMarker + `<w:t xml:space="preserve">`
+
+// rPrOrder - the order of run properties required by the WordprocessingML schema.
+var rPrOrder = []string{
+	"w:rStyle", "w:rFonts", "w:b", "w:bCs", "w:i", "w:iCs", "w:caps", "w:smallCaps",
+	"w:strike", "w:dstrike", "w:outline", "w:shadow", "w:emboss", "w:imprint", "w:noProof",
+	"w:snapToGrid", "w:vanish", "w:webHidden", "w:color", "w:spacing", "w:w", "w:kern",
+	"w:position", "w:sz", "w:szCs", "w:highlight", "w:u", "w:effect", "w:bdr", "w:shd",
+	"w:fitText", "w:vertAlign", "w:rtl", "w:cs", "w:em", "w:lang", "w:eastAsianLayout",
+	"w:specVanish", "w:oMath",
+}
+
+// StyledRun wraps the value into a separate run with the given run properties (<w:b/>, <w:color w:val="FF0000"/>, ...).
+// If the value is already a styled run (the previous modifier in the pipeline), the properties are merged:
+// a property with the same element name is replaced.
+func StyledRun(value any, props ...string) RawXML {
+	text, existing, ok := parseStyledRun(ValueText(value))
+	if !ok {
+		s := ValueText(value)
+		if s == "" {
+			return ""
+		}
+		text = escapeOrRaw(s)
+	}
+
+	byName := make(map[string]string, len(existing)+len(props))
+	for _, p := range append(existing, props...) {
+		byName[rPrName(p)] = p
+	}
+
+	names := make([]string, 0, len(byName))
+	for n := range byName {
+		names = append(names, n)
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		return rPrIndex(names[i]) < rPrIndex(names[j])
+	})
+
+	var b strings.Builder
+	b.WriteString(styledRunOpen)
+	for _, n := range names {
+		b.WriteString(byName[n])
+	}
+	b.WriteString(styledRunText)
+	b.WriteString(text)
+	b.WriteString(styledRunClose)
+	return RawXML(b.String())
+}
+
+// IsStyledRun reports whether the value is a run produced by StyledRun.
+func IsStyledRun(s string) bool {
+	_, _, ok := parseStyledRun(s)
+	return ok
+}
+
+// parseStyledRun splits a styled run back into text and a list of properties.
+func parseStyledRun(s string) (text string, props []string, ok bool) {
+	if !strings.HasPrefix(s, styledRunOpen) || !strings.HasSuffix(s, styledRunClose) {
+		return "", nil, false
+	}
+	inner := strings.TrimSuffix(strings.TrimPrefix(s, styledRunOpen), styledRunClose)
+	rPr, text, found := strings.Cut(inner, styledRunText)
+	if !found {
+		return "", nil, false
+	}
+	for rPr != "" {
+		end := strings.Index(rPr, "/>")
+		if !strings.HasPrefix(rPr, "<") || end < 0 {
+			return "", nil, false
+		}
+		props = append(props, rPr[:end+2])
+		rPr = rPr[end+2:]
+	}
+	return text, props, true
+}
+
+// pPrOrder - the order of paragraph properties required by the WordprocessingML schema.
+var pPrOrder = []string{
+	"w:pStyle", "w:keepNext", "w:keepLines", "w:pageBreakBefore", "w:framePr", "w:widowControl",
+	"w:numPr", "w:suppressLineNumbers", "w:pBdr", "w:shd", "w:tabs", "w:suppressAutoHyphens",
+	"w:kinsoku", "w:wordWrap", "w:overflowPunct", "w:topLinePunct", "w:autoSpaceDE", "w:autoSpaceDN",
+	"w:bidi", "w:adjustRightInd", "w:snapToGrid", "w:spacing", "w:ind", "w:contextualSpacing",
+	"w:mirrorIndents", "w:suppressOverlap", "w:jc", "w:textDirection", "w:textAlignment",
+	"w:textboxTightWrap", "w:outlineLvl", "w:divId", "w:cnfStyle", "w:rPr", "w:sectPr", "w:pPrChange",
+}
+
+// MergeRunProps merges the content of two <w:rPr>: the properties of own replace those of base with
+// the same element name, the result is in the order of the schema. The tracked change of base
+// (w:rPrChange) is not taken.
+func MergeRunProps(base, own string) string {
+	return mergeProps(base, own, rPrOrder, "w:rPrChange")
+}
+
+// MergeParagraphProps is MergeRunProps for the content of two <w:pPr>; the section break
+// and the tracked change of base (w:sectPr, w:pPrChange) are not taken.
+func MergeParagraphProps(base, own string) string {
+	return mergeProps(base, own, pPrOrder, "w:sectPr", "w:pPrChange")
+}
+
+// mergeProps merges two lists of properties in the given order; skip are the names not taken from base.
+func mergeProps(base, own string, order []string, skip ...string) string {
+	byName := map[string]string{}
+	for _, p := range splitRunProps(base) {
+		if name := rPrName(p); !slices.Contains(skip, name) {
+			byName[name] = p
+		}
+	}
+	for _, p := range splitRunProps(own) {
+		byName[rPrName(p)] = p
+	}
+	names := make([]string, 0, len(byName))
+	for n := range byName {
+		names = append(names, n)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if a, b := propIndex(order, names[i]), propIndex(order, names[j]); a != b {
+			return a < b
+		}
+		return names[i] < names[j]
+	})
+	var b strings.Builder
+	for _, n := range names {
+		b.WriteString(byName[n])
+	}
+	return b.String()
+}
+
+// splitRunProps splits the content of <w:rPr> into its elements, <w:b/> and <w:x>…</w:x> alike.
+func splitRunProps(s string) []string {
+	var out []string
+	for s = strings.TrimSpace(s); strings.HasPrefix(s, "<"); s = strings.TrimSpace(s) {
+		end := strings.IndexByte(s, '>')
+		if end < 0 {
+			break
+		}
+		n := end + 1
+		if s[end-1] != '/' {
+			closing := "</" + rPrName(s) + ">"
+			c := strings.Index(s, closing)
+			if c < 0 {
+				break
+			}
+			n = c + len(closing)
+		}
+		out = append(out, s[:n])
+		s = s[n:]
+	}
+	return out
+}
+
+// rPrName - element name of the property: `<w:color w:val="FF0000"/>` → "w:color".
+func rPrName(p string) string {
+	p = strings.TrimPrefix(p, "<")
+	if i := strings.IndexAny(p, " />"); i >= 0 {
+		return p[:i]
+	}
+	return p
+}
+
+func rPrIndex(name string) int {
+	return propIndex(rPrOrder, name)
+}
+
+// propIndex - the place of the property in the schema order, unknown ones go last.
+func propIndex(order []string, name string) int {
+	if i := slices.Index(order, name); i >= 0 {
+		return i
+	}
+	return len(order)
+}
+
+// -------- Legal headings --------
+
+// SmallCaps - outputs the value in small capitals (w:smallCaps) regardless of the template style.
+//
+// Example:
+//
+//	{title|smallcaps} → "Договор поставки" in small caps
+func SmallCaps(v any) RawXML {
+	return StyledRun(v, "<w:smallCaps/>")
+}
+
+// Caps - outputs the value in capital letters by formatting (w:caps); the text itself is not changed.
+//
+// Example:
+//
+//	{title|caps} → "ДОГОВОР ПОСТАВКИ"
+func Caps(v any) RawXML {
+	return StyledRun(v, "<w:caps/>")
+}
+
+// LetterSpacing - sets the letter spacing in points (w:spacing); negative values condense the text.
+//
+// Examples:
+//
+//	{title|spacing:2}            → letter-spaced by 2 pt
+//	{title|smallcaps|spacing:1.5} → small caps, letter-spaced by 1.5 pt
+func LetterSpacing(v any, pt string) RawXML {
+	f, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSuffix(strings.TrimSpace(pt), "pt"), ",", "."), 64)
+	if err != nil {
+		return StyledRun(v)
+	}
+	// spacing is set in twentieths of a point
+	return StyledRun(v, fmt.Sprintf(`<w:spacing w:val="%d"/>`, int(f*20)))
+}
+
+// -------- Emphasis --------
+
+// Bold - outputs the value in bold regardless of the template style.
+//
+// Example:
+//
+//	{warning|bold|color:`FF0000`} → bold red text
+func Bold(v any) RawXML {
+	return StyledRun(v, "<w:b/>", "<w:bCs/>")
+}
+
+// Italic - outputs the value in italics.
+//
+// Example:
+//
+//	{term|italic}
+func Italic(v any) RawXML {
+	return StyledRun(v, "<w:i/>", "<w:iCs/>")
+}
+
+// underlineStyles - the line kinds of w:u accepted by underline.
+var underlineStyles = map[string]string{
+	"single": "single", "double": "double", "thick": "thick", "dotted": "dotted",
+	"dash": "dash", "wave": "wave", "words": "words", "none": "none",
+}
+
+// Underline - underlines the value: a single line by default or the given kind
+// (single, double, thick, dotted, dash, wave, words — only the words, not the spaces).
+//
+// Examples:
+//
+//	{fio|underline}
+//	{sum|underline:`double`}
+func Underline(v any, opts ...string) RawXML {
+	style := "single"
+	if len(opts) > 0 {
+		if s, ok := underlineStyles[strings.ToLower(strings.TrimSpace(opts[0]))]; ok {
+			style = s
+		}
+	}
+	return StyledRun(v, fmt.Sprintf(`<w:u w:val="%s"/>`, style))
+}
+
+// Strike - strikes the value through (a cancelled item, an old price).
+//
+// Example:
+//
+//	{old_price|money|strike}
+func Strike(v any) RawXML {
+	return StyledRun(v, "<w:strike/>")
+}
+
+// FontSize - sets the font size in points (halves are allowed: 10.5).
+// A size that is not a positive number leaves the value in a run without a size.
+//
+// Example:
+//
+//	{title|bold|size:14}
+func FontSize(v any, pt string) RawXML {
+	f, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSuffix(strings.TrimSpace(pt), "pt"), ",", "."), 64)
+	if err != nil || f <= 0 {
+		return StyledRun(v)
+	}
+	// the size is set in half-points
+	half := int(f*2 + 0.5)
+	return StyledRun(v, fmt.Sprintf(`<w:sz w:val="%d"/>`, half), fmt.Sprintf(`<w:szCs w:val="%d"/>`, half))
+}
+
+// Font - outputs the value in the given font family.
+//
+// Example:
+//
+//	{code|font:`Courier New`}
+func Font(v any, name string) RawXML {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return StyledRun(v)
+	}
+	return StyledRun(v, fmt.Sprintf(`<w:rFonts w:ascii="%[1]s" w:hAnsi="%[1]s" w:cs="%[1]s"/>`, escapeOrRaw(name)))
+}
+
+// -------- Color and highlight --------
+
+// colorNames - named colors accepted by color (and by highlight as a shading fill).
+var colorNames = map[string]string{
+	"black": "000000", "white": "FFFFFF", "red": "FF0000", "green": "008000", "blue": "0000FF",
+	"yellow": "FFFF00", "orange": "FFA500", "gray": "808080", "grey": "808080",
+	"черный": "000000", "чёрный": "000000", "белый": "FFFFFF", "красный": "FF0000",
+	"зеленый": "008000", "зелёный": "008000", "синий": "0000FF", "желтый": "FFFF00",
+	"жёлтый": "FFFF00", "оранжевый": "FFA500", "серый": "808080",
+}
+
+// highlightNames - the fixed palette of w:highlight (ST_HighlightColor).
+var highlightNames = map[string]string{
+	"yellow": "yellow", "green": "green", "cyan": "cyan", "magenta": "magenta", "blue": "blue",
+	"red": "red", "darkblue": "darkBlue", "darkcyan": "darkCyan", "darkgreen": "darkGreen",
+	"darkmagenta": "darkMagenta", "darkred": "darkRed", "darkyellow": "darkYellow",
+	"darkgray": "darkGray", "lightgray": "lightGray", "black": "black", "white": "white",
+	"желтый": "yellow", "жёлтый": "yellow", "зеленый": "green", "зелёный": "green",
+	"голубой": "cyan", "синий": "blue", "красный": "red", "серый": "lightGray",
+}
+
+// Color - outputs the value in the given font color: hex "RRGGBB" (with or without "#") or a color name.
+// An unknown color leaves the value in a run without a color.
+//
+// Examples:
+//
+//	{status|color:`FF0000`} → red text
+//	{status|color:`#2E7D32`}
+//	{status|color:`красный`}
+func Color(v any, color string) RawXML {
+	hex, ok := ParseColor(color)
+	if !ok {
+		return StyledRun(v)
+	}
+	return StyledRun(v, fmt.Sprintf(`<w:color w:val="%s"/>`, hex))
+}
+
+// Highlight - highlights the value with a marker. Word palette names (yellow, green, cyan, ...)
+// produce w:highlight; any hex color produces a shading fill (w:shd), since w:highlight has no arbitrary colors.
+//
+// Examples:
+//
+//	{value|highlight:`yellow`}
+//	{value|highlight:`FFE0B2`}
+func Highlight(v any, color string) RawXML {
+	key := strings.ToLower(strings.TrimSpace(color))
+	if name, ok := highlightNames[key]; ok {
+		return StyledRun(v, fmt.Sprintf(`<w:highlight w:val="%s"/>`, name))
+	}
+	if hex, ok := ParseColor(color); ok {
+		return StyledRun(v, fmt.Sprintf(`<w:shd w:val="clear" w:color="auto" w:fill="%s"/>`, hex))
+	}
+	return StyledRun(v)
+}
+
+// ParseColor normalizes "#ff0000", "f00", "FF0000" or a color name to "FF0000".
+// Used by the formatting modifiers and by the drawing modifiers of the document.
+func ParseColor(s string) (string, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if hex, ok := colorNames[s]; ok {
+		return hex, true
+	}
+	s = strings.TrimPrefix(s, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return "", false
+	}
+	if _, err := strconv.ParseUint(s, 16, 32); err != nil {
+		return "", false
+	}
+	return strings.ToUpper(s), true
+}
+
+// -------- Icons --------
+
+// IconDef - a built-in icon: a symbol character and its default color.
+type IconDef struct {
+	Char  string // symbol, e.g. "✔"
+	Color string // "RRGGBB", empty — the color of the surrounding text
+}
+
+// iconFont - a font with good coverage of symbols; Word and LibreOffice fall back to another one if it is missing.
+const iconFont = "Segoe UI Symbol"
+
+// Icons - The icon set of the icon modifier. The key is the name used in the template.
+// Applications can register their own icons before rendering.
+var Icons = map[string]IconDef{
+	"check":   {Char: "✔", Color: "2E7D32"},
+	"cross":   {Char: "✘", Color: "C62828"},
+	"warning": {Char: "⚠", Color: "EF6C00"},
+	"star":    {Char: "★", Color: "F9A825"},
+	"info":    {Char: "ℹ", Color: "1565C0"},
+	"dot":     {Char: "●"},
+}
+
+// iconAliases - synonyms of the icon names, including typical status values.
+var iconAliases = map[string]string{
+	"ok": "check", "yes": "check", "done": "check", "да": "check", "готово": "check", "true": "check",
+	"x": "cross", "no": "cross", "fail": "cross", "error": "cross", "нет": "cross", "false": "cross",
+	"warn": "warning", "!": "warning", "внимание": "warning",
+}
+
+// Icon - outputs a small icon as a symbol run (check, cross, warning, star, info, dot).
+// With a name in the first parameter the icon is shown for any non-empty value
+// (an empty value, "0" and "false" give nothing); without a parameter the value itself is the icon name,
+// which is convenient for status columns. The second parameter overrides the color ("RRGGBB" or a name).
+// An unknown icon name leaves the value as is.
+//
+// Examples:
+//
+//	{row.approved|icon:`check`}   → ✔ (green) if approved is set
+//	{row.status|icon}             → ✔ for "ok", ✘ for "fail", ⚠ for "warning"
+//	{row.rating|icon:`star`:`gray`}
+func Icon(v any, opts ...string) RawXML {
+	s := strings.TrimSpace(ValueText(v))
+	name := s
+	if len(opts) >= 1 && strings.TrimSpace(opts[0]) != "" {
+		switch strings.ToLower(s) {
+		case "", "0", "false", "<nil>":
+			return ""
+		}
+		name = opts[0]
+	}
+
+	def, ok := lookupIcon(name)
+	if !ok {
+		return RawXML(escapeOrRaw(s))
+	}
+
+	color := def.Color
+	if len(opts) >= 2 {
+		if hex, ok := ParseColor(opts[1]); ok {
+			color = hex
+		}
+	}
+
+	props := []string{fmt.Sprintf(`<w:rFonts w:ascii="%[1]s" w:hAnsi="%[1]s" w:cs="%[1]s"/>`, iconFont)}
+	if color != "" {
+		props = append(props, fmt.Sprintf(`<w:color w:val="%s"/>`, color))
+	}
+	return StyledRun(def.Char, props...)
+}
+
+// lookupIcon finds the icon by name or alias, case-insensitively.
+func lookupIcon(name string) (IconDef, bool) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := iconAliases[key]; ok {
+		key = alias
+	}
+	def, ok := Icons[key]
+	return def, ok
+}
+
+// escapeOrRaw escapes the text for insertion as RawXML.
+func escapeOrRaw(s string) string {
+	escaped, err := escapeForWord(s)
+	if err != nil {
+		return s
+	}
+	return escaped
+}
+
+// -------- Checkboxes and symbols --------
+
+// symbolFonts - fonts with a symbol encoding: their characters are written as <w:sym> with a code F0xx.
+var symbolFonts = map[string]bool{
+	"wingdings": true, "wingdings 2": true, "wingdings 3": true, "webdings": true, "symbol": true,
+}
+
+// checkboxGlyphs - the boxes of checkbox: checked with a cross, checked with a tick, empty.
+// Wingdings codes are those of Insert → Symbol in Word, the Unicode ones are drawn in iconFont.
+var checkboxGlyphs = map[string][3]rune{
+	"wingdings": {0xF0FD, 0xF0FE, 0xF0A8},
+	"segoe":     {'☒', '☑', '☐'},
+}
+
+// Checkbox - outputs a tick box of a form: ☒ for a filled value, ☐ for an empty one
+// (empty, "0", "false", "no", "нет", "off"). The parameters, in any order:
+//   - wingdings (default) — a <w:sym> of Wingdings, as Word inserts it; segoe — the Unicode box in Segoe UI Symbol;
+//   - check — a tick (☑) instead of a cross in the checked box;
+//   - any other text — the box is checked when the value equals it (radio-like groups of a form).
+//
+// Examples:
+//
+//	{agree|checkbox}              → ☒ / ☐
+//	{agree|checkbox:`check`}      → ☑ / ☐
+//	{sex|checkbox:`м`} муж. {sex|checkbox:`ж`} жен.
+func Checkbox(v any, opts ...string) RawXML {
+	style, glyph := "wingdings", 0
+	checked := isChecked(v)
+	for _, opt := range opts {
+		switch o := strings.TrimSpace(opt); strings.ToLower(o) {
+		case "wingdings", "segoe":
+			style = strings.ToLower(o)
+		case "check":
+			glyph = 1
+		case "":
+		default:
+			checked = strings.EqualFold(strings.TrimSpace(ValueText(v)), o)
+		}
+	}
+	if !checked {
+		glyph = 2
+	}
+
+	r := checkboxGlyphs[style][glyph]
+	if style == "wingdings" {
+		return symbolRun("Wingdings", r)
+	}
+	return symbolRun(iconFont, r)
+}
+
+// Symbol - outputs a character of the given font for a filled value (an empty value, "0" and "false" give nothing).
+// The code is hex ("F0FE", "0xFE", "U+2612") or the character itself; the font is optional.
+// Symbol fonts (Wingdings, Webdings, Symbol) get a <w:sym> run, codes below 0x100 are moved to F0xx as Word does.
+//
+// Examples:
+//
+//	{agree|symbol:`F0FE`:`Wingdings`} → ☑ of Wingdings
+//	{done|symbol:`U+2714`}            → ✔
+func Symbol(v any, code string, font ...string) RawXML {
+	if !isChecked(v) {
+		return ""
+	}
+	r, ok := parseSymbolCode(code)
+	if !ok {
+		return RawXML(escapeOrRaw(code))
+	}
+	name := iconFont
+	if len(font) > 0 && strings.TrimSpace(font[0]) != "" {
+		name = strings.TrimSpace(font[0])
+	}
+	if symbolFonts[strings.ToLower(name)] && r < 0x100 {
+		r += 0xF000
+	}
+	return symbolRun(name, r)
+}
+
+// symbolRun - the character in its own run: <w:sym> for a symbol font, text with rFonts for any other.
+// Both runs take the properties of the tag run, and so does the text after the character.
+func symbolRun(font string, r rune) RawXML {
+	if symbolFonts[strings.ToLower(font)] {
+		return RawXML(fmt.Sprintf(`</w:t></w:r><w:r>`+InheritRunMarker+`<w:sym w:font="%s" w:char="%04X"/></w:r>`,
+			escapeOrRaw(font), r) + strings.TrimPrefix(RunReopen, "</w:t></w:r>"))
+	}
+	return StyledRun(string(r), fmt.Sprintf(`<w:rFonts w:ascii="%[1]s" w:hAnsi="%[1]s" w:cs="%[1]s"/>`, escapeOrRaw(font)))
+}
+
+// parseSymbolCode reads "F0FE", "0xF0FE", "U+2612" or a single character.
+func parseSymbolCode(code string) (rune, bool) {
+	code = strings.TrimSpace(code)
+	if rs := []rune(code); len(rs) == 1 && !isHexDigit(rs[0]) {
+		return rs[0], true
+	}
+	hex := strings.TrimPrefix(strings.TrimPrefix(strings.ToUpper(code), "U+"), "0X")
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || n == 0 || n > 0x10FFFF {
+		return 0, false
+	}
+	return rune(n), true
+}
+
+func isHexDigit(r rune) bool {
+	return r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F'
+}
+
+// isChecked - whether the value fills a box: false, nil, zero numbers and the empty, "0", "false", "no", "нет", "off" texts do not.
+func isChecked(v any) bool {
+	if _, text := v.(string); !text {
+		if f, ok := numberValue(v); ok {
+			return f != 0
+		}
+	}
+	switch strings.ToLower(strings.TrimSpace(ValueText(v))) {
+	case "", "0", "false", "no", "нет", "off", "<nil>":
+		return false
+	}
+	return true
+}
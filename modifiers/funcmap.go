@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"docxgen/metrics"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
@@ -21,9 +22,23 @@ type Options struct {
 	Fonts *metrics.FontSet
 	// Data — template input data (needed for concat to be able to pick up other tags by name).
 	Data map[string]any
+	// Calendar is a production calendar for add_days/next_workday. If nil, only weekends are days off.
+	Calendar *WorkCalendar
 	// ExtraFuncs are custom modifiers with a number of fixed parameters.
 	// The behavior is completely similar to builtins.
 	ExtraFuncs map[string]ModifierMeta
+	// Limits is the default sandbox of ExtraFuncs (time and output size); builtins are not limited.
+	Limits Limits
+	// Locale is the number locale of money/percent/unit (see RegisterLocale); the data key _locale wins.
+	// Empty — DefaultLocale.
+	Locale string
+	// StrictTypes turns on Limits.StrictTypes for every modifier, builtins included.
+	StrictTypes bool
+	// OnLossy is told about every lossy coercion of an argument (see coerce), strict or not.
+	OnLossy func(modifier string, err error)
+	// OnUnknownOption is told about an option the date arithmetic (add_days, next_workday, add_months)
+	// does not know and ignores.
+	OnUnknownOption func(modifier, option string)
 }
 
 // For Word to display the tab correctly, you need to close the previous text element.
@@ -58,11 +73,35 @@ func escapeForWord(s string) (string, error) {
 	return wordReplacer.Replace(b.String()), nil
 }
 
+// wordUnescaper - reverses escapeForWord.
+var wordUnescaper = strings.NewReplacer(
+	NEWLINE, "\n",
+	TAB, "\t",
+	"&amp;", "&",
+	"&lt;", "<",
+	"&gt;", ">",
+	"&#34;", `"`,
+	"&#39;", "'",
+)
+
+// wordMarkup - removes the line breaks and tabs of escapeForWord.
+var wordMarkup = strings.NewReplacer(NEWLINE, "", TAB, "")
+
+// UnescapeForWord - the text of a string that escapeForWord prepared (the result of a string modifier),
+// so that the next modifier of a chain escapes it only once. Raw XML (runs, drawings) is returned as is.
+func UnescapeForWord(s string) string {
+	if strings.Contains(wordMarkup.Replace(s), "<") {
+		return s
+	}
+	return wordUnescaper.Replace(s)
+}
+
 // ---- Register of modifiers ----
 
 type ModifierMeta struct {
-	Func  any // target function (with a "beautiful" signature)
-	Count int // how many FIRST arguments are considered fixed; pipeline-value is always the last
+	Func   any     // target function (with a "beautiful" signature)
+	Count  int     // how many FIRST arguments are considered fixed; pipeline-value is always the last
+	Limits *Limits // sandbox of the call; nil — Options.Limits, a zero Limits — no limits
 }
 
 // Built-in modifiers.
@@ -87,6 +126,23 @@ var builtins = map[string]ModifierMeta{
 	"compact":  {Func: Compact, Count: 0},
 	"abbr":     {Func: Abbr, Count: 0},
 	"ru_phone": {Func: RuPhone, Count: 0},
+	"spell":    {Func: Spell, Count: 0},
+
+	// run formatting mods
+	"bold":      {Func: Bold, Count: 0},
+	"italic":    {Func: Italic, Count: 0},
+	"underline": {Func: Underline, Count: 0},
+	"strike":    {Func: Strike, Count: 0},
+	"size":      {Func: FontSize, Count: 1},
+	"font":      {Func: Font, Count: 1},
+	"smallcaps": {Func: SmallCaps, Count: 0},
+	"caps":      {Func: Caps, Count: 0},
+	"spacing":   {Func: LetterSpacing, Count: 1},
+	"color":     {Func: Color, Count: 1},
+	"highlight": {Func: Highlight, Count: 1},
+	"icon":      {Func: Icon, Count: 0},
+	"checkbox":  {Func: Checkbox, Count: 0},
+	"symbol":    {Func: Symbol, Count: 1},
 
 	// numeric mods
 	"numeral":   {Func: Numeral, Count: 0},
@@ -94,19 +150,30 @@ var builtins = map[string]ModifierMeta{
 	"sign":      {Func: Sign, Count: 0},
 	"pad_left":  {Func: PadLeft, Count: 2},
 	"pad_right": {Func: PadRight, Count: 2},
-	"money":     {Func: Money, Count: 1},
 	"roman":     {Func: Roman, Count: 0},
 
+	// checksum mods
+	"luhn":          {Func: Luhn, Count: 0},
+	"luhn_check":    {Func: LuhnCheck, Count: 0},
+	"control_digit": {Func: ControlDigit, Count: 1},
+	"control_check": {Func: ControlCheck, Count: 1},
+
 	// declension mods
 	"decl":       {Func: Declension, Count: 1},
 	"declension": {Func: Declension, Count: 1},
 
 	// date mods
-	"date_format": {Func: DateFormat, Count: 1},
+	"date_format":  {Func: DateFormat, Count: 1},
+	"date_ordinal": {Func: DateOrdinal, Count: 0},
+	"add_months":   {Func: AddMonths, Count: 1},
 
 	// qrcode mod
 	"qrcode":  {Func: QrCode, Count: 0},
 	"barcode": {Func: BarCode, Count: 0},
+
+	// charts
+	"sparkline":   {Func: Sparkline, Count: 0},
+	"progressbar": {Func: ProgressBar, Count: 0},
 }
 
 // NewFuncMap returns a function map for Go templates.
@@ -114,28 +181,61 @@ var builtins = map[string]ModifierMeta{
 func NewFuncMap(opts Options) template.FuncMap {
 	fm := template.FuncMap{}
 
+	// Builtins are not limited, only the type checks of opts reach them
+	wrap := func(name string, fn any, fixed int, l Limits) any {
+		l.StrictTypes = l.StrictTypes || opts.StrictTypes
+		if opts.OnLossy != nil {
+			l.onLossy = func(err error) { opts.OnLossy(name, err) }
+		}
+		return WrapModifier(fn, fixed, l)
+	}
+
 	// Registering builtins taking into account the number of fixed parameters
 	for name, meta := range builtins {
-		fm[name] = WrapModifier(meta.Func, meta.Count)
+		fm[name] = wrap(name, meta.Func, meta.Count, Limits{})
 	}
 
 	// concat is special: you need access to opts. Data; signature: func(base string, parts ... string) string
 	//	In the template: {base|concat:'x':'y':', '}
 	//	Here Count=0: all parameters are considered "formats", they come after value.
-	fm["concat"] = WrapModifier(ConcatFactory(opts.Data), 0)
+	fm["concat"] = wrap("concat", ConcatFactory(opts.Data), 0, Limits{})
+
+	// Date arithmetic depends on the production calendar.
+	//	In the template: {date|add_days:10:`рабочие`}, {date|next_workday}
+	//	An option they do not know goes to OnUnknownOption.
+	unknown := func(name string) func(string) {
+		if opts.OnUnknownOption == nil {
+			return nil
+		}
+		return func(option string) { opts.OnUnknownOption(name, option) }
+	}
+	fm["add_days"] = wrap("add_days", addDays(opts.Calendar, unknown("add_days")), 1, Limits{})
+	fm["next_workday"] = wrap("next_workday", nextWorkday(opts.Calendar, unknown("next_workday")), 0, Limits{})
+	fm["add_months"] = wrap("add_months", addMonths(unknown("add_months")), 1, Limits{})
+
+	// Numbers are written in the locale of the render.
+	//	In the template: {sum|money:`symbol`}, {rate|percent:1}, {area|unit:`м²`}
+	loc := resolveLocale(opts.Locale, opts.Data)
+	fm["money"] = wrap("money", MoneyFactory(loc), 1, Limits{})
+	fm["percent"] = wrap("percent", PercentFactory(loc), 0, Limits{})
+	fm["unit"] = wrap("unit", UnitFactory(loc), 1, Limits{})
 
 	// p_split include if there are fonts.
 	//	Closure signature: func(text string, firstUnders, otherUnders, nLine any, extra ... any) string
 	//	In the template: {text|p_split:20:65:2} or {text|p_split:20:65:+2:'bold':12}
 	//	Here, Count=3 (firstUnders, otherUnders, nLine) — extra will go as variadic after them.
 	if opts.Fonts != nil {
-		fm["p_split"] = WrapModifier(MakePSplit(opts.Fonts), 3)
+		fm["p_split"] = wrap("p_split", MakePSplit(opts.Fonts), 3, Limits{})
 	}
 
 	// Merge custom modifiers (full DSL participants)
 	if opts.ExtraFuncs != nil {
 		for k, meta := range opts.ExtraFuncs {
-			fm[k] = WrapModifier(meta.Func, meta.Count)
+			limits := opts.Limits
+			if meta.Limits != nil {
+				limits = *meta.Limits
+			}
+			fm[k] = wrap(k, meta.Func, meta.Count, limits)
 		}
 	}
 
@@ -194,79 +294,145 @@ func splitArgs(countFirst int, args []any) (values []any, formats []any, value a
 //
 // fn(value, fixed..., formats...)
 //
-// Supports variadics.
-func WrapModifier(fn any, fixed int) any {
-	return func(args ...any) any {
-		values, formats, value := splitArgs(fixed, args)
-
-		fnVal := reflect.ValueOf(fn)
-		fnType := fnVal.Type()
-		if fnType.Kind() != reflect.Func {
-			// не функция — безопасно вернуть pipeline как есть
-			return value
+// Supports variadics. With limits (see Limits) the call is sandboxed: the returned function
+// reports a timeout, a panic or a too large result as a template error (or truncates it),
+// and with Limits.StrictTypes — an argument that does not fit its parameter (see coerce).
+func WrapModifier(fn any, fixed int, limits ...Limits) any {
+	var l Limits
+	if len(limits) > 0 {
+		l = limits[0]
+	}
+	if !l.enabled() {
+		return func(args ...any) any {
+			value, call, _ := prepareCall(fn, fixed, args)
+			if call == nil {
+				return value
+			}
+			return normalizeReturn(call())
+		}
+	}
+	return func(args ...any) (any, error) {
+		value, call, lossy := prepareCall(fn, fixed, args)
+		if call == nil {
+			return value, nil
+		}
+		if lossy != nil {
+			if l.onLossy != nil {
+				l.onLossy(lossy)
+			}
+			if l.StrictTypes && l.Policy == LimitTruncate {
+				return value, nil
+			}
+			if l.StrictTypes {
+				return nil, lossy
+			}
+		}
+		out, err := l.call(call)
+		if errors.Is(err, ErrModifierTimeout) && l.Policy == LimitTruncate {
+			return value, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := returnedError(out); err != nil {
+			return nil, err
 		}
+		return normalizeReturn(out), nil
+	}
+}
 
-		// How many parameters does a function have?
-		numIn := fnType.NumIn()
-		isVariadic := fnType.IsVariadic()
+// errorType - the type of the error result of func(...) (string, error).
+var errorType = reflect.TypeFor[error]()
 
-		// How many non-variadic parameters are expected?
-		nonVarCount := numIn
-		if isVariadic {
-			nonVarCount = numIn - 1
-		}
+// returnedError - the non-nil error a func(...) (T, error) modifier returned, nil otherwise.
+func returnedError(out []reflect.Value) error {
+	if len(out) != 2 || !out[1].IsValid() || out[1].Type() != errorType || out[1].IsNil() {
+		return nil
+	}
+	return out[1].Interface().(error)
+}
 
-		// Assembling a list of final DSL-level arguments: value, fixed..., formats...
-		final := make([]any, 0, 1+len(values)+len(formats))
-		final = append(final, value)
-		final = append(final, values...)
-		final = append(final, formats...)
+// prepareCall converts the template arguments into the call of fn.
+// call is nil when fn cannot be called with them: then value is returned as is (strategy B).
+// lossy is the first argument that was changed on the way (see coerce).
+func prepareCall(fn any, fixed int, args []any) (value any, call func() []reflect.Value, lossy error) {
+	values, formats, value := splitArgs(fixed, args)
+
+	fnVal := reflect.ValueOf(fn)
+	fnType := fnVal.Type()
+	if fnType.Kind() != reflect.Func {
+		// не функция — безопасно вернуть pipeline как есть
+		return value, nil, nil
+	}
 
-		// If there are fewer finite arguments than the non-variadic function expects, softly return value (B).
-		if len(final) < nonVarCount {
-			return value
-		}
+	// How many parameters does a function have?
+	numIn := fnType.NumIn()
+	isVariadic := fnType.IsVariadic()
 
-		callArgs := make([]reflect.Value, 0, numIn)
+	// How many non-variadic parameters are expected?
+	nonVarCount := numIn
+	if isVariadic {
+		nonVarCount = numIn - 1
+	}
+
+	// Assembling a list of final DSL-level arguments: value, fixed..., formats...
+	final := make([]any, 0, 1+len(values)+len(formats))
+	final = append(final, value)
+	final = append(final, values...)
+	final = append(final, formats...)
+
+	// If there are fewer finite arguments than the non-variadic function expects, softly return value (B).
+	if len(final) < nonVarCount {
+		return value, nil, nil
+	}
 
-		// Type casting for non-variadic parameters
-		for i := 0; i < nonVarCount; i++ {
-			paramT := fnType.In(i)
-			argV := toReflectValue(final[i], paramT)
-			callArgs = append(callArgs, argV)
+	callArgs := make([]reflect.Value, 0, numIn)
+
+	// Type casting for non-variadic parameters
+	for i := 0; i < nonVarCount; i++ {
+		paramT := fnType.In(i)
+		argV, err := coerce(final[i], paramT)
+		if lossy == nil {
+			lossy = err
 		}
+		callArgs = append(callArgs, argV)
+	}
 
-		// Variadic part (if required)
-		if isVariadic {
-			// The expected type of the last parameter is slice
-			variadicSliceT := fnType.In(numIn - 1)
-			elemT := variadicSliceT.Elem()
-
-			// Assemble the formats (final remainder) into a slice of the desired type
-			variadicCount := len(final) - nonVarCount
-			sliceV := reflect.MakeSlice(variadicSliceT, variadicCount, variadicCount)
-			for i := 0; i < variadicCount; i++ {
-				elemV := toReflectValue(final[nonVarCount+i], elemT)
-				sliceV.Index(i).Set(elemV)
+	// Variadic part (if required)
+	if isVariadic {
+		// The expected type of the last parameter is slice
+		variadicSliceT := fnType.In(numIn - 1)
+		elemT := variadicSliceT.Elem()
+
+		// Assemble the formats (final remainder) into a slice of the desired type
+		variadicCount := len(final) - nonVarCount
+		sliceV := reflect.MakeSlice(variadicSliceT, variadicCount, variadicCount)
+		for i := 0; i < variadicCount; i++ {
+			elemV, err := coerce(final[nonVarCount+i], elemT)
+			if lossy == nil {
+				lossy = err
 			}
-			callArgs = append(callArgs, sliceV)
-
-			// Calling CallSlice for Variadics
-			out := fnVal.CallSlice(callArgs)
-			return normalizeReturn(out)
+			sliceV.Index(i).Set(elemV)
 		}
+		callArgs = append(callArgs, sliceV)
 
-		// If you don't have a variadic, ignore unnecessary arguments
-		out := fnVal.Call(callArgs)
-		return normalizeReturn(out)
+		// Calling CallSlice for Variadics
+		return value, func() []reflect.Value { return fnVal.CallSlice(callArgs) }, lossy
 	}
+
+	// If you don't have a variadic, ignore unnecessary arguments
+	return value, func() []reflect.Value { return fnVal.Call(callArgs) }, lossy
 }
 
 // normalizeReturn - Normalizes the return values of the modifier:
 // - one string → escaped under Word
 // - Any one → as is
+// - (T, nil) → as T
 // - multiple → []any
 func normalizeReturn(out []reflect.Value) any {
+	if len(out) == 2 && out[1].IsValid() && out[1].Type() == errorType && out[1].IsNil() {
+		out = out[:1]
+	}
 	// if the modifier returned RawXML, paste it as it is
 	if len(out) == 1 && out[0].IsValid() {
 		if raw, ok := out[0].Interface().(RawXML); ok {
@@ -289,12 +455,33 @@ func normalizeReturn(out []reflect.Value) any {
 	return res
 }
 
-// toReflectValue - Gently casts the value to the desired function parameter type.
-// Strategy: Assignable → Convertible → special cases (string, int) → zero value.
+// ErrLossyCoercion - an argument does not fit the parameter of the modifier and would be changed
+// on the way (see Limits.StrictTypes).
+var ErrLossyCoercion = errors.New("lossy type coercion")
+
+// toReflectValue - Gently casts the value to the desired function parameter type (see coerce).
 func toReflectValue(v any, target reflect.Type) reflect.Value {
+	out, _ := coerce(v, target)
+	return out
+}
+
+// coerce casts the argument of a template to the parameter type of a modifier.
+// The rules, in order:
+//   - nil → the zero value (a missing key is not an error);
+//   - a value of the parameter type (or assignable to it) is passed as is;
+//   - a number into a string parameter → its text: 65 → "65";
+//   - a number into a number parameter → converted; a lost fraction or an overflow is lossy: 1.5 → int 1;
+//   - other Go conversions (named types, string ↔ []byte) → converted;
+//   - anything into an interface parameter → as is;
+//   - anything into a string parameter → ValueText: a date as 02.01.2006, json.Number and big numbers exactly;
+//   - a string or a number (json.Number, *big.Int, ...) into an int/uint/float parameter → parsed; "abc" → 0 is lossy;
+//   - anything else → the zero value, lossy (strategy B — don't panic).
+//
+// The value is always usable; err wraps ErrLossyCoercion when the data was changed on the way.
+func coerce(v any, target reflect.Type) (reflect.Value, error) {
 	// nil → zero
 	if v == nil {
-		return reflect.Zero(target)
+		return reflect.Zero(target), nil
 	}
 
 	rv := reflect.ValueOf(v)
@@ -302,66 +489,82 @@ func toReflectValue(v any, target reflect.Type) reflect.Value {
 
 	// If already assignable, you're done
 	if rt.AssignableTo(target) {
-		return rv
+		return rv, nil
+	}
+
+	lossy := func(out reflect.Value) (reflect.Value, error) {
+		return out, fmt.Errorf("%w: %T %q to %s", ErrLossyCoercion, v, fmt.Sprint(v), target)
 	}
 
-	// If convertible, convert
+	// Go converts an integer into a string as a rune (65 → "A"), the number is meant
+	if target.Kind() == reflect.String && isNumeric(rt) {
+		return reflect.ValueOf(fmt.Sprint(v)).Convert(target), nil
+	}
+
+	// If convertible, convert; numbers must survive the round trip
 	if rt.ConvertibleTo(target) {
-		return rv.Convert(target)
+		out := rv.Convert(target)
+		if isNumeric(rt) && isNumeric(target) && out.Convert(rt).Interface() != rv.Interface() {
+			return lossy(out)
+		}
+		return out, nil
 	}
 
 	// Frequent convenient ghosts:
 	switch target.Kind() {
 	case reflect.Interface:
 		// Any value fits interface{}
-		return rv
+		return rv, nil
 
 	case reflect.String:
 		// Everything can be turned into a string
-		return reflect.ValueOf(fmt.Sprint(v))
+		return reflect.ValueOf(ValueText(v)).Convert(target), nil
 
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		// A special case: a line came — let's try atoi
-		if s, ok := v.(string); ok {
-			if n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
-				x := reflect.New(target).Elem()
-				x.SetInt(n)
-				return x
+		// A string (or a number of another kind) is parsed
+		if rt.Kind() == reflect.String || isNumberValue(v) {
+			x := reflect.New(target).Elem()
+			n, err := strconv.ParseInt(strings.TrimSpace(numberText(rv)), 10, 64)
+			if err != nil || x.OverflowInt(n) {
+				return lossy(x)
 			}
+			x.SetInt(n)
+			return x, nil
 		}
-		// If it's a number, but of a different sign type, let's try to convert it via fmt → atoi
-		if isNumeric(rt) {
-			s := fmt.Sprint(v)
-			if n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
-				x := reflect.New(target).Elem()
-				x.SetInt(n)
-				return x
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rt.Kind() == reflect.String || isNumberValue(v) {
+			x := reflect.New(target).Elem()
+			n, err := strconv.ParseUint(strings.TrimSpace(numberText(rv)), 10, 64)
+			if err != nil || x.OverflowUint(n) {
+				return lossy(x)
 			}
+			x.SetUint(n)
+			return x, nil
 		}
 
 	case reflect.Float32, reflect.Float64:
-		if s, ok := v.(string); ok {
-			if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
-				x := reflect.New(target).Elem()
-				x.SetFloat(f)
-				return x
-			}
-		}
-		if isNumeric(rt) {
-			s := fmt.Sprint(v)
-			if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
-				x := reflect.New(target).Elem()
-				x.SetFloat(f)
-				return x
+		if rt.Kind() == reflect.String || isNumberValue(v) {
+			x := reflect.New(target).Elem()
+			f, err := strconv.ParseFloat(strings.TrimSpace(numberText(rv)), 64)
+			if err != nil || x.OverflowFloat(f) {
+				return lossy(x)
 			}
+			x.SetFloat(f)
+			return x, nil
 		}
-	default:
-		// Unsupported type – just return zero value
-		return reflect.Zero(target)
 	}
 
 	// If you can't, give zero value of the desired type (strategy B – don't panic)
-	return reflect.Zero(target)
+	return lossy(reflect.Zero(target))
+}
+
+// numberText - the text of a number or a string argument for parsing (json.Number is a string kind).
+func numberText(rv reflect.Value) string {
+	if rv.Kind() == reflect.String {
+		return rv.String()
+	}
+	return ValueText(rv.Interface())
 }
 
 func isNumeric(t reflect.Type) bool {
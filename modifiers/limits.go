@@ -0,0 +1,127 @@
+package modifiers
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+	"unicode/utf8"
+)
+
+// LimitPolicy - what happens when a modifier breaks its limits.
+type LimitPolicy int
+
+const (
+	// LimitError - the template execution fails with ErrModifierTimeout or ErrModifierOutput.
+	LimitError LimitPolicy = iota
+	// LimitTruncate - the document is built anyway: text is cut to MaxOutput bytes,
+	// raw XML (which cannot be cut safely) is dropped, a timed out modifier outputs its input unchanged.
+	LimitTruncate
+)
+
+// Limits - the sandbox of a custom modifier.
+//   - Timeout — the limit of one call (0 — no limit). Go cannot stop a goroutine, so a modifier
+//     that loops forever keeps running in the background, but the document is not held by it;
+//   - MaxOutput — the limit of the result in bytes (0 — no limit);
+//   - ValidateXML — RawXML results are checked with ValidateRawXML before they reach the document;
+//   - StrictTypes — an argument that does not fit its parameter ("abc" into an int) fails the call
+//     with ErrLossyCoercion instead of becoming the zero value (see coerce);
+//   - Policy — error or truncate (an unsafe RawXML is dropped, a lossy call outputs its input unchanged).
+type Limits struct {
+	Timeout     time.Duration
+	MaxOutput   int
+	ValidateXML bool
+	StrictTypes bool
+	Policy      LimitPolicy
+
+	onLossy func(error) // see Options.OnLossy
+}
+
+var (
+	// ErrModifierTimeout - the modifier did not return within Limits.Timeout.
+	ErrModifierTimeout = errors.New("modifier timed out")
+	// ErrModifierOutput - the result of the modifier is larger than Limits.MaxOutput.
+	ErrModifierOutput = errors.New("modifier output too large")
+)
+
+func (l Limits) enabled() bool {
+	return l.Timeout > 0 || l.MaxOutput > 0 || l.ValidateXML || l.StrictTypes || l.onLossy != nil
+}
+
+// call runs the modifier within the limits; the returned values replace the result of the call.
+// The limits of the output apply to the string result of both func(...) string and
+// func(...) (string, error); the error, if any, is kept.
+func (l Limits) call(run func() []reflect.Value) ([]reflect.Value, error) {
+	out, err := l.run(run)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 || !out[0].IsValid() || out[0].Kind() != reflect.String {
+		return out, nil
+	}
+	// with the first result replaced, the others (the error) as they are
+	with := func(v reflect.Value) []reflect.Value {
+		return append([]reflect.Value{v}, out[1:]...)
+	}
+	if raw, ok := out[0].Interface().(RawXML); ok && l.ValidateXML {
+		if err := ValidateRawXML(raw); err != nil {
+			if l.Policy == LimitTruncate {
+				return with(reflect.ValueOf(RawXML(""))), nil
+			}
+			return nil, err
+		}
+	}
+	if l.MaxOutput <= 0 {
+		return out, nil
+	}
+
+	s := out[0].String()
+	if len(s) <= l.MaxOutput {
+		return out, nil
+	}
+	if l.Policy != LimitTruncate {
+		return nil, fmt.Errorf("%w: %d bytes, limit %d", ErrModifierOutput, len(s), l.MaxOutput)
+	}
+	if _, raw := out[0].Interface().(RawXML); raw {
+		return with(reflect.ValueOf(RawXML(""))), nil
+	}
+	cut := l.MaxOutput
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return with(reflect.ValueOf(s[:cut])), nil
+}
+
+// run calls the modifier, in its own goroutine when there is a timeout.
+// A panic of the modifier becomes an error instead of taking the process down.
+func (l Limits) run(run func() []reflect.Value) (out []reflect.Value, err error) {
+	guarded := func() (out []reflect.Value, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("modifier panic: %v", r)
+			}
+		}()
+		return run(), nil
+	}
+	if l.Timeout <= 0 {
+		return guarded()
+	}
+
+	type result struct {
+		out []reflect.Value
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		out, err := guarded()
+		done <- result{out, err}
+	}()
+	timer := time.NewTimer(l.Timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.out, r.err
+	case <-timer.C:
+		return nil, fmt.Errorf("%w after %s", ErrModifierTimeout, l.Timeout)
+	}
+}
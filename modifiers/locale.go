@@ -0,0 +1,160 @@
+package modifiers
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// NumberLocale - how numbers are written in a locale, for money, percent and unit.
+//   - Decimal — the decimal separator;
+//   - Group — the thousands separator, Grouping — digits per group (0 — no grouping);
+//   - Currency — the currency symbol of money:`symbol`, CurrencyBefore — it goes before the number;
+//   - CurrencySep — between the number and the symbol;
+//   - PercentSep — between the number and "%".
+type NumberLocale struct {
+	Decimal        string
+	Group          string
+	Grouping       int
+	Currency       string
+	CurrencyBefore bool
+	CurrencySep    string
+	PercentSep     string
+}
+
+// DefaultLocale - the locale of the numeric modifiers when none is set.
+const DefaultLocale = "ru"
+
+// LocaleKey - the data key that switches the locale of one render: {"_locale": "en"}.
+const LocaleKey = "_locale"
+
+var (
+	localesMu sync.RWMutex
+	locales   = map[string]NumberLocale{
+		"ru": {Decimal: ",", Group: " ", Grouping: 3, Currency: "₽", CurrencySep: NBSP, PercentSep: NBSP},
+		"en": {Decimal: ".", Group: ",", Grouping: 3, Currency: "$", CurrencyBefore: true},
+		"de": {Decimal: ",", Group: ".", Grouping: 3, Currency: "€", CurrencySep: NBSP, PercentSep: NBSP},
+		"fr": {Decimal: ",", Group: NNBSP, Grouping: 3, Currency: "€", CurrencySep: NBSP, PercentSep: NNBSP},
+	}
+)
+
+// RegisterLocale adds or replaces a locale of the numeric modifiers; the name is case-insensitive.
+//
+// Example:
+//
+//	modifiers.RegisterLocale("en-GB", modifiers.NumberLocale{Decimal: ".", Group: ",", Grouping: 3, Currency: "£", CurrencyBefore: true})
+func RegisterLocale(name string, loc NumberLocale) {
+	localesMu.Lock()
+	defer localesMu.Unlock()
+	locales[normalizeLocale(name)] = loc
+}
+
+// LookupLocale finds a locale by name: "en-US" and "en_US" fall back to "en".
+func LookupLocale(name string) (NumberLocale, bool) {
+	localesMu.RLock()
+	defer localesMu.RUnlock()
+	name = normalizeLocale(name)
+	if loc, ok := locales[name]; ok {
+		return loc, true
+	}
+	if lang, _, ok := strings.Cut(name, "-"); ok {
+		loc, ok := locales[lang]
+		return loc, ok
+	}
+	return NumberLocale{}, false
+}
+
+// Locales returns the names of the registered locales.
+func Locales() []string {
+	localesMu.RLock()
+	defer localesMu.RUnlock()
+	names := make([]string, 0, len(locales))
+	for name := range locales {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveLocale - the locale of a render: the data key _locale, then the option, then DefaultLocale.
+func resolveLocale(option string, data map[string]any) NumberLocale {
+	if name, ok := data[LocaleKey].(string); ok {
+		if loc, ok := LookupLocale(name); ok {
+			return loc
+		}
+	}
+	if loc, ok := LookupLocale(option); ok {
+		return loc
+	}
+	loc, _ := LookupLocale(DefaultLocale)
+	return loc
+}
+
+func normalizeLocale(name string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "_", "-")
+}
+
+// groupDigits separates the groups of an unsigned integer: "1234567" → "1 234 567".
+func (l NumberLocale) groupDigits(intStr string) string {
+	if l.Grouping <= 0 || l.Group == "" {
+		return intStr
+	}
+	n := len(intStr)
+	if n <= l.Grouping {
+		return intStr
+	}
+	// the first group takes what is left over the full ones, every next one is preceded by a separator
+	first := n % l.Grouping
+	if first == 0 {
+		first = l.Grouping
+	}
+	var b strings.Builder
+	b.Grow(n + (n-1)/l.Grouping*len(l.Group))
+	b.WriteString(intStr[:first])
+	for i := first; i < n; i += l.Grouping {
+		b.WriteString(l.Group)
+		b.WriteString(intStr[i : i+l.Grouping])
+	}
+	return b.String()
+}
+
+// formatNumber writes a number in the locale.
+// precision < 0 means "up to 2 digits, trailing zeros are removed".
+func (l NumberLocale) formatNumber(f float64, precision int) string {
+	digits := precision
+	if digits < 0 {
+		digits = 2
+	}
+	scale := math.Pow(10, float64(digits))
+	s := strconv.FormatFloat(math.Round(math.Abs(f)*scale)/scale, 'f', digits, 64)
+
+	intStr, frac, _ := strings.Cut(s, ".")
+	if precision < 0 {
+		frac = strings.TrimRight(frac, "0")
+	}
+
+	out := l.groupDigits(intStr)
+	if frac != "" {
+		out += l.Decimal + frac
+	}
+	if f < 0 && strings.Trim(intStr+frac, "0") != "" {
+		out = "-" + out
+	}
+	return out
+}
+
+// withCurrency adds the currency symbol on its side.
+func (l NumberLocale) withCurrency(num string) string {
+	if l.Currency == "" {
+		return num
+	}
+	if l.CurrencyBefore {
+		if neg, ok := strings.CutPrefix(num, "-"); ok {
+			return "-" + l.Currency + l.CurrencySep + neg
+		}
+		return l.Currency + l.CurrencySep + num
+	}
+	return num + l.CurrencySep + l.Currency
+}
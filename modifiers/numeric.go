@@ -2,9 +2,11 @@ package modifiers
 
 import (
 	"fmt"
-	"math"
+	"math/big"
+	"reflect"
 	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/normiridium/rusnum"
 )
@@ -132,16 +134,7 @@ func Plural(v any, forms ...string) string {
 		forms = []string{forms[0], forms[1], forms[1]}
 	}
 
-	var idx int
-	if n%10 == 1 && n%100 != 11 {
-		idx = 0 // один
-	} else if n%10 >= 2 && n%10 <= 4 && (n%100 < 10 || n%100 >= 20) {
-		idx = 1 // два–четыре
-	} else {
-		idx = 2 // остальные
-	}
-
-	return forms[idx]
+	return forms[pluralIndex(n)]
 }
 
 // -------- Sign --------
@@ -156,7 +149,7 @@ func Plural(v any, forms ...string) string {
 func Sign(v any) string {
 	f, ok := parseFloat(v)
 	if !ok {
-		return fmt.Sprint(v)
+		return ValueText(v)
 	}
 	if f > 0 {
 		return fmt.Sprintf("+%v", f)
@@ -172,7 +165,7 @@ func Sign(v any) string {
 //
 //	{num|pad_left:`5`:`0`} → "00042"
 func PadLeft(v any, length int, char string) string {
-	s := fmt.Sprint(v)
+	s := ValueText(v)
 	for len(s) < length {
 		s = char + s
 	}
@@ -187,7 +180,7 @@ func PadLeft(v any, length int, char string) string {
 //
 //	{num|pad_right:`3`:`0`} → "420"
 func PadRight(v any, length int, char string) string {
-	s := fmt.Sprint(v)
+	s := ValueText(v)
 	for len(s) < length {
 		s += char
 	}
@@ -197,57 +190,117 @@ func PadRight(v any, length int, char string) string {
 // -------- Money --------
 
 // Money - Formats a number as a monetary value separated by thousands of spaces.
-// Supports the "int" / "целое" flag to hide the fractional part,
-// as well as custom format via FMT template. Sprintf.
+// Supports the "int" / "целое" flag to hide the fractional part, the "symbol" / "валюта" flag
+// to add the currency symbol, as well as custom format via FMT template. Sprintf.
+// The separators and the symbol come from the locale (see NumberLocale), Money itself uses DefaultLocale.
+// The amount is counted in exact decimals: decimal strings, json.Number and big numbers keep every
+// digit, a float64 is taken as it prints (1.005 → "1,01"); kopecks are rounded half away from zero.
 //
 // Examples:
 //
 //	{sum|money}                    → "1 234,56"
+//	{"12345678901234567.89"|money} → "12 345 678 901 234 567,89"
 //	{sum|money:`int`}              → "1 234"
 //	{sum|money:`целое`}            → "1 234"
+//	{sum|money:`symbol`}           → "1 234,56 ₽" ("$1,234.56" with {"_locale": "en"})
 //	{sum|money:`%s рублей`}        → "1 234 рублей"
 //	{sum|money:`%s рублей %02d копеек`} → "1 234 рублей 56 копеек"
 //
 // Если шаблон содержит только один плейсхолдер (%s), дробная часть будет опущена.
 // Некорректный формат не вызывает паники — просто игнорируется.
 func Money(v any, opts ...string) string {
-	f, ok := parseFloat(v)
-	if !ok {
-		return fmt.Sprint(v)
-	}
+	loc, _ := LookupLocale(DefaultLocale)
+	return MoneyFactory(loc)(v, opts...)
+}
 
-	intPart := int64(f)
-	fracPart := int64(math.Round((f - float64(intPart)) * 100))
-	intStr := fmt.Sprintf("%d", intPart)
+// MoneyFactory returns money for the locale.
+func MoneyFactory(loc NumberLocale) func(v any, opts ...string) string {
+	return func(v any, opts ...string) (out string) {
+		amount, ok := decimalValue(v)
+		if !ok {
+			return ValueText(v)
+		}
 
-	var parts []string
-	for len(intStr) > 3 {
-		parts = append([]string{intStr[len(intStr)-3:]}, parts...)
-		intStr = intStr[:len(intStr)-3]
-	}
-	parts = append([]string{intStr}, parts...)
-	main := strings.Join(parts, " ")
-
-	if len(opts) > 0 {
-		format := strings.TrimSpace(opts[0])
-		lower := strings.ToLower(format)
-
-		switch lower {
-		case "int", "целое":
-			return main
-		default:
-			if strings.Contains(format, "%") {
-				defer func() { _ = recover() }() // безопасно подавляем форматные ошибки
-
-				if strings.Count(format, "%") == 1 {
-					return fmt.Sprintf(format, main)
+		// kopecks are counted in big.Int: no float64 rounding, no limit of 2^53
+		rubles, fracPart := splitCents(amount)
+		main := loc.groupDigits(rubles)
+		if amount.Sign() < 0 && (rubles != "0" || fracPart != 0) {
+			main = "-" + main
+		}
+
+		intOnly, symbol := false, false
+		for _, o := range opts {
+			format := strings.TrimSpace(o)
+			switch strings.ToLower(format) {
+			case "int", "целое":
+				intOnly = true
+			case "symbol", "валюта":
+				symbol = true
+			default:
+				if strings.Contains(format, "%") {
+					defer func() {
+						if recover() != nil { // безопасно подавляем форматные ошибки
+							out = fmt.Sprintf("%s%s%02d", main, loc.Decimal, fracPart)
+						}
+					}()
+
+					if strings.Count(format, "%") == 1 {
+						return fmt.Sprintf(format, main)
+					}
+					return fmt.Sprintf(format, main, fracPart)
 				}
-				return fmt.Sprintf(format, main, fracPart)
 			}
 		}
+
+		out = main
+		if !intOnly {
+			out = fmt.Sprintf("%s%s%02d", main, loc.Decimal, fracPart)
+		}
+		if symbol {
+			out = loc.withCurrency(out)
+		}
+		return out
 	}
+}
+
+// -------- Percent --------
 
-	return fmt.Sprintf("%s,%02d", main, fracPart)
+// Percent - Formats a number as a percentage in the locale. Options in any order:
+//   - a number — precision; by default up to 2 digits without trailing zeros;
+//   - "fraction" / "доля" — the value is a fraction (0.125 is 12.5%).
+//
+// Examples:
+//
+//	{rate|percent}              → "12,5 %"
+//	{rate|percent:1}            → "12,0 %"
+//	{share|percent:`доля`}      → "12,5 %" (share = 0.125)
+//	{rate|percent} with "en"    → "12.5%"
+func Percent(v any, opts ...string) string {
+	loc, _ := LookupLocale(DefaultLocale)
+	return PercentFactory(loc)(v, opts...)
+}
+
+// PercentFactory returns percent for the locale.
+func PercentFactory(loc NumberLocale) func(v any, opts ...string) string {
+	return func(v any, opts ...string) string {
+		f, ok := parseFloat(strings.TrimSuffix(strings.TrimSpace(ValueText(v)), "%"))
+		if !ok {
+			return ValueText(v)
+		}
+		precision := -1
+		for _, o := range opts {
+			o = strings.TrimSpace(o)
+			switch {
+			case strings.EqualFold(o, "fraction"), strings.EqualFold(o, "доля"):
+				f *= 100
+			default:
+				if n, err := strconv.Atoi(o); err == nil && n >= 0 {
+					precision = n
+				}
+			}
+		}
+		return loc.formatNumber(f, precision) + loc.PercentSep + "%"
+	}
 }
 
 // -------- Roman --------
@@ -277,36 +330,79 @@ func Roman(v any) string {
 
 // -------------------- helpers --------------------
 
+// parseInt - the value as an int: numbers of any kind (a fraction is dropped), json.Number,
+// big numbers and integer strings.
 func parseInt(v any) (int, bool) {
 	switch x := v.(type) {
 	case int:
 		return x, true
-	case int64:
-		return int(x), true
-	case float64:
-		return int(x), true
 	case string:
 		n, err := strconv.Atoi(strings.TrimSpace(x))
 		return n, err == nil
-	default:
-		s := fmt.Sprint(x)
-		n, err := strconv.Atoi(strings.TrimSpace(s))
-		return n, err == nil
 	}
+	if isNumberValue(v) {
+		if n, err := strconv.Atoi(ValueText(v)); err == nil {
+			return n, true
+		}
+		f, ok := numberValue(v)
+		return int(f), ok
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(ValueText(v)))
+	return n, err == nil
 }
 
+// splitCents rounds |amount| to kopecks (half away from zero) and returns the integer part
+// in digits and the kopecks.
+func splitCents(amount *big.Rat) (string, int64) {
+	cents := new(big.Rat).Abs(amount)
+	cents.Mul(cents, big.NewRat(100, 1))
+	// round(x) = floor(x + 1/2) for x >= 0
+	cents.Add(cents, big.NewRat(1, 2))
+	n := new(big.Int).Quo(cents.Num(), cents.Denom())
+
+	rubles, frac := new(big.Int).QuoRem(n, big.NewInt(100), new(big.Int))
+	return rubles.String(), frac.Int64()
+}
+
+// parseFloat - the value as a float64, see numberValue.
 func parseFloat(v any) (float64, bool) {
-	switch x := v.(type) {
-	case float64:
-		return x, true
-	case int:
-		return float64(x), true
-	case string:
-		f, err := strconv.ParseFloat(strings.TrimSpace(strings.ReplaceAll(x, ",", ".")), 64)
-		return f, err == nil
-	default:
-		s := fmt.Sprint(x)
-		f, err := strconv.ParseFloat(strings.TrimSpace(strings.ReplaceAll(s, ",", ".")), 64)
-		return f, err == nil
+	return numberValue(v)
+}
+
+// ParseNumbers converts an array of numbers to []float64: a slice of any numeric values
+// ([]any from JSON, []int, []float64, []string) or a string "1; 2; 3" / "1, 2, 3" / "1 2 3".
+// Elements that are not numbers are skipped.
+func ParseNumbers(v any) []float64 {
+	if v == nil {
+		return nil
+	}
+	if s, ok := v.(string); ok {
+		sep := func(r rune) bool { return r == ',' || unicode.IsSpace(r) }
+		if strings.Contains(s, ";") {
+			// "1,5; 2,5" — a decimal comma is possible
+			sep = func(r rune) bool { return r == ';' }
+		}
+		var out []float64
+		for _, part := range strings.FieldsFunc(s, sep) {
+			if f, ok := parseFloat(part); ok {
+				out = append(out, f)
+			}
+		}
+		return out
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		if f, ok := parseFloat(v); ok {
+			return []float64{f}
+		}
+		return nil
+	}
+	out := make([]float64, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		if f, ok := parseFloat(rv.Index(i).Interface()); ok {
+			out = append(out, f)
+		}
 	}
+	return out
 }
@@ -0,0 +1,39 @@
+package modifiers
+
+var ProgressBarFunc func(any, ...string) RawXML
+
+// ProgressBar - inserts a horizontal progress bar: a filled part proportional to the percentage
+// over a light track (completion indicators in status reports).
+//
+// Example of use:
+//
+// {task.pct|progressbar:`60mm*4mm`:`0=red`:`50=orange`:`90=green`}
+//
+// Format:
+//
+// {value|progressbar:[size]:[thresholds...]:[color]:[bg=color]}
+//
+// Parameters (all optional, the order is not important):
+//
+//   - size — "<W>mm*<H>mm" or "<W>mm" (height 3 mm), 40mm*3mm by default.
+//     A picture larger than 20 MB (or SizeLimits.MaxPartSize) drawn fails the render.
+//
+//   - thresholds — "<from>=<color>": the color of the bar from the given percentage.
+//     By default: 0=C62828 (red), 50=F9A825 (amber), 80=2E7D32 (green).
+//
+//   - color — a single color ("RRGGBB" or a name) for any percentage, disables the thresholds.
+//
+//   - bg=<color> — the color of the track, EEEEEE by default; "bg=none" — no track.
+//
+// The value is a percentage 0..100 ("60", 60, "60%", "60,5"); it is clamped to 0..100.
+// A value that is not a number gives an empty string.
+//
+// Returns:
+//
+// An inline XML fragment <w:drawing> with the PNG bar.
+func ProgressBar(value any, opts ...string) RawXML {
+	if ProgressBarFunc == nil {
+		return ""
+	}
+	return ProgressBarFunc(value, opts...)
+}
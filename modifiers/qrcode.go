@@ -7,10 +7,11 @@ var QrCodeFunc func(string, ...string) RawXML
 // Example of use:
 //
 // {project.code|qrcode:`right`:`top`:`8%`:`5/5`:`border`}
+// {stamp.code|qrcode:`behind`:`z=0`:`left`}
 //
 // Format:
 //
-// {value|qrcode:[mode]:[align]:[valign]:[crop%]:[margins]:[border]}
+// {value|qrcode:[mode]:[align]:[valign]:[crop%]:[margins]:[border]:[wrap]:[z=N]}
 //
 // Parameters (all optional, the order is not important):
 //
@@ -36,6 +37,11 @@ var QrCodeFunc func(string, ...string) RawXML
 //
 // - border — a flag that adds a thin black border (≈ 0.5 pt) around the QR code.
 //
+//   - wrap — text wrapping of the anchor: "square" (default), "tight", "through", "topbottom",
+//     "none" / "front" (over the text), "behind" (under the text — stamps and seals under a signature).
+//
+//   - z=<N> — z-order of the anchor among other drawings (2 by default).
+//
 // Returns:
 //
 // Inserted XML fragment <w:drawing> with the generated QR image.
@@ -0,0 +1,83 @@
+package modifiers
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrUnsafeRawXML - RawXML of a modifier would break the document.
+var ErrUnsafeRawXML = errors.New("unsafe raw xml")
+
+// DefaultDisallowedElements - elements a modifier must never output into a run:
+// they belong to the document structure, and a copy of them in the middle of a paragraph
+// makes Word reject the whole file.
+var DefaultDisallowedElements = []string{
+	"w:document", "w:body", "w:sectPr", "w:hdr", "w:ftr", "w:altChunk", "w:subDoc",
+}
+
+// rawXMLContext - the markup a modifier result is spliced into: it is output inside <w:t>
+// of a run, so a well-formed result closes and reopens them ("</w:t></w:r><w:r>...<w:t>").
+const (
+	rawXMLContextOpen  = "<w:p><w:r><w:t>"
+	rawXMLContextClose = "</w:t></w:r></w:p>"
+)
+
+// wordPrefixes - the usual prefixes of the WordprocessingML namespaces, for fragments that declare them.
+var wordPrefixes = map[string]string{
+	"http://schemas.openxmlformats.org/wordprocessingml/2006/main":        "w",
+	"http://schemas.openxmlformats.org/officeDocument/2006/relationships": "r",
+}
+
+// ValidateRawXML checks RawXML returned by a modifier before it is spliced into the run:
+// the tags must be balanced within the run (closing </w:t></w:r> only together with reopening them),
+// there must be no DTD or processing instructions, and no disallowed elements
+// (DefaultDisallowedElements if none are given). The error wraps ErrUnsafeRawXML.
+//
+// Example:
+//
+//	ValidateRawXML(`</w:t></w:r><w:r><w:br/><w:t>`)  // nil
+//	ValidateRawXML(`</w:t></w:r><w:r><w:t>`+"<w:b>") // error: <w:b> is not closed
+func ValidateRawXML(x RawXML, disallowed ...string) error {
+	if len(disallowed) == 0 {
+		disallowed = DefaultDisallowedElements
+	}
+	deny := make(map[string]bool, len(disallowed))
+	for _, name := range disallowed {
+		deny[name] = true
+	}
+
+	dec := xml.NewDecoder(strings.NewReader(rawXMLContextOpen + string(x) + rawXMLContextClose))
+	dec.Strict = true
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrUnsafeRawXML, err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if name := qualifiedName(t.Name); deny[name] {
+				return fmt.Errorf("%w: <%s> is not allowed", ErrUnsafeRawXML, name)
+			}
+		case xml.Directive, xml.ProcInst:
+			return fmt.Errorf("%w: directives and processing instructions are not allowed", ErrUnsafeRawXML)
+		}
+	}
+	return nil
+}
+
+// qualifiedName - "w:body" for both an undeclared prefix and the declared WordprocessingML namespace.
+func qualifiedName(n xml.Name) string {
+	if p, ok := wordPrefixes[n.Space]; ok {
+		return p + ":" + n.Local
+	}
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
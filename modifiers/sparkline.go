@@ -0,0 +1,38 @@
+package modifiers
+
+var SparklineFunc func(any, ...string) RawXML
+
+// Sparkline - inserts a tiny inline chart of an array of numbers (KPI tables, dynamics in a cell).
+//
+// Example of use:
+//
+// {row.history|sparkline:`30mm*8mm`:`bar`:`1565C0`}
+//
+// Format:
+//
+// {value|sparkline:[size]:[kind]:[color]}
+//
+// Parameters (all optional, the order is not important):
+//
+//   - size — "<W>mm*<H>mm" or "<W>mm" (height is 1/4 of the width), 30mm*8mm by default.
+//     A picture larger than 20 MB (or SizeLimits.MaxPartSize) drawn fails the render.
+//
+//   - kind — "line" (default), "area" (line with a filled area) or "bar" (columns).
+//     Negative values in "bar" go down from the zero line.
+//
+//   - color — "RRGGBB" or a color name (see color), 1565C0 by default.
+//
+//   - "dot" / "nodot" — mark the last point of the line (on by default for line and area).
+//
+// The value is an array of numbers ([]any from JSON, []float64, ...) or a string "1; 2; 3".
+// An empty array gives an empty string.
+//
+// Returns:
+//
+// An inline XML fragment <w:drawing> with the PNG chart.
+func Sparkline(value any, opts ...string) RawXML {
+	if SparklineFunc == nil {
+		return ""
+	}
+	return SparklineFunc(value, opts...)
+}
@@ -0,0 +1,98 @@
+package modifiers
+
+import (
+	"strings"
+	"unicode"
+)
+
+// spellDigits - digit names for the spell modifier.
+var spellDigits = map[rune]string{
+	'0': "ноль", '1': "один", '2': "два", '3': "три", '4': "четыре",
+	'5': "пять", '6': "шесть", '7': "семь", '8': "восемь", '9': "девять",
+}
+
+// spellSymbols - names of punctuation and special characters found in codes.
+var spellSymbols = map[rune]string{
+	'-': "дефис", '–': "тире", '—': "тире", '/': "косая черта", '\\': "обратная косая черта",
+	'.': "точка", ',': "запятая", ':': "двоеточие", '№': "номер", '#': "решётка",
+	'+': "плюс", '_': "нижнее подчёркивание", '(': "открывающая скобка", ')': "закрывающая скобка",
+	'*': "звёздочка", '@': "собака",
+}
+
+// SpellAlphabets - Letter names by alphabet. The key is the name used in the template ({code|spell:`ru`}).
+// Applications can register their own alphabets (for example, a phonetic one) before rendering.
+// Letters that are not in the chosen alphabet are spelled as the capital letter itself.
+var SpellAlphabets = map[string]map[rune]string{
+	"ru": {
+		'а': "а", 'б': "бэ", 'в': "вэ", 'г': "гэ", 'д': "дэ", 'е': "е", 'ё': "ё", 'ж': "жэ",
+		'з': "зэ", 'и': "и", 'й': "и краткое", 'к': "ка", 'л': "эль", 'м': "эм", 'н': "эн",
+		'о': "о", 'п': "пэ", 'р': "эр", 'с': "эс", 'т': "тэ", 'у': "у", 'ф': "эф", 'х': "ха",
+		'ц': "цэ", 'ч': "чэ", 'ш': "ша", 'щ': "ща", 'ъ': "твёрдый знак", 'ы': "ы",
+		'ь': "мягкий знак", 'э': "э", 'ю': "ю", 'я': "я",
+	},
+	"latin": {
+		'a': "эй", 'b': "би", 'c': "си", 'd': "ди", 'e': "и", 'f': "эф", 'g': "джи", 'h': "эйч",
+		'i': "ай", 'j': "джей", 'k': "кей", 'l': "эл", 'm': "эм", 'n': "эн", 'o': "оу", 'p': "пи",
+		'q': "кью", 'r': "ар", 's': "эс", 't': "ти", 'u': "ю", 'v': "ви", 'w': "дабл-ю", 'x': "экс",
+		'y': "уай", 'z': "зед",
+	},
+}
+
+// Spell - Spells out an alphanumeric code character by character, as required in notarial documents.
+// Digits are named by words, punctuation by its name, letters stay capital letters
+// or are named by the alphabet given in the first parameter ("ru", "latin", or a registered one;
+// "ru+latin" combines alphabets). The second parameter is the separator (a space by default).
+// Spaces in the code are skipped.
+//
+// Examples:
+//
+//	{code|spell}                  → "А дефис один два три" (code "а-123")
+//	{code|spell:`ru`}             → "а дефис один два три"
+//	{code|spell:`ru+latin`:`, `}  → "би, эм, дабл-ю, косая черта, семь" (code "BMW/7", Latin letters)
+//	{code|spell:`ru+latin`:`, `}  → "бэ, эм, дабл-ю, косая черта, семь" (code "БМW/7", Cyrillic Б and М)
+func Spell(s string, opts ...string) string {
+	var alphabets []map[rune]string
+	sep := " "
+	if len(opts) >= 1 {
+		for _, name := range strings.Split(opts[0], "+") {
+			if a, ok := SpellAlphabets[strings.ToLower(strings.TrimSpace(name))]; ok {
+				alphabets = append(alphabets, a)
+			}
+		}
+	}
+	if len(opts) >= 2 && opts[1] != "" {
+		sep = opts[1]
+	}
+
+	words := make([]string, 0, len(s))
+	for _, r := range strings.TrimSpace(s) {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		if w, ok := spellDigits[r]; ok {
+			words = append(words, w)
+			continue
+		}
+		if w, ok := spellSymbols[r]; ok {
+			words = append(words, w)
+			continue
+		}
+		if unicode.IsLetter(r) {
+			words = append(words, spellLetter(r, alphabets))
+			continue
+		}
+		words = append(words, string(r))
+	}
+	return strings.Join(words, sep)
+}
+
+// spellLetter names the letter by the first alphabet that knows it.
+func spellLetter(r rune, alphabets []map[rune]string) string {
+	low := unicode.ToLower(r)
+	for _, a := range alphabets {
+		if w, ok := a[low]; ok {
+			return w
+		}
+	}
+	return string(unicode.ToUpper(r))
+}
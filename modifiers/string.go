@@ -1,7 +1,6 @@
 package modifiers
 
 import (
-	"fmt"
 	"strings"
 )
 
@@ -177,7 +176,7 @@ func ConcatFactory(data map[string]any) func(base string, parts ...string) strin
 				if str, ok := v.(string); ok {
 					val = str
 				} else {
-					val = fmt.Sprint(v)
+					val = ValueText(v)
 				}
 			}
 
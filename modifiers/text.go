@@ -34,6 +34,9 @@ func Compact(s string) string {
 	return strings.ReplaceAll(s, " ", NNBSP)
 }
 
+// reAbbr - one or two short words or abbreviations before a space, Cyrillic and Latin.
+var reAbbr = regexp.MustCompile(`(?i)((?:(?:^|\s)[a-zа-яё.-]{1,5}\.?){1,2})\s+`)
+
 // Abbr - makes abbreviations, initials, and short words inseparable from the subsequent word.
 // This prevents the breakage of "g.", "st.", "LLC", "I.", etc. at the ends of the lines.
 //
@@ -43,13 +46,22 @@ func Compact(s string) string {
 //	"И. И. Иванов" → "И. И. Иванов"
 //	"ООО Центр" → "ООО Центр"
 func Abbr(s string) string {
-	// поддержка и кириллицы, и латиницы
-	re := regexp.MustCompile(`(?i)((?:(?:^|\s)[a-zа-яё.-]{1,5}\.?){1,2})\s+`)
-	return re.ReplaceAllStringFunc(s, func(m string) string {
+	return reAbbr.ReplaceAllStringFunc(s, func(m string) string {
 		return strings.ReplaceAll(m, " ", NBSP)
 	})
 }
 
+// phonePatterns - the regional (the first two) and the mobile numbers of RuPhone.
+var phonePatterns = []*regexp.Regexp{
+	// --- regional ---
+	regexp.MustCompile(`[+]?([78])[-\s]?\(?([1-7]\d{3})\)?[-\s]?(\d{3})[-\s]?(\d{3})`),
+	regexp.MustCompile(`[+]?([78])[-\s]?([1-7]\d{3})[-\s]?(\d{3})[-\s]?(\d{3})`),
+
+	// --- mobile ---
+	regexp.MustCompile(`[+]?([78])[-\s]?\(?(\d{3})\)?[-\s]?(\d{3})[-\s]?(\d{2})[-\s]?(\d{2})`),
+	regexp.MustCompile(`[+]?([78])[-\s]?(\d{3})[-\s]?(\d{3})[-\s]?(\d{2})[-\s]?(\d{2})`),
+}
+
 // RuPhone formats Russian phone numbers according to templates.
 // If the number is not recognized, returns the original string.
 //
@@ -78,16 +90,6 @@ func RuPhone(s string, formats ...string) string {
 		regional = formats[1]
 	}
 
-	patterns := []*regexp.Regexp{
-		// --- regional ---
-		regexp.MustCompile(`[+]?([78])[-\s]?\(?([1-7]\d{3})\)?[-\s]?(\d{3})[-\s]?(\d{3})`),
-		regexp.MustCompile(`[+]?([78])[-\s]?([1-7]\d{3})[-\s]?(\d{3})[-\s]?(\d{3})`),
-
-		// --- mobile ---
-		regexp.MustCompile(`[+]?([78])[-\s]?\(?(\d{3})\)?[-\s]?(\d{3})[-\s]?(\d{2})[-\s]?(\d{2})`),
-		regexp.MustCompile(`[+]?([78])[-\s]?(\d{3})[-\s]?(\d{3})[-\s]?(\d{2})[-\s]?(\d{2})`),
-	}
-
 	replacements := []string{
 		regional,
 		regional,
@@ -96,7 +98,7 @@ func RuPhone(s string, formats ...string) string {
 	}
 
 	var buf strings.Builder
-	for i, p := range patterns {
+	for i, p := range phonePatterns {
 		repl := replacements[i]
 
 		matches := p.FindAllStringIndex(s, -1)
@@ -0,0 +1,166 @@
+package modifiers
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// unitDef describes a unit of measurement: its dimension, factor to the base unit
+// of the dimension and three plural word forms ("один", "два", "пять").
+type unitDef struct {
+	dim    string
+	factor float64
+	forms  [3]string
+}
+
+// units - known units by abbreviation. Units of the same dimension can be converted into each other.
+var units = map[string]unitDef{
+	// mass (base — kg)
+	"г":  {"mass", 0.001, [3]string{"грамм", "грамма", "граммов"}},
+	"кг": {"mass", 1, [3]string{"килограмм", "килограмма", "килограммов"}},
+	"ц":  {"mass", 100, [3]string{"центнер", "центнера", "центнеров"}},
+	"т":  {"mass", 1000, [3]string{"тонна", "тонны", "тонн"}},
+
+	// length (base — m)
+	"мм": {"length", 0.001, [3]string{"миллиметр", "миллиметра", "миллиметров"}},
+	"см": {"length", 0.01, [3]string{"сантиметр", "сантиметра", "сантиметров"}},
+	"м":  {"length", 1, [3]string{"метр", "метра", "метров"}},
+	"км": {"length", 1000, [3]string{"километр", "километра", "километров"}},
+
+	// area (base — m²)
+	"м²":  {"area", 1, [3]string{"квадратный метр", "квадратных метра", "квадратных метров"}},
+	"а":   {"area", 100, [3]string{"ар", "ара", "аров"}},
+	"сот": {"area", 100, [3]string{"сотка", "сотки", "соток"}},
+	"га":  {"area", 10000, [3]string{"гектар", "гектара", "гектаров"}},
+	"км²": {"area", 1000000, [3]string{"квадратный километр", "квадратных километра", "квадратных километров"}},
+
+	// volume (base — l)
+	"мл": {"volume", 0.001, [3]string{"миллилитр", "миллилитра", "миллилитров"}},
+	"л":  {"volume", 1, [3]string{"литр", "литра", "литров"}},
+	"м³": {"volume", 1000, [3]string{"кубический метр", "кубических метра", "кубических метров"}},
+
+	// pieces
+	"шт": {"count", 1, [3]string{"штука", "штуки", "штук"}},
+}
+
+// unitAliases - alternative spellings of unit abbreviations.
+var unitAliases = map[string]string{
+	"м2": "м²", "кв.м": "м²", "кв. м": "м²",
+	"км2": "км²", "м3": "м³", "куб.м": "м³",
+	"шт.": "шт", "сотка": "сот", "соток": "сот",
+}
+
+// -------- Unit --------
+
+// Unit - Formats a number with a unit of measurement separated by a narrow non-breaking space.
+// The first parameter is the unit in which the value is given. Further options in any order:
+//   - a number — precision (digits after the comma); by default up to 2 digits without trailing zeros;
+//   - another unit of the same dimension — convert the value into it (kg↔t, m²↔ha, etc.);
+//   - "words" / "словами" — the full pluralized name of the unit instead of the abbreviation.
+//
+// Unknown units are simply appended as a suffix without conversion.
+//
+// Examples:
+//
+//	{area|unit:`м²`:2}             → "1 250,50 м²"
+//	{area|unit:`м²`:`га`:2}        → "0,13 га"
+//	{weight|unit:`кг`:`т`}         → "1,5 т"
+//	{weight|unit:`кг`:`словами`}   → "15 килограммов"
+//	{weight|unit:`кг`:`т`:`words`} → "1,5 тонны"
+func Unit(v any, unit string, opts ...string) string {
+	loc, _ := LookupLocale(DefaultLocale)
+	return UnitFactory(loc)(v, unit, opts...)
+}
+
+// UnitFactory returns unit for the locale: the number is written with its separators.
+func UnitFactory(loc NumberLocale) func(v any, unit string, opts ...string) string {
+	return func(v any, unit string, opts ...string) string {
+		return formatUnit(loc, v, unit, opts)
+	}
+}
+
+func formatUnit(loc NumberLocale, v any, unit string, opts []string) string {
+	f, ok := parseFloat(v)
+	if !ok {
+		return strings.TrimSpace(ValueText(v) + " " + unit)
+	}
+
+	from := normalizeUnit(unit)
+	to := from
+	precision := -1
+	words := false
+
+	for _, o := range opts {
+		o = strings.TrimSpace(o)
+		switch {
+		case o == "":
+			continue
+		case strings.EqualFold(o, "words"), strings.EqualFold(o, "словами"), strings.EqualFold(o, "слова"):
+			words = true
+		default:
+			if n, err := strconv.Atoi(o); err == nil && n >= 0 {
+				precision = n
+				continue
+			}
+			to = normalizeUnit(o)
+		}
+	}
+
+	// conversion is possible only within one dimension
+	src, okFrom := units[from]
+	dst, okTo := units[to]
+	if okFrom && okTo && src.dim == dst.dim {
+		f = f * src.factor / dst.factor
+	} else {
+		to = from
+		dst, okTo = src, okFrom
+	}
+
+	num := loc.formatNumber(f, precision)
+
+	label := to
+	if !okTo {
+		label = strings.TrimSpace(unit)
+	}
+	if words && okTo {
+		label = unitWord(f, strings.Contains(num, loc.Decimal), dst.forms)
+	}
+
+	if label == "" {
+		return num
+	}
+	return num + NNBSP + label
+}
+
+// normalizeUnit reduces the unit abbreviation to the canonical form of the units table.
+func normalizeUnit(u string) string {
+	u = strings.ToLower(strings.TrimSpace(u))
+	if a, ok := unitAliases[u]; ok {
+		return a
+	}
+	return u
+}
+
+// unitWord selects the plural form of the unit name; fractional values take the genitive singular.
+func unitWord(f float64, fractional bool, forms [3]string) string {
+	if fractional {
+		return forms[1]
+	}
+	return forms[pluralIndex(int(math.Abs(f)))]
+}
+
+// pluralIndex returns the index of the Russian plural form for n: 0 — "один", 1 — "два", 2 — "пять".
+func pluralIndex(n int) int {
+	if n < 0 {
+		n = -n
+	}
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return 0
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 10 || n%100 >= 20):
+		return 1
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,195 @@
+package modifiers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValueText - the text of a data value as a tag writes it:
+//   - time.Time — "02.01.2006", with "15:04" when the time of day is set; the zero time — "";
+//   - json.Number (json.Decoder.UseNumber), *big.Int, *big.Float, *big.Rat — the exact decimal number;
+//   - anything else — fmt.Sprint.
+func ValueText(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case time.Time:
+		return dateText(x)
+	case *time.Time:
+		if x == nil {
+			return ""
+		}
+		return dateText(*x)
+	case json.Number:
+		return x.String()
+	case *big.Int:
+		if x == nil {
+			return ""
+		}
+		return x.String()
+	case *big.Float:
+		if x == nil {
+			return ""
+		}
+		return x.Text('f', -1)
+	case *big.Rat:
+		if x == nil {
+			return ""
+		}
+		if x.IsInt() {
+			return x.Num().String()
+		}
+		f, _ := x.Float64()
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+func dateText(t time.Time) string {
+	switch {
+	case t.IsZero():
+		return ""
+	case t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0:
+		return t.Format("02.01.2006")
+	}
+	return t.Format("02.01.2006 15:04")
+}
+
+// numberValue - the value of any number: Go numeric types, json.Number, big numbers
+// and numeric strings ("1 234,5" is not a number here, only "1234.5" / "1234,5").
+func numberValue(v any) (float64, bool) {
+	switch x := v.(type) {
+	case nil:
+		return 0, false
+	case json.Number:
+		f, err := x.Float64()
+		return f, err == nil
+	case *big.Int:
+		if x == nil {
+			return 0, false
+		}
+		f, _ := new(big.Float).SetInt(x).Float64()
+		return f, true
+	case *big.Float:
+		if x == nil {
+			return 0, false
+		}
+		f, _ := x.Float64()
+		return f, true
+	case *big.Rat:
+		if x == nil {
+			return 0, false
+		}
+		f, _ := x.Float64()
+		return f, true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	s := strings.TrimSpace(strings.ReplaceAll(ValueText(v), ",", "."))
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil
+}
+
+// decimalValue - the exact value of a number for money arithmetic: integers, json.Number, big numbers
+// and decimal strings ("12345678901234567.89", "0,1") are taken digit for digit; a float64 is taken
+// as its shortest decimal form (0.1 is 1/10, not 0.1000000000000000055...). Only [-]digits[.digits]
+// with an exponent up to ±maxDecimalExp ("1e3", "2.5E-2") is taken: big.Rat would also read "1e100000"
+// into a number of a hundred thousand digits and "1/3".
+func decimalValue(v any) (*big.Rat, bool) {
+	var text string
+	switch x := v.(type) {
+	case nil:
+		return nil, false
+	case *big.Rat:
+		if x == nil {
+			return nil, false
+		}
+		return new(big.Rat).Set(x), true
+	case *big.Float:
+		if x == nil || x.IsInf() {
+			return nil, false
+		}
+		r, _ := x.Rat(nil)
+		return r, true
+	case float32:
+		text = strconv.FormatFloat(float64(x), 'f', -1, 32)
+	case float64:
+		text = strconv.FormatFloat(x, 'f', -1, 64)
+	case string:
+		text = strings.ReplaceAll(strings.TrimSpace(x), ",", ".")
+	default:
+		text = strings.ReplaceAll(strings.TrimSpace(ValueText(v)), ",", ".")
+	}
+	if !isDecimal(text) {
+		return nil, false
+	}
+	r, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return nil, false
+	}
+	return r, true
+}
+
+// maxDecimalExp - the largest exponent of a decimal string, far beyond any amount.
+const maxDecimalExp = 30
+
+// isDecimal - whether s is [+-]digits[.digits] (".5" and "5." too) with an optional exponent
+// e[+-]digits of at most maxDecimalExp: no fraction or hex.
+func isDecimal(s string) bool {
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		exp := strings.TrimLeft(s[i+1:], "+-")
+		if exp == "" || len(s[i+1:])-len(exp) > 1 || !isDigits(exp) {
+			return false
+		}
+		if n, err := strconv.Atoi(exp); err != nil || n > maxDecimalExp {
+			return false
+		}
+		s = s[:i]
+	}
+	intPart, frac, _ := strings.Cut(s, ".")
+	if intPart == "" && frac == "" {
+		return false
+	}
+	return isDigits(intPart) && isDigits(frac)
+}
+
+// isDigits - whether s has only the digits 0-9 (an empty s too).
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Decimal - the exact value of a number of the data (see decimalValue); used by the pivot tables of the document.
+func Decimal(v any) (*big.Rat, bool) {
+	return decimalValue(v)
+}
+
+// isNumberValue - whether v is a number of any kind (not a numeric string).
+func isNumberValue(v any) bool {
+	switch v.(type) {
+	case json.Number, *big.Int, *big.Float, *big.Rat:
+		return true
+	}
+	return v != nil && isNumeric(reflect.TypeOf(v))
+}
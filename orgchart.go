@@ -0,0 +1,247 @@
+package docxgen
+
+import (
+	"docxgen/modifiers"
+	"fmt"
+	"strings"
+)
+
+// ============================================================================
+// [orgchart/staff] — a hierarchy of nested data: an indented list or boxes by level
+// ============================================================================
+
+// orgChartOpenPrefix - the start of the hierarchy marker.
+const orgChartOpenPrefix = "[orgchart/"
+
+// orgChartMaxDepth - the deepest level shown (data with a cycle stops there).
+const orgChartMaxDepth = 16
+
+// orgChartIndent - the indent of a level of the list, twips (6.35 mm).
+const orgChartIndent = 360
+
+// orgChartBoxFill - the fill of the boxes.
+const orgChartBoxFill = "DEEAF6"
+
+// orgChartSpec - the options of the [orgchart/name ...] marker.
+//   - name — the data key of the root (a map) or of the roots (a list);
+//   - textField, noteField — the name of a node and the line under it ("" — none);
+//   - childrenField — the list of the subordinates of a node;
+//   - boxes — a table of boxes by level instead of an indented list.
+type orgChartSpec struct {
+	name                 string
+	textField, noteField string
+	childrenField        string
+	boxes                bool
+}
+
+// orgNode - a node of the hierarchy.
+type orgNode struct {
+	text, note string
+	children   []*orgNode
+}
+
+// parseOrgChartSpec reads "staff text:name note:position children:reports mode:boxes".
+func parseOrgChartSpec(spec string) (orgChartSpec, error) {
+	o := orgChartSpec{textField: "name", childrenField: "children"}
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return o, fmt.Errorf("want [orgchart/name ...], got %q", spec)
+	}
+	o.name = fields[0]
+	for _, f := range fields[1:] {
+		key, val, _ := strings.Cut(f, ":")
+		switch strings.ToLower(key) {
+		case "text":
+			o.textField = val
+		case "note":
+			o.noteField = val
+		case "children":
+			o.childrenField = val
+		case "mode":
+			switch strings.ToLower(val) {
+			case "boxes":
+				o.boxes = true
+			case "list":
+				o.boxes = false
+			default:
+				return o, fmt.Errorf("mode:%s is not list or boxes", val)
+			}
+		}
+	}
+	return o, nil
+}
+
+// ResolveOrgCharts replaces the paragraphs with [orgchart/staff] by the hierarchy of data[staff]:
+// a node is a map with name and children (the list of the subordinates); text:, note: and children:
+// take other fields, note: adds a line such as the position. The default mode:list is an indented list,
+// the subordinates under their head; mode:boxes is a table with a row per level, where every node is
+// a box over the columns of its subordinates.
+//
+// A broken marker or data that is not a map or a list of maps is removed with the bad_orgchart warning.
+func (d *Docx) ResolveOrgCharts(body string, data map[string]any) string {
+	return replaceMarkerParagraphs(body, orgChartOpenPrefix, func(spec string) string {
+		return d.renderOrgChart(spec, data)
+	})
+}
+
+// renderOrgChart builds the hierarchy of one marker; "" — nothing to show (a warning is issued).
+func (d *Docx) renderOrgChart(specText string, data map[string]any) string {
+	spec, err := parseOrgChartSpec(specText)
+	if err != nil {
+		d.warn(WarnBadOrgChart, specText, "%v", err)
+		return ""
+	}
+	raw := blockValue(data, spec.name)
+	roots := spec.nodes(raw, 0)
+	if len(roots) == 0 {
+		d.warn(WarnBadOrgChart, spec.name, "%T is not a map or a list of maps", raw)
+		return ""
+	}
+	if spec.boxes {
+		return orgChartBoxes(roots, d.CurrentPageLayout().UsableWidth().Twips())
+	}
+	var b strings.Builder
+	for _, n := range roots {
+		writeOrgList(&b, n, 0)
+	}
+	return b.String()
+}
+
+// nodes reads a map (one node) or a list of maps into the nodes of the level.
+func (o orgChartSpec) nodes(v any, depth int) []*orgNode {
+	if v == nil || depth >= orgChartMaxDepth {
+		return nil
+	}
+	if m := orgMap(v); m != nil && blockValue(m, o.textField) != nil {
+		return []*orgNode{o.node(m, depth)}
+	}
+	items, ok := normalizeItems(v)
+	if !ok {
+		return nil
+	}
+	var out []*orgNode
+	for _, it := range items {
+		if m := orgMap(it); m != nil {
+			out = append(out, o.node(m, depth))
+		}
+	}
+	return out
+}
+
+// orgMap - the node as a map; unlike the items of the tables, a map with lists (the subordinates) is a node too.
+func orgMap(v any) map[string]any {
+	if m, ok := v.(map[string]any); ok {
+		return m
+	}
+	return normalizeItem(v).mapVal
+}
+
+func (o orgChartSpec) node(m map[string]any, depth int) *orgNode {
+	n := &orgNode{text: modifiers.ValueText(blockValue(m, o.textField))}
+	if o.noteField != "" {
+		n.note = modifiers.ValueText(blockValue(m, o.noteField))
+	}
+	n.children = o.nodes(blockValue(m, o.childrenField), depth+1)
+	return n
+}
+
+// writeOrgList writes the node and its subordinates as paragraphs indented by level:
+// "Иванов И. И., директор", "— Петров П. П., заместитель".
+func writeOrgList(b *strings.Builder, n *orgNode, level int) {
+	fmt.Fprintf(b, `<w:p><w:pPr><w:ind w:left="%d"/></w:pPr>`, level*orgChartIndent)
+	if level > 0 {
+		b.WriteString(`<w:r><w:t xml:space="preserve">— </w:t></w:r>`)
+	}
+	b.WriteString(`<w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">` + xmlEscape(n.text) + `</w:t></w:r>`)
+	if n.note != "" {
+		b.WriteString(`<w:r><w:t xml:space="preserve">, ` + xmlEscape(n.note) + `</w:t></w:r>`)
+	}
+	b.WriteString(`</w:p>`)
+	for _, c := range n.children {
+		writeOrgList(b, c, level+1)
+	}
+}
+
+// orgLeaves - the width of the node in columns: the number of its lowest subordinates.
+func orgLeaves(n *orgNode) int {
+	if len(n.children) == 0 {
+		return 1
+	}
+	w := 0
+	for _, c := range n.children {
+		w += orgLeaves(c)
+	}
+	return w
+}
+
+// orgDepth - the number of levels under and including the node.
+func orgDepth(n *orgNode) int {
+	depth := 0
+	for _, c := range n.children {
+		depth = max(depth, orgDepth(c))
+	}
+	return depth + 1
+}
+
+// orgChartBoxes - a table with a row per level: a node is a shaded box over the columns of its
+// subordinates, the separated cells (w:tblCellSpacing) look like the boxes of a chart.
+func orgChartBoxes(roots []*orgNode, usable int) string {
+	cols, depth := 0, 0
+	for _, n := range roots {
+		cols += orgLeaves(n)
+		depth = max(depth, orgDepth(n))
+	}
+	width := usable / cols
+
+	var b strings.Builder
+	b.WriteString(`<w:tbl><w:tblPr><w:tblW w:w="5000" w:type="pct"/><w:tblCellSpacing w:w="113" w:type="dxa"/>` +
+		`<w:tblLayout w:type="fixed"/></w:tblPr><w:tblGrid>`)
+	for range cols {
+		fmt.Fprintf(&b, `<w:gridCol w:w="%d"/>`, width)
+	}
+	b.WriteString(`</w:tblGrid>`)
+
+	// a level is the nodes of that depth; a node without subordinates leaves an empty cell below it
+	level := make([]*orgNode, len(roots))
+	copy(level, roots)
+	for range depth {
+		b.WriteString(`<w:tr>`)
+		var next []*orgNode
+		for _, n := range level {
+			span := 1
+			if n != nil {
+				span = orgLeaves(n)
+			}
+			fmt.Fprintf(&b, `<w:tc><w:tcPr><w:tcW w:w="%d" w:type="dxa"/>`, width*span)
+			if span > 1 {
+				fmt.Fprintf(&b, `<w:gridSpan w:val="%d"/>`, span)
+			}
+			if n == nil {
+				b.WriteString(`</w:tcPr><w:p/></w:tc>`)
+				next = append(next, nil)
+				continue
+			}
+			b.WriteString(`<w:tcBorders>`)
+			for _, side := range []string{"top", "left", "bottom", "right"} {
+				fmt.Fprintf(&b, `<w:%s w:val="single" w:sz="8" w:space="0" w:color="5B9BD5"/>`, side)
+			}
+			b.WriteString(`</w:tcBorders><w:shd w:val="clear" w:color="auto" w:fill="` + orgChartBoxFill + `"/>` +
+				`<w:vAlign w:val="center"/></w:tcPr>`)
+			b.WriteString(`<w:p><w:pPr><w:jc w:val="center"/></w:pPr><w:r><w:rPr><w:b/></w:rPr>` +
+				`<w:t xml:space="preserve">` + xmlEscape(n.text) + `</w:t></w:r></w:p>`)
+			if n.note != "" {
+				b.WriteString(`<w:p><w:pPr><w:jc w:val="center"/></w:pPr><w:r><w:rPr><w:sz w:val="18"/></w:rPr>` +
+					`<w:t xml:space="preserve">` + xmlEscape(n.note) + `</w:t></w:r></w:p>`)
+			}
+			b.WriteString(`</w:tc>`)
+			if len(n.children) == 0 {
+				next = append(next, nil)
+			}
+			next = append(next, n.children...)
+		}
+		b.WriteString(`</w:tr>`)
+		level = next
+	}
+	b.WriteString(`</w:tbl>`)
+	return b.String()
+}
@@ -0,0 +1,277 @@
+package docxgen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"docxgen/geometry"
+)
+
+// ============================================================================
+// Page setup: paper, orientation and margins of the sections, [section landscape] … [/section]
+// ============================================================================
+
+// Orientation - the orientation of the pages of a section.
+type Orientation int
+
+const (
+	OrientationUnchanged Orientation = iota // the orientation stays as it is
+	Portrait                                // the height is the longer side
+	Landscape                               // the width is the longer side
+)
+
+// SectionOptions - the changes of the page setup of a section, the zero fields stay as they are.
+//   - Paper — "A3", "A4", "A5", "A6", "B5", "Letter" or "Legal", in the current orientation;
+//   - Width, Height — a paper size of their own instead of Paper;
+//   - Orientation — the longer side of the page, the sides are swapped to fit; the margins stay;
+//   - Margins — the page margins (nil — unchanged).
+type SectionOptions struct {
+	Paper         string
+	Width, Height geometry.Length
+	Orientation   Orientation
+	Margins       *geometry.Sides
+}
+
+// paperSizes - the paper sizes by name (lower case), portrait.
+var paperSizes = map[string][2]geometry.Length{
+	"a3":     {297 * geometry.MM, 420 * geometry.MM},
+	"a4":     {geometry.A4Width, geometry.A4Height},
+	"a5":     {148 * geometry.MM, 210 * geometry.MM},
+	"a6":     {105 * geometry.MM, 148 * geometry.MM},
+	"b5":     {176 * geometry.MM, 250 * geometry.MM},
+	"letter": {geometry.FromMM(215.9), geometry.FromMM(279.4)},
+	"legal":  {geometry.FromMM(215.9), geometry.FromMM(355.6)},
+}
+
+const (
+	// sectionOpenPrefix, sectionCloseMarker - the markers of a section of its own page setup.
+	sectionOpenPrefix  = "[section"
+	sectionCloseMarker = "[/section]"
+)
+
+var (
+	rePageSize    = regexp.MustCompile(`<w:pgSz\b[^>]*/>`)
+	rePageMargins = regexp.MustCompile(`<w:pgMar\b[^>]*/>`)
+	reSectionType = regexp.MustCompile(`<w:type\b[^>]*/>`)
+	// reBeforePageSize - the elements of sectPr that go before pgSz in the schema.
+	reBeforePageSize = regexp.MustCompile(`(?s)^<w:sectPr\b[^>]*>(?:<w:(?:headerReference|footerReference|footnotePr|endnotePr|type)\b(?:[^>]*/>|.*?</w:(?:footnotePr|endnotePr)>))*`)
+)
+
+// ParseSectionOptions parses the options of the [section ...] marker, separated by spaces: a paper
+// name ("A5"), "landscape" or "portrait", "margins=20/10/20/30" (see geometry.ParseSides).
+func ParseSectionOptions(spec string) (SectionOptions, error) {
+	var opts SectionOptions
+	for _, word := range strings.Fields(spec) {
+		name, value, isOption := strings.Cut(word, "=")
+		switch lower := strings.ToLower(name); {
+		case isOption && lower == "margins":
+			sides, err := geometry.ParseSides(value)
+			if err != nil {
+				return opts, err
+			}
+			opts.Margins = &sides
+		case isOption:
+			return opts, fmt.Errorf("section: unknown option %q", name)
+		case lower == "landscape":
+			opts.Orientation = Landscape
+		case lower == "portrait":
+			opts.Orientation = Portrait
+		default:
+			if _, ok := paperSizes[lower]; !ok {
+				return opts, fmt.Errorf("section: unknown paper %q", word)
+			}
+			opts.Paper = word
+		}
+	}
+	return opts, nil
+}
+
+// SetSection changes the page setup of every section of the document, a document without sectPr
+// gets one:
+//
+//	doc.SetSection(docxgen.SectionOptions{Paper: "A5", Orientation: docxgen.Landscape})
+//
+// A part of the document gets a section of its own with the [section ...] markers, see ResolveSections.
+func (d *Docx) SetSection(opts SectionOptions) error {
+	if opts.Paper != "" {
+		if _, ok := paperSizes[strings.ToLower(opts.Paper)]; !ok {
+			return fmt.Errorf("section: unknown paper %q", opts.Paper)
+		}
+	}
+	body, ok := d.files["word/document.xml"]
+	if !ok {
+		return fmt.Errorf("section: no word/document.xml")
+	}
+	s := string(body)
+	if !reSectPr.MatchString(s) {
+		end := strings.LastIndex(s, "</w:body>")
+		if end < 0 {
+			return fmt.Errorf("section: no <w:body> in document.xml")
+		}
+		s = s[:end] + "<w:sectPr/>" + s[end:]
+	}
+	s = reSectPr.ReplaceAllStringFunc(s, func(sect string) string { return applySection(sect, opts) })
+	d.files["word/document.xml"] = []byte(s)
+	return nil
+}
+
+// ResolveSections gives the paragraphs between the markers a section of their own:
+//
+//	[section landscape]
+//	  a wide table
+//	[/section]
+//
+// The section starts on a new page and takes the page setup of the section around it with the
+// options of the marker (see ParseSectionOptions; %key% is replaced with data[key]): its paper,
+// orientation, margins. The page setup of the text after [/section] is not changed. Every marker
+// must be the only text of its paragraph, outside the tables; the markers without their pair and
+// with a broken spec are removed with a broken_section warning.
+func (d *Docx) ResolveSections(body string, data map[string]any) string {
+	markers := findSectionMarkers(body)
+	if len(markers) == 0 {
+		return body
+	}
+
+	var out strings.Builder
+	pos := 0
+	for i := 0; i < len(markers); i++ {
+		open := markers[i]
+		out.WriteString(body[pos:open.start])
+		pos = open.end
+		if open.closing {
+			d.warn(WarnBadSection, sectionCloseMarker, "no opening marker, removed")
+			continue
+		}
+		if i+1 == len(markers) || !markers[i+1].closing {
+			d.warn(WarnBadSection, open.spec, "no closing marker, removed")
+			continue
+		}
+		closeM := markers[i+1]
+		i++
+
+		spec := open.spec
+		for k, v := range data {
+			spec = strings.ReplaceAll(spec, "%"+k+"%", fmt.Sprint(v))
+		}
+		opts, err := ParseSectionOptions(spec)
+		if err != nil {
+			d.warn(WarnBadSection, open.spec, "%v, the markers are removed", err)
+			out.WriteString(body[open.end:closeM.start])
+			pos = closeM.end
+			continue
+		}
+		// the section around: the first sectPr after the block, the one of the body at the end
+		around := "<w:sectPr/>"
+		if loc := reSectPr.FindStringIndex(body[closeM.end:]); loc != nil {
+			around = body[closeM.end+loc[0] : closeM.end+loc[1]]
+		}
+		out.WriteString(sectionBreak(around))
+		out.WriteString(body[open.end:closeM.start])
+		out.WriteString(sectionBreak(applySection(reSectionType.ReplaceAllString(around, ""), opts)))
+		pos = closeM.end
+	}
+	out.WriteString(body[pos:])
+	return out.String()
+}
+
+// sectionMarker - a paragraph with [section ...] or [/section]: its bounds and the spec after "[section".
+type sectionMarker struct {
+	start, end int
+	spec       string
+	closing    bool
+}
+
+// findSectionMarkers lists the marker paragraphs of the body in order.
+func findSectionMarkers(body string) []sectionMarker {
+	var markers []sectionMarker
+	for pos := 0; ; {
+		start := paragraphStart(body, pos)
+		if start < 0 {
+			return markers
+		}
+		end := elementEnd(body, start)
+		pos = end
+		text := strings.TrimSpace(extractParagraphText(body[start:end]))
+		switch {
+		case text == sectionCloseMarker:
+			markers = append(markers, sectionMarker{start: start, end: end, closing: true})
+		case strings.HasPrefix(text, sectionOpenPrefix) && strings.HasSuffix(text, "]"):
+			spec := strings.TrimSuffix(text[len(sectionOpenPrefix):], "]")
+			if spec == "" || spec[0] == ' ' || spec[0] == '/' {
+				markers = append(markers, sectionMarker{start: start, end: end, spec: strings.TrimLeft(spec, " /")})
+			}
+		}
+	}
+}
+
+// sectionBreak - the empty paragraph that ends a section with the sectPr.
+func sectionBreak(sectPr string) string {
+	return "<w:p><w:pPr>" + sectPr + "</w:pPr></w:p>"
+}
+
+// applySection changes the pgSz and pgMar of the sectPr by the options.
+func applySection(sect string, opts SectionOptions) string {
+	if strings.HasSuffix(sect, "/>") {
+		sect = strings.TrimSuffix(sect, "/>") + "></w:sectPr>"
+	}
+	w, h := extractAttrInt(sect, "w:pgSz", "w:w"), extractAttrInt(sect, "w:pgSz", "w:h")
+	if !rePageSize.MatchString(sect) || w <= 0 || h <= 0 {
+		w, h = geometry.A4Width.Twips(), geometry.A4Height.Twips()
+	}
+	if size, ok := paperSizes[strings.ToLower(opts.Paper)]; ok {
+		if w > h {
+			w, h = size[1].Twips(), size[0].Twips()
+		} else {
+			w, h = size[0].Twips(), size[1].Twips()
+		}
+	}
+	if opts.Width > 0 {
+		w = opts.Width.Twips()
+	}
+	if opts.Height > 0 {
+		h = opts.Height.Twips()
+	}
+	if opts.Orientation == Landscape && w < h || opts.Orientation == Portrait && w > h {
+		w, h = h, w
+	}
+	pgSz := fmt.Sprintf(`<w:pgSz w:w="%d" w:h="%d"/>`, w, h)
+	if w > h {
+		pgSz = fmt.Sprintf(`<w:pgSz w:w="%d" w:h="%d" w:orient="landscape"/>`, w, h)
+	}
+	if loc := rePageSize.FindStringIndex(sect); loc != nil {
+		sect = sect[:loc[0]] + pgSz + sect[loc[1]:]
+	} else {
+		at := len(reBeforePageSize.FindString(sect))
+		sect = sect[:at] + pgSz + sect[at:]
+	}
+
+	if opts.Margins == nil {
+		return sect
+	}
+	m := opts.Margins
+	if loc := rePageMargins.FindStringIndex(sect); loc != nil {
+		pgMar := sect[loc[0]:loc[1]]
+		for _, a := range []struct {
+			name string
+			v    geometry.Length
+		}{{"w:top", m.Top}, {"w:right", m.Right}, {"w:bottom", m.Bottom}, {"w:left", m.Left}} {
+			pgMar = setXMLAttr(pgMar, a.name, fmt.Sprint(a.v.Twips()))
+		}
+		return sect[:loc[0]] + pgMar + sect[loc[1]:]
+	}
+	at := rePageSize.FindStringIndex(sect)[1]
+	pgMar := fmt.Sprintf(`<w:pgMar w:top="%d" w:right="%d" w:bottom="%d" w:left="%d" w:header="708" w:footer="708" w:gutter="0"/>`,
+		m.Top.Twips(), m.Right.Twips(), m.Bottom.Twips(), m.Left.Twips())
+	return sect[:at] + pgMar + sect[at:]
+}
+
+// setXMLAttr sets the attribute of the self-closing element el, adding it if there is none.
+func setXMLAttr(el, name, value string) string {
+	if i := strings.Index(el, " "+name+`="`); i >= 0 {
+		start := i + len(name) + 3
+		end := start + strings.IndexByte(el[start:], '"')
+		return el[:start] + xmlEscape(value) + el[end:]
+	}
+	return strings.TrimSuffix(el, "/>") + " " + name + `="` + xmlEscape(value) + `"/>`
+}
@@ -0,0 +1,224 @@
+package docxgen
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// ParseError - the template of a part does not parse; points at the tag in the document.
+//   - Part — "document", "header1", ...;
+//   - Tag — the tag as the author wrote it ("{name|uper}"), empty when it could not be found;
+//   - Excerpt — about 80 characters of the document text around the tag;
+//   - Err — the error of text/template, its line is the line of the transformed template (see TransformedTemplates).
+type ParseError struct {
+	Part    string
+	Tag     string
+	Excerpt string
+	Err     error
+}
+
+func (e *ParseError) Error() string {
+	if e.Tag == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%v; tag %s in %s near %q", e.Err, e.Tag, e.Part, e.Excerpt)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// excerptRunes - the document text taken on each side of the tag.
+const excerptRunes = 40
+
+var (
+	reParseErrorPrefix = regexp.MustCompile(`^template: [^:]*:(\d+): `)
+	reXMLMarkup        = regexp.MustCompile(`<[^>]*>`)
+)
+
+// locateParseError finds the action of text that broke the parse and the tag of source it came from.
+// text/template reports only the line, and document.xml is usually one line, so the actions are
+// parsed one by one; unbalanced {if}/{range}/{end} are found by counting the blocks.
+func locateParseError(part, source, text string, funcs template.FuncMap, err error) error {
+	perr := &ParseError{Part: part, Err: err}
+
+	actions := templateActions(text)
+	i := failingAction(text, actions, funcs, err)
+	if i < 0 {
+		return perr
+	}
+	action := text[actions[i][0]:actions[i][1]]
+	nth := 0
+	for _, a := range actions[:i] {
+		if text[a[0]:a[1]] == action {
+			nth++
+		}
+	}
+
+	// the same tags of source, in the form TransformTemplate gives them
+	cursor := 0
+	rewriteTags(source, func(tag string) string {
+		start := cursor + strings.Index(source[cursor:], tag)
+		cursor = start + len(tag)
+		if perr.Tag != "" || TransformTemplate(tag) != action {
+			return tag
+		}
+		if nth > 0 {
+			nth--
+			return tag
+		}
+		perr.Tag = html.UnescapeString(tag)
+		perr.Excerpt = excerpt(source, start, cursor)
+		return tag
+	})
+	return perr
+}
+
+// failingAction returns the index of the action that the parse error is about, -1 if it is unknown.
+func failingAction(text string, actions [][2]int, funcs template.FuncMap, err error) int {
+	m := reParseErrorPrefix.FindStringSubmatchIndex(err.Error())
+	if m == nil {
+		return -1
+	}
+	msg := err.Error()[m[1]:]
+	line, _ := strconv.Atoi(err.Error()[m[2]:m[3]])
+
+	// an action that fails on its own with the same message; blocks get their other half
+	lineNo := 1
+	pos := 0
+	for i, a := range actions {
+		lineNo += strings.Count(text[pos:a[0]], "\n")
+		pos = a[0]
+		if lineNo != line {
+			continue
+		}
+		src := text[a[0]:a[1]]
+		switch actionKeyword(src) {
+		case "if", "range", "with", "block", "define":
+			src += "{end}"
+		case "else":
+			src = "{if 1}" + src + "{end}"
+		case "break", "continue":
+			src = "{range .}" + src + "{end}"
+		case "end":
+			continue
+		}
+		if _, perr := parseTemplate(src, funcs); perr != nil && reParseErrorPrefix.ReplaceAllString(perr.Error(), "") == msg {
+			return i
+		}
+	}
+
+	// unbalanced blocks: a stray {else}/{end}, or the last block that is not closed
+	var open []int
+	for i, a := range actions {
+		switch actionKeyword(text[a[0]:a[1]]) {
+		case "if", "range", "with", "block", "define":
+			open = append(open, i)
+		case "else":
+			if len(open) == 0 {
+				return i
+			}
+		case "end":
+			if len(open) == 0 {
+				return i
+			}
+			open = open[:len(open)-1]
+		}
+	}
+	if len(open) > 0 && strings.Contains(msg, "unexpected EOF") {
+		return open[len(open)-1]
+	}
+	return -1
+}
+
+// templateActions returns the bounds of every {...} of the template; "}" inside quotes does not close it.
+func templateActions(text string) [][2]int {
+	var out [][2]int
+	for i := 0; i < len(text); i++ {
+		if text[i] != '{' {
+			continue
+		}
+		var quote byte
+		end := -1
+		for j := i + 1; j < len(text) && end < 0; j++ {
+			c := text[j]
+			switch {
+			case quote != 0:
+				if c == '\\' && quote != '`' {
+					j++
+				} else if c == quote {
+					quote = 0
+				}
+			case c == '"' || c == '`' || c == '\'':
+				quote = c
+			case c == '}':
+				end = j
+			}
+		}
+		if end < 0 {
+			break
+		}
+		out = append(out, [2]int{i, end + 1})
+		i = end
+	}
+	return out
+}
+
+// actionKeyword - the first word of the action: "if", "end", ... (with the trim markers removed).
+func actionKeyword(action string) string {
+	s := strings.TrimSuffix(strings.TrimPrefix(action, "{"), "}")
+	s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "-"))
+	if i := strings.IndexAny(s, " \t\r\n"); i >= 0 {
+		s = s[:i]
+	}
+	return s
+}
+
+// excerpt - the tag with the document text around it: "…the contract No. {number|nosuch} of 01.02…".
+func excerpt(source string, start, end int) string {
+	before := []rune(plainText(source[max(0, start-excerptRunes*40):start], true))
+	after := []rune(plainText(source[end:min(len(source), end+excerptRunes*40)], false))
+
+	var b strings.Builder
+	if len(before) > excerptRunes {
+		b.WriteString("…")
+		before = before[len(before)-excerptRunes:]
+	}
+	b.WriteString(string(before))
+	b.WriteString(html.UnescapeString(source[start:end]))
+	if len(after) > excerptRunes {
+		b.WriteString(string(after[:excerptRunes]))
+		b.WriteString("…")
+	} else {
+		b.WriteString(string(after))
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// plainText - the text of an XML window; a tag cut by the window edge is dropped,
+// paragraphs are separated by a space.
+func plainText(xml string, cutStart bool) string {
+	if cutStart {
+		if i, j := strings.IndexByte(xml, '>'), strings.IndexByte(xml, '<'); i >= 0 && (j < 0 || i < j) {
+			xml = xml[i+1:]
+		}
+	} else if i := strings.LastIndexByte(xml, '<'); i >= 0 && !strings.Contains(xml[i:], ">") {
+		xml = xml[:i]
+	}
+	xml = strings.ReplaceAll(xml, "</w:p>", " ")
+	text := html.UnescapeString(reXMLMarkup.ReplaceAllString(xml, ""))
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+	// keep a single space at the edges: it separates the tag from the words
+	lead, trail := "", ""
+	if strings.TrimLeft(text, " \t\r\n") != text {
+		lead = " "
+	}
+	if strings.TrimRight(text, " \t\r\n") != text {
+		trail = " "
+	}
+	return lead + strings.Join(strings.Fields(text), " ") + trail
+}
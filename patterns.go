@@ -0,0 +1,32 @@
+package docxgen
+
+import (
+	"regexp"
+	"sync"
+	"sync/atomic"
+)
+
+// maxPatterns - how many run-time patterns the cache keeps; the ones beyond are compiled per call.
+// The patterns built from ids (bookmarks, comments) differ per document and must not grow it forever.
+const maxPatterns = 1024
+
+var (
+	// patternCache - the compiled regular expressions built at run time from a name or an id
+	// (an element of the properties, a field of a smart table), shared by all documents.
+	patternCache sync.Map // string → *regexp.Regexp
+	patternCount atomic.Int32
+)
+
+// pattern returns the compiled expr, compiling it once; expr is built by the package and must be valid.
+func pattern(expr string) *regexp.Regexp {
+	if re, ok := patternCache.Load(expr); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(expr)
+	if patternCount.Load() < maxPatterns {
+		if _, loaded := patternCache.LoadOrStore(expr, re); !loaded {
+			patternCount.Add(1)
+		}
+	}
+	return re
+}
@@ -0,0 +1,165 @@
+package pdf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// PageRasterizer renders the pages of a PDF to images, one PNG per page in page order.
+type PageRasterizer interface {
+	Pages(ctx context.Context, pdf []byte) ([][]byte, error)
+}
+
+// DefaultRasterEngines - rasterization tools, from best to worst:
+// pdftoppm (poppler-utils), mutool (MuPDF), gs (Ghostscript).
+var DefaultRasterEngines = []string{"pdftoppm", "mutool", "gs"}
+
+// defaultDPI - the resolution of page images: enough for a web preview of A4.
+const defaultDPI = 110
+
+// RasterOptions - settings of the rasterizer.
+//   - DPI — the resolution of the images (0 — 110);
+//   - Engines — tools in the order of preference (empty — DefaultRasterEngines);
+//   - TempDir — where scratch directories are created (empty — os.TempDir()), see Options;
+//   - Timeout — the limit of one run (0 — 2 minutes).
+type RasterOptions struct {
+	DPI     int
+	Engines []string
+	TempDir string
+	Timeout time.Duration
+}
+
+// Rasterizer - PageRasterizer on top of external tools.
+type Rasterizer struct {
+	opts RasterOptions
+}
+
+// NewRasterizer creates a rasterizer with the given options.
+func NewRasterizer(opts RasterOptions) *Rasterizer {
+	if opts.DPI <= 0 {
+		opts.DPI = defaultDPI
+	}
+	if len(opts.Engines) == 0 {
+		opts.Engines = DefaultRasterEngines
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	return &Rasterizer{opts: opts}
+}
+
+// Pages renders every page of the PDF to PNG with the first installed tool that succeeds.
+func (r *Rasterizer) Pages(ctx context.Context, pdf []byte) ([][]byte, error) {
+	dir, err := os.MkdirTemp(r.opts.TempDir, "docxgen-png-*")
+	if err != nil {
+		return nil, fmt.Errorf("pdf: scratch dir: %w", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	src := filepath.Join(dir, "document.pdf")
+	if err := os.WriteFile(src, pdf, 0600); err != nil {
+		return nil, fmt.Errorf("pdf: %w", err)
+	}
+
+	var failed []EngineError
+	for _, engine := range r.opts.Engines {
+		if _, err := exec.LookPath(engine); err != nil {
+			continue
+		}
+		out := filepath.Join(dir, engine)
+		if err := os.Mkdir(out, 0700); err != nil {
+			return nil, fmt.Errorf("pdf: %w", err)
+		}
+		pages, err := r.run(ctx, engine, src, out)
+		if err == nil {
+			return pages, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		failed = append(failed, EngineError{Engine: engine, Attempt: 1, Err: err})
+	}
+	if len(failed) == 0 {
+		return nil, ErrNoRasterEngine
+	}
+	return nil, &ConversionError{Attempts: failed}
+}
+
+// ErrNoRasterEngine - none of the rasterization tools is installed.
+var ErrNoRasterEngine = errors.New("no available PDF rasterization tools found (pdftoppm, mutool, gs)")
+
+// run renders the pages with one tool into the directory out and reads them in page order.
+func (r *Rasterizer) run(ctx context.Context, engine, src, out string) ([][]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opts.Timeout)
+	defer cancel()
+
+	dpi := strconv.Itoa(r.opts.DPI)
+	var cmd *exec.Cmd
+	switch engine {
+	case "pdftoppm":
+		cmd = exec.CommandContext(ctx, engine, "-png", "-r", dpi, src, filepath.Join(out, "page"))
+	case "mutool":
+		cmd = exec.CommandContext(ctx, engine, "draw", "-q", "-r", dpi, "-o", filepath.Join(out, "page-%d.png"), src)
+	case "gs":
+		cmd = exec.CommandContext(ctx, engine, "-q", "-dSAFER", "-dBATCH", "-dNOPAUSE",
+			"-sDEVICE=png16m", "-r"+dpi, "-o", filepath.Join(out, "page-%d.png"), src)
+	default:
+		return nil, fmt.Errorf("unknown engine: %s", engine)
+	}
+
+	cmd.Env = scratchEnv(filepath.Dir(src))
+	killOnCancel(cmd)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("timeout after %s", r.opts.Timeout)
+		}
+		if msg := lastLine(string(output)); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return readPages(out)
+}
+
+// rePageNumber - the page number in the file names of the tools: page-1.png, page-01.png.
+var rePageNumber = regexp.MustCompile(`(\d+)\.png$`)
+
+// readPages reads the PNG files of the directory ordered by their page number.
+func readPages(dir string) ([][]byte, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.png"))
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no output produced")
+	}
+	number := func(name string) int {
+		m := rePageNumber.FindStringSubmatch(name)
+		if m == nil {
+			return 0
+		}
+		n, _ := strconv.Atoi(m[1])
+		return n
+	}
+	sort.Slice(names, func(i, j int) bool { return number(names[i]) < number(names[j]) })
+
+	pages := make([][]byte, 0, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, data)
+	}
+	return pages, nil
+}
@@ -0,0 +1,834 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// A minimal reader/writer of the PDF object structure: just enough to append an incremental update
+// (new objects and new versions of pages) to a document produced by an office engine.
+// Content streams are never decoded; only cross-reference and object streams are (FlateDecode).
+
+// ErrEncrypted - encrypted documents cannot be updated without their keys.
+var ErrEncrypted = errors.New("pdf: encrypted documents are not supported")
+
+type (
+	pdfName string // /Name without the slash, as written (#xx escapes kept)
+	pdfRaw  string // a string, boolean or null token, written back as is
+	pdfRef  struct{ num, gen int }
+	pdfDict struct {
+		keys []string
+		vals map[string]any
+	}
+	pdfStream struct {
+		dict *pdfDict
+		data []byte // raw (encoded) data
+	}
+)
+
+func newDict() *pdfDict {
+	return &pdfDict{vals: map[string]any{}}
+}
+
+func (d *pdfDict) get(key string) any {
+	return d.vals[key]
+}
+
+func (d *pdfDict) set(key string, v any) {
+	if _, ok := d.vals[key]; !ok {
+		d.keys = append(d.keys, key)
+	}
+	d.vals[key] = v
+}
+
+func (d *pdfDict) clone() *pdfDict {
+	c := &pdfDict{keys: append([]string(nil), d.keys...), vals: make(map[string]any, len(d.vals))}
+	for k, v := range d.vals {
+		c.vals[k] = v
+	}
+	return c
+}
+
+// -------- Lexer / parser --------
+
+type parser struct {
+	b     []byte
+	pos   int
+	depth int // nesting of the arrays and dictionaries being parsed
+	// length resolves an indirect /Length of a stream
+	length func(v any) (int, bool)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isDelim(c byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+func (p *parser) skip() {
+	for p.pos < len(p.b) {
+		c := p.b[p.pos]
+		switch {
+		case isSpace(c):
+			p.pos++
+		case c == '%':
+			for p.pos < len(p.b) && p.b[p.pos] != '\n' && p.b[p.pos] != '\r' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// token reads a regular token (number, keyword).
+func (p *parser) token() string {
+	p.skip()
+	start := p.pos
+	for p.pos < len(p.b) && !isSpace(p.b[p.pos]) && !isDelim(p.b[p.pos]) {
+		p.pos++
+	}
+	return string(p.b[start:p.pos])
+}
+
+func (p *parser) peekKeyword(kw string) bool {
+	p.skip()
+	end := p.pos + len(kw)
+	if end > len(p.b) || string(p.b[p.pos:end]) != kw {
+		return false
+	}
+	return end == len(p.b) || isSpace(p.b[end]) || isDelim(p.b[end])
+}
+
+// maxNesting - the deepest nesting of arrays and dictionaries the parser follows.
+const maxNesting = 256
+
+// object parses one direct object; "N G R" becomes pdfRef.
+func (p *parser) object() (any, error) {
+	if p.depth >= maxNesting {
+		return nil, fmt.Errorf("pdf: objects nested too deep at %d", p.pos)
+	}
+	p.depth++
+	defer func() { p.depth-- }()
+	p.skip()
+	if p.pos >= len(p.b) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	switch c := p.b[p.pos]; {
+	case c == '/':
+		p.pos++
+		start := p.pos
+		for p.pos < len(p.b) && !isSpace(p.b[p.pos]) && !isDelim(p.b[p.pos]) {
+			p.pos++
+		}
+		return pdfName(p.b[start:p.pos]), nil
+
+	case c == '<' && p.pos+1 < len(p.b) && p.b[p.pos+1] == '<':
+		p.pos += 2
+		d := newDict()
+		for {
+			p.skip()
+			if p.pos+1 < len(p.b) && p.b[p.pos] == '>' && p.b[p.pos+1] == '>' {
+				p.pos += 2
+				return d, nil
+			}
+			k, err := p.object()
+			if err != nil {
+				return nil, err
+			}
+			key, ok := k.(pdfName)
+			if !ok {
+				return nil, fmt.Errorf("pdf: dictionary key is not a name at %d", p.pos)
+			}
+			v, err := p.object()
+			if err != nil {
+				return nil, err
+			}
+			d.set(string(key), v)
+		}
+
+	case c == '<':
+		end := bytes.IndexByte(p.b[p.pos:], '>')
+		if end < 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		raw := p.b[p.pos : p.pos+end+1]
+		p.pos += end + 1
+		return pdfRaw(raw), nil
+
+	case c == '(':
+		start, depth := p.pos, 0
+		for ; p.pos < len(p.b); p.pos++ {
+			switch p.b[p.pos] {
+			case '\\':
+				p.pos++
+			case '(':
+				depth++
+			case ')':
+				depth--
+				if depth == 0 {
+					p.pos++
+					return pdfRaw(p.b[start:p.pos]), nil
+				}
+			}
+		}
+		return nil, io.ErrUnexpectedEOF
+
+	case c == '[':
+		p.pos++
+		var arr []any
+		for {
+			p.skip()
+			if p.pos < len(p.b) && p.b[p.pos] == ']' {
+				p.pos++
+				return arr, nil
+			}
+			v, err := p.object()
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+	}
+
+	tok := p.token()
+	if tok == "" {
+		return nil, fmt.Errorf("pdf: unexpected %q at %d", p.b[p.pos], p.pos)
+	}
+	n, err := strconv.ParseFloat(tok, 64)
+	if err != nil {
+		return pdfRaw(tok), nil // true, false, null
+	}
+	// N G R
+	if num, err := strconv.Atoi(tok); err == nil {
+		save := p.pos
+		if gen, err := strconv.Atoi(p.token()); err == nil && p.peekKeyword("R") {
+			p.pos++
+			return pdfRef{num, gen}, nil
+		}
+		p.pos = save
+		return num, nil
+	}
+	return n, nil
+}
+
+// indirect parses "N G obj ... endobj" at the current position.
+func (p *parser) indirect() (num, gen int, v any, err error) {
+	if num, err = strconv.Atoi(p.token()); err != nil {
+		return 0, 0, nil, fmt.Errorf("pdf: object number: %w", err)
+	}
+	if gen, err = strconv.Atoi(p.token()); err != nil {
+		return 0, 0, nil, fmt.Errorf("pdf: generation: %w", err)
+	}
+	if p.token() != "obj" {
+		return 0, 0, nil, fmt.Errorf("pdf: %d %d: obj expected", num, gen)
+	}
+	if v, err = p.object(); err != nil {
+		return 0, 0, nil, err
+	}
+	d, ok := v.(*pdfDict)
+	if !ok || !p.peekKeyword("stream") {
+		return num, gen, v, nil
+	}
+
+	p.pos += len("stream")
+	if p.pos < len(p.b) && p.b[p.pos] == '\r' {
+		p.pos++
+	}
+	if p.pos < len(p.b) && p.b[p.pos] == '\n' {
+		p.pos++
+	}
+	n, ok := p.length(d.get("Length"))
+	if !ok || n < 0 || n > len(p.b)-p.pos {
+		// a missing, negative or too long /Length: look for the keyword instead
+		end := bytes.Index(p.b[p.pos:], []byte("endstream"))
+		if end < 0 {
+			return 0, 0, nil, fmt.Errorf("pdf: %d %d: endstream not found", num, gen)
+		}
+		n = len(bytes.TrimRight(p.b[p.pos:p.pos+end], "\r\n"))
+	}
+	s := &pdfStream{dict: d, data: p.b[p.pos : p.pos+n]}
+	p.pos += n
+	return num, gen, s, nil
+}
+
+// -------- Writer --------
+
+func writeObject(w *bytes.Buffer, v any) {
+	switch x := v.(type) {
+	case nil:
+		w.WriteString("null")
+	case pdfName:
+		w.WriteString("/" + string(x))
+	case pdfRaw:
+		w.WriteString(string(x))
+	case pdfRef:
+		fmt.Fprintf(w, "%d %d R", x.num, x.gen)
+	case int:
+		w.WriteString(strconv.Itoa(x))
+	case float64:
+		w.WriteString(strconv.FormatFloat(x, 'f', -1, 64))
+	case []any:
+		w.WriteByte('[')
+		for i, e := range x {
+			if i > 0 {
+				w.WriteByte(' ')
+			}
+			writeObject(w, e)
+		}
+		w.WriteByte(']')
+	case *pdfDict:
+		w.WriteString("<<")
+		for _, k := range x.keys {
+			w.WriteString("/" + k + " ")
+			writeObject(w, x.vals[k])
+		}
+		w.WriteString(">>")
+	case *pdfStream:
+		x.dict.set("Length", len(x.data))
+		writeObject(w, x.dict)
+		w.WriteString("\nstream\n")
+		w.Write(x.data)
+		w.WriteString("\nendstream")
+	default:
+		panic(fmt.Sprintf("pdf: cannot write %T", v))
+	}
+}
+
+// -------- Document and cross-reference --------
+
+type xrefEntry struct {
+	kind   int   // 1 — at offset, 2 — inside an object stream
+	offset int64 // kind 1: byte offset; kind 2: number of the object stream
+	index  int   // kind 2: index in the object stream
+	gen    int
+}
+
+type document struct {
+	data       []byte
+	xref       map[int]xrefEntry
+	trailer    *pdfDict
+	startxref  int64
+	xrefStream bool
+	cache      map[int]any
+	objStreams map[int][]objStmEntry
+	loading    map[int]bool // the objects being read: an object that needs itself is an error
+}
+
+type objStmEntry struct {
+	num    int
+	offset int
+}
+
+// parseDocument reads the cross-reference chain of the document.
+func parseDocument(data []byte) (*document, error) {
+	d := &document{data: data, xref: map[int]xrefEntry{}, cache: map[int]any{}, objStreams: map[int][]objStmEntry{},
+		loading: map[int]bool{}}
+
+	tail := data
+	if len(tail) > 2048 {
+		tail = tail[len(tail)-2048:]
+	}
+	i := bytes.LastIndex(tail, []byte("startxref"))
+	if i < 0 {
+		return nil, errors.New("pdf: startxref not found")
+	}
+	p := &parser{b: tail, pos: i + len("startxref")}
+	off, err := strconv.ParseInt(p.token(), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("pdf: startxref: %w", err)
+	}
+	d.startxref = off
+
+	seen := map[int64]bool{}
+	for first := true; ; first = false {
+		if off <= 0 || off >= int64(len(data)) || seen[off] {
+			if first {
+				return nil, fmt.Errorf("pdf: bad xref offset %d", off)
+			}
+			break
+		}
+		seen[off] = true
+
+		trailer, isStream, err := d.readXref(off)
+		if err != nil {
+			return nil, err
+		}
+		if first {
+			d.trailer, d.xrefStream = trailer, isStream
+		}
+		// hybrid files: the xref stream of the section
+		if stm, ok := trailer.get("XRefStm").(int); ok && !seen[int64(stm)] {
+			seen[int64(stm)] = true
+			if _, _, err := d.readXref(int64(stm)); err != nil {
+				return nil, err
+			}
+		}
+		prev, ok := trailer.get("Prev").(int)
+		if !ok {
+			break
+		}
+		off = int64(prev)
+	}
+
+	if d.trailer.get("Encrypt") != nil {
+		return nil, ErrEncrypted
+	}
+	return d, nil
+}
+
+// readXref reads one cross-reference section (a table or a stream); newer entries are kept.
+func (d *document) readXref(off int64) (*pdfDict, bool, error) {
+	p := &parser{b: d.data, pos: int(off), length: d.length}
+	if p.peekKeyword("xref") {
+		p.token()
+		for {
+			if p.peekKeyword("trailer") {
+				p.token()
+				t, err := p.object()
+				if err != nil {
+					return nil, false, err
+				}
+				td, ok := t.(*pdfDict)
+				if !ok {
+					return nil, false, errors.New("pdf: bad trailer")
+				}
+				return td, false, nil
+			}
+			start, err1 := strconv.Atoi(p.token())
+			count, err2 := strconv.Atoi(p.token())
+			if err1 != nil || err2 != nil || !xrefRange(start, count, len(p.b)-p.pos, xrefEntrySize) {
+				return nil, false, errors.New("pdf: bad xref table")
+			}
+			for n := start; n < start+count; n++ {
+				o, _ := strconv.ParseInt(p.token(), 10, 64)
+				g, _ := strconv.Atoi(p.token())
+				typ := p.token()
+				if typ == "" {
+					return nil, false, errors.New("pdf: xref table cut short")
+				}
+				if _, ok := d.xref[n]; !ok && typ == "n" {
+					d.xref[n] = xrefEntry{kind: 1, offset: o, gen: g}
+				} else if !ok {
+					d.xref[n] = xrefEntry{}
+				}
+			}
+		}
+	}
+
+	_, _, v, err := p.indirect()
+	if err != nil {
+		return nil, false, err
+	}
+	s, ok := v.(*pdfStream)
+	if !ok || s.dict.get("Type") != pdfName("XRef") {
+		return nil, false, fmt.Errorf("pdf: no xref at %d", off)
+	}
+	raw, err := d.decode(s)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var w [3]int
+	wa, _ := s.dict.get("W").([]any)
+	for i := 0; i < 3 && i < len(wa); i++ {
+		w[i], _ = wa[i].(int)
+		if w[i] < 0 || w[i] > 8 {
+			return nil, false, errors.New("pdf: bad xref stream /W")
+		}
+	}
+	size, _ := s.dict.get("Size").(int)
+	index := []any{0, size}
+	if ia, ok := s.dict.get("Index").([]any); ok {
+		index = ia
+	}
+
+	row := w[0] + w[1] + w[2]
+	if row == 0 {
+		return nil, false, errors.New("pdf: bad xref stream /W")
+	}
+	for i := 0; i+1 < len(index); i += 2 {
+		start, ok1 := index[i].(int)
+		count, ok2 := index[i+1].(int)
+		if !ok1 || !ok2 || !xrefRange(start, count, len(raw), row) {
+			return nil, false, errors.New("pdf: bad xref stream /Size or /Index")
+		}
+	}
+	field := func(b []byte, def int) int64 {
+		if len(b) == 0 {
+			return int64(def)
+		}
+		var n int64
+		for _, c := range b {
+			n = n<<8 | int64(c)
+		}
+		return n
+	}
+	pos := 0
+	for i := 0; i+1 < len(index); i += 2 {
+		start, _ := index[i].(int)
+		count, _ := index[i+1].(int)
+		for n := start; n < start+count && pos+row <= len(raw); n++ {
+			r := raw[pos : pos+row]
+			pos += row
+			typ := field(r[:w[0]], 1)
+			f2 := field(r[w[0]:w[0]+w[1]], 0)
+			f3 := field(r[w[0]+w[1]:], 0)
+			if _, ok := d.xref[n]; ok {
+				continue
+			}
+			switch typ {
+			case 1:
+				d.xref[n] = xrefEntry{kind: 1, offset: f2, gen: int(f3)}
+			case 2:
+				d.xref[n] = xrefEntry{kind: 2, offset: f2, index: int(f3)}
+			default:
+				d.xref[n] = xrefEntry{}
+			}
+		}
+	}
+	return s.dict, true, nil
+}
+
+// xrefEntrySize - the bytes of one entry of a cross-reference table ("0000000000 65535 f \n").
+const xrefEntrySize = 20
+
+// xrefRange checks a subsection of count objects from start, each taking entry of the left bytes:
+// a larger count than the data can hold is a broken or hostile file.
+func xrefRange(start, count, left, entry int) bool {
+	return start >= 0 && count >= 0 && count <= left/entry && start <= math.MaxInt32-count
+}
+
+// length resolves the /Length of a stream (possibly an indirect object).
+func (d *document) length(v any) (int, bool) {
+	if r, ok := v.(pdfRef); ok {
+		obj, err := d.get(r.num)
+		if err != nil {
+			return 0, false
+		}
+		v = obj
+	}
+	n, ok := v.(int)
+	return n, ok
+}
+
+// get returns the object by its number.
+func (d *document) get(num int) (any, error) {
+	if v, ok := d.cache[num]; ok {
+		return v, nil
+	}
+	if d.loading[num] {
+		// a /Length that points to its own object, an object stream that contains itself
+		return nil, fmt.Errorf("pdf: object %d refers to itself", num)
+	}
+	d.loading[num] = true
+	defer delete(d.loading, num)
+
+	e, ok := d.xref[num]
+	var v any
+	switch {
+	case !ok || e.kind == 0:
+		return nil, nil // a missing object is null
+	case e.kind == 1:
+		p := &parser{b: d.data, pos: int(e.offset), length: d.length}
+		n, _, obj, err := p.indirect()
+		if err != nil {
+			return nil, fmt.Errorf("pdf: object %d: %w", num, err)
+		}
+		if n != num {
+			return nil, fmt.Errorf("pdf: object %d: found %d at its offset", num, n)
+		}
+		v = obj
+	case e.kind == 2:
+		obj, err := d.fromObjStream(int(e.offset), e.index, num)
+		if err != nil {
+			return nil, err
+		}
+		v = obj
+	}
+	d.cache[num] = v
+	return v, nil
+}
+
+// fromObjStream reads an object compressed into an object stream.
+func (d *document) fromObjStream(stmNum, index, num int) (any, error) {
+	obj, err := d.get(stmNum)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := obj.(*pdfStream)
+	if !ok {
+		return nil, fmt.Errorf("pdf: object stream %d not found", stmNum)
+	}
+	raw, err := d.decode(s)
+	if err != nil {
+		return nil, err
+	}
+	first, _ := s.dict.get("First").(int)
+
+	entries, ok := d.objStreams[stmNum]
+	if !ok {
+		n, _ := s.dict.get("N").(int)
+		p := &parser{b: raw}
+		for i := 0; i < n; i++ {
+			on, err1 := strconv.Atoi(p.token())
+			oo, err2 := strconv.Atoi(p.token())
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("pdf: bad object stream %d", stmNum)
+			}
+			entries = append(entries, objStmEntry{num: on, offset: oo})
+		}
+		d.objStreams[stmNum] = entries
+	}
+	if index >= len(entries) || entries[index].num != num {
+		// the index is only a hint: find the object by its number
+		index = -1
+		for i, e := range entries {
+			if e.num == num {
+				index = i
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("pdf: object %d not in stream %d", num, stmNum)
+		}
+	}
+	off := entries[index].offset
+	if first < 0 || off < 0 || off >= len(raw)-first {
+		return nil, fmt.Errorf("pdf: object %d is outside stream %d", num, stmNum)
+	}
+	p := &parser{b: raw, pos: first + off, length: d.length}
+	return p.object()
+}
+
+// decode decodes FlateDecode streams with an optional PNG/TIFF predictor.
+func (d *document) decode(s *pdfStream) ([]byte, error) {
+	filter := s.dict.get("Filter")
+	parms, _ := s.dict.get("DecodeParms").(*pdfDict)
+	if arr, ok := filter.([]any); ok {
+		if len(arr) > 1 {
+			return nil, errors.New("pdf: chained filters are not supported")
+		}
+		if len(arr) == 1 {
+			filter = arr[0]
+		} else {
+			filter = nil
+		}
+		if pa, ok := s.dict.get("DecodeParms").([]any); ok && len(pa) == 1 {
+			parms, _ = pa[0].(*pdfDict)
+		}
+	}
+	switch filter {
+	case nil:
+		return s.data, nil
+	case pdfName("FlateDecode"):
+	default:
+		return nil, fmt.Errorf("pdf: unsupported filter %v", filter)
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(s.data))
+	if err != nil {
+		return nil, fmt.Errorf("pdf: flate: %w", err)
+	}
+	out, err := io.ReadAll(zr)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("pdf: flate: %w", err)
+	}
+	if parms == nil {
+		return out, nil
+	}
+	predictor, _ := parms.get("Predictor").(int)
+	if predictor < 10 {
+		return out, nil
+	}
+	columns, ok := parms.get("Columns").(int)
+	if !ok {
+		columns = 1
+	}
+	return unpredictPNG(out, columns)
+}
+
+// unpredictPNG reverses the PNG row filters (each row starts with its filter type byte).
+func unpredictPNG(data []byte, columns int) ([]byte, error) {
+	if columns < 1 || columns >= len(data) {
+		return nil, fmt.Errorf("pdf: bad predictor /Columns %d", columns)
+	}
+	row := columns + 1
+	if len(data)%row != 0 {
+		return nil, errors.New("pdf: bad predictor data")
+	}
+	out := make([]byte, 0, len(data)/row*columns)
+	prev := make([]byte, columns)
+	for i := 0; i < len(data); i += row {
+		typ, cur := data[i], append([]byte(nil), data[i+1:i+row]...)
+		for j := range cur {
+			var left, up, upLeft byte
+			if j > 0 {
+				left, upLeft = cur[j-1], prev[j-1]
+			}
+			up = prev[j]
+			switch typ {
+			case 1:
+				cur[j] += left
+			case 2:
+				cur[j] += up
+			case 3:
+				cur[j] += byte((int(left) + int(up)) / 2)
+			case 4:
+				cur[j] += paeth(left, up, upLeft)
+			}
+		}
+		out = append(out, cur...)
+		prev = cur
+	}
+	return out, nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// resolve follows a reference.
+func (d *document) resolve(v any) (any, error) {
+	if r, ok := v.(pdfRef); ok {
+		return d.get(r.num)
+	}
+	return v, nil
+}
+
+// size - the number of objects of the document (the next free object number).
+func (d *document) size() int {
+	n, _ := d.trailer.get("Size").(int)
+	for num := range d.xref {
+		if num >= n {
+			n = num + 1
+		}
+	}
+	return n
+}
+
+// -------- Incremental update --------
+
+// update collects new objects and new versions of existing ones.
+type update struct {
+	doc     *document
+	next    int
+	objects map[int]any
+	gens    map[int]int
+}
+
+func (d *document) newUpdate() *update {
+	return &update{doc: d, next: d.size(), objects: map[int]any{}, gens: map[int]int{}}
+}
+
+// add adds a new object and returns the reference to it.
+func (u *update) add(v any) pdfRef {
+	num := u.next
+	u.next++
+	u.objects[num] = v
+	return pdfRef{num, 0}
+}
+
+// replace writes a new version of an existing object.
+func (u *update) replace(r pdfRef, v any) {
+	u.objects[r.num] = v
+	u.gens[r.num] = r.gen
+	u.doc.cache[r.num] = v
+}
+
+// bytes appends the update to the original document.
+func (u *update) bytes() []byte {
+	var w bytes.Buffer
+	w.Write(u.doc.data)
+	if !bytes.HasSuffix(u.doc.data, []byte("\n")) {
+		w.WriteByte('\n')
+	}
+
+	nums := make([]int, 0, len(u.objects)+1)
+	for n := range u.objects {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+
+	offsets := map[int]int{}
+	for _, n := range nums {
+		offsets[n] = w.Len()
+		fmt.Fprintf(&w, "%d %d obj\n", n, u.gens[n])
+		writeObject(&w, u.objects[n])
+		w.WriteString("\nendobj\n")
+	}
+
+	trailer := newDict()
+	for _, k := range []string{"Root", "Info", "ID"} {
+		if v := u.doc.trailer.get(k); v != nil {
+			trailer.set(k, v)
+		}
+	}
+	trailer.set("Prev", int(u.doc.startxref))
+
+	if !u.doc.xrefStream {
+		xrefAt := w.Len()
+		trailer.set("Size", u.next)
+		w.WriteString("xref\n")
+		for i := 0; i < len(nums); {
+			j := i
+			for j+1 < len(nums) && nums[j+1] == nums[j]+1 {
+				j++
+			}
+			fmt.Fprintf(&w, "%d %d\n", nums[i], j-i+1)
+			for k := i; k <= j; k++ {
+				fmt.Fprintf(&w, "%010d %05d n \n", offsets[nums[k]], u.gens[nums[k]])
+			}
+			i = j + 1
+		}
+		w.WriteString("trailer\n")
+		writeObject(&w, trailer)
+		fmt.Fprintf(&w, "\nstartxref\n%d\n%%%%EOF\n", xrefAt)
+		return w.Bytes()
+	}
+
+	// the original uses a cross-reference stream: the update does the same
+	xrefNum := u.next
+	xrefAt := w.Len()
+	nums = append(nums, xrefNum)
+	offsets[xrefNum] = xrefAt
+
+	var index []any
+	var rows bytes.Buffer
+	for _, n := range nums {
+		index = append(index, n, 1)
+		o := offsets[n]
+		rows.Write([]byte{1, byte(o >> 24), byte(o >> 16), byte(o >> 8), byte(o), byte(u.gens[n] >> 8), byte(u.gens[n])})
+	}
+	trailer.set("Type", pdfName("XRef"))
+	trailer.set("Size", xrefNum+1)
+	trailer.set("W", []any{1, 4, 2})
+	trailer.set("Index", index)
+
+	fmt.Fprintf(&w, "%d 0 obj\n", xrefNum)
+	writeObject(&w, &pdfStream{dict: trailer, data: rows.Bytes()})
+	fmt.Fprintf(&w, "\nendobj\nstartxref\n%d\n%%%%EOF\n", xrefAt)
+	return w.Bytes()
+}
@@ -0,0 +1,333 @@
+// Package pdf converts DOCX documents to PDF with an external office engine
+// (LibreOffice soffice/lowriter or unoconv).
+//
+// Example:
+//
+//	conv := pdf.New(pdf.Options{Preferred: "soffice", Timeout: time.Minute})
+//	out, err := conv.Convert(ctx, docxBytes)
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Converter converts a DOCX document to PDF.
+type Converter interface {
+	Convert(ctx context.Context, docx []byte) ([]byte, error)
+}
+
+// DefaultEngines - Engine Order: From Best to Worst.
+var DefaultEngines = []string{
+	"soffice", // LibreOffice headless
+	"libreoffice",
+	"lowriter",
+	"unoconv", // fallback, but extremely unreliable
+}
+
+// ErrNoEngine - none of the engines is installed.
+var ErrNoEngine = errors.New("no available PDF engines found")
+
+// Options - settings of the engine converter.
+//   - Engines — engines in the order of preference (empty — DefaultEngines);
+//   - Preferred — the engine tried first (the --pdf-engine flag);
+//   - TempDir — where scratch directories are created (empty — os.TempDir()); the engines write
+//     nothing outside them, so a tmpfs here is enough for a read-only root file system;
+//   - Timeout — the limit of one engine run (0 — 2 minutes);
+//   - MaxParallel — how many conversions run at the same time, the rest wait in a queue
+//     (0 — the number of CPUs);
+//   - Retries — extra attempts of a failed engine before moving to the next one;
+//   - Backoff — the pause before the first retry, doubled for every next one (0 — 500ms);
+//   - Log — receives the progress messages and the failures of engines (nil — silent).
+type Options struct {
+	Engines     []string
+	Preferred   string
+	TempDir     string
+	Timeout     time.Duration
+	MaxParallel int
+	Retries     int
+	Backoff     time.Duration
+	Log         func(format string, a ...any)
+}
+
+// defaultTimeout - a conversion that takes longer is considered hung.
+const defaultTimeout = 2 * time.Minute
+
+// defaultBackoff - the pause before the first retry of an engine.
+const defaultBackoff = 500 * time.Millisecond
+
+// EngineError - a failed attempt of an engine (1-based).
+type EngineError struct {
+	Engine  string
+	Attempt int
+	Err     error
+}
+
+func (e EngineError) Error() string {
+	return fmt.Sprintf("%s (attempt %d): %v", e.Engine, e.Attempt, e.Err)
+}
+
+func (e EngineError) Unwrap() error {
+	return e.Err
+}
+
+// ConversionError - every installed engine failed; Attempts lists the failures in order.
+type ConversionError struct {
+	Attempts []EngineError
+}
+
+func (e *ConversionError) Error() string {
+	parts := make([]string, len(e.Attempts))
+	for i, a := range e.Attempts {
+		parts[i] = a.Error()
+	}
+	return "pdf: all engines failed: " + strings.Join(parts, "; ")
+}
+
+func (e *ConversionError) Unwrap() []error {
+	errs := make([]error, len(e.Attempts))
+	for i, a := range e.Attempts {
+		errs[i] = a
+	}
+	return errs
+}
+
+// EngineConverter - Converter on top of external engines.
+// It is safe for concurrent use: every LibreOffice run gets its own temporary user profile
+// (parallel runs on the default profile lock each other and fail at random),
+// and slots limits the number of simultaneous conversions.
+type EngineConverter struct {
+	opts  Options
+	slots chan struct{}
+}
+
+// New creates a converter with the given options.
+func New(opts Options) *EngineConverter {
+	if len(opts.Engines) == 0 {
+		opts.Engines = DefaultEngines
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.MaxParallel <= 0 {
+		opts.MaxParallel = runtime.NumCPU()
+	}
+	if opts.Retries < 0 {
+		opts.Retries = 0
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = defaultBackoff
+	}
+	return &EngineConverter{opts: opts, slots: make(chan struct{}, opts.MaxParallel)}
+}
+
+// Engines returns the engines in the order they are tried: Preferred first, then the rest.
+func (c *EngineConverter) Engines() []string {
+	order := make([]string, 0, len(c.opts.Engines)+1)
+	if c.opts.Preferred != "" {
+		order = append(order, c.opts.Preferred)
+	}
+	for _, e := range c.opts.Engines {
+		if e != c.opts.Preferred {
+			order = append(order, e)
+		}
+	}
+	return order
+}
+
+// Available returns the installed engines among the given ones (DefaultEngines if none are given).
+func Available(engines ...string) []string {
+	if len(engines) == 0 {
+		engines = DefaultEngines
+	}
+	var found []string
+	for _, e := range engines {
+		if _, err := exec.LookPath(e); err == nil {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// Convert converts the document with the first engine that succeeds, retrying a failed engine
+// Retries times with backoff. Every call works in its own scratch directory, which is removed afterwards.
+// When MaxParallel conversions are already running, the call waits for a free slot or for ctx.
+//
+// If no engine is installed, the error is ErrNoEngine; if all of them failed, it is *ConversionError
+// with the cause of every attempt.
+func (c *EngineConverter) Convert(ctx context.Context, docx []byte) ([]byte, error) {
+	select {
+	case c.slots <- struct{}{}:
+		defer func() { <-c.slots }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	dir, err := os.MkdirTemp(c.opts.TempDir, "docxgen-pdf-*")
+	if err != nil {
+		return nil, fmt.Errorf("pdf: scratch dir: %w", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	src := filepath.Join(dir, "document.docx")
+	dst := filepath.Join(dir, "document.pdf")
+	profile := filepath.Join(dir, "profile")
+	if err := os.WriteFile(src, docx, 0600); err != nil {
+		return nil, fmt.Errorf("pdf: %w", err)
+	}
+
+	var failed []EngineError
+	for _, engine := range c.Engines() {
+		if _, err := exec.LookPath(engine); err != nil {
+			continue
+		}
+		backoff := c.opts.Backoff
+		for attempt := 1; attempt <= c.opts.Retries+1; attempt++ {
+			if attempt > 1 {
+				select {
+				case <-time.After(backoff):
+					backoff *= 2
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			}
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			c.logf("📑  пробуем конвертацию в pdf через: %s", engine)
+			data, err := c.attempt(ctx, engine, src, dst, profile)
+			if err == nil {
+				return data, nil
+			}
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			failed = append(failed, EngineError{Engine: engine, Attempt: attempt, Err: err})
+			c.logf("⚠️  %s: попытка %d: %v", engine, attempt, err)
+		}
+	}
+	if len(failed) == 0 {
+		return nil, ErrNoEngine
+	}
+	return nil, &ConversionError{Attempts: failed}
+}
+
+// attempt runs the engine once and reads its result.
+func (c *EngineConverter) attempt(ctx context.Context, engine, src, dst, profile string) ([]byte, error) {
+	_ = os.Remove(dst)
+	if err := c.run(ctx, engine, src, dst, profile); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(dst)
+	if err != nil || len(data) == 0 {
+		return nil, fmt.Errorf("no output produced")
+	}
+	return data, nil
+}
+
+// run runs one engine with the timeout of the options.
+// LibreOffice engines use the profile directory instead of the shared user profile.
+func (c *EngineConverter) run(ctx context.Context, engine, docx, pdf, profile string) error {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	switch engine {
+	case "soffice", "libreoffice":
+		cmd = exec.CommandContext(ctx, engine,
+			userInstallation(profile),
+			"--headless",
+			"--convert-to", "pdf:writer_pdf_Export",
+			"--outdir", filepath.Dir(pdf),
+			docx,
+		)
+	case "lowriter":
+		cmd = exec.CommandContext(ctx, engine,
+			userInstallation(profile),
+			"--convert-to", "pdf",
+			"--outdir", filepath.Dir(pdf),
+			docx,
+		)
+	case "unoconv":
+		// unoconv требует basename без расширения
+		outNoExt := pdf[:len(pdf)-len(filepath.Ext(pdf))]
+		cmd = exec.CommandContext(ctx, engine, "-f", "pdf", "-o", outNoExt, docx)
+	default:
+		return fmt.Errorf("unknown engine: %s", engine)
+	}
+
+	cmd.Env = scratchEnv(filepath.Dir(pdf))
+	killOnCancel(cmd)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("timeout after %s", c.opts.Timeout)
+		}
+		if msg := lastLine(output.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// waitDelay - how long an engine killed on the timeout may hold its output open: the processes
+// it started keep the pipes until they die, and Wait must not wait for them.
+const waitDelay = 5 * time.Second
+
+// killOnCancel makes the timeout stop the whole engine, not only the process it was started as:
+// soffice is a script that runs soffice.bin, which would go on converting and holding the profile.
+func killOnCancel(cmd *exec.Cmd) {
+	cmd.WaitDelay = waitDelay
+	processGroup(cmd)
+}
+
+// scratchEnv - the environment of an engine run in the scratch directory dir: the home, the caches
+// (fontconfig, Java) and the temporary files of the engine go there, not to the file system
+// of the process, which may be read-only.
+func scratchEnv(dir string) []string {
+	env := os.Environ()
+	for _, name := range []string{"HOME", "TMPDIR", "TMP", "TEMP", "XDG_CACHE_HOME", "XDG_CONFIG_HOME", "XDG_DATA_HOME"} {
+		env = append(env, name+"="+dir)
+	}
+	return env
+}
+
+// lastLine - the last non-empty line of the engine output, usually the cause of the failure.
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	line := strings.TrimSpace(lines[len(lines)-1])
+	if len(line) > 300 {
+		line = line[:300] + "…"
+	}
+	return line
+}
+
+// userInstallation - the LibreOffice option of the user profile location (a file URL).
+func userInstallation(dir string) string {
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(dir)}
+	if !strings.HasPrefix(u.Path, "/") {
+		u.Path = "/" + u.Path // C:/... → file:///C:/...
+	}
+	return "-env:UserInstallation=" + u.String()
+}
+
+func (c *EngineConverter) logf(format string, a ...any) {
+	if c.opts.Log != nil {
+		c.opts.Log(format, a...)
+	}
+}
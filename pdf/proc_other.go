@@ -0,0 +1,8 @@
+//go:build !unix
+
+package pdf
+
+import "os/exec"
+
+// processGroup - without process groups the context kills the engine process only.
+func processGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package pdf
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// processGroup starts the engine in a process group of its own and kills the whole group
+// when the context is done.
+func processGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,545 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"docxgen/geometry"
+
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// StampPages - the pages that get the stamp.
+type StampPages string
+
+const (
+	StampFirstPage StampPages = "first"
+	StampLastPage  StampPages = "last"
+	StampEveryPage StampPages = "all"
+)
+
+// Stamp - a registration stamp drawn over the pages of a ready PDF: a frame with text lines,
+// the date and an optional QR code.
+//   - Lines — text lines ("Вх. № 123-А", "ООО «Ромашка»"); {page} and {pages} in them become
+//     the number of the stamped page and the number of pages ("Лист {page} из {pages}");
+//   - Date — added as the last line in DateFormat (zero — no date; the format by default is 02.01.2006);
+//   - QR — the content of the QR code on the left side of the stamp (empty — no QR);
+//   - Pages — first (by default), last or all;
+//   - Corner — the corner of the page the stamp is attached to: "bottom-right" (by default),
+//     "bottom-left", "top-right", "top-left";
+//   - OffsetX, OffsetY — the distance from the corner (by default 10 mm);
+//   - Width — the width of the stamp (by default 60 mm, at most the width of the page and MaxStampWidth),
+//     the height follows from the content;
+//   - Color — "RRGGBB" of the frame, the text and the QR (by default 1F3F9F, the ink of stamps).
+type Stamp struct {
+	Lines      []string
+	Date       time.Time
+	DateFormat string
+	QR         string
+	Pages      StampPages
+	Corner     string
+	OffsetX    geometry.Length
+	OffsetY    geometry.Length
+	Width      geometry.Length
+	Color      string
+}
+
+// stampDPI - the resolution of the stamp raster.
+const stampDPI = 300
+
+// The limits of a stamp: its raster is drawn in memory at stampDPI.
+const (
+	MaxStampLines   = 20
+	MaxStampLineLen = 200 // characters
+	MaxStampWidth   = 300 * geometry.MM
+)
+
+// ErrStampTooLarge - the stamp is beyond the limits or wider than the page.
+var ErrStampTooLarge = errors.New("pdf: the stamp is too large")
+
+// stampXObject - the name of the stamp image in the page resources.
+const stampXObject = "DxStamp"
+
+// ApplyStamp draws the stamp over the pages of the PDF. The document is not rewritten:
+// the stamp is appended as an incremental update, so the original bytes (and signatures
+// made before the stamp) stay intact.
+func ApplyStamp(doc []byte, s Stamp) ([]byte, error) {
+	s = s.withDefaults()
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	d, err := parseDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := d.pages()
+	if err != nil {
+		return nil, err
+	}
+	if len(pages) == 0 {
+		return nil, errors.New("pdf: the document has no pages")
+	}
+
+	// the numbers of the stamped pages, from 1
+	var stamped []int
+	switch s.Pages {
+	case StampLastPage:
+		stamped = []int{len(pages)}
+	case StampEveryPage:
+		for n := range pages {
+			stamped = append(stamped, n+1)
+		}
+	default:
+		stamped = []int{1}
+	}
+	for _, n := range stamped {
+		if box := pages[n-1].box; s.Width.Pt() > box[2]-box[0] {
+			return nil, fmt.Errorf("%w: %s is wider than page %d", ErrStampTooLarge, s.Width, n)
+		}
+	}
+
+	u := d.newUpdate()
+	// q/Q around the original content: its graphics state may be left changed
+	save := u.add(&pdfStream{dict: newDict(), data: []byte("q")})
+
+	// one image for every different text: the same one for all pages without {page}
+	type stampImage struct {
+		ref    pdfRef
+		height float64
+	}
+	images := map[string]stampImage{}
+	for _, n := range stamped {
+		pg := pages[n-1]
+		lines := s.lines(n, len(pages))
+		key := strings.Join(lines, "\n")
+		im, ok := images[key]
+		if !ok {
+			img, err := s.render(lines)
+			if err != nil {
+				return nil, err
+			}
+			im = stampImage{u.add(imageXObject(u, img)), s.Width.Pt() * float64(img.Bounds().Dy()) / float64(img.Bounds().Dx())}
+			images[key] = im
+		}
+
+		name, err := u.addXObject(pg, im.ref)
+		if err != nil {
+			return nil, err
+		}
+		w, h := s.Width.Pt(), im.height
+		x, y := s.position(pg.box, w, h)
+		ops := fmt.Sprintf("Q\nq %s 0 0 %s %s %s cm /%s Do Q", num(w), num(h), num(x), num(y), name)
+		restore := u.add(&pdfStream{dict: newDict(), data: []byte(ops)})
+
+		contents, err := d.resolve(pg.dict.get("Contents"))
+		if err != nil {
+			return nil, err
+		}
+		var list []any
+		switch c := contents.(type) {
+		case []any:
+			list = c
+		case nil:
+		default:
+			list = []any{pg.dict.get("Contents")}
+		}
+		all := append([]any{save}, list...)
+		pg.dict.set("Contents", append(all, restore))
+		u.replace(pg.ref, pg.dict)
+	}
+	return u.bytes(), nil
+}
+
+// Validate checks the stamp against MaxStampLines, MaxStampLineLen and MaxStampWidth;
+// the error wraps ErrStampTooLarge.
+func (s Stamp) Validate() error {
+	if len(s.Lines) > MaxStampLines {
+		return fmt.Errorf("%w: %d lines, at most %d", ErrStampTooLarge, len(s.Lines), MaxStampLines)
+	}
+	for _, l := range s.Lines {
+		if n := utf8.RuneCountInString(l); n > MaxStampLineLen {
+			return fmt.Errorf("%w: a line of %d characters, at most %d", ErrStampTooLarge, n, MaxStampLineLen)
+		}
+	}
+	if s.Width > MaxStampWidth {
+		return fmt.Errorf("%w: width %s, at most %s", ErrStampTooLarge, s.Width, MaxStampWidth)
+	}
+	return nil
+}
+
+// lines - the text lines of the stamp on the page n of total, the date last.
+func (s Stamp) lines(n, total int) []string {
+	r := strings.NewReplacer("{page}", strconv.Itoa(n), "{pages}", strconv.Itoa(total))
+	lines := make([]string, 0, len(s.Lines)+1)
+	for _, l := range s.Lines {
+		lines = append(lines, r.Replace(l))
+	}
+	if !s.Date.IsZero() {
+		lines = append(lines, s.Date.Format(s.DateFormat))
+	}
+	return lines
+}
+
+func (s Stamp) withDefaults() Stamp {
+	if s.DateFormat == "" {
+		s.DateFormat = "02.01.2006"
+	}
+	if s.Corner == "" {
+		s.Corner = "bottom-right"
+	}
+	if s.OffsetX == 0 {
+		s.OffsetX = 10 * geometry.MM
+	}
+	if s.OffsetY == 0 {
+		s.OffsetY = 10 * geometry.MM
+	}
+	if s.Width <= 0 {
+		s.Width = 60 * geometry.MM
+	}
+	if s.Color == "" {
+		s.Color = "1F3F9F"
+	}
+	return s
+}
+
+// position - the lower left corner of the stamp on the page box [x0 y0 x1 y1] in points.
+func (s Stamp) position(box [4]float64, w, h float64) (x, y float64) {
+	ox, oy := s.OffsetX.Pt(), s.OffsetY.Pt()
+	x, y = box[2]-ox-w, box[1]+oy
+	if strings.Contains(s.Corner, "left") {
+		x = box[0] + ox
+	}
+	if strings.Contains(s.Corner, "top") {
+		y = box[3] - oy - h
+	}
+	return x, y
+}
+
+// render draws the stamp: a frame, the QR code on the left and the text lines on the right.
+func (s Stamp) render(lines []string) (*image.NRGBA, error) {
+	ink, err := parseInk(s.Color)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 && s.QR == "" {
+		return nil, errors.New("pdf: empty stamp")
+	}
+
+	px := func(l geometry.Length) int { return l.Pixels(stampDPI) }
+	width, pad, border := px(s.Width), px(2*geometry.MM), px(geometry.FromMM(0.5))
+
+	qrSize := 0
+	if s.QR != "" {
+		qrSize = px(18 * geometry.MM)
+	}
+	textW := width - 2*pad - 2*border
+	if qrSize > 0 {
+		textW -= qrSize + pad
+	}
+	if textW <= 0 && len(lines) > 0 {
+		return nil, errors.New("pdf: the stamp is too narrow for the text")
+	}
+
+	face, lineH, err := fitFace(lines, textW)
+	if err != nil {
+		return nil, err
+	}
+	textH := lineH * len(lines)
+	height := max(qrSize, textH) + 2*pad + 2*border
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	// a light background keeps the stamp readable over the text of the page
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.NRGBA{255, 255, 255, 230}), image.Point{}, draw.Src)
+	inkU := image.NewUniform(ink)
+	for _, r := range []image.Rectangle{
+		image.Rect(0, 0, width, border), image.Rect(0, height-border, width, height),
+		image.Rect(0, 0, border, height), image.Rect(width-border, 0, width, height),
+	} {
+		draw.Draw(img, r, inkU, image.Point{}, draw.Src)
+	}
+
+	left := border + pad
+	if qrSize > 0 {
+		q, err := qrcode.New(s.QR, qrcode.Medium)
+		if err != nil {
+			return nil, fmt.Errorf("pdf: stamp qr: %w", err)
+		}
+		q.DisableBorder = true
+		bits := q.Bitmap()
+		top := (height - qrSize) / 2
+		for y := 0; y < qrSize; y++ {
+			for x := 0; x < qrSize; x++ {
+				if bits[y*len(bits)/qrSize][x*len(bits)/qrSize] {
+					img.SetNRGBA(left+x, top+y, ink)
+				}
+			}
+		}
+		left += qrSize + pad
+	}
+
+	dr := &font.Drawer{Dst: img, Src: inkU, Face: face}
+	top := (height - textH) / 2
+	for i, line := range lines {
+		dr.Dot = fixed.P(left, top+lineH*i+face.Metrics().Ascent.Ceil())
+		dr.DrawString(line)
+	}
+	return img, nil
+}
+
+// fitFace picks the largest font size (up to 10 pt) at which the longest line fits into the width.
+func fitFace(lines []string, width int) (font.Face, int, error) {
+	f, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		return nil, 0, err
+	}
+	for pt := 10.0; ; pt -= 0.5 {
+		face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: pt, DPI: stampDPI, Hinting: font.HintingFull})
+		if err != nil {
+			return nil, 0, err
+		}
+		widest := 0
+		for _, l := range lines {
+			widest = max(widest, font.MeasureString(face, l).Ceil())
+		}
+		if widest <= width || pt <= 4 {
+			return face, face.Metrics().Height.Ceil() * 6 / 5, nil
+		}
+	}
+}
+
+func parseInk(hex string) (color.NRGBA, error) {
+	hex = strings.TrimPrefix(strings.TrimSpace(hex), "#")
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return color.NRGBA{}, fmt.Errorf("pdf: bad stamp color %q", hex)
+	}
+	return color.NRGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}, nil
+}
+
+// imageXObject - the stamp as an RGB image with its alpha channel in /SMask.
+func imageXObject(u *update, img *image.NRGBA) *pdfStream {
+	b := img.Bounds()
+	rgb := make([]byte, 0, b.Dx()*b.Dy()*3)
+	alpha := make([]byte, 0, b.Dx()*b.Dy())
+	for i := 0; i < len(img.Pix); i += 4 {
+		rgb = append(rgb, img.Pix[i], img.Pix[i+1], img.Pix[i+2])
+		alpha = append(alpha, img.Pix[i+3])
+	}
+
+	imageDict := func(colorSpace string, data []byte) (*pdfDict, []byte) {
+		d := newDict()
+		d.set("Type", pdfName("XObject"))
+		d.set("Subtype", pdfName("Image"))
+		d.set("Width", b.Dx())
+		d.set("Height", b.Dy())
+		d.set("ColorSpace", pdfName(colorSpace))
+		d.set("BitsPerComponent", 8)
+		d.set("Filter", pdfName("FlateDecode"))
+		return d, deflate(data)
+	}
+
+	maskDict, maskData := imageDict("DeviceGray", alpha)
+	mask := u.add(&pdfStream{dict: maskDict, data: maskData})
+	dict, data := imageDict("DeviceRGB", rgb)
+	dict.set("SMask", mask)
+	return &pdfStream{dict: dict, data: data}
+}
+
+func deflate(data []byte) []byte {
+	var buf bytes.Buffer
+	zw, _ := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+	_, _ = zw.Write(data)
+	_ = zw.Close()
+	return buf.Bytes()
+}
+
+func num(f float64) string {
+	return strconv.FormatFloat(f, 'f', 2, 64)
+}
+
+// -------- Pages --------
+
+// page - a leaf of the page tree with the attributes inherited from its parents.
+type page struct {
+	ref       pdfRef
+	dict      *pdfDict
+	box       [4]float64
+	resources any // own or inherited /Resources
+}
+
+// pages lists the pages of the document in order.
+func (d *document) pages() ([]*page, error) {
+	root, err := d.resolve(d.trailer.get("Root"))
+	if err != nil {
+		return nil, err
+	}
+	catalog, ok := root.(*pdfDict)
+	if !ok {
+		return nil, errors.New("pdf: catalog not found")
+	}
+	top, ok := catalog.get("Pages").(pdfRef)
+	if !ok {
+		return nil, errors.New("pdf: page tree not found")
+	}
+
+	var out []*page
+	seen := map[int]bool{}
+	var walk func(r pdfRef, box [4]float64, res any) error
+	walk = func(r pdfRef, box [4]float64, res any) error {
+		if seen[r.num] {
+			return fmt.Errorf("pdf: page tree loop at %d", r.num)
+		}
+		seen[r.num] = true
+		v, err := d.get(r.num)
+		if err != nil {
+			return err
+		}
+		node, ok := v.(*pdfDict)
+		if !ok {
+			return fmt.Errorf("pdf: page node %d is not a dictionary", r.num)
+		}
+		if mb, err := d.resolve(node.get("MediaBox")); err == nil {
+			if b, ok := rect(mb); ok {
+				box = b
+			}
+		}
+		if cb, err := d.resolve(node.get("CropBox")); err == nil {
+			if b, ok := rect(cb); ok {
+				box = b
+			}
+		}
+		if own := node.get("Resources"); own != nil {
+			res = own
+		}
+
+		if node.get("Type") == pdfName("Page") || node.get("Kids") == nil {
+			out = append(out, &page{ref: r, dict: node.clone(), box: box, resources: res})
+			return nil
+		}
+		kids, err := d.resolve(node.get("Kids"))
+		if err != nil {
+			return err
+		}
+		arr, _ := kids.([]any)
+		for _, k := range arr {
+			if kr, ok := k.(pdfRef); ok {
+				if err := walk(kr, box, res); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	// A4 when the document does not say otherwise
+	a4 := [4]float64{0, 0, geometry.A4Width.Pt(), geometry.A4Height.Pt()}
+	return out, walk(top, a4, nil)
+}
+
+// rect reads [x0 y0 x1 y1].
+func rect(v any) ([4]float64, bool) {
+	arr, ok := v.([]any)
+	if !ok || len(arr) != 4 {
+		return [4]float64{}, false
+	}
+	var r [4]float64
+	for i, e := range arr {
+		switch n := e.(type) {
+		case int:
+			r[i] = float64(n)
+		case float64:
+			r[i] = n
+		default:
+			return [4]float64{}, false
+		}
+	}
+	if r[0] > r[2] {
+		r[0], r[2] = r[2], r[0]
+	}
+	if r[1] > r[3] {
+		r[1], r[3] = r[3], r[1]
+	}
+	return r, true
+}
+
+// addXObject registers the image in the resources of the page and returns its name there.
+// Resources and XObject dictionaries shared through references get a new version;
+// inherited inline resources are copied into the page.
+func (u *update) addXObject(pg *page, img pdfRef) (string, error) {
+	d := u.doc
+
+	var res *pdfDict
+	resRef, resIsRef := pg.resources.(pdfRef)
+	if resIsRef {
+		v, err := d.get(resRef.num)
+		if err != nil {
+			return "", err
+		}
+		if r, ok := v.(*pdfDict); ok {
+			res = r.clone()
+		}
+	} else if r, ok := pg.resources.(*pdfDict); ok {
+		res = r.clone()
+	}
+	if res == nil {
+		res = newDict()
+		resIsRef = false
+	}
+
+	var xo *pdfDict
+	xoRef, xoIsRef := res.get("XObject").(pdfRef)
+	if xoIsRef {
+		v, err := d.get(xoRef.num)
+		if err != nil {
+			return "", err
+		}
+		if x, ok := v.(*pdfDict); ok {
+			xo = x.clone()
+		}
+	} else if x, ok := res.get("XObject").(*pdfDict); ok {
+		xo = x.clone()
+	}
+	if xo == nil {
+		xo = newDict()
+		xoIsRef = false
+	}
+
+	// the same image may already be there (a page sharing resources with a stamped one)
+	name := stampXObject
+	for i := 1; ; i++ {
+		cur, exists := xo.vals[name]
+		if !exists {
+			break
+		}
+		if cur == img {
+			return name, nil
+		}
+		name = stampXObject + strconv.Itoa(i)
+	}
+	xo.set(name, img)
+
+	if xoIsRef {
+		u.replace(xoRef, xo)
+	} else {
+		res.set("XObject", xo)
+	}
+	if resIsRef {
+		u.replace(resRef, res)
+	} else {
+		pg.dict.set("Resources", res)
+	}
+	return name, nil
+}
@@ -0,0 +1,50 @@
+package docxgen
+
+// Stage - a point of the ExecuteTemplate pipeline where application steps are called.
+//
+// The pipeline of every part (document, headers, footers):
+//
+//	RepairTags → ConvertMergeFields → [StageBeforeIncludes] → ResolveIncludes → ResolveAnnexes → ResolveBlocks →
+//	ResolveTables → ResolvePivots → ResolveCalendars → ResolveGantts → ResolveOrgCharts → ResolveSigners →
+//	ResolveRequisites → RepairTags → [StageAfterTables] → ProcessUnWrapParagraphTags → ProcessTrimTags →
+//	TransformTemplate → execution → [StageAfterExecute] → UpdateContentPart
+type Stage int
+
+const (
+	// StageBeforeIncludes - the XML of the part with repaired tags, before [include/...] and smart tables.
+	StageBeforeIncludes Stage = iota
+	// StageAfterTables - after includes and smart tables, before the tags are transformed into a Go template.
+	StageAfterTables
+	// StageAfterExecute - the resulting XML of the part after the template has been executed.
+	StageAfterExecute
+)
+
+// ProcessFunc - an application processing step: receives the part name ("document", "header1", ...)
+// and its XML, returns the new XML.
+type ProcessFunc func(part, xml string) string
+
+// Use registers a processing step at the stage of ExecuteTemplate.
+// Steps of one stage are called in the order of registration.
+//
+// Example — drop the draft marks before the template is built:
+//
+//	doc.Use(docxgen.StageAfterTables, func(part, xml string) string {
+//		return strings.ReplaceAll(xml, "[draft]", "")
+//	})
+func (d *Docx) Use(stage Stage, fn ProcessFunc) {
+	if fn == nil {
+		return
+	}
+	if d.middleware == nil {
+		d.middleware = make(map[Stage][]ProcessFunc)
+	}
+	d.middleware[stage] = append(d.middleware[stage], fn)
+}
+
+// runStage passes the XML of the part through the steps of the stage.
+func (d *Docx) runStage(stage Stage, part, xml string) string {
+	for _, fn := range d.middleware[stage] {
+		xml = fn(part, xml)
+	}
+	return xml
+}
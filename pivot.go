@@ -0,0 +1,289 @@
+package docxgen
+
+import (
+	"docxgen/modifiers"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ============================================================================
+// [pivot/name rows:field cols:field value:sum(field)] — a cross-tab of flat records
+// ============================================================================
+
+// pivotOpenPrefix - the start of the pivot marker.
+const pivotOpenPrefix = "[pivot/"
+
+// pivotSpec - the options of the [pivot/name ...] marker.
+//   - rows, cols — the fields whose values make the rows and the columns (cols is optional);
+//   - agg, field — the aggregate (sum, count, avg, min, max) and the field it takes;
+//   - total — the label of the totals row and column, "" — no totals;
+//   - format — a modifier chain for the numbers: format:money → {`1234.5`|money}.
+type pivotSpec struct {
+	name, rows, cols string
+	agg, field       string
+	total            string
+	format           string
+}
+
+// parsePivotSpec reads "sales rows:region cols:month value:sum(amount) total:Всего format:money".
+func parsePivotSpec(spec string) (pivotSpec, error) {
+	p := pivotSpec{agg: "count", total: "Итого"}
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return p, fmt.Errorf("no data key")
+	}
+	p.name = fields[0]
+	for _, f := range fields[1:] {
+		key, val, _ := strings.Cut(f, ":")
+		if k, v, ok := strings.Cut(f, "="); ok && !strings.Contains(k, ":") {
+			key, val = k, v
+		}
+		switch strings.ToLower(key) {
+		case "rows":
+			p.rows = val
+		case "cols":
+			p.cols = val
+		case "value":
+			agg, field, _ := strings.Cut(strings.TrimSuffix(val, ")"), "(")
+			p.agg, p.field = strings.ToLower(agg), field
+		case "total":
+			p.total = val
+			if strings.EqualFold(val, "none") || val == "-" {
+				p.total = ""
+			}
+		case "format":
+			p.format = val
+		}
+	}
+	switch {
+	case p.rows == "":
+		return p, fmt.Errorf("no rows:field")
+	case p.agg != "count" && p.agg != "sum" && p.agg != "avg" && p.agg != "min" && p.agg != "max":
+		return p, fmt.Errorf("unknown aggregate %q, want sum, count, avg, min or max", p.agg)
+	case p.agg != "count" && p.field == "":
+		return p, fmt.Errorf("%s() needs a field", p.agg)
+	}
+	return p, nil
+}
+
+// ResolvePivots replaces the paragraphs with [pivot/name rows:a cols:b value:sum(c)] by a cross-tab of the
+// records of data[name]: a row per value of a, a column per value of b (in the order they appear in
+// the data), and the aggregate of c in the cells. The totals row and column aggregate the records
+// themselves (an avg total is the average of all the records, not of the cells).
+//
+// Aggregates: sum, count (count without a field counts the records), avg, min, max; the sums are exact
+// decimals. A record without a number in the field counts for count only. Empty cells stay empty.
+// Without data or with a broken marker the paragraph is removed with the empty_pivot warning.
+func (d *Docx) ResolvePivots(body string, data map[string]any) string {
+	return replaceMarkerParagraphs(body, pivotOpenPrefix, func(spec string) string {
+		return d.renderPivot(spec, data)
+	})
+}
+
+// renderPivot builds the table of one marker; "" — nothing to show (a warning is issued).
+func (d *Docx) renderPivot(specText string, data map[string]any) string {
+	spec, err := parsePivotSpec(specText)
+	if err != nil {
+		d.warn(WarnEmptyPivot, specText, "%v", err)
+		return ""
+	}
+	raw := blockValue(data, spec.name)
+	if raw == nil {
+		d.warn(WarnEmptyPivot, spec.name, "no data for the pivot table, removed")
+		return ""
+	}
+	items, ok := normalizeItems(raw)
+	if !ok || len(items) == 0 {
+		d.warn(WarnEmptyPivot, spec.name, "the data is %T, not a list of records", raw)
+		return ""
+	}
+	if !d.takeRows("pivot "+spec.name, len(items)) {
+		return ""
+	}
+
+	// the cells, the totals of the rows and the columns and the grand total
+	var rowKeys, colKeys []string
+	seenRow, seenCol := map[string]bool{}, map[string]bool{}
+	cells := map[[2]string]*pivotAcc{}
+	rowTotals, colTotals := map[string]*pivotAcc{}, map[string]*pivotAcc{}
+	grand := &pivotAcc{}
+	acc := func(m map[string]*pivotAcc, k string) *pivotAcc {
+		if m[k] == nil {
+			m[k] = &pivotAcc{}
+		}
+		return m[k]
+	}
+
+	for _, it := range items {
+		rec := normalizeItem(it).mapVal
+		if rec == nil {
+			continue
+		}
+		r := modifiers.ValueText(blockValue(rec, spec.rows))
+		c := ""
+		if spec.cols != "" {
+			c = modifiers.ValueText(blockValue(rec, spec.cols))
+		}
+		if !seenRow[r] {
+			seenRow[r] = true
+			rowKeys = append(rowKeys, r)
+		}
+		if !seenCol[c] {
+			seenCol[c] = true
+			colKeys = append(colKeys, c)
+		}
+		var v any
+		if spec.field != "" {
+			v = blockValue(rec, spec.field)
+		}
+		key := [2]string{r, c}
+		if cells[key] == nil {
+			cells[key] = &pivotAcc{}
+		}
+		for _, a := range []*pivotAcc{cells[key], acc(rowTotals, r), acc(colTotals, c), grand} {
+			a.add(v)
+		}
+	}
+	if len(rowKeys) == 0 {
+		d.warn(WarnEmptyPivot, spec.name, "no records with fields")
+		return ""
+	}
+
+	// -------- The table: a header row, a row per key, the totals row --------
+	header := []string{cellText(spec.rows, true, false)}
+	if spec.cols == "" {
+		header = append(header, cellText(spec.valueLabel(), true, false))
+	} else {
+		for _, c := range colKeys {
+			header = append(header, cellText(c, true, false))
+		}
+		if spec.total != "" {
+			header = append(header, cellText(spec.total, true, false))
+		}
+	}
+	rows := [][]string{header}
+
+	for _, r := range rowKeys {
+		row := []string{cellText(r, false, false)}
+		for _, c := range colKeys {
+			row = append(row, spec.numberCell(cells[[2]string{r, c}], false))
+		}
+		if spec.cols != "" && spec.total != "" {
+			row = append(row, spec.numberCell(rowTotals[r], true))
+		}
+		rows = append(rows, row)
+	}
+
+	if spec.total != "" {
+		row := []string{cellText(spec.total, true, false)}
+		for _, c := range colKeys {
+			row = append(row, spec.numberCell(colTotals[c], true))
+		}
+		if spec.cols != "" {
+			row = append(row, spec.numberCell(grand, true))
+		}
+		rows = append(rows, row)
+	}
+
+	d.emit(TableRendered{Part: d.activePart, Name: spec.name, Rows: len(items)})
+	return gridTable(rows, d.CurrentPageLayout().UsableWidth().Twips())
+}
+
+// valueLabel - the header of the only value column: "sum(amount)".
+func (p pivotSpec) valueLabel() string {
+	if p.field == "" {
+		return p.agg
+	}
+	return p.agg + "(" + p.field + ")"
+}
+
+// numberCell - the cell of an aggregate, right-aligned; with format the number goes through the modifiers.
+func (p pivotSpec) numberCell(a *pivotAcc, bold bool) string {
+	text := a.result(p.agg)
+	if text != "" && p.format != "" {
+		return cellXML("{`"+text+"`|"+p.format+"}", bold, true)
+	}
+	return cellText(text, bold, true)
+}
+
+// cellText - a paragraph of a generated table cell with the text escaped.
+func cellText(text string, bold, right bool) string {
+	return cellXML(xmlEscape(text), bold, right)
+}
+
+func cellXML(text string, bold, right bool) string {
+	var b strings.Builder
+	b.WriteString("<w:p>")
+	if right {
+		b.WriteString(`<w:pPr><w:jc w:val="right"/></w:pPr>`)
+	}
+	if text == "" {
+		b.WriteString("</w:p>")
+		return b.String()
+	}
+	b.WriteString("<w:r>")
+	if bold {
+		b.WriteString("<w:rPr><w:b/></w:rPr>")
+	}
+	b.WriteString(`<w:t xml:space="preserve">` + text + `</w:t></w:r></w:p>`)
+	return b.String()
+}
+
+// pivotAcc - the aggregate of the records of one cell.
+//   - records — all the records (count);
+//   - numbers, sum, min, max — the records with a number in the field.
+type pivotAcc struct {
+	records, numbers int
+	sum, min, max    *big.Rat
+}
+
+func (a *pivotAcc) add(v any) {
+	a.records++
+	r, ok := modifiers.Decimal(v)
+	if !ok {
+		return
+	}
+	a.numbers++
+	if a.sum == nil {
+		a.sum, a.min, a.max = new(big.Rat), new(big.Rat).Set(r), new(big.Rat).Set(r)
+	}
+	a.sum.Add(a.sum, r)
+	if r.Cmp(a.min) < 0 {
+		a.min.Set(r)
+	}
+	if r.Cmp(a.max) > 0 {
+		a.max.Set(r)
+	}
+}
+
+// result - the text of the aggregate; "" for a cell without records or numbers.
+func (a *pivotAcc) result(agg string) string {
+	if a == nil || a.records == 0 {
+		return ""
+	}
+	if agg == "count" {
+		return fmt.Sprint(a.records)
+	}
+	if a.numbers == 0 {
+		return ""
+	}
+	switch agg {
+	case "avg":
+		return ratText(new(big.Rat).Quo(a.sum, big.NewRat(int64(a.numbers), 1)))
+	case "min":
+		return ratText(a.min)
+	case "max":
+		return ratText(a.max)
+	}
+	return ratText(a.sum)
+}
+
+// ratText - the decimal text of the number without trailing zeros, at most 6 decimals.
+func ratText(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	s := strings.TrimRight(r.FloatString(6), "0")
+	return strings.TrimSuffix(s, ".")
+}
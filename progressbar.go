@@ -0,0 +1,136 @@
+package docxgen
+
+import (
+	"docxgen/geometry"
+	"docxgen/modifiers"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// progressThreshold - the color of the bar starting from the percentage.
+type progressThreshold struct {
+	from  float64
+	color string
+}
+
+// defaultProgressThresholds - red, amber and green zones.
+var defaultProgressThresholds = []progressThreshold{
+	{from: 0, color: "C62828"},
+	{from: 50, color: "F9A825"},
+	{from: 80, color: "2E7D32"},
+}
+
+// ProgressBar - Inserts a horizontal progress bar for a percentage.
+// The parameters are described in modifiers.ProgressBar.
+func (d *Docx) ProgressBar(value any, opts ...string) modifiers.RawXML {
+	pct, ok := parsePercent(value)
+	if !ok {
+		return ""
+	}
+
+	// ---------- Default parameters ----------
+	width, height := geometry.FromMM(40), geometry.FromMM(3)
+	track := "EEEEEE"
+	fixed := ""
+	var thresholds []progressThreshold
+
+	// ---------- Parsing options ----------
+	for _, token := range opts {
+		token = strings.TrimSpace(token)
+		lower := strings.ToLower(token)
+		switch {
+		case lower == "":
+			continue
+		case strings.HasPrefix(lower, "bg="):
+			if lower == "bg=none" {
+				track = ""
+			} else if hex, ok := modifiers.ParseColor(token[3:]); ok {
+				track = hex
+			}
+		case strings.Contains(lower, "="):
+			from, col, _ := strings.Cut(token, "=")
+			f, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(from), "%"), 64)
+			hex, ok := modifiers.ParseColor(col)
+			if err == nil && ok {
+				thresholds = append(thresholds, progressThreshold{from: f, color: hex})
+			}
+		case strings.HasSuffix(lower, "mm"):
+			if w, h, err := geometry.ParseSize(lower, 0, 0); err == nil && w > 0 {
+				width = w
+				if h > 0 {
+					height = h
+				}
+			}
+		default:
+			if hex, ok := modifiers.ParseColor(token); ok {
+				fixed = hex
+			}
+		}
+	}
+
+	barColor := fixed
+	if barColor == "" {
+		if len(thresholds) == 0 {
+			thresholds = defaultProgressThresholds
+		}
+		barColor = thresholdColor(thresholds, pct)
+	}
+
+	w, h := width.Pixels(rasterDPI), height.Pixels(rasterDPI)
+	if !d.takeRaster("progressbar", w, h) {
+		return ""
+	}
+	img := renderProgressBar(pct, hexToRGBA(barColor), track, w, h)
+	data, err := encodePNG(img)
+	if err != nil {
+		return ""
+	}
+	rId, base := d.AddImageRel(data)
+
+	id, name := d.nextDrawing(base)
+	drawing := inlinePictureXML(id, rId, name, width.EMU(), height.EMU())
+	return modifiers.RawXML(runBreakout(drawing))
+}
+
+// renderProgressBar draws the track and the filled part.
+func renderProgressBar(pct float64, bar color.RGBA, track string, w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	if track != "" {
+		draw.Draw(img, img.Bounds(), image.NewUniform(hexToRGBA(track)), image.Point{}, draw.Src)
+	}
+	filled := int(math.Round(float64(w) * pct / 100))
+	if filled > 0 {
+		draw.Draw(img, image.Rect(0, 0, filled, h), image.NewUniform(bar), image.Point{}, draw.Src)
+	}
+	return img
+}
+
+// thresholdColor picks the color of the highest threshold not greater than pct.
+func thresholdColor(thresholds []progressThreshold, pct float64) string {
+	sorted := append([]progressThreshold(nil), thresholds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].from < sorted[j].from })
+	c := sorted[0].color
+	for _, t := range sorted {
+		if pct >= t.from {
+			c = t.color
+		}
+	}
+	return c
+}
+
+// parsePercent reads a percentage ("60", "60%", "60,5", 60) and clamps it to 0..100.
+func parsePercent(v any) (float64, bool) {
+	s := strings.TrimSpace(fmt.Sprint(v))
+	s = strings.ReplaceAll(strings.TrimSuffix(s, "%"), ",", ".")
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, false
+	}
+	return math.Max(0, math.Min(100, f)), true
+}
@@ -1,78 +1,83 @@
 package docxgen
 
 import (
+	"bytes"
+	"docxgen/geometry"
 	"docxgen/modifiers"
 	"fmt"
+	"image"
+	"image/color"
 	"strconv"
 	"strings"
 
 	"github.com/skip2/go-qrcode"
+	"golang.org/x/image/draw"
 )
 
-// QrCode — output QR code by parameters
-func (d *Docx) QrCode(value string, opts ...string) modifiers.RawXML {
-	const emuPerMM = 36000
+// qrLevels - the error correction levels of ec:L|M|Q|H (7, 15, 25 and 30% of the code may be lost).
+var qrLevels = map[string]qrcode.RecoveryLevel{
+	"l": qrcode.Low, "m": qrcode.Medium, "q": qrcode.High, "h": qrcode.Highest,
+}
 
+// qrLogoShare - the width of the center logo, a share of the code width; with ec:H up to 30%
+// of the modules may be covered, the logo with its pad takes about 8% of the area.
+const qrLogoShare = 0.22
+
+// QrCode — output QR code by parameters.
+//
+// Besides the placement options of the picture (anchor/inline, wrapping, left/center/right, size, crop, "5/5"):
+//   - ec:L|M|Q|H — the error correction level, M by default, H with a logo;
+//   - fg:RRGGBB, bg:RRGGBB — the colors of the modules and of the background (hex or a color name);
+//   - logo:value — a picture over the center: a path next to the template, base64, a data URI or an URL.
+//
+// A logo that cannot be loaded is skipped with the bad_image warning, the code is still output.
+func (d *Docx) QrCode(value string, opts ...string) modifiers.RawXML {
 	if value == "" {
 		return ""
 	}
 
 	// -------- Default values ----------
 	mode := "anchor"
-	sizeMM := 32.0
+	size := geometry.FromMM(32)
 	crop := 4.0
 	align := "right"
 	valign := "top"
-	distT, distB, distL, distR := 0, 0, 0, 0
+	var dist geometry.Sides
 	hasBorder := false
+	wrap := defaultAnchorWrap
+	level, levelSet := qrcode.Medium, false
+	var fg, bg color.Color = color.Black, color.White
+	logo := ""
 
 	// -------- Parse the parameters ----------
 	for _, token := range opts {
 		token = strings.TrimSpace(token)
+		key, val, _ := strings.Cut(token, ":")
 		switch {
+		case strings.EqualFold(key, "ec"):
+			if l, ok := qrLevels[strings.ToLower(strings.TrimSpace(val))]; ok {
+				level, levelSet = l, true
+			}
+		case strings.EqualFold(key, "fg"), strings.EqualFold(key, "bg"):
+			if hex, ok := modifiers.ParseColor(val); ok {
+				if strings.EqualFold(key, "fg") {
+					fg = hexToRGBA(hex)
+				} else {
+					bg = hexToRGBA(hex)
+				}
+			}
+		case strings.EqualFold(key, "logo"):
+			logo = strings.TrimSpace(val)
 		case token == "anchor" || token == "inline":
 			mode = token
+		case parseWrapToken(token, &wrap):
+			// wrapping and z-order of the anchor
 		case strings.HasSuffix(token, "%"):
 			crop, _ = strconv.ParseFloat(strings.TrimSuffix(token, "%"), 64)
 		case strings.Contains(token, "/"):
-			parts := strings.Split(token, "/")
-			switch len(parts) {
-			case 2:
-				// top/bottom = parts[0], left/right = parts[1]
-				if v, err := strconv.ParseFloat(parts[0], 64); err == nil {
-					distT = int(v * emuPerMM)
-					distB = distT
-				}
-				if v, err := strconv.ParseFloat(parts[1], 64); err == nil {
-					distL = int(v * emuPerMM)
-					distR = distL
-				}
-			case 3:
-				// top, left/right, bottom
-				if v, err := strconv.ParseFloat(parts[0], 64); err == nil {
-					distT = int(v * emuPerMM)
-				}
-				if v, err := strconv.ParseFloat(parts[1], 64); err == nil {
-					distL = int(v * emuPerMM)
-					distR = distL
-				}
-				if v, err := strconv.ParseFloat(parts[2], 64); err == nil {
-					distB = int(v * emuPerMM)
-				}
-			case 4:
-				// top, right, bottom, left
-				if v, err := strconv.ParseFloat(parts[0], 64); err == nil {
-					distT = int(v * emuPerMM)
-				}
-				if v, err := strconv.ParseFloat(parts[1], 64); err == nil {
-					distR = int(v * emuPerMM)
-				}
-				if v, err := strconv.ParseFloat(parts[2], 64); err == nil {
-					distB = int(v * emuPerMM)
-				}
-				if v, err := strconv.ParseFloat(parts[3], 64); err == nil {
-					distL = int(v * emuPerMM)
-				}
+			// top/bottom, left/right — see geometry.ParseSides
+			if sides, err := geometry.ParseSides(token); err == nil {
+				dist = sides
 			}
 		case token == "left" || token == "center" || token == "right":
 			align = token
@@ -84,99 +89,83 @@ func (d *Docx) QrCode(value string, opts ...string) modifiers.RawXML {
 		case token == "border":
 			hasBorder = true
 		default:
-			if v, err := strconv.ParseFloat(strings.TrimSuffix(token, "mm"), 64); err == nil {
-				sizeMM = v
+			if v, err := geometry.ParseLength(token, 0); err == nil && v > 0 {
+				size = v
 			}
 		}
 	}
 
 	// -------- generate QR --------
-	sizePx := int(sizeMM / 25.4 * 96)
-	data, err := qrcode.Encode(value, qrcode.Medium, sizePx)
+	if logo != "" && !levelSet {
+		level = qrcode.Highest // the logo hides modules, the code has to survive it
+	}
+	sizePx := size.Pixels(96)
+	q, err := qrcode.New(value, level)
+	if err != nil {
+		return modifiers.RawXML(fmt.Sprintf("<w:p><w:t>QR error: %v</w:t></w:p>", err))
+	}
+	q.ForegroundColor, q.BackgroundColor = fg, bg
+	img := q.Image(sizePx)
+	if logo != "" {
+		if img, err = d.overlayQrLogo(img, logo, bg); err != nil {
+			d.warn(WarnBadImage, shortSource(logo), "QR logo: %v", err)
+			img = q.Image(sizePx)
+		}
+	}
+	data, err := encodePNG(img)
 	if err != nil {
 		return modifiers.RawXML(fmt.Sprintf("<w:p><w:t>QR error: %v</w:t></w:p>", err))
 	}
 
 	rId, base := d.AddImageRel(data)
+	id, name := d.nextDrawing(base)
 
 	// -------- Translation to EMU --------
+	cx := size.EMU()
+	drawing := pictureDrawing{
+		id: id, rId: rId, name: name,
+		cx: cx, cy: cx,
+		inline: mode == "inline",
+		align:  align, valign: valign,
+		dist: dist, wrap: wrap,
+		crop: crop, border: hasBorder,
+	}.xml()
 
-	cx := int(sizeMM * emuPerMM)
-	cy := cx
-	cropVal := int(crop * 1000)
+	// -------- Leaving the paragraph  --------
+	xml := "</w:t></w:r><w:r>" + drawing + strings.TrimPrefix(modifiers.RunReopen, "</w:t>")
 
-	// -------- crop section --------
-	cropXML := ""
-	if crop > 0 {
-		cropXML = fmt.Sprintf(`<a:srcRect l="%d" t="%d" r="%d" b="%d"/>`, cropVal, cropVal, cropVal, cropVal)
-	}
+	return modifiers.RawXML(xml)
+}
 
-	borderXML := ""
-	if hasBorder {
-		borderXML = `<a:ln w="12700"><a:solidFill><a:srgbClr val="000000"/></a:solidFill></a:ln>`
+// overlayQrLogo draws the logo over the center of the code, on a pad of the background color,
+// scaled to qrLogoShare of the code width with its aspect ratio kept.
+func (d *Docx) overlayQrLogo(code image.Image, src string, bg color.Color) (image.Image, error) {
+	data, err := d.loadImage(src)
+	if err != nil {
+		return nil, err
 	}
-
-	// -------- common piece <pic:pic> --------
-	pic := fmt.Sprintf(`
-<pic:pic xmlns:pic="http://schemas.openxmlformats.org/drawingml/2006/picture">
-  <pic:nvPicPr>
-    <pic:cNvPr id="1" name="%s"/>
-    <pic:cNvPicPr><a:picLocks noChangeAspect="1" noChangeArrowheads="1"/></pic:cNvPicPr>
-  </pic:nvPicPr>
-  <pic:blipFill>
-    <a:blip r:embed="%s" cstate="print"/>
-    %s
-    <a:stretch><a:fillRect/></a:stretch>
-  </pic:blipFill>
-  <pic:spPr bwMode="auto">
-    <a:xfrm><a:off x="0" y="0"/><a:ext cx="%d" cy="%d"/></a:xfrm>
-    <a:prstGeom prst="rect"><a:avLst/></a:prstGeom>
-    <a:noFill/>%s
-  </pic:spPr>
-</pic:pic>`, base, rId, cropXML, cx, cy, borderXML)
-
-	// -------- branch inline / anchor --------
-	var drawing string
-
-	if mode == "inline" {
-		drawing = fmt.Sprintf(`
-<w:drawing xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
-  <wp:inline distT="0" distB="0" distL="0" distR="0">
-    <wp:extent cx="%d" cy="%d"/>
-    <wp:effectExtent l="0" t="0" r="0" b="0"/>
-    <wp:docPr id="1" name="%s"/>
-    <wp:cNvGraphicFramePr>
-      <a:graphicFrameLocks xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" noChangeAspect="1"/>
-    </wp:cNvGraphicFramePr>
-    <a:graphic xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main">
-      <a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/picture">%s</a:graphicData>
-    </a:graphic>
-  </wp:inline>
-</w:drawing>`, cx, cy, base, pic)
-	} else { // anchor (default)
-		drawing = fmt.Sprintf(`
-<w:drawing xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
-  <wp:anchor behindDoc="0" distT="%d" distB="%d" distL="%d" distR="%d" 
-	simplePos="0" locked="0" layoutInCell="0" allowOverlap="1" relativeHeight="2">
-	<wp:simplePos x="0" y="0"/>
-    <wp:positionH relativeFrom="column"><wp:align>%s</wp:align></wp:positionH>
-    <wp:positionV relativeFrom="paragraph"><wp:align>%s</wp:align></wp:positionV>
-    <wp:extent cx="%d" cy="%d"/>
-    <wp:effectExtent l="0" t="0" r="0" b="0"/>
-    <wp:wrapSquare wrapText="bothSides"/>
-    <wp:docPr id="1" name="%s"/>
-    <wp:cNvGraphicFramePr>
-      <a:graphicFrameLocks xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" noChangeAspect="1"/>
-    </wp:cNvGraphicFramePr>
-    <a:graphic xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main">
-      <a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/picture">%s</a:graphicData>
-    </a:graphic>
-  </wp:anchor>
-</w:drawing>`, distT, distB, distL, distR, align, valign, cx, cy, base, pic)
+	logo, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("not a picture: %w", err)
 	}
 
-	// -------- Leaving the paragraph  --------
-	xml := fmt.Sprintf("</w:t></w:r><w:r>%s</w:r><w:r><w:t>", drawing)
+	b := code.Bounds()
+	out := image.NewRGBA(b)
+	draw.Draw(out, b, code, b.Min, draw.Src)
 
-	return modifiers.RawXML(xml)
+	lb := logo.Bounds()
+	if lb.Dx() == 0 || lb.Dy() == 0 {
+		return nil, fmt.Errorf("empty picture")
+	}
+	box := int(float64(b.Dx()) * qrLogoShare)
+	w, h := box, box*lb.Dy()/lb.Dx()
+	if lb.Dy() > lb.Dx() {
+		w, h = box*lb.Dx()/lb.Dy(), box
+	}
+	cx, cy := b.Min.X+b.Dx()/2, b.Min.Y+b.Dy()/2
+	pad := box / 10
+	padRect := image.Rect(cx-w/2-pad, cy-h/2-pad, cx+w/2+pad, cy+h/2+pad)
+	draw.Draw(out, padRect, image.NewUniform(bg), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(out, image.Rect(cx-w/2, cy-h/2, cx-w/2+w, cy-h/2+h), logo, lb, draw.Over, nil)
+	return out, nil
 }
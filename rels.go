@@ -0,0 +1,304 @@
+package docxgen
+
+import (
+	"crypto/sha1"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Relationship types used by the generator.
+const (
+	RelTypeImage     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/image"
+	RelTypeHyperlink = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink"
+	RelTypeHeader    = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/header"
+	RelTypeFooter    = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/footer"
+
+	relsNamespace = "http://schemas.openxmlformats.org/package/2006/relationships"
+)
+
+// Relationship - one <Relationship> of a .rels part.
+type Relationship struct {
+	ID         string `xml:"Id,attr"`
+	Type       string `xml:"Type,attr"`
+	Target     string `xml:"Target,attr"`
+	TargetMode string `xml:"TargetMode,attr,omitempty"`
+}
+
+// External reports whether the relationship points outside the package (hyperlinks).
+func (r Relationship) External() bool {
+	return r.TargetMode == "External"
+}
+
+type relationships struct {
+	XMLName xml.Name       `xml:"Relationships"`
+	XMLNS   string         `xml:"xmlns,attr,omitempty"`
+	Items   []Relationship `xml:"Relationship"`
+}
+
+// RelationshipManager - reading and editing the relationships (word/_rels/*.xml.rels) of the document parts.
+// The files of the document remain the only storage: every call reads the current .rels,
+// every change writes it back, so the manager can be taken at any moment via Docx.Rels.
+//
+// The part is named as in ContentPart: "document", "header1", "footer2" or the full path "word/document.xml".
+type RelationshipManager struct {
+	d *Docx
+}
+
+// Rels returns the relationship manager of the document.
+func (d *Docx) Rels() *RelationshipManager {
+	return &RelationshipManager{d: d}
+}
+
+// List returns all relationships of the part (nil if the part has no .rels).
+func (m *RelationshipManager) List(part string) []Relationship {
+	rels, _ := m.read(part)
+	return rels.Items
+}
+
+// Lookup finds the relationship by id.
+func (m *RelationshipManager) Lookup(part, id string) (Relationship, bool) {
+	for _, r := range m.List(part) {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return Relationship{}, false
+}
+
+// LookupTarget finds the relationship of the given type by its target ("media/x.png", "header1.xml", URL).
+func (m *RelationshipManager) LookupTarget(part, relType, target string) (Relationship, bool) {
+	for _, r := range m.List(part) {
+		if r.Type == relType && r.Target == target {
+			return r, true
+		}
+	}
+	return Relationship{}, false
+}
+
+// Add adds a relationship to the part and returns its id.
+// An existing relationship with the same id, or with the same type and target, is reused.
+// If rel.ID is empty, a free "rIdN" is assigned.
+func (m *RelationshipManager) Add(part string, rel Relationship) string {
+	rels, _ := m.read(part)
+	for _, r := range rels.Items {
+		if (rel.ID != "" && r.ID == rel.ID) || (rel.ID == "" && r.Type == rel.Type && r.Target == rel.Target) {
+			return r.ID
+		}
+	}
+	if rel.ID == "" {
+		rel.ID = freeRelID(rels.Items)
+	}
+	rels.Items = append(rels.Items, rel)
+	m.write(part, rels)
+	return rel.ID
+}
+
+// AddImage adds an image relationship (target relative to word/, e.g. "media/logo.png").
+func (m *RelationshipManager) AddImage(part, target string) string {
+	return m.Add(part, Relationship{Type: RelTypeImage, Target: target})
+}
+
+// AddHyperlink adds an external hyperlink relationship.
+func (m *RelationshipManager) AddHyperlink(part, url string) string {
+	return m.Add(part, Relationship{Type: RelTypeHyperlink, Target: url, TargetMode: "External"})
+}
+
+// Remove removes the relationship by id. Returns false if there was none.
+func (m *RelationshipManager) Remove(part, id string) bool {
+	removed := false
+	m.Rewrite(part, func(r Relationship) (Relationship, bool) {
+		if r.ID == id {
+			removed = true
+			return r, false
+		}
+		return r, true
+	})
+	return removed
+}
+
+// Rewrite passes every relationship of the part through fn: the returned value replaces it,
+// keep=false removes it. The .rels is written only if something has changed.
+func (m *RelationshipManager) Rewrite(part string, fn func(Relationship) (rel Relationship, keep bool)) {
+	rels, ok := m.read(part)
+	if !ok {
+		return
+	}
+	changed := false
+	items := rels.Items[:0:0]
+	for _, r := range rels.Items {
+		nr, keep := fn(r)
+		if !keep || nr != r {
+			changed = true
+		}
+		if keep {
+			items = append(items, nr)
+		}
+	}
+	if changed {
+		rels.Items = items
+		m.write(part, rels)
+	}
+}
+
+// ResolveTarget returns the path of the relationship target inside the package ("word/media/x.png").
+// External targets are returned as is.
+func (m *RelationshipManager) ResolveTarget(part string, r Relationship) string {
+	if r.External() {
+		return r.Target
+	}
+	if strings.HasPrefix(r.Target, "/") {
+		return strings.TrimPrefix(r.Target, "/")
+	}
+	return path.Join(path.Dir(partPath(part)), r.Target)
+}
+
+// reRelRef - references to relationships from the part XML (r:embed, r:id, r:link, r:pict ...).
+var reRelRef = regexp.MustCompile(`\br:(?:embed|id|link|pict)="([^"]+)"`)
+
+// ImportFragment copies the relationships that an XML fragment of another document refers to
+// (images with their media files, charts with their workbooks, hyperlinks) into the part of this document
+// and returns the fragment with the rewritten r:ids. Used by [include/...] and AppendDocument.
+func (m *RelationshipManager) ImportFragment(src *Docx, srcPart, part, fragment string) string {
+	srcRels := src.Rels()
+	ids := map[string]string{}
+	for _, match := range reRelRef.FindAllStringSubmatch(fragment, -1) {
+		oldID := match[1]
+		if _, done := ids[oldID]; done {
+			continue
+		}
+		r, ok := srcRels.Lookup(srcPart, oldID)
+		if !ok {
+			continue
+		}
+		switch {
+		case r.External():
+			ids[oldID] = m.Add(part, Relationship{Type: r.Type, Target: r.Target, TargetMode: r.TargetMode})
+		case r.Type == RelTypeImage:
+			data, ok := src.files[srcRels.ResolveTarget(srcPart, r)]
+			if !ok {
+				continue
+			}
+			name := fmt.Sprintf("%s_inc_%x%s", partName(part), sha1.Sum(data), path.Ext(r.Target))
+			m.d.files["word/media/"+name] = data
+			m.d.ContentTypes().AddDefaultForFile(name)
+			ids[oldID] = m.AddImage(part, "media/"+name)
+		case r.Type == RelTypeChart:
+			target, ok := m.importChart(src, srcRels.ResolveTarget(srcPart, r), part)
+			if !ok {
+				continue
+			}
+			ids[oldID] = m.Add(part, Relationship{Type: RelTypeChart, Target: target})
+		}
+	}
+	if len(ids) == 0 {
+		return fragment
+	}
+	return reRelRef.ReplaceAllStringFunc(fragment, func(s string) string {
+		sub := reRelRef.FindStringSubmatch(s)
+		if newID, ok := ids[sub[1]]; ok {
+			return strings.Replace(s, `"`+sub[1]+`"`, `"`+newID+`"`, 1)
+		}
+		return s
+	})
+}
+
+// importChart copies the chart part of src and the parts it refers to (the workbook, colors, style)
+// under new names, and returns its target relative to word/.
+func (m *RelationshipManager) importChart(src *Docx, chartPath, part string) (string, bool) {
+	data, ok := src.files[chartPath]
+	if !ok {
+		return "", false
+	}
+	name := fmt.Sprintf("word/charts/chart_%s_inc_%x.xml", partName(part), sha1.Sum(data))
+	m.d.files[name] = data
+	m.d.ContentTypes().SetOverride(name, chartContentType)
+
+	types := src.ContentTypes()
+	for _, r := range src.Rels().List(chartPath) {
+		if r.External() {
+			m.Add(name, r)
+			continue
+		}
+		p := src.Rels().ResolveTarget(chartPath, r)
+		sub, ok := src.files[p]
+		if !ok {
+			continue
+		}
+		subName := path.Join(path.Dir(p), fmt.Sprintf("inc_%x%s", sha1.Sum(sub), path.Ext(p)))
+		m.d.files[subName] = sub
+		if ct, ok := types.Override(p); ok {
+			m.d.ContentTypes().SetOverride(subName, ct)
+		} else if ext := strings.TrimPrefix(path.Ext(p), "."); ext != "" {
+			if _, ok := m.d.ContentTypes().Default(ext); !ok {
+				if ct, ok := types.Default(ext); ok {
+					m.d.ContentTypes().SetDefault(ext, ct)
+				}
+			}
+		}
+		target := strings.TrimPrefix(subName, "word/charts/")
+		if target == subName {
+			target = "../" + strings.TrimPrefix(subName, "word/")
+		}
+		m.Add(name, Relationship{ID: r.ID, Type: r.Type, Target: target})
+	}
+	return strings.TrimPrefix(name, "word/"), true
+}
+
+// read parses the .rels of the part; ok=false if there is no .rels (an empty set is returned).
+func (m *RelationshipManager) read(part string) (relationships, bool) {
+	var rels relationships
+	data, ok := m.d.files[relsPathOf(part)]
+	if !ok || len(data) == 0 {
+		return relationships{XMLNS: relsNamespace}, false
+	}
+	if err := xml.Unmarshal(data, &rels); err != nil {
+		return relationships{XMLNS: relsNamespace}, false
+	}
+	if rels.XMLNS == "" {
+		rels.XMLNS = relsNamespace
+	}
+	return rels, true
+}
+
+func (m *RelationshipManager) write(part string, rels relationships) {
+	rels.XMLNS = relsNamespace
+	out, _ := xml.MarshalIndent(rels, "", "  ")
+	m.d.files[relsPathOf(part)] = append([]byte(xml.Header), out...)
+}
+
+// freeRelID returns "rIdN" not used by the relationships.
+func freeRelID(items []Relationship) string {
+	maxN := 0
+	for _, r := range items {
+		if n, err := strconv.Atoi(strings.TrimPrefix(r.ID, "rId")); err == nil && n > maxN {
+			maxN = n
+		}
+	}
+	return "rId" + strconv.Itoa(maxN+1)
+}
+
+// partPath - "document" → "word/document.xml".
+func partPath(part string) string {
+	if !strings.Contains(part, "/") {
+		part = "word/" + part
+	}
+	if !strings.HasSuffix(part, ".xml") {
+		part += ".xml"
+	}
+	return part
+}
+
+// partName - "word/header1.xml" → "header1".
+func partName(part string) string {
+	return strings.TrimSuffix(path.Base(part), ".xml")
+}
+
+// relsPathOf - "document" → "word/_rels/document.xml.rels".
+func relsPathOf(part string) string {
+	p := partPath(part)
+	return path.Join(path.Dir(p), "_rels", path.Base(p)+".rels")
+}
@@ -0,0 +1,46 @@
+package docxgen
+
+import "io"
+
+// DocumentRenderer - the rendering of a template as the services embedding docxgen use it,
+// implemented by *Docx. A service that takes a DocumentRenderer (and a RendererOpener to get one)
+// instead of *Docx is unit-tested with a fake or a generated mock, without templates on disk:
+//
+//	type Service struct{ Open docxgen.RendererOpener }
+//
+//	func (s Service) Contract(w io.Writer, data map[string]any) error {
+//		doc, err := s.Open("templates/contract.docx")
+//		if err != nil {
+//			return err
+//		}
+//		if err := doc.ExecuteTemplate(data); err != nil {
+//			return err
+//		}
+//		return doc.SaveToWriter(w)
+//	}
+//
+// In production Service{Open: docxgen.OpenRenderer}.
+type DocumentRenderer interface {
+	// ExecuteTemplate fills the template with the data, see (*Docx).ExecuteTemplate.
+	ExecuteTemplate(data map[string]any) error
+	// ContentPart returns the XML of a part ("document", "header1", ...).
+	ContentPart(part string) (string, error)
+	// Save writes the document to a DOCX file.
+	Save(path string) error
+	// SaveToWriter writes the document as DOCX to w.
+	SaveToWriter(w io.Writer) error
+}
+
+// RendererOpener - opens a template by its path as a DocumentRenderer.
+type RendererOpener func(path string) (DocumentRenderer, error)
+
+var _ DocumentRenderer = (*Docx)(nil)
+
+// OpenRenderer - Open as a RendererOpener.
+func OpenRenderer(path string) (DocumentRenderer, error) {
+	doc, err := Open(path)
+	if err != nil {
+		return nil, err // not a typed nil inside the interface
+	}
+	return doc, nil
+}
@@ -0,0 +1,138 @@
+package docxgen
+
+import (
+	"docxgen/modifiers"
+	"fmt"
+	"strings"
+)
+
+// ============================================================================
+// [requisites/party_a:party_b] — «Реквизиты сторон»: the details of the two parties side by side
+// ============================================================================
+
+// requisitesOpenPrefix - the start of the requisites marker.
+const requisitesOpenPrefix = "[requisites/"
+
+// requisiteLine - a line of the details of a party: the label before the value of the field.
+type requisiteLine struct {
+	field, label string
+}
+
+// requisiteLines - the lines of a party in the usual order; the fields that are empty are omitted.
+var requisiteLines = []requisiteLine{
+	{"legal_address", "Юридический адрес: "},
+	{"address", "Адрес: "},
+	{"postal_address", "Почтовый адрес: "},
+	{"inn", "ИНН "},
+	{"kpp", "КПП "},
+	{"ogrn", "ОГРН "},
+	{"ogrnip", "ОГРНИП "},
+	{"passport", "Паспорт "},
+	{"account", "р/с "},
+	{"bank", "в "},
+	{"corr_account", "к/с "},
+	{"bik", "БИК "},
+	{"phone", "Тел.: "},
+	{"email", "E-mail: "},
+}
+
+// ResolveRequisites replaces the paragraphs with [requisites/party_a:party_b] by the two-column table
+// of the details of data[party_a] and data[party_b]. A party is a map: role (Заказчик) and name in bold,
+// then the lines of legal_address, address, postal_address, inn, kpp, ogrn, ogrnip, passport, account,
+// bank, corr_account, bik, phone and email with their labels (ИНН, р/с, БИК, ...) and, with signer,
+// the signature line "position ____________ / signer /" and "М.П.". The lines of the empty fields are
+// omitted. titles:Заказчик,Исполнитель gives the roles of the parties that have none.
+//
+// A broken marker or a party that is not a map is reported with the bad_requisites warning;
+// the marker is removed when neither party is a map.
+func (d *Docx) ResolveRequisites(body string, data map[string]any) string {
+	return replaceMarkerParagraphs(body, requisitesOpenPrefix, func(spec string) string {
+		return d.renderRequisites(spec, data)
+	})
+}
+
+// renderRequisites builds the table of one marker; "" — nothing to show (a warning is issued).
+func (d *Docx) renderRequisites(specText string, data map[string]any) string {
+	fields := strings.Fields(specText)
+	var names []string
+	if len(fields) > 0 {
+		names = strings.Split(fields[0], ":")
+	}
+	if len(names) != 2 || names[0] == "" || names[1] == "" {
+		d.warn(WarnBadRequisites, specText, "want [requisites/party_a:party_b ...]")
+		return ""
+	}
+	var titles []string
+	for _, f := range fields[1:] {
+		if key, val, _ := strings.Cut(f, ":"); strings.EqualFold(key, "titles") {
+			titles = strings.Split(strings.ReplaceAll(val, "_", " "), ",")
+		}
+	}
+
+	cells := make([]string, 2)
+	shown := 0
+	for i, name := range names {
+		raw := blockValue(data, name)
+		party := orgMap(raw)
+		if party == nil {
+			d.warn(WarnBadRequisites, name, "the party is %T, not a map", raw)
+			cells[i] = "<w:p/>"
+			continue
+		}
+		title := ""
+		if i < len(titles) {
+			title = titles[i]
+		}
+		cells[i] = requisitesCell(party, title)
+		shown++
+	}
+	if shown == 0 {
+		return ""
+	}
+
+	width := d.CurrentPageLayout().UsableWidth().Twips() / 2
+	var b strings.Builder
+	fmt.Fprintf(&b, `<w:tbl><w:tblPr><w:tblW w:w="5000" w:type="pct"/><w:tblLayout w:type="fixed"/></w:tblPr>`+
+		`<w:tblGrid><w:gridCol w:w="%d"/><w:gridCol w:w="%d"/></w:tblGrid><w:tr>`, width, width)
+	for _, c := range cells {
+		fmt.Fprintf(&b, `<w:tc><w:tcPr><w:tcW w:w="%d" w:type="dxa"/></w:tcPr>%s</w:tc>`, width, c)
+	}
+	b.WriteString(`</w:tr></w:tbl>`)
+	return b.String()
+}
+
+// requisitesCell - the paragraphs of the details of one party.
+func requisitesCell(party map[string]any, title string) string {
+	text := func(field string) string {
+		return strings.TrimSpace(modifiers.ValueText(blockValue(party, field)))
+	}
+	var b strings.Builder
+	if role := text("role"); role != "" {
+		title = role
+	}
+	if title != "" {
+		b.WriteString(cellText(title, true, false))
+	}
+	if name := text("name"); name != "" {
+		b.WriteString(cellText(name, true, false))
+	}
+	for _, l := range requisiteLines {
+		if v := text(l.field); v != "" {
+			b.WriteString(cellText(l.label+v, false, false))
+		}
+	}
+	if signer := text("signer"); signer != "" {
+		if position := text("position"); position != "" {
+			b.WriteString(`<w:p><w:pPr><w:spacing w:before="240"/></w:pPr><w:r><w:t xml:space="preserve">` +
+				xmlEscape(position) + `</w:t></w:r></w:p><w:p>`)
+		} else {
+			b.WriteString(`<w:p><w:pPr><w:spacing w:before="240"/></w:pPr>`)
+		}
+		b.WriteString(`<w:r><w:t xml:space="preserve">____________ / ` + xmlEscape(signer) + ` /</w:t></w:r></w:p>`)
+		b.WriteString(cellText("М.П.", false, false))
+	}
+	if b.Len() == 0 {
+		return "<w:p/>"
+	}
+	return b.String()
+}
@@ -0,0 +1,248 @@
+package docxgen
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ============================================================================
+// Tracked changes (w:ins, w:del, w:*PrChange) accepted or rejected in the XML
+// ============================================================================
+
+// OpenOption - an option of Open, OpenReader and OpenBytes, applied before the archive is unpacked;
+// the steps that need the parts are added to afterOpen and run before the tags are repaired.
+type OpenOption func(*Docx)
+
+// WithAcceptedRevisions - accept the tracked changes of the template on open, see AcceptAllRevisions.
+func WithAcceptedRevisions() OpenOption {
+	return func(d *Docx) { d.afterOpen = append(d.afterOpen, d.AcceptAllRevisions) }
+}
+
+// WithRejectedRevisions - reject the tracked changes of the template on open, see RejectAllRevisions.
+func WithRejectedRevisions() OpenOption {
+	return func(d *Docx) { d.afterOpen = append(d.afterOpen, d.RejectAllRevisions) }
+}
+
+var (
+	reRevisionPart = regexp.MustCompile(`^(?:document|header\d*|footer\d*|footnotes|endnotes|comments)\.xml$`)
+	// reRevisionMark - a self-closing mark of a change: of a paragraph mark or a row (in rPr, trPr),
+	// of a cell, of the bounds of a move.
+	reRevisionMark = regexp.MustCompile(`<w:(?:ins|del|cellIns|cellDel|cellMerge|moveFromRangeStart|moveFromRangeEnd|moveToRangeStart|moveToRangeEnd)\b[^>]*/>`)
+	reTableRowOpen = regexp.MustCompile(`<w:tr(?:\s[^>]*)?>`)
+)
+
+// delTextTags - the deleted text as a text again.
+var delTextTags = strings.NewReplacer("<w:delText", "<w:t", "</w:delText>", "</w:t>",
+	"<w:delInstrText", "<w:instrText", "</w:delInstrText>", "</w:instrText>")
+
+// revisionWrappers - the wrappers of the changed runs as (kept, dropped) on accept; reject swaps them.
+var revisionWrappers = [2][]string{{"ins", "moveTo"}, {"del", "moveFrom"}}
+
+// revisionProps - the properties with a change inside (<w:rPrChange> in <w:rPr>, ...), the inner
+// first, and the elements of the current properties that the old ones in the change do not hold.
+var revisionProps = []struct {
+	name string
+	keep []string
+}{
+	{"rPr", nil},
+	{"pPr", []string{"rPr", "sectPr"}},
+	{"tcPr", nil},
+	{"trPr", nil},
+	{"tblPr", nil},
+	{"tblGrid", nil},
+	{"sectPr", []string{"headerReference", "footerReference"}},
+}
+
+// AcceptAllRevisions accepts the tracked changes of the document, headers, footers, footnotes and
+// comments, as Word's "Accept All Changes": inserted text stays, deleted text goes away, the new
+// formatting stays, deleted rows and paragraph marks are removed (the paragraph joins the next one).
+// A template edited with the tracking on has its tags cut by w:ins and w:del, which RepairTags
+// cannot glue and which leave the deleted text in the result; as Open repairs the tags of the body,
+// such a template is opened with WithAcceptedRevisions. The tracking itself (trackRevisions in
+// settings.xml) stays as it is.
+func (d *Docx) AcceptAllRevisions() {
+	d.resolveRevisions(true)
+}
+
+// RejectAllRevisions rejects the tracked changes, as Word's "Reject All Changes": the document returns
+// to the text and the formatting before the changes. See AcceptAllRevisions, WithRejectedRevisions.
+func (d *Docx) RejectAllRevisions() {
+	d.resolveRevisions(false)
+}
+
+// resolveRevisions accepts or rejects the changes in every part with a text.
+func (d *Docx) resolveRevisions(accept bool) {
+	for name, data := range d.files {
+		if path.Dir(name) != "word" || !reRevisionPart.MatchString(path.Base(name)) {
+			continue
+		}
+		if s := string(data); strings.Contains(s, "<w:ins") || strings.Contains(s, "<w:del") ||
+			strings.Contains(s, "<w:move") || strings.Contains(s, "PrChange") || strings.Contains(s, "<w:tblGridChange") {
+			d.files[name] = []byte(resolveRevisions(s, accept))
+		}
+	}
+}
+
+// resolveRevisions accepts or rejects the changes in the XML of a part.
+func resolveRevisions(s string, accept bool) string {
+	kept, dropped := revisionWrappers[0], revisionWrappers[1]
+	if !accept {
+		kept, dropped = dropped, kept
+	}
+	// the dropped first: an insertion deleted later (<w:ins><w:del>…) goes away with the insertion
+	for _, name := range dropped {
+		s = replaceElements(s, name, func(string) string { return "" })
+	}
+	for _, name := range kept {
+		s = replaceElements(s, name, func(el string) string {
+			inner := el[strings.IndexByte(el, '>')+1 : strings.LastIndex(el, "</")]
+			return delTextTags.Replace(inner)
+		})
+	}
+
+	for _, p := range revisionProps {
+		s = replaceElements(s, p.name+"Change", func(change string) string {
+			if accept {
+				return ""
+			}
+			return "\x00" + change // the old properties take the place of the current ones below
+		})
+		if !accept {
+			s = restoreProps(s, p.name, p.keep)
+		}
+	}
+
+	// a row or a paragraph mark deleted goes away on accept, an inserted one — on reject
+	mark := "del"
+	if !accept {
+		mark = "ins"
+	}
+	s = removeMarkedRows(s, mark)
+	s = joinMarkedParagraphs(s, mark)
+	return reRevisionMark.ReplaceAllString(s, "")
+}
+
+// replaceElements replaces every <w:name ...>…</w:name> of s (not the self-closing ones) by fn of it.
+func replaceElements(s, name string, fn func(string) string) string {
+	open := pattern(`<w:` + name + `(?:\s[^>]*[^/])?>`)
+	var b strings.Builder
+	pos := 0
+	for {
+		loc := open.FindStringIndex(s[pos:])
+		if loc == nil {
+			break
+		}
+		start := pos + loc[0]
+		end := elementEnd(s, start)
+		b.WriteString(s[pos:start])
+		b.WriteString(fn(s[start:end]))
+		pos = end
+	}
+	b.WriteString(s[pos:])
+	return b.String()
+}
+
+// restoreProps replaces the properties marked with "\x00<w:namePrChange>" by the old ones from the
+// change, with the elements keep of the current properties appended.
+func restoreProps(s, name string, keep []string) string {
+	for {
+		at := strings.Index(s, "\x00<w:"+name+"Change")
+		if at < 0 {
+			return s
+		}
+		changeEnd := elementEnd(s, at+1)
+		change := s[at+1 : changeEnd]
+		old := ""
+		if i := strings.Index(change, "<w:"+name); i >= 0 {
+			e := elementEnd(change, i)
+			if el := change[i:e]; !strings.HasSuffix(el[:strings.IndexByte(el, '>')+1], "/>") {
+				old = el[strings.IndexByte(el, '>')+1 : strings.LastIndex(el, "</")]
+			}
+		}
+		parent := max(strings.LastIndex(s[:at], "<w:"+name+">"), strings.LastIndex(s[:at], "<w:"+name+" "))
+		closing := strings.Index(s[changeEnd:], "</w:"+name+">")
+		if parent < 0 || closing < 0 {
+			s = s[:at] + s[changeEnd:]
+			continue
+		}
+		closing += changeEnd
+		openTag := s[parent : parent+strings.IndexByte(s[parent:], '>')+1]
+		current := s[parent+len(openTag) : at]
+		var kept strings.Builder
+		for _, k := range keep {
+			re := pattern(`<w:` + k + `\b`)
+			for _, loc := range re.FindAllStringIndex(current, -1) {
+				kept.WriteString(current[loc[0]:elementEnd(current, loc[0])])
+			}
+		}
+		s = s[:parent] + openTag + old + kept.String() + s[closing:]
+	}
+}
+
+// removeMarkedRows removes the table rows with <w:mark .../> in their properties.
+func removeMarkedRows(s, mark string) string {
+	re := pattern(`<w:` + mark + `\b[^>]*/>`)
+	var b strings.Builder
+	pos := 0
+	for {
+		loc := reTableRowOpen.FindStringIndex(s[pos:])
+		if loc == nil {
+			break
+		}
+		start, open := pos+loc[0], pos+loc[1]
+		end := elementEnd(s, start)
+		b.WriteString(s[pos:start])
+		pos = end
+		if i := strings.Index(s[open:end], "<w:trPr>"); i >= 0 && !strings.Contains(s[open:open+i], "<w:tc") {
+			if re.MatchString(s[open+i : elementEnd(s, open+i)]) {
+				continue
+			}
+		}
+		b.WriteString(s[start:open]) // the rows of the nested tables are looked at too
+		pos = open
+	}
+	b.WriteString(s[pos:])
+	return b.String()
+}
+
+// joinMarkedParagraphs joins the paragraphs whose mark (the rPr of pPr) has <w:mark .../> with the
+// paragraph that follows, which keeps its properties; the last paragraph of a cell or of the body
+// just loses the mark.
+func joinMarkedParagraphs(s, mark string) string {
+	re := pattern(`<w:` + mark + `\b[^>]*/>`)
+	for pos := 0; ; {
+		start := paragraphStart(s, pos)
+		if start < 0 {
+			return s
+		}
+		end := elementEnd(s, start)
+		pos = start + len("<w:p")
+		props := reParagraphProps.FindStringSubmatch(s[start:end])
+		if props == nil || props[1] == "" {
+			continue
+		}
+		rPr := paragraphMarkProps(props[1])
+		if !re.MatchString(rPr) {
+			continue
+		}
+		next := strings.TrimLeft(s[end:], " \t\r\n")
+		if paragraphStart(next, 0) != 0 {
+			continue
+		}
+		nextStart := len(s) - len(next)
+		nextProps := reParagraphProps.FindString(s[nextStart:])
+		content := s[start+len(props[0]) : end-len("</w:p>")]
+		s = s[:start] + nextProps + content + s[nextStart+len(nextProps):]
+		pos = start
+	}
+}
+
+// paragraphMarkProps - the <w:rPr> of the paragraph mark in the paragraph properties, if any.
+func paragraphMarkProps(pPr string) string {
+	i := strings.Index(pPr, "<w:rPr>")
+	if i < 0 {
+		return ""
+	}
+	return pPr[i:elementEnd(pPr, i)]
+}
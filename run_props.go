@@ -0,0 +1,85 @@
+package docxgen
+
+import (
+	"strings"
+
+	"docxgen/modifiers"
+)
+
+// ============================================================================
+// The runs of the modifiers take the properties of the run of their tag
+// ============================================================================
+
+// inheritRunProps gives every run marked with modifiers.InheritRunMarker the <w:rPr> of the run
+// of its tag — the nearest unmarked run before it in the paragraph — with its own properties
+// on top, and removes the markers: {x|smallcaps} in an Arial 16pt run stays Arial 16pt, and so
+// does the text after the tag.
+func inheritRunProps(body string) string {
+	return mergeMarkedRuns(body, func(i int) string { return tagRunProps(body, i) })
+}
+
+// mergeMarkedRuns puts the properties base gives for the position of every modifiers.InheritRunMarker
+// under the own <w:rPr> of its run and removes the markers.
+func mergeMarkedRuns(body string, base func(pos int) string) string {
+	const marker = modifiers.InheritRunMarker
+	if !strings.Contains(body, marker) {
+		return body
+	}
+
+	var out strings.Builder
+	out.Grow(len(body))
+	last := 0
+	for pos := 0; ; {
+		i := strings.Index(body[pos:], marker)
+		if i < 0 {
+			break
+		}
+		i += pos
+		own, rest := "", i+len(marker)
+		if strings.HasPrefix(body[rest:], "<w:rPr>") {
+			if e := strings.Index(body[rest:], "</w:rPr>"); e >= 0 {
+				own = body[rest+len("<w:rPr>") : rest+e]
+				rest += e + len("</w:rPr>")
+			}
+		}
+		out.WriteString(body[last:i])
+		if merged := modifiers.MergeRunProps(base(i), own); merged != "" {
+			out.WriteString("<w:rPr>" + merged + "</w:rPr>")
+		}
+		last, pos = rest, rest
+	}
+	out.WriteString(body[last:])
+	return out.String()
+}
+
+// tagRunProps - the content of <w:rPr> of the nearest run before pos in its paragraph that is not
+// marked with modifiers.InheritRunMarker; empty when there is none or it has no properties.
+func tagRunProps(body string, pos int) string {
+	for end := pos; ; {
+		start := lastRunStart(body[:end])
+		if start < 0 || paragraphStart(body[start:end], 0) >= 0 {
+			return ""
+		}
+		open := strings.IndexByte(body[start:], '>')
+		if open < 0 {
+			return ""
+		}
+		run := body[start+open+1:]
+		if strings.HasPrefix(run, modifiers.InheritRunMarker) {
+			end = start
+			continue
+		}
+		if !strings.HasPrefix(run, "<w:rPr>") {
+			return ""
+		}
+		if e := strings.Index(run, "</w:rPr>"); e >= 0 {
+			return run[len("<w:rPr>"):e]
+		}
+		return ""
+	}
+}
+
+// lastRunStart - the start of the last <w:r> or <w:r ...> in s, -1 if there is none.
+func lastRunStart(s string) int {
+	return max(strings.LastIndex(s, "<w:r>"), strings.LastIndex(s, "<w:r "))
+}
@@ -0,0 +1,209 @@
+package docxgen
+
+import (
+	"docxgen/modifiers"
+	"maps"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// Content controls (w:sdt) filled by their tag or title from the data
+// ============================================================================
+
+var (
+	reSdtTag          = regexp.MustCompile(`<w:tag w:val="([^"]*)"`)
+	reSdtAlias        = regexp.MustCompile(`<w:alias w:val="([^"]*)"`)
+	reSdtRepeating    = regexp.MustCompile(`<w15:repeatingSection\s*/>`)
+	reSdtRepeatItem   = regexp.MustCompile(`<w15:repeatingSectionItem\s*/>`)
+	reSdtShowing      = regexp.MustCompile(`<w:showingPlcHdr\s*/>|<w:dataBinding\b[^>]*/>`)
+	reSdtPlaceholder  = regexp.MustCompile(`<w:rStyle w:val="PlaceholderText"\s*/>`)
+	reSdtChecked      = regexp.MustCompile(`<w14:checked w14:val="[^"]*"\s*/>`)
+	reSdtCheckedState = regexp.MustCompile(`<w14:(checked|unchecked)State w14:val="([0-9A-Fa-f]+)"`)
+	reSdtRunProps     = regexp.MustCompile(`(?s)<w:r(?:\s[^>]*)?>(<w:rPr>.*?</w:rPr>)?`)
+)
+
+// BindContentControls fills the content controls (w:sdt) of the part by their tag, or by their title
+// when there is no tag, from the data; a dotted tag (client.name) takes a nested field. It is the mode
+// of SetContentControls, applied by ExecuteTemplate after the {tags}:
+//   - a text, combo box, drop-down or date control gets the value as its text, in the format of
+//     its first run, the lines of the value split by line breaks;
+//   - a check box is checked when the value is filled as for [if] (true, a text, a non-zero number);
+//   - a repeating section gets an item per element of a list, the first item is the pattern and
+//     the controls inside it are filled from the element (and from the data for the other tags);
+//     an empty list removes the section;
+//   - a group (a control with controls inside) fills the controls inside, from its value when that
+//     is a map.
+//
+// A control whose tag has no value in the data keeps its content. A filled control loses its
+// placeholder look and its binding to the custom XML (Word would restore the old value from it).
+func (d *Docx) BindContentControls(body string, data map[string]any) string {
+	return bindSdts(body, data)
+}
+
+// bindSdts fills every outer content control of s.
+func bindSdts(s string, scope map[string]any) string {
+	var b strings.Builder
+	pos := 0
+	for {
+		i := sdtStart(s, pos)
+		if i < 0 {
+			break
+		}
+		end := elementEnd(s, i)
+		b.WriteString(s[pos:i])
+		b.WriteString(bindSdt(s[i:end], scope))
+		pos = end
+	}
+	b.WriteString(s[pos:])
+	return b.String()
+}
+
+// sdtStart - the position of the next <w:sdt> in s[from:] (not <w:sdtPr>, <w:sdtContent>), or -1.
+func sdtStart(s string, from int) int {
+	for {
+		i := strings.Index(s[from:], "<w:sdt")
+		if i < 0 {
+			return -1
+		}
+		i += from
+		if next := i + len("<w:sdt"); next < len(s) && (s[next] == '>' || s[next] == ' ') {
+			return i
+		}
+		from = i + 1
+	}
+}
+
+// bindSdt fills one content control.
+func bindSdt(sdt string, scope map[string]any) string {
+	open := strings.Index(sdt, "<w:sdtContent>")
+	closing := strings.LastIndex(sdt, "</w:sdtContent>")
+	if open < 0 || closing < open {
+		return sdt
+	}
+	pr, content, rest := sdt[:open+len("<w:sdtContent>")], sdt[open+len("<w:sdtContent>"):closing], sdt[closing:]
+
+	key := ""
+	if m := reSdtTag.FindStringSubmatch(pr); m != nil {
+		key = m[1]
+	} else if m := reSdtAlias.FindStringSubmatch(pr); m != nil {
+		key = m[1]
+	}
+	var value any
+	if key != "" {
+		value = blockValue(scope, key)
+	}
+
+	switch {
+	case reSdtRepeatItem.MatchString(pr):
+		return pr + bindSdts(content, scope) + rest
+	case reSdtRepeating.MatchString(pr):
+		items, ok := normalizeItems(value)
+		if !ok {
+			return pr + bindSdts(content, scope) + rest
+		}
+		if len(items) == 0 {
+			return ""
+		}
+		first := sdtStart(content, 0)
+		if first < 0 {
+			return sdt
+		}
+		end := elementEnd(content, first)
+		last := strings.LastIndex(content, "</w:sdt>") + len("</w:sdt>")
+		item := content[first:end]
+		var b strings.Builder
+		b.WriteString(content[:first])
+		for _, it := range items {
+			b.WriteString(bindSdt(item, withItem(scope, it)))
+		}
+		b.WriteString(content[max(last, end):])
+		return reSdtShowing.ReplaceAllString(pr, "") + b.String() + rest
+	case sdtStart(content, 0) >= 0:
+		if m := orgMap(value); m != nil {
+			scope = withItem(scope, m)
+		}
+		return pr + bindSdts(content, scope) + rest
+	case value == nil:
+		return sdt
+	case strings.Contains(pr, "<w14:checkbox>"):
+		pr, content = checkSdt(reSdtShowing.ReplaceAllString(pr, ""), content, filledValue(value))
+		return pr + content + rest
+	case strings.Contains(content, "<w:tr") || strings.Contains(content, "<w:tc"):
+		return sdt // rows or cells without controls inside have no place for a text
+	}
+	pr = reSdtShowing.ReplaceAllString(pr, "")
+	return pr + fillSdtContent(content, modifiers.ValueText(value)) + rest
+}
+
+// withItem - the data with the fields of the element of a list on top.
+func withItem(scope map[string]any, item any) map[string]any {
+	m := orgMap(item)
+	if m == nil {
+		return scope
+	}
+	out := maps.Clone(scope)
+	if out == nil {
+		out = map[string]any{}
+	}
+	maps.Copy(out, m)
+	return out
+}
+
+// fillSdtContent replaces the content with the text: in a paragraph with the properties of the first
+// paragraph for a block level control, in a run for an inline one; the run keeps the first format.
+func fillSdtContent(content, text string) string {
+	rPr := ""
+	if m := reSdtRunProps.FindStringSubmatch(content); m != nil {
+		rPr = reSdtPlaceholder.ReplaceAllString(m[1], "")
+		if rPr == "<w:rPr></w:rPr>" {
+			rPr = ""
+		}
+	}
+	var run strings.Builder
+	run.WriteString("<w:r>" + rPr)
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			run.WriteString("<w:br/>")
+		}
+		run.WriteString(`<w:t xml:space="preserve">` + xmlEscape(line) + `</w:t>`)
+	}
+	run.WriteString("</w:r>")
+
+	p := paragraphStart(content, 0)
+	if p < 0 {
+		return run.String()
+	}
+	pOpen := content[p : p+strings.IndexByte(content[p:], '>')+1]
+	if strings.HasSuffix(pOpen, "/>") {
+		pOpen = strings.TrimSuffix(pOpen, "/>") + ">"
+	} else if m := reParagraphProps.FindString(content[p:]); m != "" {
+		pOpen = m
+	}
+	return pOpen + run.String() + "</w:p>"
+}
+
+// checkSdt sets the state of a check box in its properties and its symbol in its content.
+func checkSdt(pr, content string, checked bool) (string, string) {
+	val, state, glyph := "0", "unchecked", '☐'
+	if checked {
+		val, state, glyph = "1", "checked", '☒'
+	}
+	for _, m := range reSdtCheckedState.FindAllStringSubmatch(pr, -1) {
+		if n, err := strconv.ParseInt(m[2], 16, 32); m[1] == state && err == nil {
+			glyph = rune(n)
+		}
+	}
+	if i := strings.Index(content, "<w:t>"); i >= 0 {
+		if j := strings.Index(content[i:], "</w:t>"); j >= 0 {
+			content = content[:i] + "<w:t>" + string(glyph) + content[i+j:]
+		}
+	} else if i := strings.Index(content, "<w:t "); i >= 0 {
+		gt := i + strings.IndexByte(content[i:], '>') + 1
+		if j := strings.Index(content[gt:], "</w:t>"); j >= 0 {
+			content = content[:gt] + string(glyph) + content[gt+j:]
+		}
+	}
+	return reSdtChecked.ReplaceAllString(pr, `<w14:checked w14:val="`+val+`"/>`), content
+}
@@ -0,0 +1,153 @@
+package docxgen
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"docxgen/geometry"
+)
+
+// PageLayout - page geometry of a document section (<w:sectPr>): paper size and margins.
+type PageLayout struct {
+	Width, Height geometry.Length
+	Margins       geometry.Sides
+
+	refs []string // r:id of the header/footer parts of the section
+}
+
+// UsableWidth - the width of the text area (page width minus the left and right margins).
+func (p PageLayout) UsableWidth() geometry.Length {
+	return max(0, p.Width-p.Margins.Left-p.Margins.Right)
+}
+
+// UsableHeight - the height of the text area (page height minus the top and bottom margins).
+func (p PageLayout) UsableHeight() geometry.Length {
+	return max(0, p.Height-p.Margins.Top-p.Margins.Bottom)
+}
+
+// defaultPageLayout - A4 without margins, used if the document has no <w:sectPr>.
+var defaultPageLayout = PageLayout{Width: geometry.A4Width, Height: geometry.A4Height}
+
+var (
+	reSectPr    = regexp.MustCompile(`(?s)<w:sectPr\b[^>]*/>|<w:sectPr\b[^>]*>.*?</w:sectPr>`)
+	reHdrFtrRef = regexp.MustCompile(`<w:(?:headerReference|footerReference)[^>]+r:id="([^"]+)"`)
+)
+
+// sectionMarkerFunc - the template function that switches the current section during execution.
+const sectionMarkerFunc = "_section"
+
+// Sections returns the page layout of every section of the document in order.
+// The last one is the section of the <w:body> level sectPr.
+func (d *Docx) Sections() []PageLayout {
+	return parseSections(string(d.files["word/document.xml"]))
+}
+
+// CurrentPageLayout returns the layout of the section in which the template is being executed
+// (the section of the tag for modifiers, the referencing section for headers and footers).
+func (d *Docx) CurrentPageLayout() PageLayout {
+	sections := d.sections
+	if sections == nil {
+		sections = d.Sections()
+	}
+	if d.section >= 0 && d.section < len(sections) {
+		return sections[d.section]
+	}
+	if len(sections) > 0 {
+		return sections[0]
+	}
+	return defaultPageLayout
+}
+
+// GetUsableSizeEMU — the size of the text area of the current section in EMU (page minus margins).
+// "% of page" sizes of drawings are calculated from it, so that they do not overflow into the margins.
+func (d *Docx) GetUsableSizeEMU() (width, height int) {
+	l := d.CurrentPageLayout()
+	return l.UsableWidth().EMU(), l.UsableHeight().EMU()
+}
+
+// parseSections reads all <w:sectPr> of document.xml.
+func parseSections(doc string) []PageLayout {
+	var sections []PageLayout
+	for _, sect := range reSectPr.FindAllString(doc, -1) {
+		l := defaultPageLayout
+		if w := extractAttrInt(sect, "w:pgSz", "w:w"); w > 0 {
+			l.Width = geometry.FromTwips(w)
+		}
+		if h := extractAttrInt(sect, "w:pgSz", "w:h"); h > 0 {
+			l.Height = geometry.FromTwips(h)
+		}
+		l.Margins = geometry.Sides{
+			Top:    geometry.FromTwips(abs(extractAttrInt(sect, "w:pgMar", "w:top"))),
+			Right:  geometry.FromTwips(extractAttrInt(sect, "w:pgMar", "w:right")),
+			Bottom: geometry.FromTwips(abs(extractAttrInt(sect, "w:pgMar", "w:bottom"))),
+			Left:   geometry.FromTwips(extractAttrInt(sect, "w:pgMar", "w:left")),
+		}
+		for _, m := range reHdrFtrRef.FindAllStringSubmatch(sect, -1) {
+			l.refs = append(l.refs, m[1])
+		}
+		sections = append(sections, l)
+	}
+	return sections
+}
+
+// markSections inserts a {_section N} call after every paragraph that ends a section,
+// so that modifiers know the section of the tag during execution.
+func markSections(content string) string {
+	var b strings.Builder
+	n := 0
+	last := 0
+	for _, loc := range reSectPr.FindAllStringIndex(content, -1) {
+		rest := content[loc[1]:]
+		end := strings.Index(rest, "</w:p>")
+		if end < 0 {
+			break // sectPr of the body: the last section, nothing follows it
+		}
+		if next := strings.Index(rest, "<w:p>"); next >= 0 && next < end {
+			continue
+		}
+		if next := strings.Index(rest, "<w:p "); next >= 0 && next < end {
+			continue
+		}
+		n++
+		pos := loc[1] + end + len("</w:p>")
+		b.WriteString(content[last:pos])
+		b.WriteString("{" + sectionMarkerFunc + " " + strconv.Itoa(n) + "}")
+		last = pos
+	}
+	b.WriteString(content[last:])
+	return b.String()
+}
+
+// sectionOfPart returns the index of the first section that references the header/footer part (0 if none).
+func (d *Docx) sectionOfPart(part string, sections []PageLayout) int {
+	rels := d.Rels()
+	for i, s := range sections {
+		for _, id := range s.refs {
+			if r, ok := rels.Lookup("document", id); ok && partName(r.Target) == part {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+// sectionFunc - the implementation of {_section N}.
+func (d *Docx) sectionFunc(n int) string {
+	d.section = n
+	return ""
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// String - "210mm×297mm (margins 20/10/20/30mm)".
+func (p PageLayout) String() string {
+	return fmt.Sprintf("%v×%v (margins %v/%v/%v/%v)", p.Width, p.Height,
+		p.Margins.Top, p.Margins.Right, p.Margins.Bottom, p.Margins.Left)
+}
@@ -0,0 +1,244 @@
+package docxgen
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"docxgen/geometry"
+)
+
+const (
+	settingsPath    = "word/settings.xml"
+	relTypeSettings = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/settings"
+	wordCompatURI   = "http://schemas.microsoft.com/office/word"
+)
+
+// reCompatMode - the compatibility mode setting inside <w:compat>.
+var reCompatMode = regexp.MustCompile(`<w:compatSetting\b[^>]*w:name="compatibilityMode"[^>]*/>`)
+
+// settingsOrder - the order of the child elements of <w:settings> required by the schema (CT_Settings).
+// New elements are inserted according to it, otherwise Word reports the file as damaged.
+var settingsOrder = []string{
+	"writeProtection", "view", "zoom", "removePersonalInformation", "removeDateAndTime",
+	"doNotDisplayPageBoundaries", "displayBackgroundShape", "printPostScriptOverText",
+	"printFractionalCharacterWidth", "printFormsData", "embedTrueTypeFonts", "embedSystemFonts",
+	"saveSubsetFonts", "saveFormsData", "mirrorMargins", "alignBordersAndEdges",
+	"bordersDoNotSurroundHeader", "bordersDoNotSurroundFooter", "gutterAtTop", "hideSpellingErrors",
+	"hideGrammaticalErrors", "activeWritingStyle", "proofState", "formsDesign", "attachedTemplate",
+	"linkStyles", "stylePaneFormatFilter", "stylePaneSortMethod", "documentType", "mailMerge",
+	"revisionView", "trackRevisions", "doNotTrackMoves", "doNotTrackFormatting", "documentProtection",
+	"autoFormatOverride", "styleLockTheme", "styleLockQFSet", "defaultTabStop", "autoHyphenation",
+	"consecutiveHyphenLimit", "hyphenationZone", "doNotHyphenateCaps", "showEnvelope", "summaryLength",
+	"clickAndTypeStyle", "defaultTableStyle", "evenAndOddHeaders", "bookFoldRevPrinting",
+	"bookFoldPrinting", "bookFoldPrintingSheets", "drawingGridHorizontalSpacing",
+	"drawingGridVerticalSpacing", "displayHorizontalDrawingGridEvery", "displayVerticalDrawingGridEvery",
+	"doNotUseMarginsForDrawingGridOrigin", "drawingGridHorizontalOrigin", "drawingGridVerticalOrigin",
+	"doNotShadeFormData", "noPunctuationKerning", "characterSpacingControl", "printTwoOnOne",
+	"strictFirstAndLastChars", "noLineBreaksAfter", "noLineBreaksBefore", "savePreviewPicture",
+	"doNotValidateAgainstSchema", "saveInvalidXml", "ignoreMixedContent", "alwaysShowPlaceholderText",
+	"doNotDemarcateInvalidXml", "saveXmlDataOnly", "useXSLTWhenSaving", "saveThroughXslt", "showXMLTags",
+	"alwaysMergeEmptyNamespace", "updateFields", "hdrShapeDefaults", "footnotePr", "endnotePr", "compat",
+	"docVars", "rsids", "mathPr", "attachedSchema", "themeFontLang", "clrSchemeMapping",
+	"doNotIncludeSubdocsInStats", "doNotAutoCompressPictures", "forceUpgrade", "captions",
+	"readModeInkLockDown", "smartTagType", "schemaLibrary", "shapeDefaults", "doNotEmbedSmartTags",
+	"decimalSymbol", "listSeparator",
+}
+
+// SettingsManager - typed access to word/settings.xml.
+// Like the other managers, it works directly on the files of the document;
+// if the template has no settings.xml, it is created (with its relationship and content type) on the first change.
+type SettingsManager struct {
+	d *Docx
+}
+
+// Settings returns the settings.xml manager of the document.
+func (d *Docx) Settings() *SettingsManager {
+	return &SettingsManager{d: d}
+}
+
+// -------- typed settings --------
+
+// CompatibilityMode returns the Word compatibility mode (15 — Word 2013+, 14 — 2010, 12 — 2007), 0 if not set.
+func (s *SettingsManager) CompatibilityMode() int {
+	compat, ok := s.Element("compat")
+	if !ok {
+		return 0
+	}
+	m := reCompatMode.FindString(compat)
+	n, _ := strconv.Atoi(xmlAttr(m, "w:val"))
+	return n
+}
+
+// SetCompatibilityMode sets the compatibility mode; other compatibility settings are kept.
+func (s *SettingsManager) SetCompatibilityMode(mode int) {
+	setting := fmt.Sprintf(`<w:compatSetting w:name="compatibilityMode" w:uri="%s" w:val="%d"/>`, wordCompatURI, mode)
+	compat, ok := s.Element("compat")
+	switch {
+	case !ok || strings.HasSuffix(compat, "/>"):
+		compat = "<w:compat>" + setting + "</w:compat>"
+	default:
+		if reCompatMode.MatchString(compat) {
+			compat = reCompatMode.ReplaceAllLiteralString(compat, setting)
+		} else {
+			compat = strings.Replace(compat, "</w:compat>", setting+"</w:compat>", 1)
+		}
+	}
+	s.SetElement("compat", compat)
+}
+
+// DefaultTabStop returns the default tab stop interval (0 if not set).
+func (s *SettingsManager) DefaultTabStop() geometry.Length {
+	el, _ := s.Element("defaultTabStop")
+	tw, _ := strconv.Atoi(xmlAttr(el, "w:val"))
+	return geometry.FromTwips(tw)
+}
+
+// SetDefaultTabStop sets the default tab stop interval.
+func (s *SettingsManager) SetDefaultTabStop(l geometry.Length) {
+	s.SetElement("defaultTabStop", fmt.Sprintf(`<w:defaultTabStop w:val="%d"/>`, l.Twips()))
+}
+
+// HideSpellingErrors reports whether the wavy underline of spelling errors is hidden.
+func (s *SettingsManager) HideSpellingErrors() bool { return s.onOff("hideSpellingErrors") }
+
+// SetHideSpellingErrors hides (or shows) spelling errors: generated documents with names and codes
+// are otherwise full of red underlines.
+func (s *SettingsManager) SetHideSpellingErrors(on bool) { s.setOnOff("hideSpellingErrors", on) }
+
+// HideGrammaticalErrors reports whether grammar errors are hidden.
+func (s *SettingsManager) HideGrammaticalErrors() bool { return s.onOff("hideGrammaticalErrors") }
+
+// SetHideGrammaticalErrors hides (or shows) grammar errors.
+func (s *SettingsManager) SetHideGrammaticalErrors(on bool) { s.setOnOff("hideGrammaticalErrors", on) }
+
+// EvenAndOddHeaders reports whether even and odd pages have different headers and footers.
+func (s *SettingsManager) EvenAndOddHeaders() bool { return s.onOff("evenAndOddHeaders") }
+
+// SetEvenAndOddHeaders turns different even/odd headers and footers on or off.
+func (s *SettingsManager) SetEvenAndOddHeaders(on bool) { s.setOnOff("evenAndOddHeaders", on) }
+
+// EmbedTrueTypeFonts reports whether the fonts embedded in the document are used when it is opened.
+func (s *SettingsManager) EmbedTrueTypeFonts() bool { return s.onOff("embedTrueTypeFonts") }
+
+// SetEmbedTrueTypeFonts turns the embedded fonts on or off (set by Docx.EmbedFonts).
+func (s *SettingsManager) SetEmbedTrueTypeFonts(on bool) { s.setOnOff("embedTrueTypeFonts", on) }
+
+// UpdateFields reports whether Word updates fields (TOC, PAGE, REF) when the document is opened.
+func (s *SettingsManager) UpdateFields() bool { return s.onOff("updateFields") }
+
+// SetUpdateFields asks Word to update the fields when the document is opened.
+func (s *SettingsManager) SetUpdateFields(on bool) { s.setOnOff("updateFields", on) }
+
+// -------- generic access --------
+
+// Element returns the XML of the child element <w:name> of <w:settings>.
+func (s *SettingsManager) Element(name string) (string, bool) {
+	data, ok := s.d.files[settingsPath]
+	if !ok {
+		return "", false
+	}
+	loc := settingsElementRe(name).FindStringIndex(string(data))
+	if loc == nil {
+		return "", false
+	}
+	return string(data)[loc[0]:loc[1]], true
+}
+
+// SetElement replaces the element <w:name> with elementXML or inserts it in the schema order.
+func (s *SettingsManager) SetElement(name, elementXML string) {
+	content := s.ensure()
+	re := settingsElementRe(name)
+	if loc := re.FindStringIndex(content); loc != nil {
+		s.d.files[settingsPath] = []byte(content[:loc[0]] + elementXML + content[loc[1]:])
+		return
+	}
+
+	// insert before the first existing element that follows it in the schema
+	pos := strings.LastIndex(content, "</w:settings>")
+	if idx := settingsIndex(name); idx >= 0 {
+		for _, next := range settingsOrder[idx+1:] {
+			if loc := settingsElementRe(next).FindStringIndex(content); loc != nil && loc[0] < pos {
+				pos = loc[0]
+			}
+		}
+	}
+	s.d.files[settingsPath] = []byte(content[:pos] + elementXML + content[pos:])
+}
+
+// RemoveElement removes the element <w:name>. Returns false if there was none.
+func (s *SettingsManager) RemoveElement(name string) bool {
+	data, ok := s.d.files[settingsPath]
+	if !ok {
+		return false
+	}
+	re := settingsElementRe(name)
+	if !re.Match(data) {
+		return false
+	}
+	s.d.files[settingsPath] = re.ReplaceAll(data, nil)
+	return true
+}
+
+// onOff reads an on/off element: present without w:val or with a true value → true.
+func (s *SettingsManager) onOff(name string) bool {
+	el, ok := s.Element(name)
+	if !ok {
+		return false
+	}
+	switch xmlAttr(el, "w:val") {
+	case "false", "0", "off":
+		return false
+	}
+	return true
+}
+
+func (s *SettingsManager) setOnOff(name string, on bool) {
+	if on {
+		s.SetElement(name, "<w:"+name+"/>")
+		return
+	}
+	s.RemoveElement(name)
+}
+
+// ensure returns settings.xml, creating it with the relationship and the content type if it is missing.
+func (s *SettingsManager) ensure() string {
+	if data, ok := s.d.files[settingsPath]; ok && strings.Contains(string(data), "</w:settings>") {
+		return string(data)
+	}
+	content := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:settings xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"></w:settings>`
+	s.d.files[settingsPath] = []byte(content)
+	s.d.Rels().Add("document", Relationship{Type: relTypeSettings, Target: "settings.xml"})
+	s.d.ContentTypes().SetOverride(settingsPath, wmlType+"settings+xml")
+	return content
+}
+
+// settingsElementRe matches <w:name .../> or <w:name ...>...</w:name>.
+func settingsElementRe(name string) *regexp.Regexp {
+	q := regexp.QuoteMeta("w:" + name)
+	return pattern(`(?s)<` + q + `\b[^>]*/>|<` + q + `\b[^>]*>.*?</` + q + `>`)
+}
+
+func settingsIndex(name string) int {
+	for i, n := range settingsOrder {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// xmlAttr returns the value of the attribute in an element string (empty if there is none).
+func xmlAttr(el, attr string) string {
+	i := strings.Index(el, " "+attr+`="`)
+	if i < 0 {
+		return ""
+	}
+	rest := el[i+len(attr)+3:]
+	if j := strings.Index(rest, `"`); j >= 0 {
+		return rest[:j]
+	}
+	return ""
+}
@@ -0,0 +1,164 @@
+package docxgen
+
+import (
+	"docxgen/metrics"
+	"docxgen/modifiers"
+	"fmt"
+	"strings"
+)
+
+// ============================================================================
+// [signers/approvers] — the approval sheet: ФИО, должность, подпись, дата of every signer
+// ============================================================================
+
+// signersOpenPrefix - the start of the approval sheet marker.
+const signersOpenPrefix = "[signers/"
+
+// signerColumn - a column of the approval sheet.
+//   - field — the field of a signer ("" — the row number);
+//   - header — the text of the header;
+//   - share — the share of the text width;
+//   - ruled — a blank line of underscores to sign or date by hand when the field is empty.
+type signerColumn struct {
+	field, header string
+	share         float64
+	ruled         bool
+}
+
+// signerColumns - the known columns of cols:, by name.
+var signerColumns = map[string]signerColumn{
+	"num":      {header: "№", share: 0.06},
+	"fio":      {field: "fio", header: "ФИО", share: 0.28},
+	"position": {field: "position", header: "Должность", share: 0.28},
+	"sign":     {field: "sign", header: "Подпись", share: 0.22, ruled: true},
+	"date":     {field: "date", header: "Дата", share: 0.16, ruled: true},
+	"note":     {field: "note", header: "Замечания", share: 0.22},
+}
+
+// defaultSignerColumns - the columns of [signers/name] without cols:.
+const defaultSignerColumns = "fio,position,sign,date"
+
+// signerUnderscorePt - the width of "_" in Times New Roman 12 pt (half an em) when no fonts are loaded.
+const signerUnderscorePt = 6.0
+
+// signerCellMarginPt - the left and right margins of a table cell together (2 × 108 twips).
+const signerCellMarginPt = 10.8
+
+// parseSignerColumns reads "num,fio=Фамилия И.О.,position,sign,date": a known column or any field,
+// "=" renames the header; the widths are shared out by the columns in proportion.
+func parseSignerColumns(spec string) ([]signerColumn, error) {
+	var cols []signerColumn
+	total := 0.0
+	for _, c := range strings.Split(spec, ",") {
+		name, header, renamed := strings.Cut(strings.TrimSpace(c), "=")
+		if name == "" {
+			continue
+		}
+		col, ok := signerColumns[strings.ToLower(name)]
+		if !ok {
+			col = signerColumn{field: name, header: name, share: 0.2}
+		}
+		if renamed {
+			col.header = strings.ReplaceAll(header, "_", " ")
+		}
+		cols = append(cols, col)
+		total += col.share
+	}
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("cols:%s has no columns", spec)
+	}
+	for i := range cols {
+		cols[i].share /= total
+	}
+	return cols, nil
+}
+
+// ResolveSigners replaces the paragraphs with [signers/approvers] by the approval sheet of data[approvers]:
+// a header and a row per signer (a map with fio, position, date, ...). The columns are those of
+// cols:num,fio,position,sign,date,note in any order and set (fio,position,sign,date by default);
+// any other name is a field of the signer, "fio=Фамилия_И.О." renames the header ("_" is a space).
+// The signature and an empty date are blank lines of underscores as wide as the cell: measured with
+// the fonts of LoadFontsForPSplit, or taken for Times New Roman 12 pt.
+//
+// A broken marker or signers that are not a list are removed with the empty_signers warning.
+func (d *Docx) ResolveSigners(body string, data map[string]any) string {
+	return replaceMarkerParagraphs(body, signersOpenPrefix, func(spec string) string {
+		return d.renderSigners(spec, data)
+	})
+}
+
+// renderSigners builds the sheet of one marker; "" — nothing to show (a warning is issued).
+func (d *Docx) renderSigners(specText string, data map[string]any) string {
+	fields := strings.Fields(specText)
+	if len(fields) == 0 {
+		d.warn(WarnEmptySigners, specText, "want [signers/name ...]")
+		return ""
+	}
+	name, colSpec := fields[0], defaultSignerColumns
+	for _, f := range fields[1:] {
+		if key, val, _ := strings.Cut(f, ":"); strings.EqualFold(key, "cols") {
+			colSpec = val
+		}
+	}
+	cols, err := parseSignerColumns(colSpec)
+	if err != nil {
+		d.warn(WarnEmptySigners, name, "%v", err)
+		return ""
+	}
+	raw := blockValue(data, name)
+	items, ok := normalizeItems(raw)
+	if !ok || len(items) == 0 {
+		d.warn(WarnEmptySigners, name, "the signers are %T, not a list", raw)
+		return ""
+	}
+
+	usable := d.CurrentPageLayout().UsableWidth().Twips()
+	widths := make([]int, len(cols))
+	header := make([]string, len(cols))
+	for i, c := range cols {
+		widths[i] = int(float64(usable) * c.share)
+		header[i] = cellText(c.header, true, false)
+	}
+	rows := [][]string{header}
+	for n, it := range items {
+		rec := normalizeItem(it).mapVal
+		row := make([]string, len(cols))
+		for i, c := range cols {
+			var text string
+			switch {
+			case c.field == "":
+				text = fmt.Sprint(n + 1)
+			case c.field == "fio":
+				text = modifiers.ValueText(blockValue(rec, "fio"))
+				if text == "" {
+					text = modifiers.ValueText(blockValue(rec, "name"))
+				}
+			default:
+				text = modifiers.ValueText(blockValue(rec, c.field))
+			}
+			if text == "" && c.ruled {
+				row[i] = signatureLine(d.underscores(widths[i]))
+				continue
+			}
+			row[i] = cellText(text, false, false)
+		}
+		rows = append(rows, row)
+	}
+	return columnGridTable(rows, widths, nil)
+}
+
+// underscores - how many "_" of 12 pt fit into a cell of the width (twips) without its margins.
+func (d *Docx) underscores(width int) int {
+	unit := signerUnderscorePt
+	if d.fonts != nil {
+		if w, err := d.fonts.Measure("_", metrics.Regular, 12); err == nil && w > 0 {
+			unit = w
+		}
+	}
+	return max(1, int((float64(width)/20-signerCellMarginPt)/unit))
+}
+
+// signatureLine - a blank ruled line with room above it for the handwriting.
+func signatureLine(n int) string {
+	return `<w:p><w:pPr><w:spacing w:before="360"/></w:pPr><w:r><w:t>` + strings.Repeat("_", n) + `</w:t></w:r></w:p>`
+}
@@ -1,10 +1,17 @@
 package docxgen
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"reflect"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"unicode"
+
+	"docxgen/modifiers"
 )
 
 // ============================================================================
@@ -20,106 +27,528 @@ import (
 //
 // and replaces them with the result of RenderSmartTable(...) using items from data[name].
 //
+// The marker takes options after the name: [table/name/unmatched=skip|error|fallback] — what to do
+// with the items that fit no template row: leave them out (default), fail ExecuteTemplate with
+// ErrUnmatchedItems, or render them with the row that contains [unmatched]. Every such item is
+// reported as an unmatched_item warning.
+//
 // Option A (as agreed):
 //   - if there is no data, leave the table as it is,
 //     However, the paragraphs with the [table/...] and [/table] markers are removed.
 //   - if there is data, substitute the rendered table in place of the paragraph with [table/...],
 //     Delete the paragraph with [/table], cut out the original table from the block.
 //
-// It works without regulars, in the ResolveIncludes style.
+// The markers are found by paragraphs (with any attributes) at any depth: a block may stand
+// in a header or a footer, or inside a cell of another table, the table of the block may hold
+// tables of its own. The markers pair as brackets, and the outer block is resolved first: a block
+// in a row of its table is bound to a list of the item of the row, once per item, for the
+// order → items → components data:
+//
+//	[table/orders]
+//	| {number} | [table/items] | {name} | {qty} | [/table] |
+//	[/table]
+//
+// The nested block takes its data as a [for] block does: the fields of the item over the data of
+// the outer one. In the header and footer rows it takes the data of the outer block.
 func (d *Docx) ResolveTables(body string, data map[string]any) string {
-	const openPrefix = "[table/"
 	const closeTag = "[/table]"
 
 	for {
-		// 1) Looking for the opening marker
-		start := strings.Index(body, openPrefix)
-		if start < 0 {
+		markers := findTableMarkers(body)
+		blocks, unclosed := pairTableMarkers(markers)
+
+		if n := len(unclosed); n > 0 {
+			// if there is no closing marker, just delete the paragraph with the opening marker
+			om := markers[unclosed[n-1]]
+			name, _ := parseTableOptions(strings.TrimSuffix(strings.TrimPrefix(om.tag, tableOpenPrefix), "]"))
+			d.warn(WarnEmptyTable, name, "no closing %s", closeTag)
+			body = body[:om.start] + replaceInParagraph(om.text, om.tag, "") + body[om.end:]
+			continue
+		}
+		if len(blocks) == 0 {
 			break
 		}
+		// the first block is an outer one: the blocks in the rows of its table are rendered per item
+		om, cm := markers[blocks[0].open], markers[blocks[0].close]
+		name, opts := parseTableOptions(strings.TrimSuffix(strings.TrimPrefix(om.tag, tableOpenPrefix), "]"))
 
-		// 2) Looking for the end of the opening ']' tag
-		openEnd := strings.Index(body[start:], "]")
-		if openEnd < 0 {
-			// broken markup — delete the bullet paragraph and exit
-			body = ReplaceTagWithParagraph(body, body[start:], "")
-			break
+		// content between markers and the first table in it (with its nested tables)
+		inner := body[om.end:cm.start]
+		content := ""
+		tblStart := tableStart(inner, 0)
+		tblEnd := -1
+		if tblStart >= 0 {
+			tblEnd = tableEnd(inner, tblStart)
 		}
-		openEnd = start + openEnd + 1
+		if tblStart < 0 || tblEnd < 0 {
+			d.warn(WarnEmptyTable, name, "no table between the markers")
+		} else if rendered, ok := d.renderTableBlock(name, opts, inner[tblStart:tblEnd], data); ok {
+			// the rendered table takes the place of the opening marker, the source table is cut out
+			content = rendered
+			inner = inner[:tblStart] + inner[tblEnd:]
+		}
+
+		body = body[:om.start] + replaceInParagraph(om.text, om.tag, content) + inner +
+			replaceInParagraph(cm.text, closeTag, "") + body[cm.end:]
+	}
 
-		openTag := body[start:openEnd] // For example: [table/budget_report]
-		name := strings.TrimSuffix(strings.TrimPrefix(openTag, openPrefix), "]")
+	return ensureCellParagraphs(body)
+}
 
-		// 3) look for the closing marker [/table] AFTER the opening
-		closePos := strings.Index(body[openEnd:], closeTag)
-		if closePos < 0 {
-			// if there is no closing marker, just delete the paragraph with the opening marker
-			body = ReplaceTagWithParagraph(body, openTag, "")
-			break
+// renderTableBlock renders the table of [table/name] with data[name].
+// false — the source table stays as it is (no data, not a list, nothing rendered).
+func (d *Docx) renderTableBlock(name string, opts tableOptions, tableXML string, data map[string]any) (string, bool) {
+	raw, ok := data[name]
+	if !ok {
+		d.warn(WarnEmptyTable, name, "no data for the table, the template table is left as is")
+		return "", false
+	}
+	items, ok := normalizeItems(raw)
+	if !ok {
+		d.warn(WarnEmptyTable, name, "the data is %T, not a list of items", raw)
+		return "", false
+	}
+
+	if !d.takeRows("table "+name, len(items)) {
+		return "", false
+	}
+
+	opts.data = data
+	opts.nested = func(block string, item map[string]any) string {
+		scope := data
+		if item != nil {
+			scope = maps.Clone(data)
+			if scope == nil {
+				scope = map[string]any{}
+			}
+			maps.Copy(scope, item)
 		}
-		closePos = openEnd + closePos
+		return d.ResolveTables(block, scope)
+	}
+	rendered, report, err := renderSmartTable(tableXML, items, opts)
+	switch {
+	case err != nil:
+		d.warn(WarnEmptyTable, name, "%v", err)
+	case len(items) == 0:
+		d.warn(WarnEmptyTable, name, "the list is empty")
+	case report.matched == 0:
+		d.warn(WarnEmptyTable, name, "none of %d items matched a row of the table", len(items))
+	}
+	for _, u := range report.unmatched {
+		d.warn(WarnUnmatchedItem, name, "%s, %s", u, opts.unmatched.outcome())
+	}
+	if opts.unmatched == unmatchedError && len(report.unmatched) > 0 && d.tableErr == nil {
+		d.tableErr = fmt.Errorf("table %s: %w: %s", name, ErrUnmatchedItems, report.unmatched[0])
+	}
+	if err != nil || strings.TrimSpace(rendered) == "" {
+		return "", false
+	}
+	if !strings.Contains(rendered, "<w:tr") {
+		// an empty list and no header: the table goes away
+		return "", true
+	}
+	d.emit(TableRendered{Part: d.activePart, Name: name, Rows: len(items)})
+	return rendered, true
+}
 
-		// 4) content between markers
-		inner := body[openEnd:closePos]
+// tableOpenPrefix - the start of the opening marker [table/name].
+const tableOpenPrefix = "[table/"
 
-		// 5) Let's find the first table inside the block
-		tblStart := strings.Index(inner, "<w:tbl")
-		tblEnd := strings.Index(inner, "</w:tbl>")
-		if tblStart < 0 || tblEnd < 0 {
-			// There is no table, so remove both markers and move on
-			body = ReplaceTagWithParagraph(body, closeTag, "")
-			body = ReplaceTagWithParagraph(body, openTag, "")
+// tableMarker - a paragraph with [table/name] or [/table].
+//   - start, end — the bounds of the paragraph, text — its text;
+//   - tag — the marker as written, closing — [/table].
+type tableMarker struct {
+	start, end int
+	text, tag  string
+	closing    bool
+}
+
+// findTableMarkers lists the marker paragraphs of the body in order, at any depth.
+func findTableMarkers(body string) []tableMarker {
+	var markers []tableMarker
+	for pos := 0; ; {
+		start := paragraphStart(body, pos)
+		if start < 0 {
+			return markers
+		}
+		end := strings.Index(body[start:], ParagraphClosingTag)
+		if end < 0 {
+			return markers
+		}
+		end += start + len(ParagraphClosingTag)
+		pos = end
+
+		text := extractParagraphText(body[start:end])
+		if strings.Contains(text, "[/table]") {
+			markers = append(markers, tableMarker{start: start, end: end, text: text, tag: "[/table]", closing: true})
+			continue
+		}
+		i := strings.Index(text, tableOpenPrefix)
+		if i < 0 {
+			continue
+		}
+		j := strings.Index(text[i:], "]")
+		if j < 0 {
 			continue
 		}
-		tblEnd += len("</w:tbl>")
-		tableXML := inner[tblStart:tblEnd]
+		markers = append(markers, tableMarker{start: start, end: end, text: text, tag: text[i : i+j+1]})
+	}
+}
 
-		// 6) prepare the source code without blocks, if necessary
-		// (let's remove the closing bullet paragraph right away — we definitely don't need it)
-		body = ReplaceTagWithParagraph(body, closeTag, "")
+// tableBlock - a [table/...] block of findTableMarkers: the indexes of its markers and the number
+// of the blocks around it.
+type tableBlock struct {
+	open, close, depth int
+}
 
-		// 7) Let's check the availability of data
-		raw, ok := data[name]
-		if !ok {
-			// There is no data → leave the table as it is, only remove the markers:
-			body = ReplaceTagWithParagraph(body, openTag, "")
-			// (the original table remains in place between paragraphs)
+// pairTableMarkers pairs the markers as brackets: it gives the blocks in the order of their opening
+// markers and the opening markers without a pair. A closing marker without a pair is left alone.
+func pairTableMarkers(markers []tableMarker) (blocks []tableBlock, unclosed []int) {
+	for i, m := range markers {
+		if !m.closing {
+			unclosed = append(unclosed, i)
 			continue
 		}
+		if n := len(unclosed); n > 0 {
+			blocks = append(blocks, tableBlock{open: unclosed[n-1], close: i, depth: n - 1})
+			unclosed = unclosed[:n-1]
+		}
+	}
+	slices.SortFunc(blocks, func(a, b tableBlock) int { return a.open - b.open })
+	return blocks, unclosed
+}
+
+// nestedPlaceholder - the place of a nested block in a row of a smart table while the row is filled.
+const nestedPlaceholder = "\x00table:%d\x00"
 
-		// 8) normalize items and render
-		items, ok := normalizeItems(raw)
-		if !ok {
-			// Incorrect data format — leave the original table, removing the markers
-			body = ReplaceTagWithParagraph(body, openTag, "")
+// cutNestedTables takes the [table/...] … [/table] blocks out of a row of a smart table: they are
+// filled with their own list, and the fields of the row must not reach them.
+func cutNestedTables(row string) (string, []string) {
+	markers := findTableMarkers(row)
+	blocks, _ := pairTableMarkers(markers)
+	var (
+		b      strings.Builder
+		nested []string
+		last   int
+	)
+	for _, bl := range blocks {
+		if bl.depth > 0 {
 			continue
 		}
+		start, end := markers[bl.open].start, markers[bl.close].end
+		b.WriteString(row[last:start])
+		fmt.Fprintf(&b, nestedPlaceholder, len(nested))
+		nested = append(nested, row[start:end])
+		last = end
+	}
+	if nested == nil {
+		return row, nil
+	}
+	b.WriteString(row[last:])
+	return b.String(), nested
+}
 
-		rendered, err := RenderSmartTable(tableXML, items)
-		if err != nil || strings.TrimSpace(rendered) == "" {
-			// If it doesn't work, we'll keep the original table, and remove the opening bullet paragraph
-			body = ReplaceTagWithParagraph(body, openTag, "")
+// restoreNestedTables puts the blocks of cutNestedTables back into the filled row, rendered by fill.
+func restoreNestedTables(row string, nested []string, fill func(string) string) string {
+	for i, block := range nested {
+		row = strings.Replace(row, fmt.Sprintf(nestedPlaceholder, i), fill(block), 1)
+	}
+	return row
+}
+
+// tableStart - the next <w:tbl> or <w:tbl ...> (not <w:tblPr>) from pos, -1 if there is none.
+func tableStart(s string, pos int) int {
+	for {
+		i := strings.Index(s[pos:], "<w:tbl")
+		if i < 0 {
+			return -1
+		}
+		i += pos
+		if next := i + len("<w:tbl"); next < len(s) && (s[next] == '>' || s[next] == ' ') {
+			return i
+		}
+		pos = i + len("<w:tbl")
+	}
+}
+
+// tableEnd - the end of the table that starts at start, after its </w:tbl>; -1 if it is not closed.
+// Tables nested in its cells are skipped.
+func tableEnd(s string, start int) int {
+	depth := 0
+	pos := start
+	for {
+		open := tableStart(s, pos)
+		closeAt := strings.Index(s[pos:], TableEndingTag)
+		if closeAt < 0 {
+			return -1
+		}
+		closeAt += pos
+		if open >= 0 && open < closeAt {
+			depth++
+			pos = open + len("<w:tbl")
 			continue
 		}
+		depth--
+		pos = closeAt + len(TableEndingTag)
+		if depth == 0 {
+			return pos
+		}
+	}
+}
+
+// reCellWithoutParagraph - the end of a cell that has no paragraph after its last table (or no content).
+var reCellWithoutParagraph = regexp.MustCompile(`(</w:tbl>|</w:tcPr>|<w:tc>)(\s*)</w:tc>`)
+
+// ensureCellParagraphs adds an empty paragraph to the cells that end without one:
+// Word requires a paragraph as the last element of a cell.
+func ensureCellParagraphs(body string) string {
+	return reCellWithoutParagraph.ReplaceAllString(body, "$1$2<w:p/></w:tc>")
+}
+
+// ErrUnmatchedItems - a smart table with unmatched=error got items that fit none of its rows.
+var ErrUnmatchedItems = errors.New("items match no row of the table")
 
-		// 9) delete the source table from the document (the first occurrence within the block)
-		// Since we haven't touched inner itself yet, tableXML in the text still exists.
-		// Remove EXACTLY one occurrence so as not to touch other tables.
-		body = strings.Replace(body, tableXML, "", 1)
+// unmatchedRowMarker - the text of the row that takes the unmatched items (unmatched=fallback).
+const unmatchedRowMarker = "[unmatched]"
 
-		// 10) Substitute a rendered table instead of a paragraph with an opening marker
-		body = ReplaceTagWithParagraph(body, openTag, rendered)
+// unmatchedPolicy - what a smart table does with the items no template row fits.
+type unmatchedPolicy int
+
+const (
+	unmatchedSkip     unmatchedPolicy = iota // the item is left out (the default)
+	unmatchedError                           // ExecuteTemplate fails with ErrUnmatchedItems
+	unmatchedFallback                        // the item goes to the row marked [unmatched]
+)
 
-		// 11) The cycle will continue — looking for the next one [table/...]
+// outcome - what happened to an unmatched item, for the warning.
+func (p unmatchedPolicy) outcome() string {
+	switch p {
+	case unmatchedError:
+		return "the render fails"
+	case unmatchedFallback:
+		return "rendered with the " + unmatchedRowMarker + " row"
 	}
+	return "skipped"
+}
+
+// tableOptions - the options of the [table/name/...] marker.
+//   - unmatched — unmatched=skip|error|fallback;
+//   - cols — cols:1-3, the values of the list items the table takes;
+//   - split — split=N, at most N data rows per table, the next ones go to a new page with the header again;
+//   - sort — sort=date desc (also sort=department,-date): the order of the items before rendering;
+//   - group, subtotals — group=field subtotal=a,b: the items grouped by the field, each group with
+//     a title row and a row of the sums of the fields (see smart_table_groups.go);
+//   - collapseEmpty — collapse-empty-cols: the columns whose fields no item of the table fills are
+//     removed, with their header and footer cells (see smart_table_columns.go);
+//   - data — the data of the block, the values of the global tags of the rows;
+//   - nested — renders a block nested in a row with the fields of the item (nil — of no item, the header
+//     and footer rows); without it the nested blocks stay as they are.
+type tableOptions struct {
+	unmatched     unmatchedPolicy
+	cols          colRange
+	split         int
+	sort          []sortKey
+	group         string
+	subtotals     []string
+	collapseEmpty bool
+	data          map[string]any
+	nested        func(block string, item map[string]any) string
+}
 
-	return body
+// colRange - the columns 1-3, 4 or 4- (to the last one) of the list items; zero — all of them.
+type colRange struct {
+	from, to int
 }
 
+// parseColRange reads "1-3", "4" and "4-".
+func parseColRange(s string) (colRange, bool) {
+	lo, hi, isRange := strings.Cut(strings.TrimSpace(s), "-")
+	from, err := strconv.Atoi(strings.TrimSpace(lo))
+	if err != nil || from < 1 {
+		return colRange{}, false
+	}
+	to := from
+	if isRange {
+		if to = 0; strings.TrimSpace(hi) != "" {
+			if to, err = strconv.Atoi(strings.TrimSpace(hi)); err != nil || to < from {
+				return colRange{}, false
+			}
+		}
+	}
+	return colRange{from: from, to: to}, true
+}
+
+// apply gives the items with only the columns of the range: a list item ([...] or {"group": [...]})
+// is cut to them, so that %[1]s of the table is the first column of the range. Named fields are
+// picked by name anyway and stay as they are. The data itself is not changed: several tables may share it.
+func (c colRange) apply(items []any) []any {
+	if c.from == 0 {
+		return items
+	}
+	cut := func(vals []any) []any {
+		from, to := c.from-1, c.to
+		if to == 0 || to > len(vals) {
+			to = len(vals)
+		}
+		if from >= to {
+			return []any{}
+		}
+		return vals[from:to]
+	}
+
+	out := make([]any, len(items))
+	for i, it := range items {
+		out[i] = it
+		switch nit := normalizeItem(it); {
+		case nit.kind != "slice":
+		case nit.groupKey != "":
+			out[i] = map[string]any{nit.groupKey: cut(nit.sliceVal)}
+		default:
+			out[i] = cut(nit.sliceVal)
+		}
+	}
+	return out
+}
+
+// sortKey - a field of sort=..., desc — in descending order.
+type sortKey struct {
+	field string
+	desc  bool
+}
+
+// parseSortKeys reads "date", "department,-date" and "date:desc".
+func parseSortKeys(s string) []sortKey {
+	var keys []sortKey
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		k := sortKey{field: strings.TrimPrefix(f, "-"), desc: strings.HasPrefix(f, "-")}
+		if name, dir, ok := strings.Cut(k.field, ":"); ok {
+			k.field, k.desc = name, strings.EqualFold(dir, "desc")
+		}
+		if k.field != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// sortItems orders a copy of the items by the fields (the data itself is shared, see colRange.apply);
+// order[i] is the index in the data of the item i, for the reports. The sort is stable, the items
+// without the field go last in any direction.
+func sortItems(items []any, keys []sortKey) ([]any, []int) {
+	order := make([]int, len(items))
+	for i := range order {
+		order[i] = i
+	}
+	if len(keys) == 0 {
+		return items, order
+	}
+	fields := make([]map[string]any, len(items))
+	for i, it := range items {
+		fields[i] = normalizeItem(it).mapVal
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		for _, k := range keys {
+			va, okA := fields[a][k.field]
+			vb, okB := fields[b][k.field]
+			switch {
+			case !okA && !okB:
+				continue
+			case !okA:
+				return 1
+			case !okB:
+				return -1
+			}
+			if c := compareValues(va, vb); c != 0 {
+				if k.desc {
+					return -c
+				}
+				return c
+			}
+		}
+		return 0
+	})
+	sorted := make([]any, len(items))
+	for i, idx := range order {
+		sorted[i] = items[idx]
+	}
+	return sorted, order
+}
+
+// compareValues compares two values of the data: as numbers, as dates (time.Time, "15.01.2024",
+// "2024-01-15"), otherwise as text without regard to case.
+func compareValues(a, b any) int {
+	if x, ok := modifiers.Decimal(a); ok {
+		if y, ok := modifiers.Decimal(b); ok {
+			return x.Cmp(y)
+		}
+	}
+	if x, ok := modifiers.DateValue(a); ok {
+		if y, ok := modifiers.DateValue(b); ok {
+			return x.Compare(y)
+		}
+	}
+	return strings.Compare(strings.ToLower(modifiers.ValueText(a)), strings.ToLower(modifiers.ValueText(b)))
+}
+
+// parseTableOptions splits "orders/unmatched=error" or "items cols:1-3" into the data key and the options:
+// the options follow the name after "/" or a space, as key=value or key:value.
+// Unknown options are ignored.
+func parseTableOptions(spec string) (string, tableOptions) {
+	var opts tableOptions
+	parts := strings.FieldsFunc(spec, func(r rune) bool { return r == '/' || unicode.IsSpace(r) })
+	if len(parts) == 0 {
+		return "", opts
+	}
+	for _, p := range parts[1:] {
+		key, val, found := strings.Cut(p, "=")
+		if !found {
+			key, val, _ = strings.Cut(p, ":")
+		}
+		switch strings.ToLower(key) {
+		case "unmatched":
+			switch strings.ToLower(val) {
+			case "error":
+				opts.unmatched = unmatchedError
+			case "fallback":
+				opts.unmatched = unmatchedFallback
+			case "skip":
+				opts.unmatched = unmatchedSkip
+			}
+		case "cols":
+			if c, ok := parseColRange(val); ok {
+				opts.cols = c
+			}
+		case "split":
+			if n, err := strconv.Atoi(val); err == nil && n > 0 {
+				opts.split = n
+			}
+		case "sort":
+			opts.sort = parseSortKeys(val)
+		case "desc", "asc":
+			// sort=date desc: the direction of the last sort field
+			if n := len(opts.sort); n > 0 && val == "" {
+				opts.sort[n-1].desc = strings.EqualFold(key, "desc")
+			}
+		case "group":
+			opts.group = val
+		case "subtotal", "subtotals":
+			opts.subtotals = strings.FieldsFunc(val, func(r rune) bool { return r == ',' || r == ';' })
+		case "collapse-empty-cols":
+			opts.collapseEmpty = val == "" || !strings.EqualFold(val, "false")
+		}
+	}
+	return parts[0], opts
+}
+
+// normalizeItems gives the data of a table or a [for] block as a list of items. Besides lists of
+// maps and lists of lists it takes column-major data, {"fio": [...], "pos": [...]}: see transposeColumns.
 func normalizeItems(v any) ([]any, bool) {
 	switch x := v.(type) {
 	case []any:
 		return x, true
+	case map[string]any:
+		return transposeColumns(x)
 	case []map[string]any:
 		out := make([]any, len(x))
 		for i := range x {
@@ -156,6 +585,38 @@ func normalizeItems(v any) ([]any, bool) {
 	return nil, false
 }
 
+// transposeColumns turns column-major data {"fio": ["A", "B"], "pos": ["x", "y"]} into the items
+// {"fio": "A", "pos": "x"}, {"fio": "B", "pos": "y"}. Every value must be a list; the shorter columns
+// are filled with "" up to the longest one. false — the map is not column-major.
+func transposeColumns(m map[string]any) ([]any, bool) {
+	if len(m) == 0 {
+		return nil, false
+	}
+	cols := make(map[string]reflect.Value, len(m))
+	rows := 0
+	for k, v := range m {
+		rv := reflect.ValueOf(v)
+		if !rv.IsValid() || rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return nil, false
+		}
+		cols[k] = rv
+		rows = max(rows, rv.Len())
+	}
+
+	out := make([]any, rows)
+	for i := range out {
+		item := make(map[string]any, len(cols))
+		for k, rv := range cols {
+			item[k] = ""
+			if i < rv.Len() {
+				item[k] = rv.Index(i).Interface()
+			}
+		}
+		out[i] = item
+	}
+	return out, true
+}
+
 /*
  CANON:
 
@@ -183,11 +644,63 @@ type normItem struct {
 	sliceVal []any
 }
 
+// RenderSmartTable fills the template rows of the table with the items, see the CANON above.
 func RenderSmartTable(tableXML string, items []any) (string, error) {
+	out, _, err := renderSmartTable(tableXML, items, tableOptions{})
+	return out, err
+}
+
+// tableReport - how the items of a smart table were placed.
+//   - matched — the items that got a template row;
+//   - unmatched — the items no row fits (they are skipped or go to the [unmatched] row).
+type tableReport struct {
+	matched   int
+	unmatched []unmatchedItem
+}
+
+// unmatchedItem - an item of a smart table without a row.
+//   - index — its number in the data (from 1);
+//   - groupKey — the group of {"group": {...}} items, "" for a flat item;
+//   - fields — its keys, or the number of values of a list item.
+type unmatchedItem struct {
+	index    int
+	groupKey string
+	fields   string
+}
+
+func (u unmatchedItem) String() string {
+	s := fmt.Sprintf("item #%d", u.index)
+	if u.groupKey != "" {
+		s += fmt.Sprintf(" (group %q)", u.groupKey)
+	}
+	return s + " matched no row: " + u.fields
+}
+
+// renderSmartTable is RenderSmartTable with the options of the marker; it also reports
+// which items got a row.
+func renderSmartTable(tableXML string, items []any, opts tableOptions) (string, tableReport, error) {
+	var report tableReport
+	items, order := sortItems(opts.cols.apply(items), opts.sort)
 	inner := stripOuterTable(tableXML)
+	// the tblPr/tblGrid of the template go once, before the rows
+	props := ""
+	if i := strings.Index(inner, "<w:tr"); i > 0 {
+		props, inner = inner[:i], inner[i:]
+	}
 	rows := extractTableRows(inner)
 	if len(rows) == 0 {
-		return "", fmt.Errorf("smart table: no rows found")
+		return "", report, fmt.Errorf("smart table: no rows found")
+	}
+	// the blocks nested in the rows wait for the items, see restore
+	nested := make([][]string, len(rows))
+	for i, r := range rows {
+		rows[i], nested[i] = cutNestedTables(r)
+	}
+	restore := func(row int, xml string, item map[string]any) string {
+		if opts.nested == nil {
+			return restoreNestedTables(xml, nested[row], func(block string) string { return block })
+		}
+		return restoreNestedTables(xml, nested[row], func(block string) string { return opts.nested(block, item) })
 	}
 
 	// 1) Mark up the rows of the table: header / templateRows / footer
@@ -203,33 +716,50 @@ func RenderSmartTable(tableXML string, items []any) (string, error) {
 		tplRows     []tplRow
 		firstTplIdx = -1
 		lastTplIdx  = -1
+		fallback    = ""
+		fallbackIdx = -1
+		groupRows   groupTemplate
 	)
 	localKeys := collectLocalKeys(items)
 	for i, r := range rows {
+		// the row for the items no other row fits (unmatched=fallback)
+		if fallback == "" && strings.Contains(extractParagraphText(r), unmatchedRowMarker) {
+			fallback = strings.Replace(r, unmatchedRowMarker, "", 1)
+			fallbackIdx = i
+			continue
+		}
+		// the title and subtotal rows of the groups (group=field)
+		if groupRows.mark(r, nested[i]) {
+			continue
+		}
 		m := parseTplMeta(r)
 		isPos := m.percentSeen > 0
-		isNamed := !isPos && len(m.names) > 0 && metaHasAnyKnown(m, localKeys)
+		// with an empty list every row with tags is a template row: only the header and footer are left
+		isNamed := !isPos && len(m.names) > 0 && (len(items) == 0 || metaHasAnyKnown(m, localKeys))
 		isStatic := !isPos && !isNamed
 		tr := tplRow{idx: i, xml: r, meta: m, isNamed: isNamed, isPos: isPos, isStatic: isStatic}
 		if isNamed || isPos {
 			if firstTplIdx == -1 {
-				firstTplIdx = i
+				firstTplIdx = len(tplRows)
 			}
-			lastTplIdx = i
+			lastTplIdx = len(tplRows)
 		}
 		tplRows = append(tplRows, tr)
 	}
+	if opts.unmatched != unmatchedFallback {
+		fallback = ""
+	}
 
 	// Header/ Footer
 	var headerRows, footerRows []string
 	if firstTplIdx > 0 {
 		for i := 0; i < firstTplIdx; i++ {
-			headerRows = append(headerRows, tplRows[i].xml)
+			headerRows = append(headerRows, restore(tplRows[i].idx, tplRows[i].xml, nil))
 		}
 	}
 	if lastTplIdx >= 0 && lastTplIdx < len(tplRows)-1 {
 		for i := lastTplIdx + 1; i < len(tplRows); i++ {
-			footerRows = append(footerRows, tplRows[i].xml)
+			footerRows = append(footerRows, restore(tplRows[i].idx, tplRows[i].xml, nil))
 		}
 	}
 
@@ -242,21 +772,24 @@ func RenderSmartTable(tableXML string, items []any) (string, error) {
 	}
 	if len(templates) == 0 {
 		// There are no template rows → return the original table
-		return TableOpeningTag + inner + TableEndingTag, nil
+		return TableOpeningTag + inner + TableEndingTag, report, nil
 	}
 
-	var nitems []normItem
-	for _, it := range items {
-		ni := normalizeItem(it)
-		if ni.kind == "other" {
-			// Single scalars are not supported as meaningful strings (we'll leave them for later)
-			continue
+	// Single scalars ("other") match no row: they are reported as unmatched
+	nitems := make([]normItem, len(items))
+	rowItems := 0
+	for i, it := range items {
+		nitems[i] = normalizeItem(it)
+		if nitems[i].kind != "other" {
+			rowItems++
 		}
-		nitems = append(nitems, ni)
 	}
-	if len(nitems) == 0 {
+	if rowItems == 0 && fallback == "" {
 		// only header+footer
-		return TableOpeningTag + strings.Join(headerRows, "") + strings.Join(footerRows, "") + TableEndingTag, nil
+		for i := range nitems {
+			report.unmatched = append(report.unmatched, describeUnmatched(order[i], nitems[i]))
+		}
+		return assembleTable(props, headerRows, nil, footerRows, 0, nil), report, nil
 	}
 
 	// 3) Matching Phase#1: key→template binding, plus waitZone
@@ -381,32 +914,167 @@ func RenderSmartTable(tableXML string, items []any) (string, error) {
 		}
 	}
 
+	// collapse-empty-cols: the columns every filled template row leaves empty; the fallback row
+	// of the unmatched items is not looked into, with it nothing is collapsed
+	var collapsed map[int]bool
+	if opts.collapseEmpty && (fallback == "" || !slices.Contains(assigned, -1)) {
+		for tIdx, b := range buckets {
+			if len(b.items) == 0 {
+				continue
+			}
+			empty := map[int]bool{}
+			if t := templates[tIdx]; t.isNamed {
+				values := make([]map[string]any, len(b.items))
+				for n, i := range b.items {
+					values[n] = nitems[i].mapVal
+				}
+				empty = emptyColumns(t.xml, values, unionFields[tIdx], opts.data)
+			}
+			if collapsed == nil {
+				collapsed = empty
+				continue
+			}
+			for col := range collapsed {
+				if !empty[col] {
+					delete(collapsed, col)
+				}
+			}
+		}
+		if len(collapsed) >= tableColumns(props, rows) {
+			collapsed = nil // not a table without columns
+		}
+	}
+
 	// 4) Result generation: HEADER + (based on data) + FOOTER
 	var outRows []string
-	if len(headerRows) > 0 {
-		outRows = append(outRows, headerRows...)
-	}
 
+	fallbackUnion := make(map[string]struct{})
 	for i, it := range nitems {
+		if assigned[i] < 0 {
+			for k := range it.mapVal {
+				fallbackUnion[k] = struct{}{}
+			}
+		}
+	}
+	renderItem := func(i int) {
+		it := nitems[i]
 		tidx := assigned[i]
 		if tidx < 0 {
-			// skip
-			continue
+			report.unmatched = append(report.unmatched, describeUnmatched(order[i], it))
+			if fallback != "" {
+				outRows = append(outRows, restore(fallbackIdx, renderFallbackRow(fallback, it, fallbackUnion), it.mapVal))
+			}
+			return
 		}
+		report.matched++
 		t := templates[tidx]
 		if t.isPos {
-			outRows = append(outRows, renderPositional(t.xml, it.sliceVal))
-			continue
+			outRows = append(outRows, restore(t.idx, renderPositional(t.xml, it.sliceVal), nil))
+			return
 		}
 		// named
-		outRows = append(outRows, renderNamedWithUnion(t.xml, t.meta, it.mapVal, unionFields[tidx]))
+		outRows = append(outRows, restore(t.idx, renderNamedWithUnion(t.xml, t.meta, it.mapVal, unionFields[tidx]), it.mapVal))
 	}
+	if opts.group == "" {
+		for i := range nitems {
+			renderItem(i)
+		}
+		props = collapseColumns(collapsed, props, headerRows, outRows, footerRows)
+		return assembleTable(props, headerRows, outRows, footerRows, opts.split, nil), report, nil
+	}
+
+	// group=field: a title row, the rows of the group and its subtotal row, the groups in the order of the data
+	columns := tableColumns(props, rows)
+	titles := map[int]bool{}
+	for _, g := range groupItems(nitems, opts) {
+		titles[len(outRows)] = true
+		outRows = append(outRows, groupRows.titleRow(g, localKeys, columns))
+		for _, i := range g.items {
+			renderItem(i)
+		}
+		if row, ok := groupRows.subtotalRow(g, localKeys); ok {
+			outRows = append(outRows, row)
+			continue
+		}
+		if len(opts.subtotals) == 0 {
+			continue
+		}
+		// no [subtotal] row: the data row of the group with the sums, the other fields empty
+		for _, i := range g.items {
+			if tidx := assigned[i]; tidx >= 0 && templates[tidx].isNamed {
+				t := templates[tidx]
+				outRows = append(outRows, restoreNestedTables(renderNamedWithUnion(t.xml, t.meta, g.values, localKeys), nested[t.idx], dropBlock))
+				break
+			}
+		}
+	}
+	props = collapseColumns(collapsed, props, headerRows, outRows, footerRows)
+	return assembleTable(props, headerRows, outRows, footerRows, opts.split, titles), report, nil
+}
+
+// pageBreakParagraph - the paragraph between the parts of a split table.
+const pageBreakParagraph = `<w:p><w:r><w:br w:type="page"/></w:r></w:p>`
 
-	if len(footerRows) > 0 {
-		outRows = append(outRows, footerRows...)
+// assembleTable puts the table properties, the header, the data rows and the footer into a table.
+// With split > 0 the data rows go into tables of at most split rows each, one per page:
+// every table repeats the properties and the header, the footer ends the last one.
+// The rows of keepWithNext (the group titles) do not end a table, they go over with the row after them.
+func assembleTable(props string, header, rows, footer []string, split int, keepWithNext map[int]bool) string {
+	if split <= 0 || len(rows) <= split {
+		split = max(len(rows), 1)
 	}
 
-	return TableOpeningTag + strings.Join(outRows, "") + TableEndingTag, nil
+	var b strings.Builder
+	for from := 0; ; {
+		to := min(from+split, len(rows))
+		for to < len(rows) && to-1 > from && keepWithNext[to-1] {
+			to--
+		}
+		if from > 0 {
+			b.WriteString(pageBreakParagraph)
+		}
+		b.WriteString(TableOpeningTag)
+		b.WriteString(props)
+		b.WriteString(strings.Join(header, ""))
+		b.WriteString(strings.Join(rows[from:to], ""))
+		if to == len(rows) {
+			b.WriteString(strings.Join(footer, ""))
+		}
+		b.WriteString(TableEndingTag)
+		if to == len(rows) {
+			return b.String()
+		}
+		from = to
+	}
+}
+
+// renderFallbackRow fills the [unmatched] row with an item: the fields of a map item as {name},
+// the values of a list or a scalar as %[N]s. The fields that other unmatched items have (union)
+// and the missing %[N]s become empty, the other tags stay global.
+func renderFallbackRow(row string, it normItem, union map[string]struct{}) string {
+	row = renderNamedWithUnion(row, parseTplMeta(row), it.mapVal, union)
+	switch it.kind {
+	case "map":
+		return renderPositional(row, nil)
+	case "slice":
+		return renderPositional(row, it.sliceVal)
+	}
+	return renderPositional(row, []any{it.raw})
+}
+
+// describeUnmatched - the report entry of the item with the number i (from 0).
+func describeUnmatched(i int, it normItem) unmatchedItem {
+	u := unmatchedItem{index: i + 1, groupKey: it.groupKey}
+	switch it.kind {
+	case "map":
+		keys := slices.Sorted(maps.Keys(it.mapVal))
+		u.fields = "fields " + strings.Join(keys, ", ")
+	case "slice":
+		u.fields = fmt.Sprintf("%d values", len(it.sliceVal))
+	default:
+		u.fields = fmt.Sprintf("a %T value", it.raw)
+	}
+	return u
 }
 
 func metaHasAnyKnown(meta tplMeta, known map[string]struct{}) bool {
@@ -419,7 +1087,7 @@ func metaHasAnyKnown(meta tplMeta, known map[string]struct{}) bool {
 }
 
 // collectLocalKeys pulls the names of local fields from the input items.
-// Look at {"group": { ... }} and flat map[string]any without slices.
+// Look at {"group": { ... }} and flat map[string]any.
 func collectLocalKeys(items []any) map[string]struct{} {
 	keys := make(map[string]struct{})
 	for _, it := range items {
@@ -428,28 +1096,29 @@ func collectLocalKeys(items []any) map[string]struct{} {
 		case map[string]any:
 			// {"group": {...}} = Taking the keys from the Inner Map
 			if len(m) == 1 {
-				for _, v := range m {
-					if inner, ok := v.(map[string]any); ok {
+				for name, v := range m {
+					switch inner := v.(type) {
+					case map[string]any:
+						for k := range inner {
+							keys[k] = struct{}{}
+						}
+					case map[string]string:
 						for k := range inner {
 							keys[k] = struct{}{}
 						}
+					case []any, []string:
+						// {"group": [...]} is positional
+					default:
+						// a flat item of one field
+						keys[name] = struct{}{}
 					}
 				}
 				break
 			}
 
-			// Flat map without slices = also considered local keys
-			flat := true
-			for _, v := range m {
-				switch v.(type) {
-				case []any, []string:
-					flat = false
-				}
-			}
-			if flat {
-				for k := range m {
-					keys[k] = struct{}{}
-				}
+			// Flat map = also considered local keys (its lists are the data of the nested tables)
+			for k := range m {
+				keys[k] = struct{}{}
 			}
 		}
 	}
@@ -460,6 +1129,13 @@ func collectLocalKeys(items []any) map[string]struct{} {
 // Rendering helpers
 // ============================================================================
 
+var (
+	// reNameMod - {name|mod...} of a smart table template.
+	reNameMod = regexp.MustCompile(`\{[ \t]*([A-Za-z0-9_.]+)[ \t]*\|([^}]*)}`)
+	// reBacktickMod - {`...%[N]s...`|mod} of a positional smart table template.
+	reBacktickMod = regexp.MustCompile("(?s)\\{[ \\t]*`([^`]*)`[ \\t]*\\|([^}]*)}")
+)
+
 // L1/L2-bucket/L3-global/L4-leave implementation:
 // - if the name in the data → is substituted
 // - otherwise, if the name is present in union (found in other bucket items) → substitute ""
@@ -468,7 +1144,6 @@ func renderNamedWithUnion(xmlTpl string, meta tplMeta, data map[string]any, unio
 	out := xmlTpl
 
 	// 1) {name|mod...}
-	reNameMod := regexp.MustCompile(`\{[ \t]*([A-Za-z0-9_.]+)[ \t]*\|([^}]*)}`)
 	out = reNameMod.ReplaceAllStringFunc(out, func(tok string) string {
 		m := reNameMod.FindStringSubmatch(tok)
 		if len(m) != 3 {
@@ -477,7 +1152,7 @@ func renderNamedWithUnion(xmlTpl string, meta tplMeta, data map[string]any, unio
 		name := m[1]
 		modTail := strings.TrimSpace(m[2])
 		if valAny, ok := data[name]; ok {
-			val := fmt.Sprint(valAny)
+			val := literalText(valAny)
 			return "{ `" + val + "` | " + modTail + " }"
 		}
 		// L2 — if a field occurs in bucket → an empty string via the
@@ -491,9 +1166,9 @@ func renderNamedWithUnion(xmlTpl string, meta tplMeta, data map[string]any, unio
 	// 2) Pure {name}
 	for _, name := range meta.names {
 		// Clean is exactly { name } without a pipe
-		reExact := regexp.MustCompile(`\{[ \t]*` + regexp.QuoteMeta(name) + `[ \t]*\}`)
+		reExact := pattern(`\{[ \t]*` + regexp.QuoteMeta(name) + `[ \t]*\}`)
 		if valAny, ok := data[name]; ok {
-			val := fmt.Sprint(valAny)
+			val := modifiers.ValueText(valAny)
 			out = reExact.ReplaceAllString(out, val)
 			continue
 		}
@@ -513,7 +1188,6 @@ func renderNamedWithUnion(xmlTpl string, meta tplMeta, data map[string]any, unio
 func renderPositional(xmlTpl string, arr []any) string {
 	out := xmlTpl
 
-	reBacktickMod := regexp.MustCompile("(?s)\\{[ \\t]*`([^`]*)`[ \\t]*\\|([^}]*)}")
 	out = reBacktickMod.ReplaceAllStringFunc(out, func(tok string) string {
 		m := reBacktickMod.FindStringSubmatch(tok)
 		if len(m) != 3 {
@@ -541,7 +1215,7 @@ func replacePerc(s string, arr []any) string {
 		if idx < 0 || idx >= len(arr) {
 			return ""
 		}
-		return fmt.Sprint(arr[idx])
+		return modifiers.ValueText(arr[idx])
 	})
 }
 
@@ -605,14 +1279,8 @@ func normalizeItem(v any) normItem {
 	}
 
 	// fallback: flat map (we'll treat it as a one-time map-item without an explicit groupKey)
+	// (the lists in its values are the data of the tables nested in the row)
 	if m, ok := v.(map[string]any); ok {
-		// If there are slices in the values, do not count map-item
-		for _, vv := range m {
-			switch vv.(type) {
-			case []any, []string:
-				return normItem{raw: v, kind: "other"}
-			}
-		}
 		return normItem{raw: v, kind: "map", mapVal: m}
 	}
 
@@ -646,14 +1314,27 @@ func stripOuterTable(s string) string {
 
 func extractTableRows(tbl string) []string {
 	s := strings.TrimSpace(tbl)
-	parts := strings.Split(s, TableRowClosingTag)
 	var rows []string
-	for _, p := range parts {
-		if strings.Contains(strings.ToLower(p), TableRowPartTag) {
-			rows = append(rows, p+TableRowClosingTag)
+	last, pos := 0, 0
+	for {
+		closeAt := strings.Index(s[pos:], TableRowClosingTag)
+		if closeAt < 0 {
+			return rows
+		}
+		closeAt += pos
+		// rows of the tables nested in the cells belong to their row
+		if open := tableStart(s, pos); open >= 0 && open < closeAt {
+			if end := tableEnd(s, open); end > 0 {
+				pos = end
+				continue
+			}
+		}
+		pos = closeAt + len(TableRowClosingTag)
+		if p := s[last:pos]; strings.Contains(strings.ToLower(p), TableRowPartTag) {
+			rows = append(rows, p)
 		}
+		last = pos
 	}
-	return rows
 }
 
 /*
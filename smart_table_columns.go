@@ -0,0 +1,177 @@
+package docxgen
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"docxgen/modifiers"
+)
+
+// ============================================================================
+// Smart table columns: [table/items collapse-empty-cols]
+// ============================================================================
+
+// tableCell - a cell of a table row.
+//   - start, end — its bounds in the row, <w:tc>…</w:tc>;
+//   - col, span — the first column of the grid it takes and the number of the columns (gridSpan).
+type tableCell struct {
+	start, end, col, span int
+}
+
+var (
+	// reGridSpan - the gridSpan of a cell.
+	reGridSpan = regexp.MustCompile(`<w:gridSpan w:val="(\d+)"/>`)
+	// reGridCol - a column of the table grid.
+	reGridCol = regexp.MustCompile(`<w:gridCol\b[^>]*/>`)
+)
+
+// rowCells lists the cells of a row in order; the cells of the tables nested in them are skipped.
+func rowCells(row string) []tableCell {
+	var cells []tableCell
+	col := 0
+	for pos := 0; ; {
+		start := cellStart(row, pos)
+		if start < 0 {
+			return cells
+		}
+		end := cellEnd(row, start)
+		if end < 0 {
+			return cells
+		}
+		span := 1
+		props := row[start:end]
+		if i := strings.Index(props, "</w:tcPr>"); i >= 0 {
+			props = props[:i]
+			if m := reGridSpan.FindStringSubmatch(props); m != nil {
+				if n, err := strconv.Atoi(m[1]); err == nil && n > 1 {
+					span = n
+				}
+			}
+		}
+		cells = append(cells, tableCell{start: start, end: end, col: col, span: span})
+		col += span
+		pos = end
+	}
+}
+
+// cellStart - the next <w:tc> or <w:tc ...> (not <w:tcPr>) from pos, -1 if there is none.
+func cellStart(s string, pos int) int {
+	for {
+		i := strings.Index(s[pos:], "<w:tc")
+		if i < 0 {
+			return -1
+		}
+		i += pos
+		if next := i + len("<w:tc"); next < len(s) && (s[next] == '>' || s[next] == ' ') {
+			return i
+		}
+		pos = i + len("<w:tc")
+	}
+}
+
+// cellEnd - the end of the cell that starts at start, after its </w:tc>; -1 if it is not closed.
+func cellEnd(s string, start int) int {
+	const closing = "</w:tc>"
+	for pos := start; ; {
+		closeAt := strings.Index(s[pos:], closing)
+		if closeAt < 0 {
+			return -1
+		}
+		closeAt += pos
+		if open := tableStart(s, pos); open >= 0 && open < closeAt {
+			if pos = tableEnd(s, open); pos < 0 {
+				return -1
+			}
+			continue
+		}
+		return closeAt + len(closing)
+	}
+}
+
+// emptyColumns - the columns of the template row whose cells have no global tag (of data) and
+// none of the items has a value in their fields.
+func emptyColumns(row string, items []map[string]any, union map[string]struct{}, data map[string]any) map[int]bool {
+	empty := map[int]bool{}
+	for _, c := range rowCells(row) {
+		names := parseTplMeta(row[c.start:c.end]).names
+		if c.span != 1 || len(names) == 0 {
+			continue
+		}
+		if cellIsEmpty(names, items, union, data) {
+			empty[c.col] = true
+		}
+	}
+	return empty
+}
+
+// cellIsEmpty - whether all the names are absent or empty in each of the items (union — their fields)
+// and none of them is a global tag.
+func cellIsEmpty(names []string, items []map[string]any, union map[string]struct{}, data map[string]any) bool {
+	for _, name := range names {
+		if _, local := union[name]; !local {
+			root, _, _ := strings.Cut(name, ".")
+			if _, global := data[root]; global {
+				return false
+			}
+			continue // a field none of the items has
+		}
+		for _, it := range items {
+			if v, ok := it[name]; ok && v != nil && strings.TrimSpace(modifiers.ValueText(v)) != "" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// dropColumns removes the columns from a row: the cells of only these columns go away,
+// a wider cell loses them from its gridSpan.
+func dropColumns(row string, cols map[int]bool) string {
+	cells := rowCells(row)
+	for i := len(cells) - 1; i >= 0; i-- {
+		c := cells[i]
+		dropped := 0
+		for col := c.col; col < c.col+c.span; col++ {
+			if cols[col] {
+				dropped++
+			}
+		}
+		switch {
+		case dropped == 0:
+		case dropped == c.span:
+			row = row[:c.start] + row[c.end:]
+		default:
+			cell := reGridSpan.ReplaceAllString(row[c.start:c.end], fmt.Sprintf(`<w:gridSpan w:val="%d"/>`, c.span-dropped))
+			row = row[:c.start] + cell + row[c.end:]
+		}
+	}
+	return row
+}
+
+// dropGridColumns removes the columns from the grid of the table properties.
+func dropGridColumns(props string, cols map[int]bool) string {
+	n := -1
+	return reGridCol.ReplaceAllStringFunc(props, func(col string) string {
+		n++
+		if cols[n] {
+			return ""
+		}
+		return col
+	})
+}
+
+// collapseColumns removes the columns from the rows (in place) and from the grid of the table
+// properties, which it returns.
+func collapseColumns(cols map[int]bool, props string, rows ...[]string) string {
+	if len(cols) == 0 {
+		return props
+	}
+	for _, part := range rows {
+		for i := range part {
+			part[i] = dropColumns(part[i], cols)
+		}
+	}
+	return dropGridColumns(props, cols)
+}
@@ -0,0 +1,131 @@
+package docxgen
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"docxgen/modifiers"
+)
+
+// ============================================================================
+// Smart table groups: [table/budget group=department subtotal=amount]
+// ============================================================================
+
+// The rows of a group are marked in the template like the [unmatched] row:
+//
+//	| [group]{department}                 |               |
+//	| {title}                             | {amount}      |
+//	| [subtotal]Итого по {department}     | {amount|money}|
+//
+// The title and subtotal rows take the group field and the sums of the subtotal fields. Without
+// a [group] row the title is a bold row across the table; without a [subtotal] row the sums go
+// into the data row of the group, its other fields empty.
+const (
+	groupRowMarker    = "[group]"
+	subtotalRowMarker = "[subtotal]"
+)
+
+// groupTemplate - the [group] and [subtotal] rows of a smart table, without the markers;
+// nested — the blocks cut out of them (they are dropped: a group has no list of its own).
+type groupTemplate struct {
+	title, subtotal             string
+	titleNested, subtotalNested []string
+}
+
+// mark takes the row if it is the [group] or the [subtotal] one.
+func (g *groupTemplate) mark(row string, nested []string) bool {
+	text := extractParagraphText(row)
+	switch {
+	case g.title == "" && strings.Contains(text, groupRowMarker):
+		g.title, g.titleNested = strings.Replace(row, groupRowMarker, "", 1), nested
+	case g.subtotal == "" && strings.Contains(text, subtotalRowMarker):
+		g.subtotal, g.subtotalNested = strings.Replace(row, subtotalRowMarker, "", 1), nested
+	default:
+		return false
+	}
+	return true
+}
+
+// titleRow - the title row of the group: the [group] row, or a bold row of columns cells across the table.
+func (g *groupTemplate) titleRow(tg tableGroup, union map[string]struct{}, columns int) string {
+	if g.title != "" {
+		return restoreNestedTables(renderNamedWithUnion(g.title, parseTplMeta(g.title), tg.values, union), g.titleNested, dropBlock)
+	}
+	span := ""
+	if columns > 1 {
+		span = fmt.Sprintf(`<w:tcPr><w:gridSpan w:val="%d"/></w:tcPr>`, columns)
+	}
+	return "<w:tr><w:tc>" + span + cellText(tg.value, true, false) + "</w:tc></w:tr>"
+}
+
+// subtotalRow - the [subtotal] row of the group; false — the template has none.
+func (g *groupTemplate) subtotalRow(tg tableGroup, union map[string]struct{}) (string, bool) {
+	if g.subtotal == "" {
+		return "", false
+	}
+	return restoreNestedTables(renderNamedWithUnion(g.subtotal, parseTplMeta(g.subtotal), tg.values, union), g.subtotalNested, dropBlock), true
+}
+
+// tableGroup - the items of a smart table with one value of the group field.
+//   - value — the value as text, "" for the items without the field;
+//   - items — the indexes of the items in the data order;
+//   - values — the fields of the title and subtotal rows: the group field and the sums.
+type tableGroup struct {
+	value  string
+	items  []int
+	values map[string]any
+}
+
+// groupItems splits the items by the group field in the order of the first item of every group
+// and sums the subtotal fields of each group exactly; a field without numbers stays empty.
+func groupItems(items []normItem, opts tableOptions) []tableGroup {
+	var groups []tableGroup
+	index := map[string]int{}
+	for i, it := range items {
+		raw := it.mapVal[opts.group]
+		value := ""
+		if raw != nil {
+			value = modifiers.ValueText(raw)
+		}
+		n, ok := index[value]
+		if !ok {
+			n = len(groups)
+			index[value] = n
+			groups = append(groups, tableGroup{value: value, values: map[string]any{opts.group: value}})
+		}
+		groups[n].items = append(groups[n].items, i)
+	}
+
+	for gi := range groups {
+		for _, field := range opts.subtotals {
+			var sum *big.Rat
+			for _, i := range groups[gi].items {
+				if r, ok := modifiers.Decimal(items[i].mapVal[field]); ok {
+					if sum == nil {
+						sum = new(big.Rat)
+					}
+					sum.Add(sum, r)
+				}
+			}
+			if sum != nil {
+				groups[gi].values[field] = ratText(sum)
+			}
+		}
+	}
+	return groups
+}
+
+// tableColumns - the number of the columns of a table: its grid, or the cells of the first row.
+func tableColumns(props string, rows []string) int {
+	if n := strings.Count(props, "<w:gridCol"); n > 0 {
+		return n
+	}
+	if len(rows) == 0 {
+		return 1
+	}
+	return max(strings.Count(rows[0], "<w:tc>")+strings.Count(rows[0], "<w:tc "), 1)
+}
+
+// dropBlock removes a nested block from a row that has no item of its own.
+func dropBlock(string) string { return "" }
@@ -0,0 +1,195 @@
+package docxgen
+
+import (
+	"docxgen/geometry"
+	"docxgen/modifiers"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/vector"
+)
+
+// Sparkline - Inserts a tiny inline chart (line, area or bar) of an array of numbers.
+// The parameters are described in modifiers.Sparkline.
+func (d *Docx) Sparkline(value any, opts ...string) modifiers.RawXML {
+	values := modifiers.ParseNumbers(value)
+	if len(values) == 0 {
+		return ""
+	}
+
+	// ---------- Default parameters ----------
+	width, height := geometry.FromMM(30), geometry.FromMM(8)
+	kind := "line"
+	lineColor := "1565C0"
+	dot := true
+
+	// ---------- Parsing options ----------
+	for _, token := range opts {
+		token = strings.TrimSpace(token)
+		switch lower := strings.ToLower(token); {
+		case lower == "":
+			continue
+		case lower == "line" || lower == "area" || lower == "bar":
+			kind = lower
+		case lower == "dot":
+			dot = true
+		case lower == "nodot":
+			dot = false
+		case strings.HasSuffix(lower, "mm"):
+			w, h, err := geometry.ParseSize(lower, 0, 0)
+			if err != nil || w == 0 {
+				continue
+			}
+			width, height = w, h
+			if h == 0 {
+				height = w / 4
+			}
+		default:
+			if hex, ok := modifiers.ParseColor(token); ok {
+				lineColor = hex
+			}
+		}
+	}
+
+	w, h := width.Pixels(rasterDPI), height.Pixels(rasterDPI)
+	if !d.takeRaster("sparkline", w, h) {
+		return ""
+	}
+	img := renderSparkline(values, kind, dot, hexToRGBA(lineColor), w, h)
+	data, err := encodePNG(img)
+	if err != nil {
+		return ""
+	}
+	rId, base := d.AddImageRel(data)
+
+	id, name := d.nextDrawing(base)
+	drawing := inlinePictureXML(id, rId, name, width.EMU(), height.EMU())
+	return modifiers.RawXML(runBreakout(drawing))
+}
+
+// renderSparkline draws the chart on a transparent background.
+func renderSparkline(values []float64, kind string, dot bool, c color.RGBA, w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+	if kind == "bar" {
+		// the columns grow from zero
+		lo = math.Min(lo, 0)
+		hi = math.Max(hi, 0)
+	}
+
+	stroke := math.Max(2, float64(h)/16)
+	pad := stroke * 1.5 // room for the last-point dot
+	plotW, plotH := float64(w)-2*pad, float64(h)-2*pad
+
+	y := func(v float64) float64 {
+		if hi == lo {
+			return pad + plotH/2
+		}
+		return pad + plotH*(hi-v)/(hi-lo)
+	}
+
+	if kind == "bar" {
+		n := float64(len(values))
+		slot := plotW / n
+		zero := y(0)
+		for i, v := range values {
+			x0 := pad + slot*float64(i) + slot*0.15
+			x1 := x0 + slot*0.7
+			top, bottom := math.Min(y(v), zero), math.Max(y(v), zero)
+			if bottom-top < 1 {
+				bottom = top + 1
+			}
+			fillPolygon(img, c, [][2]float64{{x0, top}, {x1, top}, {x1, bottom}, {x0, bottom}})
+		}
+		return img
+	}
+
+	points := make([][2]float64, len(values))
+	for i, v := range values {
+		x := pad + plotW/2
+		if len(values) > 1 {
+			x = pad + plotW*float64(i)/float64(len(values)-1)
+		}
+		points[i] = [2]float64{x, y(v)}
+	}
+
+	if kind == "area" && len(points) > 1 {
+		base := float64(h) - pad
+		poly := append([][2]float64{{points[0][0], base}}, points...)
+		poly = append(poly, [2]float64{points[len(points)-1][0], base})
+		area := c
+		area.A = 0x50
+		area.R, area.G, area.B = premul(c.R, 0x50), premul(c.G, 0x50), premul(c.B, 0x50)
+		fillPolygon(img, area, poly)
+	}
+
+	for i := 1; i < len(points); i++ {
+		strokeSegment(img, c, points[i-1], points[i], stroke)
+	}
+	if dot || len(points) == 1 {
+		last := points[len(points)-1]
+		fillPolygon(img, c, circlePolygon(last, stroke*1.4))
+	}
+	return img
+}
+
+// strokeSegment draws a segment of the given width with rounded ends (so the joints of a polyline are smooth).
+func strokeSegment(img *image.RGBA, c color.RGBA, p0, p1 [2]float64, width float64) {
+	dx, dy := p1[0]-p0[0], p1[1]-p0[1]
+	length := math.Hypot(dx, dy)
+	if length > 0 {
+		nx, ny := -dy/length*width/2, dx/length*width/2
+		fillPolygon(img, c, [][2]float64{
+			{p0[0] + nx, p0[1] + ny}, {p1[0] + nx, p1[1] + ny},
+			{p1[0] - nx, p1[1] - ny}, {p0[0] - nx, p0[1] - ny},
+		})
+	}
+	fillPolygon(img, c, circlePolygon(p1, width/2))
+}
+
+// fillPolygon fills a closed polygon with antialiasing over what is already drawn.
+func fillPolygon(img *image.RGBA, c color.RGBA, poly [][2]float64) {
+	if len(poly) < 3 {
+		return
+	}
+	b := img.Bounds()
+	r := vector.NewRasterizer(b.Dx(), b.Dy())
+	r.DrawOp = draw.Over
+	r.MoveTo(float32(poly[0][0]), float32(poly[0][1]))
+	for _, p := range poly[1:] {
+		r.LineTo(float32(p[0]), float32(p[1]))
+	}
+	r.ClosePath()
+	r.Draw(img, b, image.NewUniform(c), image.Point{})
+}
+
+// circlePolygon approximates a circle with a polygon.
+func circlePolygon(center [2]float64, radius float64) [][2]float64 {
+	const n = 16
+	poly := make([][2]float64, n)
+	for i := range poly {
+		a := 2 * math.Pi * float64(i) / n
+		poly[i] = [2]float64{center[0] + radius*math.Cos(a), center[1] + radius*math.Sin(a)}
+	}
+	return poly
+}
+
+// hexToRGBA converts "RRGGBB" to an opaque color.
+func hexToRGBA(hex string) color.RGBA {
+	v, _ := strconv.ParseUint(hex, 16, 32)
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xFF}
+}
+
+// premul multiplies a color channel by alpha (color.RGBA is alpha-premultiplied).
+func premul(ch, a uint8) uint8 {
+	return uint8(uint16(ch) * uint16(a) / 0xFF)
+}
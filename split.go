@@ -0,0 +1,213 @@
+package docxgen
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	rePageBreak       = regexp.MustCompile(`<w:br\b[^>]*w:type="page"[^>]*/>`)
+	rePageBreakBefore = regexp.MustCompile(`<w:pageBreakBefore(?:\s+w:val="(?:1|true|on)")?\s*/>`)
+	reVisibleContent  = regexp.MustCompile(`<w:(?:t|drawing|pict|object|sym|tbl)\b`)
+)
+
+// SplitBySections splits the document into a document per section, so that a bulk-generated file
+// (one section per recipient) can be delivered piece by piece. Every piece keeps the page setup
+// and the headers and footers of its section (a section without its own takes those of the previous
+// one, as in Word); the other parts — styles, lists, pictures — are shared, unused pictures are
+// dropped on Save. The document itself is not changed; a document without a body gives nil.
+func (d *Docx) SplitBySections() []*Docx {
+	head, body, sect, tail, ok := d.splitBody()
+	if !ok {
+		return nil
+	}
+	var pieces []*Docx
+	var inherited []string
+	last := 0
+	for _, loc := range reSectPr.FindAllStringIndex(body, -1) {
+		rest := body[loc[1]:]
+		end := strings.Index(rest, "</w:p>")
+		if end < 0 {
+			break
+		}
+		pos := loc[1] + end + len("</w:p>")
+		own := body[loc[0]:loc[1]]
+		own, inherited = inheritHeaders(own, inherited)
+		pieces = append(pieces, d.withBody(head, body[last:loc[0]]+body[loc[1]:pos], own, tail))
+		last = pos
+	}
+	sect, _ = inheritHeaders(sect, inherited)
+	return append(pieces, d.withBody(head, body[last:], sect, tail))
+}
+
+// SplitByPageBreaks splits the document into a document per page started by hand: at every page
+// break (<w:br w:type="page"/>, as PageBreak and AppendDocument insert them) and before every paragraph
+// with "page break before". The breaks themselves and the empty paragraphs left of them are removed,
+// every piece takes the page setup of the last section. Breaks inside tables are not split at.
+// The document itself is not changed; a document without a body gives nil.
+func (d *Docx) SplitByPageBreaks() []*Docx {
+	head, body, sect, tail, ok := d.splitBody()
+	if !ok {
+		return nil
+	}
+	var pieces []*Docx
+	var current strings.Builder
+	flush := func() {
+		if s := current.String(); reVisibleContent.MatchString(s) {
+			pieces = append(pieces, d.withBody(head, s, sect, tail))
+		}
+		current.Reset()
+	}
+	for _, block := range topLevelBlocks(body) {
+		if !strings.HasPrefix(block, "<w:p>") && !strings.HasPrefix(block, "<w:p ") {
+			current.WriteString(block)
+			continue
+		}
+		if loc := rePageBreakBefore.FindStringIndex(block); loc != nil {
+			flush()
+			block = block[:loc[0]] + block[loc[1]:]
+		}
+		split := false
+		for {
+			loc := rePageBreak.FindStringIndex(block)
+			if loc == nil {
+				break
+			}
+			before, after := splitParagraph(block, loc[0], loc[1])
+			if reVisibleContent.MatchString(before) {
+				current.WriteString(before)
+			}
+			flush()
+			block, split = after, true
+		}
+		if !split || reVisibleContent.MatchString(block) {
+			current.WriteString(block)
+		}
+	}
+	flush()
+	if len(pieces) == 0 {
+		pieces = append(pieces, d.withBody(head, body, sect, tail))
+	}
+	return pieces
+}
+
+// splitBody cuts document.xml into the part up to the body content, the content, the body level
+// sectPr and the rest.
+func (d *Docx) splitBody() (head, body, sect, tail string, ok bool) {
+	doc := string(d.files["word/document.xml"])
+	start := strings.Index(doc, BodyOpeningTag)
+	end := strings.LastIndex(doc, BodyClosingTag)
+	if start < 0 || end < start {
+		return "", "", "", "", false
+	}
+	start += len(BodyOpeningTag)
+	at := bodyEnd(doc[:end], end)
+	if at < start {
+		at = end
+	}
+	return doc[:start], doc[start:at], doc[at:end], doc[end:], true
+}
+
+// withBody - a copy of the document with the body content and the body level sectPr replaced.
+func (d *Docx) withBody(head, body, sect, tail string) *Docx {
+	c := d.Clone()
+	c.files["word/document.xml"] = []byte(head + body + sect + tail)
+	return c
+}
+
+// inheritHeaders gives a sectPr without header and footer references those of the previous section
+// and returns the references in force after it.
+func inheritHeaders(sect string, prev []string) (string, []string) {
+	own := reHdrFtrRefTag.FindAllString(sect, -1)
+	if len(own) > 0 {
+		return sect, own
+	}
+	if len(prev) == 0 {
+		return sect, prev
+	}
+	open := strings.Index(sect, ">") + 1
+	if strings.HasSuffix(sect[:open], "/>") {
+		return sect[:open-2] + ">" + strings.Join(prev, "") + "</w:sectPr>", prev
+	}
+	return sect[:open] + strings.Join(prev, "") + sect[open:], prev
+}
+
+// topLevelBlocks splits the body content into its elements (paragraphs, tables, bookmarks, ...) and
+// the text between them; nested paragraphs of tables and text boxes stay inside their element.
+func topLevelBlocks(body string) []string {
+	var blocks []string
+	for i := 0; i < len(body); {
+		lt := strings.IndexByte(body[i:], '<')
+		if lt < 0 {
+			blocks = append(blocks, body[i:])
+			break
+		}
+		if lt > 0 {
+			blocks = append(blocks, body[i:i+lt])
+			i += lt
+		}
+		end := elementEnd(body, i)
+		blocks = append(blocks, body[i:end])
+		i = end
+	}
+	return blocks
+}
+
+// elementEnd - the end of the element that starts at s[start] (after its closing tag).
+func elementEnd(s string, start int) int {
+	gt := strings.IndexByte(s[start:], '>')
+	if gt < 0 {
+		return len(s)
+	}
+	open := s[start : start+gt+1]
+	if strings.HasSuffix(open, "/>") || strings.HasPrefix(open, "</") || strings.HasPrefix(open, "<?") {
+		return start + gt + 1
+	}
+	name := strings.TrimPrefix(strings.FieldsFunc(open, func(r rune) bool {
+		return r == ' ' || r == '>' || r == '\t' || r == '\n' || r == '\r'
+	})[0], "<")
+	depth := 0
+	for i := start; i < len(s); {
+		next := strings.Index(s[i:], name)
+		if next < 0 {
+			return len(s)
+		}
+		i += next
+		after := i + len(name)
+		if after >= len(s) || !strings.ContainsRune(" >/\t\r\n", rune(s[after])) {
+			i = after
+			continue
+		}
+		switch {
+		case i > 1 && s[i-2:i] == "</":
+			depth--
+			if depth == 0 {
+				return i + strings.IndexByte(s[i:], '>') + 1
+			}
+		case s[i-1] == '<':
+			if gt := strings.IndexByte(s[i:], '>'); gt < 0 || s[i+gt-1] != '/' {
+				depth++
+			}
+		}
+		i = after
+	}
+	return len(s)
+}
+
+// splitParagraph cuts the paragraph at the break s[from:to]: the part before it ends its run and
+// the paragraph, the part after it starts with the paragraph and the run properties again.
+func splitParagraph(p string, from, to int) (before, after string) {
+	pOpen := p[:strings.IndexByte(p, '>')+1]
+	if i := strings.Index(p, "</w:pPr>"); i >= 0 && i < from {
+		pOpen = p[:i+len("</w:pPr>")]
+	}
+	run := max(strings.LastIndex(p[:from], "<w:r>"), strings.LastIndex(p[:from], "<w:r "))
+	if run < 0 {
+		return p[:from] + "</w:p>", pOpen + p[to:]
+	}
+	rOpen := p[run : run+strings.IndexByte(p[run:], '>')+1]
+	if i := strings.Index(p[run:from], "</w:rPr>"); i >= 0 {
+		rOpen = p[run : run+i+len("</w:rPr>")]
+	}
+	return p[:from] + "</w:r></w:p>", pOpen + rOpen + p[to:]
+}
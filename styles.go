@@ -0,0 +1,300 @@
+package docxgen
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ============================================================================
+// Styles of styles.xml: the list, registration of new ones and their use by the tags
+// ============================================================================
+
+var (
+	reStyleName    = regexp.MustCompile(`<w:name\b[^>]*\bw:val="([^"]*)"`)
+	reStyleBasedOn = regexp.MustCompile(`<w:basedOn\b[^>]*\bw:val="([^"]*)"`)
+	reStyleRef     = regexp.MustCompile(`<w:(?:pStyle|rStyle|tblStyle)\b[^>]*/>`)
+)
+
+// Style - a style of styles.xml.
+//   - ID — w:styleId, as pStyle, rStyle and tblStyle reference it;
+//   - Type — "paragraph", "character", "table" or "numbering";
+//   - Name — the name Word shows ("heading 1", "Сумма прописью");
+//   - BasedOn — the id of the style it inherits from, if any.
+type Style struct {
+	ID      string
+	Type    string
+	Name    string
+	BasedOn string
+}
+
+// StylesManager - access to word/styles.xml, like SettingsManager: it works directly on the files
+// of the document, styles.xml (with its relationship and content type) is created on the first
+// registered style if the template has none.
+type StylesManager struct {
+	d *Docx
+}
+
+// Styles returns the styles.xml manager of the document.
+func (d *Docx) Styles() *StylesManager {
+	return &StylesManager{d: d}
+}
+
+// List returns the styles in the order of styles.xml.
+func (s *StylesManager) List() []Style {
+	var styles []Style
+	for _, el := range reStyle.FindAllString(string(s.d.files[stylesPath]), -1) {
+		styles = append(styles, parseStyle(el))
+	}
+	return styles
+}
+
+// Lookup returns the style by its id.
+func (s *StylesManager) Lookup(id string) (Style, bool) {
+	el, ok := s.XML(id)
+	if !ok {
+		return Style{}, false
+	}
+	return parseStyle(el), true
+}
+
+// XML returns the <w:style> element of the style with the id.
+func (s *StylesManager) XML(id string) (string, bool) {
+	for _, el := range reStyle.FindAllString(string(s.d.files[stylesPath]), -1) {
+		if styleID(el) == id {
+			return el, true
+		}
+	}
+	return "", false
+}
+
+// Register adds the <w:style> element to styles.xml; a style with the same type and id is replaced,
+// so a template registers its styles on every render without duplicates:
+//
+//	doc.Styles().Register(`<w:style w:type="character" w:customStyle="1" w:styleId="Total">` +
+//		`<w:name w:val="Итог"/><w:rPr><w:b/><w:color w:val="1F3864"/></w:rPr></w:style>`)
+func (s *StylesManager) Register(styleXML string) error {
+	styleXML = strings.TrimSpace(styleXML)
+	if reStyle.FindString(styleXML) != styleXML {
+		return fmt.Errorf("register style: not a <w:style> element")
+	}
+	if styleID(styleXML) == "" || xmlAttr(styleXML[:strings.IndexByte(styleXML, '>')+1], "w:type") == "" {
+		return fmt.Errorf("register style: w:type and w:styleId are required")
+	}
+
+	content := string(s.d.files[stylesPath])
+	if !strings.Contains(content, "</w:styles>") {
+		content = xml.Header + `<w:styles xmlns:w="` + wordMainNamespace + `"></w:styles>`
+		s.d.setPart(stylesPath, []byte(content), relTypeStyles, wmlType+"styles+xml")
+	}
+	key := styleKey(styleXML)
+	for _, loc := range reStyle.FindAllStringIndex(content, -1) {
+		if styleKey(content[loc[0]:loc[1]]) == key {
+			s.d.files[stylesPath] = []byte(content[:loc[0]] + styleXML + content[loc[1]:])
+			return nil
+		}
+	}
+	end := strings.LastIndex(content, "</w:styles>")
+	s.d.files[stylesPath] = []byte(content[:end] + styleXML + content[end:])
+	return nil
+}
+
+// Remove removes the style with the id; the references to it stay and fall back to the defaults.
+// Returns false if there was none.
+func (s *StylesManager) Remove(id string) bool {
+	content := string(s.d.files[stylesPath])
+	for _, loc := range reStyle.FindAllStringIndex(content, -1) {
+		if styleID(content[loc[0]:loc[1]]) == id {
+			s.d.files[stylesPath] = []byte(content[:loc[0]] + content[loc[1]:])
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterStyle adds or replaces a style of styles.xml, see StylesManager.Register.
+func (d *Docx) RegisterStyle(styleXML string) error {
+	return d.Styles().Register(styleXML)
+}
+
+// ApplyStyleToTag gives the style of styles.xml to where the tag ("{total}", "[table/goods]") stands
+// in the template, in the body, the headers, the footers and the notes: a paragraph style to its
+// paragraphs, a character style to the runs of the tag, a table style to the innermost table around
+// it. Called before ExecuteTemplate, so the rows of a smart table and the copies of a block take the
+// style from their template row. Returns how many paragraphs, runs or tables got the style.
+func (d *Docx) ApplyStyleToTag(tag, styleID string) (int, error) {
+	style, ok := d.Styles().Lookup(styleID)
+	if !ok {
+		return 0, fmt.Errorf("apply style: no style %q in styles.xml", styleID)
+	}
+	if tag == "" {
+		return 0, fmt.Errorf("apply style: empty tag")
+	}
+	applied := 0
+	for _, part := range d.templateParts() {
+		name := "word/" + part + ".xml"
+		content, ok := d.files[name]
+		if !ok {
+			continue
+		}
+		s, n := applyStyle(string(content), tag, style)
+		if n > 0 {
+			d.files[name] = []byte(s)
+			applied += n
+		}
+	}
+	return applied, nil
+}
+
+// applyStyle gives the style to the paragraphs (runs, tables) of s with the tag in their text.
+func applyStyle(s, tag string, style Style) (string, int) {
+	applied := 0
+	var tables []int // the starts of the tables already styled
+	for pos := 0; ; {
+		start := paragraphStart(s, pos)
+		if start < 0 {
+			return s, applied
+		}
+		end := elementEnd(s, start)
+		pos = end
+		text := xmlUnescaper.Replace(extractParagraphText(s[start:end]))
+		if !strings.Contains(text, tag) {
+			continue
+		}
+
+		var p string
+		switch style.Type {
+		case "paragraph":
+			p = setProperty(s[start:end], "pPr", "pStyle", style.ID)
+			applied++
+		case "character":
+			var n int
+			p, n = styleTagRuns(s[start:end], text, tag, style.ID)
+			applied += n
+		case "table":
+			if tbl := enclosingTable(s, start); tbl >= 0 && !slices.Contains(tables, tbl) {
+				tblEnd := elementEnd(s, tbl)
+				styled := setProperty(s[tbl:tblEnd], "tblPr", "tblStyle", style.ID)
+				s = s[:tbl] + styled + s[tblEnd:]
+				tables = append(tables, tbl)
+				pos = end + len(styled) - (tblEnd - tbl)
+				applied++
+			}
+			continue
+		default:
+			return s, applied
+		}
+		s = s[:start] + p + s[end:]
+		pos = start + len(p)
+	}
+}
+
+// styleTagRuns gives the character style to the runs of the paragraph p (with the unescaped text)
+// that hold the characters of the tag.
+func styleTagRuns(p, text, tag, id string) (string, int) {
+	var spans [][2]int // the occurrences of the tag in the text
+	for from := 0; ; {
+		i := strings.Index(text[from:], tag)
+		if i < 0 {
+			break
+		}
+		spans = append(spans, [2]int{from + i, from + i + len(tag)})
+		from += i + len(tag)
+	}
+
+	var out strings.Builder
+	styled, offset, pos := 0, 0, 0
+	for {
+		run := runStart(p, pos)
+		if run < 0 {
+			break
+		}
+		runEnd := elementEnd(p, run)
+		r := p[run:runEnd]
+		n := len(xmlUnescaper.Replace(extractParagraphText(r)))
+		out.WriteString(p[pos:run])
+		for _, sp := range spans {
+			if n > 0 && offset < sp[1] && offset+n > sp[0] {
+				r = setProperty(r, "rPr", "rStyle", id)
+				styled++
+				break
+			}
+		}
+		out.WriteString(r)
+		offset += n
+		pos = runEnd
+	}
+	out.WriteString(p[pos:])
+	return out.String(), styled
+}
+
+// runStart - the start of the next <w:r> or <w:r ...> of s from pos (not rPr), -1 if none.
+func runStart(s string, pos int) int {
+	for {
+		i := strings.Index(s[pos:], "<w:r")
+		if i < 0 {
+			return -1
+		}
+		i += pos
+		if next := i + len("<w:r"); next < len(s) && (s[next] == '>' || s[next] == ' ') {
+			return i
+		}
+		pos = i + len("<w:r")
+	}
+}
+
+// setProperty sets <w:prop w:val="id"/> as the first element of the <w:props> of the element el
+// (a paragraph, a run, a table), creating the properties if there are none.
+func setProperty(el, props, prop, id string) string {
+	ref := `<w:` + prop + ` w:val="` + xmlEscape(id) + `"/>`
+	open := el[:strings.IndexByte(el, '>')+1]
+	rest := el[len(open):]
+	switch {
+	case strings.HasPrefix(rest, "<w:"+props+"/>"):
+		return open + "<w:" + props + ">" + ref + "</w:" + props + ">" + rest[len("<w:"+props+"/>"):]
+	case strings.HasPrefix(rest, "<w:"+props+">") || strings.HasPrefix(rest, "<w:"+props+" "):
+		end := elementEnd(rest, 0)
+		gt := strings.IndexByte(rest, '>') + 1
+		inner := reStyleRef.ReplaceAllStringFunc(rest[gt:end], func(r string) string {
+			if strings.HasPrefix(r, "<w:"+prop+" ") {
+				return ""
+			}
+			return r
+		})
+		return open + rest[:gt] + ref + inner + rest[end:]
+	}
+	return open + "<w:" + props + ">" + ref + "</w:" + props + ">" + rest
+}
+
+// enclosingTable - the start of the innermost table of s around the position, -1 if none.
+func enclosingTable(s string, at int) int {
+	for i := at; ; {
+		i = max(strings.LastIndex(s[:i], "<w:tbl>"), strings.LastIndex(s[:i], "<w:tbl "))
+		if i < 0 {
+			return -1
+		}
+		if elementEnd(s, i) > at {
+			return i
+		}
+	}
+}
+
+// parseStyle reads the id, the type, the name and the parent of a <w:style>.
+func parseStyle(el string) Style {
+	open := el[:strings.IndexByte(el, '>')+1]
+	st := Style{ID: xmlAttr(open, "w:styleId"), Type: xmlAttr(open, "w:type")}
+	if m := reStyleName.FindStringSubmatch(el); m != nil {
+		st.Name = xmlUnescaper.Replace(m[1])
+	}
+	if m := reStyleBasedOn.FindStringSubmatch(el); m != nil {
+		st.BasedOn = m[1]
+	}
+	return st
+}
+
+// styleID - w:styleId of a <w:style>.
+func styleID(el string) string {
+	return xmlAttr(el[:strings.IndexByte(el, '>')+1], "w:styleId")
+}
@@ -2,13 +2,17 @@ package docxgen
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
+	"docxgen/modifiers"
+
 	securejoin "github.com/cyphar/filepath-securejoin"
 )
 
@@ -20,12 +24,22 @@ import (
 // If the tag was the only content, the paragraph is simply cut out.
 // If the paragraph had text before or after the tag, they turn into separate <w:p>.
 func ReplaceTagWithParagraph(body, tag, content string) string {
+	return ReplaceTagsWithParagraphs(body, map[string]string{tag: content})
+}
+
+// ReplaceTagsWithParagraphs - ReplaceTagWithParagraph for many tags at once (tag → XML fragment):
+// the body is scanned once, whatever the number of tags, and a paragraph may hold several of them.
+func ReplaceTagsWithParagraphs(body string, replacements map[string]string) string {
 	const (
 		openTag  = "<w:p>"
 		closeTag = "</w:p>"
 	)
+	if len(replacements) == 0 {
+		return body
+	}
 
 	var out strings.Builder
+	out.Grow(len(body))
 	pos := 0
 
 	for {
@@ -46,43 +60,133 @@ func ReplaceTagWithParagraph(body, tag, content string) string {
 		paragraph := body[start:end]
 		text := extractParagraphText(paragraph)
 
-		// тег НЕ найден: просто копируем как есть
-		if !strings.Contains(text, tag) {
+		// тегов нет: просто копируем как есть
+		if tag, _ := firstTag(text, replacements); tag == "" {
 			out.WriteString(body[pos:end])
 			pos = end
 			continue
 		}
 
-		// тег найден - обрабатываем
-		if strings.TrimSpace(text) == tag {
-			out.WriteString(body[pos:start])
-			out.WriteString(content)
-			pos = end
+		// теги найдены - обрабатываем
+		out.WriteString(body[pos:start])
+		replaceTagsInParagraph(&out, text, replacements)
+
+		pos = end
+	}
+
+	return out.String()
+}
+
+// firstTag - the tag of the replacements met first in the text (the longest one of those at the same
+// place) and its position; "" if the text has none.
+func firstTag(text string, replacements map[string]string) (string, int) {
+	first, at := "", -1
+	for tag := range replacements {
+		if tag == "" {
 			continue
 		}
+		i := strings.Index(text, tag)
+		if i >= 0 && (at < 0 || i < at || i == at && len(tag) > len(first)) {
+			first, at = tag, i
+		}
+	}
+	return first, at
+}
 
-		// иначе делим текст на before/after и строим параграфы
-		before, after, _ := strings.Cut(text, tag)
+// replaceTagsInParagraph writes the fragments of the tags of the paragraph text to out, the text
+// between the tags turning into separate <w:p>.
+func replaceTagsInParagraph(out *strings.Builder, text string, replacements map[string]string) {
+	for {
+		tag, at := firstTag(text, replacements)
+		if tag == "" {
+			writeTextParagraph(out, text)
+			return
+		}
+		writeTextParagraph(out, text[:at])
+		out.WriteString(replacements[tag])
+		text = text[at+len(tag):]
+	}
+}
 
-		out.WriteString(body[pos:start])
+// writeTextParagraph writes the text as a plain <w:p>, unless it is blank.
+func writeTextParagraph(out *strings.Builder, text string) {
+	if text = strings.TrimSpace(text); text != "" {
+		out.WriteString(`<w:p><w:r><w:t xml:space="preserve">`)
+		out.WriteString(xmlEscape(text))
+		out.WriteString(`</w:t></w:r></w:p>`)
+	}
+}
 
-		if strings.TrimSpace(before) != "" {
-			out.WriteString(`<w:p><w:r><w:t xml:space="preserve">`)
-			out.WriteString(xmlEscape(strings.TrimSpace(before)))
-			out.WriteString(`</w:t></w:r></w:p>`)
+// replaceMarkerParagraphs replaces every marker "prefix...]" of the paragraphs (at any depth) with the
+// block XML that render gives for the text between the prefix and "]"; the text around the marker stays.
+func replaceMarkerParagraphs(body, prefix string, render func(spec string) string) string {
+	for pos := 0; ; {
+		start := paragraphStart(body, pos)
+		if start < 0 {
+			return body
+		}
+		end := strings.Index(body[start:], ParagraphClosingTag)
+		if end < 0 {
+			return body
 		}
+		end += start + len(ParagraphClosingTag)
 
-		out.WriteString(content)
+		text := extractParagraphText(body[start:end])
+		i := strings.Index(text, prefix)
+		if i < 0 {
+			pos = end
+			continue
+		}
+		j := strings.Index(text[i:], "]")
+		if j < 0 {
+			pos = end
+			continue
+		}
+		tag := text[i : i+j+1]
+		repl := replaceInParagraph(text, tag, render(strings.TrimSuffix(strings.TrimPrefix(tag, prefix), "]")))
+		body = body[:start] + repl + body[end:]
+		pos = start + len(repl)
+	}
+}
 
-		if strings.TrimSpace(after) != "" {
-			out.WriteString(`<w:p><w:r><w:t xml:space="preserve">`)
-			out.WriteString(xmlEscape(strings.TrimSpace(after)))
-			out.WriteString(`</w:t></w:r></w:p>`)
+// replaceTagParagraphs replaces every paragraph (at any depth, with or without attributes) whose text has
+// tag with content, the text around the tag turning into separate <w:p>.
+func replaceTagParagraphs(body, tag, content string) string {
+	for pos := 0; ; {
+		start := paragraphStart(body, pos)
+		if start < 0 {
+			return body
 		}
+		end := strings.Index(body[start:], ParagraphClosingTag)
+		if end < 0 {
+			return body
+		}
+		end += start + len(ParagraphClosingTag)
 
-		pos = end
+		text := extractParagraphText(body[start:end])
+		if !strings.Contains(text, tag) {
+			pos = end
+			continue
+		}
+		var out strings.Builder
+		replaceTagsInParagraph(&out, text, map[string]string{tag: content})
+		body = body[:start] + out.String() + body[end:]
+		pos = start + out.Len()
+	}
+}
+
+// replaceInParagraph - the replacement of a paragraph with the text that contains tag:
+// the text before and after the tag turns into separate <w:p>.
+func replaceInParagraph(text, tag, content string) string {
+	if strings.TrimSpace(text) == tag {
+		return content
 	}
+	before, after, _ := strings.Cut(text, tag)
 
+	var out strings.Builder
+	writeTextParagraph(&out, before)
+	out.WriteString(content)
+	writeTextParagraph(&out, after)
 	return out.String()
 }
 
@@ -152,20 +256,23 @@ func xmlEscape(s string) string {
 
 // ProcessUnWrapParagraphTags - Looks for {*tag*} and turns it into block {tags}.
 func (d *Docx) ProcessUnWrapParagraphTags(body string) string {
-	for {
-		start := strings.Index(body, "{*")
+	replacements := map[string]string{}
+	for pos := 0; ; {
+		start := strings.Index(body[pos:], "{*")
 		if start == -1 {
-			return body
+			break
 		}
+		start += pos
 		endRel := strings.Index(body[start:], "*}")
 		if endRel == -1 {
-			return body
+			break
 		}
 
 		starTag := body[start : start+endRel+2] // "{*tag*}"
-		name := strings.TrimSpace(body[start+2 : start+endRel])
-		body = ReplaceTagWithParagraph(body, starTag, "{"+name+"}")
+		replacements[starTag] = "{" + strings.TrimSpace(body[start+2:start+endRel]) + "}"
+		pos = start + endRel + 2
 	}
+	return ReplaceTagsWithParagraphs(body, replacements)
 }
 
 // RepairTags — restores {tag} and [include] after Word tore them at <w:t>.
@@ -222,32 +329,65 @@ func (d *Docx) RepairTags(body string) (string, error) {
 // [include/file.docs], [include/file.docs/table/2], [include/file.docs/p/3]
 //============================================================================
 
+// ResolveIncludes replaces the paragraphs with [include/...] by the fragments of the documents; the tags
+// found are resolved first and put in one pass over the body, the includes of the fragments — in the next.
 func (d *Docx) ResolveIncludes(body string, data map[string]any) string {
 	for {
-		start := strings.Index(body, "[include/")
-		if start < 0 {
-			break
+		replacements := map[string]string{}
+		var failed []string
+		for pos := 0; ; {
+			start := strings.Index(body[pos:], "[include/")
+			if start < 0 {
+				break
+			}
+			start += pos
+			end := strings.Index(body[start:], "]")
+			if end < 0 {
+				break
+			}
+			end += start + 1
+			pos = end
+
+			raw := body[start:end]
+			if _, seen := replacements[raw]; seen || slices.Contains(failed, raw) {
+				continue
+			}
+			spec, err := ParseBracketIncludeTag(raw, data)
+			if err != nil {
+				d.warn(WarnEmptyInclude, raw, "%v", err)
+				failed = append(failed, raw)
+				continue
+			}
+			xmlFrag, _, err := d.getIncludeXML(spec)
+			if err != nil {
+				d.warn(WarnEmptyInclude, spec.File, "%v", err)
+				failed = append(failed, raw)
+				continue
+			}
+			if strings.TrimSpace(xmlFrag) == "" {
+				d.warn(WarnEmptyInclude, spec.File, "the fragment is empty")
+			}
+			replacements[spec.RawTag] = xmlFrag
+			d.emit(IncludeResolved{Part: d.activePart, File: spec.File})
 		}
-		end := strings.Index(body[start:], "]")
-		if end < 0 {
-			break
+		for _, raw := range failed {
+			body = strings.ReplaceAll(body, raw, "")
 		}
-		end += start + 1
-
-		raw := body[start:end]
-		spec, err := ParseBracketIncludeTag(raw, data)
-		if err != nil {
-			body = body[:start] + body[end:]
-			continue
+		if len(replacements) == 0 {
+			return body
 		}
-		xmlFrag, _, err := d.getIncludeXML(spec)
-		if err != nil {
-			body = body[:start] + body[end:]
-			continue
+		next := ReplaceTagsWithParagraphs(body, replacements)
+		for _, raw := range slices.Sorted(maps.Keys(replacements)) {
+			if strings.Contains(next, raw) {
+				// a paragraph the single pass does not take (<w:p w:rsidR="...">): split it for this tag alone
+				next = replaceTagParagraphs(next, raw, replacements[raw])
+			}
 		}
-		body = ReplaceTagWithParagraph(body, spec.RawTag, xmlFrag)
+		if next == body {
+			return body // the tags are not in the text of plain paragraphs
+		}
+		body = next
 	}
-	return body
 }
 
 // --- helpers include ---
@@ -265,16 +405,24 @@ func (d *Docx) getIncludeXML(spec BracketIncludeSpec) (xmlFragment string, isTab
 	switch spec.Fragment {
 	case "body":
 		xmlFragment, err = GetBodyFragment(doc)
-		return xmlFragment, false, err
 	case "table":
 		xmlFragment, err = GetTableN(doc, spec.Index)
-		return xmlFragment, true, err
+		isTable = true
 	case "p":
 		xmlFragment, err = GetParagraphN(doc, spec.Index)
-		return xmlFragment, false, err
 	default:
 		return "", false, fmt.Errorf("unknown fragment")
 	}
+	if err != nil {
+		return "", isTable, err
+	}
+
+	// images and links of the fragment are carried over with new r:ids
+	part := d.activePart
+	if part == "" {
+		part = "document"
+	}
+	return d.Rels().ImportFragment(child, "document", part, xmlFragment), isTable, nil
 }
 
 func (d *Docx) openFragmentDoc(rel string) (*Docx, error) {
@@ -282,6 +430,16 @@ func (d *Docx) openFragmentDoc(rel string) (*Docx, error) {
 	if ext != ".docx" && ext != ".dotx" {
 		return nil, fmt.Errorf("unsupported include extension: %s", rel)
 	}
+	if d.fsys != nil {
+		data, err := d.readFS(rel, d.limits.MaxTotalSize)
+		if err != nil {
+			return nil, err
+		}
+		return OpenBytes(data, WithSizeLimits(d.limits), WithFS(d.fsys))
+	}
+	if d.sourcePath == "" {
+		return nil, fmt.Errorf("include %s: the template was opened from memory and has no directory (see WithFS)", rel)
+	}
 	base := filepath.Dir(d.sourcePath)
 	full, err := securejoin.SecureJoin(base, rel)
 	if err != nil {
@@ -290,7 +448,7 @@ func (d *Docx) openFragmentDoc(rel string) (*Docx, error) {
 	if _, err := os.Stat(full); err != nil {
 		return nil, err
 	}
-	return Open(full)
+	return Open(full, WithSizeLimits(d.limits))
 }
 
 // --- extracting fragments ---
@@ -461,10 +619,8 @@ func (d *Docx) ProcessTrimTags(body string) string {
 		}
 
 		// 3. Work inside the paragraph as before — line by line according to <w:r>
-		reRun := regexp.MustCompile(`(?s)<w:r>.*?</w:r>`)
-		content = reRun.ReplaceAllStringFunc(content, func(run string) string {
-			reT := regexp.MustCompile(`(?s)<w:t[^>]*>.*?</w:t>`)
-			partsT := reT.FindAllString(run, -1)
+		content = reTrimRun.ReplaceAllStringFunc(content, func(run string) string {
+			partsT := reTrimText.FindAllString(run, -1)
 			if len(partsT) == 0 {
 				return run
 			}
@@ -524,21 +680,31 @@ func (d *Docx) ProcessTrimTags(body string) string {
 	return strings.Join(parts, "<w:p>")
 }
 
+var (
+	reTrimRun        = regexp.MustCompile(`(?s)<w:r>.*?</w:r>`)
+	reTrimText       = regexp.MustCompile(`(?s)<w:t[^>]*>.*?</w:t>`)
+	reTrimOpenAll    = regexp.MustCompile(`[\s]*\{~`)
+	reTrimCloseAll   = regexp.MustCompile(`~}[\s]*`)
+	reTrimOpenSpace  = regexp.MustCompile(`[ \t]*\{-`)
+	reTrimCloseSpace = regexp.MustCompile(`-}[ \t]*`)
+	reTrimGlued      = regexp.MustCompile(`([A-Za-zА-Яа-яЁё])\{`)
+)
+
 // cleanTrimTags — removes spaces, tabs, and hyphens around {~}/{-} by correcting spaces.
 func cleanTrimTags(s string) string {
 	// {~...~} — eats everything
-	s = regexp.MustCompile(`[\s]*\{~`).ReplaceAllString(s, "{")
-	s = regexp.MustCompile(`~}[\s]*`).ReplaceAllString(s, "}")
+	s = reTrimOpenAll.ReplaceAllString(s, "{")
+	s = reTrimCloseAll.ReplaceAllString(s, "}")
 	// {-...-} — eats only spaces and tabs
-	s = regexp.MustCompile(`[ \t]*\{-`).ReplaceAllString(s, "{")
-	s = regexp.MustCompile(`-}[ \t]*`).ReplaceAllString(s, "}")
+	s = reTrimOpenSpace.ReplaceAllString(s, "{")
+	s = reTrimCloseSpace.ReplaceAllString(s, "}")
 	// Removing markers
 	s = strings.ReplaceAll(s, "{~", "{")
 	s = strings.ReplaceAll(s, "~}", "}")
 	s = strings.ReplaceAll(s, "{-", "{")
 	s = strings.ReplaceAll(s, "-}", "}")
 	// Restore the space before the tag
-	s = regexp.MustCompile(`([A-Za-zА-Яа-яЁё])\{`).ReplaceAllString(s, `$1 {`)
+	s = reTrimGlued.ReplaceAllString(s, `$1 {`)
 	return s
 }
 
@@ -561,7 +727,7 @@ func expandVars(s string, data map[string]any) string {
 	for k, v := range data {
 		placeholder := "%" + k + "%"
 		if strings.Contains(s, placeholder) {
-			s = strings.ReplaceAll(s, placeholder, fmt.Sprint(v))
+			s = strings.ReplaceAll(s, placeholder, modifiers.ValueText(v))
 		}
 	}
 	return s
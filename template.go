@@ -0,0 +1,60 @@
+package docxgen
+
+// Template - an opened and configured document that is rendered many times, also from several
+// goroutines at once: every render works on its own Clone, the template itself is never changed.
+//
+// Example (one parsed template, parallel requests):
+//
+//	doc, _ := docxgen.Open("act.docx")
+//	doc.SetFonts(fonts)
+//	tpl := docxgen.NewTemplate(doc)
+//
+//	http.HandleFunc("/act", func(w http.ResponseWriter, r *http.Request) {
+//		out, err := tpl.Render(dataOf(r))
+//		...
+//		out.SaveToWriter(w)
+//	})
+type Template struct {
+	base *Docx
+}
+
+// NewTemplate takes a snapshot of the document with its settings; later changes of doc
+// do not reach the template.
+func NewTemplate(doc *Docx) *Template {
+	return &Template{base: doc.Clone()}
+}
+
+// Docx returns a fresh copy of the template to work with by hand (TransformedTemplates, ExecuteTemplate).
+func (t *Template) Docx() *Docx {
+	return t.base.Clone()
+}
+
+// Render executes a copy of the template with data and returns the rendered document.
+func (t *Template) Render(data map[string]any) (*Docx, error) {
+	doc := t.Docx()
+	if err := doc.ExecuteTemplate(data); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// RenderChannel is Render for the output channel, see SetChannel: one template holds the print
+// variant (with the signature blocks) and the email one (with the links).
+func (t *Template) RenderChannel(channel string, data map[string]any) (*Docx, error) {
+	doc := t.Docx()
+	doc.SetChannel(channel)
+	if err := doc.ExecuteTemplate(data); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// RenderResult is Render with the non-fatal issues of the render, see ExecuteTemplateResult.
+func (t *Template) RenderResult(data map[string]any) (*Docx, RenderResult, error) {
+	doc := t.Docx()
+	res, err := doc.ExecuteTemplateResult(data)
+	if err != nil {
+		return nil, res, err
+	}
+	return doc, res, nil
+}
@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"docxgen"
+)
+
+func TestAnnexes(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "annexes"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	annex := func(name, text string) {
+		writeDocx(t, filepath.Join(dir, "annexes", name), map[string]string{
+			"word/document.xml": `<w:document><w:body><w:p><w:r><w:t>` + text + `</w:t></w:r></w:p>` +
+				`<w:sectPr><w:pgSz w:w="16838" w:h="11906"/></w:sectPr></w:body></w:document>`,
+		})
+	}
+	annex("price_rus.docx", "Цены")
+	annex("plan.docx", "График")
+	writeDocx(t, filepath.Join(dir, "contract.docx"), map[string]string{
+		"word/document.xml": `<w:document><w:body>` +
+			`<w:p><w:r><w:t>Договор. Приложения:</w:t></w:r></w:p><w:p><w:r><w:t>[annexes]</w:t></w:r></w:p>` +
+			`<w:p><w:r><w:t>[annex/annexes/price_%lang%.docx Прайс-лист]</w:t></w:r></w:p>` +
+			`<w:p><w:r><w:t>[annex/annexes/missing.docx Нет такого]</w:t></w:r></w:p>` +
+			`<w:p><w:r><w:t>[annex/annexes/plan.docx]</w:t></w:r></w:p>` +
+			`<w:sectPr><w:pgSz w:w="11906" w:h="16838"/></w:sectPr></w:body></w:document>`,
+	})
+	doc, err := docxgen.Open(filepath.Join(dir, "contract.docx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := doc.ExecuteTemplateResult(map[string]any{"lang": "rus"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Warnings) != 1 || res.Warnings[0].Kind != docxgen.WarnEmptyInclude {
+		t.Errorf("warnings: %v", res.Warnings)
+	}
+	xml, _ := doc.ContentPart("document")
+	want := "Договор. Приложения:|Приложение № 1 — Прайс-лист|Приложение № 2|" +
+		"Приложение № 1|Прайс-лист|Цены|Приложение № 2|График"
+	if got := strings.Join(paragraphTexts(xml), "|"); got != want {
+		t.Errorf("texts:\n%s\nwant\n%s", got, want)
+	}
+	if strings.Count(xml, `<w:br w:type="page"/>`) != 2 || strings.Count(xml, "<w:sectPr") != 1 {
+		t.Errorf("page breaks or sections:\n%s", xml)
+	}
+}
@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"docxgen"
+)
+
+// paragraphs returns the text of every paragraph of the rendered document.
+func paragraphs(t *testing.T, body string, data map[string]any) []string {
+	t.Helper()
+	doc := openTemplate(t, body)
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatal(err)
+	}
+	xml, _ := doc.ContentPart("document")
+	var out []string
+	for _, p := range regexp.MustCompile(`(?s)<w:p[ >].*?</w:p>`).FindAllString(xml, -1) {
+		var text strings.Builder
+		for _, m := range regexp.MustCompile(`<w:t[^>]*>([^<]*)</w:t>`).FindAllStringSubmatch(p, -1) {
+			text.WriteString(m[1])
+		}
+		out = append(out, text.String())
+	}
+	return out
+}
+
+func para(text string) string {
+	return "<w:p><w:r><w:t>" + text + "</w:t></w:r></w:p>"
+}
+
+func TestForBlock(t *testing.T) {
+	body := para("Договор {number}") +
+		para("[for items]") +
+		para("{title}: {price|money}") +
+		para("{note}") +
+		para("[/for]") +
+		para("Итого")
+	got := paragraphs(t, body, map[string]any{
+		"number": "17",
+		"items": []any{
+			map[string]any{"title": "Стол", "price": 1200, "note": "дуб"},
+			map[string]any{"title": "Стул", "price": 300},
+		},
+	})
+	want := []string{"Договор 17", "Стол: 1 200,00", "дуб", "Стул: 300,00", "", "Итого"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+
+	// scalars fill %[1]s, a missing list removes the block
+	body = para("[for/tags]") + para("— %[1]s") + para("[/for]") + para("[for missing]") + para("x") + para("[/for]")
+	got = paragraphs(t, body, map[string]any{"tags": []string{"a", "b"}})
+	if fmt.Sprint(got) != fmt.Sprint([]string{"— a", "— b"}) {
+		t.Errorf("scalars: %q", got)
+	}
+}
+
+func TestRepeatBlock(t *testing.T) {
+	region := para("Карточка {name}") +
+		`<w:tbl><w:tr><w:tc>` + para("{position}") + `</w:tc></w:tr></w:tbl>` +
+		`<w:p><w:r><w:drawing><wp:inline><wp:docPr id="5" name="Logo"/></wp:inline></w:drawing></w:r></w:p>`
+	data := map[string]any{"employees": []any{
+		map[string]any{"name": "Иванов", "position": "инженер"},
+		map[string]any{"name": "Петров", "position": "юрист"},
+		map[string]any{"name": "Сидоров", "position": "бухгалтер"},
+	}}
+	doc := openTemplate(t, para("[repeat/employees]")+region+para("[/repeat]")+para("Конец"))
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatal(err)
+	}
+	xml, _ := doc.ContentPart("document")
+	want := "Карточка Иванов|инженер|Карточка Петров|юрист|Карточка Сидоров|бухгалтер|Конец"
+	if got := strings.Join(paragraphTexts(xml), "|"); got != want {
+		t.Errorf("texts %q, want %q", got, want)
+	}
+	if n := strings.Count(xml, `<w:br w:type="page"/>`); n != 2 {
+		t.Errorf("%d page breaks between the copies, want 2", n)
+	}
+	ids := regexp.MustCompile(`<wp:docPr id="(\d+)"`).FindAllStringSubmatch(xml, -1)
+	if len(ids) != 3 || ids[0][1] == ids[1][1] || ids[1][1] == ids[2][1] {
+		t.Errorf("drawing ids: %v", ids)
+	}
+
+	// a region with its own page break gets no other
+	doc = openTemplate(t, para("[repeat employees]")+para("{name}")+`<w:p><w:r><w:br w:type="page"/></w:r></w:p>`+para("[/repeat]"))
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatal(err)
+	}
+	if xml, _ := doc.ContentPart("document"); strings.Count(xml, `<w:br w:type="page"/>`) != 3 {
+		t.Errorf("page breaks:\n%s", xml)
+	}
+}
+
+func TestChannelBlocks(t *testing.T) {
+	body := para("Счёт {number}") +
+		para("[if-channel/print]") + para("Подпись ____") + para("[/if-channel]") +
+		para("[if-channel email,sms]") + para("Оплатить: {link}") + para("[/if-channel]") +
+		para("[if-channel/!print]") + para("Электронный документ") + para("[/if-channel]")
+	data := map[string]any{"number": "7", "link": "https://pay.example/7"}
+
+	tpl := docxgen.NewTemplate(openTemplate(t, body))
+	for channel, want := range map[string][]string{
+		"print": {"Счёт 7", "Подпись ____"},
+		"Email": {"Счёт 7", "Оплатить: https://pay.example/7", "Электронный документ"},
+		"":      {"Счёт 7", "Электронный документ"},
+	} {
+		doc, err := tpl.RenderChannel(channel, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		xml, _ := doc.ContentPart("document")
+		if got := paragraphTexts(xml); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("channel %q: %q, want %q", channel, got, want)
+		}
+	}
+}
+
+func TestIfBlock(t *testing.T) {
+	body := para("[if vip]") + para("Скидка {discount}%") + para("[/if]") +
+		para("[if !vip]") + para("Без скидки") + para("[/if]") +
+		para("[if client.email]") + para("Почта: {client.email}") + para("[/if]")
+
+	got := paragraphs(t, body, map[string]any{"vip": true, "discount": 5, "client": map[string]any{"email": "a@b.ru"}})
+	if fmt.Sprint(got) != fmt.Sprint([]string{"Скидка 5%", "Почта: a@b.ru"}) {
+		t.Errorf("vip: %q", got)
+	}
+	got = paragraphs(t, body, map[string]any{"vip": false, "client": map[string]any{"email": ""}})
+	if fmt.Sprint(got) != fmt.Sprint([]string{"Без скидки"}) {
+		t.Errorf("not vip: %q", got)
+	}
+}
+
+func TestNestedBlocks(t *testing.T) {
+	body := para("[for groups]") +
+		para("Группа {name}") +
+		para("[if lead]") + para("Старший: {lead}") + para("[/if]") +
+		para("[for members]") + para("• %[1]s") + para("[/for]") +
+		para("[/for]")
+	got := paragraphs(t, body, map[string]any{
+		"groups": []map[string]any{
+			{"name": "А", "lead": "Иванов", "members": []string{"Петров", "Сидоров"}},
+			{"name": "Б", "members": []string{"Козлов"}},
+		},
+	})
+	want := []string{"Группа А", "Старший: Иванов", "• Петров", "• Сидоров", "Группа Б", "• Козлов"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestBrokenBlockWarnings(t *testing.T) {
+	res, err := openTemplate(t, para("[for items]")+para("{name}")+para("[/if]")).ExecuteTemplateResult(map[string]any{"name": "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	for _, w := range res.Warnings {
+		kinds = append(kinds, string(w.Kind)+":"+w.Name)
+	}
+	if fmt.Sprint(kinds) != "[broken_block:[for items] broken_block:[/if]]" {
+		t.Errorf("warnings: %v", kinds)
+	}
+}
+
+func TestNamedBlock(t *testing.T) {
+	body := para("Стороны:") +
+		para("[block/parties]") +
+		para("{role}: {name}") +
+		para("[if signer]") + para("в лице {signer}") + para("[/if]") +
+		para("[/block]") +
+		para("[block/missing]") + para("{x}") + para("[/block]") +
+		para("[block/parties]") + para("{name}") + para("[/for]")
+	got := paragraphs(t, body, map[string]any{
+		"parties": []any{
+			map[string]any{"role": "Заказчик", "name": "ООО «Ромашка»", "signer": "Иванова И. И."},
+			map[string]any{"role": "Исполнитель", "name": "ИП Петров"},
+		},
+	})
+	want := []string{"Стороны:", "Заказчик: ООО «Ромашка»", "в лице Иванова И. И.", "Исполнитель: ИП Петров"}
+	if len(got) < len(want) || fmt.Sprint(got[:len(want)]) != fmt.Sprint(want) {
+		t.Fatalf("paragraphs = %q, want %q first", got, want)
+	}
+	if slices.Contains(got, "{x}") || slices.Contains(got, "[block/missing]") {
+		t.Errorf("a block without data must be removed: %q", got)
+	}
+	if len(got) != len(want)+1 || got[len(want)] != "" {
+		t.Errorf("[/for] must not close a [block], the broken markers are removed: %q", got)
+	}
+}
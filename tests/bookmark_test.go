@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"docxgen"
+)
+
+func TestBookmarks(t *testing.T) {
+	body := `<w:p><w:r><w:t xml:space="preserve">Истец: </w:t></w:r><w:bookmarkStart w:id="1" w:name="plaintiff"/>` +
+		`<w:r><w:t>ФИО</w:t></w:r><w:bookmarkEnd w:id="1"/><w:r><w:t>.</w:t></w:r></w:p>` +
+		`<w:p><w:bookmarkStart w:id="2" w:name="claims"/><w:r><w:t>Требования</w:t></w:r><w:bookmarkEnd w:id="2"/></w:p>` +
+		`<w:p><w:bookmarkStart w:id="0" w:name="_GoBack"/><w:bookmarkEnd w:id="0"/><w:r><w:t>Подпись</w:t></w:r></w:p>`
+	doc := openTemplate(t, body)
+	if got := strings.Join(doc.Bookmarks(), ","); got != "plaintiff,claims" {
+		t.Errorf("bookmarks: %s", got)
+	}
+
+	if err := doc.ReplaceBookmark("plaintiff", `<w:r><w:t>Иванов И. И.</w:t></w:r>`); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.ReplaceBookmark("claims", `<w:p><w:r><w:t>1. Взыскать долг</w:t></w:r></w:p><w:p><w:r><w:t>2. Взыскать пени</w:t></w:r></w:p>`); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.InsertAtBookmark("plaintiff", `<w:r><w:t xml:space="preserve">гр. </w:t></w:r>`, docxgen.BookmarkBefore); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.InsertAtBookmark("claims", `<w:p><w:r><w:t>Приложения</w:t></w:r></w:p>`, docxgen.BookmarkAfter); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.ReplaceBookmark("missing", "<w:r/>"); err == nil {
+		t.Error("an unknown bookmark must be an error")
+	}
+
+	xml, _ := doc.ContentPart("document")
+	want := "Истец: гр. Иванов И. И..|1. Взыскать долг|2. Взыскать пени|Приложения|Подпись"
+	if got := strings.Join(paragraphTexts(xml), "|"); got != want {
+		t.Errorf("texts:\n%s\nwant\n%s", got, want)
+	}
+	// the bookmarks stay around the new content
+	if !strings.Contains(xml, `<w:bookmarkStart w:id="2" w:name="claims"/><w:p><w:r><w:t>1.`) ||
+		!strings.Contains(xml, `пени</w:t></w:r></w:p><w:bookmarkEnd w:id="2"/><w:p><w:r><w:t>Приложения`) {
+		t.Errorf("bookmark marks:\n%s", xml)
+	}
+}
@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"docxgen"
+)
+
+func TestApplyBrandMerge(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "neutral.docx")
+	writeDocx(t, tmp, map[string]string{
+		"word/document.xml": `<w:document><w:body><w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr></w:p></w:body></w:document>`,
+		"word/styles.xml": `<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+			`<w:docDefaults><w:rPrDefault><w:rPr><w:sz w:val="24"/></w:rPr></w:rPrDefault></w:docDefaults>` +
+			`<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/></w:style>` +
+			`<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:rPr><w:color w:val="000000"/></w:rPr></w:style>` +
+			`<w:style w:type="character" w:styleId="Heading1"><w:name w:val="heading 1 char"/></w:style>` +
+			`</w:styles>`,
+	})
+	doc, err := docxgen.Open(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pack := docxgen.BrandPack{
+		Styles: []byte(`<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:w14="http://schemas.microsoft.com/office/word/2010/wordml">` +
+			`<w:docDefaults><w:rPrDefault><w:rPr><w:rFonts w:ascii="Arial"/></w:rPr></w:rPrDefault></w:docDefaults>` +
+			`<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:rPr><w:color w:val="C00000"/></w:rPr></w:style>` +
+			`<w:style w:type="paragraph" w:default="1" w:styleId="BrandBody"><w:name w:val="Brand Body"/></w:style>` +
+			`</w:styles>`),
+		Theme: []byte(`<a:theme name="Brand"/>`),
+	}
+	if err := doc.ApplyBrand(pack); err != nil {
+		t.Fatalf("apply brand: %v", err)
+	}
+
+	styles, _ := doc.GetFile("word/styles.xml")
+	s := string(styles)
+	for _, want := range []string{
+		`xmlns:w14=`,      // the prefixes of the pack are declared
+		`w:ascii="Arial"`, // docDefaults of the pack
+		`w:val="C00000"`,  // the heading of the pack
+		`heading 1 char`,  // a style of another type with the same id stays
+		`w:styleId="BrandBody"`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("%s not found:\n%s", want, s)
+		}
+	}
+	if strings.Contains(s, `w:val="000000"`) || strings.Contains(s, `w:val="24"`) {
+		t.Errorf("template styles were not replaced:\n%s", s)
+	}
+	if strings.Count(s, `w:default="1"`) != 1 {
+		t.Errorf("expected one default paragraph style:\n%s", s)
+	}
+
+	theme, ok := doc.GetFile("word/theme/theme1.xml")
+	if !ok || string(theme) != `<a:theme name="Brand"/>` {
+		t.Errorf("theme not written: %s", theme)
+	}
+	if ct, _ := doc.ContentTypes().Override("word/theme/theme1.xml"); !strings.HasSuffix(ct, "theme+xml") {
+		t.Errorf("theme content type: %q", ct)
+	}
+	found := false
+	for _, r := range doc.Rels().List("document") {
+		found = found || r.Target == "theme/theme1.xml"
+	}
+	if !found {
+		t.Error("no theme relationship")
+	}
+}
+
+func TestApplyBrandReplace(t *testing.T) {
+	doc := openTemplate(t, `<w:p/>`)
+	styles := `<w:styles><w:style w:type="paragraph" w:styleId="Only"/></w:styles>`
+	if err := doc.ApplyBrand(docxgen.BrandPack{Styles: []byte(styles), Mode: docxgen.StyleReplace}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := doc.GetFile("word/styles.xml"); string(got) != styles {
+		t.Errorf("styles.xml = %s", got)
+	}
+	if ct, _ := doc.ContentTypes().Override("word/styles.xml"); !strings.HasSuffix(ct, "styles+xml") {
+		t.Errorf("styles content type: %q", ct)
+	}
+}
@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"docxgen"
+)
+
+func TestCalendarBlock(t *testing.T) {
+	data := map[string]any{
+		"period": "2025-11",
+		"meetings": []any{
+			map[string]any{"date": "05.11.2025 14:00", "title": "Заседание"},
+			map[string]any{"date": time.Date(2025, 11, 5, 9, 30, 0, 0, time.UTC), "title": "Планёрка"},
+			map[string]any{"date": "2025-11-30", "title": "Дежурство: Иванов"},
+			map[string]any{"date": "2025-12-01", "title": "другой месяц"},
+		},
+	}
+	doc := openTemplate(t, "<w:p><w:r><w:t>[calendar/month period events:meetings]</w:t></w:r></w:p>")
+	res, err := doc.ExecuteTemplateResult(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Warnings) != 0 {
+		t.Errorf("warnings: %v", res.Warnings)
+	}
+	xml, _ := doc.ContentPart("document")
+
+	// November 2025 starts on Saturday: a header and 5 weeks
+	if strings.Count(xml, "<w:tr>") != 6 || strings.Count(xml, "<w:tc>") != 42 {
+		t.Errorf("want 6 rows of 7 cells: %s", xml)
+	}
+	texts := strings.Join(paragraphTexts(xml), "|")
+	for _, want := range []string{"Ноябрь 2025|Пн|Вт|Ср|Чт|Пт|Сб|Вс|1|2|3|4|5|09:30 Планёрка|14:00 Заседание|6", "30|Дежурство: Иванов"} {
+		if !strings.Contains(texts, want) {
+			t.Errorf("want %q in %q", want, texts)
+		}
+	}
+	if strings.Contains(texts, "другой месяц") {
+		t.Errorf("an event of another month: %q", texts)
+	}
+	// 10 days off: 1, 2, 8, 9, 15, 16, 22, 23, 29, 30
+	if got := strings.Count(xml, `w:fill="F2F2F2"`); got != 10 {
+		t.Errorf("want 10 shaded days off, got %d", got)
+	}
+	if strings.Contains(xml, `</w:tcPr></w:tc>`) {
+		t.Errorf("a cell without a paragraph: %s", xml)
+	}
+
+	doc = openTemplate(t, "<w:p><w:r><w:t>[calendar/month ноябрь]</w:t></w:r></w:p>")
+	res, _ = doc.ExecuteTemplateResult(nil)
+	if len(res.Warnings) != 1 || res.Warnings[0].Kind != docxgen.WarnBadCalendar {
+		t.Errorf("bad month: %v", res.Warnings)
+	}
+}
@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"docxgen"
+)
+
+// zipParts reads the parts of the saved document by name.
+func zipParts(t *testing.T, doc *docxgen.Docx) map[string]string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := doc.SaveToWriter(&out); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(data)
+	}
+	return parts
+}
+
+func TestChartModifier(t *testing.T) {
+	doc := openTemplate(t, "<w:p><w:r><w:t>{sales|chart:`label:month`:`value:plan,fact`:`series:План,Факт`:`title:Продажи & план`}</w:t></w:r></w:p>"+
+		"<w:p><w:r><w:t>{shares|chart:`pie`:`80mm`}</w:t></w:r></w:p>"+
+		"<w:p><w:r><w:t>{name|chart}</w:t></w:r></w:p>")
+	res, err := doc.ExecuteTemplateResult(map[string]any{
+		"sales": []map[string]any{
+			{"month": "Янв", "plan": 100, "fact": 90.5},
+			{"month": "Фев", "plan": 120, "fact": "130"},
+		},
+		"shares": []float64{60, 30, 10},
+		"name":   "not numbers",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Warnings) != 1 || res.Warnings[0].Kind != docxgen.WarnBadChart {
+		t.Errorf("warnings: %v", res.Warnings)
+	}
+	xml, _ := doc.ContentPart("document")
+	if strings.Count(xml, `<c:chart `) != 2 || !strings.Contains(xml, `<wp:extent cx="2880000" cy="1440000"/>`) {
+		t.Fatalf("want 2 inline charts, the pie 80×40 mm: %s", xml)
+	}
+
+	parts := zipParts(t, doc)
+	var charts []string
+	for name, data := range parts {
+		if strings.HasPrefix(name, "word/charts/") {
+			charts = append(charts, data)
+		}
+	}
+	if len(charts) != 2 {
+		t.Fatalf("want 2 chart parts, got %d", len(charts))
+	}
+	if strings.Count(parts["word/_rels/document.xml.rels"], docxgen.RelTypeChart) != 2 ||
+		strings.Count(parts["[Content_Types].xml"], "drawingml.chart+xml") != 2 {
+		t.Errorf("the charts are not registered:\n%s\n%s", parts["word/_rels/document.xml.rels"], parts["[Content_Types].xml"])
+	}
+
+	bar, pie := charts[0], charts[1]
+	if strings.Contains(bar, "<c:pieChart>") {
+		bar, pie = pie, bar
+	}
+	for _, want := range []string{
+		`<c:barDir val="col"/>`, `<c:v>План</c:v>`, `<c:v>Факт</c:v>`, `<c:v>Фев</c:v>`,
+		`<c:v>90.5</c:v>`, `<c:v>130</c:v>`, `<a:t>Продажи &amp; план</a:t>`, `<c:legend>`,
+	} {
+		if !strings.Contains(bar, want) {
+			t.Errorf("bar chart: no %s in\n%s", want, bar)
+		}
+	}
+	if strings.Count(pie, "<c:ser>") != 1 || !strings.Contains(pie, `<c:pt idx="2"><c:v>10</c:v></c:pt>`) ||
+		strings.Contains(pie, "<c:catAx>") {
+		t.Errorf("pie chart:\n%s", pie)
+	}
+}
+
+func TestUnusedChartRemoved(t *testing.T) {
+	doc := openTemplate(t, "<w:p><w:r><w:t>{v|chart:`line`}</w:t></w:r></w:p>")
+	if err := doc.ExecuteTemplate(map[string]any{"v": []int{1, 3, 2}}); err != nil {
+		t.Fatal(err)
+	}
+	doc.UpdateContentPart("document", `<w:document><w:body><w:p/></w:body></w:document>`)
+
+	parts := zipParts(t, doc)
+	for name := range parts {
+		if strings.HasPrefix(name, "word/charts/") {
+			t.Errorf("the chart nobody shows is kept: %s", name)
+		}
+	}
+	if strings.Contains(parts["[Content_Types].xml"], "chart") || strings.Contains(parts["word/_rels/document.xml.rels"], "chart") {
+		t.Errorf("the chart is still registered")
+	}
+}
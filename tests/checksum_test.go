@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"testing"
+
+	"docxgen/modifiers"
+)
+
+func TestLuhn(t *testing.T) {
+	if got := modifiers.Luhn("7992739871"); got != "79927398713" {
+		t.Errorf("Luhn() = %q, want %q", got, "79927398713")
+	}
+	if got := modifiers.LuhnCheck("4276 3800 1234 5675"); got != "✗" {
+		t.Errorf("LuhnCheck(bad) = %q, want ✗", got)
+	}
+	if got := modifiers.LuhnCheck("7992 7398 713", "верно", "ошибка"); got != "верно" {
+		t.Errorf("LuhnCheck(good) = %q, want %q", got, "верно")
+	}
+}
+
+func TestControlDigits(t *testing.T) {
+	cases := []struct {
+		kind, full string
+	}{
+		{"инн", "7707083893"},
+		{"inn", "500100732259"},
+		{"огрн", "1027700132195"},
+		{"ogrnip", "304500116000157"},
+		{"снилс", "11223344595"},
+		{"ean13", "4006381333931"},
+		{"luhn", "79927398713"},
+	}
+
+	for _, c := range cases {
+		if !modifiers.ValidControlDigits(c.full, c.kind) {
+			t.Errorf("ValidControlDigits(%q, %q) = false, want true", c.full, c.kind)
+		}
+
+		n := 1
+		if len(c.full) == 12 && c.kind == "inn" || c.kind == "снилс" {
+			n = 2
+		}
+		base := c.full[:len(c.full)-n]
+		if got := modifiers.ControlDigit(base, c.kind); got != c.full {
+			t.Errorf("ControlDigit(%q, %q) = %q, want %q", base, c.kind, got, c.full)
+		}
+	}
+
+	if got := modifiers.ControlDigit("112-233-445", "снилс"); got != "112-233-445 95" {
+		t.Errorf("ControlDigit(snils formatted) = %q", got)
+	}
+	if got := modifiers.ControlCheck("7707083894", "инн", "да", "нет"); got != "нет" {
+		t.Errorf("ControlCheck(bad inn) = %q, want нет", got)
+	}
+	if got := modifiers.ControlDigit("123", "инн"); got != "123" {
+		t.Errorf("ControlDigit(short) = %q, want unchanged", got)
+	}
+}
@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestComments(t *testing.T) {
+	body := `<w:p><w:r><w:t xml:space="preserve">ИНН: </w:t></w:r><w:r><w:t>{client.</w:t></w:r><w:r><w:t>inn}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>Подпись</w:t></w:r></w:p>`
+	doc := openTemplate(t, body)
+	if err := doc.AddComment("{client.inn}", "Генератор отчётов", "Нет ИНН в данных\nзапросить у клиента"); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.AddComment("Подпись", "Юрист", "Проверить"); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.AddComment("КПП", "Юрист", "?"); err == nil {
+		t.Error("a missing anchor must be an error")
+	}
+
+	comments := doc.ListComments()
+	if len(comments) != 2 {
+		t.Fatalf("comments: %+v", comments)
+	}
+	c := comments[0]
+	if c.ID != 0 || c.Author != "Генератор отчётов" || c.Initials != "ГО" || c.Anchor != "{client.inn}" ||
+		c.Text != "Нет ИНН в данных\nзапросить у клиента" || c.Date.IsZero() {
+		t.Errorf("first comment: %+v", c)
+	}
+	if comments[1].ID != 1 || comments[1].Anchor != "Подпись" {
+		t.Errorf("second comment: %+v", comments[1])
+	}
+
+	files := zipParts(t, doc)
+	if _, ok := files["word/comments.xml"]; !ok || !strings.Contains(files["[Content_Types].xml"], "comments+xml") ||
+		!strings.Contains(files["word/_rels/document.xml.rels"], "comments.xml") {
+		t.Fatal("comments.xml is not in the package")
+	}
+
+	if n := doc.StripComments(); n != 2 {
+		t.Errorf("stripped %d comments", n)
+	}
+	xml, _ := doc.ContentPart("document")
+	if strings.Contains(xml, "comment") {
+		t.Errorf("comment marks are left:\n%s", xml)
+	}
+	if got := strings.Join(paragraphTexts(xml), "|"); got != "ИНН: {client.inn}|Подпись" {
+		t.Errorf("texts: %s", got)
+	}
+	files = zipParts(t, doc)
+	if _, ok := files["word/comments.xml"]; ok || strings.Contains(files["[Content_Types].xml"], "comments+xml") ||
+		strings.Contains(files["word/_rels/document.xml.rels"], "comments.xml") {
+		t.Error("comments.xml is left in the package")
+	}
+}
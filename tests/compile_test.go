@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"docxgen"
+	"docxgen/docxtest"
+)
+
+func TestCompileAndOpenCompiled(t *testing.T) {
+	dir := t.TempDir()
+	docxtest.New().Paragraph("Вставка").WriteFile(t, filepath.Join(dir, "part.docx"))
+	src := filepath.Join(dir, "act.docx")
+	docxtest.New().
+		// the tag split across runs, as Word saves it
+		Body(`<w:p><w:r><w:t>Клиент: {cli</w:t></w:r><w:r><w:t>ent.name}</w:t></w:r></w:p>`).
+		Paragraph("[include/part.docx]").
+		Paragraph("[table/goods]").
+		Table([]string{"{name}", "{price}"}).
+		Paragraph("[/table]").
+		Header(`<w:p><w:r><w:t>{number}</w:t></w:r></w:p>`).
+		WriteFile(t, src)
+
+	m, err := docxgen.CompileFile(src, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Source != "act.docx" || m.SourceHash == "" || len(m.Parts) != 2 {
+		t.Fatalf("manifest = %+v", m)
+	}
+	parts := map[string]docxgen.PartManifest{}
+	for _, p := range m.Parts {
+		parts[p.Part] = p
+	}
+	body := parts["document"]
+	if !slices.Equal(body.Tables, []string{"goods"}) ||
+		!slices.Equal(body.Includes, []string{"part.docx"}) || !slices.Contains(body.Tags, "client.name") {
+		t.Errorf("document manifest = %+v", body)
+	}
+	if !slices.Equal(parts["header1"].Tags, []string{"number"}) {
+		t.Errorf("header manifest = %+v", parts["header1"])
+	}
+
+	compiled := filepath.Join(dir, "act"+docxgen.CompiledExt)
+	doc, err := docxgen.OpenCompiled(compiled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := doc.GetFile("docxgen/manifest.json"); ok {
+		t.Error("the manifest must not stay among the files")
+	}
+	err = doc.ExecuteTemplate(map[string]any{
+		"client": map[string]any{"name": "ромашка"},
+		"number": "17",
+		"goods":  []any{map[string]any{"name": "Стол", "price": "100"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := strings.Join(paragraphTexts(string(must(doc.GetFile("word/document.xml")))), "|")
+	for _, want := range []string{"Клиент: ромашка", "Вставка", "Стол"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("no %q in the rendered body: %s", want, text)
+		}
+	}
+}
+
+func TestCompileFailsOnBrokenTemplate(t *testing.T) {
+	dir := t.TempDir()
+	for name, text := range map[string]string{
+		"modifier.docx": "{name|no_such_modifier}",
+		"include.docx":  "[include/missing.docx]",
+	} {
+		src := filepath.Join(dir, name)
+		docxtest.New().Paragraph(text).WriteFile(t, src)
+		if _, err := docxgen.CompileFile(src, "", nil); err == nil {
+			t.Errorf("%s: the compile must fail", name)
+		}
+		if _, err := os.Stat(strings.TrimSuffix(src, ".docx") + docxgen.CompiledExt); err == nil {
+			t.Errorf("%s: a broken template must not be written", name)
+		}
+	}
+
+	if _, err := docxgen.OpenCompiled(filepath.Join(dir, "modifier.docx")); err == nil {
+		t.Error("a DOCX without the manifest is not a compiled template")
+	}
+}
+
+func must(data []byte, ok bool) []byte {
+	if !ok {
+		panic("no file")
+	}
+	return data
+}
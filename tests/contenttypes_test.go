@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"docxgen"
+)
+
+func TestContentTypesManager(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "ct.docx")
+	writeDocx(t, tmp, map[string]string{
+		"[Content_Types].xml": `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Default Extension="PNG" ContentType="image/png"/>` +
+			`<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>` +
+			`</Types>`,
+		"word/document.xml": `<w:document><w:body><w:p/></w:body></w:document>`,
+	})
+	doc, err := docxgen.Open(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct := doc.ContentTypes()
+
+	if got, ok := ct.Default(".png"); !ok || got != "image/png" {
+		t.Errorf("Default(png) = %q, %v", got, ok)
+	}
+	ct.AddDefaultForFile("photo.JPG")
+	if got, _ := ct.Default("jpg"); got != "image/jpeg" {
+		t.Errorf("Default(jpg) = %q", got)
+	}
+	if got := ct.ContentTypeOf("word/media/x.jpg"); got != "image/jpeg" {
+		t.Errorf("ContentTypeOf(jpg) = %q", got)
+	}
+
+	ct.SetOverride("word/header1.xml", "application/vnd.openxmlformats-officedocument.wordprocessingml.header+xml")
+	if _, ok := ct.Override("/word/header1.xml"); !ok {
+		t.Error("override without leading slash not found")
+	}
+	if !ct.RemoveOverride("/word/header1.xml") || ct.RemoveOverride("word/header1.xml") {
+		t.Error("RemoveOverride must remove the override exactly once")
+	}
+}
+
+func TestContentTypesRepair(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "broken.docx")
+	writeDocx(t, tmp, map[string]string{
+		"[Content_Types].xml":   `<Types><Default Extension="png"`, // truncated
+		"word/document.xml":     `<w:document><w:body><w:p/></w:body></w:document>`,
+		"word/header1.xml":      `<w:hdr/>`,
+		"word/media/image1.png": "png",
+	})
+	doc, err := docxgen.Open(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct := doc.ContentTypes()
+	ct.Repair()
+
+	for part, want := range map[string]string{
+		"word/document.xml":     "document.main+xml",
+		"word/header1.xml":      "header+xml",
+		"word/media/image1.png": "image/png",
+		"_rels/.rels":           "relationships+xml",
+	} {
+		if got := ct.ContentTypeOf(part); !strings.HasSuffix(got, want) {
+			t.Errorf("ContentTypeOf(%s) = %q, want *%s", part, got, want)
+		}
+	}
+
+	// duplicates collapse
+	ct.SetDefault("PNG", "image/png")
+	data, _ := doc.GetFile("[Content_Types].xml")
+	if n := strings.Count(strings.ToLower(string(data)), `extension="png"`); n != 1 {
+		t.Errorf("expected one png Default, got %d:\n%s", n, data)
+	}
+}
@@ -0,0 +1,252 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"docxgen"
+	"docxgen/daemon"
+)
+
+// templateBase64 packs a minimal template with the paragraph XML into base64 for the daemon requests.
+func templateBase64(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "template.docx")
+	writeDocx(t, path, map[string]string{
+		"word/document.xml": `<w:document><w:body>` + body + `</w:body></w:document>`,
+	})
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func TestDaemonHandlerMounted(t *testing.T) {
+	prepared := 0
+	h := daemon.NewHandler(daemon.Config{
+		Prepare: func(doc *docxgen.Docx) error {
+			prepared++
+			return nil
+		},
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/docs/", http.StripPrefix("/docs", h))
+
+	body, _ := json.Marshal(map[string]any{
+		"template": templateBase64(t, `<w:p><w:r><w:t>{name}</w:t></w:r></w:p>`),
+		"data":     map[string]any{"name": "Оленька"},
+		"format":   "xml",
+	})
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/docs/generate", bytes.NewReader(body)))
+
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Оленька") {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	if prepared != 1 {
+		t.Errorf("Prepare called %d times, want 1", prepared)
+	}
+
+	// no converter — no pdf
+	body, _ = json.Marshal(map[string]any{"template": templateBase64(t, ""), "format": "pdf"})
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/docs/generate", bytes.NewReader(body)))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("pdf without converter: status %d", w.Code)
+	}
+}
+
+func TestDaemonServerShutdownFirst(t *testing.T) {
+	srv := &daemon.Server{Addr: "127.0.0.1:0"}
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	// a daemon stopped before it started does not start
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("ListenAndServe after Shutdown: %v", err)
+	}
+}
+
+func TestDaemonBatchProgress(t *testing.T) {
+	body, _ := json.Marshal(map[string]any{
+		"template": templateBase64(t, `<w:p><w:r><w:t>{name}</w:t></w:r></w:p>`),
+		"items":    []map[string]any{{"name": "Оля"}, {"name": "Аня"}},
+	})
+	req := httptest.NewRequest("POST", "/batch", bytes.NewReader(body))
+	req.Header.Set("Accept", "text/event-stream")
+	w := httptest.NewRecorder()
+	daemon.NewHandler(daemon.Config{}).ServeHTTP(w, req)
+
+	stream := w.Body.String()
+	if !strings.Contains(stream, `"done":1,"total":2,"name":"result_002.docx"`) {
+		t.Fatalf("no progress of the second document:\n%s", stream)
+	}
+	_, payload, ok := strings.Cut(stream, "event: done\ndata: ")
+	if !ok {
+		t.Fatalf("no done event:\n%s", stream)
+	}
+	var done struct {
+		Zip   string              `json:"zip"`
+		Files []daemon.FileDigest `json:"files"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(payload)), &done); err != nil {
+		t.Fatalf("done: %v", err)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(done.Zip)
+	zr, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		t.Fatalf("zip: %v", err)
+	}
+	if len(zr.File) != 3 || zr.File[0].Name != "result_001.docx" || zr.File[2].Name != daemon.ManifestName {
+		t.Fatalf("unexpected files in zip: %d", len(zr.File))
+	}
+
+	// the manifest has the checksums of the documents, the same as in the done event
+	var manifest []daemon.FileDigest
+	if err := json.Unmarshal(readZipEntry(t, zr.File[2]), &manifest); err != nil {
+		t.Fatalf("manifest: %v", err)
+	}
+	if !reflect.DeepEqual(manifest, done.Files) || len(manifest) != 2 {
+		t.Fatalf("manifest %v, done %v", manifest, done.Files)
+	}
+	for i, f := range zr.File[:2] {
+		sum := sha256.Sum256(readZipEntry(t, f))
+		if manifest[i].Name != f.Name || manifest[i].SHA256 != hex.EncodeToString(sum[:]) {
+			t.Errorf("manifest entry %v does not match %s", manifest[i], f.Name)
+		}
+	}
+}
+
+// readZipEntry reads an entry of a zip.
+func readZipEntry(t *testing.T, f *zip.File) []byte {
+	t.Helper()
+	r, err := f.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestChecksum(t *testing.T) {
+	doc := openTemplate(t, `<w:p><w:r><w:t>{name}</w:t></w:r></w:p>`)
+	if err := doc.ExecuteTemplate(map[string]any{"name": "Оля"}); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := doc.Checksum()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var a, b bytes.Buffer
+	if err := doc.SaveToWriter(&a); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.SaveToWriter(&b); err != nil {
+		t.Fatal(err)
+	}
+	got := sha256.Sum256(a.Bytes())
+	if hex.EncodeToString(got[:]) != sum || !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Fatalf("the checksum %s must be the one of every saved copy", sum)
+	}
+
+	// the daemon returns it in the headers
+	body, _ := json.Marshal(map[string]any{
+		"template": templateBase64(t, `<w:p><w:r><w:t>{name}</w:t></w:r></w:p>`),
+		"data":     map[string]any{"name": "Оля"},
+	})
+	w := httptest.NewRecorder()
+	daemon.NewHandler(daemon.Config{}).ServeHTTP(w, httptest.NewRequest("POST", "/generate", bytes.NewReader(body)))
+	got = sha256.Sum256(w.Body.Bytes())
+	if w.Header().Get("X-Content-SHA256") != hex.EncodeToString(got[:]) ||
+		w.Header().Get("Content-Digest") != "sha-256=:"+base64.StdEncoding.EncodeToString(got[:])+":" {
+		t.Errorf("digest headers %v do not match the body", w.Header())
+	}
+}
+
+func TestDaemonTenants(t *testing.T) {
+	root := func(text string) string {
+		dir := t.TempDir()
+		writeDocx(t, filepath.Join(dir, "act.docx"), map[string]string{
+			"word/document.xml": `<w:document><w:body><w:p><w:r><w:t>` + text + `</w:t></w:r></w:p></w:body></w:document>`,
+		})
+		return dir
+	}
+	legal, finance := root("legal {name|shout}"), root("finance {name}")
+	h := daemon.NewHandler(daemon.Config{
+		TenantHeader: "X-Tenant",
+		Tenants: []daemon.Tenant{
+			{Name: "legal", Keys: []string{"key-legal"}, TemplateRoot: legal, Prepare: func(doc *docxgen.Docx) error {
+				doc.AddModifier("shout", strings.ToUpper, 0)
+				return nil
+			}},
+			{Name: "finance", Keys: []string{"key-finance"}, TemplateRoot: finance, Rate: 1, Burst: 3},
+		},
+	})
+	generate := func(template string, headers ...string) *httptest.ResponseRecorder {
+		t.Helper()
+		body, _ := json.Marshal(map[string]any{"template": template, "data": map[string]any{"name": "Оля"}, "format": "xml"})
+		req := httptest.NewRequest("POST", "/generate", bytes.NewReader(body))
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := generate("act.docx"); w.Code != http.StatusUnauthorized {
+		t.Errorf("no key: status %d", w.Code)
+	}
+	if w := generate("act.docx", "Authorization", "Bearer key-other"); w.Code != http.StatusUnauthorized {
+		t.Errorf("unknown key: status %d", w.Code)
+	}
+	// the same path is the template of the tenant of the key, with its modifiers
+	if w := generate("act.docx", "Authorization", "Bearer key-legal"); w.Code != 200 || !strings.Contains(w.Body.String(), "legal ОЛЯ") {
+		t.Errorf("legal: status %d: %s", w.Code, w.Body.String())
+	}
+	if w := generate("act.docx", "X-API-Key", "key-finance"); w.Code != 200 || !strings.Contains(w.Body.String(), "finance Оля") {
+		t.Errorf("finance: status %d: %s", w.Code, w.Body.String())
+	}
+	// the templates and the modifiers of another tenant are not there
+	if w := generate(filepath.Join(legal, "act.docx"), "X-Tenant", "finance"); w.Code != http.StatusBadRequest {
+		t.Errorf("finance opened the template of legal: status %d: %s", w.Code, w.Body.String())
+	}
+	if w := generate("../"+filepath.Base(legal)+"/act.docx", "X-API-Key", "key-legal"); w.Code != http.StatusBadRequest {
+		t.Errorf("a path out of the root: status %d", w.Code)
+	}
+	if w := generate("act.docx", "X-API-Key", "key-legal", "X-Tenant", "finance"); w.Code != http.StatusForbidden {
+		t.Errorf("the key of another tenant: status %d", w.Code)
+	}
+	shout := templateBase64(t, `<w:p><w:r><w:t>{name|shout}</w:t></w:r></w:p>`)
+	if w := generate(shout, "X-Tenant", "finance"); w.Code != 500 || !strings.Contains(w.Body.String(), `shout\" not defined`) {
+		t.Errorf("finance used the modifier of legal: status %d: %s", w.Code, w.Body.String())
+	}
+
+	// finance: 1 request a second, 3 at once, all taken above
+	w := generate("act.docx", "X-Tenant", "finance")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("rate limit: status %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w := generate("act.docx", "X-API-Key", "key-legal"); w.Code != 200 {
+		t.Errorf("the limit of finance stopped legal: status %d", w.Code)
+	}
+}
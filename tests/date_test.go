@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"docxgen/modifiers"
+)
+
+func TestDateFormat(t *testing.T) {
+	cases := []struct {
+		v    any
+		want string
+	}{
+		{"2025-10-14", "14.10.2025"},
+		{time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), "01.03.2026"},
+		{"", ""},
+		{"не дата", "не дата"},
+	}
+	for _, c := range cases {
+		if got := modifiers.DateFormat(c.v, "02.01.2006"); got != c.want {
+			t.Errorf("DateFormat(%v) = %q, want %q", c.v, got, c.want)
+		}
+	}
+}
+
+func TestDateOrdinal(t *testing.T) {
+	cases := []struct {
+		v    any
+		opts []string
+		want string
+	}{
+		{"2025-10-14", nil, "14-е октября"},
+		{"14.10.2025", []string{"род"}, "14-го октября"},
+		{"2025-10-14", []string{"родительный", "год"}, "14-го октября 2025 г."},
+		{1, []string{"род", "числа"}, "1-го числа"},
+		{"1", []string{"пред", "числа"}, "1-м числе"},
+		{3.0, []string{"дат"}, "3-му"},
+		{"когда-нибудь", nil, "когда-нибудь"},
+		{"", nil, ""},
+	}
+	for _, c := range cases {
+		if got := modifiers.DateOrdinal(c.v, c.opts...); got != c.want {
+			t.Errorf("DateOrdinal(%v, %v) = %q, want %q", c.v, c.opts, got, c.want)
+		}
+	}
+}
+
+func TestAddDays(t *testing.T) {
+	cal, err := modifiers.ParseWorkCalendar([]byte(`{"holidays":["2025-11-04"],"workdays":["2025-11-01"]}`))
+	if err != nil {
+		t.Fatalf("parse calendar: %v", err)
+	}
+
+	plain := modifiers.AddDaysFactory(nil)
+	withCal := modifiers.AddDaysFactory(cal)
+
+	cases := []struct {
+		name string
+		fn   func(any, int, ...string) string
+		v    any
+		n    int
+		opts []string
+		want string
+	}{
+		{"calendar days", plain, "2025-10-14", 10, nil, "24.10.2025"},
+		{"working days", plain, "2025-10-14", 10, []string{"рабочие"}, "28.10.2025"},
+		{"working days back", plain, "14.10.2025", -3, []string{"рабочих"}, "09.10.2025"},
+		{"layout", plain, "2025-10-14", 1, []string{"2006-01-02"}, "2025-10-15"},
+		{"explicit layout", plain, "2025-10-14", 1, []string{"layout:2 January 2006"}, "15 October 2025"},
+		{"layout with a colon", plain, "2025-10-14", 1, []string{"рабочие", "формат:02.01 15:04"}, "15.10 00:00"},
+		{"layout without a reference date", plain, "2025-10-14", 1, []string{"layout:Jan 2"}, "Oct 15"},
+		// not a layout: the default one stays
+		{"bare option without 2006, 01 or 02", plain, "2025-10-14", 1, []string{"Jan 2"}, "15.10.2025"},
+		{"text option", plain, "2025-10-14", 1, []string{"рабочии"}, "15.10.2025"},
+		{"text option with a layout letter", plain, "2025-10-14", 1, []string{"Monday"}, "15.10.2025"},
+		// 31.10 (Fri) → 01.11 (working Saturday) → 03.11 → 05.11 (04.11 is a holiday)
+		{"production calendar", withCal, "2025-10-31", 3, []string{"working"}, "05.11.2025"},
+		{"not a date", plain, "завтра", 1, nil, "завтра"},
+		// a shift beyond MaxWorkdayShift leaves the date as it is
+		{"too many working days", plain, "2025-10-14", 1_000_000_000, []string{"рабочие"}, "2025-10-14"},
+		{"too many working days back", plain, "2025-10-14", -modifiers.MaxWorkdayShift - 1, []string{"рабочие"}, "2025-10-14"},
+	}
+	for _, c := range cases {
+		if got := c.fn(c.v, c.n, c.opts...); got != c.want {
+			t.Errorf("%s: add_days(%v, %d) = %q, want %q", c.name, c.v, c.n, got, c.want)
+		}
+	}
+
+	next := modifiers.NextWorkdayFactory(cal)
+	if got := next("2025-11-02"); got != "03.11.2025" {
+		t.Errorf("next_workday(Sunday) = %q, want %q", got, "03.11.2025")
+	}
+	if got := next("2025-11-04"); got != "05.11.2025" {
+		t.Errorf("next_workday(holiday) = %q, want %q", got, "05.11.2025")
+	}
+}
+
+func TestAddMonths(t *testing.T) {
+	cases := []struct {
+		v    any
+		n    int
+		want string
+	}{
+		{"2025-10-14", 3, "14.01.2026"},
+		{"2025-01-31", 1, "28.02.2025"},
+		{"2024-01-31", 1, "29.02.2024"},
+		{"2025-03-31", -1, "28.02.2025"},
+	}
+	for _, c := range cases {
+		if got := modifiers.AddMonths(c.v, c.n); got != c.want {
+			t.Errorf("AddMonths(%v, %d) = %q, want %q", c.v, c.n, got, c.want)
+		}
+	}
+}
+
+func TestDateArithUnknownOption(t *testing.T) {
+	body := "<w:p><w:r><w:t>{d|add_days:1:`рабочии`} {d|add_months:1:`2006-01-02`}</w:t></w:r></w:p>"
+	res, err := openTemplate(t, body).ExecuteTemplateResult(map[string]any{"d": "2025-10-14"})
+	if err != nil {
+		t.Fatalf("execute template: %v", err)
+	}
+	if len(res.Warnings) != 1 || res.Warnings[0].Kind != "unknown_option" || res.Warnings[0].Name != "add_days" ||
+		!strings.Contains(res.Warnings[0].Message, "рабочии") {
+		t.Errorf("warnings: %v", res.Warnings)
+	}
+}
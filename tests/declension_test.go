@@ -3,7 +3,10 @@ package tests
 import (
 	"docxgen/modifiers"
 	"strings"
+	"sync"
 	"testing"
+
+	"github.com/normiridium/petrovich"
 )
 
 var Declension = modifiers.Declension
@@ -173,3 +176,33 @@ func TestDeclension_EdgeCases(t *testing.T) {
 		}
 	})
 }
+
+// ————————————————————————————————————————————————————————————————
+// Тест: правила petrovich загружаются один раз и могут быть переданы снаружи
+// ————————————————————————————————————————————————————————————————
+func TestDeclension_SharedRules(t *testing.T) {
+	rules, err := petrovich.LoadRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	modifiers.SetDeclensionRules(rules)
+	defer modifiers.SetDeclensionRules(nil)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got := Declension("Иванов Иван Иванович", "дательный"); got != "Иванову Ивану Ивановичу" {
+				t.Errorf("Declension = %q", got)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkDeclension(b *testing.B) {
+	for b.Loop() {
+		Declension("Петрова Анна Сергеевна", "дательный", "фамилия и.о.")
+	}
+}
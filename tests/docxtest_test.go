@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"docxgen"
+	"docxgen/docxtest"
+)
+
+func TestDocxtestBuilder(t *testing.T) {
+	doc := docxtest.New().
+		Paragraph("Договор № {number}").
+		Table([]string{"Товар", "Цена"}, []string{"{name}", "{price}"}).
+		Image(docxtest.PNG(4, 2), 40, 20).
+		Header("<w:p><w:r><w:t>{company}</w:t></w:r></w:p>").
+		Footer("<w:p><w:r><w:t>стр.</w:t></w:r></w:p>").
+		Style("Title", "Title", "<w:b/>").
+		Open(t)
+
+	if got := strings.Join(doc.ListHeaderFooterParts(), ","); got != "header1,footer1" {
+		t.Errorf("headers and footers: %s", got)
+	}
+	data := map[string]any{"number": "15", "name": "Бумага", "price": "300", "company": "ООО «Ромашка»"}
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatal(err)
+	}
+	body, _ := doc.ContentPart("document")
+	if got := strings.Join(paragraphTexts(body), "|"); got != "Договор № 15|Товар|Цена|Бумага|300" {
+		t.Errorf("texts: %s", got)
+	}
+	if header, _ := doc.ContentPart("header1"); !strings.Contains(header, "ООО «Ромашка»") {
+		t.Errorf("header: %s", header)
+	}
+
+	files := zipParts(t, doc)
+	for _, name := range []string{"word/media/image1.png", "word/styles.xml", "[Content_Types].xml", "_rels/.rels"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("%s is not in the package", name)
+		}
+	}
+	if !strings.Contains(files["[Content_Types].xml"], `PartName="/word/header1.xml"`) {
+		t.Error("the header has no content type")
+	}
+
+	path := filepath.Join(t.TempDir(), "fixture.docx")
+	docxtest.New().Paragraph("a", "b").WriteFile(t, path)
+	again, err := docxgen.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := again.ContentPart("document"); strings.Join(paragraphTexts(body), "|") != "a|b" {
+		t.Errorf("written fixture: %s", body)
+	}
+}
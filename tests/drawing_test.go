@@ -0,0 +1,393 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"docxgen"
+	"docxgen/docxtest"
+	"docxgen/modifiers"
+)
+
+// writeDocx packs the files into a docx archive at path.
+func writeDocx(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	if err := os.WriteFile(path, docxtest.Zip(files), 0644); err != nil {
+		t.Fatalf("failed to write temp docx: %v", err)
+	}
+}
+
+// openTemplate creates a minimal docx with the given paragraph XML and opens it.
+func openTemplate(t *testing.T, body string) *docxgen.Docx {
+	t.Helper()
+	tmp := filepath.Join(t.TempDir(), "template.docx")
+	writeDocx(t, tmp, map[string]string{
+		"word/document.xml": `<w:document><w:body>` + body + `</w:body></w:document>`,
+	})
+	doc, err := docxgen.Open(tmp)
+	if err != nil {
+		t.Fatalf("failed to open temp docx: %v", err)
+	}
+	doc.ImportBuiltins()
+	return doc
+}
+
+func TestParseNumbers(t *testing.T) {
+	cases := []struct {
+		in   any
+		want []float64
+	}{
+		{[]any{1, 2.5, "3", "x"}, []float64{1, 2.5, 3}},
+		{[]int{4, 5}, []float64{4, 5}},
+		{"1, 2 3", []float64{1, 2, 3}},
+		{"1,5; 2,5", []float64{1.5, 2.5}},
+		{7, []float64{7}},
+		{nil, nil},
+	}
+	for _, c := range cases {
+		if got := modifiers.ParseNumbers(c.in); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("ParseNumbers(%v) = %v, want %v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	doc := openTemplate(t, "<w:p><w:r><w:t>{history|sparkline:`20mm*5mm`:`bar`}</w:t></w:r></w:p>"+
+		"<w:p><w:r><w:t>[{empty|sparkline}]</w:t></w:r></w:p>")
+
+	data := map[string]any{"history": []any{3, 7, -2, 5}, "empty": []any{}}
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatalf("execute template: %v", err)
+	}
+	content, err := doc.ContentPart("document")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(content, "<wp:inline") || !strings.Contains(content, `r:embed="rId_`) {
+		t.Errorf("sparkline drawing not inserted:\n%s", content)
+	}
+	// 20mm × 5mm in EMU
+	if !strings.Contains(content, `cx="720000" cy="180000"`) {
+		t.Errorf("unexpected sparkline size:\n%s", content)
+	}
+	if strings.Count(content, "<wp:inline") != 1 {
+		t.Errorf("empty array must not produce a drawing:\n%s", content)
+	}
+}
+
+func TestSparklineSizeLimit(t *testing.T) {
+	data := map[string]any{"history": []any{3, 7, -2, 5}}
+
+	doc := openTemplate(t, "<w:p><w:r><w:t>{history|sparkline:`5000mm*5000mm`}</w:t></w:r></w:p>")
+	if err := doc.ExecuteTemplate(data); !errors.Is(err, docxgen.ErrLimitExceeded) || !strings.Contains(err.Error(), "sparkline") {
+		t.Errorf("a sparkline beyond the picture limit: %v", err)
+	}
+
+	doc = openTemplate(t, "<w:p><w:r><w:t>{history|sparkline:`100mm*25mm`}</w:t></w:r></w:p>")
+	doc.SetSizeLimits(docxgen.SizeLimits{MaxPartSize: 1 << 20})
+	if err := doc.ExecuteTemplate(data); !errors.Is(err, docxgen.ErrLimitExceeded) {
+		t.Errorf("a sparkline beyond MaxPartSize: %v", err)
+	}
+}
+
+func TestProgressBar(t *testing.T) {
+	doc := openTemplate(t, "<w:p><w:r><w:t>{a|progressbar:`60mm`} {b|progressbar:`50mm*2mm`:`green`} [{c|progressbar}]</w:t></w:r></w:p>")
+
+	data := map[string]any{"a": "75%", "b": 140, "c": "n/a"}
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatalf("execute template: %v", err)
+	}
+	content, err := doc.ContentPart("document")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Count(content, "<wp:inline"); got != 2 {
+		t.Errorf("expected 2 progress bars, got %d:\n%s", got, content)
+	}
+	for _, size := range []string{`cx="2160000" cy="108000"`, `cx="1800000" cy="72000"`} {
+		if !strings.Contains(content, size) {
+			t.Errorf("size %s not found:\n%s", size, content)
+		}
+	}
+	if !strings.Contains(content, "[]") {
+		t.Errorf("non-numeric value must give an empty string:\n%s", content)
+	}
+}
+
+func TestProgressBarSizeLimit(t *testing.T) {
+	doc := openTemplate(t, "<w:p><w:r><w:t>{a|progressbar:`5000mm*5000mm`}</w:t></w:r></w:p>")
+	if err := doc.ExecuteTemplate(map[string]any{"a": 60}); !errors.Is(err, docxgen.ErrLimitExceeded) || !strings.Contains(err.Error(), "progressbar") {
+		t.Errorf("a progress bar beyond the picture limit: %v", err)
+	}
+
+	doc = openTemplate(t, "<w:p><w:r><w:t>{a|progressbar:`200mm*10mm`}</w:t></w:r></w:p>")
+	doc.SetSizeLimits(docxgen.SizeLimits{MaxPartSize: 1 << 20})
+	if err := doc.ExecuteTemplate(map[string]any{"a": 60}); !errors.Is(err, docxgen.ErrLimitExceeded) {
+		t.Errorf("a progress bar beyond MaxPartSize: %v", err)
+	}
+}
+
+func TestBarcodeCaptionAndQuietZone(t *testing.T) {
+	doc := openTemplate(t, "<w:p><w:r><w:t>{a|barcode:`inline`:`60mm*20mm`:`8pt`} {b|barcode:`ean13`:`inline`:`quiet=0`}</w:t></w:r></w:p>")
+
+	data := map[string]any{"a": "ABC-123", "b": "4006381333931"}
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatalf("execute template: %v", err)
+	}
+	content, err := doc.ContentPart("document")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(content, "<wp:inline"); got != 2 {
+		t.Errorf("expected 2 barcodes, got %d:\n%s", got, content)
+	}
+	// the drawing keeps the requested size, the caption goes inside the image
+	if !strings.Contains(content, `cx="2160000" cy="720000"`) {
+		t.Errorf("barcode size changed:\n%s", content)
+	}
+	if strings.Contains(content, "barcode error") {
+		t.Errorf("barcode failed:\n%s", content)
+	}
+}
+
+func TestBarcodeSymbologies(t *testing.T) {
+	cases := []struct {
+		typ, value string
+		square     bool
+	}{
+		{"code39", "ABC-123", false},
+		{"itf14", "1234567890123", false},
+		{"datamatrix", "https://example.com/doc/42", true},
+		{"aztec", "Договор № 42", true},
+		{"pdf417", "Иванов Иван Иванович;1234567890", false},
+	}
+	for _, c := range cases {
+		doc := openTemplate(t, "<w:p><w:r><w:t>{v|barcode:`"+c.typ+"`:`inline`:`40mm`}</w:t></w:r></w:p>")
+		if err := doc.ExecuteTemplate(map[string]any{"v": c.value}); err != nil {
+			t.Fatalf("%s: %v", c.typ, err)
+		}
+		content, _ := doc.ContentPart("document")
+		if strings.Contains(content, "barcode error") {
+			t.Errorf("%s failed:\n%s", c.typ, content)
+			continue
+		}
+		m := regexp.MustCompile(`<wp:extent cx="(\d+)" cy="(\d+)"/>`).FindStringSubmatch(content)
+		if m == nil {
+			t.Errorf("%s: no drawing:\n%s", c.typ, content)
+			continue
+		}
+		cx, _ := strconv.Atoi(m[1])
+		cy, _ := strconv.Atoi(m[2])
+		switch {
+		case cx != 1440000:
+			t.Errorf("%s: width %d, want 40mm", c.typ, cx)
+		case c.square && cy != cx:
+			t.Errorf("%s: %d×%d, want a square", c.typ, cx, cy)
+		case c.typ == "pdf417" && (cy >= cx || cy <= cx/10):
+			t.Errorf("pdf417: %d×%d, want its own proportions", cx, cy)
+		case c.typ != "pdf417" && !c.square && cy != cx/3:
+			t.Errorf("%s: %d×%d, want 1:3", c.typ, cx, cy)
+		}
+	}
+
+	doc := openTemplate(t, "<w:p><w:r><w:t>{v|barcode:`itf14`}</w:t></w:r></w:p>")
+	_ = doc.ExecuteTemplate(map[string]any{"v": "123"})
+	if content, _ := doc.ContentPart("document"); !strings.Contains(content, "barcode error: itf14") {
+		t.Errorf("itf14 must report a wrong length:\n%s", content)
+	}
+}
+
+func TestAnchorWrapOptions(t *testing.T) {
+	doc := openTemplate(t, "<w:p><w:r><w:t>{a|qrcode:`behind`:`z=0`} {b|barcode:`tight`} {c|qrcode}</w:t></w:r></w:p>")
+
+	if err := doc.ExecuteTemplate(map[string]any{"a": "seal", "b": "123", "c": "plain"}); err != nil {
+		t.Fatalf("execute template: %v", err)
+	}
+	content, _ := doc.ContentPart("document")
+
+	for _, want := range []string{
+		`behindDoc="1"`, `relativeHeight="0"`, `<wp:wrapNone/>`, // behind the text
+		`<wp:wrapTight wrapText="bothSides">`,   // tight
+		`<wp:wrapSquare wrapText="bothSides"/>`, // default stays square
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("%s not found:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "barcode error") {
+		t.Errorf("wrap option was taken as a barcode type:\n%s", content)
+	}
+}
+
+func TestQrCodeOptions(t *testing.T) {
+	logo := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	draw.Draw(logo, logo.Bounds(), image.NewUniform(color.RGBA{R: 0xFF, A: 0xFF}), image.Point{}, draw.Src)
+	var logoPNG bytes.Buffer
+	_ = png.Encode(&logoPNG, logo)
+
+	doc := openTemplate(t, "<w:p><w:r><w:t>{v|qrcode:`ec:H`:`fg:1F3864`:`bg:FFF2CC`:`logo:"+
+		base64.StdEncoding.EncodeToString(logoPNG.Bytes())+"`} {v|qrcode:`logo:nope.png`}</w:t></w:r></w:p>")
+	res, err := doc.ExecuteTemplateResult(map[string]any{"v": "INV-2025-0042"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the logo that cannot be loaded: a warning, the code is still there
+	if len(res.Warnings) != 1 || res.Warnings[0].Kind != docxgen.WarnBadImage {
+		t.Errorf("warnings: %v", res.Warnings)
+	}
+	if content, _ := doc.ContentPart("document"); strings.Count(content, "</w:drawing>") != 2 {
+		t.Fatalf("want 2 codes:\n%s", content)
+	}
+
+	var out bytes.Buffer
+	if err := doc.SaveToWriter(&out); err != nil {
+		t.Fatal(err)
+	}
+	zr, _ := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	var branded image.Image
+	for _, f := range zr.File {
+		if !strings.HasPrefix(f.Name, "word/media/") {
+			continue
+		}
+		rc, _ := f.Open()
+		img, err := png.Decode(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r, _, _, _ := img.At(img.Bounds().Dx()/2, img.Bounds().Dy()/2).RGBA(); r>>8 == 0xFF {
+			branded = img
+		}
+	}
+	if branded == nil {
+		t.Fatal("no code with the red logo in the center")
+	}
+	hexAt := func(x, y int) string {
+		r, g, b, _ := branded.At(x, y).RGBA()
+		return strconv.FormatUint(uint64(r>>8)<<16|uint64(g>>8)<<8|uint64(b>>8), 16)
+	}
+	if got := hexAt(0, 0); got != "fff2cc" {
+		t.Errorf("background %s, want fff2cc", got)
+	}
+	colors := map[string]bool{}
+	for x := 0; x < branded.Bounds().Dx(); x++ {
+		colors[hexAt(x, branded.Bounds().Dy()/4)] = true
+	}
+	if !colors["1f3864"] {
+		t.Errorf("no modules of the fg color: %v", colors)
+	}
+}
+
+func TestUniqueDrawingIDs(t *testing.T) {
+	doc := openTemplate(t, `<w:p><w:r><w:drawing><wp:inline><wp:docPr id="7" name="Logo"/></wp:inline></w:drawing></w:r></w:p>`+
+		"<w:p><w:r><w:t>{a|qrcode} {a|qrcode:`inline`} {h|sparkline}</w:t></w:r></w:p>")
+
+	if err := doc.ExecuteTemplate(map[string]any{"a": "same", "h": []any{1, 2}}); err != nil {
+		t.Fatalf("execute template: %v", err)
+	}
+	content, _ := doc.ContentPart("document")
+
+	ids := regexp.MustCompile(`<wp:docPr id="(\d+)" name="([^"]+)"`).FindAllStringSubmatch(content, -1)
+	if len(ids) != 4 {
+		t.Fatalf("expected 4 drawings, got %d:\n%s", len(ids), content)
+	}
+	seenID, seenName := map[string]bool{}, map[string]bool{}
+	for _, m := range ids {
+		if seenID[m[1]] || seenName[m[2]] {
+			t.Errorf("duplicate drawing id or name: %v", m[0])
+		}
+		seenID[m[1]], seenName[m[2]] = true, true
+	}
+	if !seenID["8"] || !seenID["10"] {
+		t.Errorf("new ids must continue after the template ids: %v", ids)
+	}
+}
+
+func TestRemoveUnusedMedia(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "media.docx")
+	writeDocx(t, tmp, map[string]string{
+		"[Content_Types].xml": `<Types><Override PartName="/word/media/used.png" ContentType="image/png"/>` +
+			`<Override PartName="/word/media/orphan.png" ContentType="image/png"/></Types>`,
+		"word/document.xml": `<w:document><w:body>` +
+			`<w:p><w:r><w:drawing><a:blip r:embed="rIdUsed"/></w:drawing></w:r></w:p>` +
+			`</w:body></w:document>`,
+		"word/_rels/document.xml.rels": `<Relationships>` +
+			`<Relationship Id="rIdUsed" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="media/used.png"/>` +
+			`<Relationship Id="rIdOrphan" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="media/orphan.png"/>` +
+			`<Relationship Id="rIdLink" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://example.com" TargetMode="External"/>` +
+			`</Relationships>`,
+		"word/media/used.png":   "png",
+		"word/media/orphan.png": "png",
+	})
+	doc, err := docxgen.Open(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := doc.RemoveUnusedMedia(); n != 1 {
+		t.Errorf("RemoveUnusedMedia() = %d, want 1", n)
+	}
+	if _, ok := doc.GetFile("word/media/orphan.png"); ok {
+		t.Error("orphan media was not removed")
+	}
+	if _, ok := doc.GetFile("word/media/used.png"); !ok {
+		t.Error("used media was removed")
+	}
+	rels, _ := doc.GetFile("word/_rels/document.xml.rels")
+	if strings.Contains(string(rels), "rIdOrphan") || !strings.Contains(string(rels), "rIdLink") {
+		t.Errorf("unexpected relationships:\n%s", rels)
+	}
+	ct, _ := doc.GetFile("[Content_Types].xml")
+	if strings.Contains(string(ct), "orphan.png") || !strings.Contains(string(ct), "used.png") {
+		t.Errorf("unexpected content types:\n%s", ct)
+	}
+}
+
+func TestBarcodeSizeLimits(t *testing.T) {
+	extent := func(tag string, v string) (cx, cy int, content string) {
+		t.Helper()
+		doc := openTemplate(t, "<w:p><w:r><w:t>"+tag+"</w:t></w:r></w:p>")
+		if err := doc.ExecuteTemplate(map[string]any{"v": v}); err != nil {
+			t.Fatal(err)
+		}
+		content, _ = doc.ContentPart("document")
+		if m := regexp.MustCompile(`<wp:extent cx="(\d+)" cy="(\d+)"/>`).FindStringSubmatch(content); m != nil {
+			cx, _ = strconv.Atoi(m[1])
+			cy, _ = strconv.Atoi(m[2])
+		}
+		return cx, cy, content
+	}
+
+	// a height only keeps the proportions: 1:3 for a 1D code, the symbol's own for a matrix
+	if cx, cy, content := extent("{v|barcode:`inline`:`*15mm`}", "ABC-123"); cx != 3*540000 || cy != 540000 {
+		t.Errorf("code128 *15mm: %d×%d, want 45mm×15mm:\n%s", cx, cy, content)
+	}
+	if cx, cy, content := extent("{v|barcode:`datamatrix`:`inline`:`*20mm`}", "https://example.com"); cx != 720000 || cy != 720000 {
+		t.Errorf("datamatrix *20mm: %d×%d, want a 20mm square:\n%s", cx, cy, content)
+	}
+
+	// a width with less than a pixel for a module is an error, not bars past the edge
+	long := strings.Repeat("Z9", 20)
+	if _, _, content := extent("{v|barcode:`inline`:`10mm`}", long); !strings.Contains(content, "barcode error: code128") ||
+		!strings.Contains(content, "need a width of at least") || strings.Contains(content, "<wp:inline") {
+		t.Errorf("too narrow:\n%s", content)
+	}
+	if _, _, content := extent("{v|barcode:`inline`:`60mm`}", long); strings.Contains(content, "barcode error") {
+		t.Errorf("wide enough:\n%s", content)
+	}
+}
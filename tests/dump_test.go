@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"docxgen"
+	"docxgen/daemon"
+)
+
+func TestTransformedTemplates(t *testing.T) {
+	doc := openTemplate(t, "<w:p><w:r><w:t>{name|nowrap}</w:t></w:r></w:p>\n<w:p><w:r><w:t>{name|nosuch}</w:t></w:r></w:p>")
+	parts, err := doc.TransformedTemplates(map[string]any{"name": "Иван"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 1 || parts[0].Part != "document" {
+		t.Fatalf("parts: %+v", parts)
+	}
+	if !strings.Contains(parts[0].Template, "{.name | nowrap}") {
+		t.Errorf("tags must be transformed:\n%s", parts[0].Template)
+	}
+	if parts[0].Err == nil || !strings.Contains(parts[0].Err.Error(), "docx:2:") {
+		t.Errorf("the parse error must point at line 2: %v", parts[0].Err)
+	}
+
+	var out bytes.Buffer
+	if err := docxgen.WriteTransformed(&out, parts); err != nil {
+		t.Fatal(err)
+	}
+	dump := out.String()
+	for _, want := range []string{"=== word/document.xml ===\n", "!!! template: docx:2:", "   1 | <w:document>", "   2 | <w:p>"} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("dump has no %q:\n%s", want, dump)
+		}
+	}
+
+	// the dump does not execute the template
+	if content, _ := doc.ContentPart("document"); !strings.Contains(content, "{name|nowrap}") {
+		t.Error("the document must stay a template")
+	}
+}
+
+func TestDaemonDumpTemplate(t *testing.T) {
+	body, _ := json.Marshal(map[string]any{
+		"template": templateBase64(t, "<w:p><w:r><w:t>{name|nosuch}</w:t></w:r></w:p>"),
+		"format":   "template",
+	})
+	w := httptest.NewRecorder()
+	daemon.NewHandler(daemon.Config{}).ServeHTTP(w, httptest.NewRequest("POST", "/generate", bytes.NewReader(body)))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `function "nosuch" not defined`) {
+		t.Errorf("status %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestParseErrorLocation(t *testing.T) {
+	filler := "<w:p><w:r><w:t>{name}</w:t></w:r></w:p>"
+	cases := []struct {
+		body, tag, excerpt string
+	}{
+		{"<w:p><w:r><w:t>Уважаемый {name|nosuch}, добрый день!</w:t></w:r></w:p>", "{name|nosuch}", "Уважаемый {name|nosuch}, добрый день!"},
+		{"<w:p><w:r><w:t>{if .vip}VIP</w:t></w:r></w:p>", "{if .vip}", "{if .vip}VIP"},
+		{"<w:p><w:r><w:t>A {end} B</w:t></w:r></w:p>", "{end}", "A {end} B"},
+		{"<w:p><w:r><w:t>Сумма: {.sum | printf `%d` )}</w:t></w:r></w:p>", "{.sum | printf `%d` )}", "Сумма: {.sum | printf `%d` )}"},
+	}
+	for _, c := range cases {
+		doc := openTemplate(t, filler+c.body+filler)
+		err := doc.ExecuteTemplate(map[string]any{"name": "Иван"})
+		var perr *docxgen.ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("%s: expected ParseError, got %v", c.tag, err)
+			continue
+		}
+		if perr.Part != "document" || perr.Tag != c.tag || !strings.Contains(perr.Excerpt, c.excerpt) {
+			t.Errorf("%s: got tag %q excerpt %q", c.tag, perr.Tag, perr.Excerpt)
+		}
+		if !strings.Contains(err.Error(), c.tag) {
+			t.Errorf("the message must name the tag: %v", err)
+		}
+	}
+
+	long := strings.Repeat("слово ", 30)
+	doc := openTemplate(t, "<w:p><w:r><w:t>"+long+"{name|nosuch}"+long+"</w:t></w:r></w:p>")
+	var perr *docxgen.ParseError
+	if err := doc.ExecuteTemplate(nil); !errors.As(err, &perr) {
+		t.Fatal(err)
+	}
+	if n := len([]rune(perr.Excerpt)); n > 100 || !strings.HasPrefix(perr.Excerpt, "…") || !strings.HasSuffix(perr.Excerpt, "…") {
+		t.Errorf("the excerpt must be cut to about 80 characters: %q", perr.Excerpt)
+	}
+}
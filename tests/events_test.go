@@ -0,0 +1,42 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"docxgen"
+)
+
+func TestRenderEvents(t *testing.T) {
+	doc := openTemplate(t, `<w:p><w:r><w:t>{client.name} {client.inn} {missing|default:`+"`-`"+`}</w:t></w:r></w:p>`+
+		`<w:p><w:r><w:t>[table/items]</w:t></w:r></w:p>`+
+		`<w:tbl><w:tr><w:tc><w:p><w:r><w:t>{title} {qty}</w:t></w:r></w:p></w:tc></w:tr></w:tbl>`+
+		`<w:p><w:r><w:t>[/table]</w:t></w:r></w:p>`)
+
+	var events []string
+	doc.Observe(func(e docxgen.Event) {
+		switch ev := e.(type) {
+		case docxgen.PartStarted:
+			events = append(events, "part:"+ev.Part)
+		case docxgen.TableRendered:
+			events = append(events, fmt.Sprintf("table:%s:%d", ev.Name, ev.Rows))
+		case docxgen.IncludeResolved:
+			events = append(events, "include:"+ev.File)
+		case docxgen.TagUnresolved:
+			events = append(events, "unresolved:"+ev.Name)
+		}
+	})
+
+	err := doc.ExecuteTemplate(map[string]any{
+		"client": map[string]any{"name": "ООО Ромашка"},
+		"items":  []map[string]any{{"title": "a", "qty": 1}, {"title": "b", "qty": 2}},
+	})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+
+	want := []string{"part:document", "table:items:2", "unresolved:client.inn", "unresolved:missing"}
+	if fmt.Sprint(events) != fmt.Sprint(want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+}
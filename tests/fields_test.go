@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"docxgen/docxtest"
+)
+
+const fieldsBody = `<w:p><w:r><w:fldChar w:fldCharType="begin"/></w:r><w:r><w:instrText xml:space="preserve"> TOC \o "1-3" \h </w:instrText></w:r>` +
+	`<w:r><w:fldChar w:fldCharType="separate"/></w:r><w:r><w:t>Введение</w:t></w:r>` +
+	`<w:r><w:fldChar w:fldCharType="begin"/></w:r><w:r><w:instrText> PAGEREF _Toc1 \h </w:instrText></w:r>` +
+	`<w:r><w:fldChar w:fldCharType="separate"/></w:r><w:r><w:t>3</w:t></w:r><w:r><w:fldChar w:fldCharType="end"/></w:r>` +
+	`<w:r><w:fldChar w:fldCharType="end"/></w:r></w:p>` +
+	`<w:p><w:r><w:t xml:space="preserve">См. пункт </w:t></w:r><w:fldSimple w:instr=" REF _Ref2 \h "><w:r><w:t>1.2</w:t></w:r></w:fldSimple></w:p>`
+
+func TestListFields(t *testing.T) {
+	doc := docxtest.New().Body(fieldsBody).
+		Footer(`<w:p><w:fldSimple w:instr="PAGE"><w:r><w:t>1</w:t></w:r></w:fldSimple></w:p>`).Open(t)
+
+	var got []string
+	for _, f := range doc.ListFields() {
+		got = append(got, f.Part+":"+f.Type+"="+f.Result)
+	}
+	want := "footer1:PAGE=1|document:REF=1.2|document:TOC=Введение3|document:PAGEREF=3"
+	if strings.Join(got, "|") != want {
+		t.Errorf("ListFields() = %s, want %s", strings.Join(got, "|"), want)
+	}
+	if f := doc.ListFields()[2]; f.Instruction != ` TOC \o "1-3" \h ` {
+		t.Errorf("TOC instruction = %q", f.Instruction)
+	}
+}
+
+func TestRefreshFieldsOnOpen(t *testing.T) {
+	doc := docxtest.New().Body(fieldsBody).
+		Footer(`<w:p><w:fldSimple w:instr="PAGE"/></w:p>`).Open(t)
+
+	if n := doc.MarkFieldsDirty("toc", "REF"); n != 2 {
+		t.Errorf("MarkFieldsDirty(toc, REF) = %d, want 2", n)
+	}
+	body, _ := doc.ContentPart("document")
+	if !strings.Contains(body, `<w:fldChar w:fldCharType="begin" w:dirty="true"/></w:r><w:r><w:instrText xml:space="preserve"> TOC`) ||
+		!strings.Contains(body, `<w:fldSimple w:instr=" REF _Ref2 \h " w:dirty="true">`) {
+		t.Errorf("TOC and REF must be dirty:\n%s", body)
+	}
+	if strings.Count(body, "w:dirty") != 2 {
+		t.Errorf("PAGEREF must stay as it is:\n%s", body)
+	}
+
+	if n := doc.RefreshFieldsOnOpen(); n != 2 {
+		t.Errorf("RefreshFieldsOnOpen() = %d, want 2 (PAGEREF and PAGE)", n)
+	}
+	footer, _ := doc.ContentPart("footer1")
+	if !strings.Contains(footer, `<w:fldSimple w:instr="PAGE" w:dirty="true"/>`) {
+		t.Errorf("PAGE must be dirty:\n%s", footer)
+	}
+	if !doc.Settings().UpdateFields() {
+		t.Error("updateFields must be set")
+	}
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"docxgen"
+)
+
+// deobfuscate reverses the font obfuscation with the key of the fontTable entry.
+func deobfuscate(data []byte, key string) []byte {
+	hex := strings.NewReplacer("{", "", "}", "", "-", "").Replace(key)
+	out := append([]byte(nil), data...)
+	for i := 0; i < 32; i++ {
+		j := 15 - i%16
+		var b byte
+		for _, c := range hex[2*j : 2*j+2] {
+			b <<= 4
+			switch {
+			case c >= '0' && c <= '9':
+				b |= byte(c - '0')
+			default:
+				b |= byte(c - 'A' + 10)
+			}
+		}
+		out[i] ^= b
+	}
+	return out
+}
+
+func TestEmbedFonts(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "fonts.docx")
+	writeDocx(t, tmp, map[string]string{
+		"word/document.xml": `<w:document><w:body><w:p/></w:body></w:document>`,
+		"word/fontTable.xml": `<w:fonts xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+			`<w:font w:name="Times New Roman"><w:charset w:val="CC"/></w:font><w:font w:name="Arial"/></w:fonts>`,
+	})
+	doc, err := docxgen.Open(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join("..", "fonts", "TimesNewRoman")
+	bold := filepath.Join(dir, "TimesNewRomanBold.ttf")
+	if err := doc.EmbedFonts(bold, filepath.Join(dir, "TimesNewRoman.ttf"), bold); err != nil {
+		t.Fatalf("embed: %v", err)
+	}
+
+	table, _ := doc.GetFile("word/fontTable.xml")
+	entry := regexp.MustCompile(`<w:font w:name="Times New Roman">(.*?)</w:font>`).FindSubmatch(table)
+	if entry == nil || !bytes.Contains(table, []byte(`xmlns:r=`)) {
+		t.Fatalf("unexpected font table:\n%s", table)
+	}
+	// the charset stays, the embedding elements follow it in the schema order
+	embeds := regexp.MustCompile(`<w:(embed\w+) r:id="(\w+)" w:fontKey="(\{[0-9A-F-]+\})"/>`).FindAllSubmatch(entry[1], -1)
+	if !bytes.HasPrefix(entry[1], []byte(`<w:charset`)) || len(embeds) != 2 ||
+		string(embeds[0][1]) != "embedRegular" || string(embeds[1][1]) != "embedBold" {
+		t.Fatalf("unexpected font entry:\n%s", entry[0])
+	}
+
+	// the stored part is the original font after de-obfuscation
+	original, _ := os.ReadFile(bold)
+	rel, ok := doc.Rels().Lookup("fontTable", string(embeds[1][2]))
+	if !ok || rel.Type != docxgen.RelTypeFont {
+		t.Fatalf("no font relationship %s", embeds[1][2])
+	}
+	part, ok := doc.GetFile(doc.Rels().ResolveTarget("fontTable", rel))
+	if !ok || !bytes.Equal(deobfuscate(part, string(embeds[1][3])), original) || bytes.Equal(part, original) {
+		t.Error("the font part is not the obfuscated font")
+	}
+	if n := len(doc.Rels().List("fontTable")); n != 2 {
+		t.Errorf("embedding the same font twice must not leave extra relationships: %d", n)
+	}
+
+	if !doc.Settings().EmbedTrueTypeFonts() {
+		t.Error("embedTrueTypeFonts is not set")
+	}
+	if ct, _ := doc.ContentTypes().Default("odttf"); ct != "application/vnd.openxmlformats-officedocument.obfuscatedFont" {
+		t.Errorf("odttf content type: %q", ct)
+	}
+}
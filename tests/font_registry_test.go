@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"docxgen/daemon"
+	"docxgen/metrics"
+)
+
+func TestFontRegistry(t *testing.T) {
+	reg, err := metrics.ScanFonts(filepath.Join("..", "fonts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID, err := reg.Lookup("timesnewroman")
+	if err != nil {
+		t.Fatal(err)
+	}
+	byFamily, err := reg.Lookup("Times New Roman")
+	if err != nil || byFamily != byID {
+		t.Fatalf("lookup by family: %v", err)
+	}
+	if byID.Bold == byID.Regular || byID.BoldItalic == byID.Italic {
+		t.Error("styles must be detected from the font names")
+	}
+	if paths, _ := reg.Paths("TimesNewRoman"); len(paths) != 4 {
+		t.Errorf("paths: %v", paths)
+	}
+
+	_, err = reg.Lookup("Comic Sans")
+	if !errors.Is(err, metrics.ErrUnknownFontSet) || !strings.Contains(err.Error(), "TimesNewRoman") {
+		t.Errorf("unknown set must list the available ones: %v", err)
+	}
+}
+
+func TestDaemonFontSet(t *testing.T) {
+	reg, err := metrics.ScanFonts(filepath.Join("..", "fonts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	post := func(cfg daemon.Config, fonts string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]any{
+			"template": templateBase64(t, "<w:p><w:r><w:t>{name|p_split:`1`:`1`:`1`}</w:t></w:r></w:p>"),
+			"data":     map[string]any{"name": "Оленька"},
+			"format":   "xml",
+			"fonts":    fonts,
+		})
+		w := httptest.NewRecorder()
+		daemon.NewHandler(cfg).ServeHTTP(w, httptest.NewRequest("POST", "/generate", bytes.NewReader(body)))
+		return w
+	}
+
+	if w := post(daemon.Config{Fonts: reg}, "Times New Roman"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Оленька") {
+		t.Errorf("status %d: %s", w.Code, w.Body.String())
+	}
+	if w := post(daemon.Config{Fonts: reg}, "Comic Sans"); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown font set: status %d, want 400", w.Code)
+	}
+	if w := post(daemon.Config{}, "Times New Roman"); w.Code != http.StatusNotImplemented {
+		t.Errorf("no registry: status %d, want 501", w.Code)
+	}
+}
@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"docxgen"
+)
+
+const rulesYAML = `# display rules of the finance department
+rules:
+  - field: balance
+    when: < 0
+    color: "C00000"
+  - field: "*"          # every tag
+    when: "> 100000"
+    bold: true
+  - field: status
+    when: == closed
+    italic: true
+`
+
+func TestParseFormatRules(t *testing.T) {
+	rules, err := docxgen.ParseFormatRules([]byte(rulesYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []docxgen.FormatRule{
+		{Field: "balance", When: "< 0", Color: "C00000"},
+		{Field: "*", When: "> 100000", Bold: true},
+		{Field: "status", When: "== closed", Italic: true},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("got %d rules: %+v", len(rules), rules)
+	}
+	for i := range want {
+		if rules[i] != want[i] {
+			t.Errorf("rule %d = %+v, want %+v", i, rules[i], want[i])
+		}
+	}
+
+	if _, err := docxgen.ParseFormatRules([]byte("rules:\n  - field: a\n    colour: red\n")); err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("unknown key must be reported with its line: %v", err)
+	}
+	doc := openTemplate(t, "<w:p/>")
+	if err := doc.SetFormatRules([]docxgen.FormatRule{{Field: "a", When: "> many", Bold: true}}); err == nil {
+		t.Error("a non-numeric comparison must be rejected")
+	}
+}
+
+func TestFormatRulesApplied(t *testing.T) {
+	doc := openTemplate(t, "<w:p><w:r><w:t>{balance|nowrap} {status} {other}</w:t></w:r></w:p>"+
+		"<w:p><w:r><w:t>{range .rows}[{.balance}]{end}</w:t></w:r></w:p>")
+	rules, _ := docxgen.ParseFormatRules([]byte(rulesYAML))
+	if err := doc.SetFormatRules(rules); err != nil {
+		t.Fatal(err)
+	}
+
+	data := map[string]any{
+		"balance": "-1 500,00",
+		"status":  "closed",
+		"other":   250000,
+		"rows":    []any{map[string]any{"balance": 10}, map[string]any{"balance": -3}},
+	}
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatalf("execute template: %v", err)
+	}
+	content, _ := doc.ContentPart("document")
+
+	for _, want := range []string{
+		"<w:color w:val=\"C00000\"/></w:rPr><w:t xml:space=\"preserve\">-1\u00a0500,00</w:t>", // after the modifier
+		`<w:i/><w:iCs/></w:rPr><w:t xml:space="preserve">closed</w:t>`,
+		`<w:b/><w:bCs/></w:rPr><w:t xml:space="preserve">250000</w:t>`,
+		`[10]`, // inside range: the positive value stays plain
+		`<w:color w:val="C00000"/></w:rPr><w:t xml:space="preserve">-3</w:t>`,
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("%s not found:\n%s", want, content)
+		}
+	}
+}
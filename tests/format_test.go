@@ -0,0 +1,224 @@
+package tests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"text/template"
+
+	"docxgen/modifiers"
+)
+
+func TestStyledRun_Chain(t *testing.T) {
+	doc := openTemplate(t, `<w:p><w:r><w:t xml:space="preserve">`+
+		"{title|smallcaps|spacing:1.5}|{warning|bold|color:`FF0000`}|{price|money|strike}|{title|bold|size:14}|{name|prefix:`ООО «`|postfix:`»`|italic}"+
+		`</w:t></w:r></w:p>`)
+	if err := doc.ExecuteTemplate(map[string]any{
+		"title": "Договор & акт", "warning": "Просрочено", "price": 1234.5, "name": "Рога & копыта",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	xml, _ := doc.ContentPart("document")
+	for _, want := range []string{
+		`<w:rPr><w:smallCaps/><w:spacing w:val="30"/></w:rPr><w:t xml:space="preserve">Договор &amp; акт</w:t>`,
+		`<w:rPr><w:b/><w:bCs/><w:color w:val="FF0000"/></w:rPr><w:t xml:space="preserve">Просрочено</w:t>`,
+		`<w:rPr><w:strike/></w:rPr><w:t xml:space="preserve">1 234,50</w:t>`,
+		`<w:rPr><w:b/><w:bCs/><w:sz w:val="28"/><w:szCs w:val="28"/></w:rPr><w:t xml:space="preserve">Договор &amp; акт</w:t>`,
+		// the text of string modifiers is escaped once
+		`<w:rPr><w:i/><w:iCs/></w:rPr><w:t xml:space="preserve">ООО «Рога &amp; копыта»</w:t>`,
+	} {
+		if !strings.Contains(xml, want) {
+			t.Errorf("no %s in\n%s", want, xml)
+		}
+	}
+}
+
+func TestStyledRun_ReplaceSameProperty(t *testing.T) {
+	first := modifiers.LetterSpacing("текст", "1")
+	second := string(modifiers.LetterSpacing(string(first), "-0.5"))
+	if strings.Count(second, "<w:spacing") != 1 || !strings.Contains(second, `w:val="-10"`) {
+		t.Errorf("spacing not replaced: %s", second)
+	}
+	if got := modifiers.Caps(""); got != "" {
+		t.Errorf("Caps(empty) = %q, want empty", got)
+	}
+}
+
+func TestColorAndHighlight(t *testing.T) {
+	cases := []struct {
+		name string
+		got  modifiers.RawXML
+		want string
+	}{
+		{"hex", modifiers.Color("Просрочено", "#ff0000"), `<w:color w:val="FF0000"/>`},
+		{"short hex", modifiers.Color("x", "f00"), `<w:color w:val="FF0000"/>`},
+		{"named", modifiers.Color("x", "зелёный"), `<w:color w:val="008000"/>`},
+		{"highlight palette", modifiers.Highlight("x", "Yellow"), `<w:highlight w:val="yellow"/>`},
+		{"highlight hex", modifiers.Highlight("x", "FFE0B2"), `<w:shd w:val="clear" w:color="auto" w:fill="FFE0B2"/>`},
+	}
+	for _, c := range cases {
+		if !strings.Contains(string(c.got), c.want) {
+			t.Errorf("%s: %s does not contain %s", c.name, c.got, c.want)
+		}
+	}
+
+	if got := string(modifiers.Color("x", "not-a-color")); strings.Contains(got, "w:color") {
+		t.Errorf("unknown color must not emit w:color: %s", got)
+	}
+
+	// color + highlight in one run, in schema order
+	chained := string(modifiers.Highlight(string(modifiers.Color("x", "red")), "yellow"))
+	if strings.Index(chained, "w:color") > strings.Index(chained, "w:highlight") || strings.Count(chained, "<w:r>") != 2 {
+		t.Errorf("bad chained run: %s", chained)
+	}
+}
+
+func TestIcon(t *testing.T) {
+	cases := []struct {
+		name string
+		got  modifiers.RawXML
+		want string
+	}{
+		{"by name", modifiers.Icon("1", "check"), "✔"},
+		{"status value", modifiers.Icon("fail"), "✘"},
+		{"alias", modifiers.Icon("Внимание"), "⚠"},
+		{"color override", modifiers.Icon("5", "star", "gray"), `<w:color w:val="808080"/>`},
+		{"default color", modifiers.Icon("ok"), `<w:color w:val="2E7D32"/>`},
+		{"unknown passes through", modifiers.Icon("в работе"), "в работе"},
+	}
+	for _, c := range cases {
+		if !strings.Contains(string(c.got), c.want) {
+			t.Errorf("%s: %s does not contain %s", c.name, c.got, c.want)
+		}
+	}
+
+	for _, empty := range []any{"", "0", "false", nil} {
+		if got := modifiers.Icon(empty, "check"); got != "" {
+			t.Errorf("Icon(%v, check) = %q, want empty", empty, got)
+		}
+	}
+}
+
+func TestCheckboxAndSymbol(t *testing.T) {
+	cases := []struct {
+		name string
+		got  modifiers.RawXML
+		want string
+	}{
+		{"true", modifiers.Checkbox(true), `<w:sym w:font="Wingdings" w:char="F0FD"/>`},
+		{"false", modifiers.Checkbox(false), `<w:sym w:font="Wingdings" w:char="F0A8"/>`},
+		{"zero", modifiers.Checkbox(0), `w:char="F0A8"`},
+		{"нет", modifiers.Checkbox("Нет"), `w:char="F0A8"`},
+		{"tick", modifiers.Checkbox("да", "check"), `w:char="F0FE"`},
+		{"segoe", modifiers.Checkbox(1, "segoe"), `<w:rFonts w:ascii="Segoe UI Symbol" w:hAnsi="Segoe UI Symbol" w:cs="Segoe UI Symbol"/></w:rPr><w:t xml:space="preserve">☒</w:t>`},
+		{"segoe empty", modifiers.Checkbox(nil, "segoe"), "☐"},
+		{"radio match", modifiers.Checkbox("Ж", "ж"), `w:char="F0FD"`},
+		{"radio other", modifiers.Checkbox("м", "ж"), `w:char="F0A8"`},
+		{"symbol font", modifiers.Symbol(true, "FE", "Wingdings"), `<w:sym w:font="Wingdings" w:char="F0FE"/>`},
+		{"unicode", modifiers.Symbol("x", "U+2714"), `<w:t xml:space="preserve">✔</w:t>`},
+		{"char", modifiers.Symbol("x", "★", "Arial"), `w:ascii="Arial"`},
+	}
+	for _, c := range cases {
+		if !strings.Contains(string(c.got), c.want) {
+			t.Errorf("%s: %s does not contain %s", c.name, c.got, c.want)
+		}
+	}
+
+	if got := modifiers.Symbol("", "F0FE", "Wingdings"); got != "" {
+		t.Errorf("Symbol(empty) = %q, want empty", got)
+	}
+
+	// a box of a template is a valid run
+	doc := openTemplate(t, "<w:p><w:r><w:t>{agree|checkbox} согласен</w:t></w:r></w:p>")
+	if err := doc.ExecuteTemplate(map[string]any{"agree": true}); err != nil {
+		t.Fatal(err)
+	}
+	xml, _ := doc.ContentPart("document")
+	if !strings.Contains(xml, `<w:r><w:sym w:font="Wingdings" w:char="F0FD"/></w:r>`) {
+		t.Errorf("checkbox in a document: %s", xml)
+	}
+}
+
+func TestCheckboxInheritsTagRun(t *testing.T) {
+	const tagProps = `<w:color w:val="333333"/><w:sz w:val="32"/>`
+	doc := openTemplate(t, `<w:p><w:r><w:rPr>`+tagProps+`</w:rPr>`+
+		"<w:t xml:space=\"preserve\">{sex|checkbox:`м`} муж.</w:t></w:r></w:p>")
+	if err := doc.ExecuteTemplate(map[string]any{"sex": "м"}); err != nil {
+		t.Fatal(err)
+	}
+	xml, _ := doc.ContentPart("document")
+	for _, want := range []string{
+		// the box: the size and colour of the tag run
+		`<w:r><w:rPr>` + tagProps + `</w:rPr><w:sym w:font="Wingdings" w:char="F0FD"/></w:r>`,
+		// the text after the box: the same run properties, the leading space kept
+		`<w:r><w:rPr>` + tagProps + `</w:rPr><w:t xml:space="preserve"> муж.</w:t></w:r>`,
+	} {
+		if !strings.Contains(xml, want) {
+			t.Errorf("no %s in\n%s", want, xml)
+		}
+	}
+	if strings.Contains(xml, modifiers.InheritRunMarker) {
+		t.Errorf("the marker is left: %s", xml)
+	}
+}
+
+func TestEmphasis(t *testing.T) {
+	fm := modifiers.NewFuncMap(modifiers.Options{})
+	tmpl, err := template.New("fmt").Funcs(fm).Parse(
+		"{{ .warning | bold | color `FF0000` }}|{{ .term | italic | underline `double` }}|{{ .title | size `10,5` | font `Arial` }}|{{ .old | strike }}")
+	if err != nil {
+		t.Fatalf("parse template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, map[string]any{"warning": "Внимание", "term": "оферта", "title": "Акт", "old": 100}); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	parts := strings.Split(buf.String(), "|")
+	want := []string{
+		`<w:rPr><w:b/><w:bCs/><w:color w:val="FF0000"/></w:rPr><w:t xml:space="preserve">Внимание</w:t>`,
+		`<w:rPr><w:i/><w:iCs/><w:u w:val="double"/></w:rPr>`,
+		`<w:rPr><w:rFonts w:ascii="Arial" w:hAnsi="Arial" w:cs="Arial"/><w:sz w:val="21"/><w:szCs w:val="21"/></w:rPr>`,
+		`<w:rPr><w:strike/></w:rPr><w:t xml:space="preserve">100</w:t>`,
+	}
+	for i, w := range want {
+		if !strings.Contains(parts[i], w) || strings.Count(parts[i], "<w:rPr>") != 1 {
+			t.Errorf("part %d: %s\nwant %s", i, parts[i], w)
+		}
+	}
+
+	if got := modifiers.Bold(""); got != "" {
+		t.Errorf("Bold(empty) = %q, want empty", got)
+	}
+	if got := string(modifiers.Underline("x", "zigzag")); !strings.Contains(got, `<w:u w:val="single"/>`) {
+		t.Errorf("unknown underline kind: %s", got)
+	}
+	if got := string(modifiers.FontSize("x", "big")); strings.Contains(got, "w:sz") {
+		t.Errorf("bad size must not emit w:sz: %s", got)
+	}
+}
+
+func TestStyledRunInheritsTagRun(t *testing.T) {
+	const tagProps = `<w:rFonts w:ascii="Arial" w:hAnsi="Arial"/><w:color w:val="333333"/><w:sz w:val="32"/>`
+	doc := openTemplate(t, `<w:p><w:r><w:rPr>`+tagProps+`</w:rPr>`+
+		`<w:t xml:space="preserve">{x|smallcaps} и {y|color:"red"} далее</w:t></w:r></w:p>`)
+	if err := doc.ExecuteTemplate(map[string]any{"x": "Договор", "y": "акт"}); err != nil {
+		t.Fatal(err)
+	}
+	xml, _ := doc.ContentPart("document")
+	for _, want := range []string{
+		// the value: the font and size of the tag run with its own property
+		`<w:r><w:rPr><w:rFonts w:ascii="Arial" w:hAnsi="Arial"/><w:smallCaps/><w:color w:val="333333"/><w:sz w:val="32"/></w:rPr><w:t xml:space="preserve">Договор</w:t></w:r>`,
+		// the text after the tag: the same run properties, the leading space kept
+		`<w:r><w:rPr>` + tagProps + `</w:rPr><w:t xml:space="preserve"> и </w:t></w:r>`,
+		// the own color replaces the one of the tag run
+		`<w:r><w:rPr><w:rFonts w:ascii="Arial" w:hAnsi="Arial"/><w:color w:val="FF0000"/><w:sz w:val="32"/></w:rPr><w:t xml:space="preserve">акт</w:t></w:r>`,
+		`<w:r><w:rPr>` + tagProps + `</w:rPr><w:t xml:space="preserve"> далее</w:t></w:r>`,
+	} {
+		if !strings.Contains(xml, want) {
+			t.Errorf("no %s in\n%s", want, xml)
+		}
+	}
+	if strings.Contains(xml, modifiers.InheritRunMarker) {
+		t.Errorf("the marker is left: %s", xml)
+	}
+}
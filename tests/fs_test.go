@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"docxgen"
+	"docxgen/docxtest"
+	"docxgen/metrics"
+)
+
+func TestIncludesFromFS(t *testing.T) {
+	files := fstest.MapFS{
+		"blocks/sign.docx":  {Data: docxtest.New().Paragraph("Подпись").Paragraph("[include/blocks/stamp.docx]").Bytes()},
+		"blocks/stamp.docx": {Data: docxtest.New().Paragraph("М. П.").Bytes()},
+		"secret.docx":       {Data: docxtest.New().Paragraph("тайна").Bytes()},
+	}
+	template := docxtest.New().
+		Paragraph("Акт {number}").
+		Paragraph("[include/blocks/sign.docx]").
+		Paragraph("[include/../secret.docx]").
+		Bytes()
+
+	doc, err := docxgen.OpenBytes(template, docxgen.WithFS(files))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := doc.ExecuteTemplateResult(map[string]any{"number": 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := doc.ContentPart("document")
+	text := strings.Join(paragraphTexts(body), "|")
+	if text != "Акт 7|Подпись|М. П." {
+		t.Errorf("paragraphs = %q: the includes come from the FS", text)
+	}
+	if len(res.Warnings) != 1 || !strings.Contains(res.Warnings[0].Message, "forbidden") {
+		t.Errorf("a path out of the FS must be refused: %v", res.Warnings)
+	}
+}
+
+func TestParseFonts(t *testing.T) {
+	var data [4][]byte
+	for i, name := range []string{"TimesNewRoman", "TimesNewRomanBold", "TimesNewRomanItalic", "TimesNewRomanBoldItalic"} {
+		raw, err := os.ReadFile(filepath.Join("..", "fonts", "TimesNewRoman", name+".ttf"))
+		if err != nil {
+			t.Skip(err)
+		}
+		data[i] = raw
+	}
+	fonts, err := metrics.ParseFonts(data[0], data[1], data[2], data[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, err := fonts.Measure("Акт", metrics.Bold, 12); err != nil || w <= 0 {
+		t.Errorf("Measure = %v, %v", w, err)
+	}
+	if _, err := metrics.ParseFonts(data[0], nil, data[2], data[3]); err == nil || !strings.Contains(err.Error(), "bold") {
+		t.Errorf("a broken style must be named: %v", err)
+	}
+}
+
+func TestIncludeInline(t *testing.T) {
+	files := fstest.MapFS{"stamp.docx": {Data: docxtest.New().Paragraph("М. П.").Bytes()}}
+	for body, want := range map[string]string{
+		`<w:p><w:r><w:t>См. [include/stamp.docx] ниже</w:t></w:r></w:p>`:                                           "См.|М. П.|ниже",
+		`<w:p w:rsidR="00A1"><w:r><w:t>См. [include/stamp.docx] ниже</w:t></w:r></w:p>`:                            "См.|М. П.|ниже",
+		`<w:p w:rsidR="00A1"><w:r><w:t>[include/stamp.docx]</w:t></w:r></w:p><w:p><w:r><w:t>{n}</w:t></w:r></w:p>`: "М. П.|7",
+	} {
+		doc, err := docxgen.OpenBytes(docxtest.New().Body(body).Bytes(), docxgen.WithFS(files))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := doc.ExecuteTemplate(map[string]any{"n": 7}); err != nil {
+			t.Fatal(err)
+		}
+		out, _ := doc.ContentPart("document")
+		if got := strings.Join(paragraphTexts(out), "|"); got != want || strings.Contains(out, "[include/") {
+			t.Errorf("%s:\ngot  %s\nwant %s", body, got, want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package tests
 
 import (
 	"docxgen/modifiers"
+	"encoding/json"
 	"reflect"
 	"testing"
 )
@@ -60,6 +61,18 @@ func TestBuiltinsSignature(t *testing.T) {
 		{"money", []any{12345.67}, "12 345,67"},
 		{"money", []any{"int", 12345.00}, "12 345"},
 		{"money", []any{"%s рублей %02d копеек", 12345.67}, "12 345 рублей 67 копеек"},
+		{"money", []any{"12345678901234567.89"}, "12 345 678 901 234 567,89"},
+		{"money", []any{json.Number("9007199254740993")}, "9 007 199 254 740 993,00"},
+		{"money", []any{1.005}, "1,01"},
+		{"money", []any{"0,125"}, "0,13"},
+		{"money", []any{-0.004}, "0,00"},
+		{"money", []any{"int", "99999999999999999.49"}, "99 999 999 999 999 999"},
+		{"money", []any{"1e100000"}, "1e100000"},
+		{"money", []any{json.Number("1e3")}, "1 000,00"},
+		{"money", []any{"2,5E-2"}, "0,03"},
+		{"money", []any{"1e31"}, "1e31"},
+		{"money", []any{"1/3"}, "1/3"},
+		{"money", []any{".5"}, "0,50"},
 		{"roman", []any{14}, "XIV"},
 		{"roman", []any{1}, "I"},
 		{"roman", []any{3999}, "MMMCMXCIX"},
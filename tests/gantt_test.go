@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"docxgen"
+)
+
+// ganttBars - the shaded cells of every row of the first table: "#" — shaded, "." — not.
+func ganttBars(xml string) []string {
+	var bars []string
+	for _, row := range strings.Split(xml, "<w:tr>")[1:] {
+		var b strings.Builder
+		for _, cell := range strings.Split(row, "<w:tc>")[1:] {
+			if strings.Contains(cell, "w:fill=") {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		bars = append(bars, b.String())
+	}
+	return bars
+}
+
+func TestGanttBlock(t *testing.T) {
+	doc := openTemplate(t, "<w:p><w:r><w:t>[gantt/tasks text:name]</w:t></w:r></w:p>")
+	res, err := doc.ExecuteTemplateResult(map[string]any{"tasks": []any{
+		map[string]any{"name": "Анализ", "start": "2025-11-03", "end": "05.11.2025"},
+		map[string]any{"name": "Разработка", "start": time.Date(2025, 11, 5, 0, 0, 0, 0, time.UTC), "end": "2025-11-09"},
+		map[string]any{"name": "Релиз", "start": "2025-11-09"},
+		map[string]any{"name": "Без срока"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Warnings) != 0 {
+		t.Errorf("warnings: %v", res.Warnings)
+	}
+	xml, _ := doc.ContentPart("document")
+	texts := strings.Join(paragraphTexts(xml), "|")
+	if !strings.HasPrefix(texts, "Задача|03.11|04.11|05.11|06.11|07.11|08.11|09.11|Анализ|Разработка|Релиз|Без срока") {
+		t.Errorf("texts: %s", texts)
+	}
+	want := []string{"........", ".###....", "...#####", ".......#", "........"}
+	if got := ganttBars(xml); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("bars %v, want %v", got, want)
+	}
+}
+
+func TestGanttSteps(t *testing.T) {
+	tasks := []any{
+		map[string]any{"title": "Этап 1", "start": "2025-01-15", "end": "2025-02-10"},
+		map[string]any{"title": "Этап 2", "start": "2025-02-11", "end": "2025-03-20"},
+	}
+
+	// 65 days do not fit the days: by weeks from Monday 13.01
+	doc := openTemplate(t, "<w:p><w:r><w:t>[gantt/tasks color:green]</w:t></w:r></w:p>")
+	if err := doc.ExecuteTemplate(map[string]any{"tasks": tasks}); err != nil {
+		t.Fatal(err)
+	}
+	xml, _ := doc.ContentPart("document")
+	if texts := paragraphTexts(xml); len(texts) < 2 || texts[1] != "13.01" || !strings.Contains(xml, `w:fill="008000"`) {
+		t.Errorf("weeks: %v", texts)
+	}
+	if got := ganttBars(xml); got[1] != ".#####....." || got[2] != ".....######" {
+		t.Errorf("week bars: %v", got)
+	}
+
+	doc = openTemplate(t, "<w:p><w:r><w:t>[gantt/tasks step:month]</w:t></w:r></w:p>"+
+		"<w:p><w:r><w:t>[gantt/missing]</w:t></w:r></w:p><w:p><w:r><w:t>[gantt/tasks step:year]</w:t></w:r></w:p>")
+	res, err := doc.ExecuteTemplateResult(map[string]any{"tasks": tasks})
+	if err != nil {
+		t.Fatal(err)
+	}
+	xml, _ = doc.ContentPart("document")
+	if got := ganttBars(xml); strings.Join(got, " ") != ".... .##. ..##" {
+		t.Errorf("month bars: %v", got)
+	}
+	if len(res.Warnings) != 2 || res.Warnings[0].Kind != docxgen.WarnBadGantt || strings.Contains(xml, "[gantt/") {
+		t.Errorf("warnings: %v", res.Warnings)
+	}
+}
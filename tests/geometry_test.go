@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"docxgen/geometry"
+)
+
+func TestParseLength(t *testing.T) {
+	page := geometry.FromMM(180)
+	cases := []struct {
+		in   string
+		want geometry.Length
+	}{
+		{"40mm", 40 * geometry.MM},
+		{"2,5cm", 25 * geometry.MM},
+		{"12pt", 12 * geometry.Point},
+		{"1in", geometry.Inch},
+		{"96px", geometry.Inch},
+		{"1440tw", geometry.Inch},
+		{"360000emu", 10 * geometry.MM},
+		{"50%", 90 * geometry.MM},
+		{"15", 15 * geometry.MM},
+	}
+	for _, c := range cases {
+		got, err := geometry.ParseLength(c.in, page)
+		if err != nil || got != c.want {
+			t.Errorf("ParseLength(%q) = %v, %v; want %v", c.in, got, err, c.want)
+		}
+	}
+	if _, err := geometry.ParseLength("abc", page); err == nil {
+		t.Error("expected an error for an invalid length")
+	}
+}
+
+func TestLengthConversions(t *testing.T) {
+	l := geometry.FromTwips(11906) // A4 width in w:pgSz
+	if mm := l.MM(); mm < 209.9 || mm > 210.1 {
+		t.Errorf("11906 twips = %v mm, want ≈210", mm)
+	}
+	if got := geometry.FromPt(12).HalfPoints(); got != 24 {
+		t.Errorf("12pt = %d half-points, want 24", got)
+	}
+	if got := geometry.FromMM(10).Pixels(304.8); got != 120 {
+		t.Errorf("10mm at 304.8 dpi = %d px, want 120", got)
+	}
+}
+
+func TestParseSizeAndSides(t *testing.T) {
+	w, h, err := geometry.ParseSize("80%*10mm", geometry.FromMM(200), geometry.FromMM(300))
+	if err != nil || w != 160*geometry.MM || h != 10*geometry.MM {
+		t.Errorf("ParseSize = %v*%v, %v", w, h, err)
+	}
+	if _, h, _ := geometry.ParseSize("40mm", 0, 0); h != 0 {
+		t.Errorf("height without * must be 0, got %v", h)
+	}
+
+	s, err := geometry.ParseSides("5/3/7")
+	want := geometry.Sides{Top: 5 * geometry.MM, Right: 3 * geometry.MM, Bottom: 7 * geometry.MM, Left: 3 * geometry.MM}
+	if err != nil || s != want {
+		t.Errorf("ParseSides = %+v, %v; want %+v", s, err, want)
+	}
+}
+
+func TestSectionUsableSize(t *testing.T) {
+	sect := func(w, h, margin int) string {
+		return fmt.Sprintf(`<w:sectPr><w:pgSz w:w="%d" w:h="%d"/><w:pgMar w:top="%[3]d" w:right="%[3]d" w:bottom="%[3]d" w:left="%[3]d"/></w:sectPr>`, w, h, margin)
+	}
+	body := "<w:p><w:r><w:t>{a|barcode:`inline`:`50%*10mm`}</w:t></w:r></w:p>" +
+		"<w:p><w:pPr>" + sect(11906, 16838, 1134) + "</w:pPr></w:p>" +
+		"<w:p><w:r><w:t>{b|barcode:`inline`:`50%*10mm`}</w:t></w:r></w:p>" +
+		sect(16838, 11906, 1134)
+	doc := openTemplate(t, body)
+
+	sections := doc.Sections()
+	if len(sections) != 2 {
+		t.Fatalf("expected 2 sections, got %d", len(sections))
+	}
+	if sections[1].Width != geometry.FromTwips(16838) || sections[1].Margins.Left != geometry.FromTwips(1134) {
+		t.Errorf("unexpected second section: %v", sections[1])
+	}
+
+	if err := doc.ExecuteTemplate(map[string]any{"a": "A-1", "b": "B-2"}); err != nil {
+		t.Fatalf("execute template: %v", err)
+	}
+	content, _ := doc.ContentPart("document")
+
+	for i, s := range sections {
+		want := fmt.Sprintf(`cx="%d"`, s.UsableWidth()/2)
+		if !strings.Contains(content, want) {
+			t.Errorf("section %d: barcode width %s not found:\n%s", i, want, content)
+		}
+	}
+	if strings.Contains(content, "_section") {
+		t.Errorf("section markers left in the output:\n%s", content)
+	}
+}
@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestHTMLModifier(t *testing.T) {
+	rich := `<p>Hello <b>bold <i>both</i></b> &amp; <a href="https://example.com">link</a></p>
+<ul><li>one<li>two<ul><li>deep</li></ul></li></ul><ol><li>first</li></ol>
+<table><tr><th>A</th><td>B</td></tr><tr><td>C</td></tr></table>tail<br>next<script>alert(1)</script>`
+
+	// the tag run is 14pt, the text width of the section is 11906 - 1134 - 850 twips
+	doc := openTemplate(t, `<w:p><w:pPr><w:jc w:val="both"/></w:pPr><w:r><w:rPr><w:sz w:val="28"/></w:rPr><w:t>{text|html}</w:t></w:r></w:p>`+
+		`<w:sectPr><w:pgSz w:w="11906" w:h="16838"/><w:pgMar w:top="1134" w:right="850" w:bottom="1134" w:left="1134"/></w:sectPr>`)
+	if err := doc.ExecuteTemplate(map[string]any{"text": rich}); err != nil {
+		t.Fatal(err)
+	}
+	xml, _ := doc.ContentPart("document")
+
+	got := paragraphTexts(xml)
+	want := []string{"Hello bold both & link", "•one", "•two", "•deep", "1.first", "A", "B", "C", "tailnext"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("paragraphs %q\nwant %q", got, want)
+	}
+	for _, part := range []string{
+		`<w:rPr><w:b/><w:bCs/><w:i/><w:iCs/><w:sz w:val="28"/></w:rPr><w:t xml:space="preserve">both</w:t>`,
+		`<w:hyperlink r:id="rId1"`,
+		`<w:rPr><w:color w:val="0563C1"/><w:sz w:val="28"/><w:u w:val="single"/></w:rPr><w:t xml:space="preserve">link</w:t>`,
+		`<w:ind w:left="1080" w:hanging="360"/>`,
+		`<w:p><w:pPr><w:jc w:val="both"/></w:pPr><w:r><w:rPr><w:sz w:val="28"/></w:rPr><w:t xml:space="preserve">Hello </w:t>`,
+		`<w:tblGrid><w:gridCol w:w="4961"/><w:gridCol w:w="4961"/></w:tblGrid>`,
+		`<w:tc><w:tcPr><w:tcW w:w="4961" w:type="dxa"/></w:tcPr><w:p/></w:tc>`,
+		`<w:r><w:rPr><w:sz w:val="28"/></w:rPr><w:br/></w:r>`,
+	} {
+		if !strings.Contains(xml, part) {
+			t.Errorf("no %s in %s", part, xml)
+		}
+	}
+	if strings.Contains(xml, "alert") || strings.Contains(xml, "docxgen:") || strings.Contains(xml, "<w:t></w:t>") {
+		t.Errorf("leftovers in %s", xml)
+	}
+	if rel, ok := doc.Rels().Lookup("document", "rId1"); !ok || rel.Target != "https://example.com" || !rel.External() {
+		t.Errorf("link relationship: %+v", rel)
+	}
+}
+
+func TestHTMLModifierSurroundings(t *testing.T) {
+	data := map[string]any{"text": "<p>one</p><p>two</p>"}
+
+	// the text around the tag stays in paragraphs of its own
+	got := paragraphs(t, para("Before {text|html} after"), data)
+	if fmt.Sprint(got) != fmt.Sprint([]string{"Before ", "one", "two", " after"}) {
+		t.Errorf("inline: %q", got)
+	}
+
+	// an unwrapped tag stands between paragraphs
+	got = paragraphs(t, para("start")+para("{*text|html*}")+para("end"), data)
+	if fmt.Sprint(got) != fmt.Sprint([]string{"start", "one", "two", "end"}) {
+		t.Errorf("unwrapped: %q", got)
+	}
+
+	// the text after the blocks keeps the run properties of the tag and its leading space
+	doc := openTemplate(t, `<w:p><w:r><w:rPr><w:sz w:val="32"/></w:rPr><w:t xml:space="preserve">{text|html} пост</w:t></w:r></w:p>`)
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatal(err)
+	}
+	if xml, _ := doc.ContentPart("document"); !strings.Contains(xml, `<w:r><w:rPr><w:sz w:val="32"/></w:rPr><w:t xml:space="preserve"> пост</w:t>`) {
+		t.Errorf("after the blocks: %s", xml)
+	}
+
+	// empty text removes the tag only
+	got = paragraphs(t, para("x {text|html}"), map[string]any{"text": ""})
+	if fmt.Sprint(got) != fmt.Sprint([]string{"x "}) {
+		t.Errorf("empty: %q", got)
+	}
+}
+
+func TestHTMLModifierMalformed(t *testing.T) {
+	cases := []struct {
+		html string
+		want []string
+	}{
+		// a bare < and & are text
+		{"<p>Цена < 100 руб.</p><p>Второй абзац</p>", []string{"Цена &lt; 100 руб.", "Второй абзац"}},
+		{"plain < text & more", []string{"plain &lt; text &amp; more"}},
+		{"a<1 & c&amp;d", []string{"a&lt;1 &amp; c&amp;d"}},
+		// unquoted attributes and a literal ]]> are valid HTML
+		{`<table><tr><td colspan=2>1</td></tr></table><p>x <img src=x> y</p>`, []string{"1", "x y"}},
+		{"<p>a ]]> b</p><p>c</p>", []string{"a ]]&gt; b", "c"}},
+		// implied end tags of the cells keep their order
+		{"<table><tr><td>1<td>2<tr><td>3</table>", []string{"1", "2", "3"}},
+		{"<table><tr><th>A<td>B</td></td></tr></table>после", []string{"A", "B", "после"}},
+		{"<p>a</b></p></p><p>b</p>", []string{"a", "b"}},
+	}
+	for _, c := range cases {
+		got := paragraphs(t, para("{text|html}"), map[string]any{"text": c.html})
+		if fmt.Sprint(got) != fmt.Sprint(c.want) {
+			t.Errorf("%s: %q, want %q", c.html, got, c.want)
+		}
+	}
+
+	// markup a browser reads too: <b =x> is <b>, no warning
+	doc := openTemplate(t, para("{text|html}"))
+	res, err := doc.ExecuteTemplateResult(map[string]any{"text": "<p>До</p><b =x>после</b>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	xml, _ := doc.ContentPart("document")
+	if got := paragraphTexts(xml); fmt.Sprint(got) != fmt.Sprint([]string{"До", "после"}) {
+		t.Errorf("broken markup: %q", got)
+	}
+	if len(res.Warnings) != 0 {
+		t.Errorf("warnings: %v", res.Warnings)
+	}
+}
+
+func TestHTMLModifierParagraphProps(t *testing.T) {
+	doc := openTemplate(t, `<w:p><w:pPr><w:pStyle w:val="Body"/><w:jc w:val="center"/></w:pPr><w:r><w:t>{text|html}</w:t></w:r></w:p>`)
+	if err := doc.ExecuteTemplate(map[string]any{"text": `<a href=https://example.com>link</a> text<p>two</p><ul><li>item</li></ul>`}); err != nil {
+		t.Fatal(err)
+	}
+	xml, _ := doc.ContentPart("document")
+	const pPr = `<w:pPr><w:pStyle w:val="Body"/><w:jc w:val="center"/></w:pPr>`
+	if n := strings.Count(xml, "<w:p>"+pPr); n != 2 {
+		t.Errorf("%d paragraphs with the tag properties, want 2: %s", n, xml)
+	}
+	// a list item keeps its indent among the properties of the tag
+	if !strings.Contains(xml, `<w:p><w:pPr><w:pStyle w:val="Body"/><w:ind w:left="720" w:hanging="360"/><w:jc w:val="center"/></w:pPr>`) {
+		t.Errorf("list item: %s", xml)
+	}
+	if !strings.Contains(xml, `<w:hyperlink r:id=`) {
+		t.Errorf("unquoted href: %s", xml)
+	}
+}
+
+// paragraphTexts - the text of every top-level and cell paragraph.
+func paragraphTexts(xml string) []string {
+	reText := regexp.MustCompile(`<w:t(?: [^>]*)?>([^<]*)</w:t>`)
+	var out []string
+	for _, p := range strings.Split(xml, "</w:p>") {
+		var text strings.Builder
+		for _, m := range reText.FindAllStringSubmatch(p, -1) {
+			text.WriteString(m[1])
+		}
+		if s := strings.ReplaceAll(text.String(), "&amp;", "&"); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
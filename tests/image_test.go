@@ -0,0 +1,163 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"docxgen"
+)
+
+func TestImageModifier(t *testing.T) {
+	// 200×100 px: the aspect ratio is 2:1
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	var pngBuf, jpegBuf bytes.Buffer
+	_ = png.Encode(&pngBuf, img)
+	_ = jpeg.Encode(&jpegBuf, img, nil)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "photo.jpg"), jpegBuf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(pngBuf.Bytes())
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(dir, "card.docx")
+	writeDocx(t, path, map[string]string{
+		"word/document.xml": `<w:document><w:body>` +
+			`<w:p><w:r><w:t>{b64|image:40mm}</w:t></w:r></w:p>` +
+			`<w:p><w:r><w:t>{file|image:*30mm:anchor:left}</w:t></w:r></w:p>` +
+			`<w:p><w:r><w:t>{url|image:20mm*20mm}</w:t></w:r></w:p>` +
+			`<w:p><w:r><w:t>{missing|image}</w:t></w:r></w:p>` +
+			`</w:body></w:document>`,
+	})
+	doc, err := docxgen.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc.ImportBuiltins()
+	doc.SetImageHosts("127.0.0.1") // the test server
+	res, err := doc.ExecuteTemplateResult(map[string]any{
+		"b64":     base64.StdEncoding.EncodeToString(pngBuf.Bytes()),
+		"file":    "photo.jpg",
+		"url":     srv.URL + "/logo.png",
+		"missing": "../../nope.png",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	xml, _ := doc.ContentPart("document")
+
+	// 40 mm wide → 20 mm high; 30 mm high → 60 mm wide; explicit size as is
+	mm := 36000
+	extents := regexp.MustCompile(`<wp:extent cx="(\d+)" cy="(\d+)"/>`).FindAllStringSubmatch(xml, -1)
+	want := [][2]int{{40 * mm, 20 * mm}, {60 * mm, 30 * mm}, {20 * mm, 20 * mm}}
+	if len(extents) != len(want) {
+		t.Fatalf("want %d pictures, got %d: %s", len(want), len(extents), xml)
+	}
+	for i, e := range extents {
+		if e[1] != strconv.Itoa(want[i][0]) || e[2] != strconv.Itoa(want[i][1]) {
+			t.Errorf("picture %d: extent %s×%s, want %v", i, e[1], e[2], want[i])
+		}
+	}
+	if !strings.Contains(xml, "<wp:anchor") || strings.Count(xml, "<wp:inline") != 2 {
+		t.Errorf("inline/anchor modes are wrong: %s", xml)
+	}
+
+	// the path is resolved inside the template directory, there is no such file
+	if len(res.Warnings) != 1 || res.Warnings[0].Kind != docxgen.WarnBadImage {
+		t.Errorf("warnings: %v", res.Warnings)
+	}
+
+	// the media keep their format and are registered
+	var out bytes.Buffer
+	if err := doc.SaveToWriter(&out); err != nil {
+		t.Fatal(err)
+	}
+	media := strings.Join(mediaFiles(t, out.Bytes()), " ")
+	if strings.Count(media, ".png") != 1 || strings.Count(media, ".jpeg") != 1 {
+		t.Errorf("media: %s", media) // the same PNG twice is stored once
+	}
+	zr, _ := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	for _, f := range zr.File {
+		if f.Name != "[Content_Types].xml" && f.Name != "word/_rels/document.xml.rels" {
+			continue
+		}
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		if f.Name == "[Content_Types].xml" && !strings.Contains(string(data), `Extension="jpeg" ContentType="image/jpeg"`) {
+			t.Errorf("content types: %s", data)
+		}
+		if f.Name == "word/_rels/document.xml.rels" && strings.Count(string(data), "media/document_") != 2 {
+			t.Errorf("rels: %s", data)
+		}
+	}
+}
+
+func TestImageByURLGuard(t *testing.T) {
+	var pngBuf bytes.Buffer
+	_ = png.Encode(&pngBuf, image.NewRGBA(image.Rect(0, 0, 10, 10)))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/away":
+			// the same server under another name
+			http.Redirect(w, r, strings.Replace("http://"+r.Host, "127.0.0.1", "localhost", 1)+"/logo.png", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			_, _ = w.Write(pngBuf.Bytes())
+		}
+	}))
+	defer srv.Close()
+	port := srv.URL[strings.LastIndex(srv.URL, ":"):]
+
+	render := func(url string, hosts ...string) (bool, []docxgen.Warning) {
+		t.Helper()
+		doc := openTemplate(t, "<w:p><w:r><w:t>{url|image:10mm}</w:t></w:r></w:p>")
+		doc.SetImageHosts(hosts...)
+		res, err := doc.ExecuteTemplateResult(map[string]any{"url": url})
+		if err != nil {
+			t.Fatal(err)
+		}
+		xml, _ := doc.ContentPart("document")
+		return strings.Contains(xml, "<wp:inline"), res.Warnings
+	}
+
+	cases := []struct {
+		name, url string
+		hosts     []string
+		ok        bool
+	}{
+		{"off by default", srv.URL + "/logo.png", nil, false},
+		{"allowed IP", srv.URL + "/logo.png", []string{"127.0.0.1"}, true},
+		{"not in the list", srv.URL + "/logo.png", []string{"cdn.example.com"}, false},
+		// an allowed name that resolves to an internal address
+		{"internal after DNS", "http://localhost" + port + "/logo.png", []string{"localhost"}, false},
+		{"redirect to another host", srv.URL + "/away", []string{"127.0.0.1"}, false},
+		{"redirect loop", srv.URL + "/loop", []string{"127.0.0.1"}, false},
+	}
+	for _, c := range cases {
+		inserted, warnings := render(c.url, c.hosts...)
+		if inserted != c.ok {
+			t.Errorf("%s: inserted %v, want %v", c.name, inserted, c.ok)
+		}
+		if !c.ok && (len(warnings) != 1 || warnings[0].Kind != docxgen.WarnBadImage) {
+			t.Errorf("%s: warnings %v", c.name, warnings)
+		}
+	}
+}
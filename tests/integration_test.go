@@ -1,26 +1,23 @@
 package tests
 
 import (
-	"archive/zip"
-	"bytes"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"docxgen"
+	"docxgen/docxtest"
 )
 
 func TestDocxgenEndToEnd(t *testing.T) {
 	// создаём фейковый docx с document.xml
-	buf := new(bytes.Buffer)
-	zw := zip.NewWriter(buf)
-	w, _ := zw.Create("word/document.xml")
-	_, _ = w.Write([]byte(`<w:document><w:body><w:p><w:r><w:t>{fio}</w:t></w:r></w:p></w:body></w:document>`))
-	_ = zw.Close()
+	raw := docxtest.Zip(map[string]string{
+		"word/document.xml": `<w:document><w:body><w:p><w:r><w:t>{fio}</w:t></w:r></w:p></w:body></w:document>`,
+	})
 
 	tmp := filepath.Join(os.TempDir(), "test.docx")
-	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
 		t.Fatalf("failed to write temp docx: %v", err)
 	}
 	defer func() {
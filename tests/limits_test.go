@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"docxgen"
+	"docxgen/daemon"
+	"docxgen/docxtest"
+)
+
+func TestSizeLimitsOnOpen(t *testing.T) {
+	data := docxtest.New().Paragraph(strings.Repeat("много текста ", 1000)).Bytes()
+
+	if _, err := docxgen.OpenBytes(data, docxgen.WithSizeLimits(docxgen.SizeLimits{MaxPartSize: 4 << 10})); !errors.Is(err, docxgen.ErrLimitExceeded) ||
+		!strings.Contains(err.Error(), "word/document.xml") {
+		t.Errorf("a part beyond MaxPartSize: %v", err)
+	}
+	if _, err := docxgen.OpenBytes(data, docxgen.WithSizeLimits(docxgen.SizeLimits{MaxTotalSize: 8 << 10})); !errors.Is(err, docxgen.ErrLimitExceeded) {
+		t.Errorf("an archive beyond MaxTotalSize: %v", err)
+	}
+	if _, err := docxgen.OpenBytes(data, docxgen.WithSizeLimits(docxgen.SizeLimits{MaxPartSize: 1 << 20, MaxTotalSize: 1 << 20})); err != nil {
+		t.Errorf("a template within the limits: %v", err)
+	}
+}
+
+func TestSizeLimitsOnRender(t *testing.T) {
+	doc := docxtest.New().Paragraph("{text}").Open(t)
+	doc.SetSizeLimits(docxgen.SizeLimits{MaxPartSize: 64 << 10})
+	if err := doc.ExecuteTemplate(map[string]any{"text": strings.Repeat("x", 100<<10)}); !errors.Is(err, docxgen.ErrLimitExceeded) {
+		t.Errorf("a rendered part beyond MaxPartSize: %v", err)
+	}
+
+	table := `<w:p><w:r><w:t>[table/rows]</w:t></w:r></w:p>` +
+		`<w:tbl><w:tr><w:tc><w:p><w:r><w:t>{name}</w:t></w:r></w:p></w:tc></w:tr></w:tbl>` +
+		`<w:p><w:r><w:t>[/table]</w:t></w:r></w:p>`
+	rows := func(n int) []any {
+		items := make([]any, n)
+		for i := range items {
+			items[i] = map[string]any{"name": "строка"}
+		}
+		return items
+	}
+	base := docxtest.New().Body(table).Open(t)
+	base.SetSizeLimits(docxgen.SizeLimits{MaxRows: 10})
+	tpl := docxgen.NewTemplate(base) // every render counts its own rows
+	for range 2 {
+		if _, err := tpl.Render(map[string]any{"rows": rows(10)}); err != nil {
+			t.Errorf("MaxRows rows: %v", err)
+		}
+	}
+	if _, err := tpl.Render(map[string]any{"rows": rows(11)}); !errors.Is(err, docxgen.ErrLimitExceeded) {
+		t.Errorf("more than MaxRows rows: %v", err)
+	}
+}
+
+func TestMaxRowsOfLoops(t *testing.T) {
+	items := func(n int) []any {
+		out := make([]any, n)
+		for i := range out {
+			out[i] = map[string]any{"name": "пункт"}
+		}
+		return out
+	}
+	for _, body := range []string{
+		`<w:p><w:r><w:t>[for items]</w:t></w:r></w:p><w:p><w:r><w:t>{name}</w:t></w:r></w:p><w:p><w:r><w:t>[/for]</w:t></w:r></w:p>`,
+		`<w:p><w:r><w:t>[list items]</w:t></w:r></w:p><w:p><w:r><w:t>{name}</w:t></w:r></w:p><w:p><w:r><w:t>[/list]</w:t></w:r></w:p>`,
+	} {
+		doc := docxtest.New().Body(body).Open(t)
+		doc.SetSizeLimits(docxgen.SizeLimits{MaxRows: 10})
+		tpl := docxgen.NewTemplate(doc)
+		if _, err := tpl.Render(map[string]any{"items": items(10)}); err != nil {
+			t.Errorf("MaxRows items: %v", err)
+		}
+		_, err := tpl.Render(map[string]any{"items": items(11)})
+		if !errors.Is(err, docxgen.ErrLimitExceeded) || !strings.Contains(err.Error(), "items]") {
+			t.Errorf("more than MaxRows items: %v", err)
+		}
+	}
+}
+
+func TestDaemonSizeLimits(t *testing.T) {
+	h := daemon.NewHandler(daemon.Config{Limits: docxgen.SizeLimits{MaxPartSize: 16 << 10}})
+	post := func(text string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]any{
+			"template": templateBase64(t, `<w:p><w:r><w:t>{text}</w:t></w:r></w:p>`),
+			"data":     map[string]any{"text": text},
+			"format":   "xml",
+		})
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/generate", bytes.NewReader(body)))
+		return w
+	}
+	if w := post("коротко"); w.Code != http.StatusOK {
+		t.Errorf("status %d: %s", w.Code, w.Body.String())
+	}
+	if w := post(strings.Repeat("x", 32<<10)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status %d, want 413: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestDaemonMaxBodySize(t *testing.T) {
+	h := daemon.NewHandler(daemon.Config{MaxBodySize: 8 << 10})
+	tmpl := templateBase64(t, `<w:p><w:r><w:t>{text}</w:t></w:r></w:p>`)
+	for _, route := range []string{"/generate", "/batch"} {
+		post := func(text string) *httptest.ResponseRecorder {
+			body, _ := json.Marshal(map[string]any{
+				"template": tmpl,
+				"data":     map[string]any{"text": text},
+				"items":    []map[string]any{{"text": text}},
+				"format":   "xml",
+			})
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("POST", route, bytes.NewReader(body)))
+			return w
+		}
+		if w := post("коротко"); w.Code != http.StatusOK {
+			t.Errorf("%s: status %d: %s", route, w.Code, w.Body.String())
+		}
+		if w := post(strings.Repeat("x", 64<<10)); w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("%s: status %d, want 413: %s", route, w.Code, w.Body.String())
+		}
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/generate", strings.NewReader(`{"template": `)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("broken json: status %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"docxgen"
+)
+
+func TestLinkModifier(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.docx")
+	writeDocx(t, path, map[string]string{
+		"word/document.xml": `<w:document><w:body>` +
+			"<w:p><w:r><w:t>Договор: {url|link:`Открыть договор`}.</w:t></w:r></w:p>" +
+			`<w:p><w:r><w:t>{site|link} {mail|link} {anchor|link:` + "`см. раздел 2`" + `} {bad|link}</w:t></w:r></w:p>` +
+			`<w:sectPr><w:footerReference w:type="default" r:id="rIdF"/></w:sectPr>` +
+			`</w:body></w:document>`,
+		"word/_rels/document.xml.rels": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rIdF" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/footer" Target="footer1.xml"/>` +
+			`</Relationships>`,
+		"word/footer1.xml": `<w:ftr><w:p><w:r><w:t>{url|link}</w:t></w:r></w:p></w:ftr>`,
+	})
+	doc, err := docxgen.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc.ImportBuiltins()
+	res, err := doc.ExecuteTemplateResult(map[string]any{
+		"url":    "https://example.com/contract?id=1&v=2",
+		"site":   "www.example.ru",
+		"mail":   "info@example.ru",
+		"anchor": "#section2",
+		"bad":    "javascript:alert(1)",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Warnings) != 1 || res.Warnings[0].Kind != docxgen.WarnBadLink {
+		t.Errorf("warnings: %v", res.Warnings)
+	}
+
+	xml, _ := doc.ContentPart("document")
+	if !strings.Contains(xml, `<w:t xml:space="preserve">Открыть договор</w:t></w:r></w:hyperlink>`) ||
+		!strings.Contains(xml, `<w:hyperlink w:anchor="section2">`) ||
+		strings.Count(xml, "<w:hyperlink r:id=") != 3 || !strings.Contains(xml, `<w:t xml:space="preserve"> javascript:alert(1)</w:t>`) {
+		t.Errorf("document: %s", xml)
+	}
+	footer, _ := doc.ContentPart("footer1")
+	if strings.Count(footer, "<w:hyperlink r:id=") != 1 {
+		t.Errorf("footer: %s", footer)
+	}
+
+	// every r:id of a part has its relationship in the .rels of that part
+	rels := doc.Rels()
+	targets := ""
+	for part, body := range map[string]string{"document": xml, "footer1": footer} {
+		for _, m := range regexp.MustCompile(`<w:hyperlink r:id="([^"]+)"`).FindAllStringSubmatch(body, -1) {
+			r, ok := rels.Lookup(part, m[1])
+			if !ok || r.Type != docxgen.RelTypeHyperlink || !r.External() {
+				t.Errorf("%s: no hyperlink relationship %s", part, m[1])
+			}
+			targets += r.Target + " "
+		}
+	}
+	for _, want := range []string{"https://example.com/contract?id=1&v=2", "https://www.example.ru", "mailto:info@example.ru"} {
+		if !strings.Contains(targets, want) {
+			t.Errorf("want target %s in %s", want, targets)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"docxgen/docxtest"
+)
+
+var reNumID = regexp.MustCompile(`<w:numId w:val="(\d+)"/>`)
+
+// numIDs lists the w:numId of the paragraphs in order.
+func numIDs(xml string) []string {
+	var ids []string
+	for _, m := range reNumID.FindAllStringSubmatch(xml, -1) {
+		ids = append(ids, m[1])
+	}
+	return ids
+}
+
+func TestListBlock(t *testing.T) {
+	doc := docxtest.New().
+		Paragraph("[list tags]", "%[1]s", "[/list]").
+		Paragraph("[list steps numbered]", "{title}", "[/list]").
+		Paragraph("[list more/numbered]", "{title}", "[/list]").
+		Paragraph("[list none]", "{x}", "[/list]").
+		Open(t)
+	res, err := doc.ExecuteTemplateResult(map[string]any{
+		"tags":  []string{"срочно", "важно"},
+		"steps": []any{map[string]any{"title": "Подписать"}, map[string]any{"title": "Отправить"}},
+		"more":  []any{map[string]any{"title": "Оплатить"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := doc.ContentPart("document")
+	if texts := paragraphTexts(body); !slices.Equal(texts, []string{"срочно", "важно", "Подписать", "Отправить", "Оплатить"}) {
+		t.Fatalf("paragraphs = %q", texts)
+	}
+
+	ids := numIDs(body)
+	if len(ids) != 5 || ids[0] != ids[1] || ids[2] != ids[3] || ids[1] == ids[2] || ids[3] == ids[4] {
+		t.Errorf("the numIds = %v: one list per block", ids)
+	}
+	numbering, ok := doc.GetFile("word/numbering.xml")
+	if !ok {
+		t.Fatal("no numbering.xml")
+	}
+	for _, want := range []string{`<w:numFmt w:val="bullet"/>`, `<w:numFmt w:val="decimal"/>`, `<w:startOverride w:val="1"/>`} {
+		if !strings.Contains(string(numbering), want) {
+			t.Errorf("numbering.xml has no %s", want)
+		}
+	}
+	if ct, _ := doc.GetFile("[Content_Types].xml"); !strings.Contains(string(ct), "numbering+xml") {
+		t.Error("numbering.xml has no content type")
+	}
+	if len(res.Warnings) != 1 || res.Warnings[0].Name != "none" {
+		t.Errorf("warnings = %v", res.Warnings)
+	}
+}
+
+func TestListBlockKeepsTemplateNumbering(t *testing.T) {
+	doc := docxtest.New().
+		Paragraph("[list items]").
+		Body(`<w:p><w:pPr><w:pStyle w:val="ListParagraph"/><w:numPr><w:ilvl w:val="0"/><w:numId w:val="7"/></w:numPr></w:pPr><w:r><w:t>%[1]s</w:t></w:r></w:p>`).
+		Body(`<w:p><w:pPr><w:pStyle w:val="Note"/><w:jc w:val="center"/></w:pPr><w:r><w:t>—</w:t></w:r></w:p>`).
+		Paragraph("[/list]").
+		Open(t)
+	if err := doc.ExecuteTemplate(map[string]any{"items": []any{"a", "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	body, _ := doc.ContentPart("document")
+	ids := numIDs(body)
+	if len(ids) != 4 || ids[0] != "7" || ids[2] != "7" || ids[1] == "7" {
+		t.Errorf("the numIds = %v: the item of the template keeps its list", ids)
+	}
+	if !strings.Contains(body, `<w:pStyle w:val="Note"/><w:numPr>`) {
+		t.Errorf("numPr must follow pStyle: %s", body)
+	}
+}
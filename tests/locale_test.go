@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"docxgen/modifiers"
+)
+
+func TestNumberLocales(t *testing.T) {
+	call := func(fm map[string]any, name string, args ...any) string {
+		t.Helper()
+		out := fm[name].(func(...any) any)(args...)
+		return strings.NewReplacer(modifiers.NBSP, "~", modifiers.NNBSP, "^").Replace(out.(string))
+	}
+
+	ru := modifiers.NewFuncMap(modifiers.Options{})
+	en := modifiers.NewFuncMap(modifiers.Options{Locale: "en-US"})
+	de := modifiers.NewFuncMap(modifiers.Options{Locale: "en", Data: map[string]any{"_locale": "de"}})
+	cases := []struct {
+		fm   map[string]any
+		name string
+		args []any
+		want string
+	}{
+		{ru, "money", []any{1234.5}, "1 234,50"},
+		{ru, "money", []any{"symbol", 1234.5}, "1 234,50~₽"},
+		{ru, "money", []any{-1234.5}, "-1 234,50"},
+		{ru, "money", []any{"int", 1234.99}, "1 234"},
+		{ru, "percent", []any{12.5}, "12,5~%"},
+		{ru, "percent", []any{"доля", 0.125}, "12,5~%"},
+		{ru, "unit", []any{"м²", 1250.5}, "1 250,5^м²"},
+		{en, "money", []any{1234567.891}, "1,234,567.89"},
+		{en, "money", []any{"symbol", -5}, "-$5.00"},
+		{en, "percent", []any{"1", 7}, "7.0%"},
+		{en, "unit", []any{"кг", "словами", 1.5}, "1.5^килограмма"},
+		{de, "money", []any{"symbol", 1234.5}, "1.234,50~€"},
+	}
+	for _, c := range cases {
+		if got := call(c.fm, c.name, c.args...); got != c.want {
+			t.Errorf("%s%v = %q, want %q", c.name, c.args, got, c.want)
+		}
+	}
+
+	modifiers.RegisterLocale("ch", modifiers.NumberLocale{Decimal: ".", Group: "’", Grouping: 3, Currency: "CHF", CurrencyBefore: true, CurrencySep: " "})
+	ch := modifiers.NewFuncMap(modifiers.Options{Data: map[string]any{"_locale": "de-CH"}})
+	if got := call(ch, "money", "symbol", 1234.5); got != "1.234,50~€" {
+		t.Errorf("de-CH must fall back to de: %q", got)
+	}
+	ch = modifiers.NewFuncMap(modifiers.Options{Data: map[string]any{"_locale": "CH"}})
+	if got := call(ch, "money", "symbol", 1234.5); got != "CHF 1’234.50" {
+		t.Errorf("registered locale: %q", got)
+	}
+}
+
+func TestDocLocale(t *testing.T) {
+	doc := openTemplate(t, "<w:p><w:r><w:t>{sum|money}</w:t></w:r></w:p>")
+	if err := doc.SetLocale("xx"); err == nil || !strings.Contains(err.Error(), "ru") {
+		t.Errorf("unknown locale must list the available ones: %v", err)
+	}
+	if err := doc.SetLocale("en"); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.ExecuteTemplate(map[string]any{"sum": 1234.5}); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := doc.ContentPart("document"); !strings.Contains(content, "1,234.50") {
+		t.Errorf("the locale of the document must be used:\n%s", content)
+	}
+}
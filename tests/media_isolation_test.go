@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func mediaFiles(t *testing.T, docx []byte) []string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(docx), int64(len(docx)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var media []string
+	for _, f := range zr.File {
+		if strings.HasPrefix(f.Name, "word/media/") {
+			media = append(media, f.Name)
+		}
+	}
+	return media
+}
+
+func TestMediaIsolation(t *testing.T) {
+	render := func(body string, data map[string]any) []byte {
+		doc := openTemplate(t, body)
+		if err := doc.ExecuteTemplate(data); err != nil {
+			t.Error(err)
+			return nil
+		}
+		var out bytes.Buffer
+		if err := doc.SaveToWriter(&out); err != nil {
+			t.Error(err)
+		}
+		return out.Bytes()
+	}
+
+	withQR := render("<w:p><w:r><w:t>{code|qrcode}</w:t></w:r></w:p>", map[string]any{"code": "A-1"})
+	if n := len(mediaFiles(t, withQR)); n != 1 {
+		t.Errorf("the QR document must have 1 image, got %d", n)
+	}
+	plain := render("<w:p><w:r><w:t>{name}</w:t></w:r></w:p>", map[string]any{"name": "Иван"})
+	if media := mediaFiles(t, plain); len(media) != 0 {
+		t.Errorf("images of another document leaked: %v", media)
+	}
+
+	// concurrent renders do not share media
+	var wg sync.WaitGroup
+	results := make([][]byte, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = render("<w:p><w:r><w:t>{code|qrcode}</w:t></w:r></w:p>", map[string]any{"code": strings.Repeat("x", i+1)})
+		}(i)
+	}
+	wg.Wait()
+	for i, r := range results {
+		if n := len(mediaFiles(t, r)); n != 1 {
+			t.Errorf("render %d: %d images, want 1", i, n)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"docxgen"
+)
+
+const (
+	mergeNumbering = `<w:numbering xmlns:w="w">` +
+		`<w:abstractNum w:abstractNumId="0"><w:nsid w:val="1A2B3C4D"/><w:lvl w:ilvl="0"><w:numFmt w:val="decimal"/></w:lvl></w:abstractNum>` +
+		`<w:num w:numId="1"><w:abstractNumId w:val="0"/></w:num></w:numbering>`
+	mergeStyles = `<w:styles xmlns:w="w">` +
+		`<w:style w:type="paragraph" w:styleId="Normal"><w:name w:val="Normal"/></w:style>%s</w:styles>`
+)
+
+func TestAppendDocument(t *testing.T) {
+	dir := t.TempDir()
+	writeDocx(t, filepath.Join(dir, "a.docx"), map[string]string{
+		"word/document.xml": `<w:document xmlns:w="w"><w:body>` +
+			`<w:p><w:pPr><w:numPr><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>Клиент А</w:t></w:r></w:p>` +
+			`<w:p><w:r><w:drawing><wp:inline><wp:docPr id="1" name="Stamp"/></wp:inline></w:drawing></w:r></w:p>` +
+			`<w:sectPr><w:pgSz w:w="11906" w:h="16838"/></w:sectPr></w:body></w:document>`,
+		"word/numbering.xml": mergeNumbering,
+		"word/styles.xml":    strings.Replace(mergeStyles, "%s", "", 1),
+	})
+	writeDocx(t, filepath.Join(dir, "b.docx"), map[string]string{
+		"word/document.xml": `<w:document xmlns:w="w" xmlns:w14="w14"><w:body>` +
+			`<w:p><w:pPr><w:pStyle w:val="Quote"/><w:numPr><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>Клиент Б</w:t></w:r></w:p>` +
+			`<w:p><w:r><w:drawing><wp:inline><wp:docPr id="1" name="Logo"/><a:blip r:embed="rId7"/></wp:inline></w:drawing></w:r></w:p>` +
+			`<w:sectPr><w:headerReference w:type="default" r:id="rId9"/><w:pgSz w:w="16838" w:h="11906"/></w:sectPr></w:body></w:document>`,
+		"word/_rels/document.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId7" Type="` + docxgen.RelTypeImage + `" Target="media/logo.png"/>` +
+			`<Relationship Id="rId9" Type="` + docxgen.RelTypeHeader + `" Target="header1.xml"/></Relationships>`,
+		"word/media/logo.png": "PNG",
+		"word/numbering.xml":  mergeNumbering,
+		"word/styles.xml": strings.Replace(mergeStyles, "%s",
+			`<w:style w:type="paragraph" w:styleId="Quote"><w:name w:val="Quote"/></w:style>`, 1),
+	})
+	a, err := docxgen.Open(filepath.Join(dir, "a.docx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := docxgen.Open(filepath.Join(dir, "b.docx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, _ := b.ContentPart("document")
+
+	if err := a.AppendDocument(b, true); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := b.ContentPart("document"); after != before {
+		t.Errorf("the appended document has changed")
+	}
+
+	xml, _ := a.ContentPart("document")
+	if !regexp.MustCompile(`Клиент А.*<w:br w:type="page"/>.*Клиент Б.*<w:sectPr><w:pgSz w:w="11906"`).MatchString(xml) {
+		t.Errorf("the body is not appended before the section of the document:\n%s", xml)
+	}
+	if strings.Count(xml, "<w:sectPr") != 1 || strings.Contains(xml, "headerReference") {
+		t.Errorf("the section of the appended document is carried over:\n%s", xml)
+	}
+	if !strings.Contains(xml, `xmlns:w14="w14"`) {
+		t.Errorf("the namespaces of the appended document are missing:\n%s", xml)
+	}
+	if ids := regexp.MustCompile(`<w:numId w:val="(\d+)"/>`).FindAllStringSubmatch(xml, -1); len(ids) != 2 || ids[1][1] != "2" {
+		t.Errorf("the appended list is not renumbered: %v", ids)
+	}
+	if strings.Count(xml, `<wp:docPr id="1"`) != 1 {
+		t.Errorf("the drawing keeps its id:\n%s", xml)
+	}
+
+	parts := zipParts(t, a)
+	numbering := parts["word/numbering.xml"]
+	for _, want := range []string{`w:abstractNumId="1"`, `<w:num w:numId="2"><w:abstractNumId w:val="1"/></w:num>`} {
+		if !strings.Contains(numbering, want) {
+			t.Errorf("numbering: no %s in\n%s", want, numbering)
+		}
+	}
+	if strings.Count(numbering, "<w:nsid") != 1 {
+		t.Errorf("the appended list must not continue the first one:\n%s", numbering)
+	}
+	if styles := parts["word/styles.xml"]; strings.Count(styles, `w:styleId="Normal"`) != 1 || !strings.Contains(styles, `w:styleId="Quote"`) {
+		t.Errorf("styles:\n%s", styles)
+	}
+	rid := regexp.MustCompile(`r:embed="([^"]+)"`).FindStringSubmatch(xml)
+	rels := parts["word/_rels/document.xml.rels"]
+	if rid == nil || !strings.Contains(rels, `Id="`+rid[1]+`"`) || strings.Contains(rels, "header1.xml") {
+		t.Fatalf("rels:\n%s", rels)
+	}
+	media := 0
+	for name, data := range parts {
+		if strings.HasPrefix(name, "word/media/") && data == "PNG" {
+			media++
+		}
+	}
+	if media != 1 {
+		t.Errorf("the picture of the appended document is missing")
+	}
+}
+
+func TestAppendDocumentDropsNotesAndComments(t *testing.T) {
+	dir := t.TempDir()
+	writeDocx(t, filepath.Join(dir, "a.docx"), map[string]string{
+		"word/document.xml": `<w:document xmlns:w="w"><w:body>` +
+			`<w:p><w:r><w:t>Договор</w:t></w:r><w:r><w:rPr><w:rStyle w:val="FootnoteReference"/></w:rPr><w:footnoteReference w:id="1"/></w:r></w:p>` +
+			`<w:sectPr/></w:body></w:document>`,
+		"word/footnotes.xml": `<w:footnotes xmlns:w="w"><w:footnote w:id="1"><w:p><w:r><w:t>Сноска А</w:t></w:r></w:p></w:footnote></w:footnotes>`,
+	})
+	writeDocx(t, filepath.Join(dir, "b.docx"), map[string]string{
+		"word/document.xml": `<w:document xmlns:w="w"><w:body>` +
+			`<w:p><w:commentRangeStart w:id="0"/><w:r><w:t>Акт</w:t></w:r><w:commentRangeEnd w:id="0"/><w:r><w:commentReference w:id="0"/></w:r>` +
+			`<w:r><w:rPr><w:rStyle w:val="FootnoteReference"/></w:rPr><w:footnoteReference w:id="1"/></w:r>` +
+			`<w:r><w:t>сверки</w:t><w:endnoteReference w:id="2"/></w:r></w:p>` +
+			`<w:sectPr/></w:body></w:document>`,
+		"word/footnotes.xml": `<w:footnotes xmlns:w="w"><w:footnote w:id="1"><w:p><w:r><w:t>Сноска Б</w:t></w:r></w:p></w:footnote></w:footnotes>`,
+		"word/comments.xml":  `<w:comments xmlns:w="w"><w:comment w:id="0"><w:p><w:r><w:t>проверить</w:t></w:r></w:p></w:comment></w:comments>`,
+	})
+	a, err := docxgen.Open(filepath.Join(dir, "a.docx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := docxgen.Open(filepath.Join(dir, "b.docx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AppendDocument(b, false); err != nil {
+		t.Fatal(err)
+	}
+	xml, _ := a.ContentPart("document")
+	if strings.Count(xml, "<w:footnoteReference") != 1 || strings.Contains(xml, "endnoteReference") || strings.Contains(xml, "<w:comment") {
+		t.Errorf("the notes and comments of the appended document are referenced:\n%s", xml)
+	}
+	if !strings.Contains(xml, "Акт") || !strings.Contains(xml, "<w:t>сверки</w:t></w:r>") {
+		t.Errorf("the text of the appended document is lost:\n%s", xml)
+	}
+	if strings.Count(xml, `<w:rStyle w:val="FootnoteReference"/>`) != 1 {
+		t.Errorf("the empty reference run is left:\n%s", xml)
+	}
+}
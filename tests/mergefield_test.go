@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"docxgen"
+)
+
+func TestMergeFields(t *testing.T) {
+	complex := func(instr, result string) string {
+		return `<w:r><w:fldChar w:fldCharType="begin"/></w:r>` +
+			`<w:r><w:instrText xml:space="preserve">` + instr + `</w:instrText></w:r>` +
+			`<w:r><w:fldChar w:fldCharType="separate"/></w:r>` +
+			`<w:r><w:rPr><w:b/></w:rPr><w:t>` + result + `</w:t></w:r>` +
+			`<w:r><w:fldChar w:fldCharType="end"/></w:r>`
+	}
+	doc := openTemplate(t,
+		`<w:p><w:r><w:t xml:space="preserve">Уважаемый </w:t></w:r>`+
+			`<w:fldSimple w:instr=" MERGEFIELD &quot;Имя клиента&quot; \* MERGEFORMAT "><w:r><w:t>«Имя_клиента»</w:t></w:r></w:fldSimple></w:p>`+
+			`<w:p>`+complex(` MERGEFIELD City \b "г. " `, "«City»")+`</w:p>`+
+			// the instruction split into several runs, as Word saves it
+			`<w:p><w:r><w:fldChar w:fldCharType="begin"/></w:r><w:r><w:instrText> MERGE</w:instrText></w:r>`+
+			`<w:r><w:instrText>FIELD Date \@ "dd.MM.yyyy" </w:instrText></w:r><w:r><w:fldChar w:fldCharType="end"/></w:r></w:p>`+
+			`<w:p>`+complex(` PAGE `, "1")+`</w:p>`+
+			`<w:p><w:r><w:fldChar w:fldCharType="begin"/></w:r><w:r><w:instrText> IF </w:instrText></w:r>`+
+			complex(` MERGEFIELD City `, "«City»")+
+			`<w:r><w:instrText> = "Тула" "да" "нет" </w:instrText></w:r><w:r><w:fldChar w:fldCharType="end"/></w:r></w:p>`)
+	err := doc.ExecuteTemplate(map[string]any{
+		"Имя_клиента": "Иван Петрович",
+		"City":        "Тула",
+		"Date":        time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	xml, _ := doc.ContentPart("document")
+	texts := paragraphTexts(xml)
+	want := []string{"Уважаемый Иван Петрович", "г. Тула", "31.12.2025", "1", "«City»"}
+	if strings.Join(texts, "|") != strings.Join(want, "|") {
+		t.Errorf("texts %q, want %q", texts, want)
+	}
+	if !strings.Contains(xml, `<w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">г. Тула`) {
+		t.Errorf("the format of the field result is lost:\n%s", xml)
+	}
+	if strings.Count(xml, "<w:instrText") != 4 || !strings.Contains(xml, "PAGE") {
+		t.Errorf("other fields must stay:\n%s", xml)
+	}
+
+	if got := docxgen.ConvertMergeFields(`<w:fldSimple w:instr=" MERGEFIELD Name \* Upper "/>`); got != `<w:r><w:t xml:space="preserve">{Name|caps}</w:t></w:r>` {
+		t.Errorf("switches: %s", got)
+	}
+}
+
+func TestMergeFieldNames(t *testing.T) {
+	doc := openTemplate(t,
+		`<w:p><w:fldSimple w:instr=" MERGEFIELD client-name "><w:r><w:t>«client-name»</w:t></w:r></w:fldSimple></w:p>`+
+			`<w:p><w:fldSimple w:instr=" MERGEFIELD &quot;Имя клиента&quot; \f &quot;!&quot; "><w:r><w:t>«Имя клиента»</w:t></w:r></w:fldSimple></w:p>`+
+			`<w:p><w:fldSimple w:instr=" MERGEFIELD 1st.line "><w:r><w:t>«1st.line»</w:t></w:r></w:fldSimple></w:p>`)
+	// the data comes with the column names of the mail merge source
+	data := map[string]any{"client-name": "ООО Ромашка", "Имя клиента": "Иван", "1st.line": "Москва"}
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatal(err)
+	}
+	xml, _ := doc.ContentPart("document")
+	want := []string{"ООО Ромашка", "Иван!", "Москва"}
+	if texts := paragraphTexts(xml); strings.Join(texts, "|") != strings.Join(want, "|") {
+		t.Errorf("texts %q, want %q", texts, want)
+	}
+	if len(data) != 3 {
+		t.Errorf("the data of the caller was changed: %v", data)
+	}
+
+	if got := docxgen.ConvertMergeFields(`<w:fldSimple w:instr=" MERGEFIELD &quot;Дата  договора&quot; "/>`); got != `<w:r><w:t xml:space="preserve">{Дата_договора}</w:t></w:r>` {
+		t.Errorf("name: %s", got)
+	}
+}
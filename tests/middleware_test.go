@@ -0,0 +1,38 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"docxgen"
+)
+
+func TestMiddlewareStages(t *testing.T) {
+	doc := openTemplate(t, `<w:p><w:r><w:t>[draft]{name}</w:t></w:r></w:p>`)
+
+	var calls []string
+	doc.Use(docxgen.StageBeforeIncludes, func(part, xml string) string {
+		calls = append(calls, "before:"+part)
+		return xml
+	})
+	doc.Use(docxgen.StageAfterTables, func(part, xml string) string {
+		calls = append(calls, "tables:"+part)
+		return strings.ReplaceAll(xml, "[draft]", "")
+	})
+	doc.Use(docxgen.StageAfterExecute, func(part, xml string) string {
+		calls = append(calls, "after:"+part)
+		return strings.ReplaceAll(xml, "Иванов", "Петров")
+	})
+
+	if err := doc.ExecuteTemplate(map[string]any{"name": "Иванов"}); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+
+	if got, want := strings.Join(calls, ","), "before:document,tables:document,after:document"; got != want {
+		t.Errorf("calls = %s, want %s", got, want)
+	}
+	out, _ := doc.ContentPart("document")
+	if strings.Contains(out, "[draft]") || !strings.Contains(out, "Петров") {
+		t.Errorf("middleware result not applied: %s", out)
+	}
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"docxgen/modifiers"
+)
+
+func TestModifierLimits(t *testing.T) {
+	slow := func(v string) string { time.Sleep(time.Second); return v }
+	huge := func(v string) string { return strings.Repeat("ж", 1000) }
+	raw := func(v string) modifiers.RawXML { return modifiers.RawXML(strings.Repeat("<w:br/>", 1000)) }
+
+	run := func(t *testing.T, limits modifiers.Limits, value string) (string, error) {
+		t.Helper()
+		doc := openTemplate(t, "<w:p><w:r><w:t>{v|slow}|{v|huge}|{v|raw}|{v|qrcode}</w:t></w:r></w:p>")
+		doc.SetModifierLimits(limits)
+		doc.ImportModifiers(map[string]modifiers.ModifierMeta{
+			"slow": {Func: slow},
+			"huge": {Func: huge},
+			"raw":  {Func: raw},
+		})
+		if err := doc.ExecuteTemplate(map[string]any{"v": value}); err != nil {
+			return "", err
+		}
+		content, _ := doc.ContentPart("document")
+		return content, nil
+	}
+
+	t.Run("error", func(t *testing.T) {
+		start := time.Now()
+		_, err := run(t, modifiers.Limits{Timeout: 20 * time.Millisecond}, "x")
+		if !errors.Is(err, modifiers.ErrModifierTimeout) || !strings.Contains(err.Error(), "slow") {
+			t.Errorf("expected a timeout of slow: %v", err)
+		}
+		if time.Since(start) > 500*time.Millisecond {
+			t.Error("the template waited for the modifier")
+		}
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		content, err := run(t, modifiers.Limits{Timeout: 20 * time.Millisecond, MaxOutput: 11, Policy: modifiers.LimitTruncate}, "x")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the slow modifier gives its input, the text is cut on a rune boundary, raw XML is dropped
+		if !strings.Contains(content, "x|жжжжж||") {
+			t.Errorf("unexpected output:\n%s", content)
+		}
+		if !strings.Contains(content, "<wp:") {
+			t.Error("built-in drawings must not be limited")
+		}
+	})
+
+	t.Run("with an error result", func(t *testing.T) {
+		mods := map[string]modifiers.ModifierMeta{
+			"huge": {Func: func(v string) (string, error) { return strings.Repeat("ж", 1000), nil }},
+			"raw":  {Func: func(v string) (modifiers.RawXML, error) { return "<w:r><w:t>open", nil }},
+			"fail": {Func: func(v string) (string, error) { return "", errors.New("boom") }},
+		}
+		exec := func(tag string, limits modifiers.Limits) (string, error) {
+			doc := openTemplate(t, "<w:p><w:r><w:t>"+tag+"</w:t></w:r></w:p>")
+			doc.SetModifierLimits(limits)
+			doc.ImportModifiers(mods)
+			if err := doc.ExecuteTemplate(map[string]any{"v": "x"}); err != nil {
+				return "", err
+			}
+			content, _ := doc.ContentPart("document")
+			return content, nil
+		}
+
+		if _, err := exec("{v|huge}", modifiers.Limits{MaxOutput: 10}); !errors.Is(err, modifiers.ErrModifierOutput) {
+			t.Errorf("MaxOutput of (string, error): %v", err)
+		}
+		if _, err := exec("{v|raw}", modifiers.Limits{ValidateXML: true}); err == nil {
+			t.Error("ValidateXML of (RawXML, error) let the broken XML through")
+		}
+		content, err := exec("<{v|huge}>", modifiers.Limits{MaxOutput: 4, Policy: modifiers.LimitTruncate})
+		if err != nil || !strings.Contains(content, "<жж>") {
+			t.Errorf("truncated (string, error): %v\n%s", err, content)
+		}
+		if _, err := exec("{v|fail}", modifiers.Limits{MaxOutput: 10}); err == nil || !strings.Contains(err.Error(), "boom") {
+			t.Errorf("the error of the modifier is lost: %v", err)
+		}
+	})
+
+	t.Run("per modifier", func(t *testing.T) {
+		doc := openTemplate(t, "<w:p><w:r><w:t>{v|huge}</w:t></w:r></w:p>")
+		doc.SetModifierLimits(modifiers.Limits{MaxOutput: 10})
+		doc.ImportModifiers(map[string]modifiers.ModifierMeta{"huge": {Func: huge, Limits: &modifiers.Limits{}}})
+		if err := doc.ExecuteTemplate(map[string]any{"v": "x"}); err != nil {
+			t.Errorf("own limits of the modifier must win: %v", err)
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"docxgen"
+	"docxgen/docxtest"
+)
+
+func TestExecuteTemplateInNotes(t *testing.T) {
+	doc := docxtest.New().
+		Paragraph("Договор № {number}").
+		File("word/footnotes.xml", `<w:footnotes><w:footnote w:id="1"><w:p><w:r><w:t>Редакция от {date}</w:t></w:r></w:p></w:footnote></w:footnotes>`).
+		File("word/endnotes.xml", "<w:endnotes><w:endnote w:id=\"1\"><w:p><w:r><w:t>{client|prefix:`ООО `}</w:t></w:r></w:p></w:endnote></w:endnotes>").
+		Open(t)
+	doc.ImportBuiltins()
+	if err := doc.AddComment("Договор", "Юрист", "Проверить {client}"); err != nil {
+		t.Fatal(err)
+	}
+
+	data := map[string]any{"number": 15, "date": "01.02.2025", "client": "Ромашка"}
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"footnotes": "Редакция от 01.02.2025", "endnotes": "ООО Ромашка", "comments": "Проверить Ромашка"}
+	for part, text := range want {
+		xml, err := doc.ContentPart(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(paragraphTexts(xml), "|"); got != text {
+			t.Errorf("%s: %q, want %q", part, got, text)
+		}
+	}
+}
+
+func TestMediaInNotes(t *testing.T) {
+	doc := docxtest.New().
+		Paragraph("Договор").
+		File("word/footnotes.xml", `<w:footnotes><w:footnote w:id="1"><w:p><w:r><w:t>{code|qrcode}</w:t></w:r></w:p></w:footnote></w:footnotes>`).
+		Open(t)
+	doc.ImportBuiltins()
+	if err := doc.ExecuteTemplate(map[string]any{"code": "A-1"}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := doc.SaveToWriter(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := docxgen.OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes, _ := saved.GetFile("word/footnotes.xml")
+	m := regexp.MustCompile(`r:embed="([^"]+)"`).FindStringSubmatch(string(notes))
+	if m == nil {
+		t.Fatalf("no picture in the footnote: %s", notes)
+	}
+	rels, _ := saved.GetFile("word/_rels/footnotes.xml.rels")
+	target := regexp.MustCompile(`Id="` + regexp.QuoteMeta(m[1]) + `"[^>]*Target="([^"]+)"`).FindStringSubmatch(string(rels))
+	if target == nil {
+		t.Fatalf("no relationship %s of the footnotes: %s", m[1], rels)
+	}
+	if _, ok := saved.GetFile("word/" + target[1]); !ok {
+		t.Errorf("the picture %s is not saved", target[1])
+	}
+	if body, _ := saved.GetFile("word/_rels/document.xml.rels"); strings.Contains(string(body), m[1]) {
+		t.Errorf("the picture of the footnote is linked to the body: %s", body)
+	}
+}
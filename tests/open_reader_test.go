@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"docxgen"
+)
+
+func TestOpenBytes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "template.docx")
+	writeDocx(t, path, map[string]string{
+		"word/document.xml": `<w:document><w:body>` +
+			`<w:p><w:r><w:t>{na</w:t></w:r><w:r><w:t>me}</w:t></w:r></w:p>` +
+			`<w:p><w:r><w:t>[include/part.docx]</w:t></w:r></w:p>` +
+			`</w:body></w:document>`,
+	})
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := docxgen.OpenBytes(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.ExecuteTemplate(map[string]any{"name": "Иван"}); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := doc.ContentPart("document")
+	if !strings.Contains(content, "Иван") {
+		t.Errorf("a tag split across runs must be repaired like in Open:\n%s", content)
+	}
+	if strings.Contains(content, "[include/") {
+		t.Error("an include of a template without a directory must be dropped")
+	}
+
+	var out bytes.Buffer
+	if err := doc.SaveToWriter(&out); err != nil {
+		t.Fatal(err)
+	}
+	again, err := docxgen.OpenReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := again.ContentPart("document"); !strings.Contains(content, "Иван") {
+		t.Error("the saved document must open from a reader")
+	}
+
+	if _, err := docxgen.OpenBytes([]byte("not a zip")); err == nil {
+		t.Error("expected an error for a broken archive")
+	}
+}
@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"docxgen"
+)
+
+var orgData = map[string]any{
+	"staff": map[string]any{
+		"name": "Иванов И. И.", "position": "директор",
+		"reports": []any{
+			map[string]any{"name": "Петров П. П.", "position": "заместитель", "reports": []any{
+				map[string]any{"name": "Сидорова А. А.", "position": "бухгалтер"},
+				map[string]any{"name": "Кузнецов К. К.", "position": "юрист"},
+			}},
+			map[string]any{"name": "Смирнова Е. Е.", "position": "секретарь"},
+		},
+	},
+}
+
+func TestOrgChartList(t *testing.T) {
+	doc := openTemplate(t, "<w:p><w:r><w:t>[orgchart/staff note:position children:reports]</w:t></w:r></w:p>")
+	res, err := doc.ExecuteTemplateResult(orgData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Warnings) != 0 {
+		t.Errorf("warnings: %v", res.Warnings)
+	}
+	xml, _ := doc.ContentPart("document")
+	texts := strings.Join(paragraphTexts(xml), "|")
+	want := "Иванов И. И., директор|— Петров П. П., заместитель|— Сидорова А. А., бухгалтер|" +
+		"— Кузнецов К. К., юрист|— Смирнова Е. Е., секретарь"
+	if texts != want {
+		t.Errorf("got %q, want %q", texts, want)
+	}
+	indents := regexp.MustCompile(`<w:ind w:left="(\d+)"/>`).FindAllStringSubmatch(xml, -1)
+	var got []string
+	for _, m := range indents {
+		got = append(got, m[1])
+	}
+	if strings.Join(got, " ") != "0 360 720 720 360" {
+		t.Errorf("indents: %v", got)
+	}
+}
+
+func TestOrgChartBoxes(t *testing.T) {
+	doc := openTemplate(t, "<w:p><w:r><w:t>[orgchart/staff children:reports mode:boxes]</w:t></w:r></w:p>"+
+		"<w:p><w:r><w:t>[orgchart/nobody]</w:t></w:r></w:p>")
+	res, err := doc.ExecuteTemplateResult(orgData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Warnings) != 1 || res.Warnings[0].Kind != docxgen.WarnBadOrgChart {
+		t.Errorf("warnings: %v", res.Warnings)
+	}
+	xml, _ := doc.ContentPart("document")
+
+	// 3 leaves — 3 columns; the director over 3, the deputy over 2, the secretary keeps an empty cell below
+	rows := strings.Split(xml, "<w:tr>")[1:]
+	if len(rows) != 3 || strings.Count(xml, "<w:gridCol ") != 3 {
+		t.Fatalf("want 3 levels of 3 columns: %s", xml)
+	}
+	spans := func(row string) string {
+		var s []string
+		for _, c := range strings.Split(row, "<w:tc>")[1:] {
+			span := "1"
+			if m := regexp.MustCompile(`<w:gridSpan w:val="(\d+)"/>`).FindStringSubmatch(c); m != nil {
+				span = m[1]
+			}
+			if !strings.Contains(c, "w:fill=") {
+				span = "_" + span
+			}
+			s = append(s, span)
+		}
+		return strings.Join(s, " ")
+	}
+	for i, want := range []string{"3", "2 1", "1 1 _1"} {
+		if got := spans(rows[i]); got != want {
+			t.Errorf("level %d: spans %q, want %q", i, got, want)
+		}
+	}
+	if texts := strings.Join(paragraphTexts(xml), "|"); !strings.HasPrefix(texts, "Иванов И. И.|Петров П. П.|Смирнова Е. Е.|Сидорова") {
+		t.Errorf("texts: %s", texts)
+	}
+}
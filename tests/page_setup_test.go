@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"docxgen"
+	"docxgen/docxtest"
+	"docxgen/geometry"
+)
+
+// twipsLayout - the page layout in twips, as sectPr holds it: "11906×16838 1134/850/1134/1134".
+func twipsLayout(p docxgen.PageLayout) string {
+	return fmt.Sprintf("%d×%d %d/%d/%d/%d", p.Width.Twips(), p.Height.Twips(),
+		p.Margins.Top.Twips(), p.Margins.Right.Twips(), p.Margins.Bottom.Twips(), p.Margins.Left.Twips())
+}
+
+func TestSetSection(t *testing.T) {
+	doc := docxtest.New().Paragraph("Договор").Open(t)
+	margins := geometry.Sides{Top: 20 * geometry.MM, Right: 10 * geometry.MM, Bottom: 20 * geometry.MM, Left: 30 * geometry.MM}
+	if err := doc.SetSection(docxgen.SectionOptions{Paper: "A5", Orientation: docxgen.Landscape, Margins: &margins}); err != nil {
+		t.Fatal(err)
+	}
+	sections := doc.Sections()
+	if len(sections) != 1 {
+		t.Fatalf("sections: %v", sections)
+	}
+	if got := twipsLayout(sections[0]); got != "11906×8391 1134/567/1134/1701" {
+		t.Errorf("layout = %s", got)
+	}
+	body, _ := doc.ContentPart("document")
+	if !strings.Contains(body, `w:orient="landscape"`) {
+		t.Errorf("no orientation:\n%s", body)
+	}
+
+	if err := doc.SetSection(docxgen.SectionOptions{Orientation: docxgen.Portrait}); err != nil {
+		t.Fatal(err)
+	}
+	if got := twipsLayout(doc.Sections()[0]); got != "8391×11906 1134/567/1134/1701" {
+		t.Errorf("portrait layout = %s", got)
+	}
+	if err := doc.SetSection(docxgen.SectionOptions{Paper: "B7"}); err == nil {
+		t.Error("an unknown paper must be an error")
+	}
+}
+
+func TestSectionMarker(t *testing.T) {
+	doc := docxtest.New().
+		Paragraph("Договор {number}").
+		Paragraph("[section %orient% margins=10]").
+		Table([]string{"{a}", "{b}"}).
+		Paragraph("[/section]").
+		Paragraph("Подписи").
+		Open(t)
+	res, err := doc.ExecuteTemplateResult(map[string]any{"number": "7", "a": "1", "b": "2", "orient": "landscape"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Warnings) > 0 {
+		t.Errorf("warnings: %v", res.Warnings)
+	}
+
+	var got []string
+	for _, s := range doc.Sections() {
+		got = append(got, twipsLayout(s))
+	}
+	want := []string{
+		"11906×16838 1134/850/1134/1134",
+		"16838×11906 567/567/567/567",
+		"11906×16838 1134/850/1134/1134",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("sections:\n got:  %v\n want: %v", got, want)
+	}
+	body, _ := doc.ContentPart("document")
+	if got := strings.Join(paragraphTexts(body), "|"); !strings.Contains(got, "Договор 7") || strings.Contains(got, "section") {
+		t.Errorf("texts: %s", got)
+	}
+
+	doc = docxtest.New().Paragraph("[section A7]").Paragraph("текст").Paragraph("[/section]").Paragraph("[section]").Open(t)
+	res, err = doc.ExecuteTemplateResult(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Warnings) != 2 || res.Warnings[0].Kind != docxgen.WarnBadSection || len(doc.Sections()) != 1 {
+		t.Errorf("warnings %v, sections %v", res.Warnings, doc.Sections())
+	}
+}
@@ -0,0 +1,221 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"docxgen/pdf"
+)
+
+// fakeEngine puts an executable "soffice" into PATH that writes a stub PDF into --outdir.
+func fakeEngine(t *testing.T, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script engine")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "soffice"), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestPDFEngineOrder(t *testing.T) {
+	c := pdf.New(pdf.Options{Preferred: "lowriter"})
+	want := []string{"lowriter", "soffice", "libreoffice", "unoconv"}
+	if got := c.Engines(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Engines() = %v, want %v", got, want)
+	}
+}
+
+func TestPDFConvert(t *testing.T) {
+	fakeEngine(t, `while [ "$1" != "--outdir" ]; do shift; done
+printf '%%PDF-stub' > "$2/document.pdf"
+`)
+	scratch := t.TempDir()
+	c := pdf.New(pdf.Options{Engines: []string{"soffice"}, TempDir: scratch})
+
+	out, err := c.Convert(context.Background(), []byte("docx"))
+	if err != nil {
+		t.Fatalf("convert: %v", err)
+	}
+	if string(out) != "%PDF-stub" {
+		t.Errorf("out = %q", out)
+	}
+	if left, _ := os.ReadDir(scratch); len(left) != 0 {
+		t.Errorf("scratch dir not cleaned: %d entries", len(left))
+	}
+}
+
+func TestPDFEngineStaysInScratch(t *testing.T) {
+	// the engine reports where its home and temporary files are
+	fakeEngine(t, `while [ "$1" != "--outdir" ]; do shift; done
+printf '%s|%s|%s' "$HOME" "$TMPDIR" "$XDG_CACHE_HOME" > "$2/document.pdf"
+`)
+	scratch := t.TempDir()
+	out, err := pdf.New(pdf.Options{Engines: []string{"soffice"}, TempDir: scratch}).Convert(context.Background(), []byte("docx"))
+	if err != nil {
+		t.Fatalf("convert: %v", err)
+	}
+	for _, dir := range strings.Split(string(out), "|") {
+		if !strings.HasPrefix(dir, scratch+string(os.PathSeparator)) {
+			t.Errorf("the engine writes to %q, out of the scratch dir %s", dir, scratch)
+		}
+	}
+}
+
+func TestPDFNoEngine(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	_, err := pdf.New(pdf.Options{}).Convert(context.Background(), []byte("docx"))
+	if !errors.Is(err, pdf.ErrNoEngine) {
+		t.Errorf("err = %v, want ErrNoEngine", err)
+	}
+}
+
+func TestPDFIsolatedProfilesAndParallelism(t *testing.T) {
+	state := t.TempDir()
+	t.Setenv("FAKE_STATE", state)
+	fakeEngine(t, `case "$1" in -env:UserInstallation=file:///*) ;; *) exit 1 ;; esac
+echo "$1" >> "$FAKE_STATE/profiles"
+mkdir "$FAKE_STATE/run.$$"
+ls -d "$FAKE_STATE"/run.* | wc -l >> "$FAKE_STATE/running"
+sleep 0.2
+rmdir "$FAKE_STATE/run.$$"
+while [ "$1" != "--outdir" ]; do shift; done
+printf '%%PDF-stub' > "$2/document.pdf"
+`)
+	c := pdf.New(pdf.Options{Engines: []string{"soffice"}, MaxParallel: 2})
+
+	const n = 5
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := c.Convert(context.Background(), []byte("docx"))
+			errs <- err
+		}()
+	}
+	for i := 0; i < n; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("convert: %v", err)
+		}
+	}
+
+	profiles, _ := os.ReadFile(filepath.Join(state, "profiles"))
+	seen := map[string]bool{}
+	for _, p := range strings.Fields(string(profiles)) {
+		seen[p] = true
+	}
+	if len(seen) != n {
+		t.Errorf("profiles: %d unique of %d runs", len(seen), n)
+	}
+
+	running, _ := os.ReadFile(filepath.Join(state, "running"))
+	for _, v := range strings.Fields(string(running)) {
+		if v != "1" && v != "2" {
+			t.Errorf("%s conversions ran at once, MaxParallel is 2", v)
+		}
+	}
+}
+
+func TestPDFQueueRespectsContext(t *testing.T) {
+	fakeEngine(t, "sleep 1\n")
+	c := pdf.New(pdf.Options{Engines: []string{"soffice"}, MaxParallel: 1})
+
+	go func() { _, _ = c.Convert(context.Background(), []byte("docx")) }()
+	time.Sleep(100 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.Convert(ctx, []byte("docx")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("queued convert: err = %v, want deadline exceeded", err)
+	}
+}
+
+func TestPDFTimeoutKillsEngineTree(t *testing.T) {
+	state := t.TempDir()
+	t.Setenv("FAKE_STATE", state)
+	// soffice is a wrapper: the real converter is its child, which holds the output open
+	fakeEngine(t, `(sleep 1; touch "$FAKE_STATE/alive") &
+wait
+`)
+	start := time.Now()
+	_, err := pdf.New(pdf.Options{Engines: []string{"soffice"}, Timeout: 100 * time.Millisecond}).Convert(context.Background(), []byte("docx"))
+	if err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("err = %v, want a timeout", err)
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("the timeout waited for the child of the engine: %s", took)
+	}
+	time.Sleep(1500 * time.Millisecond)
+	if _, err := os.Stat(filepath.Join(state, "alive")); err == nil {
+		t.Error("the child of the engine outlived the timeout")
+	}
+}
+
+func TestPDFRetryAndFailureReport(t *testing.T) {
+	state := t.TempDir()
+	t.Setenv("FAKE_STATE", state)
+	// the first run fails, the next ones succeed
+	fakeEngine(t, `if [ ! -f "$FAKE_STATE/failed" ]; then
+  touch "$FAKE_STATE/failed"
+  echo "Error: source file could not be loaded" >&2
+  exit 1
+fi
+while [ "$1" != "--outdir" ]; do shift; done
+printf '%%PDF-stub' > "$2/document.pdf"
+`)
+
+	_, err := pdf.New(pdf.Options{Engines: []string{"soffice"}}).Convert(context.Background(), []byte("docx"))
+	var ce *pdf.ConversionError
+	if !errors.As(err, &ce) || len(ce.Attempts) != 1 || ce.Attempts[0].Engine != "soffice" {
+		t.Fatalf("err = %v, want ConversionError with one soffice attempt", err)
+	}
+	if !strings.Contains(err.Error(), "source file could not be loaded") {
+		t.Errorf("the cause is lost: %v", err)
+	}
+
+	_ = os.Remove(filepath.Join(state, "failed"))
+	var logged []string
+	c := pdf.New(pdf.Options{
+		Engines: []string{"soffice"},
+		Retries: 1,
+		Backoff: 10 * time.Millisecond,
+		Log:     func(format string, a ...any) { logged = append(logged, format) },
+	})
+	if _, err := c.Convert(context.Background(), []byte("docx")); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if len(logged) != 3 { // attempt, failure, attempt
+		t.Errorf("logged %d messages, want 3", len(logged))
+	}
+}
+
+func TestRasterizerPageOrder(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script engine")
+	}
+	dir := t.TempDir()
+	// pdftoppm names the pages prefix-N.png; write 11 pages out of order to check the numeric sort
+	script := `#!/bin/sh
+for n in 10 2 1 11 3 4 5 6 7 8 9; do printf "$n" > "$5-$n.png"; done
+`
+	if err := os.WriteFile(filepath.Join(dir, "pdftoppm"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	pages, err := pdf.NewRasterizer(pdf.RasterOptions{Engines: []string{"pdftoppm"}}).Pages(context.Background(), []byte("%PDF"))
+	if err != nil {
+		t.Fatalf("pages: %v", err)
+	}
+	if len(pages) != 11 || string(pages[0]) != "1" || string(pages[1]) != "2" || string(pages[10]) != "11" {
+		t.Errorf("unexpected page order: %q", pages)
+	}
+}
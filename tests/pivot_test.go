@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"docxgen"
+)
+
+func TestPivotTable(t *testing.T) {
+	sales := []any{
+		map[string]any{"region": "Север", "month": "Янв", "amount": 100.1},
+		map[string]any{"region": "Юг", "month": "Янв", "amount": 50},
+		map[string]any{"region": "Север", "month": "Фев", "amount": "0,2"},
+		map[string]any{"region": "Север", "month": "Янв", "amount": 10},
+		map[string]any{"region": "Юг", "month": "Мар", "amount": "н/д"},
+	}
+	run := func(marker string) (string, docxgen.RenderResult) {
+		t.Helper()
+		doc := openTemplate(t, "<w:p><w:r><w:t>"+marker+"</w:t></w:r></w:p>")
+		res, err := doc.ExecuteTemplateResult(map[string]any{"sales": sales})
+		if err != nil {
+			t.Fatal(err)
+		}
+		xml, _ := doc.ContentPart("document")
+		return xml, res
+	}
+
+	xml, res := run("[pivot/sales rows:region cols:month value:sum(amount)]")
+	if len(res.Warnings) != 0 {
+		t.Errorf("warnings: %v", res.Warnings)
+	}
+	if strings.Count(xml, "<w:tr>") != 4 || strings.Count(xml, "<w:tc>") != 20 {
+		t.Errorf("want 4 rows of 5 cells: %s", xml)
+	}
+	// the cross-tab row by row: a record without a number only makes its column
+	got := strings.Join(paragraphTexts(xml), "|")
+	want := "region|Янв|Фев|Мар|Итого|Север|110.1|0.2|110.3|Юг|50|50|Итого|160.1|0.2|160.3"
+	if got != want {
+		t.Errorf("sum:\n got  %s\n want %s", got, want)
+	}
+
+	xml, _ = run("[pivot/sales rows:region value:count total:Всего]")
+	if got, want := strings.Join(paragraphTexts(xml), "|"), "region|count|Север|3|Юг|2|Всего|5"; got != want {
+		t.Errorf("count:\n got  %s\n want %s", got, want)
+	}
+
+	xml, _ = run("[pivot/sales rows:month cols:region value:avg(amount) total:none format:money]")
+	if got, want := strings.Join(paragraphTexts(xml), "|"), "month|Север|Юг|Янв|55,05|50,00|Фев|0,20|Мар"; got != want {
+		t.Errorf("avg with money:\n got  %s\n want %s", got, want)
+	}
+
+	xml, res = run("[pivot/sales rows:region value:median(amount)]")
+	if len(res.Warnings) != 1 || res.Warnings[0].Kind != docxgen.WarnEmptyPivot || strings.Contains(xml, "pivot") {
+		t.Errorf("bad aggregate: %v %s", res.Warnings, xml)
+	}
+}
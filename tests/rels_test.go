@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"docxgen"
+)
+
+const testImageRel = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/image"
+
+func TestRelationshipManager(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "rels.docx")
+	writeDocx(t, tmp, map[string]string{
+		"word/document.xml": `<w:document><w:body><w:p/></w:body></w:document>`,
+		"word/_rels/document.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+			`<Relationship Id="rId7" Type="` + testImageRel + `" Target="media/a.png"/>` +
+			`</Relationships>`,
+	})
+	doc, err := docxgen.Open(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rels := doc.Rels()
+
+	if r, ok := rels.Lookup("document", "rId7"); !ok || r.Target != "media/a.png" {
+		t.Errorf("Lookup(rId7) = %+v, %v", r, ok)
+	}
+	if got := rels.ResolveTarget("document", docxgen.Relationship{Target: "media/a.png"}); got != "word/media/a.png" {
+		t.Errorf("ResolveTarget = %q", got)
+	}
+
+	// a new id continues after the largest one, the same target is reused
+	if id := rels.AddImage("document", "media/b.png"); id != "rId8" {
+		t.Errorf("AddImage = %q, want rId8", id)
+	}
+	if id := rels.AddImage("document", "media/a.png"); id != "rId7" {
+		t.Errorf("AddImage of an existing target = %q, want rId7", id)
+	}
+	link := rels.AddHyperlink("header1", "https://example.com")
+	if r, ok := rels.Lookup("header1", link); !ok || !r.External() {
+		t.Errorf("hyperlink in a part without .rels: %+v, %v", r, ok)
+	}
+
+	if !rels.Remove("document", "rId8") || rels.Remove("document", "rId8") {
+		t.Error("Remove must remove rId8 exactly once")
+	}
+
+	rels.Rewrite("document", func(r docxgen.Relationship) (docxgen.Relationship, bool) {
+		r.Target = strings.Replace(r.Target, "a.png", "c.png", 1)
+		return r, true
+	})
+	if r, _ := rels.Lookup("document", "rId7"); r.Target != "media/c.png" {
+		t.Errorf("Rewrite did not change the target: %+v", r)
+	}
+	if n := len(rels.List("document")); n != 2 {
+		t.Errorf("expected 2 relationships, got %d", n)
+	}
+}
+
+func TestIncludeCarriesImages(t *testing.T) {
+	dir := t.TempDir()
+	writeDocx(t, filepath.Join(dir, "logo.docx"), map[string]string{
+		"word/document.xml": `<w:document><w:body><w:p><w:r><w:drawing><a:blip r:embed="rId5"/></w:drawing></w:r></w:p></w:body></w:document>`,
+		"word/_rels/document.xml.rels": `<Relationships>` +
+			`<Relationship Id="rId5" Type="` + testImageRel + `" Target="media/image1.png"/></Relationships>`,
+		"word/media/image1.png": "PNGDATA",
+	})
+	main := filepath.Join(dir, "main.docx")
+	writeDocx(t, main, map[string]string{
+		"word/document.xml": `<w:document><w:body><w:p><w:r><w:t>[include/logo.docx]</w:t></w:r></w:p></w:body></w:document>`,
+		"word/_rels/document.xml.rels": `<Relationships>` +
+			`<Relationship Id="rId5" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`,
+	})
+
+	doc, err := docxgen.Open(main)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.ExecuteTemplate(map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := doc.ContentPart("document")
+
+	m := regexp.MustCompile(`r:embed="([^"]+)"`).FindStringSubmatch(content)
+	if m == nil {
+		t.Fatalf("fragment not included:\n%s", content)
+	}
+	id := m[1]
+	if id == "rId5" {
+		t.Fatalf("r:id of the fragment collides with the template one:\n%s", content)
+	}
+	r, ok := doc.Rels().Lookup("document", id)
+	if !ok || r.Type != testImageRel {
+		t.Fatalf("included image relationship not found: %q", id)
+	}
+	if data, ok := doc.GetFile(doc.Rels().ResolveTarget("document", r)); !ok || string(data) != "PNGDATA" {
+		t.Errorf("included media not copied: %q", r.Target)
+	}
+}
@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"docxgen"
+	"docxgen/docxtest"
+)
+
+// fakeRenderer - a DocumentRenderer of a service test: records the data, writes a fixed output.
+type fakeRenderer struct {
+	data map[string]any
+	err  error
+}
+
+func (f *fakeRenderer) ExecuteTemplate(data map[string]any) error { f.data = data; return f.err }
+func (f *fakeRenderer) ContentPart(string) (string, error)        { return "", nil }
+func (f *fakeRenderer) Save(string) error                         { return nil }
+func (f *fakeRenderer) SaveToWriter(w io.Writer) error {
+	_, err := io.WriteString(w, "docx")
+	return err
+}
+
+// renderContract - a service function that depends on the interfaces only.
+func renderContract(open docxgen.RendererOpener, w io.Writer, data map[string]any) error {
+	doc, err := open("contract.docx")
+	if err != nil {
+		return err
+	}
+	if err := doc.ExecuteTemplate(data); err != nil {
+		return err
+	}
+	return doc.SaveToWriter(w)
+}
+
+func TestDocumentRendererFake(t *testing.T) {
+	fake := &fakeRenderer{}
+	open := func(path string) (docxgen.DocumentRenderer, error) { return fake, nil }
+
+	var out bytes.Buffer
+	if err := renderContract(open, &out, map[string]any{"number": 7}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "docx" || fake.data["number"] != 7 {
+		t.Errorf("output %q, data %v", out.String(), fake.data)
+	}
+
+	fake.err = errors.New("bad template")
+	if err := renderContract(open, &out, nil); err == nil || err.Error() != "bad template" {
+		t.Errorf("the error of the renderer must come through: %v", err)
+	}
+}
+
+func TestOpenRenderer(t *testing.T) {
+	dir := t.TempDir()
+	docxtest.New().Paragraph("Договор № {number}").WriteFile(t, filepath.Join(dir, "contract.docx"))
+
+	open := func(path string) (docxgen.DocumentRenderer, error) {
+		return docxgen.OpenRenderer(filepath.Join(dir, path))
+	}
+	var out bytes.Buffer
+	if err := renderContract(open, &out, map[string]any{"number": 7}); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := docxgen.OpenBytes(out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := doc.ContentPart("document"); !strings.Contains(body, "Договор № 7") {
+		t.Errorf("rendered: %s", body)
+	}
+
+	if r, err := docxgen.OpenRenderer(filepath.Join(dir, "missing.docx")); err == nil || r != nil {
+		t.Errorf("a missing template must give a nil renderer and an error: %v, %v", r, err)
+	}
+}
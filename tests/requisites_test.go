@@ -0,0 +1,36 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"docxgen"
+)
+
+func TestRequisitesBlock(t *testing.T) {
+	doc := openTemplate(t, "<w:p><w:r><w:t>[requisites/customer:contractor titles:Заказчик,Исполнитель]</w:t></w:r></w:p>"+
+		"<w:p><w:r><w:t>[requisites/customer:nobody]</w:t></w:r></w:p><w:p><w:r><w:t>[requisites/customer]</w:t></w:r></w:p>")
+	res, err := doc.ExecuteTemplateResult(map[string]any{
+		"customer": map[string]any{
+			"name": "ООО «Ромашка»", "inn": "7701234567", "kpp": "", "bank": "ПАО Сбербанк", "bik": "044525225",
+			"signer": "Иванов И. И.", "position": "Генеральный директор",
+		},
+		"contractor": map[string]any{"role": "Подрядчик", "name": "ИП Петров П. П.", "ogrnip": 304500116000157, "email": "p@example.ru"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Warnings) != 2 || res.Warnings[0].Kind != docxgen.WarnBadRequisites || res.Warnings[1].Kind != docxgen.WarnBadRequisites {
+		t.Errorf("warnings: %v", res.Warnings)
+	}
+	xml, _ := doc.ContentPart("document")
+	if strings.Count(xml, "<w:tbl>") != 2 || strings.Contains(xml, "[requisites/") {
+		t.Fatalf("tables:\n%s", xml)
+	}
+	first := xml[:strings.Index(xml, "</w:tbl>")]
+	want := `Заказчик|ООО «Ромашка»|ИНН 7701234567|в ПАО Сбербанк|БИК 044525225|Генеральный директор|` +
+		`____________ / Иванов И. И. /|М.П.|Подрядчик|ИП Петров П. П.|ОГРНИП 304500116000157|E-mail: p@example.ru`
+	if got := strings.Join(paragraphTexts(first), "|"); got != want {
+		t.Errorf("texts:\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"docxgen"
+	"docxgen/docxtest"
+)
+
+// trackedBody - a template edited with the tracking on: the tag is cut by an insertion, a deletion
+// and a formatting change, a row and a paragraph mark are deleted.
+const trackedBody = `<w:p><w:r><w:t xml:space="preserve">Клиент: {</w:t></w:r>` +
+	`<w:del w:id="1" w:author="A"><w:r><w:delText>fio</w:delText></w:r></w:del>` +
+	`<w:ins w:id="2" w:author="A"><w:r><w:t>name</w:t></w:r></w:ins>` +
+	`<w:r><w:t>}</w:t></w:r></w:p>` +
+	`<w:p><w:r><w:rPr><w:b/><w:rPrChange w:id="3" w:author="A"><w:rPr><w:i/></w:rPr></w:rPrChange></w:rPr><w:t>Итог</w:t></w:r></w:p>` +
+	`<w:p><w:pPr><w:rPr><w:del w:id="4" w:author="A"/></w:rPr></w:pPr><w:r><w:t xml:space="preserve">Первая </w:t></w:r></w:p>` +
+	`<w:p><w:pPr><w:jc w:val="center"/></w:pPr><w:r><w:t>вторая</w:t></w:r></w:p>` +
+	`<w:tbl><w:tr><w:tc><w:p><w:r><w:t>A</w:t></w:r></w:p></w:tc></w:tr>` +
+	`<w:tr><w:trPr><w:del w:id="5" w:author="A"/></w:trPr><w:tc><w:p><w:r><w:t>B</w:t></w:r></w:p></w:tc></w:tr>` +
+	`<w:tr><w:trPr><w:ins w:id="6" w:author="A"/></w:trPr><w:tc><w:p><w:r><w:t>C</w:t></w:r></w:p></w:tc></w:tr></w:tbl>`
+
+func TestAcceptAllRevisionsOnOpen(t *testing.T) {
+	doc, err := docxgen.OpenBytes(docxtest.New().Body(trackedBody).Bytes(), docxgen.WithAcceptedRevisions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := doc.ContentPart("document")
+	if got := strings.Join(paragraphTexts(body), "|"); got != "Клиент: {name}|Итог|Первая вторая|A|C" {
+		t.Errorf("texts: %s", got)
+	}
+	if strings.Contains(body, "<w:ins") || strings.Contains(body, "<w:del") || strings.Contains(body, "Change") {
+		t.Errorf("revisions are left:\n%s", body)
+	}
+	if !strings.Contains(body, `<w:rPr><w:b/></w:rPr>`) || !strings.Contains(body, `<w:jc w:val="center"/></w:pPr><w:r><w:t xml:space="preserve">Первая </w:t></w:r><w:r><w:t>вторая`) {
+		t.Errorf("the new formatting must stay, the joined paragraph takes the properties of the next one:\n%s", body)
+	}
+}
+
+func TestRejectAllRevisionsOnOpen(t *testing.T) {
+	doc, err := docxgen.OpenBytes(docxtest.New().Body(trackedBody).Bytes(), docxgen.WithRejectedRevisions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := doc.ContentPart("document")
+	if got := strings.Join(paragraphTexts(body), "|"); got != "Клиент: {fio}|Итог|Первая |вторая|A|B" {
+		t.Errorf("texts: %s", got)
+	}
+	if !strings.Contains(body, `<w:rPr><w:i/></w:rPr>`) || strings.Contains(body, "<w:delText") {
+		t.Errorf("the old formatting must come back, the deleted text becomes a text:\n%s", body)
+	}
+	if err := doc.ExecuteTemplate(map[string]any{"fio": "Иванов"}); err != nil {
+		t.Fatal(err)
+	}
+	body, _ = doc.ContentPart("document")
+	if !strings.Contains(strings.Join(paragraphTexts(body), "|"), "Клиент: Иванов") {
+		t.Errorf("the repaired tag must be filled:\n%s", body)
+	}
+}
+
+func TestAcceptAllRevisionsInHeader(t *testing.T) {
+	doc := docxtest.New().Paragraph("Текст").
+		Header(`<w:p><w:del w:id="1" w:author="A"><w:r><w:delText>Черновик</w:delText></w:r></w:del>` +
+			`<w:ins w:id="2" w:author="A"><w:r><w:t>{company}</w:t></w:r></w:ins></w:p>`).
+		Open(t)
+	doc.AcceptAllRevisions()
+	if err := doc.ExecuteTemplate(map[string]any{"company": "ООО «Ромашка»"}); err != nil {
+		t.Fatal(err)
+	}
+	header, _ := doc.ContentPart("header1")
+	if got := strings.Join(paragraphTexts(header), "|"); got != "ООО «Ромашка»" {
+		t.Errorf("header: %s", got)
+	}
+}
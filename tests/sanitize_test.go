@@ -0,0 +1,62 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"docxgen/modifiers"
+)
+
+func TestValidateRawXML(t *testing.T) {
+	cases := []struct {
+		xml modifiers.RawXML
+		ok  bool
+	}{
+		{`</w:t></w:r><w:r><w:br/><w:t>`, true},
+		{modifiers.StyledRun("x", "<w:b/>"), true},
+		{`plain &amp; text`, true},
+		{`</w:t></w:r><w:r><w:t>`, true},
+		{`</w:t></w:r><w:r><w:b>`, false},                             // not closed
+		{`</w:t></w:r></w:p></w:body><w:body><w:p><w:r><w:t>`, false}, // structure element
+		{`</w:t></w:r><w:r><w:sectPr/><w:t>`, false},                  // disallowed
+		{`<?xml-stylesheet href="x"?>`, false},                        // processing instruction
+		{`&nbsp;`, false},                                             // unknown entity
+		{`<w:p><w:t>barcode error</w:t></w:p>`, true},                 // odd but well-formed
+	}
+	for _, c := range cases {
+		err := modifiers.ValidateRawXML(c.xml)
+		if (err == nil) != c.ok {
+			t.Errorf("ValidateRawXML(%q) = %v, want ok=%v", c.xml, err, c.ok)
+		}
+		if err != nil && !errors.Is(err, modifiers.ErrUnsafeRawXML) {
+			t.Errorf("error must wrap ErrUnsafeRawXML: %v", err)
+		}
+	}
+	if err := modifiers.ValidateRawXML(`</w:t></w:r><w:r><w:tab/><w:t>`, "w:tab"); err == nil {
+		t.Error("a custom disallowed list must be used")
+	}
+}
+
+func TestRawXMLEnforcement(t *testing.T) {
+	broken := func(v string) modifiers.RawXML { return modifiers.RawXML("</w:t></w:r><w:r><w:b>" + v) }
+
+	for _, policy := range []modifiers.LimitPolicy{modifiers.LimitError, modifiers.LimitTruncate} {
+		doc := openTemplate(t, "<w:p><w:r><w:t>[{v|broken}]</w:t></w:r></w:p>")
+		doc.SetModifierLimits(modifiers.Limits{ValidateXML: true, Policy: policy})
+		doc.AddModifier("broken", broken, 0)
+		err := doc.ExecuteTemplate(map[string]any{"v": "x"})
+
+		content, _ := doc.ContentPart("document")
+		switch policy {
+		case modifiers.LimitError:
+			if !errors.Is(err, modifiers.ErrUnsafeRawXML) {
+				t.Errorf("expected ErrUnsafeRawXML, got %v", err)
+			}
+		default:
+			if err != nil || !strings.Contains(content, "[]") {
+				t.Errorf("unsafe xml must be dropped: %v\n%s", err, content)
+			}
+		}
+	}
+}
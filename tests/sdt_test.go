@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestContentControls(t *testing.T) {
+	text := func(tag, content string) string {
+		return `<w:sdt><w:sdtPr><w:alias w:val="Клиент"/><w:tag w:val="` + tag + `"/><w:showingPlcHdr/>` +
+			`<w:dataBinding w:xpath="/root/x"/></w:sdtPr><w:sdtContent>` + content + `</w:sdtContent></w:sdt>`
+	}
+	body := text("client.name", `<w:p><w:pPr><w:jc w:val="center"/></w:pPr><w:r><w:rPr><w:rStyle w:val="PlaceholderText"/><w:b/></w:rPr>`+
+		`<w:t>Введите имя</w:t></w:r></w:p>`) +
+		`<w:p><w:r><w:t xml:space="preserve">Город: </w:t></w:r>` + text("city", `<w:r><w:t>город</w:t></w:r>`) + `</w:p>` +
+		text("unknown", `<w:p><w:r><w:t>Как было</w:t></w:r></w:p>`) +
+		`<w:p><w:sdt><w:sdtPr><w:tag w:val="vip"/><w14:checkbox><w14:checked w14:val="0"/></w14:checkbox></w:sdtPr>` +
+		`<w:sdtContent><w:r><w:t>☐</w:t></w:r></w:sdtContent></w:sdt></w:p>` +
+		`<w:tbl><w:sdt><w:sdtPr><w:tag w:val="items"/><w15:repeatingSection/></w:sdtPr><w:sdtContent>` +
+		`<w:sdt><w:sdtPr><w15:repeatingSectionItem/></w:sdtPr><w:sdtContent><w:tr>` +
+		`<w:tc>` + text("title", `<w:p><w:r><w:t>Товар</w:t></w:r></w:p>`) + `</w:tc>` +
+		`<w:tc>` + text("currency", `<w:p><w:r><w:t>Валюта</w:t></w:r></w:p>`) + `</w:tc>` +
+		`</w:tr></w:sdtContent></w:sdt></w:sdtContent></w:sdt></w:tbl>` +
+		`<w:p><w:r><w:t>{note}</w:t></w:r></w:p>`
+
+	doc := openTemplate(t, body)
+	doc.SetContentControls(true)
+	err := doc.ExecuteTemplate(map[string]any{
+		"client":   map[string]any{"name": "ООО «Ромашка»\nфилиал"},
+		"city":     "Тула",
+		"vip":      true,
+		"currency": "RUB",
+		"note":     "{теги работают}",
+		"items":    []any{map[string]any{"title": "Стол"}, map[string]any{"title": "Стул"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	xml, _ := doc.ContentPart("document")
+	want := "ООО «Ромашка»филиал|Город: Тула|Как было|☒|Стол|RUB|Стул|RUB|{теги работают}"
+	if got := strings.Join(paragraphTexts(xml), "|"); got != want {
+		t.Errorf("texts:\n%s\nwant\n%s", got, want)
+	}
+	for _, want := range []string{`<w:jc w:val="center"/></w:pPr><w:r><w:rPr><w:b/></w:rPr>`, `<w:br/>`, `<w14:checked w14:val="1"/>`} {
+		if !strings.Contains(xml, want) {
+			t.Errorf("no %s in\n%s", want, xml)
+		}
+	}
+	// only the control without data keeps the placeholder and the binding
+	if strings.Count(xml, "PlaceholderText")+strings.Count(xml, "<w:showingPlcHdr/>")+strings.Count(xml, "<w:dataBinding") != 2 {
+		t.Errorf("placeholders:\n%s", xml)
+	}
+	if strings.Count(xml, "<w15:repeatingSectionItem/>") != 2 {
+		t.Errorf("repeating section:\n%s", xml)
+	}
+
+	// without the mode the controls are left as they are
+	doc = openTemplate(t, body)
+	if err := doc.ExecuteTemplate(map[string]any{"city": "Тула", "note": ""}); err != nil {
+		t.Fatal(err)
+	}
+	if xml, _ := doc.ContentPart("document"); strings.Contains(xml, "Тула") {
+		t.Errorf("the controls are filled without SetContentControls")
+	}
+}
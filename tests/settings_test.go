@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"docxgen"
+	"docxgen/geometry"
+)
+
+func TestSettingsManager(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "settings.docx")
+	writeDocx(t, tmp, map[string]string{
+		"word/document.xml": `<w:document><w:body><w:p/></w:body></w:document>`,
+		"word/settings.xml": `<w:settings xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+			`<w:zoom w:percent="100"/><w:defaultTabStop w:val="708"/>` +
+			`<w:compat><w:compatSetting w:name="compatibilityMode" w:uri="http://schemas.microsoft.com/office/word" w:val="14"/>` +
+			`<w:compatSetting w:name="overrideTableStyleFontSizeAndJustification" w:uri="http://schemas.microsoft.com/office/word" w:val="1"/></w:compat>` +
+			`<w:themeFontLang w:val="ru-RU"/></w:settings>`,
+	})
+	doc, err := docxgen.Open(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := doc.Settings()
+
+	if got := s.CompatibilityMode(); got != 14 {
+		t.Errorf("CompatibilityMode() = %d, want 14", got)
+	}
+	s.SetCompatibilityMode(15)
+	if got := s.CompatibilityMode(); got != 15 {
+		t.Errorf("CompatibilityMode() after set = %d, want 15", got)
+	}
+	if compat, _ := s.Element("compat"); !strings.Contains(compat, "overrideTableStyleFontSizeAndJustification") {
+		t.Errorf("other compat settings lost: %s", compat)
+	}
+
+	if got := s.DefaultTabStop().Twips(); got != 708 {
+		t.Errorf("DefaultTabStop() = %d twips, want 708", got)
+	}
+	s.SetDefaultTabStop(geometry.FromMM(10))
+	if got := s.DefaultTabStop().Twips(); got != 567 {
+		t.Errorf("DefaultTabStop() after set = %d twips, want 567", got)
+	}
+
+	s.SetHideSpellingErrors(true)
+	s.SetEvenAndOddHeaders(true)
+	s.SetUpdateFields(true)
+	if !s.HideSpellingErrors() || !s.EvenAndOddHeaders() || !s.UpdateFields() {
+		t.Error("on/off settings were not turned on")
+	}
+	s.SetEvenAndOddHeaders(false)
+	if s.EvenAndOddHeaders() {
+		t.Error("evenAndOddHeaders was not turned off")
+	}
+
+	// schema order: zoom < hideSpellingErrors < defaultTabStop < updateFields < compat < themeFontLang
+	data, _ := doc.GetFile("word/settings.xml")
+	xml := string(data)
+	order := []string{"<w:zoom", "<w:hideSpellingErrors", "<w:defaultTabStop", "<w:updateFields", "<w:compat>", "<w:themeFontLang"}
+	for i := 1; i < len(order); i++ {
+		if strings.Index(xml, order[i-1]) > strings.Index(xml, order[i]) {
+			t.Errorf("%s must go before %s:\n%s", order[i-1], order[i], xml)
+		}
+	}
+}
+
+func TestSettingsCreated(t *testing.T) {
+	doc := openTemplate(t, "<w:p/>")
+	doc.Settings().SetUpdateFields(true)
+
+	if _, ok := doc.GetFile("word/settings.xml"); !ok {
+		t.Fatal("settings.xml was not created")
+	}
+	if got := doc.ContentTypes().ContentTypeOf("word/settings.xml"); !strings.HasSuffix(got, "settings+xml") {
+		t.Errorf("content type of settings.xml = %q", got)
+	}
+	found := false
+	for _, r := range doc.Rels().List("document") {
+		found = found || r.Target == "settings.xml"
+	}
+	if !found {
+		t.Error("settings relationship was not added")
+	}
+}
@@ -0,0 +1,49 @@
+package tests
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"docxgen"
+)
+
+func TestSignersBlock(t *testing.T) {
+	doc := openTemplate(t, "<w:p><w:r><w:t>[signers/approvers]</w:t></w:r></w:p>"+
+		"<w:p><w:r><w:t>[signers/approvers cols:num,fio=Фамилия_И.О.,date]</w:t></w:r></w:p>"+
+		"<w:p><w:r><w:t>[signers/missing]</w:t></w:r></w:p>")
+	res, err := doc.ExecuteTemplateResult(map[string]any{"approvers": []any{
+		map[string]any{"fio": "Иванов И. И.", "position": "Директор", "date": "01.12.2025"},
+		map[string]any{"name": "Петров П. П.", "position": "Юрист"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Warnings) != 1 || res.Warnings[0].Kind != docxgen.WarnEmptySigners {
+		t.Errorf("warnings: %v", res.Warnings)
+	}
+	xml, _ := doc.ContentPart("document")
+	if strings.Contains(xml, "[signers/") {
+		t.Errorf("a marker is left:\n%s", xml)
+	}
+	texts := paragraphTexts(xml)
+	lines := regexp.MustCompile(`^_+$`)
+	var plain []string
+	ruled := 0
+	for _, s := range texts {
+		if lines.MatchString(s) {
+			ruled++
+			continue
+		}
+		plain = append(plain, s)
+	}
+	want := "ФИО|Должность|Подпись|Дата|Иванов И. И.|Директор|01.12.2025|Петров П. П.|Юрист|" +
+		"№|Фамилия И.О.|Дата|1|Иванов И. И.|01.12.2025|2|Петров П. П."
+	if got := strings.Join(plain, "|"); got != want {
+		t.Errorf("texts:\n%s\nwant\n%s", got, want)
+	}
+	// two signatures, the empty date of Петров in both tables
+	if ruled != 4 {
+		t.Errorf("%d ruled lines, want 4: %v", ruled, texts)
+	}
+}
@@ -2,6 +2,9 @@ package tests
 
 import (
 	"docxgen"
+	"encoding/json"
+	"errors"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -120,3 +123,438 @@ func TestRenderSmartTable_NoMatchItemIgnored(t *testing.T) {
 		t.Fatalf("item without tags should be skipped: %s", got)
 	}
 }
+
+func TestSmartTableUnmatchedPolicy(t *testing.T) {
+	row := func(cells ...string) string {
+		var b strings.Builder
+		b.WriteString("<w:tr>")
+		for _, c := range cells {
+			b.WriteString("<w:tc><w:p><w:r><w:t>" + c + "</w:t></w:r></w:p></w:tc>")
+		}
+		return b.String() + "</w:tr>"
+	}
+	body := func(marker string) string {
+		return para(marker) + "<w:tbl>" +
+			row("Name", "Price") +
+			row("{name}", "{price}") +
+			row("[unmatched]%[1]s", "{title}{note}") +
+			"</w:tbl>" + para("[/table]")
+	}
+	data := map[string]any{"rows": []any{
+		map[string]any{"name": "Стол", "price": 100},
+		map[string]any{"extra": map[string]any{"title": "Доставка", "note": "!"}},
+		"итого",
+	}}
+
+	// skip (default): the items are left out and reported with their groups
+	doc := openTemplate(t, body("[table/rows]"))
+	res, err := doc.ExecuteTemplateResult(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msgs []string
+	for _, w := range res.Warnings {
+		if w.Kind == docxgen.WarnUnmatchedItem {
+			msgs = append(msgs, w.Name+": "+w.Message)
+		}
+	}
+	want := []string{
+		`rows: item #2 (group "extra") matched no row: fields note, title, skipped`,
+		`rows: item #3 matched no row: a string value, skipped`,
+	}
+	if strings.Join(msgs, "\n") != strings.Join(want, "\n") {
+		t.Errorf("warnings:\n%s\nwant\n%s", strings.Join(msgs, "\n"), strings.Join(want, "\n"))
+	}
+	xml, _ := doc.ContentPart("document")
+	if strings.Contains(xml, "Доставка") || strings.Contains(xml, "[unmatched]") {
+		t.Errorf("skip: %s", xml)
+	}
+
+	// fallback: the [unmatched] row takes them in data order
+	doc = openTemplate(t, body("[table/rows/unmatched=fallback]"))
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatal(err)
+	}
+	xml, _ = doc.ContentPart("document")
+	got := paragraphTexts(xml)
+	if strings.Join(got, "|") != "Name|Price|Стол|100|Доставка!|итого" {
+		t.Errorf("fallback: %q", got)
+	}
+
+	// error: the render fails
+	doc = openTemplate(t, body("[table/rows/unmatched=error]"))
+	if err := doc.ExecuteTemplate(data); !errors.Is(err, docxgen.ErrUnmatchedItems) {
+		t.Errorf("error policy: %v", err)
+	}
+}
+
+func TestSmartTableNestedAndHeader(t *testing.T) {
+	row := func(cells ...string) string {
+		s := `<w:tr>`
+		for _, c := range cells {
+			s += `<w:tc><w:p w:rsidR="00A1"><w:r><w:t>` + c + `</w:t></w:r></w:p></w:tc>`
+		}
+		return s + `</w:tr>`
+	}
+	block := func(name, field string) string {
+		return `<w:p w:rsidR="00A1" w:rsidRDefault="00A1"><w:r><w:t>[table/` + name + `]</w:t></w:r></w:p>` +
+			`<w:tbl><w:tblPr/>` + row("{"+field+"}") + `</w:tbl>` +
+			`<w:p w:rsidR="00A1"><w:r><w:t>[/table]</w:t></w:r></w:p>`
+	}
+
+	path := filepath.Join(t.TempDir(), "nested.docx")
+	writeDocx(t, path, map[string]string{
+		"word/document.xml": `<w:document><w:body>` +
+			// a block inside a cell of an ordinary table
+			`<w:tbl>` + row("Состав") + `<w:tr><w:tc>` + block("staff", "fio") + `</w:tc></w:tr></w:tbl>` +
+			// two blocks in a row; the table of the second has a nested table in a cell
+			block("tags", "tag") +
+			`<w:p><w:r><w:t>[table/goods]</w:t></w:r></w:p>` +
+			`<w:tbl>` + row("Товар") + `<w:tr><w:tc><w:tbl>` + row("вложенная") + `</w:tbl><w:p/></w:tc></w:tr>` +
+			row("{name}") + `</w:tbl>` +
+			`<w:p><w:r><w:t>[/table]</w:t></w:r></w:p>` +
+			`<w:sectPr><w:headerReference w:type="default" r:id="rIdH"/></w:sectPr>` +
+			`</w:body></w:document>`,
+		"word/_rels/document.xml.rels": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rIdH" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/header" Target="header1.xml"/>` +
+			`</Relationships>`,
+		"word/header1.xml": `<w:hdr>` + block("staff", "fio") + `</w:hdr>`,
+	})
+	doc, err := docxgen.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := map[string]any{
+		"staff": []any{map[string]any{"p": map[string]any{"fio": "Иванов"}}, map[string]any{"p": map[string]any{"fio": "Петров"}}},
+		"tags":  []any{map[string]any{"t": map[string]any{"tag": "срочно"}}},
+		"goods": []any{map[string]any{"g": map[string]any{"name": "Болт"}}, map[string]any{"g": map[string]any{"name": "Гайка"}}},
+	}
+	res, err := doc.ExecuteTemplateResult(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Warnings) != 0 {
+		t.Errorf("warnings: %v", res.Warnings)
+	}
+
+	for _, part := range []string{"document", "header1"} {
+		xml, _ := doc.ContentPart(part)
+		if strings.Contains(xml, "[table/") || strings.Contains(xml, "[/table]") || strings.Contains(xml, "{fio}") {
+			t.Errorf("%s: markers are left: %s", part, xml)
+		}
+		if !strings.Contains(xml, "Иванов") || !strings.Contains(xml, "Петров") {
+			t.Errorf("%s: the staff table is not rendered: %s", part, xml)
+		}
+		if strings.Contains(xml, "</w:tbl></w:tc>") {
+			t.Errorf("%s: a cell ends with a table: %s", part, xml)
+		}
+	}
+
+	xml, _ := doc.ContentPart("document")
+	texts := paragraphTexts(xml)
+	for _, want := range []string{"срочно", "Товар", "вложенная", "Болт", "Гайка"} {
+		if strings.Count(strings.Join(texts, "|"), want) != 1 {
+			t.Errorf("want %q once in %q", want, texts)
+		}
+	}
+	if strings.Count(xml, "<w:tbl>") != 5 {
+		t.Errorf("want 5 tables (outer, staff, tags, goods, nested): %s", xml)
+	}
+}
+
+func TestSmartTableColumnSubsets(t *testing.T) {
+	row := func(cells ...string) string {
+		s := `<w:tr>`
+		for _, c := range cells {
+			s += `<w:tc><w:p><w:r><w:t>` + c + `</w:t></w:r></w:p></w:tc>`
+		}
+		return s + `</w:tr>`
+	}
+	body := `<w:p><w:r><w:t>[table/items cols:1-2]</w:t></w:r></w:p>` +
+		`<w:tbl>` + row("Код", "Товар") + row("%[1]s", "%[2]s") + `</w:tbl>` +
+		`<w:p><w:r><w:t>[/table]</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>[table/items/cols=3-]</w:t></w:r></w:p>` +
+		`<w:tbl>` + row("Кол-во", "Цена") + row("%[1]s", "%[2]s") + `</w:tbl>` +
+		`<w:p><w:r><w:t>[/table]</w:t></w:r></w:p>`
+	items := []any{
+		[]any{"A1", "Болт", "10", "5,00"},
+		map[string]any{"row": []string{"B2", "Гайка", "20", "2,50"}},
+	}
+
+	doc := openTemplate(t, body)
+	res, err := doc.ExecuteTemplateResult(map[string]any{"items": items})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Warnings) != 0 {
+		t.Errorf("warnings: %v", res.Warnings)
+	}
+	xml, _ := doc.ContentPart("document")
+	got := strings.Join(paragraphTexts(xml), "|")
+	want := "Код|Товар|A1|Болт|B2|Гайка|Кол-во|Цена|10|5,00|20|2,50"
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	// the data is shared, not cut
+	if len(items[0].([]any)) != 4 {
+		t.Errorf("the data was changed: %v", items)
+	}
+}
+
+func TestSmartTableColumnMajorData(t *testing.T) {
+	body := `<w:p><w:r><w:t>[table/staff]</w:t></w:r></w:p>` +
+		`<w:tbl>` +
+		`<w:tr><w:tc><w:p><w:r><w:t>ФИО</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Должность</w:t></w:r></w:p></w:tc></w:tr>` +
+		`<w:tr><w:tc><w:p><w:r><w:t>{fio}</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>{pos}</w:t></w:r></w:p></w:tc></w:tr>` +
+		`</w:tbl>` +
+		`<w:p><w:r><w:t>[/table]</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>[for staff]</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{fio} — {pos}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>[/for]</w:t></w:r></w:p>`
+	data := map[string]any{"staff": map[string]any{
+		"fio": []any{"Иванов", "Петров", "Сидоров"},
+		"pos": []string{"Инженер", "Директор"},
+	}}
+
+	doc := openTemplate(t, body)
+	res, err := doc.ExecuteTemplateResult(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Warnings) != 0 {
+		t.Errorf("warnings: %v", res.Warnings)
+	}
+	xml, _ := doc.ContentPart("document")
+	got := strings.Join(paragraphTexts(xml), "|")
+	want := "ФИО|Должность|Иванов|Инженер|Петров|Директор|Сидоров|" + // the short column is filled with ""
+		"Иванов — Инженер|Петров — Директор|Сидоров — "
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestSmartTableSplit(t *testing.T) {
+	table := `<w:tbl><w:tblPr><w:tblW w:w="0" w:type="auto"/></w:tblPr><w:tblGrid><w:gridCol/></w:tblGrid>` +
+		`<w:tr><w:tc><w:p><w:r><w:t>№</w:t></w:r></w:p></w:tc></w:tr>` +
+		`<w:tr><w:tc><w:p><w:r><w:t>{n}</w:t></w:r></w:p></w:tc></w:tr>` +
+		`<w:tr><w:tc><w:p><w:r><w:t>Итого</w:t></w:r></w:p></w:tc></w:tr>` +
+		`</w:tbl>`
+	var items []any
+	for _, n := range []string{"1", "2", "3", "4", "5"} {
+		items = append(items, map[string]any{"row": map[string]any{"n": n}})
+	}
+	doc := openTemplate(t, `<w:p><w:r><w:t>[table/items split=2]</w:t></w:r></w:p>`+table+`<w:p><w:r><w:t>[/table]</w:t></w:r></w:p>`)
+	if err := doc.ExecuteTemplate(map[string]any{"items": items}); err != nil {
+		t.Fatal(err)
+	}
+	xml, _ := doc.ContentPart("document")
+
+	if strings.Count(xml, "<w:tbl>") != 3 || strings.Count(xml, `<w:br w:type="page"/>`) != 2 ||
+		strings.Count(xml, "<w:tblPr>") != 3 {
+		t.Errorf("want 3 tables on 3 pages: %s", xml)
+	}
+	got := strings.Join(paragraphTexts(xml), "|")
+	if want := "№|1|2|№|3|4|№|5|Итого"; got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestSmartTableNestedBlocks(t *testing.T) {
+	cell := func(content string) string { return `<w:tc>` + content + `</w:tc>` }
+	p := func(text string) string { return `<w:p><w:r><w:t>` + text + `</w:t></w:r></w:p>` }
+	block := func(name, table string) string {
+		return p("[table/"+name+"]") + `<w:tbl>` + table + `</w:tbl>` + p("[/table]")
+	}
+	components := block("parts", `<w:tr>`+cell(p("{name}"))+`</w:tr>`)
+	items := block("items", `<w:tr>`+cell(p("{name}"))+cell(components)+`</w:tr>`)
+	body := block("orders",
+		`<w:tr>`+cell(p("Заказы"))+cell(block("managers", `<w:tr>`+cell(p("{name}"))+`</w:tr>`))+`</w:tr>`+
+			`<w:tr>`+cell(p("№ {name}"))+cell(items)+`</w:tr>`)
+
+	data := map[string]any{
+		"managers": []any{map[string]any{"name": "Смирнова"}},
+		"orders": []any{
+			map[string]any{"name": "1", "items": []any{
+				map[string]any{"name": "Стол", "parts": []any{map[string]any{"name": "ножка"}, map[string]any{"name": "крышка"}}},
+				map[string]any{"name": "Стул", "parts": []any{}},
+			}},
+			map[string]any{"name": "2", "items": []any{
+				map[string]any{"name": "Шкаф", "parts": []any{map[string]any{"name": "дверь"}}},
+			}},
+		},
+	}
+	doc := openTemplate(t, body)
+	res, err := doc.ExecuteTemplateResult(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the chair has no parts: its table is empty
+	if len(res.Warnings) != 1 || res.Warnings[0].Name != "parts" {
+		t.Errorf("warnings: %v", res.Warnings)
+	}
+	xml, _ := doc.ContentPart("document")
+	got := strings.Join(paragraphTexts(xml), "|")
+	if want := "Заказы|Смирнова|№ 1|Стол|ножка|крышка|Стул|№ 2|Шкаф|дверь"; got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	if strings.Contains(xml, "[table/") || strings.Contains(xml, "[/table]") || strings.Contains(xml, "\x00") {
+		t.Errorf("markers are left: %s", xml)
+	}
+}
+
+func TestSmartTableGroups(t *testing.T) {
+	row := func(cells ...string) string {
+		s := `<w:tr>`
+		for _, c := range cells {
+			s += `<w:tc><w:p><w:r><w:t>` + c + `</w:t></w:r></w:p></w:tc>`
+		}
+		return s + `</w:tr>`
+	}
+	block := func(marker string, rows ...string) string {
+		return `<w:p><w:r><w:t>` + marker + `</w:t></w:r></w:p>` +
+			`<w:tbl><w:tblGrid><w:gridCol/><w:gridCol/></w:tblGrid>` + strings.Join(rows, "") + `</w:tbl>` +
+			`<w:p><w:r><w:t>[/table]</w:t></w:r></w:p>`
+	}
+	items := []any{
+		map[string]any{"department": "Склад", "title": "Стеллажи", "amount": 1200.5},
+		map[string]any{"department": "Офис", "title": "Бумага", "amount": "300"},
+		map[string]any{"department": "Склад", "title": "Погрузчик", "amount": json.Number("10000.25")},
+	}
+
+	// an explicit [subtotal] row, the title row is generated
+	doc := openTemplate(t, block("[table/budget group=department subtotal=amount]",
+		row("Статья", "Сумма"),
+		row("{title}", "{amount}"),
+		row("[subtotal]Итого: {department}", "{amount}"),
+		row("Всего", "{total}")))
+	if err := doc.ExecuteTemplate(map[string]any{"budget": items, "total": "11500,75"}); err != nil {
+		t.Fatal(err)
+	}
+	xml, _ := doc.ContentPart("document")
+	got := strings.Join(paragraphTexts(xml), "|")
+	want := "Статья|Сумма|Склад|Стеллажи|1200.5|Погрузчик|10000.25|Итого: Склад|11200.75|" +
+		"Офис|Бумага|300|Итого: Офис|300|Всего|11500,75"
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	if strings.Count(xml, `<w:gridSpan w:val="2"/>`) != 2 || strings.Count(xml, "<w:b/>") != 2 {
+		t.Errorf("the generated title rows span the table in bold: %s", xml)
+	}
+
+	// an explicit [group] row, the sums go into the data row
+	doc = openTemplate(t, block("[table/budget/group=department/subtotal=amount]",
+		row("[group]Отдел «{department}»", ""),
+		row("{title}", "{amount}")))
+	if err := doc.ExecuteTemplate(map[string]any{"budget": items}); err != nil {
+		t.Fatal(err)
+	}
+	xml, _ = doc.ContentPart("document")
+	got = strings.Join(paragraphTexts(xml), "|")
+	want = "Отдел «Склад»|Стеллажи|1200.5|Погрузчик|10000.25|11200.75|Отдел «Офис»|Бумага|300|300"
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	// split=N: a group title does not end a page, it goes over with the first row of the group
+	doc = openTemplate(t, block("[table/budget group=department split=2]",
+		row("Статья", "Сумма"),
+		row("{title}", "{amount}")))
+	if err := doc.ExecuteTemplate(map[string]any{"budget": items}); err != nil {
+		t.Fatal(err)
+	}
+	xml, _ = doc.ContentPart("document")
+	var pages []string
+	for _, part := range strings.Split(xml, `<w:br w:type="page"/>`) {
+		pages = append(pages, strings.Join(paragraphTexts(part), "|"))
+	}
+	got = strings.Join(pages, " / ")
+	want = "Статья|Сумма|Склад|Стеллажи|1200.5 / Статья|Сумма|Погрузчик|10000.25 / Статья|Сумма|Офис|Бумага|300"
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestSmartTableSort(t *testing.T) {
+	body := func(marker string) string {
+		return `<w:p><w:r><w:t>` + marker + `</w:t></w:r></w:p>` +
+			`<w:tbl><w:tr><w:tc><w:p><w:r><w:t>{date}</w:t></w:r></w:p></w:tc>` +
+			`<w:tc><w:p><w:r><w:t>{sum}</w:t></w:r></w:p></w:tc></w:tr></w:tbl>` +
+			`<w:p><w:r><w:t>[/table]</w:t></w:r></w:p>`
+	}
+	items := []any{
+		map[string]any{"date": "02.03.2024", "sum": 10},
+		map[string]any{"date": "15.01.2024", "sum": 9.5},
+		map[string]any{"sum": "100"},
+		map[string]any{"date": "01.12.2023", "sum": "10"},
+	}
+	for marker, want := range map[string]string{
+		"[table/items sort=date]":          "01.12.2023|10|15.01.2024|9.5|02.03.2024|10|100",
+		"[table/items sort=date desc]":     "02.03.2024|10|15.01.2024|9.5|01.12.2023|10|100",
+		"[table/items/sort=-sum,date]":     "100|01.12.2023|10|02.03.2024|10|15.01.2024|9.5",
+		"[table/items sort=sum:asc]":       "15.01.2024|9.5|02.03.2024|10|01.12.2023|10|100",
+		"[table/items sort=sum,date:desc]": "15.01.2024|9.5|02.03.2024|10|01.12.2023|10|100",
+	} {
+		doc := openTemplate(t, body(marker))
+		if err := doc.ExecuteTemplate(map[string]any{"items": items}); err != nil {
+			t.Fatal(err)
+		}
+		xml, _ := doc.ContentPart("document")
+		if got := strings.Join(paragraphTexts(xml), "|"); got != want {
+			t.Errorf("%s:\ngot  %s\nwant %s", marker, got, want)
+		}
+	}
+	if items[0].(map[string]any)["date"] != "02.03.2024" {
+		t.Error("the data was sorted in place")
+	}
+}
+
+func TestSmartTableCollapseEmptyCols(t *testing.T) {
+	cell := func(text string) string {
+		return `<w:tc><w:p><w:r><w:t>` + text + `</w:t></w:r></w:p></w:tc>`
+	}
+	body := func(marker string) string {
+		return `<w:p><w:r><w:t>` + marker + `</w:t></w:r></w:p>` +
+			`<w:tbl><w:tblGrid><w:gridCol w:w="3000"/><w:gridCol w:w="1000"/><w:gridCol w:w="2000"/><w:gridCol w:w="1000"/></w:tblGrid>` +
+			`<w:tr>` + cell("Name") + cell("Discount") + cell("Note") + cell("Qty") + `</w:tr>` +
+			`<w:tr>` + cell("{name}") + cell("{discount|money}") + cell("{note}") + cell("{qty}") + `</w:tr>` +
+			`<w:tr><w:tc><w:tcPr><w:gridSpan w:val="3"/></w:tcPr><w:p><w:r><w:t>Total</w:t></w:r></w:p></w:tc>` + cell("{total}") + `</w:tr>` +
+			`</w:tbl><w:p><w:r><w:t>[/table]</w:t></w:r></w:p>`
+	}
+	render := func(marker string, items []any) string {
+		t.Helper()
+		doc := openTemplate(t, body(marker))
+		if err := doc.ExecuteTemplate(map[string]any{"items": items, "total": "5"}); err != nil {
+			t.Fatal(err)
+		}
+		xml, _ := doc.ContentPart("document")
+		return xml
+	}
+	items := []any{
+		map[string]any{"name": "Bolt", "note": "", "qty": 2},
+		map[string]any{"name": "Nut", "note": " ", "qty": 3},
+	}
+
+	xml := render("[table/items collapse-empty-cols]", items)
+	if got := strings.Join(paragraphTexts(xml), "|"); got != "Name|Qty|Bolt|2|Nut|3|Total|5" {
+		t.Errorf("collapsed: %s", got)
+	}
+	if n := strings.Count(xml, "<w:gridCol"); n != 2 {
+		t.Errorf("grid: %d columns, want 2", n)
+	}
+	if strings.Contains(xml, "w:gridSpan") && !strings.Contains(xml, `<w:gridSpan w:val="1"/>`) {
+		t.Errorf("the Total cell keeps its span: %s", xml)
+	}
+
+	// a column with a value in one item stays, and so does everything without the option
+	items = append(items, map[string]any{"name": "Washer", "discount": 10, "qty": 1})
+	xml = render("[table/items collapse-empty-cols]", items)
+	if got := strings.Join(paragraphTexts(xml), "|"); !strings.HasPrefix(got, "Name|Discount|Qty|") {
+		t.Errorf("discount collapsed: %s", got)
+	}
+	xml = render("[table/items]", items[:2])
+	if n := strings.Count(xml, "<w:gridCol"); n != 4 {
+		t.Errorf("without the option: %d columns, want 4", n)
+	}
+}
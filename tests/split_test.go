@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"docxgen"
+)
+
+func TestSplitBySections(t *testing.T) {
+	doc := openTemplate(t, `<w:p><w:r><w:t>Письмо 1</w:t></w:r></w:p>`+
+		`<w:p><w:pPr><w:sectPr><w:headerReference w:type="default" r:id="rId1"/><w:pgSz w:w="11906" w:h="16838"/></w:sectPr></w:pPr></w:p>`+
+		`<w:p><w:r><w:t>Письмо 2</w:t></w:r></w:p>`+
+		`<w:p><w:pPr><w:sectPr><w:pgSz w:w="16838" w:h="11906"/></w:sectPr></w:pPr></w:p>`+
+		`<w:tbl><w:tr><w:tc><w:p><w:r><w:t>Письмо 3</w:t></w:r></w:p></w:tc></w:tr></w:tbl>`+
+		`<w:sectPr><w:pgSz w:w="11906" w:h="16838"/></w:sectPr>`)
+	before, _ := doc.ContentPart("document")
+
+	pieces := doc.SplitBySections()
+	if len(pieces) != 3 {
+		t.Fatalf("%d pieces, want 3", len(pieces))
+	}
+	for i, want := range []string{"Письмо 1", "Письмо 2", "Письмо 3"} {
+		xml, _ := pieces[i].ContentPart("document")
+		if got := strings.Join(paragraphTexts(xml), "|"); got != want {
+			t.Errorf("piece %d: %q, want %q", i+1, got, want)
+		}
+		if strings.Count(xml, "<w:sectPr") != 1 || !strings.Contains(xml, `rId1`) {
+			t.Errorf("piece %d keeps no section of its own or no header:\n%s", i+1, xml)
+		}
+	}
+	if layout := pieces[1].Sections(); len(layout) != 1 || layout[0].Width <= layout[0].Height {
+		t.Errorf("the landscape section is lost: %v", layout)
+	}
+	if after, _ := doc.ContentPart("document"); after != before {
+		t.Errorf("the document has changed")
+	}
+}
+
+func TestSplitByPageBreaks(t *testing.T) {
+	doc := openTemplate(t, `<w:p><w:r><w:t>Акт 1</w:t></w:r></w:p>`+docxgen.PageBreak+
+		`<w:p><w:r><w:rPr><w:b/></w:rPr><w:t>Акт 2</w:t><w:br w:type="page"/><w:t>Акт 3</w:t></w:r></w:p>`+
+		`<w:p><w:pPr><w:pageBreakBefore/></w:pPr><w:r><w:t>Акт 4</w:t></w:r></w:p>`+
+		`<w:tbl><w:tr><w:tc><w:p><w:r><w:br w:type="page"/><w:t>в таблице</w:t></w:r></w:p></w:tc></w:tr></w:tbl>`+
+		`<w:p/>`+docxgen.PageBreak+
+		`<w:sectPr><w:pgSz w:w="11906" w:h="16838"/></w:sectPr>`)
+
+	pieces := doc.SplitByPageBreaks()
+	var got []string
+	for _, p := range pieces {
+		xml, _ := p.ContentPart("document")
+		got = append(got, strings.Join(paragraphTexts(xml), "+"))
+		if strings.Count(xml, "<w:sectPr") != 1 || strings.Contains(xml, "pageBreakBefore") {
+			t.Errorf("piece %q:\n%s", got[len(got)-1], xml)
+		}
+	}
+	if want := "Акт 1|Акт 2|Акт 3|Акт 4+в таблице"; strings.Join(got, "|") != want {
+		t.Errorf("pieces %q, want %q", strings.Join(got, "|"), want)
+	}
+	if xml, _ := pieces[2].ContentPart("document"); !strings.Contains(xml, `<w:r><w:rPr><w:b/></w:rPr><w:t>Акт 3`) {
+		t.Errorf("the run properties are lost after the break:\n%s", xml)
+	}
+}
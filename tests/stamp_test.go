@@ -0,0 +1,274 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"docxgen/daemon"
+	"docxgen/pdf"
+)
+
+// minimalPDF builds a one-page PDF: the page (object 3) has inline resources and one content stream.
+// With xrefStream the page is compressed into an object stream and the cross-reference is a stream,
+// as newer writers do.
+func minimalPDF(xrefStream bool) []byte {
+	page := `<</Type/Page/Parent 2 0 R/Resources<</Font<<>>>>/Contents 4 0 R>>`
+	objects := map[int]string{
+		1: `<</Type/Catalog/Pages 2 0 R>>`,
+		2: `<</Type/Pages/Kids[3 0 R]/Count 1/MediaBox[0 0 595 842]>>`,
+		4: "<</Length 5>>\nstream\nBT ET\nendstream",
+	}
+	if xrefStream {
+		header := "3 0 "
+		objects[5] = fmt.Sprintf("<</Type/ObjStm/N 1/First %d/Length %d>>\nstream\n%s%s\nendstream",
+			len(header), len(header)+len(page), header, page)
+	} else {
+		objects[3] = page
+	}
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n")
+	offsets := map[int]int{}
+	for n := 1; n <= 5; n++ {
+		if body, ok := objects[n]; ok {
+			offsets[n] = b.Len()
+			fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", n, body)
+		}
+	}
+
+	if !xrefStream {
+		xref := b.Len()
+		b.WriteString("xref\n0 5\n0000000000 65535 f \n")
+		for n := 1; n <= 4; n++ {
+			fmt.Fprintf(&b, "%010d 00000 n \n", offsets[n])
+		}
+		fmt.Fprintf(&b, "trailer\n<</Size 5/Root 1 0 R>>\nstartxref\n%d\n%%%%EOF\n", xref)
+		return b.Bytes()
+	}
+
+	// W [1 2 1]: type, offset / object stream, generation / index
+	var rows []byte
+	row := func(typ, f2, f3 int) { rows = append(rows, byte(typ), byte(f2>>8), byte(f2), byte(f3)) }
+	row(0, 0, 255)
+	row(1, offsets[1], 0)
+	row(1, offsets[2], 0)
+	row(2, 5, 0)
+	row(1, offsets[4], 0)
+	row(1, offsets[5], 0)
+	xref := b.Len()
+	row(1, xref, 0)
+	fmt.Fprintf(&b, "6 0 obj\n<</Type/XRef/Size 7/W[1 2 1]/Root 1 0 R/Length %d>>\nstream\n", len(rows))
+	b.Write(rows)
+	fmt.Fprintf(&b, "\nendstream\nendobj\nstartxref\n%d\n%%%%EOF\n", xref)
+	return b.Bytes()
+}
+
+// lastVersion returns the last written version of the object.
+func lastVersion(doc []byte, num int) string {
+	re := regexp.MustCompile(fmt.Sprintf(`(?s)\n%d 0 obj\n(.*?)\nendobj`, num))
+	all := re.FindAllSubmatch(doc, -1)
+	if len(all) == 0 {
+		return ""
+	}
+	return string(all[len(all)-1][1])
+}
+
+func TestApplyStamp(t *testing.T) {
+	stamp := pdf.Stamp{
+		Lines: []string{"Вх. № 123-А"},
+		Date:  time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC),
+		QR:    "https://example.com/doc/123",
+	}
+
+	for _, xrefStream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("xrefStream=%v", xrefStream), func(t *testing.T) {
+			src := minimalPDF(xrefStream)
+			out, err := pdf.ApplyStamp(src, stamp)
+			if err != nil {
+				t.Fatalf("stamp: %v", err)
+			}
+			if !bytes.HasPrefix(out, src) {
+				t.Fatal("the original bytes must stay intact (incremental update)")
+			}
+
+			page := lastVersion(out, 3)
+			if !regexp.MustCompile(`/Contents \[\d+ 0 R 4 0 R \d+ 0 R\]`).MatchString(page) {
+				t.Errorf("page contents are not wrapped: %s", page)
+			}
+			if !strings.Contains(page, "/Font <<>>") || !strings.Contains(page, "/XObject <</DxStamp ") {
+				t.Errorf("page resources: %s", page)
+			}
+			if !bytes.Contains(out, []byte("/SMask")) || !bytes.Contains(out, []byte("cm /DxStamp Do Q")) {
+				t.Error("no stamp image or drawing operators")
+			}
+			if xrefStream != bytes.Contains(out[len(src):], []byte("/Type /XRef")) {
+				t.Error("the update must use the same kind of cross-reference as the original")
+			}
+
+			// the result is readable again: a second stamp goes on top of the first
+			again, err := pdf.ApplyStamp(out, stamp)
+			if err != nil {
+				t.Fatalf("second stamp: %v", err)
+			}
+			if page := lastVersion(again, 3); !strings.Contains(page, "/DxStamp1 ") {
+				t.Errorf("second stamp must get its own name: %s", page)
+			}
+		})
+	}
+}
+
+// tablePDF builds a PDF of the objects 1..n with a cross-reference table; object 1 is the catalog.
+func tablePDF(objects ...string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n")
+	offsets := make([]int, len(objects))
+	for i, body := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, body)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<</Size %d/Root 1 0 R>>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+func TestApplyStampSelfReference(t *testing.T) {
+	stamp := pdf.Stamp{Lines: []string{"Вх. № 1"}}
+
+	// a /Length that points to its own object: the stream is read up to endstream
+	src := tablePDF(`<</Type/Catalog/Pages 2 0 R>>`, `<</Type/Pages/Kids[3 0 R]/Count 1>>`,
+		`<</Type/Page/Parent 2 0 R/Contents 4 0 R>>`, "<</Length 4 0 R>>\nstream\nBT ET\nendstream")
+	if _, err := pdf.ApplyStamp(src, stamp); err != nil {
+		t.Errorf("self-referencing /Length: %v", err)
+	}
+
+	// an object stream that contains itself
+	src = minimalPDF(true)
+	xref := bytes.LastIndex(src, []byte("/Type/XRef"))
+	rows := xref + bytes.Index(src[xref:], []byte("stream\n")) + len("stream\n")
+	copy(src[rows+5*4:], []byte{2, 0, 5, 0}) // object 5: the first object of the stream 5
+	if _, err := pdf.ApplyStamp(src, stamp); err == nil || !strings.Contains(err.Error(), "refers to itself") {
+		t.Errorf("self-containing object stream: %v", err)
+	}
+
+	// arrays nested deeper than the parser follows
+	src = tablePDF(`<</Type/Catalog/Pages 2 0 R>>`, `<</Type/Pages/Kids[3 0 R]/Count 1>>`,
+		`<</Type/Page/Parent 2 0 R/Annots `+strings.Repeat("[", 100000)+`>>`)
+	if _, err := pdf.ApplyStamp(src, stamp); err == nil {
+		t.Error("deep nesting must be an error")
+	}
+}
+
+func TestApplyStampMalformed(t *testing.T) {
+	stamp := pdf.Stamp{Lines: []string{"Вх. № 1"}}
+	page := []string{`<</Type/Catalog/Pages 2 0 R>>`, `<</Type/Pages/Kids[3 0 R]/Count 1>>`,
+		`<</Type/Page/Parent 2 0 R/Contents 4 0 R>>`}
+
+	// a negative /Length: the stream is read up to endstream
+	src := tablePDF(append(page, "<</Length -5>>\nstream\nBT ET\nendstream")...)
+	if _, err := pdf.ApplyStamp(src, stamp); err != nil {
+		t.Errorf("negative /Length: %v", err)
+	}
+
+	for name, src := range map[string][]byte{
+		// the offset of an object in its object stream points outside the stream
+		"offset past the object stream":        bytes.Replace(minimalPDF(true), []byte("stream\n3 0 <<"), []byte("stream\n3 99<<"), 1),
+		"negative offset in the object stream": bytes.Replace(minimalPDF(true), []byte("stream\n3 0 <<"), []byte("stream\n3 -9<<"), 1),
+		// subsections of more objects than the file holds
+		"xref table count":        []byte("%PDF-1.7\nxref\n0 900000000\nstartxref\n9\n%%EOF\n"),
+		"negative xref count":     []byte("%PDF-1.7\nxref\n0 -1\ntrailer\n<<>>\nstartxref\n9\n%%EOF\n"),
+		"xref table cut short":    []byte("%PDF-1.7\nxref\n0 1\n0000000000 65535 f \nstartxref\n9\n%%EOF\n"),
+		"xref stream /Index":      bytes.Replace(minimalPDF(true), []byte("/W[1 2 1]"), []byte("/W[1 2 1]/Index[0 900000000]"), 1),
+		"xref stream /Size":       bytes.Replace(minimalPDF(true), []byte("/Size 7"), []byte("/Size 900000000"), 1),
+		"negative xref stream /W": bytes.Replace(minimalPDF(true), []byte("/W[1 2 1]"), []byte("/W[1 -2 1]"), 1),
+	} {
+		if _, err := pdf.ApplyStamp(src, stamp); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestApplyStampPageNumbers(t *testing.T) {
+	src := tablePDF(`<</Type/Catalog/Pages 2 0 R>>`, `<</Type/Pages/Kids[3 0 R 4 0 R]/Count 2/MediaBox[0 0 595 842]>>`,
+		`<</Type/Page/Parent 2 0 R>>`, `<</Type/Page/Parent 2 0 R>>`)
+	images := func(s pdf.Stamp) int {
+		t.Helper()
+		out, err := pdf.ApplyStamp(src, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// every image has its /SMask image
+		return bytes.Count(out[len(src):], []byte("/Subtype /Image")) / 2
+	}
+	if n := images(pdf.Stamp{Lines: []string{"Лист {page} из {pages}"}, Pages: pdf.StampEveryPage}); n != 2 {
+		t.Errorf("numbered stamp: %d images, want one per page", n)
+	}
+	if n := images(pdf.Stamp{Lines: []string{"Вх. № 1"}, Pages: pdf.StampEveryPage}); n != 1 {
+		t.Errorf("the same stamp: %d images, want one for all pages", n)
+	}
+}
+
+func TestStampLimits(t *testing.T) {
+	for name, s := range map[string]pdf.Stamp{
+		"lines":  {Lines: make([]string, pdf.MaxStampLines+1)},
+		"length": {Lines: []string{strings.Repeat("я", pdf.MaxStampLineLen+1)}},
+		"width":  {Lines: []string{"x"}, Width: pdf.MaxStampWidth + 1},
+	} {
+		if err := s.Validate(); !errors.Is(err, pdf.ErrStampTooLarge) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	// wider than the page
+	narrow := tablePDF(`<</Type/Catalog/Pages 2 0 R>>`, `<</Type/Pages/Kids[3 0 R]/Count 1/MediaBox[0 0 100 100]>>`,
+		`<</Type/Page/Parent 2 0 R>>`)
+	if _, err := pdf.ApplyStamp(narrow, pdf.Stamp{Lines: []string{"x"}}); !errors.Is(err, pdf.ErrStampTooLarge) {
+		t.Errorf("stamp wider than the page: %v", err)
+	}
+}
+
+// staticPDF - a converter that always returns the same PDF.
+type staticPDF []byte
+
+func (p staticPDF) Convert(context.Context, []byte) ([]byte, error) {
+	return p, nil
+}
+
+func TestDaemonStamp(t *testing.T) {
+	h := daemon.NewHandler(daemon.Config{PDF: staticPDF(minimalPDF(false))})
+	post := func(stamp map[string]any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]any{"template": templateBase64(t, ""), "format": "pdf", "stamp": stamp})
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/generate", bytes.NewReader(body)))
+		return w
+	}
+
+	w := post(map[string]any{"lines": []string{"Вх. № 7"}, "date": "2026-10-16", "pages": "all"})
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "/DxStamp Do") {
+		t.Fatalf("status %d: %.200s", w.Code, w.Body.String())
+	}
+	if w := post(map[string]any{"lines": []string{"x"}, "pages": "odd"}); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown pages: status %d, want 400", w.Code)
+	}
+	for _, stamp := range []map[string]any{
+		{"lines": []string{"x"}, "width": 5000},
+		{"lines": make([]string, pdf.MaxStampLines+1)},
+		{"lines": []string{"x"}, "width": 250}, // wider than A4
+	} {
+		if w := post(stamp); w.Code != http.StatusBadRequest {
+			t.Errorf("%v: status %d, want 400", stamp["width"], w.Code)
+		}
+	}
+}
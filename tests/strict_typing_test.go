@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"docxgen/modifiers"
+)
+
+func TestStrictTypes(t *testing.T) {
+	body := "<w:p><w:r><w:t>{name|truncate:`abc`:`…`}</w:t></w:r></w:p>"
+	data := map[string]any{"name": "Иванов"}
+
+	// lenient: "abc" quietly becomes 0
+	doc := openTemplate(t, body)
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatal(err)
+	}
+
+	// ExecuteTemplateResult reports the coercion
+	res, err := openTemplate(t, body).ExecuteTemplateResult(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Warnings) != 1 || res.Warnings[0].Kind != "lossy_coercion" || res.Warnings[0].Name != "truncate" {
+		t.Errorf("warnings: %v", res.Warnings)
+	}
+
+	// strict: the render fails
+	doc = openTemplate(t, body)
+	doc.SetStrictTypes(true)
+	err = doc.ExecuteTemplate(data)
+	if !errors.Is(err, modifiers.ErrLossyCoercion) || !strings.Contains(err.Error(), "truncate") {
+		t.Errorf("expected ErrLossyCoercion from truncate, got %v", err)
+	}
+
+	// strict accepts the values that fit
+	doc = openTemplate(t, "<w:p><w:r><w:t>{name|truncate:`3`:`…`} {n|pad_left:5:`0`}</w:t></w:r></w:p>")
+	doc.SetStrictTypes(true)
+	if err := doc.ExecuteTemplate(map[string]any{"name": "Иванов", "n": 42}); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := doc.ContentPart("document"); !strings.Contains(content, "Ива…") || !strings.Contains(content, "00042") {
+		t.Errorf("unexpected output: %s", content)
+	}
+}
+
+func TestWrapModifierCoercion(t *testing.T) {
+	repeat := func(s string, n int) string { return strings.Repeat(s, n) }
+	itoa := func(s string) string { return "[" + s + "]" }
+
+	lenient := modifiers.WrapModifier(repeat, 1).(func(...any) any)
+	if got := lenient(1.5, "ab"); got != "ab" {
+		t.Errorf("1.5 → int must truncate to 1: %q", got)
+	}
+	if got := modifiers.WrapModifier(itoa, 0).(func(...any) any)(65); got != "[65]" {
+		t.Errorf("a number into a string must keep its digits: %q", got)
+	}
+
+	strict := modifiers.WrapModifier(repeat, 1, modifiers.Limits{StrictTypes: true}).(func(...any) (any, error))
+	if got, err := strict("2", "ab"); err != nil || got != "abab" {
+		t.Errorf("\"2\" → int is not lossy: %q, %v", got, err)
+	}
+	for _, n := range []any{1.5, "abc", []int{1}} {
+		if _, err := strict(n, "ab"); !errors.Is(err, modifiers.ErrLossyCoercion) {
+			t.Errorf("%v → int must be lossy: %v", n, err)
+		}
+	}
+
+	truncate := modifiers.WrapModifier(repeat, 1, modifiers.Limits{StrictTypes: true, Policy: modifiers.LimitTruncate}).(func(...any) (any, error))
+	if got, err := truncate("abc", "ab"); err != nil || got != "ab" {
+		t.Errorf("LimitTruncate must output the value unchanged: %q, %v", got, err)
+	}
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"docxgen/docxtest"
+)
+
+func TestStylesManager(t *testing.T) {
+	doc := docxtest.New().Style("Note", "Примечание", `<w:i/>`).Paragraph("{text}").Open(t)
+	if st, ok := doc.Styles().Lookup("Note"); !ok || st.Name != "Примечание" || st.Type != "paragraph" {
+		t.Errorf("Lookup(Note) = %+v, %v", st, ok)
+	}
+
+	total := `<w:style w:type="character" w:customStyle="1" w:styleId="Total"><w:name w:val="Итог"/><w:rPr><w:b/></w:rPr></w:style>`
+	if err := doc.RegisterStyle(total); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.RegisterStyle(strings.Replace(total, "<w:b/>", "<w:b/><w:i/>", 1)); err != nil {
+		t.Fatal(err)
+	}
+	if el, _ := doc.Styles().XML("Total"); !strings.Contains(el, "<w:i/>") {
+		t.Errorf("a registered style must replace the one with its id: %s", el)
+	}
+	n := 0
+	for _, st := range doc.Styles().List() {
+		if st.ID == "Total" {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Errorf("Total is registered %d times", n)
+	}
+	if err := doc.RegisterStyle(`<w:p/>`); err == nil {
+		t.Error("not a style must be an error")
+	}
+	if !doc.Styles().Remove("Total") || doc.Styles().Remove("Total") {
+		t.Error("Remove must remove the style once")
+	}
+}
+
+func TestApplyStyleToTag(t *testing.T) {
+	doc := docxtest.New().
+		Style("Note", "Примечание", `<w:i/>`).
+		Paragraph("Итого: {total}").
+		Body(`<w:p><w:pPr><w:pStyle w:val="Normal"/><w:jc w:val="center"/></w:pPr><w:r><w:t>{note}</w:t></w:r></w:p>`).
+		Table([]string{"{name}", "{price}"}).
+		Open(t)
+	if err := doc.RegisterStyle(`<w:style w:type="character" w:styleId="Total"><w:name w:val="Итог"/><w:rPr><w:b/></w:rPr></w:style>`); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.RegisterStyle(`<w:style w:type="table" w:styleId="Grid"><w:name w:val="Сетка"/></w:style>`); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		tag, style string
+		want       int
+	}{{"{total}", "Total", 1}, {"{note}", "Note", 1}, {"{price}", "Grid", 1}, {"{name}", "Grid", 1}, {"{missing}", "Note", 0}} {
+		if n, err := doc.ApplyStyleToTag(c.tag, c.style); err != nil || n != c.want {
+			t.Errorf("ApplyStyleToTag(%s, %s) = %d, %v; want %d", c.tag, c.style, n, err, c.want)
+		}
+	}
+	if _, err := doc.ApplyStyleToTag("{note}", "Missing"); err == nil {
+		t.Error("an unknown style must be an error")
+	}
+
+	body, _ := doc.ContentPart("document")
+	for _, want := range []string{
+		`<w:r><w:rPr><w:rStyle w:val="Total"/></w:rPr><w:t`,
+		`<w:pPr><w:pStyle w:val="Note"/><w:jc w:val="center"/></w:pPr>`,
+		`<w:tblPr><w:tblStyle w:val="Grid"/>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("no %s in\n%s", want, body)
+		}
+	}
+	if strings.Count(body, "tblStyle") != 1 {
+		t.Errorf("the table must be styled once:\n%s", body)
+	}
+
+	if err := doc.ExecuteTemplate(map[string]any{"total": "100", "note": "н", "name": "a", "price": "1"}); err != nil {
+		t.Fatal(err)
+	}
+}
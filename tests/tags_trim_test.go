@@ -229,3 +229,13 @@ func assertTrimBehavior(t *testing.T, src, out string, isTilde bool) {
 		}
 	}
 }
+
+// ProcessTrimTags runs on every part of every render, its patterns must not be compiled per call.
+func BenchmarkProcessTrimTags(b *testing.B) {
+	doc := &docxgen.Docx{}
+	body := `<w:p><w:r><w:t xml:space="preserve">Итого:   {~sum~}   руб.</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t xml:space="preserve">Дата {-date-} </w:t></w:r></w:p>`
+	for b.Loop() {
+		doc.ProcessTrimTags(body)
+	}
+}
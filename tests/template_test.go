@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"docxgen"
+	"docxgen/daemon"
+)
+
+func TestTemplateConcurrentRender(t *testing.T) {
+	doc := openTemplate(t, "<w:p><w:r><w:t>{name} {sum|money} {code|qrcode}</w:t></w:r></w:p>")
+	doc.AddModifier("shout", strings.ToUpper, 0)
+	tpl := docxgen.NewTemplate(doc)
+
+	// the template is a snapshot: the source document may go on
+	if err := doc.ExecuteTemplate(map[string]any{"name": "источник"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	results := make([]string, 16)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			out, err := tpl.Render(map[string]any{"name": fmt.Sprintf("клиент-%d", i), "sum": i * 100, "code": i})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			results[i], _ = out.ContentPart("document")
+			var buf bytes.Buffer
+			if err := out.SaveToWriter(&buf); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for i, xml := range results {
+		if !strings.Contains(xml, fmt.Sprintf("клиент-%d ", i)) || strings.Contains(xml, "источник") {
+			t.Errorf("render %d got foreign data: %s", i, xml)
+		}
+	}
+
+	// the template itself is not rendered
+	if xml, _ := tpl.Docx().ContentPart("document"); !strings.Contains(xml, "{name}") {
+		t.Errorf("the template changed: %s", xml)
+	}
+}
+
+func TestDaemonTemplateCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "act.docx")
+	writeDocx(t, path, map[string]string{
+		"word/document.xml": `<w:document><w:body><w:p><w:r><w:t>{name}</w:t></w:r></w:p></w:body></w:document>`,
+	})
+
+	var mu sync.Mutex
+	prepared := 0
+	h := daemon.NewHandler(daemon.Config{
+		Prepare: func(doc *docxgen.Docx) error {
+			mu.Lock()
+			prepared++
+			mu.Unlock()
+			return nil
+		},
+	})
+	generate := func(name string) string {
+		body, _ := json.Marshal(map[string]any{"template": path, "data": map[string]any{"name": name}, "format": "xml"})
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/generate", bytes.NewReader(body)))
+		if w.Code != 200 {
+			t.Errorf("status %d: %s", w.Code, w.Body.String())
+		}
+		return w.Body.String()
+	}
+
+	generate("первый")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("запрос-%d", i)
+			if out := generate(name); !strings.Contains(out, name) {
+				t.Errorf("%s: %s", name, out)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if prepared != 1 {
+		t.Errorf("the template file must be prepared once, got %d", prepared)
+	}
+
+	// a changed file is opened again
+	writeDocx(t, path, map[string]string{
+		"word/document.xml": `<w:document><w:body><w:p><w:r><w:t>Новый {name}</w:t></w:r></w:p></w:body></w:document>`,
+	})
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if out := generate("второй"); !strings.Contains(out, "Новый второй") || prepared != 2 {
+		t.Errorf("the changed template was not reopened (prepared %d): %s", prepared, out)
+	}
+}
@@ -96,3 +96,25 @@ func TestPhone_All(t *testing.T) {
 		})
 	}
 }
+
+func TestSpell(t *testing.T) {
+	cases := []struct {
+		in   string
+		opts []string
+		want string
+	}{
+		{"А-123", nil, "А дефис один два три"},
+		{"а-123", nil, "А дефис один два три"},
+		{"Б-70", []string{"ru"}, "бэ дефис семь ноль"},
+		{"BMW/7", []string{"ru+latin", ", "}, "би, эм, дабл-ю, косая черта, семь"},
+		// the examples of the doc comment: the Cyrillic Б is "бэ", the Latin B is "би"
+		{"БМW/7", []string{"ru+latin", ", "}, "бэ, эм, дабл-ю, косая черта, семь"},
+		{"а-123", []string{"ru"}, "а дефис один два три"},
+		{"12 34", nil, "один два три четыре"},
+	}
+	for _, c := range cases {
+		if got := modifiers.Spell(c.in, c.opts...); got != c.want {
+			t.Errorf("Spell(%q, %v) = %q, want %q", c.in, c.opts, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"docxgen"
+	"docxgen/daemon"
+	"docxgen/docxtest"
+)
+
+func TestTimingStages(t *testing.T) {
+	doc := docxtest.New().
+		Paragraph("{title}").
+		Paragraph("[table/goods]").
+		Table([]string{"{name}"}).
+		Paragraph("[/table]").
+		Open(t)
+	if doc.Timing().Unzip <= 0 {
+		t.Errorf("the open must be timed: %v", doc.Timing())
+	}
+
+	render := docxgen.NewTemplate(doc).Docx()
+	if render.Timing() != (docxgen.Timing{}) {
+		t.Errorf("a render of a template starts from zero: %v", render.Timing())
+	}
+	res, err := render.ExecuteTemplateResult(map[string]any{"title": "Акт", "goods": []any{map[string]any{"name": "Стол"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := render.SaveToWriter(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	timing := render.Timing()
+	if res.Timing.Save != 0 || timing.Save <= 0 {
+		t.Errorf("the save is timed after the render: %v, %v", res.Timing, timing)
+	}
+	for name, d := range map[string]time.Duration{"repair": timing.Repair, "tables": timing.Tables, "parse": timing.Parse, "execute": timing.Execute} {
+		if d <= 0 {
+			t.Errorf("no time for %s: %v", name, timing)
+		}
+	}
+	if timing.Total() < timing.Execute+timing.Save || !strings.HasSuffix(timing.String(), "total="+timing.Total().String()) {
+		t.Errorf("Total, String = %v, %s", timing.Total(), timing)
+	}
+
+	st := docxgen.Timing{Parse: 1500 * time.Microsecond, Execute: 2 * time.Millisecond}.ServerTiming()
+	if st != "parse;dur=1.50, execute;dur=2.00" {
+		t.Errorf("ServerTiming = %q", st)
+	}
+}
+
+func TestDaemonServerTiming(t *testing.T) {
+	var log bytes.Buffer
+	h := daemon.NewHandler(daemon.Config{
+		Logger: slog.New(slog.NewTextHandler(&log, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	})
+	body, _ := json.Marshal(map[string]any{
+		"template": templateBase64(t, `<w:p><w:r><w:t>{name}</w:t></w:r></w:p>`),
+		"data":     map[string]any{"name": "Оленька"},
+	})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/generate", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	if st := w.Header().Get("Server-Timing"); !strings.Contains(st, "execute;dur=") || !strings.Contains(st, "save;dur=") {
+		t.Errorf("Server-Timing = %q", st)
+	}
+	if !strings.Contains(log.String(), "render timing") || !strings.Contains(log.String(), "template=inline") {
+		t.Errorf("debug log = %s", log.String())
+	}
+}
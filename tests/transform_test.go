@@ -36,6 +36,11 @@ func TestTransformTemplate(t *testing.T) {
 		// необычные символы внутри литерала
 		{`{text|replace:` + "`a`:`б:в}г`" + `}`, `{.text | replace "a" "б:в}г"}`},
 
+		// цепочка модификаторов
+		{`{title|smallcaps|spacing:2}`, `{.title | smallcaps | spacing 2}`},
+		{`{warning|bold|color:` + "`FF0000`" + `}`, `{.warning | bold | color "FF0000"}`},
+		{`{fio|abbr|prefix:` + "`гражданин `" + `}`, `{.fio | abbr | prefix "гражданин "}`},
+
 		// готовый синтаксис (одинарные скобки) — не меняем
 		{`{.fio | prefix "ООО "}`, `{.fio | prefix "ООО "}`},
 		{`{.title | truncate 10 "..."}`, `{.title | truncate 10 "..."}`},
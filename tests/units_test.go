@@ -0,0 +1,37 @@
+package tests
+
+import (
+	"testing"
+
+	"docxgen/modifiers"
+)
+
+func TestUnit(t *testing.T) {
+	nn := modifiers.NNBSP
+
+	cases := []struct {
+		name string
+		v    any
+		unit string
+		opts []string
+		want string
+	}{
+		{"precision", 1250.5, "м²", []string{"2"}, "1 250,50" + nn + "м²"},
+		{"trim zeros", 15.0, "кг", nil, "15" + nn + "кг"},
+		{"kg to t", 1500, "кг", []string{"т"}, "1,5" + nn + "т"},
+		{"m2 to ha", "1250", "м2", []string{"га", "2"}, "0,13" + nn + "га"},
+		{"words", 15, "кг", []string{"словами"}, "15" + nn + "килограммов"},
+		{"words fraction", 1500, "кг", []string{"т", "words"}, "1,5" + nn + "тонны"},
+		{"words one", 21, "шт", []string{"words"}, "21" + nn + "штука"},
+		{"other dimension ignored", 3, "м", []string{"кг"}, "3" + nn + "м"},
+		{"unknown unit", 7, "пачек", nil, "7" + nn + "пачек"},
+		{"not a number", "много", "кг", nil, "много кг"},
+	}
+
+	for _, c := range cases {
+		got := modifiers.Unit(c.v, c.unit, c.opts...)
+		if got != c.want {
+			t.Errorf("%s: Unit(%v, %q, %v) = %q, want %q", c.name, c.v, c.unit, c.opts, got, c.want)
+		}
+	}
+}
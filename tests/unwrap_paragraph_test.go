@@ -2,6 +2,7 @@ package tests
 
 import (
 	"docxgen"
+	"fmt"
 	"strings"
 	"testing"
 )
@@ -121,3 +122,58 @@ func TestReplaceTagWithParagraph(t *testing.T) {
 		})
 	}
 }
+
+func TestReplaceTagsWithParagraphs(t *testing.T) {
+	input := `<w:body>` +
+		`<w:p><w:r><w:t>{a}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>до {b} между {a} после</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>без тегов</w:t></w:r></w:p>` +
+		`</w:body>`
+	out := docxgen.ReplaceTagsWithParagraphs(input, map[string]string{"{a}": "<A/>", "{b}": "<B/>"})
+	want := `<w:body><A/>` +
+		`<w:p><w:r><w:t xml:space="preserve">до</w:t></w:r></w:p><B/>` +
+		`<w:p><w:r><w:t xml:space="preserve">между</w:t></w:r></w:p><A/>` +
+		`<w:p><w:r><w:t xml:space="preserve">после</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>без тегов</w:t></w:r></w:p></w:body>`
+	if out != want {
+		t.Errorf("unexpected output:\n got:  %s\n want: %s", out, want)
+	}
+	if out := docxgen.ReplaceTagsWithParagraphs(input, nil); out != input {
+		t.Errorf("no replacements changed the body: %s", out)
+	}
+}
+
+func TestProcessUnWrapParagraphTagsMany(t *testing.T) {
+	d := &docxgen.Docx{}
+	body := `<w:p><w:r><w:t>{*first*}</w:t></w:r></w:p><w:p><w:r><w:t>{* second *}</w:t></w:r></w:p>`
+	if out := d.ProcessUnWrapParagraphTags(body); out != `{first}{second}` {
+		t.Errorf("unexpected output: %s", out)
+	}
+}
+
+func BenchmarkReplaceTagsWithParagraphs(b *testing.B) {
+	var sb strings.Builder
+	tags := map[string]string{}
+	for i := range 2000 {
+		fmt.Fprintf(&sb, `<w:p><w:r><w:t>paragraph %d</w:t></w:r></w:p>`, i)
+		if i%20 == 0 {
+			tag := fmt.Sprintf("{*t%d*}", i)
+			fmt.Fprintf(&sb, `<w:p><w:r><w:t>%s</w:t></w:r></w:p>`, tag)
+			tags[tag] = fmt.Sprintf("{t%d}", i)
+		}
+	}
+	body := sb.String()
+	b.Run("single pass", func(b *testing.B) {
+		for b.Loop() {
+			docxgen.ReplaceTagsWithParagraphs(body, tags)
+		}
+	})
+	b.Run("tag by tag", func(b *testing.B) {
+		for b.Loop() {
+			s := body
+			for tag, content := range tags {
+				s = docxgen.ReplaceTagWithParagraph(s, tag, content)
+			}
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNativeValueTypes(t *testing.T) {
+	var data map[string]any
+	dec := json.NewDecoder(strings.NewReader(`{"sum": 1234.5, "count": 5, "day": 14, "unix": 1760400000, "title": "Очень длинный заголовок"}`))
+	dec.UseNumber()
+	if err := dec.Decode(&data); err != nil {
+		t.Fatal(err)
+	}
+	date := time.Date(2025, 10, 14, 0, 0, 0, 0, time.UTC)
+	data["date"] = date
+	data["meeting"] = time.Date(2025, 10, 14, 9, 30, 0, 0, time.UTC)
+	data["big"], _ = new(big.Int).SetString("1234567", 10)
+	data["ratio"] = big.NewFloat(0.125)
+	data["items"] = []any{map[string]any{"when": date, "n": json.Number("2")}}
+
+	cases := []struct{ tag, want string }{
+		{"{sum|money}", "1 234,50"},
+		{"{count|numeral}", "пять"},
+		{"{count|plural:`день`:`дня`:`дней`}", "дней"},
+		{"{day|date_ordinal:`род`:`числа`}", "14-го числа"},
+		{"{unix|date_format:`02.01.2006`}", "14.10.2025"},
+		{"{big|money:`int`}", "1 234 567"},
+		{"{ratio}", "0.125"},
+		{"{date}", "14.10.2025"},
+		{"{meeting}", "14.10.2025 09:30"},
+		{"{date|date_format:`2006-01-02`}", "2025-10-14"},
+		{"{date|prefix:`от `}", "от 14.10.2025"},
+		{"{sum|prefix:`= `}", "= 1234.5"},
+	}
+	for _, c := range cases {
+		doc := openTemplate(t, "<w:p><w:r><w:t>"+c.tag+"</w:t></w:r></w:p>")
+		doc.SetStrictTypes(true)
+		if err := doc.ExecuteTemplate(data); err != nil {
+			t.Errorf("%s: %v", c.tag, err)
+			continue
+		}
+		xml, _ := doc.ContentPart("document")
+		if !strings.Contains(xml, "<w:t>"+c.want+"</w:t>") {
+			t.Errorf("%s: want %q in %s", c.tag, c.want, xml)
+		}
+	}
+
+	// smart table and [for] rows get the date in a form date_format understands
+	body := "<w:p><w:r><w:t>[for items]</w:t></w:r></w:p>" +
+		"<w:p><w:r><w:t>{when|date_format:`02.01.2006 15:04`} × {n}</w:t></w:r></w:p>" +
+		"<w:p><w:r><w:t>[/for]</w:t></w:r></w:p>"
+	got := paragraphs(t, body, data)
+	if len(got) != 1 || got[0] != "14.10.2025 00:00 × 2" {
+		t.Errorf("for block: %q", got)
+	}
+
+	// a missing key prints as before
+	doc := openTemplate(t, "<w:p><w:r><w:t>{nothing}</w:t></w:r></w:p>")
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatal(err)
+	}
+	if xml, _ := doc.ContentPart("document"); !strings.Contains(xml, "<w:t><no value></w:t>") {
+		t.Errorf("missing key: %s", xml)
+	}
+}
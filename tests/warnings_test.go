@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestExecuteTemplateResult(t *testing.T) {
+	collect := func(body string, data map[string]any) []string {
+		t.Helper()
+		res, err := openTemplate(t, body).ExecuteTemplateResult(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, w := range res.Warnings {
+			if w.Part != "document" || w.Message == "" {
+				t.Errorf("incomplete warning: %+v", w)
+			}
+			got = append(got, fmt.Sprintf("%s:%s", w.Kind, w.Name))
+		}
+		return got
+	}
+	table := `<w:p><w:r><w:t>[table/items]</w:t></w:r></w:p>` +
+		`<w:tbl><w:tr><w:tc><w:p><w:r><w:t>{title} {qty}</w:t></w:r></w:p></w:tc></w:tr></w:tbl>` +
+		`<w:p><w:r><w:t>[/table]</w:t></w:r></w:p>`
+
+	cases := []struct {
+		name string
+		body string
+		data map[string]any
+		want []string
+	}{
+		{"clean", table, map[string]any{"items": []map[string]any{{"title": "a", "qty": 1}}}, nil},
+		{"unmatched items", table, map[string]any{"items": []map[string]any{{"price": 5}}}, []string{"empty_table:items"}},
+		{"no data", table, map[string]any{}, []string{"empty_table:items"}},
+		{"not a list", table, map[string]any{"items": 42}, []string{"empty_table:items"}},
+		{"include", `<w:p><w:r><w:t>[include/missing.docx]</w:t></w:r></w:p>`, nil, []string{"empty_include:missing.docx"}},
+		{"modifier", `<w:p><w:r><w:t>{name|shout} {name|shout}</w:t></w:r></w:p>`, map[string]any{"name": "Иван"}, []string{"unknown_modifier:shout"}},
+	}
+	for _, c := range cases {
+		if got := collect(c.body, c.data); fmt.Sprint(got) != fmt.Sprint(c.want) {
+			t.Errorf("%s: warnings = %v, want %v", c.name, got, c.want)
+		}
+	}
+
+	doc := openTemplate(t, `<w:p><w:r><w:t>{name|shout}</w:t></w:r></w:p>`)
+	if _, err := doc.ExecuteTemplateResult(map[string]any{"name": "Иван"}); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := doc.ContentPart("document"); !strings.Contains(content, "Иван") {
+		t.Errorf("an unknown modifier must output the value:\n%s", content)
+	}
+
+	// ExecuteTemplate stays strict about unknown modifiers
+	if err := openTemplate(t, `<w:p><w:r><w:t>{name|shout}</w:t></w:r></w:p>`).ExecuteTemplate(nil); err == nil {
+		t.Error("expected an error for the unknown modifier")
+	}
+}
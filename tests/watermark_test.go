@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"docxgen"
+	"docxgen/docxtest"
+)
+
+func TestSetWatermark(t *testing.T) {
+	doc := docxtest.New().Paragraph("Договор").Header(`<w:p><w:r><w:t>ООО «Ромашка»</w:t></w:r></w:p>`).Open(t)
+	doc.SetWatermark("DRAFT", docxgen.WatermarkOptions{})
+	doc.SetWatermark("КОПИЯ", docxgen.WatermarkOptions{Color: "#FF0000", Opacity: 0.3})
+
+	header, _ := doc.ContentPart("header1")
+	if strings.Count(header, "PowerPlusWaterMarkObject") != 1 || !strings.Contains(header, `string="КОПИЯ"`) ||
+		!strings.Contains(header, `fillcolor="#FF0000"`) || !strings.Contains(header, `<v:fill opacity="0.3"/>`) {
+		t.Errorf("the watermark must be replaced:\n%s", header)
+	}
+	if !strings.Contains(header, `xmlns:v="urn:schemas-microsoft-com:vml"`) || !strings.Contains(header, "ООО «Ромашка»") {
+		t.Errorf("the header must keep its text and get the VML namespaces:\n%s", header)
+	}
+
+	doc.SetWatermark("", docxgen.WatermarkOptions{})
+	header, _ = doc.ContentPart("header1")
+	if strings.Contains(header, "PowerPlusWaterMarkObject") || strings.Contains(header, "<w:p></w:p>") {
+		t.Errorf("an empty text must remove the watermark:\n%s", header)
+	}
+	if got := strings.Join(paragraphTexts(header), "|"); got != "ООО «Ромашка»" {
+		t.Errorf("header texts: %s", got)
+	}
+}
+
+func TestSetImageWatermarkAddsHeader(t *testing.T) {
+	doc := docxtest.New().Paragraph("Договор").Open(t)
+	if err := doc.SetImageWatermark([]byte("not a picture"), 0.3); err == nil {
+		t.Error("a broken picture must be an error")
+	}
+	if err := doc.SetImageWatermark(docxtest.PNG(2000, 500), 0.3); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(doc.ListHeaderFooterParts(), ","); got != "header1" {
+		t.Fatalf("a document without headers must get one: %q", got)
+	}
+
+	files := zipParts(t, doc)
+	header := files["word/header1.xml"]
+	if !strings.Contains(header, `behindDoc="1"`) || !strings.Contains(header, `<a:alphaModFix amt="30000"/>`) {
+		t.Errorf("image watermark:\n%s", header)
+	}
+	// 2000 px do not fit: the picture takes the width of the text (175 mm of A4 with 20 mm and 15 mm margins)
+	if !strings.Contains(header, `<wp:extent cx="6300470" cy="1575117"/>`) {
+		t.Errorf("the picture must fit the margins:\n%s", header)
+	}
+	if _, ok := files["word/media/watermark1.png"]; !ok || !strings.Contains(files["word/_rels/header1.xml.rels"], "media/watermark1.png") {
+		t.Error("the picture must be stored and related to the header")
+	}
+	if !strings.Contains(files["[Content_Types].xml"], `PartName="/word/header1.xml"`) ||
+		!strings.Contains(files["word/document.xml"], `<w:sectPr><w:headerReference w:type="default" r:id="`) {
+		t.Error("the new header must be registered and referenced by the section")
+	}
+}
@@ -0,0 +1,96 @@
+package docxgen
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// Timing: where the time of a render goes, by the stages of the hot path
+// ============================================================================
+
+// Timing - the time a document spent in the stages of the engine, since it was opened (or cloned
+// from a Template: the open of the template is not part of its renders). The middleware (Use) is
+// not counted in the stages.
+//   - Unzip — reading the archive and the open options;
+//   - Repair — the repair of the split tags, the merge fields, {*tag*} and the trim markers;
+//   - Includes — [include/...] and [annex/...];
+//   - Tables — the blocks, the sections, the smart tables, the pivots, the calendars, the charts,
+//     the signers and the requisites;
+//   - Parse — the conversion to the Go template and its parse, the format rules;
+//   - Execute — the execution of the template and the content controls;
+//   - Save — packing the archive (Save, SaveToWriter);
+//   - PDF — the conversion to PDF, filled by the caller that converts (the daemon).
+type Timing struct {
+	Unzip    time.Duration
+	Repair   time.Duration
+	Includes time.Duration
+	Tables   time.Duration
+	Parse    time.Duration
+	Execute  time.Duration
+	Save     time.Duration
+	PDF      time.Duration
+}
+
+// Timing returns the stage times of the document so far.
+//
+// Example:
+//
+//	doc := tpl.Docx()
+//	_ = doc.ExecuteTemplate(data)
+//	_ = doc.SaveToWriter(w)
+//	log.Printf("render: %v", doc.Timing()) // unzip=0s repair=1.2ms includes=0s tables=4ms ...
+func (d *Docx) Timing() Timing {
+	return d.timing
+}
+
+// Total - the sum of the stages.
+func (t Timing) Total() time.Duration {
+	var total time.Duration
+	for _, s := range t.stages() {
+		total += s.d
+	}
+	return total
+}
+
+// String - "unzip=1ms repair=2.5ms ... total=10ms".
+func (t Timing) String() string {
+	var b strings.Builder
+	for _, s := range t.stages() {
+		fmt.Fprintf(&b, "%s=%v ", s.name, s.d)
+	}
+	fmt.Fprintf(&b, "total=%v", t.Total())
+	return b.String()
+}
+
+// ServerTiming - the value of the Server-Timing HTTP header, in milliseconds, the stages that took time:
+// "repair;dur=1.2, tables;dur=4.05, execute;dur=2.1".
+func (t Timing) ServerTiming() string {
+	var metrics []string
+	for _, s := range t.stages() {
+		if s.d > 0 {
+			metrics = append(metrics, fmt.Sprintf("%s;dur=%.2f", s.name, float64(s.d)/float64(time.Millisecond)))
+		}
+	}
+	return strings.Join(metrics, ", ")
+}
+
+// stageTime - a stage of Timing with its name.
+type stageTime struct {
+	name string
+	d    time.Duration
+}
+
+// stages - the stages with their names, in the order of a render.
+func (t Timing) stages() []stageTime {
+	return []stageTime{
+		{"unzip", t.Unzip}, {"repair", t.Repair}, {"includes", t.Includes}, {"tables", t.Tables},
+		{"parse", t.Parse}, {"execute", t.Execute}, {"save", t.Save}, {"pdf", t.PDF},
+	}
+}
+
+// since adds the time since start to the stage: defer since(&d.timing.Save, time.Now()).
+func since(stage *time.Duration, start time.Time) {
+	*stage += time.Since(start)
+}
@@ -6,20 +6,32 @@ import (
 )
 
 // transformTag gets a string like {fio|declension:`genitive`:`ф: и }о`}
-// and converts it to {.fio | declension "genitive" "ф: и }о"}
+// and converts it to {.fio | declension "genitive" "ф: и }о"}.
+// Every "|" outside the `...` literals starts the next modifier of the chain:
+// {title|smallcaps|spacing:2} → {.title | smallcaps | spacing 2}
 func transformTag(tag string) string {
 	tag = strings.TrimSuffix(strings.TrimPrefix(tag, "{"), "}")
 
-	var parts []string
+	// stages[0] is the value, every next stage is a modifier with its arguments
+	stages := [][]string{nil}
 	var buf strings.Builder
 	inQuote := false
 
+	flush := func() {
+		if strings.TrimSpace(buf.String()) != "" {
+			last := len(stages) - 1
+			stages[last] = append(stages[last], buf.String())
+		}
+		buf.Reset()
+	}
+
 	for _, r := range tag {
 		switch r {
 		case '`':
 			if inQuote {
 				// closed literal
-				parts = append(parts, `"`+buf.String()+`"`)
+				last := len(stages) - 1
+				stages[last] = append(stages[last], `"`+buf.String()+`"`)
 				buf.Reset()
 				inQuote = false
 			} else {
@@ -30,63 +42,89 @@ func transformTag(tag string) string {
 		case '|', ':':
 			if inQuote {
 				buf.WriteRune(r)
-			} else {
-				if buf.Len() > 0 {
-					parts = append(parts, buf.String())
-					buf.Reset()
-				}
+				continue
+			}
+			flush()
+			if r == '|' {
+				stages = append(stages, nil)
 			}
 		default:
 			buf.WriteRune(r)
 		}
 	}
-	if buf.Len() > 0 {
-		if inQuote {
-			parts = append(parts, `"`+buf.String()+`"`)
-		} else {
-			parts = append(parts, buf.String())
-		}
+	if inQuote {
+		last := len(stages) - 1
+		stages[last] = append(stages[last], `"`+buf.String()+`"`)
+	} else {
+		flush()
 	}
 
-	if len(parts) == 0 {
-		return "{}"
+	if len(stages[0]) == 0 {
+		if len(stages) == 1 || len(stages[1]) == 0 {
+			return "{}"
+		}
+		// {|mod} without a value: the first modifier name takes its place, as before
+		stages = stages[1:]
 	}
 
 	out := new(strings.Builder)
-	out.WriteString("{.")
-	out.WriteString(strings.TrimSpace(parts[0]))
-
-	if len(parts) > 1 {
+	if first := strings.TrimSpace(stages[0][0]); strings.HasPrefix(first, `"`) {
+		// a literal value: { `14.10.2025` | date_format:`2006` } of the smart table rows
+		out.WriteString("{")
+		out.WriteString(strconv.Quote(strings.TrimSuffix(strings.TrimPrefix(first, `"`), `"`)))
+	} else {
+		out.WriteString("{.")
+		out.WriteString(first)
+	}
+	// arguments written next to the value (no "|" before them) go to the first modifier, as before
+	rest := stages[0][1:]
+	for _, st := range stages[1:] {
+		if len(rest) > 0 {
+			st = append(rest, st...)
+			rest = nil
+		}
+		if len(st) == 0 {
+			continue
+		}
 		out.WriteString(" | ")
-		out.WriteString(strings.TrimSpace(parts[1]))
-		for _, arg := range parts[2:] {
-			// If there is already a line (we marked it this way above) → insert it as it is.
-			if strings.HasPrefix(arg, `"`) && strings.HasSuffix(arg, `"`) {
-				out.WriteString(" ")
-				out.WriteString(arg)
-				continue
-			}
-			// if the number leave as it is
-			if _, err := strconv.ParseFloat(arg, 64); err == nil {
-				out.WriteString(" ")
-				out.WriteString(arg)
-				continue
-			}
-			// Everything else → line
+		out.WriteString(strings.TrimSpace(st[0]))
+		for _, arg := range st[1:] {
 			out.WriteString(" ")
-			out.WriteString(`"`)
-			out.WriteString(arg)
-			out.WriteString(`"`)
+			out.WriteString(modifierArg(arg))
 		}
 	}
 	out.WriteString("}")
 	return out.String()
 }
 
+// modifierArg writes one modifier argument of the old syntax in the Go template syntax.
+func modifierArg(arg string) string {
+	// If there is already a line (we marked it this way in transformTag) → insert it as it is.
+	if strings.HasPrefix(arg, `"`) && strings.HasSuffix(arg, `"`) {
+		return arg
+	}
+	// if the number leave as it is
+	if _, err := strconv.ParseFloat(arg, 64); err == nil {
+		return arg
+	}
+	// Everything else → line
+	return `"` + arg + `"`
+}
+
 // TransformTemplate bypasses all the text of the document and converts the old {tag|mod:arg}
 // into the valid syntax of Go templates. Ready-made Go tags ({.fio ...}, {if ...}, etc.)
 // leaves unchanged.
 func TransformTemplate(input string) string {
+	return rewriteTags(input, func(tag string) string {
+		if looksLikeOldStyle(tag) {
+			return transformTag(tag)
+		}
+		return tag
+	})
+}
+
+// rewriteTags replaces every {...} of the text with fn(tag); "}" inside `...` does not close the tag.
+func rewriteTags(input string, fn func(tag string) string) string {
 	var out strings.Builder
 	var token strings.Builder
 	inTag := false
@@ -112,12 +150,7 @@ func TransformTemplate(input string) string {
 			if inTag && !inQuote {
 				// finished tag
 				token.WriteRune(r)
-				tok := token.String()
-				if looksLikeOldStyle(tok) {
-					out.WriteString(transformTag(tok))
-				} else {
-					out.WriteString(tok)
-				}
+				out.WriteString(fn(token.String()))
 				inTag = false
 			} else {
 				// either plain text or inside quotation marks
@@ -149,9 +182,12 @@ func looksLikeOldStyle(tag string) bool {
 	if strings.HasPrefix(t, ".") {
 		return false
 	}
+	// a literal with old-style arguments: { `value` | mod:`arg` }
+	if strings.HasPrefix(t, "`") {
+		return hasOldStyleArgs(t)
+	}
 	// Do NOT touch Go-expressions
 	if strings.HasPrefix(t, ".") ||
-		strings.HasPrefix(t, "`") ||
 		strings.HasPrefix(t, "\"") {
 		return false
 	}
@@ -164,3 +200,17 @@ func looksLikeOldStyle(tag string) bool {
 	// or with a modifier in |
 	return !strings.HasPrefix(t, ".")
 }
+
+// hasOldStyleArgs reports whether the tag passes modifier arguments with ":" outside the `...` literals.
+func hasOldStyleArgs(t string) bool {
+	inQuote := false
+	for _, r := range t {
+		switch {
+		case r == '`':
+			inQuote = !inQuote
+		case r == ':' && !inQuote:
+			return true
+		}
+	}
+	return false
+}
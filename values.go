@@ -0,0 +1,112 @@
+package docxgen
+
+import (
+	"math/big"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"docxgen/modifiers"
+)
+
+// valueTextFunc - the template function that writes the value of a plain tag like the modifiers do.
+const valueTextFunc = "_value"
+
+// applyValueText appends the value function to every output tag without modifiers: {.date} → {.date | _value},
+// so that a date prints as "14.10.2025" and not as Go prints time.Time.
+func applyValueText(tree *parse.Tree) {
+	if tree == nil {
+		return
+	}
+	walkOutputFields(tree, func(n *parse.ActionNode, field *parse.FieldNode) {
+		if len(n.Pipe.Cmds) != 1 || len(n.Pipe.Cmds[0].Args) != 1 {
+			return
+		}
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      n.Pos,
+			Args:     []parse.Node{parse.NewIdentifier(valueTextFunc).SetTree(tree).SetPos(n.Pos)},
+		})
+	})
+}
+
+// chainTextFunc - the template function between two modifiers of a chain.
+const chainTextFunc = "_text"
+
+// applyChainText puts the text function between the chained modifiers of every output tag:
+// {.a | prefix "x" | bold} → {.a | prefix "x" | _text | bold}. A string modifier escapes its result
+// for the document, so the next one gets the text back and escapes it only once.
+// The conditional formatting (_format) counts as the next modifier.
+func applyChainText(tree *parse.Tree, funcs template.FuncMap) {
+	if tree == nil {
+		return
+	}
+	isModifier := func(c *parse.CommandNode, internal string) bool {
+		if len(c.Args) == 0 {
+			return false
+		}
+		id, ok := c.Args[0].(*parse.IdentifierNode)
+		if !ok {
+			return false
+		}
+		if id.Ident == internal {
+			return true
+		}
+		_, ok = funcs[id.Ident]
+		return ok && !strings.HasPrefix(id.Ident, "_")
+	}
+	walkActions(tree, func(n *parse.ActionNode) {
+		cmds := n.Pipe.Cmds
+		out := make([]*parse.CommandNode, 0, len(cmds))
+		for i, c := range cmds {
+			if i > 1 && isModifier(cmds[i-1], "") && isModifier(c, formatRuleFunc) {
+				out = append(out, &parse.CommandNode{
+					NodeType: parse.NodeCommand,
+					Pos:      c.Pos,
+					Args:     []parse.Node{parse.NewIdentifier(chainTextFunc).SetTree(tree).SetPos(c.Pos)},
+				})
+			}
+			out = append(out, c)
+		}
+		n.Pipe.Cmds = out
+	})
+}
+
+// chainText - the text of the result of a string modifier (see modifiers.UnescapeForWord),
+// the other results are passed as they are.
+func chainText(v any) any {
+	if s, ok := v.(string); ok {
+		return modifiers.UnescapeForWord(s)
+	}
+	return v
+}
+
+// plainValue - dates and big fractions become text (see modifiers.ValueText),
+// the other values are printed by the template as before.
+func plainValue(v any) any {
+	switch v.(type) {
+	case nil:
+		// what the template prints for a missing key without the function
+		return "<no value>"
+	case time.Time, *time.Time, *big.Float, *big.Rat:
+		return modifiers.ValueText(v)
+	}
+	return v
+}
+
+// literalText - a data value as the backtick literal of a modifier call in a smart table or [for]:
+// a date keeps its full time for date_format.
+func literalText(v any) string {
+	switch t := v.(type) {
+	case time.Time:
+		if !t.IsZero() {
+			return t.Format(time.RFC3339Nano)
+		}
+	case *time.Time:
+		if t != nil && !t.IsZero() {
+			return t.Format(time.RFC3339Nano)
+		}
+	}
+	return modifiers.ValueText(v)
+}
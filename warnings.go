@@ -0,0 +1,132 @@
+package docxgen
+
+import (
+	"fmt"
+	"regexp"
+	"text/template"
+)
+
+// WarningKind - the kind of a non-fatal issue of the render.
+type WarningKind string
+
+const (
+	// WarnUnknownModifier - the modifier is not registered, the tag output its value unchanged.
+	WarnUnknownModifier WarningKind = "unknown_modifier"
+	// WarnEmptyInclude - [include/...] gave nothing: the file is missing, broken or has no body.
+	WarnEmptyInclude WarningKind = "empty_include"
+	// WarnEmptyTable - the smart table [table/name] got no rows: no data, not a list, or no item matched a row.
+	WarnEmptyTable WarningKind = "empty_table"
+	// WarnUnmatchedItem - an item of the smart table fits none of its rows (see the unmatched option).
+	WarnUnmatchedItem WarningKind = "unmatched_item"
+	// WarnEmptyPivot - the pivot table [pivot/name ...] has no data, no records or a broken marker and was removed.
+	WarnEmptyPivot WarningKind = "empty_pivot"
+	// WarnBadCalendar - the calendar block [calendar/month ...] has no month it can read or its events are not a list.
+	WarnBadCalendar WarningKind = "bad_calendar"
+	// WarnBadGantt - the timeline [gantt/name ...] has a broken marker, no list of tasks or no dates and was removed.
+	WarnBadGantt WarningKind = "bad_gantt"
+	// WarnBadOrgChart - the hierarchy [orgchart/name ...] has a broken marker or no tree of maps and was removed.
+	WarnBadOrgChart WarningKind = "bad_orgchart"
+	// WarnEmptySigners - the approval sheet [signers/name] has a broken marker or no list of signers and was removed.
+	WarnEmptySigners WarningKind = "empty_signers"
+	// WarnBadRequisites - the requisites [requisites/a:b] have a broken marker or a party that is not a map.
+	WarnBadRequisites WarningKind = "bad_requisites"
+	// WarnEmptyLoop - the block [for name] got no data or not a list and was removed.
+	WarnEmptyLoop WarningKind = "empty_loop"
+	// WarnBrokenBlock - a [for]/[if] marker without its pair, the marker was removed.
+	WarnBrokenBlock WarningKind = "broken_block"
+	// WarnBadSection - a [section ...] marker without its pair or with options it cannot read, the markers were removed.
+	WarnBadSection WarningKind = "broken_section"
+	// WarnLossyCoercion - an argument of the modifier did not fit its parameter and was changed ("abc" → 0).
+	WarnLossyCoercion WarningKind = "lossy_coercion"
+	// WarnUnknownOption - the date arithmetic modifier (add_days, next_workday, add_months) got an option
+	// it does not know and ignored it.
+	WarnUnknownOption WarningKind = "unknown_option"
+	// WarnBadImage - the image modifier could not load or decode the picture, nothing was inserted.
+	WarnBadImage WarningKind = "bad_image"
+	// WarnBadLink - the link modifier got an address it does not link (javascript:, a bare word), the text was output plainly.
+	WarnBadLink WarningKind = "bad_link"
+	// WarnBadChart - the chart modifier got no numbers to draw, nothing was inserted.
+	WarnBadChart WarningKind = "bad_chart"
+	// WarnBadHTML - the html modifier could not read the markup to the end, the rest of the fragment was dropped.
+	WarnBadHTML WarningKind = "bad_html"
+)
+
+// Warning - a non-fatal issue of the render that the user may want to see.
+//   - Kind — what happened;
+//   - Part — "document", "header1", ...;
+//   - Name — the modifier, the include file, the table or the block;
+//   - Message — the details in plain words.
+type Warning struct {
+	Kind    WarningKind
+	Part    string
+	Name    string
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s %q: %s", w.Part, w.Kind, w.Name, w.Message)
+}
+
+// RenderResult - the outcome of ExecuteTemplateResult.
+//   - Warnings — the non-fatal issues of the render;
+//   - Timing — the stage times of the document up to the end of the render (no Save and PDF yet).
+type RenderResult struct {
+	Warnings []Warning
+	Timing   Timing
+}
+
+// ExecuteTemplateResult executes the template like ExecuteTemplate and collects the non-fatal issues.
+// It is more lenient: an unknown modifier is skipped with a warning instead of failing the render.
+//
+// Example:
+//
+//	res, err := doc.ExecuteTemplateResult(data)
+//	for _, w := range res.Warnings {
+//		log.Println("warning:", w)
+//	}
+func (d *Docx) ExecuteTemplateResult(data map[string]any) (RenderResult, error) {
+	var warnings []Warning
+	d.warnings = &warnings
+	defer func() { d.warnings = nil }()
+
+	err := d.ExecuteTemplate(data)
+	return RenderResult{Warnings: warnings, Timing: d.timing}, err
+}
+
+// warn records the warning when they are collected (ExecuteTemplateResult).
+func (d *Docx) warn(kind WarningKind, name, format string, a ...any) {
+	if d.warnings == nil {
+		return
+	}
+	*d.warnings = append(*d.warnings, Warning{Kind: kind, Part: d.activePart, Name: name, Message: fmt.Sprintf(format, a...)})
+}
+
+var reUndefinedFunc = regexp.MustCompile(`function "([^"]+)" not defined`)
+
+// parsePart parses the template of the part; while warnings are collected, unknown modifiers
+// are replaced with a pass-through of the value.
+func (d *Docx) parsePart(text string, funcs template.FuncMap) (*template.Template, error) {
+	for {
+		tmpl, err := parseTemplate(text, funcs)
+		if err == nil || d.warnings == nil {
+			return tmpl, err
+		}
+		m := reUndefinedFunc.FindStringSubmatch(err.Error())
+		if m == nil {
+			return nil, err
+		}
+		if _, ok := funcs[m[1]]; ok {
+			return nil, err
+		}
+		funcs[m[1]] = passThrough
+		d.warn(WarnUnknownModifier, m[1], "the modifier is not registered, the value is output unchanged")
+	}
+}
+
+// passThrough - the stand-in of an unknown modifier: the pipeline value is the last argument.
+func passThrough(args ...any) any {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[len(args)-1]
+}
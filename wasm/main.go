@@ -0,0 +1,96 @@
+//go:build js && wasm
+
+// Command wasm is the browser build of docxgen: the page fills a template itself, for a preview,
+// and the data never leaves the browser.
+//
+//	GOOS=js GOARCH=wasm go build -o docxgen.wasm ./wasm
+//	cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" .
+//
+// The module registers the global function
+//
+//	docxgenRender(template, data, files, fonts) → {docx: Uint8Array} | {error: string}
+//
+// with the arguments:
+//   - template — the DOCX as a Uint8Array;
+//   - data — the data as a JSON string;
+//   - files — the files next to the template for [include/...] and the pictures by path,
+//     {"blocks/sign.docx": Uint8Array} (optional);
+//   - fonts — the TTF of p_split, {regular, bold, italic, boldItalic: Uint8Array} (optional).
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"syscall/js"
+	"testing/fstest"
+
+	"docxgen"
+	"docxgen/metrics"
+)
+
+func main() {
+	js.Global().Set("docxgenRender", js.FuncOf(func(this js.Value, args []js.Value) any {
+		out, err := render(args)
+		if err != nil {
+			return map[string]any{"error": err.Error()}
+		}
+		docx := js.Global().Get("Uint8Array").New(len(out))
+		js.CopyBytesToJS(docx, out)
+		return map[string]any{"docx": docx}
+	}))
+	select {} // the functions live as long as the page
+}
+
+// render fills the template of the arguments of docxgenRender.
+func render(args []js.Value) ([]byte, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("docxgenRender(template, data[, files[, fonts]])")
+	}
+	var opts []docxgen.OpenOption
+	if len(args) > 2 && args[2].Type() == js.TypeObject {
+		files := fstest.MapFS{}
+		keys := js.Global().Get("Object").Call("keys", args[2])
+		for i := 0; i < keys.Length(); i++ {
+			name := keys.Index(i).String()
+			files[name] = &fstest.MapFile{Data: bytesOf(args[2].Get(name))}
+		}
+		opts = append(opts, docxgen.WithFS(files))
+	}
+	doc, err := docxgen.OpenBytes(bytesOf(args[0]), opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) > 3 && args[3].Type() == js.TypeObject {
+		f := args[3]
+		fonts, err := metrics.ParseFonts(bytesOf(f.Get("regular")), bytesOf(f.Get("bold")),
+			bytesOf(f.Get("italic")), bytesOf(f.Get("boldItalic")))
+		if err != nil {
+			return nil, err
+		}
+		doc.SetFonts(fonts)
+	}
+
+	data := map[string]any{}
+	if err := json.Unmarshal([]byte(args[1].String()), &data); err != nil {
+		return nil, fmt.Errorf("data: %w", err)
+	}
+	if err := doc.ExecuteTemplate(data); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := doc.SaveToWriter(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// bytesOf copies a Uint8Array into Go; anything else is empty.
+func bytesOf(v js.Value) []byte {
+	if v.Type() != js.TypeObject || v.Get("length").Type() != js.TypeNumber {
+		return nil
+	}
+	b := make([]byte, v.Get("length").Int())
+	js.CopyBytesToGo(b, v)
+	return b
+}
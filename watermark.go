@@ -0,0 +1,230 @@
+package docxgen
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"docxgen/geometry"
+)
+
+// ============================================================================
+// Watermarks ("КОПИЯ", "DRAFT", a logo) behind the text of every page, in the headers
+// ============================================================================
+
+// WatermarkOptions - the look of a text watermark, the zero value gives Word's default one.
+//   - Font — the font family, "Calibri" by default;
+//   - Color — the hex color ("C0C0C0", silver by default);
+//   - Opacity — from 0 to 1, 0 is 0.5 (semitransparent);
+//   - Horizontal — across the page instead of the diagonal.
+type WatermarkOptions struct {
+	Font       string
+	Color      string
+	Opacity    float64
+	Horizontal bool
+}
+
+const (
+	// watermarkTextID, watermarkImageName - the prefixes Word gives the watermark shapes; by them Word
+	// recognizes a watermark ("Remove Watermark") and docxgen replaces its own and Word's ones.
+	watermarkTextID    = "PowerPlusWaterMarkObject"
+	watermarkImageName = "WordPictureWatermark"
+	// watermarkNamespaces - the namespaces the watermark markup needs on the root of the header.
+	watermarkNamespaces = ` xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"` +
+		` xmlns:v="urn:schemas-microsoft-com:vml" xmlns:o="urn:schemas-microsoft-com:office:office"` +
+		` xmlns:w10="urn:schemas-microsoft-com:office:word"` +
+		` xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing"`
+)
+
+var reHeaderRoot = regexp.MustCompile(`<w:hdr\b[^>]*>`)
+
+// SetWatermark puts the text as a watermark (a WordArt shape behind the text, at the center of the
+// margins) into every header of the document, so every page shows it; a document without headers
+// gets one. A previous watermark — of docxgen or of Word — is replaced, an empty text removes it:
+//
+//	if data["copy"] == true {
+//		doc.SetWatermark("КОПИЯ", docxgen.WatermarkOptions{})
+//	}
+func (d *Docx) SetWatermark(text string, opts WatermarkOptions) {
+	if text == "" {
+		d.putWatermark(nil)
+		return
+	}
+	font := opts.Font
+	if font == "" {
+		font = "Calibri"
+	}
+	color := strings.TrimPrefix(opts.Color, "#")
+	if color == "" {
+		color = "C0C0C0"
+	}
+	opacity := opts.Opacity
+	if opacity <= 0 || opacity > 1 {
+		opacity = 0.5
+	}
+	width := d.firstSection().UsableWidth().Pt()
+	rotation := ";rotation:315"
+	if opts.Horizontal {
+		rotation = ""
+	} else {
+		width *= 0.9
+	}
+	height := width / max(2, 0.6*float64(utf8.RuneCountInString(text)))
+
+	d.putWatermark(func(part string) string {
+		id, _ := d.nextDrawing("")
+		return fmt.Sprintf(`<w:r><w:rPr><w:noProof/></w:rPr><w:pict>`+
+			`<v:shapetype id="_x0000_t136" coordsize="21600,21600" o:spt="136" adj="10800" path="m@7,l@8,m@5,21600l@6,21600e">`+
+			`<v:path textpathok="t" o:connecttype="custom"/><v:textpath on="t" fitshape="t"/>`+
+			`<o:lock v:ext="edit" text="t" shapetype="t"/></v:shapetype>`+
+			`<v:shape id="%s%d" o:spid="_x0000_s%d" type="#_x0000_t136" style="position:absolute;margin-left:0;margin-top:0;`+
+			`width:%.1fpt;height:%.1fpt%s;z-index:-251657216;mso-position-horizontal:center;mso-position-horizontal-relative:margin;`+
+			`mso-position-vertical:center;mso-position-vertical-relative:margin" o:allowincell="f" fillcolor="#%s" stroked="f">`+
+			`<v:fill opacity="%s"/><v:textpath style="font-family:&quot;%s&quot;;font-size:1pt" string="%s"/>`+
+			`<w10:wrap anchorx="margin" anchory="margin"/></v:shape></w:pict></w:r>`,
+			watermarkTextID, id, 2048+id, width, height, rotation, xmlEscape(color),
+			strconv.FormatFloat(opacity, 'f', -1, 64), xmlEscape(font), xmlEscape(text))
+	})
+}
+
+// SetImageWatermark puts the PNG picture as a watermark behind the text of every page, like
+// SetWatermark: at its size (96 dpi) fitted into the margins, at the center, with the opacity from 0
+// to 1 (0 — 0.5; Word's "washout" is about 0.3).
+func (d *Docx) SetImageWatermark(png []byte, opacity float64) error {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(png))
+	if err != nil || format != "png" {
+		return fmt.Errorf("image watermark: not a PNG picture")
+	}
+	if opacity <= 0 || opacity > 1 {
+		opacity = 0.5
+	}
+	layout := d.firstSection()
+	cx, cy := cfg.Width*geometry.FromPt(0.75).EMU(), cfg.Height*geometry.FromPt(0.75).EMU()
+	if w := layout.UsableWidth().EMU(); w > 0 && cx > w {
+		cx, cy = w, cy*w/cx
+	}
+	if h := layout.UsableHeight().EMU(); h > 0 && cy > h {
+		cx, cy = cx*h/cy, h
+	}
+
+	media := freePartName("word/media/watermark", ".png", d.files)
+	d.files[media] = png
+	d.putWatermark(func(part string) string {
+		rID := d.Rels().AddImage(part, strings.TrimPrefix(media, "word/"))
+		id, _ := d.nextDrawing("")
+		name := watermarkImageName + strconv.Itoa(id)
+		return fmt.Sprintf(`<w:r><w:rPr><w:noProof/></w:rPr><w:drawing>`+
+			`<wp:anchor distT="0" distB="0" distL="0" distR="0" simplePos="0" relativeHeight="0" behindDoc="1" locked="0" layoutInCell="1" allowOverlap="1">`+
+			`<wp:simplePos x="0" y="0"/><wp:positionH relativeFrom="margin"><wp:align>center</wp:align></wp:positionH>`+
+			`<wp:positionV relativeFrom="margin"><wp:align>center</wp:align></wp:positionV>`+
+			`<wp:extent cx="%[3]d" cy="%[4]d"/><wp:effectExtent l="0" t="0" r="0" b="0"/><wp:wrapNone/>`+
+			`<wp:docPr id="%[1]d" name="%[2]s"/><wp:cNvGraphicFramePr/>`+
+			`<a:graphic xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main">`+
+			`<a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/picture">`+
+			`<pic:pic xmlns:pic="http://schemas.openxmlformats.org/drawingml/2006/picture">`+
+			`<pic:nvPicPr><pic:cNvPr id="%[1]d" name="%[2]s"/><pic:cNvPicPr/></pic:nvPicPr>`+
+			`<pic:blipFill><a:blip r:embed="%[5]s"><a:alphaModFix amt="%[6]d"/></a:blip><a:stretch><a:fillRect/></a:stretch></pic:blipFill>`+
+			`<pic:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="%[3]d" cy="%[4]d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></pic:spPr>`+
+			`</pic:pic></a:graphicData></a:graphic></wp:anchor></w:drawing></w:r>`,
+			id, name, cx, cy, rID, int(math.Round(opacity*100000)))
+	})
+	return nil
+}
+
+// putWatermark removes the watermarks of the headers and, if run is given, puts the run it makes for
+// the header into the header in a paragraph of its own. A document without headers gets a header
+// referenced by its first section (the next sections inherit it).
+func (d *Docx) putWatermark(run func(part string) string) {
+	var headers []string
+	for _, part := range d.ListHeaderFooterParts() {
+		if strings.HasPrefix(part, "header") && !slices.Contains(headers, part) {
+			headers = append(headers, part)
+		}
+	}
+	if len(headers) == 0 {
+		if run == nil {
+			return
+		}
+		headers = []string{d.addHeader()}
+	}
+	for _, part := range headers {
+		content := removeWatermarks(string(d.files["word/"+part+".xml"]))
+		if run != nil {
+			if loc := reHeaderRoot.FindStringIndex(content); loc != nil {
+				open := withNamespaces(content[loc[0]:loc[1]], watermarkNamespaces)
+				content = content[:loc[0]] + open + "<w:p>" + run(part) + "</w:p>" + content[loc[1]:]
+			}
+		}
+		d.files["word/"+part+".xml"] = []byte(content)
+	}
+}
+
+// removeWatermarks removes the runs with the watermark shapes, with the paragraphs docxgen put them in.
+func removeWatermarks(s string) string {
+	for {
+		at := strings.Index(s, watermarkTextID)
+		if i := strings.Index(s, watermarkImageName); i >= 0 && (at < 0 || i < at) {
+			at = i
+		}
+		if at < 0 {
+			return strings.ReplaceAll(s, "<w:p></w:p>", "")
+		}
+		run := max(strings.LastIndex(s[:at], "<w:r>"), strings.LastIndex(s[:at], "<w:r "))
+		if run < 0 {
+			return s
+		}
+		s = s[:run] + s[elementEnd(s, run):]
+	}
+}
+
+// addHeader creates an empty default header, references it from the first section and returns its name.
+func (d *Docx) addHeader() string {
+	name := freePartName("word/header", ".xml", d.files)
+	d.files[name] = []byte(xml.Header + `<w:hdr xmlns:w="` + wordMainNamespace + `"` + watermarkNamespaces + `></w:hdr>`)
+	d.ContentTypes().SetOverride(name, wmlType+"header+xml")
+	rID := d.Rels().Add("document", Relationship{Type: RelTypeHeader, Target: strings.TrimPrefix(name, "word/")})
+
+	body := string(d.files["word/document.xml"])
+	if loc := reDocumentRoot.FindStringIndex(body); loc != nil {
+		body = body[:loc[0]] + withNamespaces(body[loc[0]:loc[1]], watermarkNamespaces) + body[loc[1]:]
+	}
+	ref := `<w:headerReference w:type="default" r:id="` + rID + `"/>`
+	if loc := reSectPr.FindStringIndex(body); loc != nil {
+		sect := body[loc[0]:loc[1]]
+		if strings.HasSuffix(sect, "/>") {
+			sect = strings.TrimSuffix(sect, "/>") + ">" + ref + "</w:sectPr>"
+		} else {
+			gt := strings.IndexByte(sect, '>') + 1
+			sect = sect[:gt] + ref + sect[gt:]
+		}
+		body = body[:loc[0]] + sect + body[loc[1]:]
+	} else if end := strings.LastIndex(body, "</w:body>"); end >= 0 {
+		body = body[:end] + "<w:sectPr>" + ref + "</w:sectPr>" + body[end:]
+	}
+	d.files["word/document.xml"] = []byte(body)
+	return partName(name)
+}
+
+// firstSection - the layout of the first section of the document.
+func (d *Docx) firstSection() PageLayout {
+	if sections := d.Sections(); len(sections) > 0 {
+		return sections[0]
+	}
+	return defaultPageLayout
+}
+
+// freePartName - prefix + the first free number + ext among the files ("word/header3.xml").
+func freePartName(prefix, ext string, files map[string][]byte) string {
+	for n := 1; ; n++ {
+		if name := prefix + strconv.Itoa(n) + ext; files[name] == nil {
+			return name
+		}
+	}
+}